@@ -9,7 +9,11 @@ package main
 import (
 	"bufio"
 	"context"
+	"crypto/ed25519"
 	"embed"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
@@ -17,24 +21,39 @@ import (
 	"os/signal"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/chzyer/readline"
+	"github.com/sipeed/picoclaw/pkg/admin"
 	"github.com/sipeed/picoclaw/pkg/agent"
 	"github.com/sipeed/picoclaw/pkg/auth"
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/channels"
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/constants"
 	"github.com/sipeed/picoclaw/pkg/cron"
 	"github.com/sipeed/picoclaw/pkg/devices"
+	"github.com/sipeed/picoclaw/pkg/federation"
+	"github.com/sipeed/picoclaw/pkg/feedback"
+	"github.com/sipeed/picoclaw/pkg/gateway"
+	"github.com/sipeed/picoclaw/pkg/governor"
 	"github.com/sipeed/picoclaw/pkg/heartbeat"
+	"github.com/sipeed/picoclaw/pkg/i18n"
+	"github.com/sipeed/picoclaw/pkg/locale"
 	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/media"
 	"github.com/sipeed/picoclaw/pkg/migrate"
+	"github.com/sipeed/picoclaw/pkg/monitor"
+	"github.com/sipeed/picoclaw/pkg/network"
 	"github.com/sipeed/picoclaw/pkg/providers"
 	"github.com/sipeed/picoclaw/pkg/skills"
 	"github.com/sipeed/picoclaw/pkg/state"
+	"github.com/sipeed/picoclaw/pkg/tenant"
 	"github.com/sipeed/picoclaw/pkg/tools"
+	"github.com/sipeed/picoclaw/pkg/update"
 	"github.com/sipeed/picoclaw/pkg/voice"
 )
 
@@ -51,6 +70,9 @@ var (
 
 const logo = "🦞"
 
+// configProfile is the config profile selected with --profile or PICOCLAW_PROFILE.
+var configProfile = os.Getenv("PICOCLAW_PROFILE")
+
 // formatVersion returns the version string with optional git commit
 func formatVersion() string {
 	v := version
@@ -118,6 +140,7 @@ func copyDirectory(src, dst string) error {
 }
 
 func main() {
+	os.Args = extractProfileFlag(os.Args)
 	if len(os.Args) < 2 {
 		printHelp()
 		os.Exit(1)
@@ -134,12 +157,22 @@ func main() {
 		gatewayCmd()
 	case "status":
 		statusCmd()
+	case "top":
+		topCmd()
+	case "config":
+		configCmd()
 	case "migrate":
 		migrateCmd()
 	case "auth":
 		authCmd()
 	case "cron":
 		cronCmd()
+	case "feedback":
+		feedbackCmd()
+	case "tenants":
+		tenantsCmd()
+	case "update":
+		updateCmd()
 	case "skills":
 		if len(os.Args) < 3 {
 			skillsHelp()
@@ -208,10 +241,37 @@ func printHelp() {
 	fmt.Println("  auth        Manage authentication (login, logout, status)")
 	fmt.Println("  gateway     Start picoclaw gateway")
 	fmt.Println("  status      Show picoclaw status")
+	fmt.Println("  top         Live view of a running gateway")
 	fmt.Println("  cron        Manage scheduled tasks")
+	fmt.Println("  feedback    Export rated answers and eval scenarios")
+	fmt.Println("  tenants     List, inspect and purge per-user workspaces")
+	fmt.Println("  update      Install a newer release (--check only looks)")
+	fmt.Println("  config      Show the effective configuration")
 	fmt.Println("  migrate     Migrate from OpenClaw to PicoClaw")
 	fmt.Println("  skills      Manage skills (install, list, remove)")
 	fmt.Println("  version     Show version information")
+	fmt.Println()
+	fmt.Println("Global options:")
+	fmt.Println("  --profile <name>  Apply ~/.picoclaw/profiles/<name>.json (or set PICOCLAW_PROFILE)")
+	fmt.Printf("                    Built in: %s\n", strings.Join(config.BuiltinProfiles(), ", "))
+}
+
+// extractProfileFlag removes --profile from args, wherever it appears, and
+// records the selected profile.
+func extractProfileFlag(args []string) []string {
+	out := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--profile" && i+1 < len(args):
+			configProfile = args[i+1]
+			i++
+		case strings.HasPrefix(args[i], "--profile="):
+			configProfile = strings.TrimPrefix(args[i], "--profile=")
+		default:
+			out = append(out, args[i])
+		}
+	}
+	return out
 }
 
 func onboard() {
@@ -415,6 +475,8 @@ func agentCmd() {
 			"skills_available": startupInfo["skills"].(map[string]interface{})["available"],
 		})
 
+	agentLoop.Governor().Start(context.Background())
+
 	if message != "" {
 		ctx := context.Background()
 		response, err := agentLoop.ProcessDirect(ctx, message, sessionKey)
@@ -516,21 +578,44 @@ func simpleInteractiveMode(agentLoop *agent.AgentLoop, sessionKey string) {
 }
 
 func gatewayCmd() {
-	// Check for --debug flag
+	// --role splits channels and agent into separate processes linked by bus.url
+	var role bus.Role
 	args := os.Args[2:]
-	for _, arg := range args {
-		if arg == "--debug" || arg == "-d" {
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--debug", "-d":
 			logger.SetLevel(logger.DEBUG)
 			fmt.Println("🔍 Debug mode enabled")
-			break
+		case "--role":
+			if i+1 < len(args) {
+				role = bus.Role(args[i+1])
+				i++
+			}
 		}
 	}
+	switch role {
+	case "", "all":
+		role = ""
+	case bus.RoleChannels, bus.RoleAgent:
+	default:
+		fmt.Printf("Error: --role must be channels, agent or all, not %q\n", role)
+		os.Exit(1)
+	}
 
 	cfg, err := loadConfig()
 	if err != nil {
 		fmt.Printf("Error loading config: %v\n", err)
 		os.Exit(1)
 	}
+	if role != "" && cfg.Bus.Token == "" {
+		fmt.Println("Error: bus.token is required to run with --role")
+		os.Exit(1)
+	}
+	exe := bootUpdate()
+	if role == bus.RoleChannels {
+		gatewayChannelsCmd(cfg, exe)
+		return
+	}
 
 	provider, err := providers.CreateProvider(cfg)
 	if err != nil {
@@ -562,12 +647,21 @@ func gatewayCmd() {
 	// Setup cron tool and service
 	cronService := setupCronTool(agentLoop, msgBus, cfg.WorkspacePath())
 
+	var federationNode *federation.Node
+	if cfg.Federation.Enabled {
+		federationNode = federation.New(cfg.Federation, agentLoop)
+		if len(federationNode.Peers()) > 0 {
+			agentLoop.RegisterTool(tools.NewAskPeerTool(federationNode, msgBus))
+		}
+	}
+
 	heartbeatService := heartbeat.NewHeartbeatService(
 		cfg.WorkspacePath(),
 		cfg.Heartbeat.Interval,
 		cfg.Heartbeat.Enabled,
 	)
 	heartbeatService.SetBus(msgBus)
+	heartbeatService.SetLocales(agentLoop.Locales())
 	heartbeatService.SetHandler(func(prompt, channel, chatID string) *tools.ToolResult {
 		// Use cli:direct as fallback if no valid channel
 		if channel == "" || chatID == "" {
@@ -586,47 +680,97 @@ func gatewayCmd() {
 		return tools.SilentResult(response)
 	})
 
+	if role == bus.RoleAgent {
+		// The channels run in the other process
+		cfg.Channels = config.ChannelsConfig{}
+	}
 	channelManager, err := channels.NewManager(cfg, msgBus)
 	if err != nil {
 		fmt.Printf("Error creating channel manager: %v\n", err)
 		os.Exit(1)
 	}
+	channelManager.SetMediaStore(agentLoop.MediaStore())
+	channelManager.SetStateStore(state.NewManager(cfg.WorkspacePath()))
 
-	var transcriber *voice.GroqTranscriber
-	if cfg.Providers.Groq.APIKey != "" {
-		transcriber = voice.NewGroqTranscriber(cfg.Providers.Groq.APIKey)
-		logger.InfoC("voice", "Groq voice transcription enabled")
+	attachTranscriber(cfg, channelManager)
+
+	enabledChannels := channelManager.GetEnabledChannels()
+	if len(enabledChannels) > 0 {
+		fmt.Printf("✓ Channels enabled: %s\n", enabledChannels)
+	} else if role != bus.RoleAgent {
+		fmt.Println("⚠ Warning: No channels enabled")
 	}
 
-	if transcriber != nil {
-		if telegramChannel, ok := channelManager.GetChannel("telegram"); ok {
-			if tc, ok := telegramChannel.(*channels.TelegramChannel); ok {
-				tc.SetTranscriber(transcriber)
-				logger.InfoC("voice", "Groq transcription attached to Telegram channel")
-			}
-		}
-		if discordChannel, ok := channelManager.GetChannel("discord"); ok {
-			if dc, ok := discordChannel.(*channels.DiscordChannel); ok {
-				dc.SetTranscriber(transcriber)
-				logger.InfoC("voice", "Groq transcription attached to Discord channel")
-			}
-		}
-		if slackChannel, ok := channelManager.GetChannel("slack"); ok {
-			if sc, ok := slackChannel.(*channels.SlackChannel); ok {
-				sc.SetTranscriber(transcriber)
-				logger.InfoC("voice", "Groq transcription attached to Slack channel")
+	gatewayServer := gateway.NewServer(cfg.Gateway)
+	channelManager.RegisterWebhooks(gatewayServer.Handle)
+
+	var busLink *bus.Link
+	if role == bus.RoleAgent {
+		busLink = newBusLink(cfg, msgBus, bus.RoleAgent, agentLoop.MediaStore(), gatewayServer)
+		busLink.OnRemoteTools(func(specs []bus.ToolSpec) {
+			for _, spec := range specs {
+				agentLoop.RegisterTool(tools.NewRemoteTool(busLink, spec))
 			}
+		})
+	}
+	if federationNode != nil {
+		gatewayServer.Handle(federationNode.Path(), federationNode)
+		gatewayServer.Handle(federationNode.Path()+"/", federationNode)
+		gatewayServer.Handle("/.well-known/agent.json", federationNode)
+	}
+
+	msgBus.AddObserver(agentLoop.Monitor())
+	monitorHandler := monitor.NewHandler(monitor.Sources{
+		Monitor:   agentLoop.Monitor(),
+		Bus:       msgBus,
+		Channels:  channelManager,
+		Subagents: agentLoop.Subagents(),
+		Cron:      cronService,
+		Governor:  agentLoop.Governor(),
+	})
+
+	var dashboard *admin.Dashboard
+	if cfg.Gateway.Admin.Enabled {
+		dashboard, err = admin.New(cfg.Gateway.Admin, admin.Deps{
+			Version:    formatVersion(),
+			ConfigPath: getConfigPath(),
+			Workspace:  cfg.WorkspacePath(),
+			Channels:   channelManager,
+			Sessions:   agentLoop.Sessions(),
+			Cron:       cronService,
+			Heartbeat:  heartbeatService,
+			Skills:     agentLoop.SkillsLoader(),
+			Monitor:    monitorHandler,
+		})
+		if err != nil {
+			fmt.Printf("Error creating admin dashboard: %v\n", err)
+		} else {
+			gatewayServer.Handle(dashboard.Pattern(), dashboard)
+			dashboard.Start()
 		}
 	}
 
-	enabledChannels := channelManager.GetEnabledChannels()
-	if len(enabledChannels) > 0 {
-		fmt.Printf("✓ Channels enabled: %s\n", enabledChannels)
+	if err := gatewayServer.Start(); err != nil {
+		fmt.Printf("Error starting gateway HTTP server: %v\n", err)
 	} else {
-		fmt.Println("⚠ Warning: No channels enabled")
+		fmt.Printf("✓ Gateway started on %s\n", gatewayServer.Addr())
+		if dashboard != nil {
+			fmt.Printf("✓ Admin dashboard at http://%s%s\n", gatewayServer.Addr(), dashboard.Pattern())
+		}
+		if federationNode != nil {
+			fmt.Printf("✓ Federation endpoint at http://%s%s (%d peers)\n", gatewayServer.Addr(), federationNode.Path(), len(federationNode.Peers()))
+		}
 	}
 
-	fmt.Printf("✓ Gateway started on %s:%d\n", cfg.Gateway.Host, cfg.Gateway.Port)
+	var controlSocket *monitor.SocketServer
+	if path := cfg.ControlSocketPath(); path != "" {
+		controlSocket, err = monitor.ListenSocket(path, monitorHandler)
+		if err != nil {
+			fmt.Printf("Error starting control socket: %v\n", err)
+		} else {
+			fmt.Printf("✓ Control socket at %s (picoclaw top)\n", path)
+		}
+	}
 	fmt.Println("Press Ctrl+C to stop")
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -654,26 +798,290 @@ func gatewayCmd() {
 		fmt.Println("✓ Device event service started")
 	}
 
+	if federationNode != nil {
+		federationNode.Start(ctx)
+	}
+	if busLink != nil {
+		go busLink.Run(ctx)
+	}
+
+	agentLoop.Governor().Start(ctx)
 	if err := channelManager.StartAll(ctx); err != nil {
 		fmt.Printf("Error starting channels: %v\n", err)
 	}
 
 	go agentLoop.Run(ctx)
+	confirmUpdate(exe)
+	startUpdateChecker(ctx, cfg, msgBus)
 
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt)
 	<-sigChan
 
 	fmt.Println("\nShutting down...")
+	// The agent stops first, so the channels still deliver the
+	// translations it flushes
+	agentLoop.Stop()
+	waitOutbound(msgBus, 5*time.Second)
 	cancel()
 	deviceService.Stop()
 	heartbeatService.Stop()
 	cronService.Stop()
-	agentLoop.Stop()
 	channelManager.StopAll(ctx)
+	gatewayServer.Stop(context.Background())
+	if dashboard != nil {
+		dashboard.Stop()
+	}
+	if controlSocket != nil {
+		controlSocket.Close()
+	}
+	fmt.Println("✓ Gateway stopped")
+}
+
+// gatewayChannelsCmd runs the channels half of a split deployment: the
+// chat channels, device monitoring and the tools exported to the agent.
+// Messages go to the agent process over the bus link.
+func gatewayChannelsCmd(cfg *config.Config, exe string) {
+	msgBus := bus.NewMessageBus()
+	workspace := cfg.WorkspacePath()
+
+	// Attachments are kept here until the link has sent them
+	mediaStore, err := media.NewStore(filepath.Join(workspace, "media"), media.Options{
+		MaxBytes: int64(cfg.Tools.Media.MaxSizeMB) << 20,
+		MaxAge:   time.Duration(cfg.Tools.Media.MaxAgeDays) * 24 * time.Hour,
+	})
+	if err != nil {
+		fmt.Printf("Error opening media store: %v\n", err)
+		os.Exit(1)
+	}
+
+	channelManager, err := channels.NewManager(cfg, msgBus)
+	if err != nil {
+		fmt.Printf("Error creating channel manager: %v\n", err)
+		os.Exit(1)
+	}
+	channelManager.SetMediaStore(mediaStore)
+	channelManager.SetStateStore(state.NewManager(workspace))
+	attachTranscriber(cfg, channelManager)
+
+	enabledChannels := channelManager.GetEnabledChannels()
+	if len(enabledChannels) > 0 {
+		fmt.Printf("✓ Channels enabled: %s\n", enabledChannels)
+	} else {
+		fmt.Println("⚠ Warning: No channels enabled")
+	}
+
+	gatewayServer := gateway.NewServer(cfg.Gateway)
+	channelManager.RegisterWebhooks(gatewayServer.Handle)
+
+	busLink := newBusLink(cfg, msgBus, bus.RoleChannels, mediaStore, gatewayServer)
+	hardware := tools.NewToolRegistry()
+	hardware.Register(tools.NewI2CTool())
+	hardware.Register(tools.NewSPITool())
+	tools.ExportTools(busLink, hardware, cfg.Bus.ExportTools)
+
+	if err := gatewayServer.Start(); err != nil {
+		fmt.Printf("Error starting gateway HTTP server: %v\n", err)
+	} else {
+		fmt.Printf("✓ Gateway started on %s\n", gatewayServer.Addr())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	governor.New(cfg.Memory).Start(ctx)
+
+	// The agent records the last active chat on its own host; device
+	// notifications here need it too
+	stateManager := state.NewManager(workspace)
+	msgBus.AddObserver(lastChannelObserver{state: stateManager})
+	deviceService := devices.NewService(devices.Config{
+		Enabled:    cfg.Devices.Enabled,
+		MonitorUSB: cfg.Devices.MonitorUSB,
+	}, stateManager)
+	deviceService.SetBus(msgBus)
+	if err := deviceService.Start(ctx); err != nil {
+		fmt.Printf("Error starting device service: %v\n", err)
+	} else if cfg.Devices.Enabled {
+		fmt.Println("✓ Device event service started")
+	}
+
+	go busLink.Run(ctx)
+	if err := channelManager.StartAll(ctx); err != nil {
+		fmt.Printf("Error starting channels: %v\n", err)
+	}
+	confirmUpdate(exe)
+	fmt.Println("Press Ctrl+C to stop")
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt)
+	<-sigChan
+
+	fmt.Println("\nShutting down...")
+	cancel()
+	deviceService.Stop()
+	channelManager.StopAll(context.Background())
+	gatewayServer.Stop(context.Background())
 	fmt.Println("✓ Gateway stopped")
 }
 
+// newBusLink creates the link to the other half of a split deployment. It
+// connects to bus.url, or else accepts the connection on the gateway.
+func newBusLink(cfg *config.Config, msgBus *bus.MessageBus, role bus.Role, store *media.Store, server *gateway.Server) *bus.Link {
+	link := bus.NewLink(msgBus, bus.LinkOptions{
+		Role:  role,
+		URL:   cfg.Bus.URL,
+		Token: cfg.Bus.Token,
+		Media: store,
+	})
+	if cfg.Bus.URL != "" {
+		fmt.Printf("✓ Bus link (%s) connecting to %s\n", role, cfg.Bus.URL)
+	} else {
+		server.Handle(cfg.Bus.Path, link)
+		fmt.Printf("✓ Bus link (%s) waiting for the other side on %s\n", role, cfg.Bus.Path)
+	}
+	return link
+}
+
+// lastChannelObserver records the chat of each inbound message as the last
+// active one.
+type lastChannelObserver struct {
+	state *state.Manager
+}
+
+func (o lastChannelObserver) ObserveInbound(msg bus.InboundMessage) {
+	if constants.IsInternalChannel(msg.Channel) || msg.ChatID == "" {
+		return
+	}
+	o.state.SetLastChannel(fmt.Sprintf("%s:%s", msg.Channel, msg.ChatID))
+}
+
+func (o lastChannelObserver) ObserveOutbound(bus.OutboundMessage) {}
+
+// attachTranscriber gives the channels that accept voice messages a
+// transcriber, when one is configured.
+func attachTranscriber(cfg *config.Config, channelManager *channels.Manager) {
+	var transcriber *voice.GroqTranscriber
+	if cfg.Providers.Groq.APIKey != "" {
+		transcriber = voice.NewGroqTranscriber(cfg.Providers.Groq.APIKey)
+		logger.InfoC("voice", "Groq voice transcription enabled")
+	}
+
+	if transcriber != nil {
+		if telegramChannel, ok := channelManager.GetChannel("telegram"); ok {
+			if tc, ok := telegramChannel.(*channels.TelegramChannel); ok {
+				tc.SetTranscriber(transcriber)
+				logger.InfoC("voice", "Groq transcription attached to Telegram channel")
+			}
+		}
+		if discordChannel, ok := channelManager.GetChannel("discord"); ok {
+			if dc, ok := discordChannel.(*channels.DiscordChannel); ok {
+				dc.SetTranscriber(transcriber)
+				logger.InfoC("voice", "Groq transcription attached to Discord channel")
+			}
+		}
+		if slackChannel, ok := channelManager.GetChannel("slack"); ok {
+			if sc, ok := slackChannel.(*channels.SlackChannel); ok {
+				sc.SetTranscriber(transcriber)
+				logger.InfoC("voice", "Groq transcription attached to Slack channel")
+			}
+		}
+		if mattermostChannel, ok := channelManager.GetChannel("mattermost"); ok {
+			if mc, ok := mattermostChannel.(*channels.MattermostChannel); ok {
+				mc.SetTranscriber(transcriber)
+				logger.InfoC("voice", "Groq transcription attached to Mattermost channel")
+			}
+		}
+	}
+}
+
+func topCmd() {
+	socketPath := ""
+	baseURL := ""
+	username := ""
+	password := ""
+	once := false
+
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--socket":
+			if i+1 < len(args) {
+				socketPath = args[i+1]
+				i++
+			}
+		case "--url":
+			if i+1 < len(args) {
+				baseURL = args[i+1]
+				i++
+			}
+		case "--user":
+			if i+1 < len(args) {
+				username = args[i+1]
+				i++
+			}
+		case "--password":
+			if i+1 < len(args) {
+				password = args[i+1]
+				i++
+			}
+		case "--once":
+			once = true
+		case "-h", "--help":
+			topHelp()
+			return
+		}
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	var client *monitor.Client
+	if baseURL != "" {
+		if username == "" {
+			username = cfg.Gateway.Admin.Username
+		}
+		if password == "" {
+			password = cfg.Gateway.Admin.Password
+		}
+		client = monitor.NewHTTPClient(baseURL, username, password)
+	} else {
+		if socketPath == "" {
+			socketPath = cfg.ControlSocketPath()
+		}
+		if socketPath == "" {
+			fmt.Println("No control socket configured; use --socket or --url")
+			os.Exit(1)
+		}
+		client = monitor.NewSocketClient(socketPath)
+	}
+
+	if once {
+		err = monitor.PrintSnapshot(context.Background(), client, os.Stdout)
+	} else {
+		err = monitor.RunTop(context.Background(), client, os.Stdin, os.Stdout)
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func topHelp() {
+	fmt.Println("\nUsage: picoclaw top [options]")
+	fmt.Println()
+	fmt.Println("Options:")
+	fmt.Println("  --socket <path>    Control socket of the gateway (default: gateway.control_socket)")
+	fmt.Println("  --url <url>        Connect over HTTP through the admin dashboard, e.g. http://host:18790/admin")
+	fmt.Println("  --user <name>      Admin username (default: gateway.admin.username)")
+	fmt.Println("  --password <pass>  Admin password (default: gateway.admin.password)")
+	fmt.Println("  --once             Print one snapshot and exit")
+	fmt.Println()
+	fmt.Println("Keys: tab switch pane, j/k select, c cancel turn, p pause/resume channel, q quit")
+}
+
 func statusCmd() {
 	cfg, err := loadConfig()
 	if err != nil {
@@ -747,6 +1155,57 @@ func statusCmd() {
 			}
 		}
 	}
+
+	printMemoryStatus(cfg)
+}
+
+// printMemoryStatus shows the memory settings and, when a gateway is
+// running, what its governor last measured.
+func printMemoryStatus(cfg *config.Config) {
+	m := cfg.Memory
+	fmt.Println("\nMemory:")
+	if configProfile != "" {
+		fmt.Printf("  Profile: %s\n", configProfile)
+	}
+	if m.LimitMB > 0 {
+		fmt.Printf("  Soft limit: %d MB (evicting at %d%%)\n", m.LimitMB, m.PressurePercent)
+	} else {
+		fmt.Println("  Soft limit: not set")
+	}
+	fmt.Printf("  Largest read: %d KB\n", m.MaxReadKB)
+	fmt.Printf("  Background tasks at once: %d\n", m.MaxBackground)
+	if m.SessionIdleMinutes > 0 || m.MaxSessions > 0 {
+		fmt.Printf("  Sessions in memory: idle %d min, at most %d (0 = no limit)\n", m.SessionIdleMinutes, m.MaxSessions)
+	}
+
+	path := cfg.ControlSocketPath()
+	if path == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	snap, err := monitor.NewSocketClient(path).Snapshot(ctx)
+	if err != nil || snap.Memory.Governor == nil {
+		fmt.Println("  Gateway: not running")
+		return
+	}
+	g := snap.Memory.Governor
+	state := "ok"
+	if g.Pressure {
+		state = "under pressure"
+	}
+	fmt.Printf("  Gateway: RSS %d MB, heap %d MB, %s\n", g.RSS>>20, g.HeapAlloc>>20, state)
+	fmt.Printf("  Gateway background tasks: %d running, %d allowed\n", g.Background, g.BackgroundLimit)
+	if len(g.Evicted) > 0 {
+		names := make([]string, 0, len(g.Evicted))
+		for name := range g.Evicted {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Printf("  Gateway evicted %s: %d\n", name, g.Evicted[name])
+		}
+	}
 }
 
 func authCmd() {
@@ -821,7 +1280,12 @@ func authLoginCmd() {
 }
 
 func authLoginOpenAI(useDeviceCode bool) {
+	if _, err := loadConfig(); err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
 	cfg := auth.OpenAIOAuthConfig()
+	cfg.Client = network.NewClient(network.ComponentProviders, network.Options{Timeout: 30 * time.Second})
 
 	var cred *auth.AuthCredential
 	var err error
@@ -842,7 +1306,7 @@ func authLoginOpenAI(useDeviceCode bool) {
 		os.Exit(1)
 	}
 
-	appCfg, err := loadConfig()
+	appCfg, err := loadBaseConfig()
 	if err == nil {
 		appCfg.Providers.OpenAI.AuthMethod = "oauth"
 		if err := config.SaveConfig(getConfigPath(), appCfg); err != nil {
@@ -868,7 +1332,7 @@ func authLoginPasteToken(provider string) {
 		os.Exit(1)
 	}
 
-	appCfg, err := loadConfig()
+	appCfg, err := loadBaseConfig()
 	if err == nil {
 		switch provider {
 		case "anthropic":
@@ -904,7 +1368,7 @@ func authLogoutCmd() {
 			os.Exit(1)
 		}
 
-		appCfg, err := loadConfig()
+		appCfg, err := loadBaseConfig()
 		if err == nil {
 			switch provider {
 			case "openai":
@@ -922,7 +1386,7 @@ func authLogoutCmd() {
 			os.Exit(1)
 		}
 
-		appCfg, err := loadConfig()
+		appCfg, err := loadBaseConfig()
 		if err == nil {
 			appCfg.Providers.OpenAI.AuthMethod = ""
 			appCfg.Providers.Anthropic.AuthMethod = ""
@@ -982,6 +1446,10 @@ func setupCronTool(agentLoop *agent.AgentLoop, msgBus *bus.MessageBus, workspace
 	// Create and register CronTool
 	cronTool := tools.NewCronTool(cronService, agentLoop, msgBus, workspace)
 	agentLoop.RegisterTool(cronTool)
+	agentLoop.AddInterceptor(cronTool.HandleAck)
+	if tenants := agentLoop.Tenants(); tenants != nil {
+		cronTool.SetTenants(tenants)
+	}
 
 	// Set the onJob handler
 	cronService.SetOnJob(func(job *cron.CronJob) (string, error) {
@@ -993,19 +1461,109 @@ func setupCronTool(agentLoop *agent.AgentLoop, msgBus *bus.MessageBus, workspace
 }
 
 func loadConfig() (*config.Config, error) {
-	return config.LoadConfig(getConfigPath())
+	cfg, err := config.LoadConfigProfile(getConfigPath(), configProfile)
+	if err != nil {
+		return nil, err
+	}
+	// Every HTTP client created from here on uses the network settings.
+	if err := network.Configure(cfg.Network); err != nil {
+		return nil, fmt.Errorf("network: %w", err)
+	}
+	return cfg, nil
 }
 
-func cronCmd() {
-	if len(os.Args) < 3 {
-		cronHelp()
+// loadBaseConfig loads only config.json, for commands that save it back.
+// Overlays, profiles and environment overrides stay out of the file.
+func loadBaseConfig() (*config.Config, error) {
+	return config.LoadBaseConfig(getConfigPath())
+}
+
+func configCmd() {
+	if len(os.Args) < 3 || os.Args[2] != "show" {
+		configHelp()
 		return
 	}
 
-	subcommand := os.Args[2]
+	resolved := false
+	showSecrets := false
+	for _, arg := range os.Args[3:] {
+		switch arg {
+		case "--resolved":
+			resolved = true
+		case "--show-secrets":
+			showSecrets = true
+		}
+	}
 
-	// Load config to get workspace path
-	cfg, err := loadConfig()
+	configPath := getConfigPath()
+	r, err := config.ResolveConfig(configPath, configProfile)
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	leaves, err := r.Leaves(showSecrets)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if !resolved {
+		for _, leaf := range leaves {
+			value, _ := json.Marshal(leaf.Value)
+			fmt.Printf("%s = %s\n", leaf.Path, value)
+		}
+		return
+	}
+
+	fmt.Println("Layers (later wins):")
+	fmt.Println("  defaults")
+	for _, layer := range r.Layers {
+		fmt.Printf("  %s\n", layer)
+	}
+	fmt.Println("  environment (PICOCLAW_*)")
+	fmt.Println()
+
+	configDir := filepath.Dir(configPath)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, leaf := range leaves {
+		value, _ := json.Marshal(leaf.Value)
+		source := leaf.Source
+		if rel, err := filepath.Rel(configDir, source); err == nil && !strings.HasPrefix(rel, "..") {
+			source = rel
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", leaf.Path, value, source)
+	}
+	w.Flush()
+}
+
+func configHelp() {
+	fmt.Println("\nConfig commands:")
+	fmt.Println("  show                 Print the effective configuration")
+	fmt.Println()
+	fmt.Println("Show options:")
+	fmt.Println("  --resolved           Also show which layer set each value")
+	fmt.Println("  --show-secrets       Do not mask tokens, keys and passwords")
+	fmt.Println()
+	fmt.Println("Layers, later ones win:")
+	fmt.Println("  defaults, config.json, config.d/*.json (lexical order),")
+	fmt.Println("  profiles/<name>.json (--profile), PICOCLAW_* environment variables")
+	fmt.Println()
+	fmt.Println("Examples:")
+	fmt.Println("  picoclaw config show --resolved")
+	fmt.Println("  picoclaw --profile lab config show --resolved")
+}
+
+func cronCmd() {
+	if len(os.Args) < 3 {
+		cronHelp()
+		return
+	}
+
+	subcommand := os.Args[2]
+
+	// Load config to get workspace path
+	cfg, err := loadConfig()
 	if err != nil {
 		fmt.Printf("Error loading config: %v\n", err)
 		return
@@ -1017,7 +1575,7 @@ func cronCmd() {
 	case "list":
 		cronListCmd(cronStorePath)
 	case "add":
-		cronAddCmd(cronStorePath)
+		cronAddCmd(cronStorePath, cfg)
 	case "remove":
 		if len(os.Args) < 4 {
 			fmt.Println("Usage: picoclaw cron remove <job_id>")
@@ -1045,8 +1603,10 @@ func cronHelp() {
 	fmt.Println("Add options:")
 	fmt.Println("  -n, --name       Job name")
 	fmt.Println("  -m, --message    Message for agent")
+	fmt.Println("  -w, --when       Schedule in words (e.g. 'every weekday at 9', '每天早上8点')")
 	fmt.Println("  -e, --every      Run every N seconds")
 	fmt.Println("  -c, --cron       Cron expression (e.g. '0 9 * * *')")
+	fmt.Println("  --tz             Time zone of --when or --cron (default: the chat's, then agents.defaults.timezone)")
 	fmt.Println("  -d, --deliver     Deliver response to channel")
 	fmt.Println("  --to             Recipient for delivery")
 	fmt.Println("  --channel        Channel for delivery")
@@ -1069,6 +1629,9 @@ func cronListCmd(storePath string) {
 			schedule = fmt.Sprintf("every %ds", *job.Schedule.EveryMS/1000)
 		} else if job.Schedule.Kind == "cron" {
 			schedule = job.Schedule.Expr
+			if job.Schedule.TZ != "" {
+				schedule += " (" + job.Schedule.TZ + ")"
+			}
 		} else {
 			schedule = "one-time"
 		}
@@ -1088,17 +1651,24 @@ func cronListCmd(storePath string) {
 		fmt.Printf("    Schedule: %s\n", schedule)
 		fmt.Printf("    Status: %s\n", status)
 		fmt.Printf("    Next run: %s\n", nextRun)
+		if r := job.State.Reminder; r != nil {
+			fmt.Printf("    Awaiting acknowledgement: %d attempts since %s\n", r.Attempts, time.UnixMilli(r.FiredAtMS).Format("2006-01-02 15:04"))
+		} else if job.State.LastAckAtMS != nil {
+			fmt.Printf("    Last acknowledged: %s by %s\n", time.UnixMilli(*job.State.LastAckAtMS).Format("2006-01-02 15:04"), job.State.LastAckBy)
+		}
 	}
 }
 
-func cronAddCmd(storePath string) {
+func cronAddCmd(storePath string, cfg *config.Config) {
 	name := ""
 	message := ""
 	var everySec *int64
 	cronExpr := ""
+	when := ""
 	deliver := false
 	channel := ""
 	to := ""
+	tz := ""
 
 	args := os.Args[3:]
 	for i := 0; i < len(args); i++ {
@@ -1125,6 +1695,11 @@ func cronAddCmd(storePath string) {
 				cronExpr = args[i+1]
 				i++
 			}
+		case "-w", "--when":
+			if i+1 < len(args) {
+				when = args[i+1]
+				i++
+			}
 		case "-d", "--deliver":
 			deliver = true
 		case "--to":
@@ -1137,6 +1712,11 @@ func cronAddCmd(storePath string) {
 				channel = args[i+1]
 				i++
 			}
+		case "--tz":
+			if i+1 < len(args) {
+				tz = args[i+1]
+				i++
+			}
 		}
 	}
 
@@ -1150,22 +1730,42 @@ func cronAddCmd(storePath string) {
 		return
 	}
 
-	if everySec == nil && cronExpr == "" {
-		fmt.Println("Error: Either --every or --cron must be specified")
+	if when == "" && everySec == nil && cronExpr == "" {
+		fmt.Println("Error: One of --when, --every or --cron must be specified")
 		return
 	}
 
 	var schedule cron.CronSchedule
-	if everySec != nil {
+	var understood string
+	if everySec != nil && when == "" {
 		everyMS := *everySec * 1000
 		schedule = cron.CronSchedule{
 			Kind:    "every",
 			EveryMS: &everyMS,
 		}
 	} else {
-		schedule = cron.CronSchedule{
-			Kind: "cron",
-			Expr: cronExpr,
+		// Without --tz, use the zone set for the target chat or the default
+		if tz == "" {
+			locales := locale.NewStore(cfg.WorkspacePath(), locale.Settings{TimeZone: cfg.Agents.Defaults.Timezone})
+			tz = locales.Resolve(channel, to, "").TimeZone
+		}
+		settings, err := (locale.Settings{TimeZone: tz}).Normalize()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		if when != "" {
+			schedule, understood, err = cron.ParseWhen(when, time.Now().In(settings.Location()))
+			if err != nil {
+				fmt.Printf("Error: could not parse --when: %v\n", err)
+				return
+			}
+		} else {
+			schedule = cron.CronSchedule{
+				Kind: "cron",
+				Expr: cronExpr,
+				TZ:   tz,
+			}
 		}
 	}
 
@@ -1177,6 +1777,9 @@ func cronAddCmd(storePath string) {
 	}
 
 	fmt.Printf("✓ Added job '%s' (%s)\n", job.Name, job.ID)
+	if understood != "" {
+		fmt.Printf("  Scheduled %s\n", understood)
+	}
 }
 
 func cronRemoveCmd(storePath, jobID string) {
@@ -1210,6 +1813,535 @@ func cronEnableCmd(storePath string, disable bool) {
 	}
 }
 
+func feedbackCmd() {
+	if len(os.Args) < 3 {
+		feedbackHelp()
+		return
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		return
+	}
+	store := feedback.NewStore(filepath.Join(cfg.WorkspacePath(), "feedback"), cfg.Feedback.MaxTurns)
+
+	switch os.Args[2] {
+	case "stats":
+		feedbackStatsCmd(store)
+	case "export":
+		feedbackExportCmd(store)
+	case "scenarios":
+		feedbackScenariosCmd(store, filepath.Join(cfg.WorkspacePath(), "evals"))
+	default:
+		fmt.Printf("Unknown feedback command: %s\n", os.Args[2])
+		feedbackHelp()
+	}
+}
+
+func feedbackHelp() {
+	fmt.Println("\nFeedback commands:")
+	fmt.Println("  stats             Count recorded turns and ratings")
+	fmt.Println("  export            Write rated turns as JSON lines (prompt, context, response, rating)")
+	fmt.Println("  scenarios         Turn badly rated answers into eval scenario files")
+	fmt.Println()
+	fmt.Println("Export options:")
+	fmt.Println("  --rating <r>     good, bad or all (default: all)")
+	fmt.Println("  --unrated        Include turns nobody rated")
+	fmt.Println("  -o, --output     Write to a file instead of stdout")
+	fmt.Println()
+	fmt.Println("Scenarios options:")
+	fmt.Println("  --dir <path>     Where to write scenarios (default: workspace/evals)")
+}
+
+func feedbackStatsCmd(store *feedback.Store) {
+	examples, err := store.Dataset(-1, 1, false)
+	if err != nil {
+		fmt.Printf("Error reading ratings: %v\n", err)
+		return
+	}
+	var good, bad, comments int
+	for _, e := range examples {
+		switch {
+		case e.Rating > 0:
+			good++
+		case e.Rating < 0:
+			bad++
+		default:
+			comments++
+		}
+	}
+	fmt.Printf("Turns on record: %d\n", len(store.Turns()))
+	fmt.Printf("Rated: %d (👍 %d, 👎 %d, comments only %d)\n", len(examples), good, bad, comments)
+}
+
+func feedbackExportCmd(store *feedback.Store) {
+	minScore, maxScore := -1, 1
+	unrated := false
+	output := ""
+
+	args := os.Args[3:]
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--rating":
+			if i+1 < len(args) {
+				switch args[i+1] {
+				case "good":
+					minScore = 1
+				case "bad":
+					maxScore = -1
+				case "all":
+				default:
+					fmt.Printf("Error: unknown rating %q (use good, bad or all)\n", args[i+1])
+					return
+				}
+				i++
+			}
+		case "--unrated":
+			unrated = true
+		case "-o", "--output":
+			if i+1 < len(args) {
+				output = args[i+1]
+				i++
+			}
+		}
+	}
+
+	examples, err := store.Dataset(minScore, maxScore, unrated)
+	if err != nil {
+		fmt.Printf("Error reading ratings: %v\n", err)
+		return
+	}
+
+	out := os.Stdout
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		defer f.Close()
+		out = f
+	}
+	if err := feedback.WriteDataset(out, examples); err != nil {
+		fmt.Printf("Error writing dataset: %v\n", err)
+		return
+	}
+	if output != "" {
+		fmt.Printf("✓ Exported %d examples to %s\n", len(examples), output)
+	}
+}
+
+func feedbackScenariosCmd(store *feedback.Store, dir string) {
+	args := os.Args[3:]
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--dir" && i+1 < len(args) {
+			dir = args[i+1]
+			i++
+		}
+	}
+
+	examples, err := store.Dataset(-1, -1, false)
+	if err != nil {
+		fmt.Printf("Error reading ratings: %v\n", err)
+		return
+	}
+	paths, err := feedback.WriteScenarios(dir, examples)
+	if err != nil {
+		fmt.Printf("Error writing scenarios: %v\n", err)
+		return
+	}
+	if len(paths) == 0 {
+		fmt.Println("No badly rated answers to turn into scenarios.")
+		return
+	}
+	fmt.Printf("✓ Wrote %d scenarios to %s\n", len(paths), dir)
+}
+
+func tenantsCmd() {
+	if len(os.Args) < 3 {
+		tenantsHelp()
+		return
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		return
+	}
+	workspace := cfg.WorkspacePath()
+	manager := tenant.NewManager(workspace, cfg.Tenants)
+	cronService := cron.NewCronService(filepath.Join(workspace, "cron", "jobs.json"), nil)
+	cronJobs := func(id string) []cron.CronJob {
+		var jobs []cron.CronJob
+		for _, job := range cronService.ListJobs(true) {
+			if job.Payload.Tenant == id {
+				jobs = append(jobs, job)
+			}
+		}
+		return jobs
+	}
+
+	switch os.Args[2] {
+	case "list":
+		infos, err := manager.List()
+		if err != nil {
+			fmt.Printf("Error listing tenants: %v\n", err)
+			return
+		}
+		if len(infos) == 0 {
+			fmt.Println("No tenants.")
+			return
+		}
+		fmt.Printf("%-32s %-28s %12s %s\n", "ID", "Owner", "Tokens today", "Last seen")
+		for _, info := range infos {
+			fmt.Printf("%-32s %-28s %12d %s\n", info.ID, info.Channel+":"+info.Owner, info.TokensToday, info.LastSeen.Format("2006-01-02 15:04"))
+		}
+	case "show":
+		if len(os.Args) < 4 {
+			tenantsHelp()
+			return
+		}
+		tn, err := manager.Get(os.Args[3])
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		info := tn.Info()
+		fmt.Printf("ID:         %s\n", info.ID)
+		fmt.Printf("Owner:      %s:%s\n", info.Channel, info.Owner)
+		fmt.Printf("Workspace:  %s\n", tn.Workspace())
+		fmt.Printf("Disk:       %s of %d MB\n", media.FormatSize(tn.DiskUsage()), cfg.Tenants.MaxDiskMB)
+		fmt.Printf("Cron jobs:  %d of %d\n", len(cronJobs(info.ID)), cfg.Tenants.MaxCronJobs)
+		fmt.Printf("Tokens:     %d today, %d in total\n", info.TokensToday, info.TokensTotal)
+		fmt.Printf("Created:    %s\n", info.Created.Format("2006-01-02 15:04"))
+		fmt.Printf("Last seen:  %s\n", info.LastSeen.Format("2006-01-02 15:04"))
+	case "purge":
+		if len(os.Args) < 4 {
+			tenantsHelp()
+			return
+		}
+		manager.OnPurge(func(id string) {
+			for _, job := range cronJobs(id) {
+				cronService.RemoveJob(job.ID)
+			}
+			if err := tools.DeleteTenantKV(state.NewManager(workspace), id); err != nil {
+				fmt.Printf("Error deleting kv namespaces: %v\n", err)
+			}
+		})
+		if err := manager.Purge(os.Args[3]); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		fmt.Printf("✓ Tenant %s purged\n", os.Args[3])
+	default:
+		fmt.Printf("Unknown tenants command: %s\n", os.Args[2])
+		tenantsHelp()
+	}
+}
+
+// updateGrace is how long a freshly installed binary must run the gateway
+// before it counts as a good update.
+const updateGrace = 30 * time.Second
+
+// bootUpdate counts this start if the binary was just updated, restoring
+// the previous one when it keeps failing. It returns the binary's path.
+func bootUpdate() string {
+	exe, err := update.Executable()
+	if err != nil {
+		return ""
+	}
+	if err := update.Boot(exe); errors.Is(err, update.ErrRolledBack) {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	} else if err != nil {
+		fmt.Printf("Warning: update state: %v\n", err)
+	}
+	return exe
+}
+
+// waitOutbound waits up to timeout for the channels to take the queued
+// outbound messages.
+func waitOutbound(msgBus *bus.MessageBus, timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if _, outbound := msgBus.QueueDepth(); outbound == 0 {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// confirmUpdate marks a freshly installed binary as good once the gateway
+// has run for updateGrace.
+func confirmUpdate(exe string) {
+	if exe == "" || update.Pending(exe) == "" {
+		return
+	}
+	time.AfterFunc(updateGrace, func() {
+		if err := update.Confirm(exe); err != nil {
+			logger.WarnCF("update", "Failed to confirm the update", map[string]interface{}{"error": err.Error()})
+			return
+		}
+		logger.InfoCF("update", "Update confirmed", map[string]interface{}{"version": version})
+	})
+}
+
+// startUpdateChecker tells the owner about new releases every
+// update.check_interval hours.
+func startUpdateChecker(ctx context.Context, cfg *config.Config, msgBus *bus.MessageBus) {
+	if cfg.Update.CheckInterval <= 0 || cfg.Update.ManifestURL == "" {
+		return
+	}
+	workspace := cfg.WorkspacePath()
+	checker := update.NewChecker(cfg.Update, version, filepath.Join(workspace, "state", "update.json"), func(rel *update.Release) {
+		target := cfg.Update.Notify
+		if target == "" {
+			target = state.NewManager(workspace).GetLastChannel()
+		}
+		channel, chatID, ok := strings.Cut(target, ":")
+		if !ok || channel == "" || chatID == "" || constants.IsInternalChannel(channel) {
+			logger.InfoCF("update", "New release, but no chat to tell", map[string]interface{}{"version": rel.Version})
+			return
+		}
+		notes := ""
+		if rel.Notes != "" {
+			notes = "\n\n" + rel.Notes
+		}
+		msgBus.PublishOutbound(bus.OutboundMessage{
+			Channel: channel,
+			ChatID:  chatID,
+			Content: i18n.T(i18n.ForChat(channel, chatID), i18n.UpdateAvailable, rel.Version, version, notes),
+		})
+	})
+	go checker.Run(ctx)
+	fmt.Printf("✓ Checking for updates every %dh (%s channel)\n", cfg.Update.CheckInterval, cfg.Update.Channel)
+}
+
+func updateCmd() {
+	args := os.Args[2:]
+	if len(args) > 0 {
+		switch args[0] {
+		case "keygen":
+			updateKeygenCmd(args[1:])
+			return
+		case "sign":
+			updateSignCmd(args[1:])
+			return
+		case "help", "--help", "-h":
+			updateHelp()
+			return
+		}
+	}
+
+	check, rollback, channel := false, false, ""
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--check":
+			check = true
+		case "--rollback":
+			rollback = true
+		case "--channel":
+			if i+1 < len(args) {
+				channel = args[i+1]
+				i++
+			}
+		default:
+			fmt.Printf("Unknown option: %s\n", args[i])
+			updateHelp()
+			return
+		}
+	}
+
+	exe, err := update.Executable()
+	if err != nil {
+		fmt.Printf("Error finding the picoclaw binary: %v\n", err)
+		os.Exit(1)
+	}
+	if rollback {
+		if err := update.Rollback(exe); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("✓ Previous binary restored; restart the gateway to run it")
+		return
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	if channel == "" {
+		channel = cfg.Update.Channel
+	}
+	if channel != "stable" && channel != "beta" {
+		fmt.Printf("Error: --channel must be stable or beta, not %q\n", channel)
+		os.Exit(1)
+	}
+	if cfg.Update.ManifestURL == "" {
+		fmt.Println("Error: update.manifest_url is not set")
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	manifest, err := update.FetchManifest(ctx, cfg.Update.ManifestURL)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	rel, err := manifest.Latest(channel)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Running:  %s\n", version)
+	fmt.Printf("Latest %s: %s\n", channel, rel.Version)
+	if update.Compare(rel.Version, version) <= 0 {
+		fmt.Println("✓ Up to date")
+		return
+	}
+	if rel.Notes != "" {
+		fmt.Printf("\n%s\n\n", rel.Notes)
+	}
+	asset, err := rel.Asset(runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if check {
+		fmt.Printf("Update available for %s/%s; run picoclaw update to install it\n", runtime.GOOS, runtime.GOARCH)
+		return
+	}
+
+	if cfg.Update.PublicKey == "" {
+		fmt.Println("Error: update.public_key is not set; refusing to install a binary that cannot be verified")
+		os.Exit(1)
+	}
+	key, err := update.ParsePublicKey(cfg.Update.PublicKey)
+	if err != nil {
+		fmt.Printf("Error: update.public_key: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Installing %s for %s/%s into %s...\n", rel.Version, runtime.GOOS, runtime.GOARCH, exe)
+	if err := update.NewInstaller(exe, key).Install(ctx, manifest, rel, asset, version); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✓ Updated to %s; restart the gateway to run it\n", rel.Version)
+	fmt.Println("  If it fails to start, the previous binary is restored automatically,")
+	fmt.Println("  or run: picoclaw update --rollback")
+}
+
+// updateKeygenCmd creates a signing key pair for releases.
+func updateKeygenCmd(args []string) {
+	out := "picoclaw-update.key"
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--out" && i+1 < len(args) {
+			out = args[i+1]
+			i++
+		}
+	}
+	if _, err := os.Stat(out); err == nil {
+		fmt.Printf("Error: %s already exists\n", out)
+		os.Exit(1)
+	}
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(out, []byte(base64.StdEncoding.EncodeToString(priv)+"\n"), 0600); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✓ Private key written to %s; keep it off the devices\n", out)
+	fmt.Printf("Public key for update.public_key:\n%s\n", base64.StdEncoding.EncodeToString(pub))
+}
+
+// updateSignCmd prints the manifest entry of a release binary.
+func updateSignCmd(args []string) {
+	var keyFile, ver, goos, goarch, assetURL, binary string
+	for i := 0; i < len(args); i++ {
+		if strings.HasPrefix(args[i], "--") && i+1 < len(args) {
+			switch args[i] {
+			case "--key":
+				keyFile = args[i+1]
+			case "--version":
+				ver = args[i+1]
+			case "--os":
+				goos = args[i+1]
+			case "--arch":
+				goarch = args[i+1]
+			case "--url":
+				assetURL = args[i+1]
+			default:
+				fmt.Printf("Unknown option: %s\n", args[i])
+				updateHelp()
+				return
+			}
+			i++
+			continue
+		}
+		binary = args[i]
+	}
+	if keyFile == "" || ver == "" || goos == "" || goarch == "" || binary == "" {
+		fmt.Println("Usage: picoclaw update sign --key <file> --version <v> --os <os> --arch <arch> [--url <url>] <binary>")
+		return
+	}
+
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	key, err := update.ParsePrivateKey(string(data))
+	if err != nil {
+		fmt.Printf("Error: %s: %v\n", keyFile, err)
+		os.Exit(1)
+	}
+	f, err := os.Open(binary)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+	asset, err := update.Sign(key, ver, goos, goarch, f)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	asset.URL = assetURL
+	if asset.URL == "" {
+		asset.URL = filepath.Base(binary)
+	}
+	out, _ := json.MarshalIndent(asset, "", "  ")
+	fmt.Println(string(out))
+}
+
+func updateHelp() {
+	fmt.Println("\nUpdate commands:")
+	fmt.Println("  update [--channel stable|beta]   Install the latest release of the channel")
+	fmt.Println("  update --check                   Only tell whether a newer release exists")
+	fmt.Println("  update --rollback                Restore the binary the last update replaced")
+	fmt.Println("  update keygen [--out <file>]     Create a release signing key pair")
+	fmt.Println("  update sign --key <file> --version <v> --os <os> --arch <arch> [--url <url>] <binary>")
+	fmt.Println("                                   Print the signed manifest entry of a binary")
+	fmt.Println()
+	fmt.Println("Releases are read from update.manifest_url and must be signed with the key")
+	fmt.Println("in update.public_key.")
+}
+
+func tenantsHelp() {
+	fmt.Println("\nTenants commands:")
+	fmt.Println("  list              List tenants, most recently seen first")
+	fmt.Println("  show <id>         Show a tenant's owner, workspace and quota use")
+	fmt.Println("  purge <id>        Delete a tenant with its workspace, cron jobs and kv namespaces")
+	fmt.Println()
+	fmt.Println("While the gateway runs, purge from chat with /tenants purge <id> (tenant")
+	fmt.Println("admins only), so the gateway drops what it keeps for the tenant too.")
+}
+
 func skillsHelp() {
 	fmt.Println("\nSkills commands:")
 	fmt.Println("  list                    List installed skills")
@@ -0,0 +1,116 @@
+package admin
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+const activityCapacity = 500
+
+// ActivityEntry is a log entry as shown in the activity view. Entries from
+// the agent component are the turns: incoming messages, tool calls and
+// responses.
+type ActivityEntry struct {
+	Seq       int64             `json:"seq"`
+	Timestamp string            `json:"timestamp"`
+	Level     string            `json:"level"`
+	Component string            `json:"component,omitempty"`
+	Message   string            `json:"message"`
+	Fields    map[string]string `json:"fields,omitempty"`
+}
+
+// activityLog keeps the most recent log entries in a ring buffer.
+type activityLog struct {
+	mu      sync.Mutex
+	entries []ActivityEntry
+	next    int
+	seq     int64
+	remove  func()
+}
+
+func newActivityLog(capacity int) *activityLog {
+	return &activityLog{entries: make([]ActivityEntry, 0, capacity)}
+}
+
+func (a *activityLog) start() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.remove == nil {
+		a.remove = logger.AddListener(a.add)
+	}
+}
+
+func (a *activityLog) stop() {
+	a.mu.Lock()
+	remove := a.remove
+	a.remove = nil
+	a.mu.Unlock()
+
+	if remove != nil {
+		remove()
+	}
+}
+
+func (a *activityLog) add(e logger.LogEntry) {
+	// The logger reuses the caller's fields map, so copy it as strings.
+	var fields map[string]string
+	if len(e.Fields) > 0 {
+		fields = make(map[string]string, len(e.Fields))
+		for k, v := range e.Fields {
+			fields[k] = fmt.Sprint(v)
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.seq++
+	entry := ActivityEntry{
+		Seq:       a.seq,
+		Timestamp: e.Timestamp,
+		Level:     e.Level,
+		Component: e.Component,
+		Message:   e.Message,
+		Fields:    fields,
+	}
+	if len(a.entries) < cap(a.entries) {
+		a.entries = append(a.entries, entry)
+		return
+	}
+	a.entries[a.next] = entry
+	a.next = (a.next + 1) % len(a.entries)
+}
+
+// since returns entries newer than seq, oldest first.
+func (a *activityLog) since(seq int64) []ActivityEntry {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	out := make([]ActivityEntry, 0)
+	for i := 0; i < len(a.entries); i++ {
+		e := a.entries[(a.next+i)%len(a.entries)]
+		if e.Seq > seq {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func (d *Dashboard) handleActivity(w http.ResponseWriter, r *http.Request) {
+	var since int64
+	if v := r.URL.Query().Get("since"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid since")
+			return
+		}
+		since = n
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"entries": d.activity.since(since),
+	})
+}
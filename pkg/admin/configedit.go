@@ -0,0 +1,175 @@
+package admin
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// secretMask replaces secret values in the config sent to the browser.
+// Saving a config that still contains the mask keeps the stored secret.
+const secretMask = "********"
+
+// maskSecrets replaces non-empty secret strings in a decoded JSON tree.
+func maskSecrets(v interface{}) {
+	switch node := v.(type) {
+	case map[string]interface{}:
+		for k, child := range node {
+			if s, ok := child.(string); ok && s != "" && config.IsSecretKey(k) {
+				node[k] = secretMask
+				continue
+			}
+			maskSecrets(child)
+		}
+	case []interface{}:
+		for _, child := range node {
+			maskSecrets(child)
+		}
+	}
+}
+
+// restoreSecrets puts back secrets that the client returned still masked,
+// taking them from the same position in the current config.
+func restoreSecrets(edited, current interface{}) {
+	editedMap, ok := edited.(map[string]interface{})
+	if !ok {
+		return
+	}
+	currentMap, _ := current.(map[string]interface{})
+
+	for k, child := range editedMap {
+		if s, ok := child.(string); ok && s == secretMask && config.IsSecretKey(k) {
+			if orig, ok := currentMap[k].(string); ok {
+				editedMap[k] = orig
+			} else {
+				editedMap[k] = ""
+			}
+			continue
+		}
+		restoreSecrets(child, currentMap[k])
+	}
+}
+
+// currentConfigTree returns the config file as a JSON tree, or the defaults
+// if it does not exist yet. Environment overrides are deliberately not
+// applied, so that saving does not copy them into the file.
+func (d *Dashboard) currentConfigTree() (map[string]interface{}, string, error) {
+	data, hash, err := d.config.read()
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) == 0 {
+		if data, err = json.Marshal(config.DefaultConfig()); err != nil {
+			return nil, "", err
+		}
+	}
+
+	var tree map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&tree); err != nil {
+		return nil, "", err
+	}
+	return tree, hash, nil
+}
+
+func (d *Dashboard) handleConfigGet(w http.ResponseWriter, r *http.Request) {
+	if d.config == nil {
+		writeError(w, http.StatusNotFound, "config editing is not available")
+		return
+	}
+
+	tree, hash, err := d.currentConfigTree()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to read config: "+err.Error())
+		return
+	}
+	maskSecrets(tree)
+
+	versions, err := d.config.versions()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"config":   tree,
+		"hash":     hash,
+		"versions": versions,
+	})
+}
+
+func (d *Dashboard) handleConfigPut(w http.ResponseWriter, r *http.Request) {
+	if d.config == nil {
+		writeError(w, http.StatusNotFound, "config editing is not available")
+		return
+	}
+
+	var req struct {
+		Config   json.RawMessage `json:"config"`
+		BaseHash string          `json:"base_hash"`
+	}
+	if err := decodeJSON(r, &req); err != nil || len(req.Config) == 0 {
+		writeError(w, http.StatusBadRequest, "config is required")
+		return
+	}
+
+	var edited interface{}
+	dec := json.NewDecoder(bytes.NewReader(req.Config))
+	dec.UseNumber()
+	if err := dec.Decode(&edited); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+
+	current, _, err := d.currentConfigTree()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to read config: "+err.Error())
+		return
+	}
+	restoreSecrets(edited, current)
+
+	merged, err := json.Marshal(edited)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	cfg := config.DefaultConfig()
+	strict := json.NewDecoder(bytes.NewReader(merged))
+	strict.DisallowUnknownFields()
+	if err := strict.Decode(cfg); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "config does not match the schema", err.Error())
+		return
+	}
+	if err := cfg.Validate(); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "config is invalid", strings.Split(err.Error(), "\n")...)
+		return
+	}
+
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	hash, err := d.config.write(data, req.BaseHash)
+	if errors.Is(err, errConflict) {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	logger.InfoC("admin", "Config saved from dashboard")
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"hash":             hash,
+		"restart_required": true,
+	})
+}
@@ -0,0 +1,252 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// Inspired by and based on nanobot: https://github.com/HKUDS/nanobot
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+// Package admin serves the web admin dashboard on the gateway HTTP server.
+// The UI is built from embedded static assets so it works without internet
+// access, and every request is authenticated with the dashboard's own
+// credentials.
+package admin
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/cron"
+	"github.com/sipeed/picoclaw/pkg/heartbeat"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/session"
+	"github.com/sipeed/picoclaw/pkg/skills"
+)
+
+//go:embed static
+var staticFiles embed.FS
+
+const maxRequestBody = 1 << 20
+
+// ChannelStatusProvider reports the state of the configured channels.
+// channels.Manager implements it.
+type ChannelStatusProvider interface {
+	GetStatus() map[string]interface{}
+}
+
+// Deps are the running services the dashboard inspects and edits.
+// A nil service hides the matching section.
+type Deps struct {
+	Version    string
+	ConfigPath string
+	Workspace  string
+	Channels   ChannelStatusProvider
+	Sessions   *session.SessionManager
+	Cron       *cron.CronService
+	Heartbeat  *heartbeat.HeartbeatService
+	Skills     *skills.SkillsLoader
+	// Monitor serves the `picoclaw top` API under api/top when set.
+	Monitor http.Handler
+}
+
+// Dashboard is the admin UI and its JSON API.
+type Dashboard struct {
+	cfg       config.AdminConfig
+	prefix    string
+	deps      Deps
+	activity  *activityLog
+	files     map[string]*versionedFile
+	config    *versionedFile
+	handler   http.Handler
+	startedAt time.Time
+}
+
+// New creates the dashboard. It refuses to run without credentials.
+func New(cfg config.AdminConfig, deps Deps) (*Dashboard, error) {
+	if cfg.Username == "" || cfg.Password == "" {
+		return nil, fmt.Errorf("admin dashboard requires a username and password")
+	}
+
+	prefix := "/" + strings.Trim(cfg.Path, "/")
+	if prefix == "/" {
+		return nil, fmt.Errorf("admin dashboard path must not be the root")
+	}
+
+	versionsDir := filepath.Join(deps.Workspace, "state", "versions")
+	d := &Dashboard{
+		cfg:      cfg,
+		prefix:   prefix,
+		deps:     deps,
+		activity: newActivityLog(activityCapacity),
+		files: map[string]*versionedFile{
+			"heartbeat": newVersionedFile(filepath.Join(deps.Workspace, "HEARTBEAT.md"), filepath.Join(versionsDir, "heartbeat")),
+			"memory":    newVersionedFile(filepath.Join(deps.Workspace, "memory", "MEMORY.md"), filepath.Join(versionsDir, "memory")),
+		},
+		startedAt: time.Now(),
+	}
+	if deps.ConfigPath != "" {
+		// Config versions hold secrets, so they stay next to the config file
+		// rather than in the workspace the agent can read.
+		d.config = newVersionedFile(deps.ConfigPath, filepath.Join(filepath.Dir(deps.ConfigPath), "config.versions"))
+	}
+
+	d.handler = d.withAuth(d.routes())
+	return d, nil
+}
+
+// Pattern is the path pattern to register the dashboard under.
+func (d *Dashboard) Pattern() string {
+	return d.prefix + "/"
+}
+
+// Start begins recording log activity for the activity view.
+func (d *Dashboard) Start() {
+	d.activity.start()
+}
+
+// Stop stops recording log activity.
+func (d *Dashboard) Stop() {
+	d.activity.stop()
+}
+
+func (d *Dashboard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.handler.ServeHTTP(w, r)
+}
+
+func (d *Dashboard) routes() http.Handler {
+	mux := http.NewServeMux()
+	p := d.prefix
+
+	static, _ := fs.Sub(staticFiles, "static")
+	mux.Handle("GET "+p+"/", http.StripPrefix(p, http.FileServerFS(static)))
+
+	mux.HandleFunc("GET "+p+"/api/status", d.handleStatus)
+	mux.HandleFunc("GET "+p+"/api/activity", d.handleActivity)
+
+	mux.HandleFunc("GET "+p+"/api/sessions", d.handleSessions)
+	mux.HandleFunc("GET "+p+"/api/sessions/transcript", d.handleTranscript)
+	mux.HandleFunc("POST "+p+"/api/sessions/reset", d.handleSessionReset)
+
+	mux.HandleFunc("GET "+p+"/api/cron", d.handleCronList)
+	mux.HandleFunc("POST "+p+"/api/cron/{id}/enabled", d.handleCronEnable)
+
+	mux.HandleFunc("GET "+p+"/api/heartbeat", d.handleHeartbeat)
+	mux.HandleFunc("GET "+p+"/api/skills", d.handleSkills)
+
+	mux.HandleFunc("GET "+p+"/api/files/{name}", d.handleFileGet)
+	mux.HandleFunc("PUT "+p+"/api/files/{name}", d.handleFilePut)
+	mux.HandleFunc("GET "+p+"/api/files/{name}/versions/{id}", d.handleFileVersion)
+
+	mux.HandleFunc("GET "+p+"/api/config", d.handleConfigGet)
+	mux.HandleFunc("PUT "+p+"/api/config", d.handleConfigPut)
+
+	if d.deps.Monitor != nil {
+		mux.Handle(p+"/api/top/", http.StripPrefix(p+"/api/top", d.deps.Monitor))
+	}
+
+	return mux
+}
+
+// withAuth applies HTTP basic auth and browser hardening headers to every
+// request. Requests that change state must also be JSON, which a
+// cross-site form cannot send without a CORS preflight.
+func (d *Dashboard) withAuth(next http.Handler) http.Handler {
+	wantUser := sha256.Sum256([]byte(d.cfg.Username))
+	wantPass := sha256.Sum256([]byte(d.cfg.Password))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Set("Content-Security-Policy", "default-src 'self'; frame-ancestors 'none'")
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("X-Frame-Options", "DENY")
+		h.Set("Cache-Control", "no-store")
+
+		user, pass, ok := r.BasicAuth()
+		gotUser := sha256.Sum256([]byte(user))
+		gotPass := sha256.Sum256([]byte(pass))
+		userOK := subtle.ConstantTimeCompare(gotUser[:], wantUser[:]) == 1
+		passOK := subtle.ConstantTimeCompare(gotPass[:], wantPass[:]) == 1
+		if !ok || !userOK || !passOK {
+			if ok {
+				logger.WarnCF("admin", "Rejected dashboard login", map[string]interface{}{
+					"remote": r.RemoteAddr,
+				})
+			}
+			h.Set("WWW-Authenticate", `Basic realm="picoclaw admin", charset="UTF-8"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if mediaType != "application/json" {
+				writeError(w, http.StatusUnsupportedMediaType, "request body must be application/json")
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, maxRequestBody)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (d *Dashboard) handleStatus(w http.ResponseWriter, r *http.Request) {
+	status := map[string]interface{}{
+		"version":    d.deps.Version,
+		"started_at": d.startedAt,
+		"uptime_sec": int64(time.Since(d.startedAt).Seconds()),
+		"workspace":  d.deps.Workspace,
+	}
+	if d.deps.Channels != nil {
+		status["channels"] = d.deps.Channels.GetStatus()
+	}
+	if d.deps.Cron != nil {
+		status["cron"] = d.deps.Cron.Status()
+	}
+	if d.deps.Heartbeat != nil {
+		status["heartbeat"] = d.deps.Heartbeat.Status()
+	}
+	if d.deps.Sessions != nil {
+		status["sessions"] = len(d.deps.Sessions.List())
+	}
+	if d.deps.Skills != nil {
+		status["skills"] = len(d.deps.Skills.ListSkills())
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (d *Dashboard) handleSkills(w http.ResponseWriter, r *http.Request) {
+	list := []skills.SkillInfo{}
+	if d.deps.Skills != nil {
+		list = append(list, d.deps.Skills.ListSkills()...)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"skills": list})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string, details ...string) {
+	body := map[string]interface{}{"error": message}
+	if len(details) > 0 {
+		body["details"] = details
+	}
+	writeJSON(w, status, body)
+}
+
+func decodeJSON(r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
@@ -0,0 +1,484 @@
+package admin
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/cron"
+	"github.com/sipeed/picoclaw/pkg/heartbeat"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/session"
+	"github.com/sipeed/picoclaw/pkg/skills"
+)
+
+type fakeChannels map[string]interface{}
+
+func (f fakeChannels) GetStatus() map[string]interface{} { return f }
+
+type testDashboard struct {
+	t          *testing.T
+	srv        *httptest.Server
+	dash       *Dashboard
+	workspace  string
+	configPath string
+	sessions   *session.SessionManager
+	cron       *cron.CronService
+}
+
+func newTestDashboard(t *testing.T) *testDashboard {
+	t.Helper()
+	workspace := t.TempDir()
+	configDir := t.TempDir()
+	configPath := filepath.Join(configDir, "config.json")
+
+	td := &testDashboard{
+		t:          t,
+		workspace:  workspace,
+		configPath: configPath,
+		sessions:   session.NewSessionManager(filepath.Join(workspace, "sessions")),
+		cron:       cron.NewCronService(filepath.Join(workspace, "cron", "jobs.json"), nil),
+	}
+
+	dash, err := New(config.AdminConfig{Enabled: true, Path: "/admin", Username: "admin", Password: "s3cret"}, Deps{
+		Version:    "test",
+		ConfigPath: configPath,
+		Workspace:  workspace,
+		Channels:   fakeChannels{"telegram": map[string]interface{}{"enabled": true, "running": true}},
+		Sessions:   td.sessions,
+		Cron:       td.cron,
+		Heartbeat:  heartbeat.NewHeartbeatService(workspace, 30, true),
+		Skills:     skills.NewSkillsLoader(workspace, "", ""),
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	dash.Start()
+	t.Cleanup(dash.Stop)
+	td.dash = dash
+
+	mux := http.NewServeMux()
+	mux.Handle(dash.Pattern(), dash)
+	td.srv = httptest.NewServer(mux)
+	t.Cleanup(td.srv.Close)
+	return td
+}
+
+// do sends an authenticated request and decodes a JSON response into out.
+func (td *testDashboard) do(method, path string, body interface{}, out interface{}) int {
+	td.t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	}
+	req, _ := http.NewRequest(method, td.srv.URL+"/admin"+path, reader)
+	req.SetBasicAuth("admin", "s3cret")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		td.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			td.t.Fatalf("%s %s: decode response: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestNewRequiresCredentials(t *testing.T) {
+	if _, err := New(config.AdminConfig{Path: "/admin", Username: "admin"}, Deps{}); err == nil {
+		t.Error("New() without a password should fail")
+	}
+	if _, err := New(config.AdminConfig{Path: "/", Username: "admin", Password: "x"}, Deps{}); err == nil {
+		t.Error("New() at the root path should fail")
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	td := newTestDashboard(t)
+
+	tests := []struct {
+		name       string
+		user, pass string
+		wantStatus int
+	}{
+		{"no credentials", "", "", http.StatusUnauthorized},
+		{"wrong password", "admin", "nope", http.StatusUnauthorized},
+		{"wrong user", "root", "s3cret", http.StatusUnauthorized},
+		{"valid", "admin", "s3cret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, td.srv.URL+"/admin/api/status", nil)
+			if tt.user != "" {
+				req.SetBasicAuth(tt.user, tt.pass)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusUnauthorized && resp.Header.Get("WWW-Authenticate") == "" {
+				t.Error("missing WWW-Authenticate header")
+			}
+		})
+	}
+}
+
+func TestMutationsRequireJSON(t *testing.T) {
+	td := newTestDashboard(t)
+
+	req, _ := http.NewRequest(http.MethodPost, td.srv.URL+"/admin/api/sessions/reset", strings.NewReader("key=x"))
+	req.SetBasicAuth("admin", "s3cret")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("form POST status = %d, want %d", resp.StatusCode, http.StatusUnsupportedMediaType)
+	}
+}
+
+func TestStaticAssetsAreEmbeddedAndSelfContained(t *testing.T) {
+	td := newTestDashboard(t)
+
+	for _, path := range []string{"/", "/app.js", "/style.css"} {
+		req, _ := http.NewRequest(http.MethodGet, td.srv.URL+"/admin"+path, nil)
+		req.SetBasicAuth("admin", "s3cret")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("GET %s status = %d, want 200", path, resp.StatusCode)
+		}
+	}
+
+	// The dashboard must work offline: no CDN or other remote references.
+	fs.WalkDir(staticFiles, "static", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, _ := staticFiles.ReadFile(path)
+		for _, remote := range []string{"http://", "https://", "//cdn", "@import"} {
+			if bytes.Contains(data, []byte(remote)) {
+				t.Errorf("%s references %q", path, remote)
+			}
+		}
+		return nil
+	})
+}
+
+func TestStatus(t *testing.T) {
+	td := newTestDashboard(t)
+
+	var status map[string]interface{}
+	if code := td.do(http.MethodGet, "/api/status", nil, &status); code != http.StatusOK {
+		t.Fatalf("status code = %d", code)
+	}
+	for _, key := range []string{"version", "uptime_sec", "channels", "cron", "heartbeat", "sessions", "skills"} {
+		if _, ok := status[key]; !ok {
+			t.Errorf("status is missing %q: %v", key, status)
+		}
+	}
+	channels := status["channels"].(map[string]interface{})
+	if _, ok := channels["telegram"]; !ok {
+		t.Errorf("channels = %v, want telegram", channels)
+	}
+}
+
+func TestActivity(t *testing.T) {
+	td := newTestDashboard(t)
+
+	logger.InfoCF("agent", "Processing message from test", map[string]interface{}{"chat_id": "42"})
+
+	var resp struct {
+		Entries []ActivityEntry `json:"entries"`
+	}
+	td.do(http.MethodGet, "/api/activity", nil, &resp)
+
+	var found *ActivityEntry
+	for i := range resp.Entries {
+		if resp.Entries[i].Message == "Processing message from test" {
+			found = &resp.Entries[i]
+		}
+	}
+	if found == nil {
+		t.Fatalf("activity = %+v, want the logged turn", resp.Entries)
+	}
+	if found.Component != "agent" || found.Fields["chat_id"] != "42" {
+		t.Errorf("entry = %+v", found)
+	}
+
+	var newer struct {
+		Entries []ActivityEntry `json:"entries"`
+	}
+	td.do(http.MethodGet, "/api/activity?since=1000000", nil, &newer)
+	if len(newer.Entries) != 0 {
+		t.Errorf("since filter returned %d entries, want 0", len(newer.Entries))
+	}
+}
+
+func TestActivityLogRingBuffer(t *testing.T) {
+	a := newActivityLog(3)
+	for i := 0; i < 5; i++ {
+		a.add(logger.LogEntry{Message: string(rune('a' + i))})
+	}
+
+	got := a.since(0)
+	if len(got) != 3 {
+		t.Fatalf("since(0) returned %d entries, want 3", len(got))
+	}
+	for i, want := range []string{"c", "d", "e"} {
+		if got[i].Message != want {
+			t.Errorf("entry %d = %q, want %q", i, got[i].Message, want)
+		}
+	}
+	if got := a.since(4); len(got) != 1 || got[0].Message != "e" {
+		t.Errorf("since(4) = %+v, want only e", got)
+	}
+}
+
+func TestSessionTranscriptAndReset(t *testing.T) {
+	td := newTestDashboard(t)
+	td.sessions.AddMessage("telegram:42", "user", "hello")
+	td.sessions.AddMessage("telegram:42", "assistant", "hi there")
+
+	var list struct {
+		Sessions []session.SessionInfo `json:"sessions"`
+	}
+	td.do(http.MethodGet, "/api/sessions", nil, &list)
+	if len(list.Sessions) != 1 || list.Sessions[0].Key != "telegram:42" || list.Sessions[0].MessageCount != 2 {
+		t.Fatalf("sessions = %+v", list.Sessions)
+	}
+
+	var transcript struct {
+		Messages []struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"messages"`
+	}
+	td.do(http.MethodGet, "/api/sessions/transcript?key=telegram%3A42", nil, &transcript)
+	if len(transcript.Messages) != 2 || transcript.Messages[1].Content != "hi there" {
+		t.Fatalf("transcript = %+v", transcript.Messages)
+	}
+
+	if code := td.do(http.MethodPost, "/api/sessions/reset", map[string]string{"key": "telegram:42"}, nil); code != http.StatusOK {
+		t.Fatalf("reset status = %d", code)
+	}
+	if n := len(td.sessions.GetHistory("telegram:42")); n != 0 {
+		t.Errorf("history after reset has %d messages", n)
+	}
+	if code := td.do(http.MethodPost, "/api/sessions/reset", map[string]string{"key": "missing"}, nil); code != http.StatusNotFound {
+		t.Errorf("reset of missing session status = %d, want 404", code)
+	}
+}
+
+func TestCronEnableDisable(t *testing.T) {
+	td := newTestDashboard(t)
+	every := int64(60000)
+	job, err := td.cron.AddJob("ping", cron.CronSchedule{Kind: "every", EveryMS: &every}, "ping", false, "", "")
+	if err != nil {
+		t.Fatalf("AddJob: %v", err)
+	}
+
+	var updated cron.CronJob
+	if code := td.do(http.MethodPost, "/api/cron/"+job.ID+"/enabled", map[string]bool{"enabled": false}, &updated); code != http.StatusOK {
+		t.Fatalf("disable status = %d", code)
+	}
+	if updated.Enabled {
+		t.Error("job still enabled in response")
+	}
+	if jobs := td.cron.ListJobs(false); len(jobs) != 0 {
+		t.Errorf("enabled jobs = %d, want 0", len(jobs))
+	}
+
+	td.do(http.MethodPost, "/api/cron/"+job.ID+"/enabled", map[string]bool{"enabled": true}, nil)
+	if jobs := td.cron.ListJobs(false); len(jobs) != 1 {
+		t.Errorf("enabled jobs = %d, want 1", len(jobs))
+	}
+
+	if code := td.do(http.MethodPost, "/api/cron/nope/enabled", map[string]bool{"enabled": true}, nil); code != http.StatusNotFound {
+		t.Errorf("unknown job status = %d, want 404", code)
+	}
+	if code := td.do(http.MethodPost, "/api/cron/"+job.ID+"/enabled", map[string]string{}, nil); code != http.StatusBadRequest {
+		t.Errorf("missing enabled status = %d, want 400", code)
+	}
+}
+
+func TestVersionedFileEdits(t *testing.T) {
+	td := newTestDashboard(t)
+	heartbeatPath := filepath.Join(td.workspace, "HEARTBEAT.md")
+	os.WriteFile(heartbeatPath, []byte("v1"), 0644)
+
+	type fileResp struct {
+		Content  string        `json:"content"`
+		Hash     string        `json:"hash"`
+		Versions []fileVersion `json:"versions"`
+	}
+	var f fileResp
+	td.do(http.MethodGet, "/api/files/heartbeat", nil, &f)
+	if f.Content != "v1" || len(f.Versions) != 0 {
+		t.Fatalf("initial file = %+v", f)
+	}
+	staleHash := f.Hash
+
+	var saved struct {
+		Hash string `json:"hash"`
+	}
+	if code := td.do(http.MethodPut, "/api/files/heartbeat", map[string]string{"content": "v2", "base_hash": f.Hash}, &saved); code != http.StatusOK {
+		t.Fatalf("save status = %d", code)
+	}
+	if data, _ := os.ReadFile(heartbeatPath); string(data) != "v2" {
+		t.Errorf("file content = %q, want v2", data)
+	}
+
+	// A second editor still holding the old hash must not clobber v2.
+	if code := td.do(http.MethodPut, "/api/files/heartbeat", map[string]string{"content": "other", "base_hash": staleHash}, nil); code != http.StatusConflict {
+		t.Errorf("stale save status = %d, want 409", code)
+	}
+
+	td.do(http.MethodGet, "/api/files/heartbeat", nil, &f)
+	if f.Content != "v2" || len(f.Versions) != 1 {
+		t.Fatalf("file after save = %+v", f)
+	}
+
+	var old struct {
+		Content string `json:"content"`
+	}
+	td.do(http.MethodGet, "/api/files/heartbeat/versions/"+f.Versions[0].ID, nil, &old)
+	if old.Content != "v1" {
+		t.Errorf("previous version = %q, want v1", old.Content)
+	}
+
+	// MEMORY.md does not exist yet; an empty base hash matches a missing file.
+	td.do(http.MethodGet, "/api/files/memory", nil, &f)
+	if code := td.do(http.MethodPut, "/api/files/memory", map[string]string{"content": "remember", "base_hash": f.Hash}, nil); code != http.StatusOK {
+		t.Fatalf("memory save status = %d", code)
+	}
+	if data, _ := os.ReadFile(filepath.Join(td.workspace, "memory", "MEMORY.md")); string(data) != "remember" {
+		t.Errorf("MEMORY.md = %q", data)
+	}
+
+	for _, path := range []string{"/api/files/config", "/api/files/heartbeat/versions/..%2Fsecret"} {
+		if code := td.do(http.MethodGet, path, nil, nil); code != http.StatusNotFound {
+			t.Errorf("GET %s status = %d, want 404", path, code)
+		}
+	}
+}
+
+func TestVersionRetention(t *testing.T) {
+	dir := t.TempDir()
+	f := newVersionedFile(filepath.Join(dir, "doc.md"), filepath.Join(dir, "versions"))
+
+	_, hash, _ := f.read()
+	for i := 0; i < maxVersions+5; i++ {
+		var err error
+		if hash, err = f.write([]byte(strings.Repeat("x", i+1)), hash); err != nil {
+			t.Fatalf("write %d: %v", i, err)
+		}
+	}
+
+	versions, err := f.versions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != maxVersions {
+		t.Errorf("kept %d versions, want %d", len(versions), maxVersions)
+	}
+}
+
+func TestConfigEditing(t *testing.T) {
+	td := newTestDashboard(t)
+
+	cfg := config.DefaultConfig()
+	cfg.Providers.OpenRouter.APIKey = "sk-real"
+	cfg.Channels.Telegram.Token = "123:abc"
+	if err := config.SaveConfig(td.configPath, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	var got struct {
+		Config map[string]interface{} `json:"config"`
+		Hash   string                 `json:"hash"`
+	}
+	td.do(http.MethodGet, "/api/config", nil, &got)
+	providers := got.Config["providers"].(map[string]interface{})
+	openrouter := providers["openrouter"].(map[string]interface{})
+	if openrouter["api_key"] != secretMask {
+		t.Errorf("api_key = %v, want it masked", openrouter["api_key"])
+	}
+	agents := got.Config["agents"].(map[string]interface{})["defaults"].(map[string]interface{})
+	if agents["max_tokens"] == secretMask {
+		t.Error("max_tokens must not be treated as a secret")
+	}
+
+	// An invalid edit is rejected and leaves the file untouched.
+	agents["temperature"] = 5
+	var rejected struct {
+		Error   string   `json:"error"`
+		Details []string `json:"details"`
+	}
+	if code := td.do(http.MethodPut, "/api/config", map[string]interface{}{"config": got.Config, "base_hash": got.Hash}, &rejected); code != http.StatusUnprocessableEntity {
+		t.Fatalf("invalid config status = %d, want 422", code)
+	}
+	if len(rejected.Details) == 0 || !strings.Contains(strings.Join(rejected.Details, " "), "temperature") {
+		t.Errorf("details = %v, want the temperature problem", rejected.Details)
+	}
+
+	// Unknown keys are typos, not silently dropped settings.
+	agents["temperature"] = 0.5
+	agents["temprature"] = 0.5
+	if code := td.do(http.MethodPut, "/api/config", map[string]interface{}{"config": got.Config, "base_hash": got.Hash}, nil); code != http.StatusUnprocessableEntity {
+		t.Fatalf("unknown field status = %d, want 422", code)
+	}
+	delete(agents, "temprature")
+
+	// A valid edit saves, keeps masked secrets and records the old version.
+	agents["model"] = "gpt-4o"
+	if code := td.do(http.MethodPut, "/api/config", map[string]interface{}{"config": got.Config, "base_hash": got.Hash}, nil); code != http.StatusOK {
+		t.Fatalf("valid config status = %d", code)
+	}
+	saved, err := config.LoadConfig(td.configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Agents.Defaults.Model != "gpt-4o" || saved.Agents.Defaults.Temperature != 0.5 {
+		t.Errorf("saved defaults = %+v", saved.Agents.Defaults)
+	}
+	if saved.Providers.OpenRouter.APIKey != "sk-real" || saved.Channels.Telegram.Token != "123:abc" {
+		t.Error("masked secrets were not preserved")
+	}
+
+	versions, _ := td.dash.config.versions()
+	if len(versions) != 1 {
+		t.Errorf("config versions = %d, want 1", len(versions))
+	}
+	if strings.HasPrefix(td.dash.config.dir, td.workspace) {
+		t.Error("config versions must not be stored in the workspace")
+	}
+
+	// Saving again with the old hash is a conflict.
+	if code := td.do(http.MethodPut, "/api/config", map[string]interface{}{"config": got.Config, "base_hash": got.Hash}, nil); code != http.StatusConflict {
+		t.Errorf("stale config save status = %d, want 409", code)
+	}
+}
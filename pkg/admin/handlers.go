@@ -0,0 +1,225 @@
+package admin
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/cron"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/session"
+)
+
+// heartbeatLogTail is how much of the end of heartbeat.log is shown.
+const heartbeatLogTail = 16 * 1024
+
+func (d *Dashboard) handleSessions(w http.ResponseWriter, r *http.Request) {
+	list := []session.SessionInfo{}
+	if d.deps.Sessions != nil {
+		list = d.deps.Sessions.List()
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"sessions": list})
+}
+
+func (d *Dashboard) handleTranscript(w http.ResponseWriter, r *http.Request) {
+	if d.deps.Sessions == nil {
+		writeError(w, http.StatusNotFound, "sessions are not available")
+		return
+	}
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		writeError(w, http.StatusBadRequest, "key is required")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"key":      key,
+		"summary":  d.deps.Sessions.GetSummary(key),
+		"messages": d.deps.Sessions.GetHistory(key),
+	})
+}
+
+func (d *Dashboard) handleSessionReset(w http.ResponseWriter, r *http.Request) {
+	if d.deps.Sessions == nil {
+		writeError(w, http.StatusNotFound, "sessions are not available")
+		return
+	}
+	var req struct {
+		Key string `json:"key"`
+	}
+	if err := decodeJSON(r, &req); err != nil || req.Key == "" {
+		writeError(w, http.StatusBadRequest, "key is required")
+		return
+	}
+
+	ok, err := d.deps.Sessions.Reset(req.Key)
+	if !ok {
+		writeError(w, http.StatusNotFound, "session not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	logger.InfoCF("admin", "Session reset from dashboard", map[string]interface{}{
+		"session_key": req.Key,
+	})
+	writeJSON(w, http.StatusOK, map[string]interface{}{"reset": req.Key})
+}
+
+func (d *Dashboard) handleCronList(w http.ResponseWriter, r *http.Request) {
+	jobs := []cron.CronJob{}
+	if d.deps.Cron != nil {
+		jobs = append(jobs, d.deps.Cron.ListJobs(true)...)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"jobs": jobs})
+}
+
+func (d *Dashboard) handleCronEnable(w http.ResponseWriter, r *http.Request) {
+	if d.deps.Cron == nil {
+		writeError(w, http.StatusNotFound, "cron is not available")
+		return
+	}
+	var req struct {
+		Enabled *bool `json:"enabled"`
+	}
+	if err := decodeJSON(r, &req); err != nil || req.Enabled == nil {
+		writeError(w, http.StatusBadRequest, "enabled is required")
+		return
+	}
+
+	id := r.PathValue("id")
+	job := d.deps.Cron.EnableJob(id, *req.Enabled)
+	if job == nil {
+		writeError(w, http.StatusNotFound, "job not found")
+		return
+	}
+
+	logger.InfoCF("admin", "Cron job toggled from dashboard", map[string]interface{}{
+		"job_id":  id,
+		"enabled": *req.Enabled,
+	})
+	writeJSON(w, http.StatusOK, job)
+}
+
+func (d *Dashboard) handleHeartbeat(w http.ResponseWriter, r *http.Request) {
+	resp := map[string]interface{}{}
+	if d.deps.Heartbeat != nil {
+		resp["status"] = d.deps.Heartbeat.Status()
+	}
+	resp["log"] = tailFile(filepath.Join(d.deps.Workspace, "heartbeat.log"), heartbeatLogTail)
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (d *Dashboard) handleFileGet(w http.ResponseWriter, r *http.Request) {
+	f, ok := d.files[r.PathValue("name")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown file")
+		return
+	}
+
+	content, hash, err := f.read()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	versions, err := f.versions()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"name":     r.PathValue("name"),
+		"content":  string(content),
+		"hash":     hash,
+		"versions": versions,
+	})
+}
+
+func (d *Dashboard) handleFilePut(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	f, ok := d.files[name]
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown file")
+		return
+	}
+
+	var req struct {
+		Content  string `json:"content"`
+		BaseHash string `json:"base_hash"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+
+	hash, err := f.write([]byte(req.Content), req.BaseHash)
+	if errors.Is(err, errConflict) {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	logger.InfoCF("admin", "Workspace file edited from dashboard", map[string]interface{}{
+		"file": name,
+	})
+	writeJSON(w, http.StatusOK, map[string]interface{}{"hash": hash})
+}
+
+func (d *Dashboard) handleFileVersion(w http.ResponseWriter, r *http.Request) {
+	f, ok := d.files[r.PathValue("name")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown file")
+		return
+	}
+
+	content, err := f.version(r.PathValue("id"))
+	if errors.Is(err, errVersionNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id":      r.PathValue("id"),
+		"content": string(content),
+	})
+}
+
+// tailFile returns up to the last n bytes of a file, starting at a line boundary.
+func tailFile(path string, n int64) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return ""
+	}
+	offset := info.Size() - n
+	if offset < 0 {
+		offset = 0
+	}
+	data, err := io.ReadAll(io.NewSectionReader(f, offset, info.Size()-offset))
+	if err != nil {
+		return ""
+	}
+
+	text := string(data)
+	if offset > 0 {
+		if i := strings.IndexByte(text, '\n'); i >= 0 {
+			text = text[i+1:]
+		}
+	}
+	return text
+}
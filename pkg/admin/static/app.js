@@ -0,0 +1,339 @@
+// picoclaw admin dashboard. Plain JavaScript with no external dependencies,
+// so the dashboard works offline. All text is inserted with textContent.
+"use strict";
+
+const views = {};
+let currentView = "overview";
+let refreshTimer = null;
+
+// --- helpers ---------------------------------------------------------------
+
+async function api(path, options) {
+  const opts = Object.assign({ headers: {} }, options || {});
+  if (opts.body !== undefined) {
+    opts.headers["Content-Type"] = "application/json";
+    opts.body = JSON.stringify(opts.body);
+  }
+  const resp = await fetch("api/" + path, opts);
+  const data = await resp.json().catch(() => ({}));
+  if (!resp.ok) {
+    const err = new Error(data.error || resp.statusText);
+    err.status = resp.status;
+    err.details = data.details || [];
+    throw err;
+  }
+  return data;
+}
+
+function el(tag, attrs, ...children) {
+  const node = document.createElement(tag);
+  for (const [k, v] of Object.entries(attrs || {})) {
+    if (k === "class") node.className = v;
+    else if (k.startsWith("on")) node.addEventListener(k.slice(2), v);
+    else node.setAttribute(k, v);
+  }
+  for (const child of children) {
+    if (child === null || child === undefined) continue;
+    node.append(child instanceof Node ? child : String(child));
+  }
+  return node;
+}
+
+function fill(id, ...rows) {
+  const node = document.getElementById(id);
+  node.replaceChildren(...rows);
+  return node;
+}
+
+function notify(message, isError) {
+  const node = document.getElementById("notice");
+  node.textContent = message;
+  node.className = isError ? "error" : "";
+  node.hidden = false;
+  clearTimeout(notify.timer);
+  notify.timer = setTimeout(() => { node.hidden = true; }, 5000);
+}
+
+function fmtTime(value) {
+  if (!value) return "—";
+  const d = typeof value === "number" ? new Date(value) : new Date(value);
+  return isNaN(d) ? String(value) : d.toLocaleString();
+}
+
+function fmtDuration(sec) {
+  const d = Math.floor(sec / 86400), h = Math.floor(sec % 86400 / 3600), m = Math.floor(sec % 3600 / 60);
+  return (d ? d + "d " : "") + (h ? h + "h " : "") + m + "m";
+}
+
+function card(label, value) {
+  return el("div", { class: "card" }, el("div", { class: "label" }, label), el("div", { class: "value" }, value));
+}
+
+function badge(text, kind) {
+  return el("span", { class: "badge " + (kind || "") }, text);
+}
+
+// --- overview --------------------------------------------------------------
+
+views.overview = async function () {
+  const s = await api("status");
+  const cards = [
+    card("Version", s.version || "dev"),
+    card("Uptime", fmtDuration(s.uptime_sec || 0)),
+    card("Sessions", s.sessions ?? "—"),
+    card("Skills", s.skills ?? "—"),
+  ];
+  if (s.cron) cards.push(card("Cron jobs", s.cron.jobs + (s.cron.enabled ? "" : " (stopped)")));
+  if (s.heartbeat) cards.push(card("Heartbeat", s.heartbeat.running ? "every " + s.heartbeat.interval_minutes + "m" : "off"));
+  fill("overview-cards", ...cards);
+
+  const channels = Object.entries(s.channels || {}).sort(([a], [b]) => a.localeCompare(b));
+  fill("channels-body", ...(channels.length ? channels.map(([name, st]) =>
+    el("tr", null, el("td", null, name), el("td", null, st.running ? badge("running", "ok") : badge("stopped", "err")))
+  ) : [el("tr", null, el("td", { colspan: "2" }, "No channels enabled"))]));
+};
+
+// --- activity --------------------------------------------------------------
+
+let activitySeq = 0;
+
+views.activity = async function () {
+  const data = await api("activity?since=" + activitySeq);
+  const log = document.getElementById("activity-log");
+  const turnsOnly = document.getElementById("activity-turns").checked;
+  for (const e of data.entries) {
+    activitySeq = Math.max(activitySeq, e.seq);
+    const fields = Object.entries(e.fields || {}).map(([k, v]) => k + "=" + v).join(" ");
+    const line = el("div", { class: e.level + " " + (e.component || "") },
+      e.timestamp + " " + e.level + " " + (e.component ? e.component + ": " : "") + e.message + (fields ? " {" + fields + "}" : ""));
+    line.hidden = turnsOnly && e.component !== "agent";
+    log.append(line);
+  }
+  while (log.childElementCount > 1000) log.firstElementChild.remove();
+  if (document.getElementById("activity-follow").checked) log.scrollTop = log.scrollHeight;
+};
+
+document.getElementById("activity-turns").addEventListener("change", (ev) => {
+  for (const line of document.getElementById("activity-log").children) {
+    line.hidden = ev.target.checked && !line.classList.contains("agent");
+  }
+});
+
+// --- sessions --------------------------------------------------------------
+
+let selectedSession = null;
+
+views.sessions = async function () {
+  const data = await api("sessions");
+  fill("sessions-body", ...data.sessions.map((s) => {
+    const row = el("tr", { class: "selectable" + (s.key === selectedSession ? " selected" : "") },
+      el("td", null, s.key), el("td", null, s.message_count), el("td", null, fmtTime(s.updated)));
+    row.addEventListener("click", () => { selectedSession = s.key; views.sessions(); });
+    return row;
+  }));
+  if (selectedSession) await loadTranscript(selectedSession);
+};
+
+async function loadTranscript(key) {
+  const t = await api("sessions/transcript?key=" + encodeURIComponent(key));
+  document.getElementById("transcript-key").textContent = key;
+  document.getElementById("session-reset").hidden = false;
+  const summary = document.getElementById("transcript-summary");
+  summary.textContent = t.summary ? "Summary: " + t.summary : "";
+  summary.hidden = !t.summary;
+  fill("transcript-messages", ...t.messages.map((m) => {
+    let body = m.content || "";
+    if (m.tool_calls && m.tool_calls.length) {
+      body += (body ? "\n" : "") + m.tool_calls.map((tc) =>
+        "→ " + (tc.name || (tc.function && tc.function.name) || "tool") + " " + ((tc.function && tc.function.arguments) || JSON.stringify(tc.arguments || {}))).join("\n");
+    }
+    return el("div", { class: "msg " + m.role }, el("div", { class: "role" }, m.role), body);
+  }));
+}
+
+document.getElementById("session-reset").addEventListener("click", async () => {
+  if (!selectedSession || !confirm("Clear the history and summary of " + selectedSession + "?")) return;
+  try {
+    await api("sessions/reset", { method: "POST", body: { key: selectedSession } });
+    notify("Session " + selectedSession + " reset");
+    await views.sessions();
+  } catch (err) {
+    notify(err.message, true);
+  }
+});
+
+// --- cron ------------------------------------------------------------------
+
+function describeSchedule(s) {
+  if (s.kind === "every" && s.everyMs) return "every " + Math.round(s.everyMs / 1000) + "s";
+  if (s.kind === "at" && s.atMs) return "at " + fmtTime(s.atMs);
+  if (s.kind === "cron") return s.expr + (s.tz ? " (" + s.tz + ")" : "");
+  return s.kind;
+}
+
+views.cron = async function () {
+  const data = await api("cron");
+  fill("cron-body", ...(data.jobs.length ? data.jobs.map((job) => {
+    const toggle = el("input", { type: "checkbox" });
+    toggle.checked = job.enabled;
+    toggle.addEventListener("change", async () => {
+      try {
+        await api("cron/" + encodeURIComponent(job.id) + "/enabled", { method: "POST", body: { enabled: toggle.checked } });
+        notify((toggle.checked ? "Enabled " : "Disabled ") + job.name);
+        await views.cron();
+      } catch (err) {
+        toggle.checked = !toggle.checked;
+        notify(err.message, true);
+      }
+    });
+    const last = job.state.lastStatus;
+    return el("tr", null,
+      el("td", null, job.name),
+      el("td", null, describeSchedule(job.schedule)),
+      el("td", null, fmtTime(job.state.nextRunAtMs)),
+      el("td", null, last ? badge(last, last === "ok" ? "ok" : "err") : "—"),
+      el("td", null, toggle));
+  }) : [el("tr", null, el("td", { colspan: "5" }, "No cron jobs"))]));
+};
+
+// --- heartbeat -------------------------------------------------------------
+
+views.heartbeat = async function () {
+  const data = await api("heartbeat");
+  const s = data.status || {};
+  fill("heartbeat-cards",
+    card("State", s.running ? "running" : (s.enabled ? "stopped" : "disabled")),
+    card("Interval", s.interval_minutes ? s.interval_minutes + " min" : "—"),
+    card("Last run", fmtTime(s.last_run_at)),
+    card("Last result", s.last_status || "—"));
+  const log = document.getElementById("heartbeat-log");
+  log.textContent = data.log || "(empty)";
+  log.scrollTop = log.scrollHeight;
+  await loadEditor(document.querySelector('[data-file="heartbeat"]'));
+};
+
+// --- versioned file editors -------------------------------------------------
+
+const editors = new Map();
+
+async function loadEditor(container) {
+  const name = container.dataset.file;
+  let state = editors.get(name);
+  if (!state) {
+    container.append(document.getElementById("editor-template").content.cloneNode(true));
+    state = { text: container.querySelector("textarea"), versions: container.querySelector(".versions"), hash: "", dirty: false };
+    state.text.addEventListener("input", () => { state.dirty = true; });
+    container.querySelector(".save").addEventListener("click", () => saveEditor(name, state));
+    container.querySelector(".reload").addEventListener("click", () => { state.dirty = false; loadEditor(container); });
+    state.versions.addEventListener("change", async () => {
+      const id = state.versions.value;
+      if (!id) { state.dirty = false; return loadEditor(container); }
+      const v = await api("files/" + name + "/versions/" + encodeURIComponent(id));
+      state.text.value = v.content;
+      state.dirty = true;
+      notify("Loaded version from " + fmtTime(state.versions.selectedOptions[0].dataset.time) + ". Save to restore it.");
+    });
+    editors.set(name, state);
+  }
+  if (state.dirty) return;
+
+  const data = await api("files/" + name);
+  state.text.value = data.content;
+  state.hash = data.hash;
+  state.versions.replaceChildren(el("option", { value: "" }, "current"),
+    ...data.versions.map((v) => el("option", { value: v.id, "data-time": v.time }, fmtTime(v.time) + " (" + v.size + " bytes)")));
+}
+
+async function saveEditor(name, state) {
+  try {
+    const res = await api("files/" + name, { method: "PUT", body: { content: state.text.value, base_hash: state.hash } });
+    state.hash = res.hash;
+    state.dirty = false;
+    notify("Saved " + name);
+    await loadEditor(document.querySelector('[data-file="' + name + '"]'));
+  } catch (err) {
+    notify(err.status === 409 ? "The file changed on disk. Reload, then apply your edit again." : err.message, true);
+  }
+}
+
+views.memory = async function () {
+  await loadEditor(document.querySelector('[data-file="memory"]'));
+};
+
+// --- skills ----------------------------------------------------------------
+
+views.skills = async function () {
+  const data = await api("skills");
+  fill("skills-body", ...(data.skills.length ? data.skills.map((s) =>
+    el("tr", null, el("td", null, s.name), el("td", null, s.source), el("td", null, s.description))
+  ) : [el("tr", null, el("td", { colspan: "3" }, "No skills installed"))]));
+};
+
+// --- config ----------------------------------------------------------------
+
+let configHash = "";
+let configDirty = false;
+
+views.config = async function (force) {
+  if (configDirty && !force) return;
+  const data = await api("config");
+  configHash = data.hash;
+  configDirty = false;
+  document.getElementById("config-text").value = JSON.stringify(data.config, null, 2);
+  document.getElementById("config-versions").textContent = data.versions.length
+    ? data.versions.length + " previous versions kept" : "";
+  fill("config-errors");
+};
+
+document.getElementById("config-text").addEventListener("input", () => { configDirty = true; });
+document.getElementById("config-reload").addEventListener("click", () => views.config(true));
+document.getElementById("config-save").addEventListener("click", async () => {
+  let parsed;
+  try {
+    parsed = JSON.parse(document.getElementById("config-text").value);
+  } catch (err) {
+    fill("config-errors", el("li", null, "Invalid JSON: " + err.message));
+    return;
+  }
+  try {
+    const res = await api("config", { method: "PUT", body: { config: parsed, base_hash: configHash } });
+    configHash = res.hash;
+    configDirty = false;
+    fill("config-errors");
+    notify("Config saved. Restart the gateway to apply it.");
+    await views.config(true);
+  } catch (err) {
+    const msg = err.status === 409 ? "The config file changed on disk. Reload, then apply your edit again." : err.message;
+    fill("config-errors", el("li", null, msg), ...err.details.map((d) => el("li", null, d)));
+  }
+});
+
+// --- navigation ------------------------------------------------------------
+
+async function show(view) {
+  currentView = view;
+  for (const button of document.querySelectorAll("#nav button")) {
+    button.classList.toggle("active", button.dataset.view === view);
+  }
+  for (const section of document.querySelectorAll("main > section")) {
+    section.hidden = section.id !== "view-" + view;
+  }
+  await refresh();
+}
+
+async function refresh() {
+  clearTimeout(refreshTimer);
+  try {
+    await views[currentView]();
+  } catch (err) {
+    notify(err.message, true);
+  }
+  const interval = currentView === "activity" ? 2000 : 10000;
+  refreshTimer = setTimeout(refresh, interval);
+}
+
+for (const button of document.querySelectorAll("#nav button")) {
+  button.addEventListener("click", () => show(button.dataset.view));
+}
+
+show("overview");
@@ -0,0 +1,192 @@
+package admin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxVersions is how many previous revisions of each file are kept.
+const maxVersions = 20
+
+const versionTimeFormat = "20060102T150405.000000000Z"
+
+var (
+	errConflict        = errors.New("file changed since it was loaded")
+	errVersionNotFound = errors.New("version not found")
+)
+
+// versionedFile is a file edited through the dashboard. Every write first
+// copies the current content into the versions directory, and writes carry
+// the hash of the content they were based on so that concurrent edits (by
+// another admin, or by the agent itself) are not silently overwritten.
+type versionedFile struct {
+	path string
+	dir  string
+	mu   sync.Mutex
+}
+
+type fileVersion struct {
+	ID   string    `json:"id"`
+	Time time.Time `json:"time"`
+	Size int64     `json:"size"`
+}
+
+func newVersionedFile(path, dir string) *versionedFile {
+	return &versionedFile{path: path, dir: dir}
+}
+
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// read returns the current content and its hash. A missing file reads as empty.
+func (f *versionedFile) read() ([]byte, string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.readUnsafe()
+}
+
+func (f *versionedFile) readUnsafe() ([]byte, string, error) {
+	data, err := os.ReadFile(f.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, "", err
+	}
+	return data, contentHash(data), nil
+}
+
+// write replaces the file if its current hash still matches baseHash.
+func (f *versionedFile) write(content []byte, baseHash string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	current, hash, err := f.readUnsafe()
+	if err != nil {
+		return "", err
+	}
+	if baseHash != hash {
+		return "", errConflict
+	}
+
+	if len(current) > 0 {
+		if err := f.saveVersion(current); err != nil {
+			return "", fmt.Errorf("failed to save previous version: %w", err)
+		}
+	}
+
+	if err := writeFileAtomic(f.path, content); err != nil {
+		return "", err
+	}
+	return contentHash(content), nil
+}
+
+func (f *versionedFile) saveVersion(content []byte) error {
+	if err := os.MkdirAll(f.dir, 0700); err != nil {
+		return err
+	}
+	id := time.Now().UTC().Format(versionTimeFormat)
+	if err := os.WriteFile(filepath.Join(f.dir, id), content, 0600); err != nil {
+		return err
+	}
+
+	versions, err := f.versionsUnsafe()
+	if err != nil {
+		return err
+	}
+	for _, v := range versions[min(len(versions), maxVersions):] {
+		os.Remove(filepath.Join(f.dir, v.ID))
+	}
+	return nil
+}
+
+// versions lists saved revisions, newest first.
+func (f *versionedFile) versions() ([]fileVersion, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.versionsUnsafe()
+}
+
+func (f *versionedFile) versionsUnsafe() ([]fileVersion, error) {
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []fileVersion{}, nil
+		}
+		return nil, err
+	}
+
+	versions := make([]fileVersion, 0, len(entries))
+	for _, e := range entries {
+		t, err := time.Parse(versionTimeFormat, e.Name())
+		if err != nil || e.IsDir() {
+			continue
+		}
+		var size int64
+		if info, err := e.Info(); err == nil {
+			size = info.Size()
+		}
+		versions = append(versions, fileVersion{ID: e.Name(), Time: t, Size: size})
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Time.After(versions[j].Time)
+	})
+	return versions, nil
+}
+
+// version returns the content of a saved revision.
+func (f *versionedFile) version(id string) ([]byte, error) {
+	if _, err := time.Parse(versionTimeFormat, id); err != nil || strings.ContainsAny(id, `/\`) {
+		return nil, errVersionNotFound
+	}
+	data, err := os.ReadFile(filepath.Join(f.dir, id))
+	if os.IsNotExist(err) {
+		return nil, errVersionNotFound
+	}
+	return data, err
+}
+
+// writeFileAtomic writes data to a temp file in the same directory and
+// renames it into place, so readers never see a partial file.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmpFile, err := os.CreateTemp(dir, ".admin-*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmpFile.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Chmod(mode); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Sync(); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
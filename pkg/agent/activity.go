@@ -0,0 +1,210 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/constants"
+	"github.com/sipeed/picoclaw/pkg/i18n"
+)
+
+// typingInterval is how often the typing indicator is renewed during a
+// turn. Channels let it lapse after five to ten seconds.
+const typingInterval = 4 * time.Second
+
+// activity shows the user of a turn that the agent is still working: the
+// typing indicator is kept alive, and what the agent does is shown as a
+// status, at most one per interval. A nil activity does nothing.
+type activity struct {
+	bus      *bus.MessageBus
+	channel  string
+	chatID   string
+	lang     string
+	status   bool
+	interval time.Duration
+	stopped  chan struct{}
+
+	mu      sync.Mutex
+	last    time.Time // when the turn started or the last status was shown
+	current string    // status on screen
+	pending string    // status waiting for the interval to pass
+	timer   *time.Timer
+	shown   bool
+	done    bool
+}
+
+// startActivity starts the indicators for a turn on msg, or returns nil
+// when its channel has none enabled.
+func (al *AgentLoop) startActivity(msg bus.InboundMessage) *activity {
+	typing, status := al.progress.For(msg.Channel)
+	if constants.IsInternalChannel(msg.Channel) || (!typing && !status) {
+		return nil
+	}
+	a := &activity{
+		bus:      al.bus,
+		channel:  msg.Channel,
+		chatID:   msg.ChatID,
+		lang:     al.locales.Resolve(msg.Channel, msg.ChatID, msg.SenderID).Locale,
+		status:   status,
+		interval: time.Duration(al.progress.Interval) * time.Second,
+		stopped:  make(chan struct{}),
+		last:     time.Now(),
+	}
+	if typing {
+		a.publish(bus.Activity{Kind: bus.ActivityTyping})
+		go a.keepTyping()
+	}
+	return a
+}
+
+func (a *activity) keepTyping() {
+	ticker := time.NewTicker(typingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-a.stopped:
+			return
+		case <-ticker.C:
+		}
+
+		a.mu.Lock()
+		if !a.done {
+			a.publish(bus.Activity{Kind: bus.ActivityTyping})
+		}
+		a.mu.Unlock()
+	}
+}
+
+// tool shows what running the named tool means to the user.
+func (a *activity) tool(name string) {
+	if a == nil {
+		return
+	}
+	var status string
+	switch name {
+	case "web_search":
+		status = i18n.T(a.lang, i18n.ProgressWebSearch)
+	case "web_fetch":
+		status = i18n.T(a.lang, i18n.ProgressWebFetch)
+	case "exec":
+		status = i18n.T(a.lang, i18n.ProgressExec)
+	case "read_file", "write_file", "edit_file", "append_file", "list_dir":
+		status = i18n.T(a.lang, i18n.ProgressFiles)
+	case "spawn", "subagent", "ask_peer":
+		status = i18n.T(a.lang, i18n.ProgressDelegate)
+	case "message":
+		// The user sees its result soon enough
+		return
+	default:
+		status = i18n.T(a.lang, i18n.ProgressTool, name)
+	}
+	a.setStatus(status)
+}
+
+// thinking shows that the model is working with the tool results.
+func (a *activity) thinking() {
+	if a == nil {
+		return
+	}
+	a.setStatus(i18n.T(a.lang, i18n.ChannelThinking))
+}
+
+// setStatus shows status now if the interval has passed since the last one,
+// and otherwise once it has, unless a newer status replaces it first. Quick
+// turns end before the first interval and show no status at all.
+func (a *activity) setStatus(status string) {
+	if !a.status {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.done {
+		return
+	}
+	if wait := a.interval - time.Since(a.last); wait > 0 {
+		a.pending = status
+		if a.timer == nil {
+			a.timer = time.AfterFunc(wait, a.flush)
+		}
+		return
+	}
+	a.show(status)
+}
+
+func (a *activity) flush() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.timer = nil
+	if !a.done && a.pending != "" {
+		a.show(a.pending)
+	}
+}
+
+// show publishes status. a.mu must be held.
+func (a *activity) show(status string) {
+	a.pending = ""
+	if status == a.current {
+		return
+	}
+	a.current = status
+	a.last = time.Now()
+	a.shown = true
+	a.publish(bus.Activity{Kind: bus.ActivityStatus, Status: status})
+}
+
+// stop ends the indicators before the reply is sent.
+func (a *activity) stop() {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.done {
+		return
+	}
+	a.done = true
+	if a.timer != nil {
+		a.timer.Stop()
+	}
+	close(a.stopped)
+}
+
+// clear removes the status placeholder after the reply was sent. Channels
+// that turned the placeholder into the reply ignore it.
+func (a *activity) clear() {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.shown {
+		a.shown = false
+		a.publish(bus.Activity{Kind: bus.ActivityDone})
+	}
+}
+
+func (a *activity) publish(act bus.Activity) {
+	a.bus.PublishOutbound(bus.OutboundMessage{
+		Channel:  a.channel,
+		ChatID:   a.chatID,
+		Activity: &act,
+	})
+}
+
+type activityKey struct{}
+
+func withActivity(ctx context.Context, a *activity) context.Context {
+	return context.WithValue(ctx, activityKey{}, a)
+}
+
+// activityFrom returns the activity of the turn in ctx, or nil.
+func activityFrom(ctx context.Context) *activity {
+	a, _ := ctx.Value(activityKey{}).(*activity)
+	return a
+}
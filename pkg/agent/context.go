@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/sipeed/picoclaw/pkg/locale"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/providers"
 	"github.com/sipeed/picoclaw/pkg/skills"
@@ -48,8 +49,11 @@ func (cb *ContextBuilder) SetToolsRegistry(registry *tools.ToolRegistry) {
 	cb.tools = registry
 }
 
-func (cb *ContextBuilder) getIdentity() string {
-	now := time.Now().Format("2006-01-02 15:04 (Monday)")
+func (cb *ContextBuilder) getIdentity(now time.Time, settings locale.Settings) string {
+	currentTime := fmt.Sprintf("%s\nTime zone: %s (UTC%s)", now.Format("2006-01-02 15:04 (Monday)"), settings.ZoneName(), now.Format("-07:00"))
+	if settings.Locale != "" {
+		currentTime += fmt.Sprintf("\nUser locale: %s (format dates, times and numbers for it)", settings.Locale)
+	}
 	workspacePath, _ := filepath.Abs(filepath.Join(cb.workspace))
 	runtime := fmt.Sprintf("%s %s, Go %s", runtime.GOOS, runtime.GOARCH, runtime.Version())
 
@@ -81,7 +85,7 @@ Your workspace is at: %s
 2. **Be helpful and accurate** - When using tools, briefly explain what you're doing.
 
 3. **Memory** - When remembering something, write to %s/memory/MEMORY.md`,
-		now, runtime, workspacePath, workspacePath, workspacePath, workspacePath, toolsSection, workspacePath)
+		currentTime, runtime, workspacePath, workspacePath, workspacePath, workspacePath, toolsSection, workspacePath)
 }
 
 func (cb *ContextBuilder) buildToolsSection() string {
@@ -106,11 +110,14 @@ func (cb *ContextBuilder) buildToolsSection() string {
 	return sb.String()
 }
 
-func (cb *ContextBuilder) BuildSystemPrompt() string {
+// BuildSystemPrompt builds the system prompt for a user with the given
+// time zone and locale.
+func (cb *ContextBuilder) BuildSystemPrompt(settings locale.Settings) string {
 	parts := []string{}
+	now := time.Now().In(settings.Location())
 
 	// Core identity section
-	parts = append(parts, cb.getIdentity())
+	parts = append(parts, cb.getIdentity(now, settings))
 
 	// Bootstrap files
 	bootstrapContent := cb.LoadBootstrapFiles()
@@ -129,7 +136,7 @@ The following skills extend your capabilities. To use a skill, read its SKILL.md
 	}
 
 	// Memory context
-	memoryContext := cb.memory.GetMemoryContext()
+	memoryContext := cb.memory.GetMemoryContext(now)
 	if memoryContext != "" {
 		parts = append(parts, "# Memory\n\n"+memoryContext)
 	}
@@ -157,10 +164,10 @@ func (cb *ContextBuilder) LoadBootstrapFiles() string {
 	return result
 }
 
-func (cb *ContextBuilder) BuildMessages(history []providers.Message, summary string, currentMessage string, media []string, channel, chatID string) []providers.Message {
+func (cb *ContextBuilder) BuildMessages(history []providers.Message, summary string, pins []string, currentMessage string, media []string, channel, chatID string, settings locale.Settings) []providers.Message {
 	messages := []providers.Message{}
 
-	systemPrompt := cb.BuildSystemPrompt()
+	systemPrompt := cb.BuildSystemPrompt(settings)
 
 	// Add Current Session info if provided
 	if channel != "" && chatID != "" {
@@ -185,6 +192,13 @@ func (cb *ContextBuilder) BuildMessages(history []providers.Message, summary str
 			"preview": preview,
 		})
 
+	if len(pins) > 0 {
+		systemPrompt += "\n\n## Pinned Notes\n\nThe user pinned these facts to this conversation. They hold until unpinned, whatever the summary or earlier messages say.\n"
+		for _, p := range pins {
+			systemPrompt += "\n- " + p
+		}
+	}
+
 	if summary != "" {
 		systemPrompt += "\n\n## Summary of Previous Conversation\n\n" + summary
 	}
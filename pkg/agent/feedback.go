@@ -0,0 +1,147 @@
+package agent
+
+import (
+	"errors"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/feedback"
+	"github.com/sipeed/picoclaw/pkg/i18n"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+const (
+	// feedbackContextMessages is how much of the conversation before a turn
+	// is kept with it.
+	feedbackContextMessages = 6
+	feedbackContextLen      = 2000
+)
+
+// recordTurn keeps an answered turn so users can rate it.
+func (al *AgentLoop) recordTurn(opts processOptions, history []providers.Message, summary, response string, iterations int) {
+	var earlier []feedback.Message
+	if summary != "" {
+		earlier = append(earlier, feedback.Message{Role: "system", Content: "Summary of the earlier conversation: " + utils.Truncate(summary, feedbackContextLen)})
+	}
+	start := max(len(history)-feedbackContextMessages, 0)
+	for _, m := range history[start:] {
+		if (m.Role == "user" || m.Role == "assistant") && m.Content != "" {
+			earlier = append(earlier, feedback.Message{Role: m.Role, Content: utils.Truncate(m.Content, feedbackContextLen)})
+		}
+	}
+
+	_, err := al.feedback.RecordTurn(feedback.Turn{
+		SessionKey: opts.SessionKey,
+		Channel:    opts.Channel,
+		ChatID:     opts.ChatID,
+		SenderID:   opts.SenderID,
+		Model:      al.model,
+		Context:    earlier,
+		Prompt:     opts.UserMessage,
+		Response:   response,
+		Iterations: iterations,
+	})
+	if err != nil {
+		logger.WarnCF("agent", "Failed to record turn for feedback", map[string]interface{}{"error": err.Error()})
+	}
+}
+
+// lastTraceID returns the trace ID of the latest turn in msg's chat.
+func (al *AgentLoop) lastTraceID(channel, chatID string) string {
+	if al.feedback == nil {
+		return ""
+	}
+	t, _ := al.feedback.Last(channel, chatID)
+	return t.TraceID
+}
+
+// ratingButtons returns the 👍/👎 buttons for a turn, when enabled.
+func (al *AgentLoop) ratingButtons(traceID string) []bus.Button {
+	if !al.feedbackButtons || traceID == "" {
+		return nil
+	}
+	return []bus.Button{
+		{Text: "👍", Data: feedback.ButtonData(traceID, 1)},
+		{Text: "👎", Data: feedback.ButtonData(traceID, -1)},
+	}
+}
+
+// captureFeedback records ratings: reactions to answers, rating buttons,
+// /feedback and short complaints about the last answer. It reports whether msg was
+// consumed; complaints are recorded but still answered by the agent.
+func (al *AgentLoop) captureFeedback(msg bus.InboundMessage) (reply string, handled bool) {
+	if al.feedback == nil {
+		return "", false
+	}
+	lang := al.locales.Resolve(msg.Channel, msg.ChatID, msg.SenderID).Locale
+	rating := feedback.Rating{By: msg.SenderID}
+
+	if emoji := msg.Metadata["reaction"]; emoji != "" {
+		score, ok := feedback.ReactionScore(emoji)
+		if !ok {
+			return "", false
+		}
+		// Only reactions to an answer of the bot rate it; the channel
+		// passes on which turn the message belongs to
+		rating.TraceID, rating.Score, rating.Source = msg.Metadata["trace_id"], score, "reaction"
+		if rating.TraceID != "" {
+			if err := al.feedback.Rate(rating); err != nil {
+				al.logRatingError(err)
+			}
+		}
+		return "", true
+	}
+
+	if traceID, score, ok := feedback.ParseButton(msg.Content); ok {
+		rating.TraceID, rating.Score, rating.Source = traceID, score, "button"
+		if err := al.feedback.Rate(rating); err != nil {
+			al.logRatingError(err)
+			return "", true
+		}
+		return i18n.T(lang, i18n.FeedbackThanks), true
+	}
+
+	if score, comment, ok := feedback.ParseCommand(msg.Content); ok {
+		if score == 0 && comment == "" {
+			return i18n.T(lang, i18n.FeedbackUsage), true
+		}
+		rating.Score, rating.Comment, rating.Source = score, comment, "command"
+		if !al.rateLast(msg, rating) {
+			return i18n.T(lang, i18n.FeedbackNoTurn), true
+		}
+		return i18n.T(lang, i18n.FeedbackThanks), true
+	}
+
+	if feedback.IsComplaint(msg.Content) {
+		rating.Score, rating.Comment, rating.Source = -1, msg.Content, "text"
+		al.rateLast(msg, rating)
+	}
+	return "", false
+}
+
+// rateLast rates the latest turn in msg's chat and reports whether there
+// was one.
+func (al *AgentLoop) rateLast(msg bus.InboundMessage, rating feedback.Rating) bool {
+	last, ok := al.feedback.Last(msg.Channel, msg.ChatID)
+	if !ok {
+		return false
+	}
+	rating.TraceID = last.TraceID
+	if err := al.feedback.Rate(rating); err != nil {
+		al.logRatingError(err)
+	}
+	return true
+}
+
+func (al *AgentLoop) logRatingError(err error) {
+	if errors.Is(err, feedback.ErrUnknownTurn) {
+		return // rated after the turn was dropped
+	}
+	logger.WarnCF("agent", "Failed to record rating", map[string]interface{}{"error": err.Error()})
+}
+
+// Feedback returns the store of turns and ratings, or nil when disabled.
+func (al *AgentLoop) Feedback() *feedback.Store {
+	return al.feedback
+}
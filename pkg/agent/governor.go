@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/governor"
+)
+
+// pressureIdle is how recently a session must have been used to stay in
+// memory under memory pressure.
+const pressureIdle = time.Minute
+
+// evictSessions saves and drops idle sessions, in the main workspace and
+// the tenants', and forgets tenant scopes with no session left in memory.
+func (al *AgentLoop) evictSessions(pressure bool) int {
+	idle := time.Duration(al.memory.SessionIdleMinutes) * time.Minute
+	keep := al.memory.MaxSessions
+	if pressure && (idle == 0 || idle > pressureIdle) {
+		idle = pressureIdle
+	}
+	if idle == 0 && keep == 0 {
+		return 0
+	}
+
+	n := al.sessions.Evict(idle, keep)
+
+	al.scopesMu.Lock()
+	scopes := make(map[string]*scope, len(al.scopes))
+	for id, sc := range al.scopes {
+		scopes[id] = sc
+	}
+	al.scopesMu.Unlock()
+	for id, sc := range scopes {
+		n += sc.sessions.Evict(idle, keep)
+		if inMemory, _ := sc.sessions.Resident(); inMemory > 0 {
+			continue
+		}
+		// Opening the scope again is cheap: sessions load when used
+		al.scopesMu.Lock()
+		if al.scopes[id] == sc {
+			delete(al.scopes, id)
+		}
+		al.scopesMu.Unlock()
+	}
+	return n
+}
+
+// Governor returns the memory governor. Start it to apply the soft limit
+// and evict under pressure.
+func (al *AgentLoop) Governor() *governor.Governor {
+	return al.governor
+}
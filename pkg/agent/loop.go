@@ -9,9 +9,11 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -20,45 +22,91 @@ import (
 
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/connectivity"
 	"github.com/sipeed/picoclaw/pkg/constants"
+	"github.com/sipeed/picoclaw/pkg/feedback"
+	"github.com/sipeed/picoclaw/pkg/governor"
+	"github.com/sipeed/picoclaw/pkg/i18n"
+	"github.com/sipeed/picoclaw/pkg/locale"
 	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/media"
+	"github.com/sipeed/picoclaw/pkg/monitor"
+	"github.com/sipeed/picoclaw/pkg/network"
 	"github.com/sipeed/picoclaw/pkg/providers"
 	"github.com/sipeed/picoclaw/pkg/session"
+	"github.com/sipeed/picoclaw/pkg/skills"
 	"github.com/sipeed/picoclaw/pkg/state"
+	"github.com/sipeed/picoclaw/pkg/tenant"
 	"github.com/sipeed/picoclaw/pkg/tools"
+	"github.com/sipeed/picoclaw/pkg/translate"
 	"github.com/sipeed/picoclaw/pkg/utils"
 )
 
 type AgentLoop struct {
-	bus            *bus.MessageBus
-	provider       providers.LLMProvider
-	workspace      string
-	model          string
-	contextWindow  int // Maximum context window size in tokens
-	maxIterations  int
-	sessions       *session.SessionManager
-	state          *state.Manager
-	contextBuilder *ContextBuilder
-	tools          *tools.ToolRegistry
-	running        atomic.Bool
-	summarizing    sync.Map // Tracks which sessions are currently being summarized
+	bus             *bus.MessageBus
+	provider        providers.LLMProvider
+	workspace       string
+	model           string
+	contextWindow   int // Maximum context window size in tokens
+	maxIterations   int
+	sessions        *session.SessionManager
+	state           *state.Manager
+	contextBuilder  *ContextBuilder
+	tools           *tools.ToolRegistry
+	subagents       *tools.SubagentManager
+	monitor         *monitor.Monitor
+	media           *media.Store
+	locales         *locale.Store
+	online          *connectivity.Monitor // nil unless connectivity detection is enabled
+	offlineQueue    *connectivity.Queue
+	localTools      []string // tools users can run with /tool, without the model
+	interceptors    []Interceptor
+	relay           *translate.Relay // nil unless translation groups are on
+	feedback        *feedback.Store  // nil unless feedback capture is enabled
+	feedbackButtons bool
+	tenants         *tenant.Manager // nil unless multi-tenant mode is on
+	scopes          map[string]*scope
+	scopesMu        sync.Mutex
+	governor        *governor.Governor
+	memory          config.MemoryConfig
+	maxPinnedChars  int
+	progress        config.ProgressConfig
+	running         atomic.Bool
+	summarizing     sync.Map // Tracks which sessions are currently being summarized
 }
 
+// Interceptor sees inbound messages before the agent does. When it handles
+// one, no turn runs and its reply, if any, is sent instead.
+type Interceptor func(msg bus.InboundMessage) (reply string, handled bool)
+
 // processOptions configures how a message is processed
 type processOptions struct {
 	SessionKey      string // Session identifier for history/context
 	Channel         string // Target channel for tool execution
 	ChatID          string // Target chat ID for tool execution
+	SenderID        string // User the turn is for; selects time zone and locale
 	UserMessage     string // User message content (may include prefix)
 	DefaultResponse string // Response when LLM returns empty
 	EnableSummary   bool   // Whether to trigger summarization
 	SendResponse    bool   // Whether to send response via bus
 	NoHistory       bool   // If true, don't load session history (for heartbeat)
+	QueueOffline    bool   // If true, a turn that fails while offline is queued instead
 }
 
+// errOffline means a turn failed because the provider cannot be reached.
+var errOffline = errors.New("provider unreachable")
+
+// metaQueuedAt marks a message replayed from the offline queue, with the
+// time it was first received.
+const metaQueuedAt = "queued_at"
+
+// metaQueuedID is the offline queue entry of a replayed message, removed
+// once its turn is done.
+const metaQueuedID = "queued_id"
+
 // createToolRegistry creates a tool registry with common tools.
 // This is shared between main agent and subagents.
-func createToolRegistry(workspace string, restrict bool, cfg *config.Config, msgBus *bus.MessageBus) *tools.ToolRegistry {
+func createToolRegistry(workspace string, restrict bool, cfg *config.Config, msgBus *bus.MessageBus, mediaStore *media.Store) *tools.ToolRegistry {
 	registry := tools.NewToolRegistry()
 
 	// File system tools
@@ -97,8 +145,36 @@ func createToolRegistry(workspace string, restrict bool, cfg *config.Config, msg
 		})
 		return nil
 	})
+	messageTool.SetSendMediaCallback(func(channel, chatID, content string, attachments []string) error {
+		paths := make([]string, 0, len(attachments))
+		for _, path := range attachments {
+			// Stored media can be attached by reference
+			if mediaStore != nil && strings.HasPrefix(path, media.RefPrefix) {
+				item, ok := mediaStore.Get(path)
+				if !ok {
+					return fmt.Errorf("attachment %s: not found", path)
+				}
+				path = mediaStore.Path(item)
+			}
+			if _, err := os.Stat(path); err != nil {
+				return fmt.Errorf("attachment %s: %w", path, err)
+			}
+			paths = append(paths, path)
+		}
+		msgBus.PublishOutbound(bus.OutboundMessage{
+			Channel: channel,
+			ChatID:  chatID,
+			Content: content,
+			Media:   paths,
+		})
+		return nil
+	})
 	registry.Register(messageTool)
 
+	if mediaStore != nil {
+		registry.Register(tools.NewMediaTool(mediaStore, workspace))
+	}
+
 	return registry
 }
 
@@ -108,14 +184,30 @@ func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers
 
 	restrict := cfg.Agents.Defaults.RestrictToWorkspace
 
+	// Inbound attachments are kept in the workspace so file tools can read them
+	mediaStore, err := media.NewStore(filepath.Join(workspace, "media"), media.Options{
+		MaxBytes: int64(cfg.Tools.Media.MaxSizeMB) << 20,
+		MaxAge:   time.Duration(cfg.Tools.Media.MaxAgeDays) * 24 * time.Hour,
+	})
+	if err != nil {
+		logger.WarnCF("agent", "Media store unavailable", map[string]interface{}{"error": err.Error()})
+		mediaStore = nil
+	}
+
+	// The governor caps reads, limits background work and evicts idle
+	// sessions; it starts watching memory with Governor().Start
+	gov := governor.New(cfg.Memory)
+	tools.SetReadLimit(gov.MaxReadBytes())
+
 	// Create tool registry for main agent
-	toolsRegistry := createToolRegistry(workspace, restrict, cfg, msgBus)
+	toolsRegistry := createToolRegistry(workspace, restrict, cfg, msgBus, mediaStore)
 
 	// Create subagent manager with its own tool registry
 	subagentManager := tools.NewSubagentManager(provider, cfg.Agents.Defaults.Model, workspace, msgBus)
-	subagentTools := createToolRegistry(workspace, restrict, cfg, msgBus)
+	subagentTools := createToolRegistry(workspace, restrict, cfg, msgBus, mediaStore)
 	// Subagent doesn't need spawn/subagent tools to avoid recursion
 	subagentManager.SetTools(subagentTools)
+	subagentManager.SetGate(gov)
 
 	// Register spawn tool (for main agent)
 	spawnTool := tools.NewSpawnTool(subagentManager)
@@ -125,33 +217,118 @@ func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers
 	subagentTool := tools.NewSubagentTool(subagentManager)
 	toolsRegistry.Register(subagentTool)
 
+	// Time zone and locale per user and chat
+	locales := locale.NewStore(workspace, locale.Settings{
+		TimeZone: cfg.Agents.Defaults.Timezone,
+		Locale:   cfg.Agents.Defaults.Locale,
+	})
+	toolsRegistry.Register(tools.NewLocaleTool(locales))
+
+	// Fixed messages from channels, cron, devices and heartbeat follow the same settings
+	i18n.SetDefault(cfg.Agents.Defaults.Locale)
+	i18n.SetResolver(func(channel, chatID, senderID string) string {
+		return locales.Resolve(channel, chatID, senderID).Locale
+	})
+
 	sessionsManager := session.NewSessionManager(filepath.Join(workspace, "sessions"))
 
 	// Create state manager for atomic state persistence
 	stateManager := state.NewManager(workspace)
+	toolsRegistry.Register(tools.NewKVTool(stateManager))
+	toolsRegistry.Register(tools.NewPinTool(cfg.Agents.Defaults.MaxPinnedChars))
 
 	// Create context builder and set tools registry
 	contextBuilder := NewContextBuilder(workspace)
 	contextBuilder.SetToolsRegistry(toolsRegistry)
 
-	return &AgentLoop{
-		bus:            msgBus,
-		provider:       provider,
-		workspace:      workspace,
-		model:          cfg.Agents.Defaults.Model,
-		contextWindow:  cfg.Agents.Defaults.MaxTokens, // Restore context window for summarization
-		maxIterations:  cfg.Agents.Defaults.MaxToolIterations,
-		sessions:       sessionsManager,
-		state:          stateManager,
-		contextBuilder: contextBuilder,
-		tools:          toolsRegistry,
-		summarizing:    sync.Map{},
+	// Offline detection probes the provider's endpoint; turns received while
+	// it is unreachable wait in a queue that survives restarts
+	var online *connectivity.Monitor
+	var offlineQueue *connectivity.Queue
+	if cfg.Connectivity.Enabled {
+		endpoint := ""
+		if r, ok := provider.(providers.EndpointReporter); ok {
+			endpoint = r.Endpoint()
+		}
+		online = connectivity.New(cfg.Connectivity, endpoint)
+		offlineQueue = connectivity.NewQueue(filepath.Join(workspace, "state", "offline_queue.json"), cfg.Connectivity.MaxQueue)
+	}
+
+	var feedbackStore *feedback.Store
+	if cfg.Feedback.Enabled {
+		feedbackStore = feedback.NewStore(filepath.Join(workspace, "feedback"), cfg.Feedback.MaxTurns)
 	}
+
+	var tenants *tenant.Manager
+	if cfg.Tenants.Enabled {
+		tenants = tenant.NewManager(workspace, cfg.Tenants)
+	}
+
+	al := &AgentLoop{
+		bus:             msgBus,
+		provider:        provider,
+		workspace:       workspace,
+		model:           cfg.Agents.Defaults.Model,
+		contextWindow:   cfg.Agents.Defaults.MaxTokens, // Restore context window for summarization
+		maxIterations:   cfg.Agents.Defaults.MaxToolIterations,
+		sessions:        sessionsManager,
+		state:           stateManager,
+		contextBuilder:  contextBuilder,
+		tools:           toolsRegistry,
+		subagents:       subagentManager,
+		monitor:         monitor.NewMonitor(),
+		media:           mediaStore,
+		locales:         locales,
+		online:          online,
+		offlineQueue:    offlineQueue,
+		localTools:      cfg.Connectivity.LocalTools,
+		feedback:        feedbackStore,
+		feedbackButtons: cfg.Feedback.Buttons,
+		tenants:         tenants,
+		scopes:          make(map[string]*scope),
+		governor:        gov,
+		memory:          cfg.Memory,
+		maxPinnedChars:  cfg.Agents.Defaults.MaxPinnedChars,
+		progress:        cfg.Progress,
+		summarizing:     sync.Map{},
+	}
+	gov.Register("sessions", al.evictSessions)
+	gov.Register("connections", func(pressure bool) int {
+		if !pressure {
+			return 0
+		}
+		return network.CloseIdleConnections()
+	})
+	if tenants != nil {
+		tenants.OnPurge(al.dropTenant)
+	}
+	if cfg.Translate.Enabled {
+		al.relay = translate.NewRelay(cfg.Translate, provider, cfg.Agents.Defaults.Model, msgBus, filepath.Join(workspace, "translate"))
+		al.relay.OnUsage(al.monitor.RecordUsage)
+	}
+	if online != nil {
+		online.OnChange(func(online bool) {
+			if online {
+				go al.replayQueued()
+			}
+		})
+	}
+	return al
 }
 
 func (al *AgentLoop) Run(ctx context.Context) error {
 	al.running.Store(true)
 
+	if al.online != nil {
+		go func() {
+			// Answer what was queued before a restart once we know we are online
+			if al.online.Check(ctx) {
+				al.replayQueued()
+			}
+			al.online.Run(ctx)
+		}()
+	}
+
 	for al.running.Load() {
 		select {
 		case <-ctx.Done():
@@ -162,9 +339,33 @@ func (al *AgentLoop) Run(ctx context.Context) error {
 				continue
 			}
 
-			response, err := al.processMessage(ctx, msg)
+			// Messages of a channel paused from `picoclaw top` wait until it resumes
+			if al.monitor.Hold(msg) {
+				continue
+			}
+
+			if al.intercept(msg) {
+				continue
+			}
+
+			lastTrace := al.lastTraceID(msg.Channel, msg.ChatID)
+			// Users of a public bot each work in their own workspace
+			response := ""
+			act := al.startActivity(msg)
+			turnCtx, err := al.withTenant(ctx, msg)
+			if err == nil {
+				response, err = al.processMessage(withActivity(turnCtx, act), msg)
+			}
+			act.stop()
 			if err != nil {
-				response = fmt.Sprintf("Error processing message: %v", err)
+				lang := al.locales.Resolve(msg.Channel, msg.ChatID, msg.SenderID).Locale
+				if errors.Is(err, context.Canceled) && ctx.Err() == nil {
+					response = i18n.T(lang, i18n.AgentTurnCanceled)
+				} else if errors.Is(err, tenant.ErrQuota) {
+					response = i18n.T(lang, i18n.TenantQuota)
+				} else {
+					response = i18n.T(lang, i18n.AgentError, err)
+				}
 			}
 
 			if response != "" {
@@ -178,21 +379,80 @@ func (al *AgentLoop) Run(ctx context.Context) error {
 				}
 
 				if !alreadySent {
+					// Answers from a new turn can be rated with buttons and reactions
+					trace := al.lastTraceID(msg.Channel, msg.ChatID)
+					if err != nil || trace == lastTrace {
+						trace = ""
+					}
 					al.bus.PublishOutbound(bus.OutboundMessage{
 						Channel: msg.Channel,
 						ChatID:  msg.ChatID,
 						Content: response,
+						Buttons: al.ratingButtons(trace),
+						TraceID: trace,
 					})
 				}
 			}
+			act.clear()
 		}
 	}
 
 	return nil
 }
 
+// AddInterceptor registers fn to see inbound messages before the agent.
+// Interceptors run in the order added and must be added before Run.
+func (al *AgentLoop) AddInterceptor(fn Interceptor) {
+	al.interceptors = append(al.interceptors, fn)
+}
+
+// intercept offers msg to the interceptors, then to the tenant admin
+// commands, feedback capture and the translation relay, and reports whether
+// one handled it. Reactions nobody handled are dropped; they are not turns.
+func (al *AgentLoop) intercept(msg bus.InboundMessage) bool {
+	for _, fn := range al.interceptors {
+		if reply, handled := fn(msg); handled {
+			al.replyIntercepted(msg, reply)
+			return true
+		}
+	}
+	if reply, handled := al.tenantCommand(msg); handled {
+		al.replyIntercepted(msg, reply)
+		return true
+	}
+	if reply, handled := al.captureFeedback(msg); handled {
+		al.replyIntercepted(msg, reply)
+		return true
+	}
+	// Last, so reminder acks and feedback still work in relay groups; the
+	// relay takes everything else there, and the agent never answers
+	if al.relay != nil {
+		if reply, handled := al.relay.Intercept(msg); handled {
+			al.replyIntercepted(msg, reply)
+			return true
+		}
+	}
+	return msg.Metadata["reaction"] != ""
+}
+
+func (al *AgentLoop) replyIntercepted(msg bus.InboundMessage, reply string) {
+	if reply == "" {
+		return
+	}
+	al.bus.PublishOutbound(bus.OutboundMessage{
+		Channel: msg.Channel,
+		ChatID:  msg.ChatID,
+		Content: reply,
+	})
+}
+
+// Stop stops the loop and translates what relay groups still have queued,
+// so stop the agent before the channels.
 func (al *AgentLoop) Stop() {
 	al.running.Store(false)
+	if al.relay != nil {
+		al.relay.Flush()
+	}
 }
 
 func (al *AgentLoop) RegisterTool(tool tools.Tool) {
@@ -235,7 +495,7 @@ func (al *AgentLoop) ProcessHeartbeat(ctx context.Context, content, channel, cha
 		Channel:         channel,
 		ChatID:          chatID,
 		UserMessage:     content,
-		DefaultResponse: "I've completed processing but have no response to give.",
+		DefaultResponse: i18n.T(i18n.ForChat(channel, chatID), i18n.AgentNoResponse),
 		EnableSummary:   false,
 		SendResponse:    false,
 		NoHistory:       true, // Don't load session history for heartbeat
@@ -263,16 +523,162 @@ func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage)
 		return al.processSystemMessage(ctx, msg)
 	}
 
+	// Remember what the platform tells us about the sender's zone and language
+	detected := locale.Settings{TimeZone: msg.Metadata[locale.MetaTimeZone], Locale: msg.Metadata[locale.MetaLocale]}
+	if err := al.locales.Detect(msg.Channel, msg.SenderID, detected); err != nil {
+		logger.WarnCF("agent", "Failed to record detected locale", map[string]interface{}{"error": err.Error()})
+	}
+
+	if msg.Content == "/tool" || strings.HasPrefix(msg.Content, "/tool ") {
+		return al.runToolCommand(ctx, msg), nil
+	}
+	if reply, ok := al.pinCommand(ctx, msg); ok {
+		return reply, nil
+	}
+
+	queueOffline := al.online != nil && !constants.IsInternalChannel(msg.Channel)
+	if queueOffline && !al.online.Online() {
+		return al.queueMessage(msg)
+	}
+
+	userMessage := al.withAttachments(msg.Content, msg.Media)
+	if at := msg.Metadata[metaQueuedAt]; at != "" {
+		userMessage = fmt.Sprintf("[Sent at %s, while you were offline]\n%s", at, userMessage)
+	}
+
 	// Process as user message
-	return al.runAgentLoop(ctx, processOptions{
+	response, err := al.runAgentLoop(ctx, processOptions{
 		SessionKey:      msg.SessionKey,
 		Channel:         msg.Channel,
 		ChatID:          msg.ChatID,
-		UserMessage:     msg.Content,
-		DefaultResponse: "I've completed processing but have no response to give.",
+		SenderID:        msg.SenderID,
+		UserMessage:     userMessage,
+		DefaultResponse: i18n.T(al.locales.Resolve(msg.Channel, msg.ChatID, msg.SenderID).Locale, i18n.AgentNoResponse),
 		EnableSummary:   true,
 		SendResponse:    false,
+		QueueOffline:    queueOffline,
+	})
+	if errors.Is(err, errOffline) {
+		return al.queueMessage(msg)
+	}
+	if id := msg.Metadata[metaQueuedID]; id != "" {
+		// A turn cut short by shutdown is replayed after the restart
+		if ctx.Err() != nil {
+			al.offlineQueue.Release(id)
+		} else if err := al.offlineQueue.Done(id); err != nil {
+			logger.WarnCF("agent", "Failed to save offline queue", map[string]interface{}{"error": err.Error()})
+		}
+	}
+	return response, err
+}
+
+// queueMessage keeps msg until the provider is reachable again and returns
+// the notice for the user. Replayed messages were acknowledged when they
+// were first queued and stay in the queue for the next replay.
+func (al *AgentLoop) queueMessage(msg bus.InboundMessage) (string, error) {
+	if id := msg.Metadata[metaQueuedID]; id != "" {
+		al.offlineQueue.Release(id)
+		return "", nil
+	}
+	if err := al.offlineQueue.Push(msg); err != nil {
+		return "", fmt.Errorf("queueing message while offline: %w", err)
+	}
+	logger.InfoCF("agent", "Offline, message queued", map[string]interface{}{
+		"channel": msg.Channel,
+		"chat_id": msg.ChatID,
+		"queued":  al.offlineQueue.Len(),
+	})
+	return i18n.T(al.locales.Resolve(msg.Channel, msg.ChatID, msg.SenderID).Locale, i18n.AgentQueued), nil
+}
+
+// replayQueued feeds the messages queued while offline back to the agent,
+// oldest first.
+func (al *AgentLoop) replayQueued() {
+	items := al.offlineQueue.Replay()
+	if len(items) == 0 {
+		return
+	}
+	logger.InfoCF("agent", "Back online, answering queued messages", map[string]interface{}{"count": len(items)})
+	for _, item := range items {
+		msg := item.Message
+		metadata := make(map[string]string, len(msg.Metadata)+2)
+		for k, v := range msg.Metadata {
+			metadata[k] = v
+		}
+		if metadata[metaQueuedAt] == "" {
+			metadata[metaQueuedAt] = item.QueuedAt.Format(time.RFC3339)
+		}
+		metadata[metaQueuedID] = item.ID
+		msg.Metadata = metadata
+		al.bus.PublishInbound(msg)
+	}
+}
+
+// runToolCommand runs "/tool <name> [json args]" directly, without the
+// model, for the configured local tools. This keeps hardware tools usable
+// while the provider is unreachable.
+func (al *AgentLoop) runToolCommand(ctx context.Context, msg bus.InboundMessage) string {
+	lang := al.locales.Resolve(msg.Channel, msg.ChatID, msg.SenderID).Locale
+	var available []string
+	for _, name := range al.localTools {
+		if _, ok := al.tools.Get(name); ok {
+			available = append(available, name)
+		}
+	}
+	list := strings.Join(available, ", ")
+
+	name, rawArgs, _ := strings.Cut(strings.TrimSpace(strings.TrimPrefix(msg.Content, "/tool")), " ")
+	if name == "" {
+		return i18n.T(lang, i18n.AgentToolUsage, list)
+	}
+	if !slices.Contains(available, name) {
+		return i18n.T(lang, i18n.AgentToolDenied, name, list)
+	}
+	args := map[string]interface{}{}
+	if rawArgs = strings.TrimSpace(rawArgs); rawArgs != "" {
+		if err := json.Unmarshal([]byte(rawArgs), &args); err != nil {
+			return i18n.T(lang, i18n.AgentToolUsage, list)
+		}
+	}
+
+	logger.InfoCF("agent", "Running tool directly", map[string]interface{}{
+		"tool":    name,
+		"channel": msg.Channel,
+		"chat_id": msg.ChatID,
 	})
+	result := al.tools.ExecuteWithContext(ctx, name, args, msg.Channel, msg.ChatID, nil)
+	if result.ForUser != "" {
+		return result.ForUser
+	}
+	return result.ForLLM
+}
+
+// withAttachments appends a line per attachment to the user message, so the
+// session keeps the stable media reference and the model knows where to
+// find the file.
+func (al *AgentLoop) withAttachments(content string, refs []string) string {
+	if len(refs) == 0 {
+		return content
+	}
+	var sb strings.Builder
+	sb.WriteString(content)
+	for _, ref := range refs {
+		sb.WriteString("\n[attachment: ")
+		if item, ok := al.lookupMedia(ref); ok {
+			sb.WriteString(al.media.Describe(item, al.workspace))
+		} else {
+			sb.WriteString(ref)
+		}
+		sb.WriteString("]")
+	}
+	return sb.String()
+}
+
+func (al *AgentLoop) lookupMedia(ref string) (media.Item, bool) {
+	if al.media == nil || !strings.HasPrefix(ref, media.RefPrefix) {
+		return media.Item{}, false
+	}
+	return al.media.Get(ref)
 }
 
 func (al *AgentLoop) processSystemMessage(ctx context.Context, msg bus.InboundMessage) (string, error) {
@@ -296,6 +702,22 @@ func (al *AgentLoop) processSystemMessage(ctx context.Context, msg bus.InboundMe
 		originChannel = "cli"
 	}
 
+	// Results of background peer tasks are reported by the agent in the
+	// chat that asked for them
+	if strings.HasPrefix(msg.SenderID, "peer:") && !constants.IsInternalChannel(originChannel) {
+		originChatID := strings.TrimPrefix(msg.ChatID, originChannel+":")
+		_, err := al.runAgentLoop(ctx, processOptions{
+			SessionKey:      fmt.Sprintf("%s:%s", originChannel, originChatID),
+			Channel:         originChannel,
+			ChatID:          originChatID,
+			UserMessage:     msg.Content,
+			DefaultResponse: i18n.T(i18n.ForChat(originChannel, originChatID), i18n.AgentNoResponse),
+			EnableSummary:   true,
+			SendResponse:    true,
+		})
+		return "", err
+	}
+
 	// Extract subagent result from message content
 	// Format: "Task 'label' completed.\n\nResult:\n<actual content>"
 	content := msg.Content
@@ -341,31 +763,67 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, opts processOptions) (str
 		}
 	}
 
+	// Tenants keep their own sessions, memory and skills, within their quota
+	sc := al.scopeFor(ctx)
+	if sc.tenant != nil {
+		if err := sc.tenant.CheckTokens(); err != nil {
+			return "", err
+		}
+	}
+
 	// 1. Update tool contexts
 	al.updateToolContexts(opts.Channel, opts.ChatID)
 
+	// The turn can be cancelled from `picoclaw top` through its context
+	ctx, turn := al.monitor.BeginTurn(ctx, opts.SessionKey, opts.Channel, opts.ChatID)
+	defer al.monitor.EndTurn(turn)
+
+	// Tools format and schedule times in the user's zone
+	settings := al.locales.Resolve(opts.Channel, opts.ChatID, opts.SenderID)
+	ctx = locale.WithTurn(ctx, locale.Turn{
+		Channel:  opts.Channel,
+		ChatID:   opts.ChatID,
+		SenderID: opts.SenderID,
+		Settings: settings,
+	})
+
 	// 2. Build messages (skip history for heartbeat)
 	var history []providers.Message
 	var summary string
+	var pins []string
 	if !opts.NoHistory {
-		history = al.sessions.GetHistory(opts.SessionKey)
-		summary = al.sessions.GetSummary(opts.SessionKey)
+		history = sc.sessions.GetHistory(opts.SessionKey)
+		summary = sc.sessions.GetSummary(opts.SessionKey)
+		pins = sc.sessions.GetPins(opts.SessionKey)
+		// The pin tool works on the turn's session
+		ctx = session.WithContext(ctx, sc.sessions, opts.SessionKey)
 	}
-	messages := al.contextBuilder.BuildMessages(
+	messages := sc.contextBuilder.BuildMessages(
 		history,
 		summary,
+		pins,
 		opts.UserMessage,
 		nil,
 		opts.Channel,
 		opts.ChatID,
+		settings,
 	)
 
 	// 3. Save user message to session
-	al.sessions.AddMessage(opts.SessionKey, "user", opts.UserMessage)
+	sc.sessions.AddMessage(opts.SessionKey, "user", opts.UserMessage)
 
 	// 4. Run LLM iteration loop
-	finalContent, iteration, err := al.runLLMIteration(ctx, messages, opts)
+	finalContent, iteration, err := al.runLLMIteration(ctx, turn, sc, messages, opts)
 	if err != nil {
+		if ctx.Err() != nil {
+			// Providers don't always wrap the context error; report it directly
+			return "", ctx.Err()
+		}
+		if opts.QueueOffline && !al.online.Check(ctx) {
+			// The turn is replayed once back online; drop what it added so far
+			sc.sessions.Rewind(opts.SessionKey, len(history))
+			return "", errOffline
+		}
 		return "", err
 	}
 
@@ -378,12 +836,17 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, opts processOptions) (str
 	}
 
 	// 6. Save final assistant message to session
-	al.sessions.AddMessage(opts.SessionKey, "assistant", finalContent)
-	al.sessions.Save(opts.SessionKey)
+	sc.sessions.AddMessage(opts.SessionKey, "assistant", finalContent)
+	sc.sessions.Save(opts.SessionKey)
+
+	// Keep the turn for rating; heartbeats and internal channels are not rated
+	if al.feedback != nil && !opts.NoHistory && !constants.IsInternalChannel(opts.Channel) {
+		al.recordTurn(opts, history, summary, finalContent, iteration)
+	}
 
 	// 7. Optional: summarization
 	if opts.EnableSummary {
-		al.maybeSummarize(opts.SessionKey)
+		al.maybeSummarize(sc.sessions, opts.SessionKey)
 	}
 
 	// 8. Optional: send response via bus
@@ -409,12 +872,17 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, opts processOptions) (str
 
 // runLLMIteration executes the LLM call loop with tool handling.
 // Returns the final content, iteration count, and any error.
-func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.Message, opts processOptions) (string, int, error) {
+func (al *AgentLoop) runLLMIteration(ctx context.Context, turn *monitor.Turn, sc *scope, messages []providers.Message, opts processOptions) (string, int, error) {
 	iteration := 0
 	var finalContent string
+	act := activityFrom(ctx)
 
 	for iteration < al.maxIterations {
+		if err := ctx.Err(); err != nil {
+			return "", iteration, err
+		}
 		iteration++
+		al.monitor.SetIteration(turn, iteration)
 
 		logger.DebugCF("agent", "LLM iteration",
 			map[string]interface{}{
@@ -423,7 +891,7 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 			})
 
 		// Build tool definitions
-		providerToolDefs := al.tools.ToProviderDefs()
+		providerToolDefs := sc.toolDefs(al.tools)
 
 		// Log LLM request details
 		logger.DebugCF("agent", "LLM request",
@@ -445,7 +913,10 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 				"tools_json":    formatToolsForLog(providerToolDefs),
 			})
 
-		// Call LLM
+		// Call LLM; from the second iteration on it works through tool results
+		if iteration > 1 {
+			act.thinking()
+		}
 		response, err := al.provider.Chat(ctx, messages, providerToolDefs, al.model, map[string]interface{}{
 			"max_tokens":  8192,
 			"temperature": 0.7,
@@ -459,6 +930,12 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 				})
 			return "", iteration, fmt.Errorf("LLM call failed: %w", err)
 		}
+		if response.Usage != nil {
+			al.monitor.RecordUsage(response.Usage.PromptTokens, response.Usage.CompletionTokens)
+			if sc.tenant != nil {
+				sc.tenant.AddTokens(response.Usage.PromptTokens + response.Usage.CompletionTokens)
+			}
+		}
 
 		// Check if no tool calls - we're done
 		if len(response.ToolCalls) == 0 {
@@ -502,7 +979,7 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 		messages = append(messages, assistantMsg)
 
 		// Save assistant message with tool calls to session
-		al.sessions.AddFullMessage(opts.SessionKey, assistantMsg)
+		sc.sessions.AddFullMessage(opts.SessionKey, assistantMsg)
 
 		// Execute tool calls
 		for _, tc := range response.ToolCalls {
@@ -531,6 +1008,8 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 				}
 			}
 
+			al.monitor.SetTool(turn, tc.Name)
+			act.tool(tc.Name)
 			toolResult := al.tools.ExecuteWithContext(ctx, tc.Name, tc.Arguments, opts.Channel, opts.ChatID, asyncCallback)
 
 			// Send ForUser content to user immediately if not Silent
@@ -561,7 +1040,7 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 			messages = append(messages, toolResultMsg)
 
 			// Save tool result message to session
-			al.sessions.AddFullMessage(opts.SessionKey, toolResultMsg)
+			sc.sessions.AddFullMessage(opts.SessionKey, toolResultMsg)
 		}
 	}
 
@@ -588,22 +1067,65 @@ func (al *AgentLoop) updateToolContexts(channel, chatID string) {
 	}
 }
 
+type summarizingKey struct {
+	sessions *session.SessionManager
+	key      string
+}
+
 // maybeSummarize triggers summarization if the session history exceeds thresholds.
-func (al *AgentLoop) maybeSummarize(sessionKey string) {
-	newHistory := al.sessions.GetHistory(sessionKey)
+func (al *AgentLoop) maybeSummarize(sessions *session.SessionManager, sessionKey string) {
+	newHistory := sessions.GetHistory(sessionKey)
 	tokenEstimate := al.estimateTokens(newHistory)
 	threshold := al.contextWindow * 75 / 100
 
 	if len(newHistory) > 20 || tokenEstimate > threshold {
-		if _, loading := al.summarizing.LoadOrStore(sessionKey, true); !loading {
+		// Tenants can have sessions with the same key
+		key := summarizingKey{sessions, sessionKey}
+		if _, loading := al.summarizing.LoadOrStore(key, true); !loading {
 			go func() {
-				defer al.summarizing.Delete(sessionKey)
-				al.summarizeSession(sessionKey)
+				defer al.summarizing.Delete(key)
+				release, err := al.governor.Acquire(context.Background())
+				if err != nil {
+					return
+				}
+				defer release()
+				al.summarizeSession(sessions, sessionKey)
 			}()
 		}
 	}
 }
 
+// Sessions returns the session store, for inspection by the admin dashboard.
+func (al *AgentLoop) Sessions() *session.SessionManager {
+	return al.sessions
+}
+
+// Monitor returns the live activity monitor shown by `picoclaw top`.
+func (al *AgentLoop) Monitor() *monitor.Monitor {
+	return al.monitor
+}
+
+// MediaStore returns the store of inbound attachments, or nil if it could
+// not be opened.
+func (al *AgentLoop) MediaStore() *media.Store {
+	return al.media
+}
+
+// Locales returns the per-user and per-chat time zone and locale settings.
+func (al *AgentLoop) Locales() *locale.Store {
+	return al.locales
+}
+
+// Subagents returns the manager of background subagent tasks.
+func (al *AgentLoop) Subagents() *tools.SubagentManager {
+	return al.subagents
+}
+
+// SkillsLoader returns the loader used to discover skills.
+func (al *AgentLoop) SkillsLoader() *skills.SkillsLoader {
+	return al.contextBuilder.skillsLoader
+}
+
 // GetStartupInfo returns information about loaded tools and skills for logging.
 func (al *AgentLoop) GetStartupInfo() map[string]interface{} {
 	info := make(map[string]interface{})
@@ -618,6 +1140,14 @@ func (al *AgentLoop) GetStartupInfo() map[string]interface{} {
 	// Skills info
 	info["skills"] = al.contextBuilder.GetSkillsInfo()
 
+	if al.online != nil {
+		info["connectivity"] = map[string]interface{}{
+			"online":  al.online.Online(),
+			"targets": al.online.Targets(),
+			"queued":  al.offlineQueue.Len(),
+		}
+	}
+
 	return info
 }
 
@@ -673,12 +1203,12 @@ func formatToolsForLog(tools []providers.ToolDefinition) string {
 }
 
 // summarizeSession summarizes the conversation history for a session.
-func (al *AgentLoop) summarizeSession(sessionKey string) {
+func (al *AgentLoop) summarizeSession(sessions *session.SessionManager, sessionKey string) {
 	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
 	defer cancel()
 
-	history := al.sessions.GetHistory(sessionKey)
-	summary := al.sessions.GetSummary(sessionKey)
+	history := sessions.GetHistory(sessionKey)
+	summary := sessions.GetSummary(sessionKey)
 
 	// Keep last 4 messages for continuity
 	if len(history) <= 4 {
@@ -741,9 +1271,9 @@ func (al *AgentLoop) summarizeSession(sessionKey string) {
 	}
 
 	if finalSummary != "" {
-		al.sessions.SetSummary(sessionKey, finalSummary)
-		al.sessions.TruncateHistory(sessionKey, 4)
-		al.sessions.Save(sessionKey)
+		sessions.SetSummary(sessionKey, finalSummary)
+		sessions.TruncateHistory(sessionKey, 4)
+		sessions.Save(sessionKey)
 	}
 }
 
@@ -2,14 +2,22 @@ package agent
 
 import (
 	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/session"
+	"github.com/sipeed/picoclaw/pkg/tenant"
 	"github.com/sipeed/picoclaw/pkg/tools"
 )
 
@@ -527,3 +535,629 @@ func TestToolResult_UserFacingToolDoesSendMessage(t *testing.T) {
 		t.Errorf("Expected 'Command output: hello world', got: %s", response)
 	}
 }
+
+// blockingProvider blocks until the request is cancelled.
+type blockingProvider struct {
+	started chan struct{}
+}
+
+func (m *blockingProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	close(m.started)
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (m *blockingProvider) GetDefaultModel() string {
+	return "mock-model"
+}
+
+func TestAgentLoop_CancelTurnFromMonitor(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+
+	provider := &blockingProvider{started: make(chan struct{})}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := al.processMessage(context.Background(), bus.InboundMessage{
+			Channel:    "test",
+			ChatID:     "chat1",
+			Content:    "hello",
+			SessionKey: "test-session",
+		})
+		errCh <- err
+	}()
+
+	select {
+	case <-provider.started:
+	case <-time.After(responseTimeout):
+		t.Fatal("LLM was never called")
+	}
+
+	turns := al.Monitor().Turns()
+	if len(turns) != 1 {
+		t.Fatalf("Expected 1 active turn, got %d", len(turns))
+	}
+	if turns[0].SessionKey != "test-session" || turns[0].Iteration != 1 {
+		t.Errorf("Unexpected turn: %+v", turns[0])
+	}
+	if !al.Monitor().CancelTurn(turns[0].ID) {
+		t.Fatal("CancelTurn returned false")
+	}
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	case <-time.After(responseTimeout):
+		t.Fatal("Turn did not stop after cancel")
+	}
+	if n := len(al.Monitor().Turns()); n != 0 {
+		t.Errorf("Expected no active turns, got %d", n)
+	}
+}
+
+// promptRecorder records the system prompt of each request.
+type promptRecorder struct {
+	prompts []string
+}
+
+func (p *promptRecorder) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	p.prompts = append(p.prompts, messages[0].Content)
+	return &providers.LLMResponse{Content: "ok"}, nil
+}
+
+func (p *promptRecorder) GetDefaultModel() string {
+	return "mock-model"
+}
+
+func TestAgentLoop_UsesSenderTimeZone(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+				Timezone:          "UTC",
+			},
+		},
+	}
+	provider := &promptRecorder{}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+	helper := testHelper{al: al}
+
+	helper.executeAndGetResponse(t, context.Background(), bus.InboundMessage{
+		Channel: "teams", SenderID: "u1", ChatID: "c1", SessionKey: "teams:c1", Content: "hi",
+		Metadata: map[string]string{"timezone": "Asia/Tokyo", "locale": "ja-JP"},
+	})
+	helper.executeAndGetResponse(t, context.Background(), bus.InboundMessage{
+		Channel: "teams", SenderID: "u2", ChatID: "c1", SessionKey: "teams:c1", Content: "hi",
+	})
+
+	if len(provider.prompts) != 2 {
+		t.Fatalf("got %d requests", len(provider.prompts))
+	}
+	for _, want := range []string{"Time zone: Asia/Tokyo (UTC+09:00)", "User locale: ja-JP"} {
+		if !strings.Contains(provider.prompts[0], want) {
+			t.Errorf("prompt for u1 missing %q", want)
+		}
+	}
+	if !strings.Contains(provider.prompts[1], "Time zone: UTC (UTC+00:00)") {
+		t.Error("other users should get the default zone")
+	}
+}
+
+// uplinkProvider fails while its uplink is down, like a provider behind a
+// lost connection, and reports its endpoint for probing.
+type uplinkProvider struct {
+	endpoint string
+	down     atomic.Bool
+	calls    atomic.Int32
+	last     atomic.Value // last user message
+}
+
+func (p *uplinkProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	p.calls.Add(1)
+	if p.down.Load() {
+		return nil, errors.New("dial tcp: network is unreachable")
+	}
+	p.last.Store(messages[len(messages)-1].Content)
+	return &providers.LLMResponse{Content: "answered"}, nil
+}
+
+func (p *uplinkProvider) GetDefaultModel() string {
+	return "mock-model"
+}
+
+func (p *uplinkProvider) Endpoint() string {
+	return p.endpoint
+}
+
+func TestAgentLoop_QueuesWhileOffline(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	addr := srv.Listener.Addr().String()
+	provider := &uplinkProvider{endpoint: srv.URL}
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+		Connectivity: config.ConnectivityConfig{Enabled: true, Interval: 60, Timeout: 1, MaxQueue: 10},
+	}
+	msgBus := bus.NewMessageBus()
+	al := NewAgentLoop(cfg, msgBus, provider)
+	helper := testHelper{al: al}
+	ctx := context.Background()
+
+	// The uplink drops: the failed turn is queued instead of reported
+	srv.Close()
+	provider.down.Store(true)
+	msg := bus.InboundMessage{Channel: "telegram", SenderID: "7", ChatID: "42", SessionKey: "telegram:42", Content: "water the plants"}
+	if got := helper.executeAndGetResponse(t, ctx, msg); !strings.Contains(got, "queued") {
+		t.Fatalf("response = %q, want the queued notice", got)
+	}
+	if n := len(al.Sessions().GetHistory("telegram:42")); n != 0 {
+		t.Errorf("history has %d messages, want the failed turn dropped", n)
+	}
+
+	// While offline, turns are queued without calling the provider
+	msg.Content = "and close the window"
+	helper.executeAndGetResponse(t, ctx, msg)
+	if provider.calls.Load() != 1 {
+		t.Errorf("provider called %d times, want 1", provider.calls.Load())
+	}
+
+	// The uplink returns: queued turns are replayed in order
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("cannot listen on %s again: %v", addr, err)
+	}
+	srv = &httptest.Server{Listener: ln, Config: &http.Server{Handler: http.NotFoundHandler()}}
+	srv.Start()
+	defer srv.Close()
+	provider.down.Store(false)
+	if !al.online.Check(ctx) {
+		t.Fatal("endpoint still unreachable")
+	}
+
+	for i, want := range []string{"water the plants", "and close the window"} {
+		waitCtx, cancel := context.WithTimeout(ctx, responseTimeout)
+		replayed, ok := msgBus.ConsumeInbound(waitCtx)
+		cancel()
+		if !ok {
+			t.Fatalf("%q was not replayed", want)
+		}
+		// Replayed messages leave the queue only once answered
+		if n := al.offlineQueue.Len(); n != 2-i {
+			t.Errorf("queue holds %d messages before the replayed turn, want %d", n, 2-i)
+		}
+		if got := helper.executeAndGetResponse(t, ctx, replayed); got != "answered" {
+			t.Errorf("replayed response = %q", got)
+		}
+		if last, _ := provider.last.Load().(string); !strings.Contains(last, "while you were offline") || !strings.HasSuffix(last, want) {
+			t.Errorf("replayed prompt = %q", last)
+		}
+	}
+	if n := al.offlineQueue.Len(); n != 0 {
+		t.Errorf("queue holds %d messages after the replay", n)
+	}
+}
+
+func TestAgentLoop_ToolCommand(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+		Connectivity: config.ConnectivityConfig{LocalTools: config.FlexibleStringSlice{"mock_custom"}},
+	}
+	provider := &uplinkProvider{}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+	al.RegisterTool(&mockCustomTool{})
+	helper := testHelper{al: al}
+
+	msg := bus.InboundMessage{Channel: "telegram", ChatID: "42", SessionKey: "telegram:42", Content: "/tool mock_custom {}"}
+	if got := helper.executeAndGetResponse(t, context.Background(), msg); got != "Custom tool executed" {
+		t.Errorf("response = %q", got)
+	}
+	msg.Content = "/tool exec {\"command\": \"reboot\"}"
+	if got := helper.executeAndGetResponse(t, context.Background(), msg); !strings.Contains(got, "can't be run directly") {
+		t.Errorf("response = %q, want a refusal", got)
+	}
+	if provider.calls.Load() != 0 {
+		t.Error("tool commands should not reach the provider")
+	}
+}
+
+func TestAgentLoop_CapturesFeedback(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+		Feedback: config.FeedbackConfig{Enabled: true, MaxTurns: 10},
+	}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &simpleMockProvider{response: "It is sunny in Paris."})
+	helper := testHelper{al: al}
+
+	msg := bus.InboundMessage{Channel: "telegram", SenderID: "7", ChatID: "42", SessionKey: "telegram:42"}
+	if _, handled := al.captureFeedback(bus.InboundMessage{Channel: "telegram", ChatID: "42", Content: "/feedback bad"}); !handled {
+		t.Fatal("/feedback not handled")
+	}
+	msg.Content = "Weather here?"
+	helper.executeAndGetResponse(t, context.Background(), msg)
+
+	turn, ok := al.Feedback().Last("telegram", "42")
+	if !ok || turn.Prompt != "Weather here?" || turn.Response != "It is sunny in Paris." {
+		t.Fatalf("recorded turn = %+v, %v", turn, ok)
+	}
+
+	reaction := msg
+	reaction.Content, reaction.Metadata = "👎", map[string]string{"reaction": "👎", "trace_id": turn.TraceID}
+	if reply, handled := al.captureFeedback(reaction); !handled || reply != "" {
+		t.Errorf("reaction: %q, %v", reply, handled)
+	}
+	// Reactions to other messages are no rating
+	reaction.Metadata = map[string]string{"reaction": "👍"}
+	if _, handled := al.captureFeedback(reaction); !handled {
+		t.Error("reaction to another message reached the agent")
+	}
+	if ratings, _ := al.Feedback().Ratings(); len(ratings) != 1 {
+		t.Errorf("ratings after reactions = %+v", ratings)
+	}
+	reaction.Content, reaction.Metadata = "👀", map[string]string{"reaction": "👀"}
+	if _, handled := al.captureFeedback(reaction); handled {
+		t.Error("a neutral reaction was taken as a rating")
+	}
+	msg.Content = "/feedback I live in Lyon"
+	if reply, handled := al.captureFeedback(msg); !handled || reply == "" {
+		t.Errorf("/feedback: %q, %v", reply, handled)
+	}
+	msg.Content = "that's wrong"
+	if _, handled := al.captureFeedback(msg); handled {
+		t.Error("complaints should still reach the agent")
+	}
+
+	bad, err := al.Feedback().Dataset(-1, -1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bad) != 1 || len(bad[0].Comments) != 2 || len(bad[0].Sources) != 3 {
+		t.Errorf("dataset = %+v", bad)
+	}
+}
+
+func TestAgentLoop_RelayGroupCommands(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+		Feedback: config.FeedbackConfig{Enabled: true, MaxTurns: 10},
+		Translate: config.TranslateConfig{
+			Enabled:     true,
+			BatchWindow: 3600,
+			MaxBatch:    10,
+			Groups:      []config.TranslateGroup{{Channel: "telegram", ChatID: "99", Languages: config.FlexibleStringSlice{"en", "fr"}}},
+		},
+	}
+	provider := &simpleMockProvider{response: `{"messages":[{"id":1,"lang":"en","translations":{"fr":"Bonjour à tous"}}]}`}
+	msgBus := bus.NewMessageBus()
+	al := NewAgentLoop(cfg, msgBus, provider)
+	acked := 0
+	al.AddInterceptor(func(msg bus.InboundMessage) (string, bool) {
+		if msg.Content != "done" {
+			return "", false
+		}
+		acked++
+		return "", true
+	})
+
+	msg := bus.InboundMessage{Channel: "telegram", SenderID: "7", ChatID: "99", Content: "done"}
+	if !al.intercept(msg) || acked != 1 {
+		t.Fatal("a reminder ack in a relay group did not reach its interceptor")
+	}
+	msg.Content = "/feedback"
+	if !al.intercept(msg) {
+		t.Fatal("/feedback not handled")
+	}
+	if _, outbound := msgBus.QueueDepth(); outbound != 1 {
+		t.Fatalf("/feedback in a relay group got %d replies, want 1", outbound)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	msgBus.SubscribeOutbound(ctx)
+
+	msg.Content = "Hello all"
+	if !al.intercept(msg) {
+		t.Fatal("the relay let a group message through to the agent")
+	}
+	if _, outbound := msgBus.QueueDepth(); outbound != 0 {
+		t.Fatal("translated before the batch window")
+	}
+
+	// Stopping translates what is still queued
+	al.Stop()
+	out, ok := msgBus.SubscribeOutbound(ctx)
+	if !ok || !strings.Contains(out.Content, "Bonjour à tous") {
+		t.Fatalf("translation after Stop = %q, %v", out.Content, ok)
+	}
+}
+
+func TestAgentLoop_TenantWorkspaces(t *testing.T) {
+	workspace := t.TempDir()
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         workspace,
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+		Tenants: config.TenantsConfig{
+			Enabled:         true,
+			Scope:           "sender",
+			MaxDiskMB:       1,
+			MaxTokensPerDay: 1000,
+			Admins:          config.FlexibleStringSlice{"telegram:1"},
+		},
+	}
+	provider := &promptRecorder{}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+
+	msg := bus.InboundMessage{Channel: "telegram", SenderID: "7", ChatID: "42", SessionKey: "telegram:42", Content: "hi"}
+	ctx, err := al.withTenant(context.Background(), msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tn := tenant.FromContext(ctx)
+	if tn == nil {
+		t.Fatal("no tenant for a user")
+	}
+	if _, err := al.processMessage(ctx, msg); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(provider.prompts[0], tn.Workspace()) {
+		t.Error("the prompt does not point at the tenant's workspace")
+	}
+	if len(al.scopeFor(ctx).sessions.GetHistory("telegram:42")) != 2 {
+		t.Error("turn not kept in the tenant's session")
+	}
+	if len(al.Sessions().GetHistory("telegram:42")) != 0 {
+		t.Error("tenant turn leaked into the main session")
+	}
+
+	tn.AddTokens(1000)
+	if _, err := al.processMessage(ctx, msg); !errors.Is(err, tenant.ErrQuota) {
+		t.Errorf("turn over the token quota: %v", err)
+	}
+
+	admin := bus.InboundMessage{Channel: "telegram", SenderID: "1", ChatID: "1", Content: "/tenants"}
+	if ctx, _ := al.withTenant(context.Background(), admin); tenant.FromContext(ctx) != nil {
+		t.Error("admin put into a tenant")
+	}
+	if reply, handled := al.tenantCommand(admin); !handled || !strings.Contains(reply, tn.ID()) {
+		t.Errorf("/tenants = %q, %v", reply, handled)
+	}
+	msg.Content = "/tenants purge " + tn.ID()
+	if _, handled := al.tenantCommand(msg); handled {
+		t.Error("a tenant ran an admin command")
+	}
+	admin.Content = "/tenants purge " + tn.ID()
+	if reply, _ := al.tenantCommand(admin); !strings.Contains(reply, tn.ID()) {
+		t.Errorf("purge reply = %q", reply)
+	}
+	if _, err := os.Stat(tn.Workspace()); !os.IsNotExist(err) {
+		t.Error("purged workspace still there")
+	}
+}
+
+func TestAgentLoop_GovernorEvictsSessions(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+		Memory: config.MemoryConfig{MaxReadKB: 64, MaxSessions: 1, MaxBackground: 1, PressurePercent: 80, CheckInterval: 1},
+	}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &mockProvider{})
+
+	al.Sessions().AddMessage("telegram:1", "user", "old")
+	time.Sleep(2 * time.Millisecond)
+	al.Sessions().AddMessage("telegram:2", "user", "new")
+
+	status := al.Governor().Check()
+	if status.Evicted["sessions"] != 1 {
+		t.Errorf("evicted = %v, want one session", status.Evicted)
+	}
+	if inMemory, onDisk := al.Sessions().Resident(); inMemory != 1 || onDisk != 1 {
+		t.Errorf("resident = %d in memory, %d on disk", inMemory, onDisk)
+	}
+	if h := al.Sessions().GetHistory("telegram:1"); len(h) != 1 || h[0].Content != "old" {
+		t.Errorf("evicted session history = %+v", h)
+	}
+}
+
+func TestAgentLoop_PinnedNotes(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+				MaxPinnedChars:    100,
+			},
+		},
+	}
+	provider := &promptRecorder{}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+	helper := testHelper{al: al}
+	ctx := context.Background()
+	msg := bus.InboundMessage{Channel: "telegram", SenderID: "7", ChatID: "42", SessionKey: "telegram:42"}
+	send := func(content string) string {
+		msg.Content = content
+		return helper.executeAndGetResponse(t, ctx, msg)
+	}
+
+	if got := send("/pin Osaka trip, budget ¥300k"); !strings.Contains(got, "Pinned") {
+		t.Errorf("/pin = %q", got)
+	}
+	if got := send("/pin " + strings.Repeat("x", 100)); !strings.Contains(got, "100 characters") {
+		t.Errorf("/pin over the cap = %q", got)
+	}
+	if len(provider.prompts) != 0 {
+		t.Fatal("pin commands reached the provider")
+	}
+
+	// The note outlives the history it was pinned in
+	send("where do we stay?")
+	al.Sessions().SetSummary("telegram:42", "They discussed hotels.")
+	al.Sessions().TruncateHistory("telegram:42", 0)
+	send("and food?")
+	if !strings.Contains(provider.prompts[1], "## Pinned Notes") || !strings.Contains(provider.prompts[1], "- Osaka trip, budget ¥300k") {
+		t.Errorf("pinned note missing from prompt:\n%s", provider.prompts[1])
+	}
+
+	// The agent manages the same notes with the pin tool
+	toolCtx := session.WithContext(ctx, al.Sessions(), "telegram:42")
+	result := al.tools.ExecuteWithContext(toolCtx, "pin", map[string]interface{}{"action": "add", "note": "vegetarian"}, "telegram", "42", nil)
+	if result.IsError {
+		t.Fatalf("pin tool: %s", result.ForLLM)
+	}
+	if got := send("/pins"); !strings.Contains(got, "1. Osaka trip") || !strings.Contains(got, "2. vegetarian") {
+		t.Errorf("/pins = %q", got)
+	}
+	if got := send("/unpin 1"); !strings.Contains(got, "Osaka trip") {
+		t.Errorf("/unpin = %q", got)
+	}
+	if got := send("/unpin all"); !strings.Contains(got, "1 notes") {
+		t.Errorf("/unpin all = %q", got)
+	}
+}
+
+// slowToolProvider calls slow_tool once for messages asking for it.
+type slowToolProvider struct{}
+
+func (p *slowToolProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	last := messages[len(messages)-1]
+	if last.Role == "user" && strings.Contains(last.Content, "take your time") {
+		return &providers.LLMResponse{ToolCalls: []providers.ToolCall{{ID: "call-1", Name: "slow_tool"}}}, nil
+	}
+	return &providers.LLMResponse{Content: "done"}, nil
+}
+
+func (p *slowToolProvider) GetDefaultModel() string {
+	return "mock-model"
+}
+
+type slowTool struct{ mockCustomTool }
+
+func (t *slowTool) Name() string { return "slow_tool" }
+
+func (t *slowTool) Execute(ctx context.Context, args map[string]interface{}) *tools.ToolResult {
+	time.Sleep(1500 * time.Millisecond)
+	return tools.SilentResult("ok")
+}
+
+func TestAgentLoop_Progress(t *testing.T) {
+	off := false
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+		Progress: config.ProgressConfig{
+			Typing:   true,
+			Status:   true,
+			Interval: 1,
+			Channels: map[string]config.ProgressOverride{"slack": {Typing: &off, Status: &off}},
+		},
+	}
+	mb := bus.NewMessageBus()
+	al := NewAgentLoop(cfg, mb, &slowToolProvider{})
+	al.RegisterTool(&slowTool{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go al.Run(ctx)
+	defer al.Stop()
+
+	// turn returns what a turn published up to its reply and the extra
+	// messages after it.
+	turn := func(channel, content string, extra int) string {
+		mb.PublishInbound(bus.InboundMessage{Channel: channel, SenderID: "7", ChatID: "42", Content: content, SessionKey: channel + ":42"})
+		var got []string
+		for replied := false; !replied || extra > 0; {
+			subCtx, subCancel := context.WithTimeout(ctx, responseTimeout)
+			msg, ok := mb.SubscribeOutbound(subCtx)
+			subCancel()
+			if !ok {
+				break
+			}
+			if replied {
+				extra--
+			}
+			switch {
+			case msg.Activity == nil:
+				got = append(got, "reply "+msg.Content)
+				replied = true
+			case msg.Activity.Kind == bus.ActivityStatus:
+				got = append(got, "status "+msg.Activity.Status)
+			default:
+				got = append(got, msg.Activity.Kind)
+			}
+		}
+		return strings.Join(got, " | ")
+	}
+
+	// The tool status is shown once the interval passed; the thinking
+	// status that would follow is cut short by the reply.
+	if got := turn("telegram", "take your time", 1); got != "typing | status 🛠️ Using slow_tool… | reply done | done" {
+		t.Errorf("slow turn published: %s", got)
+	}
+	// A quick turn only types, and a channel with progress off gets the
+	// reply alone.
+	if got := turn("telegram", "hi", 0); got != "typing | reply done" {
+		t.Errorf("quick turn published: %s", got)
+	}
+	if got := turn("slack", "take your time", 0); got != "reply done" {
+		t.Errorf("turn with progress off published: %s", got)
+	}
+}
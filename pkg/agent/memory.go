@@ -39,9 +39,10 @@ func NewMemoryStore(workspace string) *MemoryStore {
 }
 
 // getTodayFile returns the path to today's daily note file (memory/YYYYMM/YYYYMMDD.md).
-func (ms *MemoryStore) getTodayFile() string {
-	today := time.Now().Format("20060102") // YYYYMMDD
-	monthDir := today[:6]                  // YYYYMM
+// Days follow the zone of now, so notes split at the user's midnight.
+func (ms *MemoryStore) getTodayFile(now time.Time) string {
+	today := now.Format("20060102") // YYYYMMDD
+	monthDir := today[:6]           // YYYYMM
 	filePath := filepath.Join(ms.memoryDir, monthDir, today+".md")
 	return filePath
 }
@@ -62,8 +63,8 @@ func (ms *MemoryStore) WriteLongTerm(content string) error {
 
 // ReadToday reads today's daily note.
 // Returns empty string if the file doesn't exist.
-func (ms *MemoryStore) ReadToday() string {
-	todayFile := ms.getTodayFile()
+func (ms *MemoryStore) ReadToday(now time.Time) string {
+	todayFile := ms.getTodayFile(now)
 	if data, err := os.ReadFile(todayFile); err == nil {
 		return string(data)
 	}
@@ -72,8 +73,8 @@ func (ms *MemoryStore) ReadToday() string {
 
 // AppendToday appends content to today's daily note.
 // If the file doesn't exist, it creates a new file with a date header.
-func (ms *MemoryStore) AppendToday(content string) error {
-	todayFile := ms.getTodayFile()
+func (ms *MemoryStore) AppendToday(now time.Time, content string) error {
+	todayFile := ms.getTodayFile(now)
 
 	// Ensure month directory exists
 	monthDir := filepath.Dir(todayFile)
@@ -87,7 +88,7 @@ func (ms *MemoryStore) AppendToday(content string) error {
 	var newContent string
 	if existingContent == "" {
 		// Add header for new day
-		header := fmt.Sprintf("# %s\n\n", time.Now().Format("2006-01-02"))
+		header := fmt.Sprintf("# %s\n\n", now.Format("2006-01-02"))
 		newContent = header + content
 	} else {
 		// Append to existing content
@@ -97,13 +98,13 @@ func (ms *MemoryStore) AppendToday(content string) error {
 	return os.WriteFile(todayFile, []byte(newContent), 0644)
 }
 
-// GetRecentDailyNotes returns daily notes from the last N days.
+// GetRecentDailyNotes returns daily notes from the last N days up to now.
 // Contents are joined with "---" separator.
-func (ms *MemoryStore) GetRecentDailyNotes(days int) string {
+func (ms *MemoryStore) GetRecentDailyNotes(now time.Time, days int) string {
 	var notes []string
 
 	for i := 0; i < days; i++ {
-		date := time.Now().AddDate(0, 0, -i)
+		date := now.AddDate(0, 0, -i)
 		dateStr := date.Format("20060102") // YYYYMMDD
 		monthDir := dateStr[:6]            // YYYYMM
 		filePath := filepath.Join(ms.memoryDir, monthDir, dateStr+".md")
@@ -129,8 +130,8 @@ func (ms *MemoryStore) GetRecentDailyNotes(days int) string {
 }
 
 // GetMemoryContext returns formatted memory context for the agent prompt.
-// Includes long-term memory and recent daily notes.
-func (ms *MemoryStore) GetMemoryContext() string {
+// Includes long-term memory and the daily notes of the days before now.
+func (ms *MemoryStore) GetMemoryContext(now time.Time) string {
 	var parts []string
 
 	// Long-term memory
@@ -140,7 +141,7 @@ func (ms *MemoryStore) GetMemoryContext() string {
 	}
 
 	// Recent daily notes (last 3 days)
-	recentNotes := ms.GetRecentDailyNotes(3)
+	recentNotes := ms.GetRecentDailyNotes(now, 3)
 	if recentNotes != "" {
 		parts = append(parts, "## Recent Daily Notes\n\n"+recentNotes)
 	}
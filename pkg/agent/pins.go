@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/i18n"
+	"github.com/sipeed/picoclaw/pkg/session"
+)
+
+// pinCommand runs /pin <note>, /pins and /unpin <n>|all on the session of
+// msg, without the model.
+func (al *AgentLoop) pinCommand(ctx context.Context, msg bus.InboundMessage) (reply string, handled bool) {
+	name, arg, _ := strings.Cut(strings.TrimSpace(msg.Content), " ")
+	arg = strings.TrimSpace(arg)
+	if name != "/pin" && name != "/pins" && name != "/unpin" {
+		return "", false
+	}
+	lang := al.locales.Resolve(msg.Channel, msg.ChatID, msg.SenderID).Locale
+	sessions := al.scopeFor(ctx).sessions
+	key := msg.SessionKey
+
+	switch {
+	case name == "/pins" || (name == "/pin" && arg == ""):
+		pins := sessions.GetPins(key)
+		if len(pins) == 0 {
+			return i18n.T(lang, i18n.PinNone), true
+		}
+		var sb strings.Builder
+		for i, p := range pins {
+			fmt.Fprintf(&sb, "\n%d. %s", i+1, p)
+		}
+		return i18n.T(lang, i18n.PinList, sb.String()), true
+	case name == "/pin":
+		if err := sessions.Pin(key, arg, al.maxPinnedChars); errors.Is(err, session.ErrPinsFull) {
+			return i18n.T(lang, i18n.PinFull, al.maxPinnedChars), true
+		}
+		sessions.Save(key)
+		return i18n.T(lang, i18n.PinAdded, arg), true
+	case arg == "all":
+		n := sessions.ClearPins(key)
+		sessions.Save(key)
+		return i18n.T(lang, i18n.PinCleared, n), true
+	case arg == "":
+		return i18n.T(lang, i18n.PinUsage), true
+	}
+	n, err := strconv.Atoi(arg)
+	if err != nil {
+		return i18n.T(lang, i18n.PinUsage), true
+	}
+	note, ok := sessions.Unpin(key, n)
+	if !ok {
+		return i18n.T(lang, i18n.PinUnknown, arg), true
+	}
+	sessions.Save(key)
+	return i18n.T(lang, i18n.PinRemoved, note), true
+}
@@ -0,0 +1,156 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/constants"
+	"github.com/sipeed/picoclaw/pkg/i18n"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/media"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/session"
+	"github.com/sipeed/picoclaw/pkg/tenant"
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+// maxTenantsListed keeps /tenants within a chat message.
+const maxTenantsListed = 30
+
+// scope is where a turn keeps its sessions and finds its memory and skills:
+// the main workspace, or a tenant's.
+type scope struct {
+	tenant         *tenant.Tenant // nil for the main workspace
+	sessions       *session.SessionManager
+	contextBuilder *ContextBuilder
+}
+
+// withTenant puts the tenant of msg into ctx. Internal channels and tenant
+// admins use the main workspace.
+func (al *AgentLoop) withTenant(ctx context.Context, msg bus.InboundMessage) (context.Context, error) {
+	if al.tenants == nil || msg.Channel == "system" || constants.IsInternalChannel(msg.Channel) ||
+		al.tenants.IsAdmin(msg.Channel, msg.SenderID) {
+		return ctx, nil
+	}
+	tn, err := al.tenants.Resolve(msg.Channel, msg.ChatID, msg.SenderID)
+	if err != nil {
+		return ctx, fmt.Errorf("no workspace for this chat: %w", err)
+	}
+	return tenant.WithContext(ctx, tn), nil
+}
+
+// scopeFor returns the scope of the turn's tenant, opening its sessions on
+// first use.
+func (al *AgentLoop) scopeFor(ctx context.Context) *scope {
+	tn := tenant.FromContext(ctx)
+	if tn == nil {
+		return &scope{sessions: al.sessions, contextBuilder: al.contextBuilder}
+	}
+	al.scopesMu.Lock()
+	defer al.scopesMu.Unlock()
+	if sc, ok := al.scopes[tn.ID()]; ok {
+		return sc
+	}
+	cb := NewContextBuilder(tn.Workspace())
+	cb.SetToolsRegistry(al.tools)
+	sc := &scope{
+		tenant:         tn,
+		sessions:       session.NewSessionManager(filepath.Join(tn.Workspace(), "sessions")),
+		contextBuilder: cb,
+	}
+	al.scopes[tn.ID()] = sc
+	return sc
+}
+
+// dropTenant forgets what the agent keeps for a purged tenant.
+func (al *AgentLoop) dropTenant(id string) {
+	al.scopesMu.Lock()
+	delete(al.scopes, id)
+	al.scopesMu.Unlock()
+	if err := tools.DeleteTenantKV(al.state, id); err != nil {
+		logger.WarnCF("agent", "Failed to delete tenant kv namespaces", map[string]interface{}{
+			"tenant": id,
+			"error":  err.Error(),
+		})
+	}
+}
+
+// toolDefs returns the tools offered in a scope; tenants don't see exec
+// unless they may run commands.
+func (sc *scope) toolDefs(registry *tools.ToolRegistry) []providers.ToolDefinition {
+	defs := registry.ToProviderDefs()
+	if sc.tenant == nil || sc.tenant.AllowExec() {
+		return defs
+	}
+	kept := defs[:0]
+	for _, d := range defs {
+		if d.Function.Name != "exec" {
+			kept = append(kept, d)
+		}
+	}
+	return kept
+}
+
+// tenantCommand runs "/tenants" for tenant admins: list, show and purge.
+func (al *AgentLoop) tenantCommand(msg bus.InboundMessage) (reply string, handled bool) {
+	content := strings.TrimSpace(msg.Content)
+	if al.tenants == nil || (content != "/tenants" && !strings.HasPrefix(content, "/tenants ")) ||
+		!al.tenants.IsAdmin(msg.Channel, msg.SenderID) {
+		return "", false
+	}
+	settings := al.locales.Resolve(msg.Channel, msg.ChatID, msg.SenderID)
+	lang := settings.Locale
+	args := strings.Fields(strings.TrimPrefix(content, "/tenants"))
+
+	switch {
+	case len(args) == 0 || args[0] == "list":
+		infos, err := al.tenants.List()
+		if err != nil {
+			return i18n.T(lang, i18n.AgentError, err), true
+		}
+		if len(infos) == 0 {
+			return i18n.T(lang, i18n.TenantsNone), true
+		}
+		var sb strings.Builder
+		for i, info := range infos {
+			if i == maxTenantsListed {
+				fmt.Fprintf(&sb, "…\n")
+				break
+			}
+			fmt.Fprintf(&sb, "- %s (%s:%s), %d tokens today, %s\n",
+				info.ID, info.Channel, info.Owner, info.TokensToday, settings.Format(info.LastSeen))
+		}
+		return i18n.T(lang, i18n.TenantsList, len(infos), strings.TrimRight(sb.String(), "\n")), true
+	case len(args) == 2 && args[0] == "show":
+		tn, err := al.tenants.Get(args[1])
+		if errors.Is(err, tenant.ErrNotFound) {
+			return i18n.T(lang, i18n.TenantsUnknown, args[1]), true
+		}
+		if err != nil {
+			return i18n.T(lang, i18n.AgentError, err), true
+		}
+		info := tn.Info()
+		return i18n.T(lang, i18n.TenantsShow, info.ID, info.Channel, info.Owner, tn.Workspace(),
+			media.FormatSize(tn.DiskUsage()), info.TokensToday, info.TokensTotal,
+			settings.Format(info.Created), settings.Format(info.LastSeen)), true
+	case len(args) == 2 && args[0] == "purge":
+		err := al.tenants.Purge(args[1])
+		if errors.Is(err, tenant.ErrNotFound) {
+			return i18n.T(lang, i18n.TenantsUnknown, args[1]), true
+		}
+		if err != nil {
+			return i18n.T(lang, i18n.AgentError, err), true
+		}
+		return i18n.T(lang, i18n.TenantsPurged, args[1]), true
+	}
+	return i18n.T(lang, i18n.TenantsUsage), true
+}
+
+// Tenants returns the tenant manager, or nil unless multi-tenant mode is on.
+func (al *AgentLoop) Tenants() *tenant.Manager {
+	return al.tenants
+}
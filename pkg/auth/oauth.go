@@ -16,6 +16,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/sipeed/picoclaw/pkg/network"
 )
 
 type OAuthProviderConfig struct {
@@ -24,6 +26,16 @@ type OAuthProviderConfig struct {
 	Scopes     string
 	Originator string
 	Port       int
+	// Client makes the requests to the issuer. Nil uses a client with the
+	// network settings of the providers.
+	Client *http.Client
+}
+
+func (cfg OAuthProviderConfig) client() *http.Client {
+	if cfg.Client != nil {
+		return cfg.Client
+	}
+	return network.NewClient(network.ComponentProviders, network.Options{Timeout: 30 * time.Second})
 }
 
 func OpenAIOAuthConfig() OAuthProviderConfig {
@@ -176,7 +188,7 @@ func LoginDeviceCode(cfg OAuthProviderConfig) (*AuthCredential, error) {
 		"client_id": cfg.ClientID,
 	})
 
-	resp, err := http.Post(
+	resp, err := cfg.client().Post(
 		cfg.Issuer+"/api/accounts/deviceauth/usercode",
 		"application/json",
 		strings.NewReader(string(reqBody)),
@@ -229,7 +241,7 @@ func pollDeviceCode(cfg OAuthProviderConfig, deviceAuthID, userCode string) (*Au
 		"user_code":      userCode,
 	})
 
-	resp, err := http.Post(
+	resp, err := cfg.client().Post(
 		cfg.Issuer+"/api/accounts/deviceauth/token",
 		"application/json",
 		strings.NewReader(string(reqBody)),
@@ -270,7 +282,7 @@ func RefreshAccessToken(cred *AuthCredential, cfg OAuthProviderConfig) (*AuthCre
 		"scope":         {"openid profile email"},
 	}
 
-	resp, err := http.PostForm(cfg.Issuer+"/oauth/token", data)
+	resp, err := cfg.client().PostForm(cfg.Issuer+"/oauth/token", data)
 	if err != nil {
 		return nil, fmt.Errorf("refreshing token: %w", err)
 	}
@@ -315,7 +327,7 @@ func exchangeCodeForTokens(cfg OAuthProviderConfig, code, codeVerifier, redirect
 		"code_verifier": {codeVerifier},
 	}
 
-	resp, err := http.PostForm(cfg.Issuer+"/oauth/token", data)
+	resp, err := cfg.client().PostForm(cfg.Issuer+"/oauth/token", data)
 	if err != nil {
 		return nil, fmt.Errorf("exchanging code for tokens: %w", err)
 	}
@@ -6,10 +6,18 @@ import (
 )
 
 type MessageBus struct {
-	inbound  chan InboundMessage
-	outbound chan OutboundMessage
-	handlers map[string]MessageHandler
-	mu       sync.RWMutex
+	inbound   chan InboundMessage
+	outbound  chan OutboundMessage
+	handlers  map[string]MessageHandler
+	observers []Observer
+	mu        sync.RWMutex
+}
+
+// Observer sees every message published on the bus, e.g. to show recent
+// traffic in monitoring tools. It is called synchronously and must not block.
+type Observer interface {
+	ObserveInbound(msg InboundMessage)
+	ObserveOutbound(msg OutboundMessage)
 }
 
 func NewMessageBus() *MessageBus {
@@ -21,6 +29,11 @@ func NewMessageBus() *MessageBus {
 }
 
 func (mb *MessageBus) PublishInbound(msg InboundMessage) {
+	mb.mu.RLock()
+	for _, o := range mb.observers {
+		o.ObserveInbound(msg)
+	}
+	mb.mu.RUnlock()
 	mb.inbound <- msg
 }
 
@@ -34,6 +47,11 @@ func (mb *MessageBus) ConsumeInbound(ctx context.Context) (InboundMessage, bool)
 }
 
 func (mb *MessageBus) PublishOutbound(msg OutboundMessage) {
+	mb.mu.RLock()
+	for _, o := range mb.observers {
+		o.ObserveOutbound(msg)
+	}
+	mb.mu.RUnlock()
 	mb.outbound <- msg
 }
 
@@ -59,6 +77,18 @@ func (mb *MessageBus) GetHandler(channel string) (MessageHandler, bool) {
 	return handler, ok
 }
 
+// AddObserver registers an observer for all published messages.
+func (mb *MessageBus) AddObserver(o Observer) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	mb.observers = append(mb.observers, o)
+}
+
+// QueueDepth returns the number of messages waiting in each direction.
+func (mb *MessageBus) QueueDepth() (inbound, outbound int) {
+	return len(mb.inbound), len(mb.outbound)
+}
+
 func (mb *MessageBus) Close() {
 	close(mb.inbound)
 	close(mb.outbound)
@@ -0,0 +1,512 @@
+package bus
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/media"
+	"github.com/sipeed/picoclaw/pkg/network"
+)
+
+// Role is the half of a split deployment a process runs.
+type Role string
+
+const (
+	// RoleChannels runs the chat channels. Inbound messages go to the
+	// agent; outbound messages come from it.
+	RoleChannels Role = "channels"
+	// RoleAgent runs the agent loop.
+	RoleAgent Role = "agent"
+)
+
+const (
+	// maxFileSize caps attachments sent over a link. Larger files are left out.
+	maxFileSize  = 20 << 20
+	maxFrameSize = 64 << 20
+	pingInterval = 30 * time.Second
+	pongWait     = 75 * time.Second
+	writeWait    = 30 * time.Second
+	maxBackoff   = 30 * time.Second
+)
+
+// Frame types.
+const (
+	frameInbound  = "inbound"
+	frameOutbound = "outbound"
+	frameTools    = "tools"
+	frameCall     = "call"
+	frameReply    = "reply"
+)
+
+// Frame is one message on a link.
+type Frame struct {
+	Type     string           `json:"type"`
+	Inbound  *InboundMessage  `json:"inbound,omitempty"`
+	Outbound *OutboundMessage `json:"outbound,omitempty"`
+	Files    []File           `json:"files,omitempty"`
+	Tools    []ToolSpec       `json:"tools,omitempty"`
+	Call     *ToolCall        `json:"call,omitempty"`
+	Reply    *ToolReply       `json:"reply,omitempty"`
+}
+
+// File is the content of an attachment, sent along with the message that
+// references it.
+type File struct {
+	Ref  string `json:"ref"`
+	Name string `json:"name"`
+	Data []byte `json:"data"`
+}
+
+// ToolSpec describes a tool one side offers the other.
+type ToolSpec struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Parameters  map[string]interface{} `json:"parameters"`
+}
+
+// ToolCall asks the other side to run one of its tools.
+type ToolCall struct {
+	ID      string                 `json:"id"`
+	Name    string                 `json:"name"`
+	Args    map[string]interface{} `json:"args"`
+	Channel string                 `json:"channel,omitempty"`
+	ChatID  string                 `json:"chat_id,omitempty"`
+}
+
+// ToolReply is the result of a ToolCall.
+type ToolReply struct {
+	ID      string `json:"id"`
+	ForLLM  string `json:"for_llm"`
+	ForUser string `json:"for_user,omitempty"`
+	Silent  bool   `json:"silent"`
+	IsError bool   `json:"is_error"`
+}
+
+// ToolHandler runs a tool call from the other side.
+type ToolHandler func(ctx context.Context, call ToolCall) ToolReply
+
+// LinkOptions configure a link.
+type LinkOptions struct {
+	Role Role
+	// URL is the ws:// or wss:// address of the other side's link. Empty
+	// waits for the other side to connect through ServeHTTP.
+	URL   string
+	Token string
+	// Media stores attachments received from the other side.
+	Media *media.Store
+}
+
+// Link connects the bus to the bus of another process over a WebSocket, so
+// that channels and the agent can run on different hosts. The channels side
+// forwards inbound messages and receives outbound ones; the agent side does
+// the opposite. Either side can offer tools to the other. Messages wait in
+// the bus while the link is down, and the dialing side reconnects.
+type Link struct {
+	bus  *MessageBus
+	opts LinkOptions
+
+	mu      sync.Mutex
+	conn    *websocket.Conn
+	ready   chan struct{} // closed while connected
+	writeMu sync.Mutex
+
+	exports []ToolSpec
+	handler ToolHandler
+	onTools func([]ToolSpec)
+	calls   map[string]chan ToolReply
+	nextID  atomic.Uint64
+}
+
+// NewLink creates a link for mb. Call Run to start it.
+func NewLink(mb *MessageBus, opts LinkOptions) *Link {
+	return &Link{
+		bus:   mb,
+		opts:  opts,
+		ready: make(chan struct{}),
+		calls: make(map[string]chan ToolReply),
+	}
+}
+
+// ExportTools offers tools to the other side, run by h. It must be called
+// before Run.
+func (l *Link) ExportTools(specs []ToolSpec, h ToolHandler) {
+	l.exports = specs
+	l.handler = h
+}
+
+// OnRemoteTools sets the function called with the tools the other side
+// offers, each time it connects. It must be called before Run.
+func (l *Link) OnRemoteTools(fn func([]ToolSpec)) {
+	l.onTools = fn
+}
+
+// Connected reports whether the other side is connected.
+func (l *Link) Connected() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.conn != nil
+}
+
+// Run forwards messages until ctx is done. With a URL it also keeps a
+// connection to the other side open.
+func (l *Link) Run(ctx context.Context) {
+	if l.opts.URL != "" {
+		go l.dialLoop(ctx)
+	}
+	go l.pump(ctx)
+	<-ctx.Done()
+	l.mu.Lock()
+	if l.conn != nil {
+		l.conn.Close()
+	}
+	l.mu.Unlock()
+}
+
+func (l *Link) dialLoop(ctx context.Context) {
+	backoff := time.Second
+	for ctx.Err() == nil {
+		dialer := network.WebSocketDialer(network.ComponentBus, network.Options{})
+		header := http.Header{}
+		header.Set("Authorization", "Bearer "+l.opts.Token)
+		header.Set("X-Picoclaw-Role", string(l.opts.Role))
+
+		conn, resp, err := dialer.DialContext(ctx, l.opts.URL, header)
+		if err != nil {
+			status := 0
+			if resp != nil {
+				status = resp.StatusCode
+			}
+			logger.WarnCF("bus", "Bus link connect failed", map[string]interface{}{
+				"url":    l.opts.URL,
+				"status": status,
+				"error":  err.Error(),
+			})
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, maxBackoff)
+			continue
+		}
+		backoff = time.Second
+		l.serveConn(ctx, conn)
+	}
+}
+
+// ServeHTTP accepts the other side's connection. A new connection replaces
+// the current one.
+func (l *Link) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if l.opts.Token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(l.opts.Token)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if role := Role(r.Header.Get("X-Picoclaw-Role")); role == l.opts.Role {
+		http.Error(w, fmt.Sprintf("both sides run the %s role", role), http.StatusConflict)
+		return
+	}
+	upgrader := websocket.Upgrader{
+		// The other side is a picoclaw process, not a browser
+		CheckOrigin: func(r *http.Request) bool { return true },
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	l.serveConn(r.Context(), conn)
+}
+
+// serveConn reads frames from conn until it fails.
+func (l *Link) serveConn(ctx context.Context, conn *websocket.Conn) {
+	conn.SetReadLimit(maxFrameSize)
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	l.mu.Lock()
+	if l.conn != nil {
+		l.conn.Close()
+		l.disconnectLocked()
+	}
+	l.conn = conn
+	close(l.ready)
+	l.mu.Unlock()
+	logger.InfoCF("bus", "Bus link connected", map[string]interface{}{"remote": conn.RemoteAddr().String()})
+
+	done := make(chan struct{})
+	defer close(done)
+	go l.keepAlive(conn, done)
+
+	if l.exports != nil {
+		if err := l.write(conn, Frame{Type: frameTools, Tools: l.exports}); err != nil {
+			l.drop(conn, err)
+			return
+		}
+	}
+
+	for {
+		var f Frame
+		if err := conn.ReadJSON(&f); err != nil {
+			l.drop(conn, err)
+			return
+		}
+		l.handle(ctx, conn, f)
+	}
+}
+
+func (l *Link) keepAlive(conn *websocket.Conn, done chan struct{}) {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (l *Link) drop(conn *websocket.Conn, err error) {
+	conn.Close()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.conn != conn {
+		return
+	}
+	l.disconnectLocked()
+	l.conn = nil
+	logger.WarnCF("bus", "Bus link disconnected", map[string]interface{}{"error": err.Error()})
+}
+
+// disconnectLocked resets the ready signal and fails the calls waiting
+// for a reply.
+func (l *Link) disconnectLocked() {
+	l.ready = make(chan struct{})
+	for id, ch := range l.calls {
+		ch <- ToolReply{ID: id, ForLLM: "bus link disconnected during the tool call", IsError: true}
+		delete(l.calls, id)
+	}
+}
+
+func (l *Link) write(conn *websocket.Conn, f Frame) error {
+	l.writeMu.Lock()
+	defer l.writeMu.Unlock()
+	conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return conn.WriteJSON(f)
+}
+
+// send writes f, waiting for a connection and retrying on a new one when
+// the write fails.
+func (l *Link) send(ctx context.Context, f Frame) error {
+	for {
+		l.mu.Lock()
+		conn, ready := l.conn, l.ready
+		l.mu.Unlock()
+		if conn == nil {
+			select {
+			case <-ready:
+				continue
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		err := l.write(conn, f)
+		if err == nil {
+			return nil
+		}
+		l.drop(conn, err)
+	}
+}
+
+// pump forwards the messages this side produces.
+func (l *Link) pump(ctx context.Context) {
+	for {
+		var f Frame
+		if l.opts.Role == RoleChannels {
+			msg, ok := l.bus.ConsumeInbound(ctx)
+			if !ok {
+				return
+			}
+			f = Frame{Type: frameInbound, Inbound: &msg, Files: l.readFiles(msg.Media)}
+		} else {
+			msg, ok := l.bus.SubscribeOutbound(ctx)
+			if !ok {
+				return
+			}
+			f = Frame{Type: frameOutbound, Outbound: &msg, Files: l.readFiles(msg.Media)}
+		}
+		if err := l.send(ctx, f); err != nil {
+			return
+		}
+	}
+}
+
+func (l *Link) handle(ctx context.Context, conn *websocket.Conn, f Frame) {
+	switch f.Type {
+	case frameInbound:
+		if f.Inbound == nil || l.opts.Role != RoleAgent {
+			return
+		}
+		msg := *f.Inbound
+		msg.Media = l.storeFiles(msg.Media, f.Files, media.Origin{Channel: msg.Channel, SenderID: msg.SenderID, ChatID: msg.ChatID}, false)
+		l.bus.PublishInbound(msg)
+	case frameOutbound:
+		if f.Outbound == nil || l.opts.Role != RoleChannels {
+			return
+		}
+		msg := *f.Outbound
+		msg.Media = l.storeFiles(msg.Media, f.Files, media.Origin{Channel: msg.Channel, ChatID: msg.ChatID}, true)
+		l.bus.PublishOutbound(msg)
+	case frameTools:
+		logger.InfoCF("bus", "Remote tools offered", map[string]interface{}{"count": len(f.Tools)})
+		if l.onTools != nil {
+			l.onTools(f.Tools)
+		}
+	case frameCall:
+		if f.Call == nil {
+			return
+		}
+		go func(call ToolCall) {
+			reply := ToolReply{ForLLM: fmt.Sprintf("tool %s is not offered", call.Name), IsError: true}
+			if l.handler != nil {
+				reply = l.handler(ctx, call)
+			}
+			reply.ID = call.ID
+			if err := l.write(conn, Frame{Type: frameReply, Reply: &reply}); err != nil {
+				l.drop(conn, err)
+			}
+		}(*f.Call)
+	case frameReply:
+		if f.Reply == nil {
+			return
+		}
+		l.mu.Lock()
+		ch, ok := l.calls[f.Reply.ID]
+		delete(l.calls, f.Reply.ID)
+		l.mu.Unlock()
+		if ok {
+			ch <- *f.Reply
+		}
+	}
+}
+
+// CallTool runs a tool the other side offers.
+func (l *Link) CallTool(ctx context.Context, call ToolCall) (ToolReply, error) {
+	l.mu.Lock()
+	conn := l.conn
+	if conn == nil {
+		l.mu.Unlock()
+		return ToolReply{}, errors.New("bus link is not connected")
+	}
+	call.ID = fmt.Sprintf("%d", l.nextID.Add(1))
+	ch := make(chan ToolReply, 1)
+	l.calls[call.ID] = ch
+	l.mu.Unlock()
+
+	if err := l.write(conn, Frame{Type: frameCall, Call: &call}); err != nil {
+		l.drop(conn, err)
+		return ToolReply{}, err
+	}
+	select {
+	case reply := <-ch:
+		return reply, nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		delete(l.calls, call.ID)
+		l.mu.Unlock()
+		return ToolReply{}, ctx.Err()
+	}
+}
+
+// readFiles loads the attachments of a message: media references from the
+// store, or local paths.
+func (l *Link) readFiles(refs []string) []File {
+	var files []File
+	for _, ref := range refs {
+		path, name := ref, filepath.Base(ref)
+		if strings.HasPrefix(ref, media.RefPrefix) {
+			if l.opts.Media == nil {
+				continue
+			}
+			it, ok := l.opts.Media.Get(ref)
+			if !ok {
+				continue
+			}
+			path, name = l.opts.Media.Path(it), it.Name
+		}
+		info, err := os.Stat(path)
+		if err != nil || info.Size() > maxFileSize {
+			logger.WarnCF("bus", "Attachment not sent over bus link", map[string]interface{}{
+				"ref":  ref,
+				"size": fileSize(info),
+			})
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		files = append(files, File{Ref: ref, Name: name, Data: data})
+	}
+	return files
+}
+
+func fileSize(info os.FileInfo) int64 {
+	if info == nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// storeFiles saves received attachments and returns the message's media
+// list with local references (or paths, for channels to upload).
+// Attachments that did not arrive are dropped.
+func (l *Link) storeFiles(refs []string, files []File, origin media.Origin, asPaths bool) []string {
+	if len(refs) == 0 {
+		return nil
+	}
+	byRef := make(map[string]File, len(files))
+	for _, f := range files {
+		byRef[f.Ref] = f
+	}
+	out := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		f, ok := byRef[ref]
+		if !ok || l.opts.Media == nil {
+			continue
+		}
+		origin.Name = f.Name
+		it, err := l.opts.Media.Put(bytes.NewReader(f.Data), origin)
+		if err != nil {
+			logger.WarnCF("bus", "Failed to store attachment from bus link", map[string]interface{}{
+				"ref":   ref,
+				"error": err.Error(),
+			})
+			continue
+		}
+		if asPaths {
+			out = append(out, l.opts.Media.Path(it))
+		} else {
+			out = append(out, it.Ref())
+		}
+	}
+	return out
+}
@@ -0,0 +1,138 @@
+package bus
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/media"
+)
+
+const testToken = "split-deployment-token"
+
+// linkPair connects a channels-side bus (serving) and an agent-side bus
+// (dialing), each with its own media store.
+func linkPair(t *testing.T, ctx context.Context) (channels, agent *MessageBus, chMedia, agMedia *media.Store) {
+	t.Helper()
+	var err error
+	if chMedia, err = media.NewStore(t.TempDir(), media.Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if agMedia, err = media.NewStore(t.TempDir(), media.Options{}); err != nil {
+		t.Fatal(err)
+	}
+	channels, agent = NewMessageBus(), NewMessageBus()
+
+	chLink := NewLink(channels, LinkOptions{Role: RoleChannels, Token: testToken, Media: chMedia})
+	srv := httptest.NewServer(chLink)
+	t.Cleanup(srv.Close)
+
+	agLink := NewLink(agent, LinkOptions{
+		Role:  RoleAgent,
+		URL:   "ws" + strings.TrimPrefix(srv.URL, "http"),
+		Token: testToken,
+		Media: agMedia,
+	})
+	go chLink.Run(ctx)
+	go agLink.Run(ctx)
+	return
+}
+
+func TestLinkForwardsMessages(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	channels, agent, chMedia, agMedia := linkPair(t, ctx)
+
+	photo, err := chMedia.Put(strings.NewReader("jpeg bytes"), media.Origin{Channel: "telegram", Name: "photo.jpg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	channels.PublishInbound(InboundMessage{Channel: "telegram", SenderID: "7", ChatID: "42", Content: "look", Media: []string{photo.Ref()}})
+
+	in, ok := agent.ConsumeInbound(ctx)
+	if !ok {
+		t.Fatal("inbound message not forwarded")
+	}
+	if in.Content != "look" || in.ChatID != "42" || len(in.Media) != 1 {
+		t.Fatalf("inbound = %+v", in)
+	}
+	// Content addressing gives the same reference on both hosts
+	if in.Media[0] != photo.Ref() {
+		t.Errorf("media ref = %s, want %s", in.Media[0], photo.Ref())
+	}
+	if _, ok := agMedia.Get(in.Media[0]); !ok {
+		t.Error("attachment not stored on the agent side")
+	}
+
+	report := t.TempDir() + "/report.txt"
+	os.WriteFile(report, []byte("weekly report"), 0644)
+	agent.PublishOutbound(OutboundMessage{Channel: "telegram", ChatID: "42", Content: "done", Media: []string{report}})
+
+	out, ok := channels.SubscribeOutbound(ctx)
+	if !ok {
+		t.Fatal("outbound message not forwarded")
+	}
+	if out.Content != "done" || len(out.Media) != 1 {
+		t.Fatalf("outbound = %+v", out)
+	}
+	data, err := os.ReadFile(out.Media[0])
+	if err != nil || string(data) != "weekly report" {
+		t.Errorf("outbound attachment = %q, %v", data, err)
+	}
+}
+
+func TestLinkRemoteTools(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	offered := make(chan []ToolSpec, 1)
+	chLink := NewLink(NewMessageBus(), LinkOptions{Role: RoleChannels, Token: testToken})
+	chLink.ExportTools([]ToolSpec{{Name: "i2c"}}, func(ctx context.Context, call ToolCall) ToolReply {
+		return ToolReply{ForLLM: "read bus " + call.Args["bus"].(string) + " for " + call.ChatID}
+	})
+	srv := httptest.NewServer(chLink)
+	defer srv.Close()
+	agLink := NewLink(NewMessageBus(), LinkOptions{Role: RoleAgent, URL: "ws" + strings.TrimPrefix(srv.URL, "http"), Token: testToken})
+	agLink.OnRemoteTools(func(specs []ToolSpec) { offered <- specs })
+	go chLink.Run(ctx)
+	go agLink.Run(ctx)
+
+	select {
+	case specs := <-offered:
+		if len(specs) != 1 || specs[0].Name != "i2c" {
+			t.Fatalf("offered %+v", specs)
+		}
+	case <-ctx.Done():
+		t.Fatal("tools were not offered")
+	}
+
+	reply, err := agLink.CallTool(ctx, ToolCall{Name: "i2c", Args: map[string]interface{}{"bus": "1"}, Channel: "telegram", ChatID: "42"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.ForLLM != "read bus 1 for 42" || reply.IsError {
+		t.Errorf("reply = %+v", reply)
+	}
+}
+
+func TestLinkRejectsWrongToken(t *testing.T) {
+	chLink := NewLink(NewMessageBus(), LinkOptions{Role: RoleChannels, Token: testToken})
+	srv := httptest.NewServer(chLink)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	agLink := NewLink(NewMessageBus(), LinkOptions{Role: RoleAgent, URL: "ws" + strings.TrimPrefix(srv.URL, "http"), Token: "wrong-token-value"})
+	go agLink.Run(ctx)
+
+	time.Sleep(500 * time.Millisecond)
+	if agLink.Connected() || chLink.Connected() {
+		t.Error("link connected with a wrong token")
+	}
+	if _, err := agLink.CallTool(ctx, ToolCall{Name: "i2c"}); err == nil {
+		t.Error("tool call without a connection should fail")
+	}
+}
@@ -11,9 +11,36 @@ type InboundMessage struct {
 }
 
 type OutboundMessage struct {
-	Channel string `json:"channel"`
-	ChatID  string `json:"chat_id"`
-	Content string `json:"content"`
+	Channel string   `json:"channel"`
+	ChatID  string   `json:"chat_id"`
+	Content string   `json:"content"`
+	Media   []string `json:"media,omitempty"`    // local file paths to attach, for channels that support it
+	Buttons []Button `json:"buttons,omitempty"`  // quick replies, for channels that support them
+	TraceID string   `json:"trace_id,omitempty"` // turn the reply answers; reactions to it carry the ID back
+
+	// Activity, when set, makes the message a typing or progress indicator
+	// instead of a reply; Content is ignored.
+	Activity *Activity `json:"activity,omitempty"`
+}
+
+// Activity kinds.
+const (
+	ActivityTyping = "typing" // keep the typing indicator alive
+	ActivityStatus = "status" // show Status in the progress placeholder
+	ActivityDone   = "done"   // remove the progress placeholder
+)
+
+// Activity tells the user the agent is still working on their message.
+type Activity struct {
+	Kind   string `json:"kind"`
+	Status string `json:"status,omitempty"`
+}
+
+// Button is a quick reply attached to an outbound message. Pressing it
+// arrives as an inbound message whose content is Data.
+type Button struct {
+	Text string `json:"text"`
+	Data string `json:"data"`
 }
 
 type MessageHandler func(InboundMessage) error
@@ -3,9 +3,15 @@ package channels
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"strings"
+	"time"
 
 	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/media"
+	"github.com/sipeed/picoclaw/pkg/state"
+	"github.com/sipeed/picoclaw/pkg/utils"
 )
 
 type Channel interface {
@@ -17,12 +23,37 @@ type Channel interface {
 	IsAllowed(senderID string) bool
 }
 
+// WebhookChannel is implemented by channels that receive updates on the
+// gateway HTTP server. An empty path means the channel has no webhook.
+type WebhookChannel interface {
+	WebhookPath() string
+	WebhookHandler() http.Handler
+}
+
+// TypingChannel is implemented by channels that can show a typing
+// indicator. The indicator is expected to expire on its own after a few
+// seconds; the agent repeats SendTyping while a turn runs.
+type TypingChannel interface {
+	SendTyping(ctx context.Context, chatID string) error
+}
+
+// ProgressChannel is implemented by channels that can show the status of a
+// long turn in a single placeholder message, edited in place as the status
+// changes. ClearProgress removes the placeholder, unless the reply already
+// replaced it.
+type ProgressChannel interface {
+	SendProgress(ctx context.Context, chatID, status string) error
+	ClearProgress(ctx context.Context, chatID string) error
+}
+
 type BaseChannel struct {
 	config    interface{}
 	bus       *bus.MessageBus
 	running   bool
 	name      string
 	allowList []string
+	media     *media.Store
+	state     *state.Manager
 }
 
 func NewBaseChannel(name string, config interface{}, bus *bus.MessageBus, allowList []string) *BaseChannel {
@@ -35,6 +66,37 @@ func NewBaseChannel(name string, config interface{}, bus *bus.MessageBus, allowL
 	}
 }
 
+// SetMediaStore makes the channel keep inbound media in store and pass
+// "media:<id>" references on instead of local file paths.
+func (c *BaseChannel) SetMediaStore(store *media.Store) {
+	c.media = store
+}
+
+// SetStateStore makes the channel keep its own state, such as update
+// offsets, in store so it survives a restart.
+func (c *BaseChannel) SetStateStore(store *state.Manager) {
+	c.state = store
+}
+
+// stateSaveInterval is how often channels save state that changes with
+// every message, rather than rewriting the state file each time. Stop
+// saves it as well.
+const stateSaveInterval = 10 * time.Second
+
+// saveEvery calls save every stateSaveInterval until ctx ends.
+func saveEvery(ctx context.Context, save func()) {
+	ticker := time.NewTicker(stateSaveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			save()
+		}
+	}
+}
+
 func (c *BaseChannel) Name() string {
 	return c.name
 }
@@ -95,7 +157,7 @@ func (c *BaseChannel) HandleMessage(senderID, chatID, content string, media []st
 		SenderID:   senderID,
 		ChatID:     chatID,
 		Content:    content,
-		Media:      media,
+		Media:      c.storeMedia(senderID, chatID, media),
 		SessionKey: sessionKey,
 		Metadata:   metadata,
 	}
@@ -103,6 +165,34 @@ func (c *BaseChannel) HandleMessage(senderID, chatID, content string, media []st
 	c.bus.PublishInbound(msg)
 }
 
+// storeMedia copies downloaded files into the media store. Channels remove
+// their downloads once the message is published, so the copies are what the
+// agent gets to see. Files that cannot be stored are passed on as they are.
+func (c *BaseChannel) storeMedia(senderID, chatID string, paths []string) []string {
+	if c.media == nil || len(paths) == 0 {
+		return paths
+	}
+	refs := make([]string, 0, len(paths))
+	for _, path := range paths {
+		item, err := c.media.PutFile(path, media.Origin{
+			Channel:  c.name,
+			SenderID: senderID,
+			ChatID:   chatID,
+			Name:     utils.OriginalFilename(path),
+		})
+		if err != nil {
+			logger.WarnCF(c.name, "Failed to store inbound media", map[string]interface{}{
+				"path":  path,
+				"error": err.Error(),
+			})
+			refs = append(refs, path)
+			continue
+		}
+		refs = append(refs, item.Ref())
+	}
+	return refs
+}
+
 func (c *BaseChannel) setRunning(running bool) {
 	c.running = running
 }
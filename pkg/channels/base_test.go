@@ -1,6 +1,15 @@
 package channels
 
-import "testing"
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/media"
+)
 
 func TestBaseChannelIsAllowed(t *testing.T) {
 	tests := []struct {
@@ -50,3 +59,34 @@ func TestBaseChannelIsAllowed(t *testing.T) {
 		})
 	}
 }
+
+func TestBaseChannelStoresMedia(t *testing.T) {
+	dir := t.TempDir()
+	store, err := media.NewStore(filepath.Join(dir, "media"), media.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	download := filepath.Join(dir, "1a2b3c4d_report.pdf")
+	if err := os.WriteFile(download, []byte("%PDF-1.4"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mb := bus.NewMessageBus()
+	ch := NewBaseChannel("telegram", nil, mb, nil)
+	ch.SetMediaStore(store)
+	ch.HandleMessage("42", "7", "see attached", []string{download, filepath.Join(dir, "missing.jpg")}, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	msg, ok := mb.ConsumeInbound(ctx)
+	if !ok || len(msg.Media) != 2 {
+		t.Fatalf("inbound media = %v", msg.Media)
+	}
+	item, found := store.Get(msg.Media[0])
+	if !found || item.Name != "report.pdf" || item.SenderID != "42" || item.Channel != "telegram" {
+		t.Errorf("stored item = %+v (ref %q)", item, msg.Media[0])
+	}
+	if msg.Media[1] != filepath.Join(dir, "missing.jpg") {
+		t.Errorf("unstorable file should pass through, got %q", msg.Media[1])
+	}
+}
@@ -0,0 +1,240 @@
+// Package channeltest provides a conformance suite for channel
+// implementations and local fake servers for the chat platforms they talk to,
+// so channels can be exercised end-to-end without network access.
+//
+// The package does not import pkg/channels, which lets the channel package's
+// own tests use it. Any channels.Channel satisfies the Channel interface here.
+//
+// Run the suite with -race: restarting a channel (StartStopIdempotent) is
+// where goroutines left over from the previous run show up.
+package channeltest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
+
+const (
+	// AllowedSender and DeniedSender are the platform user IDs the suite
+	// delivers messages from. They are numeric so that every platform accepts them.
+	AllowedSender = "1001"
+	DeniedSender  = "1002"
+
+	waitTimeout = 3 * time.Second
+)
+
+// Channel mirrors channels.Channel.
+type Channel interface {
+	Name() string
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+	Send(ctx context.Context, msg bus.OutboundMessage) error
+	IsRunning() bool
+	IsAllowed(senderID string) bool
+}
+
+// Platform is the suite's handle on the fake server behind a channel.
+type Platform interface {
+	// Deliver makes the platform deliver a text message from senderID and
+	// returns the chat ID the channel is expected to publish it under.
+	Deliver(t *testing.T, senderID, text string) (chatID string)
+	// Sent returns the texts the platform received for chatID, in order.
+	Sent(chatID string) []string
+}
+
+// Factory returns an unstarted channel wired to a fresh fake platform.
+type Factory func(t *testing.T, mb *bus.MessageBus, allowFrom []string) (Channel, Platform)
+
+type Options struct {
+	// ChatID is a chat the channel can send to without a prior inbound message.
+	ChatID string
+	// MaxMessageLen is the platform's per-message limit in characters.
+	// The long-message check is skipped when it is zero.
+	MaxMessageLen int
+}
+
+// Run runs the conformance suite against the channels produced by newChannel.
+func Run(t *testing.T, newChannel Factory, opts Options) {
+	t.Run("StartStopIdempotent", func(t *testing.T) {
+		ch, _ := newChannel(t, bus.NewMessageBus(), nil)
+		ctx := context.Background()
+
+		if ch.IsRunning() {
+			t.Fatal("channel reports running before Start")
+		}
+		for i := 0; i < 2; i++ {
+			if err := ch.Start(ctx); err != nil {
+				t.Fatalf("Start #%d: %v", i+1, err)
+			}
+			if !ch.IsRunning() {
+				t.Fatalf("channel not running after Start #%d", i+1)
+			}
+		}
+		for i := 0; i < 2; i++ {
+			if err := ch.Stop(ctx); err != nil {
+				t.Fatalf("Stop #%d: %v", i+1, err)
+			}
+			if ch.IsRunning() {
+				t.Fatalf("channel still running after Stop #%d", i+1)
+			}
+		}
+
+		// A stopped channel can be started again.
+		if err := ch.Start(ctx); err != nil {
+			t.Fatalf("restart: %v", err)
+		}
+		ch.Stop(ctx)
+	})
+
+	t.Run("InboundToBus", func(t *testing.T) {
+		mb := bus.NewMessageBus()
+		ch, platform := newChannel(t, mb, nil)
+		start(t, ch)
+
+		chatID := platform.Deliver(t, AllowedSender, "hello from the fake platform")
+		msg := consume(t, mb)
+
+		if msg.Channel != ch.Name() {
+			t.Errorf("Channel = %q, want %q", msg.Channel, ch.Name())
+		}
+		if msg.ChatID != chatID {
+			t.Errorf("ChatID = %q, want %q", msg.ChatID, chatID)
+		}
+		if msg.Content != "hello from the fake platform" {
+			t.Errorf("Content = %q", msg.Content)
+		}
+		if !isSender(msg.SenderID, AllowedSender) {
+			t.Errorf("SenderID = %q, want %q or %q", msg.SenderID, AllowedSender, AllowedSender+"|<username>")
+		}
+		if msg.SessionKey == "" {
+			t.Error("SessionKey is empty")
+		}
+	})
+
+	t.Run("Allowlist", func(t *testing.T) {
+		mb := bus.NewMessageBus()
+		ch, platform := newChannel(t, mb, []string{AllowedSender})
+		start(t, ch)
+
+		if ch.IsAllowed(DeniedSender) {
+			t.Fatalf("IsAllowed(%q) = true", DeniedSender)
+		}
+
+		platform.Deliver(t, DeniedSender, "from a stranger")
+		platform.Deliver(t, AllowedSender, "from a friend")
+
+		// Some clients dispatch events concurrently, so the order of the two
+		// messages is not guaranteed; only the allowed one may ever arrive.
+		msg := consume(t, mb)
+		if isSender(msg.SenderID, DeniedSender) || msg.Content != "from a friend" {
+			t.Fatalf("unexpected inbound message: %+v", msg)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		defer cancel()
+		if extra, ok := mb.ConsumeInbound(ctx); ok {
+			t.Fatalf("message from a denied sender reached the bus: %+v", extra)
+		}
+	})
+
+	t.Run("SendWhenStopped", func(t *testing.T) {
+		ch, _ := newChannel(t, bus.NewMessageBus(), nil)
+		ctx := context.Background()
+		msg := bus.OutboundMessage{ChatID: opts.ChatID, Content: "hi"}
+
+		if err := ch.Send(ctx, msg); err == nil {
+			t.Error("Send before Start succeeded")
+		}
+		if err := ch.Start(ctx); err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+		if err := ch.Stop(ctx); err != nil {
+			t.Fatalf("Stop: %v", err)
+		}
+		if err := ch.Send(ctx, msg); err == nil {
+			t.Error("Send after Stop succeeded")
+		}
+	})
+
+	t.Run("LongMessage", func(t *testing.T) {
+		if opts.MaxMessageLen <= 0 {
+			t.Skip("no platform message limit")
+		}
+		ch, platform := newChannel(t, bus.NewMessageBus(), nil)
+		start(t, ch)
+
+		content := longText(opts.MaxMessageLen * 5 / 2)
+		if err := ch.Send(context.Background(), bus.OutboundMessage{ChatID: opts.ChatID, Content: content}); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+
+		want := strings.Join(strings.Fields(content), " ")
+		var sent []string
+		waitFor(t, "the full message to be delivered", func() bool {
+			sent = platform.Sent(opts.ChatID)
+			return strings.Join(strings.Fields(strings.Join(sent, " ")), " ") == want
+		})
+
+		if len(sent) < 3 {
+			t.Errorf("expected the message to be split into at least 3 parts, got %d", len(sent))
+		}
+		for i, part := range sent {
+			if n := len([]rune(part)); n > opts.MaxMessageLen {
+				t.Errorf("part %d has %d characters, limit is %d", i, n, opts.MaxMessageLen)
+			}
+		}
+	})
+}
+
+func start(t *testing.T, ch Channel) {
+	t.Helper()
+	if err := ch.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { ch.Stop(context.Background()) })
+}
+
+func consume(t *testing.T, mb *bus.MessageBus) bus.InboundMessage {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), waitTimeout)
+	defer cancel()
+	msg, ok := mb.ConsumeInbound(ctx)
+	if !ok {
+		t.Fatal("timed out waiting for an inbound message")
+	}
+	return msg
+}
+
+func isSender(senderID, platformID string) bool {
+	return senderID == platformID || strings.HasPrefix(senderID, platformID+"|")
+}
+
+// longText returns n characters of space and newline separated words.
+func longText(n int) string {
+	var sb strings.Builder
+	for i := 0; sb.Len() < n; i++ {
+		switch {
+		case i%97 == 96:
+			sb.WriteString("\n\n")
+		case i > 0:
+			sb.WriteString(" ")
+		}
+		sb.WriteString("word")
+	}
+	return sb.String()[:n]
+}
+
+// waitFor polls cond until it holds or the suite timeout expires.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(waitTimeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
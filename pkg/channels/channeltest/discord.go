@@ -0,0 +1,264 @@
+package channeltest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// DiscordMessage is a message the bot created through the REST API.
+type DiscordMessage struct {
+	ChannelID string
+	Content   string
+}
+
+// Discord is a fake Discord REST API and gateway. discordgo's endpoints are
+// package globals, so the fake is reached through HTTPClient, which sends
+// every request to the fake regardless of host. The gateway URL it returns
+// points at the fake's own WebSocket endpoint.
+type Discord struct {
+	srv *httptest.Server
+
+	mu       sync.Mutex
+	messages []DiscordMessage
+	typing   []string
+	seq      int
+	msgID    int
+
+	conns   chan *websocket.Conn
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+}
+
+// NewDiscord starts a fake Discord server that is closed when the test ends.
+func NewDiscord(t *testing.T) *Discord {
+	f := &Discord{conns: make(chan *websocket.Conn, 4)}
+	f.srv = httptest.NewServer(f)
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+// HTTPClient returns a client that sends all requests to the fake.
+func (f *Discord) HTTPClient() *http.Client {
+	target, _ := url.Parse(f.srv.URL)
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			r = r.Clone(r.Context())
+			r.URL.Scheme = target.Scheme
+			r.URL.Host = target.Host
+			r.Host = target.Host
+			return http.DefaultTransport.RoundTrip(r)
+		}),
+	}
+}
+
+// PushMessage dispatches a MESSAGE_CREATE event from authorID in channelID,
+// waiting for the bot to connect to the gateway if needed.
+func (f *Discord) PushMessage(t *testing.T, authorID, channelID, content string) {
+	t.Helper()
+	f.mu.Lock()
+	f.msgID++
+	id := fmt.Sprintf("%d", 900000+f.msgID)
+	f.mu.Unlock()
+
+	f.dispatch(t, "MESSAGE_CREATE", map[string]interface{}{
+		"id":         id,
+		"channel_id": channelID,
+		"content":    content,
+		"timestamp":  time.Now().UTC().Format(time.RFC3339),
+		"author": map[string]interface{}{
+			"id":            authorID,
+			"username":      "user" + authorID,
+			"discriminator": "0",
+		},
+		"attachments": []interface{}{},
+	})
+}
+
+// Messages returns the messages the bot created in channelID.
+func (f *Discord) Messages(channelID string) []DiscordMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []DiscordMessage
+	for _, m := range f.messages {
+		if m.ChannelID == channelID {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// Typing returns the channel IDs the bot sent typing indicators to.
+func (f *Discord) Typing() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.typing...)
+}
+
+func (f *Discord) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// discordgo appends a slash to the gateway URL.
+	if strings.TrimSuffix(r.URL.Path, "/") == "/gateway-ws" {
+		f.serveGateway(w, r)
+		return
+	}
+
+	// Strip the /api/v<N> prefix.
+	p := r.URL.Path
+	if strings.HasPrefix(p, "/api/v") {
+		if i := strings.Index(p[len("/api/v"):], "/"); i >= 0 {
+			p = p[len("/api/v")+i:]
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case p == "/gateway" || p == "/gateway/bot":
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"url":    "ws" + strings.TrimPrefix(f.srv.URL, "http") + "/gateway-ws",
+			"shards": 1,
+		})
+
+	case p == "/users/@me":
+		w.Write([]byte(`{"id":"900","username":"picobot","discriminator":"0","bot":true}`))
+
+	case strings.HasPrefix(p, "/channels/") && strings.HasSuffix(p, "/typing"):
+		channelID := strings.TrimSuffix(strings.TrimPrefix(p, "/channels/"), "/typing")
+		f.mu.Lock()
+		f.typing = append(f.typing, channelID)
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+
+	case strings.HasPrefix(p, "/channels/") && strings.HasSuffix(p, "/messages") && r.Method == http.MethodPost:
+		channelID := strings.TrimSuffix(strings.TrimPrefix(p, "/channels/"), "/messages")
+		var body struct {
+			Content string `json:"content"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if len([]rune(body.Content)) > 2000 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"code":50035,"message":"Invalid Form Body"}`))
+			return
+		}
+		f.mu.Lock()
+		f.messages = append(f.messages, DiscordMessage{ChannelID: channelID, Content: body.Content})
+		f.msgID++
+		id := fmt.Sprintf("%d", 900000+f.msgID)
+		f.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":         id,
+			"channel_id": channelID,
+			"content":    body.Content,
+			"author":     map[string]interface{}{"id": "900", "username": "picobot", "bot": true},
+		})
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"code":0,"message":"404: Not Found"}`))
+	}
+}
+
+func (f *Discord) serveGateway(w http.ResponseWriter, r *http.Request) {
+	conn, err := websocket.Upgrade(w, r, nil, 1024, 1024)
+	if err != nil {
+		return
+	}
+
+	f.writeMu.Lock()
+	err = conn.WriteJSON(map[string]interface{}{"op": 10, "d": map[string]interface{}{"heartbeat_interval": 45000}})
+	f.writeMu.Unlock()
+	if err != nil {
+		conn.Close()
+		return
+	}
+
+	go func() {
+		defer func() {
+			f.writeMu.Lock()
+			if f.conn == conn {
+				f.conn = nil
+			}
+			f.writeMu.Unlock()
+			conn.Close()
+		}()
+		for {
+			var frame struct {
+				Op int `json:"op"`
+			}
+			if err := conn.ReadJSON(&frame); err != nil {
+				return
+			}
+			switch frame.Op {
+			case 1: // heartbeat
+				f.writeMu.Lock()
+				conn.WriteJSON(map[string]interface{}{"op": 11})
+				f.writeMu.Unlock()
+			case 2, 6: // identify, resume
+				f.mu.Lock()
+				f.seq++
+				seq := f.seq
+				f.mu.Unlock()
+				f.writeMu.Lock()
+				conn.WriteJSON(map[string]interface{}{
+					"op": 0, "t": "READY", "s": seq,
+					"d": map[string]interface{}{
+						"v":          9,
+						"session_id": "session-1",
+						"user":       map[string]interface{}{"id": "900", "username": "picobot", "discriminator": "0", "bot": true},
+						"guilds":     []interface{}{},
+					},
+				})
+				f.conn = conn
+				f.writeMu.Unlock()
+				select {
+				case f.conns <- conn:
+				default:
+				}
+			}
+		}
+	}()
+}
+
+func (f *Discord) dispatch(t *testing.T, event string, data interface{}) {
+	t.Helper()
+	deadline := time.After(waitTimeout)
+	for {
+		f.writeMu.Lock()
+		conn := f.conn
+		f.writeMu.Unlock()
+		if conn != nil {
+			break
+		}
+		select {
+		case <-f.conns:
+		case <-deadline:
+			t.Fatal("Discord client did not connect to the gateway")
+		}
+	}
+
+	f.mu.Lock()
+	f.seq++
+	seq := f.seq
+	f.mu.Unlock()
+
+	f.writeMu.Lock()
+	defer f.writeMu.Unlock()
+	if f.conn == nil {
+		t.Fatal("Discord client disconnected from the gateway")
+	}
+	if err := f.conn.WriteJSON(map[string]interface{}{"op": 0, "t": event, "s": seq, "d": data}); err != nil {
+		t.Fatalf("write gateway event: %v", err)
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (fn roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return fn(r) }
@@ -0,0 +1,195 @@
+package channeltest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// OneBotAction is an API call received from the bot.
+type OneBotAction struct {
+	Action string                 `json:"action"`
+	Params map[string]interface{} `json:"params"`
+	Echo   string                 `json:"echo"`
+}
+
+// OneBot is a fake OneBot v11 implementation serving the forward WebSocket
+// that the channel dials. Actions are answered with an ok response carrying
+// the request's echo; events are pushed to the connected bot.
+type OneBot struct {
+	srv    *httptest.Server
+	SelfID int64
+	// AccessToken, when set, is required as a Bearer token.
+	AccessToken string
+
+	mu        sync.Mutex
+	actions   []OneBotAction
+	messageID int64
+
+	conns   chan *websocket.Conn
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+}
+
+// NewOneBot starts a fake OneBot server that is closed when the test ends.
+func NewOneBot(t *testing.T) *OneBot {
+	f := &OneBot{SelfID: 99, conns: make(chan *websocket.Conn, 4)}
+	f.srv = httptest.NewServer(http.HandlerFunc(f.serveWS))
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+// URL is the WebSocket URL to configure as ws_url.
+func (f *OneBot) URL() string {
+	return "ws" + strings.TrimPrefix(f.srv.URL, "http")
+}
+
+// PushEvent sends a raw event to the bot, waiting for it to connect if needed.
+func (f *OneBot) PushEvent(t *testing.T, event map[string]interface{}) {
+	t.Helper()
+	deadline := time.After(waitTimeout)
+	for {
+		f.writeMu.Lock()
+		conn := f.conn
+		f.writeMu.Unlock()
+		if conn != nil {
+			break
+		}
+		select {
+		case <-f.conns:
+		case <-deadline:
+			t.Fatal("OneBot client did not connect")
+		}
+	}
+
+	if _, ok := event["self_id"]; !ok {
+		event["self_id"] = f.SelfID
+	}
+	if _, ok := event["time"]; !ok {
+		event["time"] = time.Now().Unix()
+	}
+
+	f.writeMu.Lock()
+	defer f.writeMu.Unlock()
+	if f.conn == nil {
+		t.Fatal("OneBot client disconnected")
+	}
+	if err := f.conn.WriteJSON(event); err != nil {
+		t.Fatalf("write OneBot event: %v", err)
+	}
+}
+
+// PushPrivateMessage sends a private text message event from userID.
+func (f *OneBot) PushPrivateMessage(t *testing.T, userID int64, text string) {
+	t.Helper()
+	f.PushEvent(t, map[string]interface{}{
+		"post_type":    "message",
+		"message_type": "private",
+		"sub_type":     "friend",
+		"message_id":   f.nextMessageID(),
+		"user_id":      userID,
+		"raw_message":  text,
+		"message":      text,
+		"sender":       map[string]interface{}{"user_id": userID, "nickname": "user"},
+	})
+}
+
+// PushGroupMessage sends a group text message event from userID in groupID.
+func (f *OneBot) PushGroupMessage(t *testing.T, groupID, userID int64, text string) {
+	t.Helper()
+	f.PushEvent(t, map[string]interface{}{
+		"post_type":    "message",
+		"message_type": "group",
+		"sub_type":     "normal",
+		"message_id":   f.nextMessageID(),
+		"group_id":     groupID,
+		"user_id":      userID,
+		"raw_message":  text,
+		"message":      text,
+		"sender":       map[string]interface{}{"user_id": userID, "nickname": "user"},
+	})
+}
+
+// Actions returns the API calls received so far, in order.
+func (f *OneBot) Actions() []OneBotAction {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]OneBotAction(nil), f.actions...)
+}
+
+func (f *OneBot) nextMessageID() int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.messageID++
+	return f.messageID
+}
+
+func (f *OneBot) serveWS(w http.ResponseWriter, r *http.Request) {
+	if f.AccessToken != "" && r.Header.Get("Authorization") != "Bearer "+f.AccessToken &&
+		r.URL.Query().Get("access_token") != f.AccessToken {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	conn, err := websocket.Upgrade(w, r, nil, 1024, 1024)
+	if err != nil {
+		return
+	}
+
+	f.writeMu.Lock()
+	f.conn = conn
+	err = conn.WriteJSON(map[string]interface{}{
+		"post_type":       "meta_event",
+		"meta_event_type": "lifecycle",
+		"sub_type":        "connect",
+		"self_id":         f.SelfID,
+		"time":            time.Now().Unix(),
+	})
+	f.writeMu.Unlock()
+	if err != nil {
+		return
+	}
+	select {
+	case f.conns <- conn:
+	default:
+	}
+
+	go func() {
+		defer func() {
+			f.writeMu.Lock()
+			if f.conn == conn {
+				f.conn = nil
+			}
+			f.writeMu.Unlock()
+			conn.Close()
+		}()
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var action OneBotAction
+			if err := json.Unmarshal(data, &action); err != nil {
+				continue
+			}
+			f.mu.Lock()
+			f.actions = append(f.actions, action)
+			f.mu.Unlock()
+
+			f.writeMu.Lock()
+			conn.WriteJSON(map[string]interface{}{
+				"status":  "ok",
+				"retcode": 0,
+				"data":    map[string]interface{}{"message_id": 1},
+				"echo":    action.Echo,
+			})
+			f.writeMu.Unlock()
+		}
+	}()
+}
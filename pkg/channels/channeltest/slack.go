@@ -0,0 +1,287 @@
+package channeltest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// SlackMessage is a chat.postMessage call received by the fake.
+type SlackMessage struct {
+	Channel  string
+	Text     string
+	ThreadTS string
+}
+
+// Slack is a fake Slack Web API and Socket Mode server. The Web API lives
+// under APIURL; apps.connections.open hands out a WebSocket URL on the same
+// server, which sends "hello", pings regularly and records envelope acks.
+type Slack struct {
+	srv *httptest.Server
+
+	mu        sync.Mutex
+	messages  []SlackMessage
+	reactions []string
+	acks      map[string]bool
+	envelope  int
+	ts        int
+
+	conns   chan *websocket.Conn
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+}
+
+// NewSlack starts a fake Slack server that is closed when the test ends.
+func NewSlack(t *testing.T) *Slack {
+	f := &Slack{
+		acks:  make(map[string]bool),
+		conns: make(chan *websocket.Conn, 4),
+	}
+	f.srv = httptest.NewServer(f)
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+// APIURL is the Web API base URL, for slack.OptionAPIURL.
+func (f *Slack) APIURL() string { return f.srv.URL + "/api/" }
+
+// PushEvent sends an Events API event over Socket Mode, waiting for the
+// client to connect if needed, and returns the envelope ID.
+func (f *Slack) PushEvent(t *testing.T, event map[string]interface{}) string {
+	t.Helper()
+	f.mu.Lock()
+	f.envelope++
+	id := fmt.Sprintf("env-%d", f.envelope)
+	f.mu.Unlock()
+
+	f.write(t, map[string]interface{}{
+		"envelope_id":              id,
+		"type":                     "events_api",
+		"accepts_response_payload": false,
+		"payload": map[string]interface{}{
+			"token":      "verification",
+			"team_id":    "T1",
+			"api_app_id": "A1",
+			"type":       "event_callback",
+			"event_id":   "Ev" + id,
+			"event_time": time.Now().Unix(),
+			"event":      event,
+		},
+	})
+	return id
+}
+
+// PushMessage sends a message event from user in channel and returns its ts.
+func (f *Slack) PushMessage(t *testing.T, user, channel, text string) string {
+	t.Helper()
+	ts := f.nextTS()
+	f.PushEvent(t, map[string]interface{}{
+		"type":         "message",
+		"user":         user,
+		"channel":      channel,
+		"text":         text,
+		"ts":           ts,
+		"event_ts":     ts,
+		"channel_type": "channel",
+	})
+	return ts
+}
+
+// PushSlashCommand sends a slash command over Socket Mode.
+func (f *Slack) PushSlashCommand(t *testing.T, user, channel, command, text string) {
+	t.Helper()
+	f.mu.Lock()
+	f.envelope++
+	id := fmt.Sprintf("env-%d", f.envelope)
+	f.mu.Unlock()
+
+	f.write(t, map[string]interface{}{
+		"envelope_id": id,
+		"type":        "slash_commands",
+		"payload": map[string]string{
+			"command":    command,
+			"text":       text,
+			"user_id":    user,
+			"channel_id": channel,
+			"trigger_id": "trigger-" + id,
+			// Sent as a string by Slack; required by slack.SlashCommand's decoder.
+			"is_enterprise_install": "false",
+		},
+	})
+}
+
+// Acked reports whether the client acknowledged envelopeID.
+func (f *Slack) Acked(envelopeID string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.acks[envelopeID]
+}
+
+// Messages returns the messages posted to channel.
+func (f *Slack) Messages(channel string) []SlackMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []SlackMessage
+	for _, m := range f.messages {
+		if m.Channel == channel {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// Reactions returns the reaction names added so far.
+func (f *Slack) Reactions() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.reactions...)
+}
+
+func (f *Slack) nextTS() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ts++
+	return fmt.Sprintf("%d.%06d", time.Now().Unix(), f.ts)
+}
+
+func (f *Slack) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/socket" {
+		f.serveSocket(w, r)
+		return
+	}
+
+	method := strings.TrimPrefix(r.URL.Path, "/api/")
+	r.ParseForm()
+
+	w.Header().Set("Content-Type", "application/json")
+	switch method {
+	case "auth.test":
+		w.Write([]byte(`{"ok":true,"url":"https://test.slack.com/","team":"Test","user":"picobot","team_id":"T1","user_id":"UBOT","bot_id":"BBOT"}`))
+	case "apps.connections.open":
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"ok":  true,
+			"url": "ws" + strings.TrimPrefix(f.srv.URL, "http") + "/socket",
+		})
+	case "chat.postMessage":
+		msg := SlackMessage{
+			Channel:  r.Form.Get("channel"),
+			Text:     r.Form.Get("text"),
+			ThreadTS: r.Form.Get("thread_ts"),
+		}
+		f.mu.Lock()
+		f.messages = append(f.messages, msg)
+		f.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"ok":      true,
+			"channel": msg.Channel,
+			"ts":      f.nextTS(),
+		})
+	case "reactions.add":
+		f.mu.Lock()
+		f.reactions = append(f.reactions, r.Form.Get("name"))
+		f.mu.Unlock()
+		w.Write([]byte(`{"ok":true}`))
+	default:
+		w.Write([]byte(`{"ok":true}`))
+	}
+}
+
+func (f *Slack) serveSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := websocket.Upgrade(w, r, nil, 1024, 1024)
+	if err != nil {
+		return
+	}
+
+	f.writeMu.Lock()
+	f.conn = conn
+	err = conn.WriteJSON(map[string]interface{}{
+		"type":            "hello",
+		"num_connections": 1,
+		"connection_info": map[string]string{"app_id": "A1"},
+	})
+	f.writeMu.Unlock()
+	if err != nil {
+		return
+	}
+	select {
+	case f.conns <- conn:
+	default:
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(500 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				f.writeMu.Lock()
+				err := conn.WriteControl(websocket.PingMessage, []byte("ping"), time.Now().Add(time.Second))
+				f.writeMu.Unlock()
+				if err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	go func() {
+		defer func() {
+			close(done)
+			f.writeMu.Lock()
+			if f.conn == conn {
+				f.conn = nil
+			}
+			f.writeMu.Unlock()
+		}()
+		for {
+			var ack struct {
+				EnvelopeID string `json:"envelope_id"`
+			}
+			if err := conn.ReadJSON(&ack); err != nil {
+				return
+			}
+			f.mu.Lock()
+			f.acks[ack.EnvelopeID] = true
+			f.mu.Unlock()
+		}
+	}()
+}
+
+// write sends a frame to the current Socket Mode connection, waiting for
+// the client to connect if it is not connected.
+func (f *Slack) write(t *testing.T, v interface{}) {
+	t.Helper()
+	deadline := time.After(waitTimeout)
+	for {
+		f.writeMu.Lock()
+		conn := f.conn
+		f.writeMu.Unlock()
+		if conn != nil {
+			break
+		}
+		select {
+		case <-f.conns:
+		case <-deadline:
+			t.Fatal("Socket Mode client did not connect")
+		}
+	}
+
+	f.writeMu.Lock()
+	defer f.writeMu.Unlock()
+	if f.conn == nil {
+		t.Fatal("Socket Mode client disconnected")
+	}
+	if err := f.conn.WriteJSON(v); err != nil {
+		t.Fatalf("write Socket Mode frame: %v", err)
+	}
+}
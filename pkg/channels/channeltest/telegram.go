@@ -0,0 +1,210 @@
+package channeltest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TelegramMessage is a message the bot sent, with edits applied.
+type TelegramMessage struct {
+	ID     int
+	ChatID string
+	Text   string
+}
+
+// Telegram is a fake Telegram Bot API server. It serves getMe and
+// getUpdates, keeps the messages the bot sends or edits, and answers any
+// other method with {"ok":true,"result":true} unless a response is set.
+type Telegram struct {
+	srv *httptest.Server
+
+	mu        sync.Mutex
+	calls     []string
+	responses map[string]string
+	updates   []string
+	updateID  int
+	messages  []TelegramMessage
+}
+
+// NewTelegram starts a fake Bot API server that is closed when the test ends.
+func NewTelegram(t *testing.T) *Telegram {
+	f := &Telegram{responses: make(map[string]string)}
+	f.srv = httptest.NewServer(f)
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+// URL is the API server to configure the bot client with.
+func (f *Telegram) URL() string { return f.srv.URL }
+
+// Client returns an HTTP client for the server.
+func (f *Telegram) Client() *http.Client { return f.srv.Client() }
+
+// SetResponse makes method answer with body instead of the default.
+func (f *Telegram) SetResponse(method, body string) {
+	f.mu.Lock()
+	f.responses[method] = body
+	f.mu.Unlock()
+}
+
+// PushUpdate queues a raw update for the next getUpdates call. The
+// update_id field is filled in if missing.
+func (f *Telegram) PushUpdate(update string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.updateID++
+	if !strings.Contains(update, `"update_id"`) {
+		update = fmt.Sprintf(`{"update_id":%d,%s`, f.updateID, strings.TrimPrefix(update, "{"))
+	}
+	f.updates = append(f.updates, update)
+}
+
+// PushMessage queues a private text message from userID.
+func (f *Telegram) PushMessage(userID int64, username, text string) {
+	from := map[string]interface{}{"id": userID, "is_bot": false, "first_name": "user" + strconv.FormatInt(userID, 10)}
+	if username != "" {
+		from["username"] = username
+	}
+	f.mu.Lock()
+	messageID := f.updateID + 1
+	f.mu.Unlock()
+	message, _ := json.Marshal(map[string]interface{}{
+		"message_id": messageID,
+		"date":       time.Now().Unix(),
+		"chat":       map[string]interface{}{"id": userID, "type": "private"},
+		"from":       from,
+		"text":       text,
+	})
+	f.PushUpdate(`{"message":` + string(message) + `}`)
+}
+
+// Called reports whether method has been called.
+func (f *Telegram) Called(method string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, m := range f.calls {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// Calls returns the methods called so far, in order.
+func (f *Telegram) Calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.calls...)
+}
+
+// Messages returns the messages the bot sent to chatID.
+func (f *Telegram) Messages(chatID string) []TelegramMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []TelegramMessage
+	for _, m := range f.messages {
+		if m.ChatID == chatID {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+func (f *Telegram) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	method := path.Base(r.URL.Path)
+	params := telegramParams(r)
+
+	f.mu.Lock()
+	f.calls = append(f.calls, method)
+	resp, hasResp := f.responses[method]
+	var pending []string
+	if method == "getUpdates" {
+		pending, f.updates = f.updates, nil
+	}
+	f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case hasResp:
+		w.Write([]byte(resp))
+	case method == "getUpdates":
+		if len(pending) == 0 {
+			time.Sleep(20 * time.Millisecond)
+		}
+		w.Write([]byte(`{"ok":true,"result":[` + strings.Join(pending, ",") + `]}`))
+	case method == "getMe":
+		w.Write([]byte(`{"ok":true,"result":{"id":1,"is_bot":true,"first_name":"bot","username":"testbot"}}`))
+	case method == "sendMessage":
+		f.mu.Lock()
+		msg := TelegramMessage{ID: len(f.messages) + 1, ChatID: params["chat_id"], Text: params["text"]}
+		f.messages = append(f.messages, msg)
+		f.mu.Unlock()
+		writeTelegramMessage(w, msg)
+	case method == "editMessageText":
+		id, _ := strconv.Atoi(params["message_id"])
+		f.mu.Lock()
+		for i := range f.messages {
+			if f.messages[i].ID == id && f.messages[i].ChatID == params["chat_id"] {
+				f.messages[i].Text = params["text"]
+			}
+		}
+		f.mu.Unlock()
+		writeTelegramMessage(w, TelegramMessage{ID: id, ChatID: params["chat_id"], Text: params["text"]})
+	default:
+		w.Write([]byte(`{"ok":true,"result":true}`))
+	}
+}
+
+func writeTelegramMessage(w http.ResponseWriter, msg TelegramMessage) {
+	chatID, _ := strconv.ParseInt(msg.ChatID, 10, 64)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"ok": true,
+		"result": map[string]interface{}{
+			"message_id": msg.ID,
+			"date":       time.Now().Unix(),
+			"chat":       map[string]interface{}{"id": chatID, "type": "private"},
+			"text":       msg.Text,
+		},
+	})
+}
+
+// telegramParams flattens a JSON or form request body into strings.
+func telegramParams(r *http.Request) map[string]string {
+	params := make(map[string]string)
+	body, _ := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var raw map[string]json.RawMessage
+		if json.Unmarshal(body, &raw) == nil {
+			for k, v := range raw {
+				var s string
+				if json.Unmarshal(v, &s) == nil {
+					params[k] = s
+				} else {
+					params[k] = string(v)
+				}
+			}
+		}
+		return params
+	}
+
+	if err := r.ParseMultipartForm(10 << 20); err == nil || r.ParseForm() == nil {
+		for k, v := range r.Form {
+			if len(v) > 0 {
+				params[k] = v[0]
+			}
+		}
+	}
+	return params
+}
@@ -0,0 +1,172 @@
+package channels
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/mymmrac/telego"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/channels/channeltest"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestTelegramConformance(t *testing.T) {
+	channeltest.Run(t, func(t *testing.T, mb *bus.MessageBus, allowFrom []string) (channeltest.Channel, channeltest.Platform) {
+		fake := channeltest.NewTelegram(t)
+		ch, err := NewTelegramChannel(config.TelegramConfig{Token: testTelegramToken, AllowFrom: allowFrom}, mb)
+		if err != nil {
+			t.Fatalf("NewTelegramChannel: %v", err)
+		}
+		ch.bot, err = telego.NewBot(testTelegramToken,
+			telego.WithAPIServer(fake.URL()),
+			telego.WithHTTPClient(fake.Client()),
+			telego.WithDiscardLogger())
+		if err != nil {
+			t.Fatalf("NewBot: %v", err)
+		}
+		return ch, telegramPlatform{fake}
+	}, channeltest.Options{ChatID: "4242", MaxMessageLen: 4096})
+}
+
+type telegramPlatform struct{ fake *channeltest.Telegram }
+
+func (p telegramPlatform) Deliver(t *testing.T, senderID, text string) string {
+	userID, _ := strconv.ParseInt(senderID, 10, 64)
+	p.fake.PushMessage(userID, "", text)
+	return senderID // private chats share the user's ID
+}
+
+func (p telegramPlatform) Sent(chatID string) []string {
+	var texts []string
+	for _, m := range p.fake.Messages(chatID) {
+		texts = append(texts, m.Text)
+	}
+	return texts
+}
+
+func TestSlackConformance(t *testing.T) {
+	channeltest.Run(t, func(t *testing.T, mb *bus.MessageBus, allowFrom []string) (channeltest.Channel, channeltest.Platform) {
+		fake := channeltest.NewSlack(t)
+		return newTestSlackChannel(t, fake, mb, allowFrom), slackPlatform{fake}
+	}, channeltest.Options{ChatID: "C100", MaxMessageLen: slackMaxMessageLen})
+}
+
+type slackPlatform struct{ fake *channeltest.Slack }
+
+func (p slackPlatform) Deliver(t *testing.T, senderID, text string) string {
+	p.fake.PushMessage(t, senderID, "C100", text)
+	return "C100"
+}
+
+func (p slackPlatform) Sent(chatID string) []string {
+	var texts []string
+	for _, m := range p.fake.Messages(chatID) {
+		texts = append(texts, m.Text)
+	}
+	return texts
+}
+
+func TestDiscordConformance(t *testing.T) {
+	channeltest.Run(t, func(t *testing.T, mb *bus.MessageBus, allowFrom []string) (channeltest.Channel, channeltest.Platform) {
+		fake := channeltest.NewDiscord(t)
+		ch, err := NewDiscordChannel(config.DiscordConfig{Token: "test-token", AllowFrom: allowFrom}, mb)
+		if err != nil {
+			t.Fatalf("NewDiscordChannel: %v", err)
+		}
+		ch.session.Client = fake.HTTPClient()
+		return ch, discordPlatform{fake}
+	}, channeltest.Options{ChatID: "700", MaxMessageLen: discordMaxMessageLen})
+}
+
+type discordPlatform struct{ fake *channeltest.Discord }
+
+func (p discordPlatform) Deliver(t *testing.T, senderID, text string) string {
+	p.fake.PushMessage(t, senderID, "700", text)
+	return "700"
+}
+
+func (p discordPlatform) Sent(chatID string) []string {
+	var texts []string
+	for _, m := range p.fake.Messages(chatID) {
+		texts = append(texts, m.Content)
+	}
+	return texts
+}
+
+func TestOneBotConformance(t *testing.T) {
+	channeltest.Run(t, func(t *testing.T, mb *bus.MessageBus, allowFrom []string) (channeltest.Channel, channeltest.Platform) {
+		fake := channeltest.NewOneBot(t)
+		fake.AccessToken = "tok"
+		ch, err := NewOneBotChannel(config.OneBotConfig{WSUrl: fake.URL(), AccessToken: "tok", AllowFrom: allowFrom}, mb)
+		if err != nil {
+			t.Fatalf("NewOneBotChannel: %v", err)
+		}
+		return ch, oneBotPlatform{fake}
+	}, channeltest.Options{ChatID: "private:1001", MaxMessageLen: oneBotMaxMessageLen})
+}
+
+type oneBotPlatform struct{ fake *channeltest.OneBot }
+
+func (p oneBotPlatform) Deliver(t *testing.T, senderID, text string) string {
+	userID, _ := strconv.ParseInt(senderID, 10, 64)
+	p.fake.PushPrivateMessage(t, userID, text)
+	return "private:" + senderID
+}
+
+func (p oneBotPlatform) Sent(chatID string) []string {
+	var texts []string
+	for _, a := range p.fake.Actions() {
+		if a.Action == "send_private_msg" && "private:"+fmt.Sprint(a.Params["user_id"]) == chatID {
+			texts = append(texts, fmt.Sprint(a.Params["message"]))
+		}
+	}
+	return texts
+}
+
+func TestMattermostConformance(t *testing.T) {
+	channeltest.Run(t, func(t *testing.T, mb *bus.MessageBus, allowFrom []string) (channeltest.Channel, channeltest.Platform) {
+		fake, srv := newFakeMattermost(t)
+		ch, err := NewMattermostChannel(config.MattermostConfig{ServerURL: srv.URL, Token: fake.token, AllowFrom: allowFrom}, mb)
+		if err != nil {
+			t.Fatalf("NewMattermostChannel: %v", err)
+		}
+		return ch, &mattermostPlatform{fake: fake}
+	}, channeltest.Options{ChatID: "town", MaxMessageLen: mattermostMaxMessageLen})
+}
+
+type mattermostPlatform struct {
+	fake *fakeMattermost
+	conn *websocket.Conn
+}
+
+func (p *mattermostPlatform) Deliver(t *testing.T, senderID, text string) string {
+	t.Helper()
+	if p.conn == nil {
+		select {
+		case p.conn = <-p.fake.conns:
+		case <-time.After(2 * time.Second):
+			t.Fatal("channel did not open the WebSocket")
+		}
+	}
+	channelID := "dm-" + senderID
+	pushPosted(t, p.conn, "D", map[string]interface{}{
+		"id": "p-" + strconv.FormatInt(time.Now().UnixNano(), 36), "user_id": senderID, "channel_id": channelID, "message": text,
+	}, nil)
+	return channelID
+}
+
+func (p *mattermostPlatform) Sent(chatID string) []string {
+	p.fake.mu.Lock()
+	defer p.fake.mu.Unlock()
+	var texts []string
+	for _, post := range p.fake.posts {
+		if post.ChannelID == chatID {
+			texts = append(texts, post.Message)
+		}
+	}
+	return texts
+}
@@ -4,12 +4,14 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/network"
 	"github.com/sipeed/picoclaw/pkg/utils"
 	"github.com/sipeed/picoclaw/pkg/voice"
 )
@@ -17,6 +19,8 @@ import (
 const (
 	transcriptionTimeout = 30 * time.Second
 	sendTimeout          = 10 * time.Second
+
+	discordMaxMessageLen = 2000
 )
 
 type DiscordChannel struct {
@@ -25,6 +29,8 @@ type DiscordChannel struct {
 	config      config.DiscordConfig
 	transcriber *voice.GroqTranscriber
 	ctx         context.Context
+
+	placeholders sync.Map // channel ID -> message ID of the progress placeholder
 }
 
 func NewDiscordChannel(cfg config.DiscordConfig, bus *bus.MessageBus) (*DiscordChannel, error) {
@@ -32,16 +38,21 @@ func NewDiscordChannel(cfg config.DiscordConfig, bus *bus.MessageBus) (*DiscordC
 	if err != nil {
 		return nil, fmt.Errorf("failed to create discord session: %w", err)
 	}
+	session.Client = network.NewClient("discord", network.Options{Timeout: 20 * time.Second})
 
 	base := NewBaseChannel("discord", cfg, bus, cfg.AllowFrom)
 
-	return &DiscordChannel{
+	c := &DiscordChannel{
 		BaseChannel: base,
 		session:     session,
 		config:      cfg,
 		transcriber: nil,
 		ctx:         context.Background(),
-	}, nil
+	}
+	// Registered once so that restarting the channel does not duplicate handlers.
+	session.AddHandler(c.handleMessage)
+
+	return c, nil
 }
 
 func (c *DiscordChannel) SetTranscriber(transcriber *voice.GroqTranscriber) {
@@ -56,10 +67,13 @@ func (c *DiscordChannel) getContext() context.Context {
 }
 
 func (c *DiscordChannel) Start(ctx context.Context) error {
+	if c.IsRunning() {
+		return nil
+	}
+
 	logger.InfoC("discord", "Starting Discord bot")
 
 	c.ctx = ctx
-	c.session.AddHandler(c.handleMessage)
 
 	if err := c.session.Open(); err != nil {
 		return fmt.Errorf("failed to open discord session: %w", err)
@@ -100,8 +114,23 @@ func (c *DiscordChannel) Send(ctx context.Context, msg bus.OutboundMessage) erro
 		return fmt.Errorf("channel ID is empty")
 	}
 
-	message := msg.Content
+	// The reply is sent as a new message, which notifies the user where an
+	// edit would not, so the placeholder goes first.
+	if err := c.ClearProgress(ctx, channelID); err != nil {
+		logger.DebugCF("discord", "Failed to remove progress placeholder", map[string]any{
+			"error": err.Error(),
+		})
+	}
 
+	for _, chunk := range utils.SplitMessage(msg.Content, discordMaxMessageLen) {
+		if err := c.sendChunk(ctx, channelID, chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *DiscordChannel) sendChunk(ctx context.Context, channelID, message string) error {
 	// 使用传入的 ctx 进行超时控制
 	sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
 	defer cancel()
@@ -123,6 +152,42 @@ func (c *DiscordChannel) Send(ctx context.Context, msg bus.OutboundMessage) erro
 	}
 }
 
+// SendTyping implements TypingChannel. Discord shows the indicator for ten
+// seconds or until the bot posts.
+func (c *DiscordChannel) SendTyping(ctx context.Context, chatID string) error {
+	if !c.IsRunning() {
+		return fmt.Errorf("discord bot not running")
+	}
+	return c.session.ChannelTyping(chatID, discordgo.WithContext(ctx))
+}
+
+// SendProgress implements ProgressChannel, posting the placeholder on the
+// first status of a turn and editing it afterwards.
+func (c *DiscordChannel) SendProgress(ctx context.Context, chatID, status string) error {
+	if !c.IsRunning() {
+		return fmt.Errorf("discord bot not running")
+	}
+	if id, ok := c.placeholders.Load(chatID); ok {
+		_, err := c.session.ChannelMessageEdit(chatID, id.(string), status, discordgo.WithContext(ctx))
+		return err
+	}
+	m, err := c.session.ChannelMessageSend(chatID, status, discordgo.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	c.placeholders.Store(chatID, m.ID)
+	return nil
+}
+
+// ClearProgress implements ProgressChannel.
+func (c *DiscordChannel) ClearProgress(ctx context.Context, chatID string) error {
+	id, ok := c.placeholders.LoadAndDelete(chatID)
+	if !ok {
+		return nil
+	}
+	return c.session.ChannelMessageDelete(chatID, id.(string), discordgo.WithContext(ctx))
+}
+
 // appendContent 安全地追加内容到现有文本
 func appendContent(content, suffix string) string {
 	if content == "" {
@@ -140,12 +205,6 @@ func (c *DiscordChannel) handleMessage(s *discordgo.Session, m *discordgo.Messag
 		return
 	}
 
-	if err := c.session.ChannelTyping(m.ChannelID); err != nil {
-		logger.ErrorCF("discord", "Failed to send typing indicator", map[string]any{
-			"error": err.Error(),
-		})
-	}
-
 	// 检查白名单，避免为被拒绝的用户下载附件和转录
 	if !c.IsAllowed(m.Author.ID) {
 		logger.DebugCF("discord", "Message rejected by allowlist", map[string]any{
@@ -28,6 +28,8 @@ type FeishuChannel struct {
 
 	mu     sync.Mutex
 	cancel context.CancelFunc
+
+	placeholders sync.Map // chat ID -> message ID of the progress placeholder
 }
 
 func NewFeishuChannel(cfg config.FeishuConfig, bus *bus.MessageBus) (*FeishuChannel, error) {
@@ -97,35 +99,120 @@ func (c *FeishuChannel) Send(ctx context.Context, msg bus.OutboundMessage) error
 		return fmt.Errorf("chat ID is empty")
 	}
 
-	payload, err := json.Marshal(map[string]string{"text": msg.Content})
+	// The reply is sent as a new message, which notifies the user where an
+	// edit would not, so the placeholder goes first.
+	if err := c.ClearProgress(ctx, msg.ChatID); err != nil {
+		logger.DebugCF("feishu", "Failed to remove progress placeholder", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	if _, err := c.createText(ctx, msg.ChatID, msg.Content); err != nil {
+		return err
+	}
+
+	logger.DebugCF("feishu", "Feishu message sent", map[string]interface{}{
+		"chat_id": msg.ChatID,
+	})
+
+	return nil
+}
+
+// SendProgress implements ProgressChannel, sending the placeholder on the
+// first status of a turn and editing it afterwards.
+func (c *FeishuChannel) SendProgress(ctx context.Context, chatID, status string) error {
+	if !c.IsRunning() {
+		return fmt.Errorf("feishu channel not running")
+	}
+
+	if id, ok := c.placeholders.Load(chatID); ok {
+		content, err := feishuText(status)
+		if err != nil {
+			return err
+		}
+		req := larkim.NewUpdateMessageReqBuilder().
+			MessageId(id.(string)).
+			Body(larkim.NewUpdateMessageReqBodyBuilder().
+				MsgType(larkim.MsgTypeText).
+				Content(content).
+				Build()).
+			Build()
+		resp, err := c.client.Im.V1.Message.Update(ctx, req)
+		if err != nil {
+			return fmt.Errorf("failed to edit feishu message: %w", err)
+		}
+		if !resp.Success() {
+			return fmt.Errorf("feishu api error: code=%d msg=%s", resp.Code, resp.Msg)
+		}
+		return nil
+	}
+
+	id, err := c.createText(ctx, chatID, status)
 	if err != nil {
-		return fmt.Errorf("failed to marshal feishu content: %w", err)
+		return err
+	}
+	if id != "" {
+		c.placeholders.Store(chatID, id)
+	}
+	return nil
+}
+
+// ClearProgress implements ProgressChannel.
+func (c *FeishuChannel) ClearProgress(ctx context.Context, chatID string) error {
+	id, ok := c.placeholders.LoadAndDelete(chatID)
+	if !ok {
+		return nil
+	}
+	req := larkim.NewDeleteMessageReqBuilder().MessageId(id.(string)).Build()
+	resp, err := c.client.Im.V1.Message.Delete(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to delete feishu message: %w", err)
+	}
+	if !resp.Success() {
+		return fmt.Errorf("feishu api error: code=%d msg=%s", resp.Code, resp.Msg)
+	}
+	return nil
+}
+
+// createText sends text to a chat and returns the ID of the new message.
+func (c *FeishuChannel) createText(ctx context.Context, chatID, text string) (string, error) {
+	content, err := feishuText(text)
+	if err != nil {
+		return "", err
 	}
 
 	req := larkim.NewCreateMessageReqBuilder().
 		ReceiveIdType(larkim.ReceiveIdTypeChatId).
 		Body(larkim.NewCreateMessageReqBodyBuilder().
-			ReceiveId(msg.ChatID).
+			ReceiveId(chatID).
 			MsgType(larkim.MsgTypeText).
-			Content(string(payload)).
+			Content(content).
 			Uuid(fmt.Sprintf("picoclaw-%d", time.Now().UnixNano())).
 			Build()).
 		Build()
 
 	resp, err := c.client.Im.V1.Message.Create(ctx, req)
 	if err != nil {
-		return fmt.Errorf("failed to send feishu message: %w", err)
+		return "", fmt.Errorf("failed to send feishu message: %w", err)
 	}
 
 	if !resp.Success() {
-		return fmt.Errorf("feishu api error: code=%d msg=%s", resp.Code, resp.Msg)
+		return "", fmt.Errorf("feishu api error: code=%d msg=%s", resp.Code, resp.Msg)
 	}
 
-	logger.DebugCF("feishu", "Feishu message sent", map[string]interface{}{
-		"chat_id": msg.ChatID,
-	})
+	if resp.Data == nil || resp.Data.MessageId == nil {
+		return "", nil
+	}
+	return *resp.Data.MessageId, nil
+}
 
-	return nil
+// feishuText returns the content of a text message.
+func feishuText(text string) (string, error) {
+	payload, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal feishu content: %w", err)
+	}
+	return string(payload), nil
 }
 
 func (c *FeishuChannel) handleMessageReceive(_ context.Context, event *larkim.P2MessageReceiveV1) error {
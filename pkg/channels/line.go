@@ -18,6 +18,7 @@ import (
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/network"
 	"github.com/sipeed/picoclaw/pkg/utils"
 )
 
@@ -124,7 +125,7 @@ func (c *LINEChannel) fetchBotInfo() error {
 	}
 	req.Header.Set("Authorization", "Bearer "+c.config.ChannelAccessToken)
 
-	client := &http.Client{Timeout: 10 * time.Second}
+	client := network.NewClient("line", network.Options{Timeout: 10 * time.Second})
 	resp, err := client.Do(req)
 	if err != nil {
 		return err
@@ -374,9 +375,6 @@ func (c *LINEChannel) processEvent(event lineEvent) {
 		"preview":      utils.Truncate(content, 50),
 	})
 
-	// Show typing/loading indicator (requires user ID, not group ID)
-	c.sendLoading(senderID)
-
 	c.HandleMessage(senderID, chatID, content, mediaPaths, metadata)
 }
 
@@ -543,17 +541,18 @@ func (c *LINEChannel) sendPush(ctx context.Context, to, content, quoteToken stri
 	return c.callAPI(ctx, linePushEndpoint, payload)
 }
 
-// sendLoading sends a loading animation indicator to the chat.
-func (c *LINEChannel) sendLoading(chatID string) {
+// SendTyping implements TypingChannel with the loading animation, which
+// LINE only shows in one-on-one chats. The animation ends early when the
+// bot replies.
+func (c *LINEChannel) SendTyping(ctx context.Context, chatID string) error {
+	if !strings.HasPrefix(chatID, "U") {
+		return nil
+	}
 	payload := map[string]interface{}{
 		"chatId":         chatID,
-		"loadingSeconds": 60,
-	}
-	if err := c.callAPI(c.ctx, lineLoadingEndpoint, payload); err != nil {
-		logger.DebugCF("line", "Failed to send loading indicator", map[string]interface{}{
-			"error": err.Error(),
-		})
+		"loadingSeconds": 10,
 	}
+	return c.callAPI(ctx, lineLoadingEndpoint, payload)
 }
 
 // callAPI makes an authenticated POST request to the LINE API.
@@ -571,7 +570,7 @@ func (c *LINEChannel) callAPI(ctx context.Context, endpoint string, payload inte
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+c.config.ChannelAccessToken)
 
-	client := &http.Client{Timeout: 30 * time.Second}
+	client := network.NewClient("line", network.Options{Timeout: 30 * time.Second})
 	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("API request failed: %w", err)
@@ -9,12 +9,16 @@ package channels
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"sync"
+	"time"
 
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/constants"
 	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/media"
+	"github.com/sipeed/picoclaw/pkg/state"
 )
 
 type Manager struct {
@@ -22,9 +26,21 @@ type Manager struct {
 	bus          *bus.MessageBus
 	config       *config.Config
 	dispatchTask *asyncTask
+	media        *media.Store
+	state        *state.Manager
 	mu           sync.RWMutex
 }
 
+// mediaChannel is implemented by channels embedding BaseChannel.
+type mediaChannel interface {
+	SetMediaStore(store *media.Store)
+}
+
+// stateChannel is implemented by channels embedding BaseChannel.
+type stateChannel interface {
+	SetStateStore(store *state.Manager)
+}
+
 type asyncTask struct {
 	cancel context.CancelFunc
 }
@@ -163,7 +179,8 @@ func (m *Manager) initChannels() error {
 		}
 	}
 
-	if m.config.Channels.OneBot.Enabled && m.config.Channels.OneBot.WSUrl != "" {
+	oneBotCfg := m.config.Channels.OneBot
+	if oneBotCfg.Enabled && (oneBotCfg.WSUrl != "" || oneBotCfg.Mode == "reverse" || oneBotCfg.Mode == "http") {
 		logger.DebugC("channels", "Attempting to initialize OneBot channel")
 		onebot, err := NewOneBotChannel(m.config.Channels.OneBot, m.bus)
 		if err != nil {
@@ -176,6 +193,32 @@ func (m *Manager) initChannels() error {
 		}
 	}
 
+	if m.config.Channels.Mattermost.Enabled && m.config.Channels.Mattermost.Token != "" {
+		logger.DebugC("channels", "Attempting to initialize Mattermost channel")
+		mattermost, err := NewMattermostChannel(m.config.Channels.Mattermost, m.bus)
+		if err != nil {
+			logger.ErrorCF("channels", "Failed to initialize Mattermost channel", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else {
+			m.channels["mattermost"] = mattermost
+			logger.InfoC("channels", "Mattermost channel enabled successfully")
+		}
+	}
+
+	if m.config.Channels.Teams.Enabled && m.config.Channels.Teams.AppID != "" {
+		logger.DebugC("channels", "Attempting to initialize Teams channel")
+		teams, err := NewTeamsChannel(m.config.Channels.Teams, m.bus)
+		if err != nil {
+			logger.ErrorCF("channels", "Failed to initialize Teams channel", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else {
+			m.channels["teams"] = teams
+			logger.InfoC("channels", "Teams channel enabled successfully")
+		}
+	}
+
 	logger.InfoCF("channels", "Channel initialization completed", map[string]interface{}{
 		"enabled_channels": len(m.channels),
 	})
@@ -272,6 +315,11 @@ func (m *Manager) dispatchOutbound(ctx context.Context) {
 				continue
 			}
 
+			if msg.Activity != nil {
+				m.showActivity(ctx, channel, msg)
+				continue
+			}
+
 			if err := channel.Send(ctx, msg); err != nil {
 				logger.ErrorCF("channels", "Error sending message to channel", map[string]interface{}{
 					"channel": msg.Channel,
@@ -282,6 +330,67 @@ func (m *Manager) dispatchOutbound(ctx context.Context) {
 	}
 }
 
+// activityTimeout bounds a typing or progress update, so a slow channel
+// API delays the replies queued behind it only briefly.
+const activityTimeout = 5 * time.Second
+
+// showActivity passes a typing or progress indicator to channel if it
+// supports it and drops it otherwise. Failures are not worth more than a
+// debug line: the next update or the reply follows shortly.
+func (m *Manager) showActivity(ctx context.Context, channel Channel, msg bus.OutboundMessage) {
+	ctx, cancel := context.WithTimeout(ctx, activityTimeout)
+	defer cancel()
+
+	var err error
+	switch msg.Activity.Kind {
+	case bus.ActivityTyping:
+		if tc, ok := channel.(TypingChannel); ok {
+			err = tc.SendTyping(ctx, msg.ChatID)
+		}
+	case bus.ActivityStatus:
+		if pc, ok := channel.(ProgressChannel); ok {
+			err = pc.SendProgress(ctx, msg.ChatID, msg.Activity.Status)
+		}
+	case bus.ActivityDone:
+		if pc, ok := channel.(ProgressChannel); ok {
+			err = pc.ClearProgress(ctx, msg.ChatID)
+		}
+	}
+	if err != nil {
+		logger.DebugCF("channels", "Failed to show activity", map[string]interface{}{
+			"channel": msg.Channel,
+			"kind":    msg.Activity.Kind,
+			"error":   err.Error(),
+		})
+	}
+}
+
+// RegisterWebhooks registers the webhook handlers of all enabled channels
+// that implement WebhookChannel and returns the number registered.
+func (m *Manager) RegisterWebhooks(register func(pattern string, handler http.Handler)) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	count := 0
+	for name, channel := range m.channels {
+		wc, ok := channel.(WebhookChannel)
+		if !ok {
+			continue
+		}
+		path := wc.WebhookPath()
+		if path == "" {
+			continue
+		}
+		register(path, wc.WebhookHandler())
+		logger.InfoCF("channels", "Webhook registered", map[string]interface{}{
+			"channel": name,
+			"path":    path,
+		})
+		count++
+	}
+	return count
+}
+
 func (m *Manager) GetChannel(name string) (Channel, bool) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -317,9 +426,41 @@ func (m *Manager) GetEnabledChannels() []string {
 func (m *Manager) RegisterChannel(name string, channel Channel) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	if mc, ok := channel.(mediaChannel); ok && m.media != nil {
+		mc.SetMediaStore(m.media)
+	}
+	if sc, ok := channel.(stateChannel); ok && m.state != nil {
+		sc.SetStateStore(m.state)
+	}
 	m.channels[name] = channel
 }
 
+// SetMediaStore makes all channels, including ones registered later, keep
+// inbound media in store.
+func (m *Manager) SetMediaStore(store *media.Store) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.media = store
+	for _, channel := range m.channels {
+		if mc, ok := channel.(mediaChannel); ok {
+			mc.SetMediaStore(store)
+		}
+	}
+}
+
+// SetStateStore makes all channels, including ones registered later, keep
+// their state in store.
+func (m *Manager) SetStateStore(store *state.Manager) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state = store
+	for _, channel := range m.channels {
+		if sc, ok := channel.(stateChannel); ok {
+			sc.SetStateStore(store)
+		}
+	}
+}
+
 func (m *Manager) UnregisterChannel(name string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -0,0 +1,690 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/network"
+	"github.com/sipeed/picoclaw/pkg/utils"
+	"github.com/sipeed/picoclaw/pkg/voice"
+)
+
+// mattermostMaxMessageLen is the server's default post size limit (in runes).
+const mattermostMaxMessageLen = 16383
+
+// MattermostChannel receives posts over the Mattermost WebSocket event API and
+// replies through the REST API. Chat IDs are "<channel_id>" or
+// "<channel_id>/<root_id>" for threads, mirroring SlackChannel.
+type MattermostChannel struct {
+	*BaseChannel
+	config      config.MattermostConfig
+	baseURL     string
+	httpClient  *http.Client
+	botUserID   string
+	botUsername string
+	transcriber *voice.GroqTranscriber
+	cancel      context.CancelFunc
+	conn        *websocket.Conn
+	mu          sync.Mutex
+	writeMu     sync.Mutex
+	seq         int64
+
+	placeholders sync.Map // chat ID -> ID of the progress placeholder post
+}
+
+type mattermostWSEvent struct {
+	Event string                     `json:"event"`
+	Data  map[string]json.RawMessage `json:"data"`
+}
+
+type mattermostFileInfo struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	MimeType string `json:"mime_type"`
+}
+
+type mattermostPost struct {
+	ID        string                 `json:"id"`
+	UserID    string                 `json:"user_id"`
+	ChannelID string                 `json:"channel_id"`
+	RootID    string                 `json:"root_id"`
+	Message   string                 `json:"message"`
+	Type      string                 `json:"type"`
+	FileIDs   []string               `json:"file_ids,omitempty"`
+	Props     map[string]interface{} `json:"props,omitempty"`
+	Metadata  struct {
+		Files []mattermostFileInfo `json:"files"`
+	} `json:"metadata"`
+}
+
+type mattermostCreatePost struct {
+	ChannelID string   `json:"channel_id"`
+	Message   string   `json:"message"`
+	RootID    string   `json:"root_id,omitempty"`
+	FileIDs   []string `json:"file_ids,omitempty"`
+}
+
+func NewMattermostChannel(cfg config.MattermostConfig, messageBus *bus.MessageBus) (*MattermostChannel, error) {
+	if cfg.ServerURL == "" || cfg.Token == "" {
+		return nil, fmt.Errorf("mattermost server_url and token are required")
+	}
+
+	base := NewBaseChannel("mattermost", cfg, messageBus, cfg.AllowFrom)
+
+	return &MattermostChannel{
+		BaseChannel: base,
+		config:      cfg,
+		baseURL:     strings.TrimRight(cfg.ServerURL, "/"),
+		httpClient:  network.NewClient("mattermost", network.Options{Timeout: 60 * time.Second}),
+	}, nil
+}
+
+func (c *MattermostChannel) SetTranscriber(transcriber *voice.GroqTranscriber) {
+	c.transcriber = transcriber
+}
+
+func (c *MattermostChannel) Start(ctx context.Context) error {
+	if c.IsRunning() {
+		return nil
+	}
+
+	logger.InfoC("mattermost", "Starting Mattermost channel")
+
+	var me struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+	}
+	if err := c.apiJSON(ctx, http.MethodGet, "/users/me", nil, &me); err != nil {
+		return fmt.Errorf("mattermost auth check failed: %w", err)
+	}
+	c.botUserID = me.ID
+	c.botUsername = me.Username
+
+	logger.InfoCF("mattermost", "Mattermost bot connected", map[string]interface{}{
+		"bot_user_id": c.botUserID,
+		"username":    c.botUsername,
+	})
+
+	runCtx, cancel := context.WithCancel(ctx)
+	c.cancel = cancel
+	go c.websocketLoop(runCtx)
+
+	c.setRunning(true)
+	logger.InfoC("mattermost", "Mattermost channel started")
+	return nil
+}
+
+func (c *MattermostChannel) Stop(ctx context.Context) error {
+	logger.InfoC("mattermost", "Stopping Mattermost channel")
+
+	if c.cancel != nil {
+		c.cancel()
+	}
+
+	c.mu.Lock()
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+	c.mu.Unlock()
+
+	c.setRunning(false)
+	logger.InfoC("mattermost", "Mattermost channel stopped")
+	return nil
+}
+
+func (c *MattermostChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	if !c.IsRunning() {
+		return fmt.Errorf("mattermost channel not running")
+	}
+
+	channelID, rootID := parseMattermostChatID(msg.ChatID)
+	if channelID == "" {
+		return fmt.Errorf("invalid mattermost chat ID: %s", msg.ChatID)
+	}
+
+	// The reply is posted as a new message, which notifies the user where an
+	// edit would not, so the placeholder goes first.
+	if err := c.ClearProgress(ctx, msg.ChatID); err != nil {
+		logger.DebugCF("mattermost", "Failed to remove progress placeholder", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	var fileIDs []string
+	for _, path := range msg.Media {
+		fileID, err := c.uploadFile(ctx, channelID, path)
+		if err != nil {
+			return fmt.Errorf("failed to upload mattermost file: %w", err)
+		}
+		fileIDs = append(fileIDs, fileID)
+	}
+
+	chunks := utils.SplitMessage(msg.Content, mattermostMaxMessageLen)
+	for i, chunk := range chunks {
+		post := mattermostCreatePost{
+			ChannelID: channelID,
+			Message:   chunk,
+			RootID:    rootID,
+		}
+		if i == len(chunks)-1 {
+			post.FileIDs = fileIDs
+		}
+		if err := c.apiJSON(ctx, http.MethodPost, "/posts", post, nil); err != nil {
+			return fmt.Errorf("failed to send mattermost message: %w", err)
+		}
+	}
+
+	logger.DebugCF("mattermost", "Message sent", map[string]interface{}{
+		"channel_id": channelID,
+		"root_id":    rootID,
+		"files":      len(fileIDs),
+	})
+
+	return nil
+}
+
+// websocketLoop keeps the event connection open, reconnecting with backoff.
+// The loop ends when ctx, the run context of one Start, is canceled.
+func (c *MattermostChannel) websocketLoop(ctx context.Context) {
+	backoff := time.Second
+	for {
+		conn, err := c.dialWebSocket(ctx)
+		if err != nil {
+			logger.ErrorCF("mattermost", "WebSocket connection failed", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else {
+			backoff = time.Second
+			c.readEvents(ctx, conn)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > time.Minute {
+			backoff = time.Minute
+		}
+	}
+}
+
+func (c *MattermostChannel) dialWebSocket(ctx context.Context) (*websocket.Conn, error) {
+	wsURL := c.baseURL + "/api/v4/websocket"
+	wsURL = "ws" + strings.TrimPrefix(wsURL, "http")
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+c.config.Token)
+
+	dialer := network.WebSocketDialer("mattermost", network.Options{})
+
+	conn, _, err := dialer.DialContext(ctx, wsURL, header)
+	if err != nil {
+		return nil, err
+	}
+
+	// Stop cancels ctx before closing c.conn, so a connection completed
+	// after that is closed here instead of outliving the channel.
+	c.mu.Lock()
+	if err := ctx.Err(); err != nil {
+		c.mu.Unlock()
+		conn.Close()
+		return nil, err
+	}
+	c.conn = conn
+	c.mu.Unlock()
+
+	logger.InfoC("mattermost", "WebSocket connected")
+	return conn, nil
+}
+
+func (c *MattermostChannel) readEvents(ctx context.Context, conn *websocket.Conn) {
+	defer func() {
+		conn.Close()
+		c.mu.Lock()
+		if c.conn == conn {
+			c.conn = nil
+		}
+		c.mu.Unlock()
+	}()
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.WarnCF("mattermost", "WebSocket read error", map[string]interface{}{
+					"error": err.Error(),
+				})
+			}
+			return
+		}
+
+		var event mattermostWSEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			logger.DebugCF("mattermost", "Failed to decode WebSocket frame", map[string]interface{}{
+				"error": err.Error(),
+			})
+			continue
+		}
+
+		if event.Event == "posted" {
+			c.handlePosted(ctx, event)
+		}
+	}
+}
+
+func (c *MattermostChannel) handlePosted(ctx context.Context, event mattermostWSEvent) {
+	var postJSON, channelType, senderName, mentionsJSON string
+	json.Unmarshal(event.Data["post"], &postJSON)
+	json.Unmarshal(event.Data["channel_type"], &channelType)
+	json.Unmarshal(event.Data["sender_name"], &senderName)
+	json.Unmarshal(event.Data["mentions"], &mentionsJSON)
+
+	var post mattermostPost
+	if err := json.Unmarshal([]byte(postJSON), &post); err != nil {
+		logger.WarnCF("mattermost", "Failed to decode post", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if post.UserID == "" || post.UserID == c.botUserID {
+		return
+	}
+	if post.Type != "" {
+		return
+	}
+	if fromBot, _ := post.Props["from_bot"].(string); fromBot == "true" {
+		return
+	}
+
+	senderID := post.UserID
+	if username := strings.TrimPrefix(senderName, "@"); username != "" {
+		senderID = post.UserID + "|" + username
+	}
+
+	// 检查白名单，避免为被拒绝的用户下载附件
+	if !c.IsAllowed(senderID) {
+		logger.DebugCF("mattermost", "Message rejected by allowlist", map[string]interface{}{
+			"user_id": post.UserID,
+		})
+		return
+	}
+
+	isDirect := channelType == "D"
+	var mentions []string
+	if mentionsJSON != "" {
+		json.Unmarshal([]byte(mentionsJSON), &mentions)
+	}
+	isMentioned := c.isMentioned(post.Message, mentions)
+
+	// In channels and group messages, only respond when mentioned.
+	if !isDirect && !isMentioned {
+		logger.DebugCF("mattermost", "Channel message ignored (not mentioned)", map[string]interface{}{
+			"channel_id": post.ChannelID,
+			"post_id":    post.ID,
+		})
+		return
+	}
+
+	rootID := post.RootID
+	if rootID == "" && !isDirect {
+		rootID = post.ID
+	}
+	chatID := post.ChannelID
+	if rootID != "" {
+		chatID = post.ChannelID + "/" + rootID
+	}
+
+	content := c.stripBotMention(post.Message)
+
+	var mediaPaths []string
+	localFiles := []string{} // 跟踪需要清理的本地文件
+
+	// 确保临时文件在函数返回时被清理
+	defer func() {
+		for _, file := range localFiles {
+			if err := os.Remove(file); err != nil {
+				logger.DebugCF("mattermost", "Failed to cleanup temp file", map[string]interface{}{
+					"file":  file,
+					"error": err.Error(),
+				})
+			}
+		}
+	}()
+
+	files := post.Metadata.Files
+	if len(files) == 0 {
+		for _, id := range post.FileIDs {
+			files = append(files, mattermostFileInfo{ID: id, Name: id})
+		}
+	}
+
+	for _, file := range files {
+		localPath := c.downloadFile(file)
+		if localPath == "" {
+			continue
+		}
+		localFiles = append(localFiles, localPath)
+		mediaPaths = append(mediaPaths, localPath)
+
+		if utils.IsAudioFile(file.Name, file.MimeType) && c.transcriber != nil && c.transcriber.IsAvailable() {
+			tctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+			defer cancel()
+			result, err := c.transcriber.Transcribe(tctx, localPath)
+
+			if err != nil {
+				logger.ErrorCF("mattermost", "Voice transcription failed", map[string]interface{}{"error": err.Error()})
+				content += fmt.Sprintf("\n[audio: %s (transcription failed)]", file.Name)
+			} else {
+				content += fmt.Sprintf("\n[voice transcription: %s]", result.Text)
+			}
+		} else {
+			content += fmt.Sprintf("\n[file: %s]", file.Name)
+		}
+	}
+
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return
+	}
+
+	metadata := map[string]string{
+		"post_id":      post.ID,
+		"channel_id":   post.ChannelID,
+		"root_id":      rootID,
+		"channel_type": channelType,
+		"platform":     "mattermost",
+	}
+	if isMentioned {
+		metadata["is_mention"] = "true"
+	}
+
+	logger.DebugCF("mattermost", "Received message", map[string]interface{}{
+		"sender_id": senderID,
+		"chat_id":   chatID,
+		"preview":   utils.Truncate(content, 50),
+	})
+
+	c.HandleMessage(senderID, chatID, content, mediaPaths, metadata)
+}
+
+func (c *MattermostChannel) isMentioned(message string, mentions []string) bool {
+	for _, id := range mentions {
+		if id == c.botUserID {
+			return true
+		}
+	}
+	return c.botUsername != "" && strings.Contains(message, "@"+c.botUsername)
+}
+
+func (c *MattermostChannel) stripBotMention(text string) string {
+	if c.botUsername != "" {
+		text = strings.ReplaceAll(text, "@"+c.botUsername, "")
+	}
+	return strings.TrimSpace(text)
+}
+
+// sendTyping shows the typing indicator in a channel or thread.
+func (c *MattermostChannel) sendTyping(channelID, parentID string) error {
+	c.mu.Lock()
+	conn := c.conn
+	c.mu.Unlock()
+	if conn == nil {
+		return fmt.Errorf("mattermost websocket not connected")
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.seq++
+	action := map[string]interface{}{
+		"action": "user_typing",
+		"seq":    c.seq,
+		"data": map[string]string{
+			"channel_id": channelID,
+			"parent_id":  parentID,
+		},
+	}
+	return conn.WriteJSON(action)
+}
+
+// SendTyping implements TypingChannel. Mattermost shows the indicator for a
+// few seconds or until the bot posts.
+func (c *MattermostChannel) SendTyping(ctx context.Context, chatID string) error {
+	channelID, rootID := parseMattermostChatID(chatID)
+	if channelID == "" {
+		return fmt.Errorf("invalid mattermost chat ID: %s", chatID)
+	}
+	return c.sendTyping(channelID, rootID)
+}
+
+// SendProgress implements ProgressChannel, creating the placeholder post on
+// the first status of a turn and patching it afterwards.
+func (c *MattermostChannel) SendProgress(ctx context.Context, chatID, status string) error {
+	if !c.IsRunning() {
+		return fmt.Errorf("mattermost channel not running")
+	}
+	channelID, rootID := parseMattermostChatID(chatID)
+	if channelID == "" {
+		return fmt.Errorf("invalid mattermost chat ID: %s", chatID)
+	}
+	if id, ok := c.placeholders.Load(chatID); ok {
+		patch := map[string]string{"message": status}
+		return c.apiJSON(ctx, http.MethodPut, "/posts/"+id.(string)+"/patch", patch, nil)
+	}
+	var created mattermostPost
+	post := mattermostCreatePost{ChannelID: channelID, Message: status, RootID: rootID}
+	if err := c.apiJSON(ctx, http.MethodPost, "/posts", post, &created); err != nil {
+		return err
+	}
+	c.placeholders.Store(chatID, created.ID)
+	return nil
+}
+
+// ClearProgress implements ProgressChannel.
+func (c *MattermostChannel) ClearProgress(ctx context.Context, chatID string) error {
+	id, ok := c.placeholders.LoadAndDelete(chatID)
+	if !ok {
+		return nil
+	}
+	return c.apiJSON(ctx, http.MethodDelete, "/posts/"+id.(string), nil, nil)
+}
+
+// WebhookPath implements WebhookChannel for the slash command endpoint. The
+// endpoint is only exposed when a slash command token is configured.
+func (c *MattermostChannel) WebhookPath() string {
+	if c.config.SlashCommandToken == "" {
+		return ""
+	}
+	if c.config.SlashCommandPath == "" {
+		return "/webhook/mattermost"
+	}
+	return c.config.SlashCommandPath
+}
+
+func (c *MattermostChannel) WebhookHandler() http.Handler {
+	return http.HandlerFunc(c.handleSlashCommand)
+}
+
+func (c *MattermostChannel) handleSlashCommand(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	token := r.PostForm.Get("token")
+	if token == "" {
+		token = strings.TrimPrefix(r.Header.Get("Authorization"), "Token ")
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(c.config.SlashCommandToken)) != 1 {
+		logger.WarnCF("mattermost", "Slash command with invalid token", map[string]interface{}{
+			"remote_addr": r.RemoteAddr,
+		})
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userID := r.PostForm.Get("user_id")
+	senderID := userID
+	if username := r.PostForm.Get("user_name"); username != "" {
+		senderID = userID + "|" + username
+	}
+	channelID := r.PostForm.Get("channel_id")
+	chatID := channelID
+	if rootID := r.PostForm.Get("root_id"); rootID != "" {
+		chatID = channelID + "/" + rootID
+	}
+
+	content := strings.TrimSpace(r.PostForm.Get("text"))
+	if content == "" {
+		content = "help"
+	}
+
+	metadata := map[string]string{
+		"channel_id": channelID,
+		"platform":   "mattermost",
+		"is_command": "true",
+		"command":    r.PostForm.Get("command"),
+		"trigger_id": r.PostForm.Get("trigger_id"),
+	}
+
+	logger.DebugCF("mattermost", "Slash command received", map[string]interface{}{
+		"sender_id": senderID,
+		"command":   metadata["command"],
+		"text":      utils.Truncate(content, 50),
+	})
+
+	c.HandleMessage(senderID, chatID, content, nil, metadata)
+
+	// An empty response posts nothing; the agent's reply arrives as a normal post.
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{}`))
+}
+
+func (c *MattermostChannel) downloadFile(file mattermostFileInfo) string {
+	return utils.DownloadFile(c.baseURL+"/api/v4/files/"+file.ID, file.Name, utils.DownloadOptions{
+		LoggerPrefix: "mattermost",
+		ExtraHeaders: map[string]string{
+			"Authorization": "Bearer " + c.config.Token,
+		},
+	})
+}
+
+func (c *MattermostChannel) uploadFile(ctx context.Context, channelID, path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("channel_id", channelID)
+	part, err := writer.CreateFormFile("files", filepath.Base(path))
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(part, f); err != nil {
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/v4/files", &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	var result struct {
+		FileInfos []mattermostFileInfo `json:"file_infos"`
+	}
+	if err := c.do(req, &result); err != nil {
+		return "", err
+	}
+	if len(result.FileInfos) == 0 {
+		return "", fmt.Errorf("upload returned no file info")
+	}
+	return result.FileInfos[0].ID, nil
+}
+
+// apiJSON calls a REST endpoint under /api/v4 with an optional JSON body.
+func (c *MattermostChannel) apiJSON(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+"/api/v4"+path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.do(req, out)
+}
+
+func (c *MattermostChannel) do(req *http.Request, out interface{}) error {
+	req.Header.Set("Authorization", "Bearer "+c.config.Token)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		json.Unmarshal(data, &apiErr)
+		return fmt.Errorf("%s %s: status %d: %s", req.Method, req.URL.Path, resp.StatusCode, apiErr.Message)
+	}
+	if out != nil {
+		return json.Unmarshal(data, out)
+	}
+	return nil
+}
+
+func parseMattermostChatID(chatID string) (channelID, rootID string) {
+	parts := strings.SplitN(chatID, "/", 2)
+	channelID = parts[0]
+	if len(parts) > 1 {
+		rootID = parts[1]
+	}
+	return
+}
@@ -0,0 +1,373 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// fakeMattermost implements the parts of the Mattermost v4 API the channel uses.
+type fakeMattermost struct {
+	t       *testing.T
+	token   string
+	mu      sync.Mutex
+	posts   []mattermostCreatePost
+	patches []string
+	deleted []string
+	uploads []string
+	conns   chan *websocket.Conn
+	actions chan map[string]interface{}
+}
+
+func newFakeMattermost(t *testing.T) (*fakeMattermost, *httptest.Server) {
+	f := &fakeMattermost{
+		t:       t,
+		token:   "bot-token",
+		conns:   make(chan *websocket.Conn, 4),
+		actions: make(chan map[string]interface{}, 10),
+	}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeMattermost) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer "+f.token {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"message":"invalid token"}`))
+		return
+	}
+
+	switch {
+	case r.URL.Path == "/api/v4/users/me":
+		w.Write([]byte(`{"id":"bot1","username":"picobot"}`))
+
+	case r.URL.Path == "/api/v4/websocket":
+		conn, err := websocket.Upgrade(w, r, nil, 1024, 1024)
+		if err != nil {
+			return
+		}
+		select {
+		case f.conns <- conn:
+		default:
+		}
+		go func() {
+			for {
+				var action map[string]interface{}
+				if err := conn.ReadJSON(&action); err != nil {
+					return
+				}
+				f.actions <- action
+			}
+		}()
+
+	case r.URL.Path == "/api/v4/posts" && r.Method == http.MethodPost:
+		var post mattermostCreatePost
+		json.NewDecoder(r.Body).Decode(&post)
+		f.mu.Lock()
+		f.posts = append(f.posts, post)
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":"p-new"}`))
+
+	case strings.HasSuffix(r.URL.Path, "/patch") && r.Method == http.MethodPut:
+		var patch struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(r.Body).Decode(&patch)
+		f.mu.Lock()
+		id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/v4/posts/"), "/patch")
+		f.patches = append(f.patches, id+":"+patch.Message)
+		f.mu.Unlock()
+		w.Write([]byte(`{}`))
+
+	case strings.HasPrefix(r.URL.Path, "/api/v4/posts/") && r.Method == http.MethodDelete:
+		f.mu.Lock()
+		f.deleted = append(f.deleted, strings.TrimPrefix(r.URL.Path, "/api/v4/posts/"))
+		f.mu.Unlock()
+		w.Write([]byte(`{}`))
+
+	case r.URL.Path == "/api/v4/files" && r.Method == http.MethodPost:
+		file, header, err := r.FormFile("files")
+		if err != nil || r.FormValue("channel_id") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(file)
+		f.mu.Lock()
+		f.uploads = append(f.uploads, header.Filename+":"+string(data))
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"file_infos":[{"id":"f-up-1","name":"` + header.Filename + `"}]}`))
+
+	case strings.HasPrefix(r.URL.Path, "/api/v4/files/"):
+		w.Write([]byte("file-content"))
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// pushPosted sends a "posted" event the way the server encodes it: the post is a JSON string.
+func pushPosted(t *testing.T, conn *websocket.Conn, channelType string, post map[string]interface{}, mentions []string) {
+	t.Helper()
+	postJSON, _ := json.Marshal(post)
+	data := map[string]string{
+		"channel_type": channelType,
+		"post":         string(postJSON),
+		"sender_name":  "@alice",
+	}
+	if mentions != nil {
+		m, _ := json.Marshal(mentions)
+		data["mentions"] = string(m)
+	}
+	if err := conn.WriteJSON(map[string]interface{}{"event": "posted", "data": data, "seq": 1}); err != nil {
+		t.Fatalf("push event: %v", err)
+	}
+}
+
+func startTestMattermost(t *testing.T, cfg config.MattermostConfig) (*MattermostChannel, *fakeMattermost, *websocket.Conn, *bus.MessageBus) {
+	t.Helper()
+	fake, srv := newFakeMattermost(t)
+	cfg.ServerURL = srv.URL
+	cfg.Token = fake.token
+
+	mb := bus.NewMessageBus()
+	ch, err := NewMattermostChannel(cfg, mb)
+	if err != nil {
+		t.Fatalf("NewMattermostChannel: %v", err)
+	}
+	if err := ch.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { ch.Stop(context.Background()) })
+
+	select {
+	case conn := <-fake.conns:
+		return ch, fake, conn, mb
+	case <-time.After(2 * time.Second):
+		t.Fatal("channel did not open the WebSocket")
+		return nil, nil, nil, nil
+	}
+}
+
+func TestMattermostInbound(t *testing.T) {
+	ch, _, conn, mb := startTestMattermost(t, config.MattermostConfig{})
+
+	if ch.botUserID != "bot1" || ch.botUsername != "picobot" {
+		t.Fatalf("bot identity = %q/%q", ch.botUserID, ch.botUsername)
+	}
+
+	// Direct message: always handled, no thread.
+	pushPosted(t, conn, "D", map[string]interface{}{
+		"id": "p1", "user_id": "u1", "channel_id": "dm1", "message": "hi there",
+	}, nil)
+	msg := consumeInbound(t, mb)
+	if msg.ChatID != "dm1" || msg.Content != "hi there" || msg.SenderID != "u1|alice" {
+		t.Fatalf("unexpected DM: %+v", msg)
+	}
+
+	// Channel message without a mention is ignored; own posts are ignored.
+	pushPosted(t, conn, "O", map[string]interface{}{
+		"id": "p2", "user_id": "u1", "channel_id": "town", "message": "just chatting",
+	}, nil)
+	pushPosted(t, conn, "O", map[string]interface{}{
+		"id": "p3", "user_id": "bot1", "channel_id": "town", "message": "@picobot echo",
+	}, []string{"bot1"})
+
+	// Mention with a file replies in a thread rooted at the post.
+	pushPosted(t, conn, "O", map[string]interface{}{
+		"id": "p4", "user_id": "u1", "channel_id": "town", "message": "@picobot summarize this",
+		"file_ids": []string{"f1"},
+		"metadata": map[string]interface{}{
+			"files": []map[string]string{{"id": "f1", "name": "notes.txt", "mime_type": "text/plain"}},
+		},
+	}, []string{"bot1"})
+
+	msg = consumeInbound(t, mb)
+	if msg.ChatID != "town/p4" {
+		t.Fatalf("chatID = %q, want town/p4 (ignored messages must not reach the bus)", msg.ChatID)
+	}
+	if !strings.HasPrefix(msg.Content, "summarize this") || !strings.Contains(msg.Content, "[file: notes.txt]") {
+		t.Fatalf("unexpected content %q", msg.Content)
+	}
+	if len(msg.Media) != 1 || msg.Metadata["is_mention"] != "true" {
+		t.Fatalf("unexpected media/metadata: %+v", msg)
+	}
+
+	// Thread replies keep the existing root.
+	pushPosted(t, conn, "P", map[string]interface{}{
+		"id": "p5", "user_id": "u1", "channel_id": "team", "root_id": "p0", "message": "@picobot and this?",
+	}, []string{"bot1"})
+	if msg := consumeInbound(t, mb); msg.ChatID != "team/p0" {
+		t.Fatalf("chatID = %q, want team/p0", msg.ChatID)
+	}
+}
+
+func TestMattermostSend(t *testing.T) {
+	ch, fake, _, _ := startTestMattermost(t, config.MattermostConfig{})
+
+	attachment := filepath.Join(t.TempDir(), "report.txt")
+	if err := os.WriteFile(attachment, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	long := strings.Repeat("a", mattermostMaxMessageLen) + "tail"
+	err := ch.Send(context.Background(), bus.OutboundMessage{
+		ChatID:  "town/p4",
+		Content: long,
+		Media:   []string{attachment},
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if len(fake.uploads) != 1 || fake.uploads[0] != "report.txt:data" {
+		t.Fatalf("uploads = %v", fake.uploads)
+	}
+	if len(fake.posts) != 2 {
+		t.Fatalf("expected long message split into 2 posts, got %d", len(fake.posts))
+	}
+	for _, p := range fake.posts {
+		if p.ChannelID != "town" || p.RootID != "p4" {
+			t.Fatalf("unexpected post target %+v", p)
+		}
+	}
+	if len(fake.posts[0].FileIDs) != 0 || len(fake.posts[1].FileIDs) != 1 || fake.posts[1].Message != "tail" {
+		t.Fatalf("files should be attached to the last chunk: %+v", fake.posts)
+	}
+}
+
+func TestMattermostProgress(t *testing.T) {
+	ch, fake, _, _ := startTestMattermost(t, config.MattermostConfig{})
+	ctx := context.Background()
+
+	// The WebSocket is stored once the client side of the handshake is done.
+	deadline := time.Now().Add(2 * time.Second)
+	for err := ch.SendTyping(ctx, "town/p4"); err != nil; err = ch.SendTyping(ctx, "town/p4") {
+		if time.Now().After(deadline) {
+			t.Fatalf("SendTyping: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case action := <-fake.actions:
+		data, _ := action["data"].(map[string]interface{})
+		if action["action"] != "user_typing" || data["channel_id"] != "town" || data["parent_id"] != "p4" {
+			t.Fatalf("unexpected action %v", action)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no typing indicator sent")
+	}
+
+	// Two statuses share one placeholder, which the reply replaces.
+	for _, status := range []string{"Searching the web…", "Running a command…"} {
+		if err := ch.SendProgress(ctx, "town/p4", status); err != nil {
+			t.Fatalf("SendProgress: %v", err)
+		}
+	}
+	if err := ch.Send(ctx, bus.OutboundMessage{ChatID: "town/p4", Content: "done"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if err := ch.ClearProgress(ctx, "town/p4"); err != nil {
+		t.Fatalf("ClearProgress: %v", err)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if len(fake.posts) != 2 || fake.posts[0].Message != "Searching the web…" || fake.posts[0].RootID != "p4" || fake.posts[1].Message != "done" {
+		t.Fatalf("posts = %+v", fake.posts)
+	}
+	if len(fake.patches) != 1 || fake.patches[0] != "p-new:Running a command…" {
+		t.Fatalf("patches = %v", fake.patches)
+	}
+	if len(fake.deleted) != 1 || fake.deleted[0] != "p-new" {
+		t.Fatalf("deleted = %v", fake.deleted)
+	}
+}
+
+func TestMattermostSlashCommand(t *testing.T) {
+	ch, _, _, mb := startTestMattermost(t, config.MattermostConfig{SlashCommandToken: "cmd-token"})
+
+	if ch.WebhookPath() != "/webhook/mattermost" {
+		t.Fatalf("WebhookPath() = %q", ch.WebhookPath())
+	}
+
+	post := func(token string) int {
+		form := url.Values{
+			"token":      {token},
+			"user_id":    {"u1"},
+			"user_name":  {"alice"},
+			"channel_id": {"town"},
+			"command":    {"/pico"},
+			"text":       {"what's up"},
+		}
+		req := httptest.NewRequest(http.MethodPost, "/webhook/mattermost", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		ch.WebhookHandler().ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := post("wrong"); code != http.StatusUnauthorized {
+		t.Fatalf("bad token: status = %d, want 401", code)
+	}
+	if code := post("cmd-token"); code != http.StatusOK {
+		t.Fatalf("good token: status = %d, want 200", code)
+	}
+
+	msg := consumeInbound(t, mb)
+	if msg.ChatID != "town" || msg.Content != "what's up" || msg.Metadata["is_command"] != "true" {
+		t.Fatalf("unexpected command message: %+v", msg)
+	}
+}
+
+func TestMattermostLifecycle(t *testing.T) {
+	if _, err := NewMattermostChannel(config.MattermostConfig{ServerURL: "http://x"}, bus.NewMessageBus()); err == nil {
+		t.Fatal("expected error without token")
+	}
+
+	ch, _, _, _ := startTestMattermost(t, config.MattermostConfig{})
+	if ch.WebhookPath() != "" {
+		t.Fatal("slash command endpoint must not be exposed without a token")
+	}
+
+	ch.Stop(context.Background())
+	if err := ch.Send(context.Background(), bus.OutboundMessage{ChatID: "town", Content: "x"}); err == nil {
+		t.Fatal("expected Send to fail after Stop")
+	}
+}
+
+func TestParseMattermostChatID(t *testing.T) {
+	tests := []struct {
+		chatID      string
+		wantChannel string
+		wantRoot    string
+	}{
+		{"abc", "abc", ""},
+		{"abc/root1", "abc", "root1"},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		channelID, rootID := parseMattermostChatID(tt.chatID)
+		if channelID != tt.wantChannel || rootID != tt.wantRoot {
+			t.Errorf("parseMattermostChatID(%q) = %q, %q", tt.chatID, channelID, rootID)
+		}
+	}
+}
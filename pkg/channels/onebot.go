@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"strconv"
 	"strings"
 	"sync"
@@ -14,20 +15,51 @@ import (
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/network"
+	"github.com/sipeed/picoclaw/pkg/utils"
 )
 
+const (
+	oneBotModeForward = "forward"
+	oneBotModeReverse = "reverse"
+	oneBotModeHTTP    = "http"
+
+	// QQ rejects overly long messages, so replies are split into parts.
+	oneBotMaxMessageLen = 4500
+
+	// oneBotDedupNamespace holds the IDs of handled messages, so messages
+	// delivered again after a reconnect or restart are skipped.
+	oneBotDedupNamespace = "onebot_dedup"
+	oneBotDedupTTL       = time.Hour
+)
+
+// OneBotChannel implements the Channel interface for OneBot v11 implementations
+// (go-cqhttp, NapCat, Lagrange, ...). It supports three transports:
+//   - forward: picoclaw dials the implementation's WebSocket server (ws_url)
+//   - reverse: the implementation dials picoclaw's WebSocket server
+//   - http: events arrive as HTTP POST, actions go to the implementation's HTTP API
 type OneBotChannel struct {
 	*BaseChannel
-	config      config.OneBotConfig
-	conn        *websocket.Conn
-	ctx         context.Context
-	cancel      context.CancelFunc
-	dedup       map[string]struct{}
-	dedupRing   []string
-	dedupIdx    int
-	mu          sync.Mutex
-	writeMu     sync.Mutex
-	echoCounter int64
+	config     config.OneBotConfig
+	mode       string
+	conn       *websocket.Conn
+	httpServer *http.Server
+	httpClient *http.Client
+	listenAddr string
+	cancel     context.CancelFunc
+	dedup      map[string]struct{}
+	dedupRing  []string
+	dedupIdx   int
+	// dedupLoaded is set once the IDs saved by a previous run are in dedup;
+	// dedupUnsaved are the IDs seen since the last save.
+	dedupLoaded  bool
+	dedupUnsaved []string
+	mu           sync.Mutex
+	writeMu      sync.Mutex
+	echoCounter  int64
+	// pending are the WebSocket actions waiting for their response, by echo
+	pending      map[string]chan oneBotAPIResponse
+	placeholders sync.Map // chatID -> message ID of the status message
 }
 
 type oneBotRawEvent struct {
@@ -43,6 +75,12 @@ type oneBotRawEvent struct {
 	SelfID        json.RawMessage `json:"self_id"`
 	Time          json.RawMessage `json:"time"`
 	MetaEventType string          `json:"meta_event_type"`
+	NoticeType    string          `json:"notice_type"`
+	RequestType   string          `json:"request_type"`
+	TargetID      json.RawMessage `json:"target_id"`
+	OperatorID    json.RawMessage `json:"operator_id"`
+	Comment       string          `json:"comment"`
+	Flag          string          `json:"flag"`
 	Echo          string          `json:"echo"`
 	RetCode       json.RawMessage `json:"retcode"`
 	Status        BotStatus       `json:"status"`
@@ -81,6 +119,18 @@ type oneBotAPIRequest struct {
 	Echo   string      `json:"echo,omitempty"`
 }
 
+// oneBotAPIResponse answers an action. Status is "ok", "async" or "failed".
+type oneBotAPIResponse struct {
+	Status  string          `json:"status"`
+	RetCode int             `json:"retcode"`
+	Data    json.RawMessage `json:"data"`
+	Echo    string          `json:"echo"`
+}
+
+type oneBotDeleteMsgParams struct {
+	MessageID int64 `json:"message_id"`
+}
+
 type oneBotSendPrivateMsgParams struct {
 	UserID  int64  `json:"user_id"`
 	Message string `json:"message"`
@@ -92,12 +142,29 @@ type oneBotSendGroupMsgParams struct {
 }
 
 func NewOneBotChannel(cfg config.OneBotConfig, messageBus *bus.MessageBus) (*OneBotChannel, error) {
+	mode := strings.ToLower(strings.TrimSpace(cfg.Mode))
+	if mode == "" {
+		mode = oneBotModeForward
+	}
+	switch mode {
+	case oneBotModeForward, oneBotModeReverse, oneBotModeHTTP:
+	default:
+		return nil, fmt.Errorf("unknown OneBot mode %q (expected forward, reverse or http)", cfg.Mode)
+	}
+	for _, rule := range cfg.EventRules {
+		if err := validateOneBotEventRule(rule); err != nil {
+			return nil, err
+		}
+	}
+
 	base := NewBaseChannel("onebot", cfg, messageBus, cfg.AllowFrom)
 
 	const dedupSize = 1024
 	return &OneBotChannel{
 		BaseChannel: base,
 		config:      cfg,
+		mode:        mode,
+		httpClient:  network.NewClient("onebot", network.Options{Timeout: 10 * time.Second}),
 		dedup:       make(map[string]struct{}, dedupSize),
 		dedupRing:   make([]string, dedupSize),
 		dedupIdx:    0,
@@ -105,32 +172,44 @@ func NewOneBotChannel(cfg config.OneBotConfig, messageBus *bus.MessageBus) (*One
 }
 
 func (c *OneBotChannel) Start(ctx context.Context) error {
+	if c.IsRunning() {
+		return nil
+	}
+
+	switch c.mode {
+	case oneBotModeReverse, oneBotModeHTTP:
+		return c.startServer(ctx)
+	}
+
 	if c.config.WSUrl == "" {
 		return fmt.Errorf("OneBot ws_url not configured")
 	}
 
 	logger.InfoCF("onebot", "Starting OneBot channel", map[string]interface{}{
+		"mode":   c.mode,
 		"ws_url": c.config.WSUrl,
 	})
 
-	c.ctx, c.cancel = context.WithCancel(ctx)
+	runCtx, cancel := context.WithCancel(ctx)
 
-	if err := c.connect(); err != nil {
+	conn, err := c.connect(runCtx)
+	if err != nil {
 		logger.WarnCF("onebot", "Initial connection failed, will retry in background", map[string]interface{}{
 			"error": err.Error(),
 		})
 	} else {
-		go c.listen()
+		go c.listen(runCtx, conn)
 	}
+	go saveEvery(runCtx, c.saveDedup)
 
 	if c.config.ReconnectInterval > 0 {
-		go c.reconnectLoop()
-	} else {
+		go c.reconnectLoop(runCtx)
+	} else if err != nil {
 		// If reconnect is disabled but initial connection failed, we cannot recover
-		if c.conn == nil {
-			return fmt.Errorf("failed to connect to OneBot and reconnect is disabled")
-		}
+		cancel()
+		return fmt.Errorf("failed to connect to OneBot and reconnect is disabled")
 	}
+	c.cancel = cancel
 
 	c.setRunning(true)
 	logger.InfoC("onebot", "OneBot channel started successfully")
@@ -138,29 +217,52 @@ func (c *OneBotChannel) Start(ctx context.Context) error {
 	return nil
 }
 
-func (c *OneBotChannel) connect() error {
-	dialer := websocket.DefaultDialer
-	dialer.HandshakeTimeout = 10 * time.Second
+// connect dials the implementation and makes the connection the one used
+// for sending, unless ctx was canceled in the meantime.
+func (c *OneBotChannel) connect(ctx context.Context) (*websocket.Conn, error) {
+	dialer := network.WebSocketDialer("onebot", network.Options{})
 
 	header := make(map[string][]string)
 	if c.config.AccessToken != "" {
 		header["Authorization"] = []string{"Bearer " + c.config.AccessToken}
 	}
 
-	conn, _, err := dialer.Dial(c.config.WSUrl, header)
+	conn, _, err := dialer.DialContext(ctx, c.config.WSUrl, header)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	if !c.setConn(ctx, conn) {
+		return nil, ctx.Err()
+	}
+
+	logger.InfoC("onebot", "WebSocket connected")
+	return conn, nil
+}
+
+// setConn makes conn the connection used for sending and closes the one it
+// replaces. Stop cancels ctx before clearing c.conn, so a connection that
+// arrives after that is closed instead and setConn reports false.
+func (c *OneBotChannel) setConn(ctx context.Context, conn *websocket.Conn) bool {
 	c.mu.Lock()
+	if ctx.Err() != nil {
+		c.mu.Unlock()
+		conn.Close()
+		return false
+	}
+	old := c.conn
 	c.conn = conn
 	c.mu.Unlock()
 
-	logger.InfoC("onebot", "WebSocket connected")
-	return nil
+	if old != nil && old != conn {
+		old.Close()
+	}
+	return true
 }
 
-func (c *OneBotChannel) reconnectLoop() {
+// reconnectLoop redials whenever the connection is lost, until ctx, the
+// run context of one Start, is canceled.
+func (c *OneBotChannel) reconnectLoop(ctx context.Context) {
 	interval := time.Duration(c.config.ReconnectInterval) * time.Second
 	if interval < 5*time.Second {
 		interval = 5 * time.Second
@@ -168,7 +270,7 @@ func (c *OneBotChannel) reconnectLoop() {
 
 	for {
 		select {
-		case <-c.ctx.Done():
+		case <-ctx.Done():
 			return
 		case <-time.After(interval):
 			c.mu.Lock()
@@ -177,12 +279,12 @@ func (c *OneBotChannel) reconnectLoop() {
 
 			if conn == nil {
 				logger.InfoC("onebot", "Attempting to reconnect...")
-				if err := c.connect(); err != nil {
+				if conn, err := c.connect(ctx); err != nil {
 					logger.ErrorCF("onebot", "Reconnect failed", map[string]interface{}{
 						"error": err.Error(),
 					})
 				} else {
-					go c.listen()
+					go c.listen(ctx, conn)
 				}
 			}
 		}
@@ -196,6 +298,18 @@ func (c *OneBotChannel) Stop(ctx context.Context) error {
 	if c.cancel != nil {
 		c.cancel()
 	}
+	c.saveDedup()
+
+	if c.httpServer != nil {
+		shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		if err := c.httpServer.Shutdown(shutdownCtx); err != nil {
+			logger.ErrorCF("onebot", "Server shutdown error", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+		c.httpServer = nil
+	}
 
 	c.mu.Lock()
 	if c.conn != nil {
@@ -212,24 +326,104 @@ func (c *OneBotChannel) Send(ctx context.Context, msg bus.OutboundMessage) error
 		return fmt.Errorf("OneBot channel not running")
 	}
 
-	c.mu.Lock()
-	conn := c.conn
-	c.mu.Unlock()
+	for _, chunk := range utils.SplitMessage(msg.Content, oneBotMaxMessageLen) {
+		part := msg
+		part.Content = chunk
+		action, params, err := c.buildSendRequest(part)
+		if err != nil {
+			return err
+		}
+		if err := c.callAction(ctx, action, params); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	if conn == nil {
-		return fmt.Errorf("OneBot WebSocket not connected")
+// SendProgress implements ProgressChannel. OneBot can't edit messages, so
+// each status replaces the previous status message.
+func (c *OneBotChannel) SendProgress(ctx context.Context, chatID, status string) error {
+	if !c.IsRunning() {
+		return fmt.Errorf("OneBot channel not running")
+	}
+	if err := c.ClearProgress(ctx, chatID); err != nil {
+		logger.WarnCF("onebot", "Failed to delete status message", map[string]interface{}{
+			"error": err.Error(),
+		})
 	}
 
-	action, params, err := c.buildSendRequest(msg)
+	action, params, err := c.buildSendRequest(bus.OutboundMessage{ChatID: chatID, Content: status})
 	if err != nil {
 		return err
 	}
+	data, err := c.request(ctx, action, params, true)
+	if err != nil {
+		return err
+	}
+	var sent struct {
+		MessageID json.RawMessage `json:"message_id"`
+	}
+	json.Unmarshal(data, &sent)
+	id, err := parseJSONInt64(sent.MessageID)
+	if err != nil || id == 0 {
+		return fmt.Errorf("no message ID for the status message")
+	}
+	c.placeholders.Store(chatID, id)
+	return nil
+}
+
+// ClearProgress implements ProgressChannel by deleting the status message.
+func (c *OneBotChannel) ClearProgress(ctx context.Context, chatID string) error {
+	id, ok := c.placeholders.LoadAndDelete(chatID)
+	if !ok {
+		return nil
+	}
+	return c.callAction(ctx, "delete_msg", oneBotDeleteMsgParams{MessageID: id.(int64)})
+}
+
+// callAction invokes a OneBot API action over the configured transport.
+// WebSocket transports are fire-and-forget; the HTTP API reports errors synchronously.
+func (c *OneBotChannel) callAction(ctx context.Context, action string, params interface{}) error {
+	_, err := c.request(ctx, action, params, false)
+	return err
+}
+
+// request invokes an action and returns the data of its response. Over
+// WebSocket, it only waits for the response when wait is set.
+func (c *OneBotChannel) request(ctx context.Context, action string, params interface{}, wait bool) (json.RawMessage, error) {
+	if c.mode == oneBotModeHTTP {
+		return c.callHTTPAction(ctx, action, params)
+	}
+
+	c.mu.Lock()
+	conn := c.conn
+	c.mu.Unlock()
+
+	if conn == nil {
+		return nil, fmt.Errorf("OneBot WebSocket not connected")
+	}
 
 	c.writeMu.Lock()
 	c.echoCounter++
 	echo := fmt.Sprintf("send_%d", c.echoCounter)
 	c.writeMu.Unlock()
 
+	var reply chan oneBotAPIResponse
+	if wait {
+		reply = make(chan oneBotAPIResponse, 1)
+		c.mu.Lock()
+		if c.pending == nil {
+			c.pending = make(map[string]chan oneBotAPIResponse)
+		}
+		c.pending[echo] = reply
+		c.mu.Unlock()
+		defer func() {
+			c.mu.Lock()
+			delete(c.pending, echo)
+			c.mu.Unlock()
+		}()
+	}
+
 	req := oneBotAPIRequest{
 		Action: action,
 		Params: params,
@@ -238,7 +432,7 @@ func (c *OneBotChannel) Send(ctx context.Context, msg bus.OutboundMessage) error
 
 	data, err := json.Marshal(req)
 	if err != nil {
-		return fmt.Errorf("failed to marshal OneBot request: %w", err)
+		return nil, fmt.Errorf("failed to marshal OneBot request: %w", err)
 	}
 
 	c.writeMu.Lock()
@@ -246,13 +440,39 @@ func (c *OneBotChannel) Send(ctx context.Context, msg bus.OutboundMessage) error
 	c.writeMu.Unlock()
 
 	if err != nil {
-		logger.ErrorCF("onebot", "Failed to send message", map[string]interface{}{
-			"error": err.Error(),
+		logger.ErrorCF("onebot", "Failed to send action", map[string]interface{}{
+			"action": action,
+			"error":  err.Error(),
 		})
-		return err
+		return nil, err
 	}
 
-	return nil
+	if !wait {
+		return nil, nil
+	}
+	timer := time.NewTimer(10 * time.Second)
+	defer timer.Stop()
+	select {
+	case resp := <-reply:
+		if resp.Status == "failed" || resp.RetCode != 0 {
+			return nil, fmt.Errorf("OneBot action %s failed: retcode %d", action, resp.RetCode)
+		}
+		return resp.Data, nil
+	case <-timer.C:
+		return nil, fmt.Errorf("OneBot action %s timed out", action)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// deliverResponse hands an action response to the request waiting for it.
+func (c *OneBotChannel) deliverResponse(resp oneBotAPIResponse) {
+	c.mu.Lock()
+	reply, ok := c.pending[resp.Echo]
+	c.mu.Unlock()
+	if ok {
+		reply <- resp
+	}
 }
 
 func (c *OneBotChannel) buildSendRequest(msg bus.OutboundMessage) (string, interface{}, error) {
@@ -291,67 +511,81 @@ func (c *OneBotChannel) buildSendRequest(msg bus.OutboundMessage) (string, inter
 	}, nil
 }
 
-func (c *OneBotChannel) listen() {
+// listen reads frames from conn until it fails or ctx is canceled.
+func (c *OneBotChannel) listen(ctx context.Context, conn *websocket.Conn) {
+	// Unblocks the read below when the channel stops, including for
+	// event-only connections that Stop does not know about
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
 	for {
 		select {
-		case <-c.ctx.Done():
+		case <-ctx.Done():
 			return
 		default:
-			c.mu.Lock()
-			conn := c.conn
-			c.mu.Unlock()
-
-			if conn == nil {
-				logger.WarnC("onebot", "WebSocket connection is nil, listener exiting")
-				return
-			}
-
 			_, message, err := conn.ReadMessage()
 			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
 				logger.ErrorCF("onebot", "WebSocket read error", map[string]interface{}{
 					"error": err.Error(),
 				})
+				conn.Close()
 				c.mu.Lock()
-				if c.conn != nil {
-					c.conn.Close()
+				if c.conn == conn {
 					c.conn = nil
 				}
 				c.mu.Unlock()
 				return
 			}
 
-			logger.DebugCF("onebot", "Raw WebSocket message received", map[string]interface{}{
-				"length":  len(message),
-				"payload": string(message),
-			})
+			c.handlePayload(message)
+		}
+	}
+}
 
-			var raw oneBotRawEvent
-			if err := json.Unmarshal(message, &raw); err != nil {
-				logger.WarnCF("onebot", "Failed to unmarshal raw event", map[string]interface{}{
-					"error":   err.Error(),
-					"payload": string(message),
-				})
-				continue
-			}
+// handlePayload decodes a single event or API response frame, regardless of
+// which transport delivered it.
+func (c *OneBotChannel) handlePayload(message []byte) {
+	logger.DebugCF("onebot", "Raw event received", map[string]interface{}{
+		"length":  len(message),
+		"payload": string(message),
+	})
 
-			if raw.Echo != "" || raw.Status.Online || raw.Status.Good {
-				logger.DebugCF("onebot", "Received API response, skipping", map[string]interface{}{
-					"echo":   raw.Echo,
-					"status": raw.Status,
-				})
-				continue
-			}
+	var resp oneBotAPIResponse
+	if err := json.Unmarshal(message, &resp); err == nil && resp.Echo != "" {
+		c.deliverResponse(resp)
+		return
+	}
 
-			logger.DebugCF("onebot", "Parsed raw event", map[string]interface{}{
-				"post_type":       raw.PostType,
-				"message_type":    raw.MessageType,
-				"sub_type":        raw.SubType,
-				"meta_event_type": raw.MetaEventType,
-			})
+	var raw oneBotRawEvent
+	if err := json.Unmarshal(message, &raw); err != nil {
+		logger.WarnCF("onebot", "Failed to unmarshal raw event", map[string]interface{}{
+			"error":   err.Error(),
+			"payload": string(message),
+		})
+		return
+	}
 
-			c.handleRawEvent(&raw)
-		}
+	if raw.Echo != "" || raw.Status.Online || raw.Status.Good {
+		logger.DebugCF("onebot", "Received API response, skipping", map[string]interface{}{
+			"echo":   raw.Echo,
+			"status": raw.Status,
+		})
+		return
 	}
+
+	logger.DebugCF("onebot", "Parsed raw event", map[string]interface{}{
+		"post_type":       raw.PostType,
+		"message_type":    raw.MessageType,
+		"notice_type":     raw.NoticeType,
+		"request_type":    raw.RequestType,
+		"sub_type":        raw.SubType,
+		"meta_event_type": raw.MetaEventType,
+	})
+
+	c.handleRawEvent(&raw)
 }
 
 func parseJSONInt64(raw json.RawMessage) (int64, error) {
@@ -449,14 +683,8 @@ func (c *OneBotChannel) handleRawEvent(raw *oneBotRawEvent) {
 		c.handleMessage(evt)
 	case "meta_event":
 		c.handleMetaEvent(raw)
-	case "notice":
-		logger.DebugCF("onebot", "Notice event received", map[string]interface{}{
-			"sub_type": raw.SubType,
-		})
-	case "request":
-		logger.DebugCF("onebot", "Request event received", map[string]interface{}{
-			"sub_type": raw.SubType,
-		})
+	case "notice", "request":
+		c.handleNoticeOrRequest(raw)
 	case "":
 		logger.DebugCF("onebot", "Event with empty post_type (possibly API response)", map[string]interface{}{
 			"echo":   raw.Echo,
@@ -646,18 +874,53 @@ func (c *OneBotChannel) isDuplicate(messageID string) bool {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	if !c.dedupLoaded && c.state != nil {
+		for _, id := range c.state.Keys(oneBotDedupNamespace) {
+			c.rememberLocked(id)
+		}
+		c.dedupLoaded = true
+	}
 	if _, exists := c.dedup[messageID]; exists {
 		return true
 	}
+	c.rememberLocked(messageID)
+	if c.state != nil {
+		c.dedupUnsaved = append(c.dedupUnsaved, messageID)
+	}
+	return false
+}
 
+// rememberLocked adds messageID to the recent IDs, dropping the oldest.
+// c.mu must be held.
+func (c *OneBotChannel) rememberLocked(messageID string) {
 	if old := c.dedupRing[c.dedupIdx]; old != "" {
 		delete(c.dedup, old)
 	}
 	c.dedupRing[c.dedupIdx] = messageID
 	c.dedup[messageID] = struct{}{}
 	c.dedupIdx = (c.dedupIdx + 1) % len(c.dedupRing)
+}
 
-	return false
+// saveDedup writes the message IDs seen since the last save to the state
+// store, so messages delivered again after a restart are skipped.
+func (c *OneBotChannel) saveDedup() {
+	c.mu.Lock()
+	unsaved := c.dedupUnsaved
+	c.dedupUnsaved = nil
+	c.mu.Unlock()
+	if len(unsaved) == 0 {
+		return
+	}
+
+	ids := make(map[string]interface{}, len(unsaved))
+	for _, id := range unsaved {
+		ids[id] = true
+	}
+	if err := c.state.SetMany(oneBotDedupNamespace, ids, oneBotDedupTTL); err != nil {
+		logger.WarnCF("onebot", "Failed to save message IDs", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
 }
 
 func truncate(s string, n int) string {
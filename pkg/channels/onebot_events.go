@@ -0,0 +1,232 @@
+package channels
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+const (
+	oneBotActionAgent   = "agent"
+	oneBotActionReply   = "reply"
+	oneBotActionApprove = "approve"
+	oneBotActionReject  = "reject"
+	oneBotActionIgnore  = "ignore"
+)
+
+type oneBotSetFriendAddRequestParams struct {
+	Flag    string `json:"flag"`
+	Approve bool   `json:"approve"`
+}
+
+type oneBotSetGroupAddRequestParams struct {
+	Flag    string `json:"flag"`
+	SubType string `json:"sub_type"`
+	Approve bool   `json:"approve"`
+}
+
+func validateOneBotEventRule(rule config.OneBotEventRule) error {
+	parts := strings.Split(rule.Event, ".")
+	if len(parts) < 2 || len(parts) > 3 || (parts[0] != "notice" && parts[0] != "request") {
+		return fmt.Errorf("invalid OneBot event rule %q: event must look like notice.<type>[.<sub_type>] or request.<type>[.<sub_type>]", rule.Event)
+	}
+
+	switch rule.Action {
+	case oneBotActionAgent, oneBotActionIgnore:
+	case oneBotActionReply:
+		if rule.Reply == "" {
+			return fmt.Errorf("invalid OneBot event rule %q: reply action requires a reply template", rule.Event)
+		}
+	case oneBotActionApprove, oneBotActionReject:
+		if parts[0] != "request" {
+			return fmt.Errorf("invalid OneBot event rule %q: %s only applies to request events", rule.Event, rule.Action)
+		}
+	default:
+		return fmt.Errorf("invalid OneBot event rule %q: unknown action %q", rule.Event, rule.Action)
+	}
+
+	return nil
+}
+
+// oneBotEventKey returns the rule key of a notice or request event, e.g.
+// "notice.group_increase.approve" or "request.friend".
+func oneBotEventKey(raw *oneBotRawEvent) string {
+	kind := raw.NoticeType
+	if raw.PostType == "request" {
+		kind = raw.RequestType
+	}
+	key := raw.PostType + "." + kind
+	if raw.SubType != "" {
+		key += "." + raw.SubType
+	}
+	return key
+}
+
+// matchEventRule returns the first rule matching the event key exactly,
+// falling back to the first rule matching it without its sub_type.
+func (c *OneBotChannel) matchEventRule(key string) (config.OneBotEventRule, bool) {
+	for _, rule := range c.config.EventRules {
+		if rule.Event == key {
+			return rule, true
+		}
+	}
+
+	parts := strings.SplitN(key, ".", 3)
+	if len(parts) == 3 {
+		base := parts[0] + "." + parts[1]
+		for _, rule := range c.config.EventRules {
+			if rule.Event == base {
+				return rule, true
+			}
+		}
+	}
+
+	return config.OneBotEventRule{}, false
+}
+
+func (c *OneBotChannel) handleNoticeOrRequest(raw *oneBotRawEvent) {
+	key := oneBotEventKey(raw)
+
+	userID, _ := parseJSONInt64(raw.UserID)
+	groupID, _ := parseJSONInt64(raw.GroupID)
+	selfID, _ := parseJSONInt64(raw.SelfID)
+	targetID, _ := parseJSONInt64(raw.TargetID)
+
+	// Pokes are broadcast for everyone in a group; only react to those aimed at us.
+	if raw.NoticeType == "notify" && raw.SubType == "poke" && targetID != selfID {
+		return
+	}
+	// Our own join/leave notices are not interesting.
+	if raw.PostType == "notice" && userID != 0 && userID == selfID {
+		return
+	}
+
+	rule, ok := c.matchEventRule(key)
+	if !ok || rule.Action == oneBotActionIgnore {
+		logger.DebugCF("onebot", "Notice/request event ignored", map[string]interface{}{
+			"event":   key,
+			"user_id": userID,
+		})
+		return
+	}
+
+	senderID := strconv.FormatInt(userID, 10)
+	chatID := "private:" + senderID
+	if groupID != 0 {
+		chatID = "group:" + strconv.FormatInt(groupID, 10)
+	}
+
+	logger.InfoCF("onebot", "Handling notice/request event", map[string]interface{}{
+		"event":   key,
+		"action":  rule.Action,
+		"user_id": userID,
+		"chat_id": chatID,
+	})
+
+	switch rule.Action {
+	case oneBotActionAgent:
+		metadata := map[string]string{
+			"onebot_event": key,
+		}
+		if groupID != 0 {
+			metadata["group_id"] = strconv.FormatInt(groupID, 10)
+		}
+		if raw.Flag != "" {
+			metadata["flag"] = raw.Flag
+		}
+		c.HandleMessage(senderID, chatID, describeOneBotEvent(raw, userID, groupID), []string{}, metadata)
+
+	case oneBotActionReply:
+		if !c.IsAllowed(senderID) {
+			return
+		}
+		content := strings.NewReplacer(
+			"{user_id}", senderID,
+			"{group_id}", strconv.FormatInt(groupID, 10),
+			"{comment}", raw.Comment,
+		).Replace(rule.Reply)
+		action, params, err := c.buildSendRequest(bus.OutboundMessage{ChatID: chatID, Content: content})
+		if err == nil {
+			err = c.callEventAction(action, params)
+		}
+		if err != nil {
+			logger.ErrorCF("onebot", "Failed to reply to event", map[string]interface{}{
+				"event": key,
+				"error": err.Error(),
+			})
+		}
+
+	case oneBotActionApprove, oneBotActionReject:
+		approve := rule.Action == oneBotActionApprove
+		if approve && !c.IsAllowed(senderID) {
+			logger.InfoCF("onebot", "Request from non-allowed user left pending", map[string]interface{}{
+				"event":   key,
+				"user_id": userID,
+			})
+			return
+		}
+
+		var err error
+		switch raw.RequestType {
+		case "friend":
+			err = c.callEventAction("set_friend_add_request", oneBotSetFriendAddRequestParams{
+				Flag:    raw.Flag,
+				Approve: approve,
+			})
+		case "group":
+			err = c.callEventAction("set_group_add_request", oneBotSetGroupAddRequestParams{
+				Flag:    raw.Flag,
+				SubType: raw.SubType,
+				Approve: approve,
+			})
+		default:
+			err = fmt.Errorf("unsupported request_type %q", raw.RequestType)
+		}
+		if err != nil {
+			logger.ErrorCF("onebot", "Failed to answer request", map[string]interface{}{
+				"event": key,
+				"error": err.Error(),
+			})
+		}
+	}
+}
+
+// callEventAction answers an event. After Stop the action fails on the
+// closed connection, so the timeout is all the bounding it needs.
+func (c *OneBotChannel) callEventAction(action string, params interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return c.callAction(ctx, action, params)
+}
+
+// describeOneBotEvent renders a notice or request as a short text the agent can act on.
+func describeOneBotEvent(raw *oneBotRawEvent, userID, groupID int64) string {
+	switch {
+	case raw.NoticeType == "group_increase":
+		return fmt.Sprintf("[notice] user %d joined group %d", userID, groupID)
+	case raw.NoticeType == "group_decrease":
+		return fmt.Sprintf("[notice] user %d left group %d", userID, groupID)
+	case raw.NoticeType == "friend_add":
+		return fmt.Sprintf("[notice] user %d is now your friend", userID)
+	case raw.NoticeType == "notify" && raw.SubType == "poke":
+		if groupID != 0 {
+			return fmt.Sprintf("[notice] user %d poked you in group %d", userID, groupID)
+		}
+		return fmt.Sprintf("[notice] user %d poked you", userID)
+	case raw.RequestType == "friend":
+		return fmt.Sprintf("[request] user %d wants to add you as a friend: %s", userID, raw.Comment)
+	case raw.RequestType == "group":
+		if raw.SubType == "invite" {
+			return fmt.Sprintf("[request] user %d invited you to group %d", userID, groupID)
+		}
+		return fmt.Sprintf("[request] user %d wants to join group %d: %s", userID, groupID, raw.Comment)
+	}
+
+	return fmt.Sprintf("[%s] %s from user %d", raw.PostType, oneBotEventKey(raw), userID)
+}
@@ -0,0 +1,222 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+const oneBotMaxBodySize = 4 << 20
+
+var oneBotUpgrader = websocket.Upgrader{
+	// OneBot implementations are not browsers; Origin is not meaningful here.
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// startServer starts the listener used by the reverse WebSocket and HTTP POST
+// modes. In both modes the OneBot implementation is the client.
+func (c *OneBotChannel) startServer(ctx context.Context) error {
+	path := c.config.ListenPath
+	if path == "" {
+		path = "/onebot"
+	}
+
+	// Connections accepted by the server stop with runCtx
+	runCtx, cancel := context.WithCancel(ctx)
+	mux := http.NewServeMux()
+	switch c.mode {
+	case oneBotModeReverse:
+		mux.HandleFunc(path, c.reverseWSHandler(runCtx))
+	case oneBotModeHTTP:
+		if c.config.HTTPAPIUrl == "" {
+			cancel()
+			return fmt.Errorf("OneBot http_api_url not configured")
+		}
+		mux.HandleFunc(path, c.httpPostHandler)
+	}
+
+	addr := fmt.Sprintf("%s:%d", c.config.ListenHost, c.config.ListenPort)
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		cancel()
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	c.cancel = cancel
+	c.listenAddr = listener.Addr().String()
+	go saveEvery(runCtx, c.saveDedup)
+	c.httpServer = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	server := c.httpServer
+	go func() {
+		logger.InfoCF("onebot", "OneBot server listening", map[string]interface{}{
+			"mode": c.mode,
+			"addr": c.listenAddr,
+			"path": path,
+		})
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logger.ErrorCF("onebot", "OneBot server error", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+	}()
+
+	c.setRunning(true)
+	logger.InfoCF("onebot", "OneBot channel started successfully", map[string]interface{}{
+		"mode": c.mode,
+	})
+	return nil
+}
+
+// reverseWSHandler accepts a reverse WebSocket connection from the OneBot
+// implementation. Universal and API connections are used for sending;
+// Event-only connections are read from but never written to. The
+// connections end when ctx is canceled.
+func (c *OneBotChannel) reverseWSHandler(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !c.checkAccessToken(r) {
+			logger.WarnCF("onebot", "Reverse WebSocket rejected: invalid access token", map[string]interface{}{
+				"remote_addr": r.RemoteAddr,
+			})
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		conn, err := oneBotUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			logger.ErrorCF("onebot", "Reverse WebSocket upgrade failed", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		role := r.Header.Get("X-Client-Role")
+		logger.InfoCF("onebot", "Reverse WebSocket connected", map[string]interface{}{
+			"remote_addr": r.RemoteAddr,
+			"self_id":     r.Header.Get("X-Self-ID"),
+			"role":        role,
+		})
+
+		if !strings.EqualFold(role, "Event") && !c.setConn(ctx, conn) {
+			return
+		}
+
+		go c.listen(ctx, conn)
+	}
+}
+
+// httpPostHandler receives events pushed by the implementation's HTTP POST reporter.
+func (c *OneBotChannel) httpPostHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, oneBotMaxBodySize))
+	if err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	if !c.verifySignature(body, r.Header.Get("X-Signature")) {
+		logger.WarnCF("onebot", "HTTP POST rejected: invalid signature", map[string]interface{}{
+			"remote_addr": r.RemoteAddr,
+		})
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	if c.config.Secret == "" && !c.checkAccessToken(r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	go c.handlePayload(body)
+}
+
+// checkAccessToken validates the access token sent either as a Bearer token
+// or as the access_token query parameter. An empty configured token disables the check.
+func (c *OneBotChannel) checkAccessToken(r *http.Request) bool {
+	if c.config.AccessToken == "" {
+		return true
+	}
+
+	token := r.URL.Query().Get("access_token")
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		token = strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(auth, "Bearer "), "Token "))
+	}
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(c.config.AccessToken)) == 1
+}
+
+// verifySignature checks the "X-Signature: sha1=<hex>" header, which is the
+// HMAC-SHA1 of the body keyed with the configured secret. An empty secret disables the check.
+func (c *OneBotChannel) verifySignature(body []byte, signature string) bool {
+	if c.config.Secret == "" {
+		return true
+	}
+	if !strings.HasPrefix(signature, "sha1=") {
+		return false
+	}
+
+	mac := hmac.New(sha1.New, []byte(c.config.Secret))
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+
+	return hmac.Equal([]byte(expected), []byte(strings.TrimPrefix(signature, "sha1=")))
+}
+
+// callHTTPAction posts an action to the implementation's HTTP API.
+func (c *OneBotChannel) callHTTPAction(ctx context.Context, action string, params interface{}) (json.RawMessage, error) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal OneBot request: %w", err)
+	}
+
+	endpoint := strings.TrimRight(c.config.HTTPAPIUrl, "/") + "/" + action
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.config.AccessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.config.AccessToken)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("OneBot HTTP API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, oneBotMaxBodySize))
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OneBot HTTP API %s returned status %d: %s", action, resp.StatusCode, string(respBody))
+	}
+
+	var result oneBotAPIResponse
+	if err := json.Unmarshal(respBody, &result); err == nil && result.Status == "failed" {
+		return nil, fmt.Errorf("OneBot HTTP API %s failed with retcode %d", action, result.RetCode)
+	}
+
+	return result.Data, nil
+}
@@ -0,0 +1,519 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/state"
+)
+
+// fakeOneBotAPI records the actions posted to a fake OneBot HTTP API.
+type fakeOneBotAPI struct {
+	mu      sync.Mutex
+	token   string
+	actions []oneBotAPIRequest
+	got     chan string
+}
+
+func newFakeOneBotAPI(token string) (*fakeOneBotAPI, *httptest.Server) {
+	api := &fakeOneBotAPI{token: token, got: make(chan string, 10)}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+api.token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var params map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&params)
+		action := strings.TrimPrefix(r.URL.Path, "/")
+		api.mu.Lock()
+		api.actions = append(api.actions, oneBotAPIRequest{Action: action, Params: params})
+		api.mu.Unlock()
+		api.got <- action
+		if strings.HasPrefix(action, "send_") {
+			w.Write([]byte(`{"status":"ok","retcode":0,"data":{"message_id":42}}`))
+			return
+		}
+		w.Write([]byte(`{"status":"ok","retcode":0,"data":null}`))
+	}))
+	return api, srv
+}
+
+func (a *fakeOneBotAPI) last() oneBotAPIRequest {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.actions[len(a.actions)-1]
+}
+
+func waitAction(t *testing.T, ch <-chan string) string {
+	t.Helper()
+	select {
+	case action := <-ch:
+		return action
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for OneBot action")
+		return ""
+	}
+}
+
+func consumeInbound(t *testing.T, mb *bus.MessageBus) bus.InboundMessage {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	msg, ok := mb.ConsumeInbound(ctx)
+	if !ok {
+		t.Fatal("timed out waiting for inbound message")
+	}
+	return msg
+}
+
+func signOneBot(secret string, body []byte) string {
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write(body)
+	return "sha1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+const oneBotPrivateMessage = `{"post_type":"message","message_type":"private","sub_type":"friend",` +
+	`"message_id":1,"user_id":10001,"self_id":99,"raw_message":"hello","message":"hello","sender":{"user_id":10001,"nickname":"alice"}}`
+
+func TestOneBotReverseWebSocket(t *testing.T) {
+	mb := bus.NewMessageBus()
+	ch, err := NewOneBotChannel(config.OneBotConfig{
+		Mode:        "reverse",
+		AccessToken: "tok",
+		ListenHost:  "127.0.0.1",
+		ListenPort:  0,
+		ListenPath:  "/onebot",
+	}, mb)
+	if err != nil {
+		t.Fatalf("NewOneBotChannel: %v", err)
+	}
+	if err := ch.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer ch.Stop(context.Background())
+
+	url := "ws://" + ch.listenAddr + "/onebot"
+
+	if _, resp, err := websocket.DefaultDialer.Dial(url, nil); err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without token, got err=%v resp=%v", err, resp)
+	}
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer tok")
+	header.Set("X-Self-ID", "99")
+	header.Set("X-Client-Role", "Universal")
+	conn, _, err := websocket.DefaultDialer.Dial(url, header)
+	if err != nil {
+		t.Fatalf("dial with token: %v", err)
+	}
+	defer conn.Close()
+
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(oneBotPrivateMessage)); err != nil {
+		t.Fatalf("write event: %v", err)
+	}
+
+	msg := consumeInbound(t, mb)
+	if msg.ChatID != "private:10001" || msg.Content != "hello" {
+		t.Fatalf("unexpected inbound message: %+v", msg)
+	}
+
+	if err := ch.Send(context.Background(), bus.OutboundMessage{ChatID: "private:10001", Content: "hi"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("read action: %v", err)
+	}
+	var req struct {
+		Action string                     `json:"action"`
+		Params oneBotSendPrivateMsgParams `json:"params"`
+	}
+	if err := json.Unmarshal(data, &req); err != nil {
+		t.Fatalf("unmarshal action: %v", err)
+	}
+	if req.Action != "send_private_msg" || req.Params.UserID != 10001 || req.Params.Message != "hi" {
+		t.Fatalf("unexpected action: %s", data)
+	}
+}
+
+func TestOneBotHTTPMode(t *testing.T) {
+	api, apiSrv := newFakeOneBotAPI("tok")
+	defer apiSrv.Close()
+
+	mb := bus.NewMessageBus()
+	ch, err := NewOneBotChannel(config.OneBotConfig{
+		Mode:        "http",
+		AccessToken: "tok",
+		Secret:      "s3cret",
+		ListenHost:  "127.0.0.1",
+		ListenPort:  0,
+		ListenPath:  "/onebot",
+		HTTPAPIUrl:  apiSrv.URL,
+		EventRules: []config.OneBotEventRule{
+			{Event: "request.friend", Action: "approve"},
+		},
+	}, mb)
+	if err != nil {
+		t.Fatalf("NewOneBotChannel: %v", err)
+	}
+	if err := ch.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer ch.Stop(context.Background())
+
+	url := "http://" + ch.listenAddr + "/onebot"
+	post := func(body []byte, signature string) int {
+		req, _ := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if signature != "" {
+			req.Header.Set("X-Signature", signature)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("post event: %v", err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	body := []byte(oneBotPrivateMessage)
+	if code := post(body, "sha1=deadbeef"); code != http.StatusForbidden {
+		t.Fatalf("bad signature: status = %d, want 403", code)
+	}
+	if code := post(body, ""); code != http.StatusForbidden {
+		t.Fatalf("missing signature: status = %d, want 403", code)
+	}
+	if code := post(body, signOneBot("s3cret", body)); code != http.StatusNoContent {
+		t.Fatalf("good signature: status = %d, want 204", code)
+	}
+	if msg := consumeInbound(t, mb); msg.Content != "hello" {
+		t.Fatalf("unexpected inbound content %q", msg.Content)
+	}
+
+	friendReq := []byte(`{"post_type":"request","request_type":"friend","user_id":10002,"self_id":99,"comment":"hi","flag":"f-1"}`)
+	if code := post(friendReq, signOneBot("s3cret", friendReq)); code != http.StatusNoContent {
+		t.Fatalf("friend request: status = %d, want 204", code)
+	}
+	if action := waitAction(t, api.got); action != "set_friend_add_request" {
+		t.Fatalf("action = %q, want set_friend_add_request", action)
+	}
+	params := api.last().Params.(map[string]interface{})
+	if params["flag"] != "f-1" || params["approve"] != true {
+		t.Fatalf("unexpected params: %v", params)
+	}
+
+	if err := ch.Send(context.Background(), bus.OutboundMessage{ChatID: "group:555", Content: "yo"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if action := waitAction(t, api.got); action != "send_group_msg" {
+		t.Fatalf("action = %q, want send_group_msg", action)
+	}
+
+	api.token = "other"
+	if err := ch.Send(context.Background(), bus.OutboundMessage{ChatID: "group:555", Content: "yo"}); err == nil {
+		t.Fatal("expected Send to fail when the HTTP API rejects the token")
+	}
+}
+
+func TestOneBotProgress(t *testing.T) {
+	api, apiSrv := newFakeOneBotAPI("tok")
+	defer apiSrv.Close()
+
+	ch, err := NewOneBotChannel(config.OneBotConfig{
+		Mode:        "http",
+		AccessToken: "tok",
+		ListenHost:  "127.0.0.1",
+		ListenPort:  0,
+		ListenPath:  "/onebot",
+		HTTPAPIUrl:  apiSrv.URL,
+	}, bus.NewMessageBus())
+	if err != nil {
+		t.Fatalf("NewOneBotChannel: %v", err)
+	}
+	if err := ch.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer ch.Stop(context.Background())
+
+	ctx := context.Background()
+	if err := ch.SendProgress(ctx, "group:555", "Thinking..."); err != nil {
+		t.Fatalf("SendProgress: %v", err)
+	}
+	if action := waitAction(t, api.got); action != "send_group_msg" {
+		t.Fatalf("action = %q, want send_group_msg", action)
+	}
+
+	// A new status replaces the old status message
+	if err := ch.SendProgress(ctx, "group:555", "Running tools..."); err != nil {
+		t.Fatalf("SendProgress: %v", err)
+	}
+	if action := waitAction(t, api.got); action != "delete_msg" {
+		t.Fatalf("action = %q, want delete_msg", action)
+	}
+	if action := waitAction(t, api.got); action != "send_group_msg" {
+		t.Fatalf("action = %q, want send_group_msg", action)
+	}
+
+	if err := ch.ClearProgress(ctx, "group:555"); err != nil {
+		t.Fatalf("ClearProgress: %v", err)
+	}
+	if action := waitAction(t, api.got); action != "delete_msg" {
+		t.Fatalf("action = %q, want delete_msg", action)
+	}
+	if id := api.last().Params.(map[string]interface{})["message_id"]; id != float64(42) {
+		t.Fatalf("deleted message %v, want 42", id)
+	}
+
+	// Nothing left to delete
+	if err := ch.ClearProgress(ctx, "group:555"); err != nil {
+		t.Fatalf("ClearProgress: %v", err)
+	}
+	select {
+	case action := <-api.got:
+		t.Fatalf("unexpected action %q", action)
+	default:
+	}
+}
+
+func TestOneBotProgressOverWebSocket(t *testing.T) {
+	ch, err := NewOneBotChannel(config.OneBotConfig{
+		Mode:       "reverse",
+		ListenHost: "127.0.0.1",
+		ListenPort: 0,
+		ListenPath: "/onebot",
+	}, bus.NewMessageBus())
+	if err != nil {
+		t.Fatalf("NewOneBotChannel: %v", err)
+	}
+	if err := ch.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer ch.Stop(context.Background())
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws://"+ch.listenAddr+"/onebot", nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	// Answer actions like an implementation would, echoing the request
+	actions := make(chan oneBotAPIRequest, 4)
+	go func() {
+		for {
+			var req oneBotAPIRequest
+			if err := conn.ReadJSON(&req); err != nil {
+				return
+			}
+			actions <- req
+			conn.WriteJSON(map[string]interface{}{
+				"status":  "ok",
+				"retcode": 0,
+				"data":    map[string]int{"message_id": 7},
+				"echo":    req.Echo,
+			})
+		}
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		ch.mu.Lock()
+		connected := ch.conn != nil
+		ch.mu.Unlock()
+		if connected || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	ctx := context.Background()
+	if err := ch.SendProgress(ctx, "private:10001", "Thinking..."); err != nil {
+		t.Fatalf("SendProgress: %v", err)
+	}
+	if req := <-actions; req.Action != "send_private_msg" {
+		t.Fatalf("action = %q, want send_private_msg", req.Action)
+	}
+	if err := ch.ClearProgress(ctx, "private:10001"); err != nil {
+		t.Fatalf("ClearProgress: %v", err)
+	}
+	select {
+	case req := <-actions:
+		params := req.Params.(map[string]interface{})
+		if req.Action != "delete_msg" || params["message_id"] != float64(7) {
+			t.Fatalf("unexpected action %s %v", req.Action, params)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for delete_msg")
+	}
+}
+
+func TestOneBotForwardNoticeToAgent(t *testing.T) {
+	events := make(chan string, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := oneBotUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for evt := range events {
+			conn.WriteMessage(websocket.TextMessage, []byte(evt))
+		}
+	}))
+	defer srv.Close()
+	defer close(events)
+
+	mb := bus.NewMessageBus()
+	ch, err := NewOneBotChannel(config.OneBotConfig{
+		WSUrl: "ws" + strings.TrimPrefix(srv.URL, "http"),
+		EventRules: []config.OneBotEventRule{
+			{Event: "notice.group_increase", Action: "agent"},
+			{Event: "notice.notify.poke", Action: "agent"},
+		},
+	}, mb)
+	if err != nil {
+		t.Fatalf("NewOneBotChannel: %v", err)
+	}
+	if err := ch.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer ch.Stop(context.Background())
+
+	// A poke aimed at someone else must be dropped; the join that follows must arrive.
+	events <- `{"post_type":"notice","notice_type":"notify","sub_type":"poke","user_id":1,"target_id":2,"group_id":7,"self_id":99}`
+	events <- `{"post_type":"notice","notice_type":"group_increase","sub_type":"approve","user_id":10003,"group_id":7,"self_id":99}`
+
+	msg := consumeInbound(t, mb)
+	if msg.ChatID != "group:7" || msg.Metadata["onebot_event"] != "notice.group_increase.approve" {
+		t.Fatalf("unexpected inbound message: %+v", msg)
+	}
+	if !strings.Contains(msg.Content, "user 10003 joined group 7") {
+		t.Fatalf("unexpected content %q", msg.Content)
+	}
+}
+
+func TestOneBotEventRules(t *testing.T) {
+	ch := &OneBotChannel{config: config.OneBotConfig{EventRules: []config.OneBotEventRule{
+		{Event: "notice.notify.poke", Action: "reply", Reply: "ouch"},
+		{Event: "notice.notify", Action: "ignore"},
+		{Event: "request.group", Action: "reject"},
+	}}}
+
+	tests := []struct {
+		name       string
+		raw        oneBotRawEvent
+		wantKey    string
+		wantAction string
+	}{
+		{
+			name:       "exact sub_type match",
+			raw:        oneBotRawEvent{PostType: "notice", NoticeType: "notify", SubType: "poke"},
+			wantKey:    "notice.notify.poke",
+			wantAction: "reply",
+		},
+		{
+			name:       "falls back to rule without sub_type",
+			raw:        oneBotRawEvent{PostType: "notice", NoticeType: "notify", SubType: "honor"},
+			wantKey:    "notice.notify.honor",
+			wantAction: "ignore",
+		},
+		{
+			name:       "request type",
+			raw:        oneBotRawEvent{PostType: "request", RequestType: "group", SubType: "add"},
+			wantKey:    "request.group.add",
+			wantAction: "reject",
+		},
+		{
+			name:    "no rule",
+			raw:     oneBotRawEvent{PostType: "request", RequestType: "friend"},
+			wantKey: "request.friend",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := oneBotEventKey(&tt.raw)
+			if key != tt.wantKey {
+				t.Fatalf("oneBotEventKey = %q, want %q", key, tt.wantKey)
+			}
+			rule, ok := ch.matchEventRule(key)
+			if ok != (tt.wantAction != "") || rule.Action != tt.wantAction {
+				t.Fatalf("matchEventRule(%q) = %+v, %v; want action %q", key, rule, ok, tt.wantAction)
+			}
+		})
+	}
+}
+
+func TestOneBotDedupSurvivesRestart(t *testing.T) {
+	store := state.NewManager(t.TempDir())
+	ch, err := NewOneBotChannel(config.OneBotConfig{}, bus.NewMessageBus())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch.SetStateStore(store)
+
+	if ch.isDuplicate("101") {
+		t.Fatal("first delivery reported as duplicate")
+	}
+	if !ch.isDuplicate("101") {
+		t.Fatal("second delivery not reported as duplicate")
+	}
+	if len(store.Keys(oneBotDedupNamespace)) != 0 {
+		t.Fatal("message ID saved before the periodic save")
+	}
+	ch.saveDedup()
+
+	restarted, err := NewOneBotChannel(config.OneBotConfig{}, bus.NewMessageBus())
+	if err != nil {
+		t.Fatal(err)
+	}
+	restarted.SetStateStore(store)
+	if !restarted.isDuplicate("101") {
+		t.Fatal("delivery after restart not reported as duplicate")
+	}
+	if restarted.isDuplicate("102") {
+		t.Fatal("new message reported as duplicate")
+	}
+}
+
+func TestValidateOneBotEventRule(t *testing.T) {
+	tests := []struct {
+		rule    config.OneBotEventRule
+		wantErr bool
+	}{
+		{config.OneBotEventRule{Event: "notice.group_increase", Action: "agent"}, false},
+		{config.OneBotEventRule{Event: "request.friend", Action: "approve"}, false},
+		{config.OneBotEventRule{Event: "notice.notify.poke", Action: "reply", Reply: "hey"}, false},
+		{config.OneBotEventRule{Event: "notice.notify.poke", Action: "reply"}, true},
+		{config.OneBotEventRule{Event: "notice.group_increase", Action: "approve"}, true},
+		{config.OneBotEventRule{Event: "message.private", Action: "agent"}, true},
+		{config.OneBotEventRule{Event: "request.friend", Action: "shout"}, true},
+	}
+
+	for _, tt := range tests {
+		err := validateOneBotEventRule(tt.rule)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("validateOneBotEventRule(%+v) error = %v, wantErr %v", tt.rule, err, tt.wantErr)
+		}
+	}
+
+	if _, err := NewOneBotChannel(config.OneBotConfig{Mode: "carrier-pigeon"}, bus.NewMessageBus()); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
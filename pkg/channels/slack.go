@@ -15,10 +15,14 @@ import (
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/network"
 	"github.com/sipeed/picoclaw/pkg/utils"
 	"github.com/sipeed/picoclaw/pkg/voice"
 )
 
+// slackMaxMessageLen is the chat.postMessage text limit; Slack truncates longer text.
+const slackMaxMessageLen = 40000
+
 type SlackChannel struct {
 	*BaseChannel
 	config       config.SlackConfig
@@ -29,6 +33,7 @@ type SlackChannel struct {
 	ctx          context.Context
 	cancel       context.CancelFunc
 	pendingAcks  sync.Map
+	placeholders sync.Map // chat ID -> timestamp of the progress placeholder
 }
 
 type slackMessageRef struct {
@@ -44,6 +49,7 @@ func NewSlackChannel(cfg config.SlackConfig, messageBus *bus.MessageBus) (*Slack
 	api := slack.New(
 		cfg.BotToken,
 		slack.OptionAppLevelToken(cfg.AppToken),
+		slack.OptionHTTPClient(network.NewClient("slack", network.Options{})),
 	)
 
 	socketClient := socketmode.New(api)
@@ -63,6 +69,10 @@ func (c *SlackChannel) SetTranscriber(transcriber *voice.GroqTranscriber) {
 }
 
 func (c *SlackChannel) Start(ctx context.Context) error {
+	if c.IsRunning() {
+		return nil
+	}
+
 	logger.InfoC("slack", "Starting Slack channel (Socket Mode)")
 
 	c.ctx, c.cancel = context.WithCancel(ctx)
@@ -78,11 +88,12 @@ func (c *SlackChannel) Start(ctx context.Context) error {
 		"team":        authResp.Team,
 	})
 
-	go c.eventLoop()
+	runCtx := c.ctx
+	go c.eventLoop(runCtx)
 
 	go func() {
-		if err := c.socketClient.RunContext(c.ctx); err != nil {
-			if c.ctx.Err() == nil {
+		if err := c.socketClient.RunContext(runCtx); err != nil {
+			if runCtx.Err() == nil {
 				logger.ErrorCF("slack", "Socket Mode connection error", map[string]interface{}{
 					"error": err.Error(),
 				})
@@ -117,17 +128,27 @@ func (c *SlackChannel) Send(ctx context.Context, msg bus.OutboundMessage) error
 		return fmt.Errorf("invalid slack chat ID: %s", msg.ChatID)
 	}
 
-	opts := []slack.MsgOption{
-		slack.MsgOptionText(msg.Content, false),
+	// The reply is posted as a new message, which notifies the user where an
+	// edit would not, so the placeholder goes first.
+	if err := c.ClearProgress(ctx, msg.ChatID); err != nil {
+		logger.DebugCF("slack", "Failed to remove progress placeholder", map[string]interface{}{
+			"error": err.Error(),
+		})
 	}
 
-	if threadTS != "" {
-		opts = append(opts, slack.MsgOptionTS(threadTS))
-	}
+	for _, chunk := range utils.SplitMessage(msg.Content, slackMaxMessageLen) {
+		opts := []slack.MsgOption{
+			slack.MsgOptionText(chunk, false),
+		}
 
-	_, _, err := c.api.PostMessageContext(ctx, channelID, opts...)
-	if err != nil {
-		return fmt.Errorf("failed to send slack message: %w", err)
+		if threadTS != "" {
+			opts = append(opts, slack.MsgOptionTS(threadTS))
+		}
+
+		_, _, err := c.api.PostMessageContext(ctx, channelID, opts...)
+		if err != nil {
+			return fmt.Errorf("failed to send slack message: %w", err)
+		}
 	}
 
 	if ref, ok := c.pendingAcks.LoadAndDelete(msg.ChatID); ok {
@@ -146,10 +167,48 @@ func (c *SlackChannel) Send(ctx context.Context, msg bus.OutboundMessage) error
 	return nil
 }
 
-func (c *SlackChannel) eventLoop() {
+// SendProgress implements ProgressChannel, posting the placeholder on the
+// first status of a turn and updating it afterwards. Slack has no typing
+// indicator for bots, so this is the only sign of a long turn.
+func (c *SlackChannel) SendProgress(ctx context.Context, chatID, status string) error {
+	if !c.IsRunning() {
+		return fmt.Errorf("slack channel not running")
+	}
+	channelID, threadTS := parseSlackChatID(chatID)
+	if channelID == "" {
+		return fmt.Errorf("invalid slack chat ID: %s", chatID)
+	}
+	if ts, ok := c.placeholders.Load(chatID); ok {
+		_, _, _, err := c.api.UpdateMessageContext(ctx, channelID, ts.(string), slack.MsgOptionText(status, false))
+		return err
+	}
+	opts := []slack.MsgOption{slack.MsgOptionText(status, false)}
+	if threadTS != "" {
+		opts = append(opts, slack.MsgOptionTS(threadTS))
+	}
+	_, ts, err := c.api.PostMessageContext(ctx, channelID, opts...)
+	if err != nil {
+		return err
+	}
+	c.placeholders.Store(chatID, ts)
+	return nil
+}
+
+// ClearProgress implements ProgressChannel.
+func (c *SlackChannel) ClearProgress(ctx context.Context, chatID string) error {
+	ts, ok := c.placeholders.LoadAndDelete(chatID)
+	if !ok {
+		return nil
+	}
+	channelID, _ := parseSlackChatID(chatID)
+	_, _, err := c.api.DeleteMessageContext(ctx, channelID, ts.(string))
+	return err
+}
+
+func (c *SlackChannel) eventLoop(ctx context.Context) {
 	for {
 		select {
-		case <-c.ctx.Done():
+		case <-ctx.Done():
 			return
 		case event, ok := <-c.socketClient.Events:
 			if !ok {
@@ -1,12 +1,30 @@
 package channels
 
 import (
+	"context"
 	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
 
 	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/channels/channeltest"
 	"github.com/sipeed/picoclaw/pkg/config"
 )
 
+func newTestSlackChannel(t *testing.T, fake *channeltest.Slack, mb *bus.MessageBus, allowFrom []string) *SlackChannel {
+	t.Helper()
+	cfg := config.SlackConfig{BotToken: "xoxb-test", AppToken: "xapp-test", AllowFrom: allowFrom}
+	ch, err := NewSlackChannel(cfg, mb)
+	if err != nil {
+		t.Fatalf("NewSlackChannel: %v", err)
+	}
+	ch.api = slack.New(cfg.BotToken, slack.OptionAppLevelToken(cfg.AppToken), slack.OptionAPIURL(fake.APIURL()))
+	ch.socketClient = socketmode.New(ch.api)
+	return ch
+}
+
 func TestParseSlackChatID(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -172,3 +190,50 @@ func TestSlackChannelIsAllowed(t *testing.T) {
 		}
 	})
 }
+
+func TestSlackSocketMode(t *testing.T) {
+	fake := channeltest.NewSlack(t)
+	mb := bus.NewMessageBus()
+	ch := newTestSlackChannel(t, fake, mb, nil)
+	if err := ch.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer ch.Stop(context.Background())
+
+	if ch.botUserID != "UBOT" {
+		t.Fatalf("botUserID = %q", ch.botUserID)
+	}
+
+	envelope := fake.PushEvent(t, map[string]interface{}{
+		"type": "app_mention", "user": "U1", "channel": "C1", "text": "<@UBOT> status?", "ts": "100.000001",
+	})
+	msg := consumeInbound(t, mb)
+	if msg.ChatID != "C1/100.000001" || msg.Content != "status?" || msg.Metadata["is_mention"] != "true" {
+		t.Fatalf("unexpected mention: %+v", msg)
+	}
+
+	if err := ch.Send(context.Background(), bus.OutboundMessage{ChatID: msg.ChatID, Content: "all good"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	sent := fake.Messages("C1")
+	if len(sent) != 1 || sent[0].ThreadTS != "100.000001" {
+		t.Fatalf("reply should go to the thread, got %+v", sent)
+	}
+	if reactions := fake.Reactions(); len(reactions) != 2 || reactions[0] != "eyes" || reactions[1] != "white_check_mark" {
+		t.Fatalf("reactions = %v", reactions)
+	}
+
+	fake.PushSlashCommand(t, "U1", "C1", "/pico", "")
+	msg = consumeInbound(t, mb)
+	if msg.Content != "help" || msg.Metadata["is_command"] != "true" {
+		t.Fatalf("unexpected slash command: %+v", msg)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !fake.Acked(envelope) {
+		if time.Now().After(deadline) {
+			t.Fatal("events_api envelope was not acknowledged")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
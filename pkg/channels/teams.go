@@ -0,0 +1,523 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/locale"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/network"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+const (
+	teamsBotFrameworkScope = "https://api.botframework.com/.default"
+	teamsMaxMessageLen     = 20000
+	teamsMaxInlineImage    = 1 << 20
+)
+
+// TeamsChannel implements the Bot Framework activity protocol used by
+// Microsoft Teams. Activities arrive on the gateway webhook and replies are
+// posted to the conversation's service URL. Chat IDs are conversation IDs.
+type TeamsChannel struct {
+	*BaseChannel
+	config        config.TeamsConfig
+	keys          *teamsKeySet
+	tokenURL      string
+	tokenSource   oauth2.TokenSource
+	apiClient     *http.Client
+	conversations sync.Map // conversation ID -> teamsConversationRef
+	ctx           context.Context
+	cancel        context.CancelFunc
+}
+
+type teamsAccount struct {
+	ID          string `json:"id"`
+	Name        string `json:"name,omitempty"`
+	AadObjectID string `json:"aadObjectId,omitempty"`
+}
+
+type teamsConversation struct {
+	ID               string `json:"id"`
+	ConversationType string `json:"conversationType,omitempty"`
+	IsGroup          bool   `json:"isGroup,omitempty"`
+	TenantID         string `json:"tenantId,omitempty"`
+}
+
+type teamsAttachment struct {
+	ContentType string          `json:"contentType"`
+	ContentURL  string          `json:"contentUrl,omitempty"`
+	Content     json.RawMessage `json:"content,omitempty"`
+	Name        string          `json:"name,omitempty"`
+}
+
+type teamsEntity struct {
+	Type      string       `json:"type"`
+	Text      string       `json:"text,omitempty"`
+	Mentioned teamsAccount `json:"mentioned,omitempty"`
+	// Timezone is set on the "clientInfo" entity.
+	Timezone string `json:"timezone,omitempty"`
+}
+
+type teamsActivity struct {
+	Type         string            `json:"type"`
+	ID           string            `json:"id,omitempty"`
+	ServiceURL   string            `json:"serviceUrl,omitempty"`
+	ChannelID    string            `json:"channelId,omitempty"`
+	From         teamsAccount      `json:"from,omitempty"`
+	Conversation teamsConversation `json:"conversation,omitempty"`
+	Recipient    teamsAccount      `json:"recipient,omitempty"`
+	Text         string            `json:"text,omitempty"`
+	TextFormat   string            `json:"textFormat,omitempty"`
+	Attachments  []teamsAttachment `json:"attachments,omitempty"`
+	Entities     []teamsEntity     `json:"entities,omitempty"`
+	ReplyToID    string            `json:"replyToId,omitempty"`
+	Value        json.RawMessage   `json:"value,omitempty"`
+	Locale       string            `json:"locale,omitempty"`
+}
+
+type teamsConversationRef struct {
+	ServiceURL     string
+	ConversationID string
+	Bot            teamsAccount
+	ReplyToID      string
+}
+
+func NewTeamsChannel(cfg config.TeamsConfig, messageBus *bus.MessageBus) (*TeamsChannel, error) {
+	if cfg.AppID == "" || cfg.AppPassword == "" {
+		return nil, fmt.Errorf("teams app_id and app_password are required")
+	}
+
+	metadataURL := cfg.OpenIDMetadataURL
+	if metadataURL == "" {
+		metadataURL = "https://login.botframework.com/v1/.well-known/openidconfiguration"
+	}
+	tenant := cfg.TenantID
+	if tenant == "" {
+		tenant = "botframework.com"
+	}
+
+	base := NewBaseChannel("teams", cfg, messageBus, cfg.AllowFrom)
+
+	return &TeamsChannel{
+		BaseChannel: base,
+		config:      cfg,
+		keys:        newTeamsKeySet(metadataURL, network.NewClient("teams", network.Options{Timeout: 30 * time.Second})),
+		tokenURL:    "https://login.microsoftonline.com/" + tenant + "/oauth2/v2.0/token",
+	}, nil
+}
+
+func (c *TeamsChannel) Start(ctx context.Context) error {
+	if c.IsRunning() {
+		return nil
+	}
+
+	logger.InfoC("teams", "Starting Teams channel")
+
+	c.ctx, c.cancel = context.WithCancel(ctx)
+
+	tokenCtx := context.WithValue(context.Background(), oauth2.HTTPClient, network.NewClient("teams", network.Options{Timeout: 30 * time.Second}))
+	credentials := &clientcredentials.Config{
+		ClientID:     c.config.AppID,
+		ClientSecret: c.config.AppPassword,
+		TokenURL:     c.tokenURL,
+		Scopes:       []string{teamsBotFrameworkScope},
+	}
+	c.tokenSource = credentials.TokenSource(tokenCtx)
+	c.apiClient = oauth2.NewClient(tokenCtx, c.tokenSource)
+	c.apiClient.Timeout = 30 * time.Second
+
+	c.setRunning(true)
+	logger.InfoCF("teams", "Teams channel started", map[string]interface{}{
+		"webhook_path": c.WebhookPath(),
+	})
+	return nil
+}
+
+func (c *TeamsChannel) Stop(ctx context.Context) error {
+	logger.InfoC("teams", "Stopping Teams channel")
+	if c.cancel != nil {
+		c.cancel()
+	}
+	c.setRunning(false)
+	return nil
+}
+
+func (c *TeamsChannel) WebhookPath() string {
+	if c.config.WebhookPath == "" {
+		return "/webhook/teams"
+	}
+	return c.config.WebhookPath
+}
+
+func (c *TeamsChannel) WebhookHandler() http.Handler {
+	return http.HandlerFunc(c.handleWebhook)
+}
+
+func (c *TeamsChannel) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !c.IsRunning() {
+		http.Error(w, "Service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, 4<<20))
+	if err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	var activity teamsActivity
+	if err := json.Unmarshal(body, &activity); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" || token == r.Header.Get("Authorization") {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	claims, err := c.keys.Verify(r.Context(), token, c.config.AppID, activity.ChannelID)
+	if err == nil && claims.ServiceURL != "" && claims.ServiceURL != activity.ServiceURL {
+		err = fmt.Errorf("serviceUrl claim does not match activity")
+	}
+	if err != nil {
+		logger.WarnCF("teams", "Rejected activity with invalid token", map[string]interface{}{
+			"error":       err.Error(),
+			"remote_addr": r.RemoteAddr,
+		})
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	go c.handleActivity(activity)
+}
+
+func (c *TeamsChannel) handleActivity(activity teamsActivity) {
+	if activity.Conversation.ID != "" && activity.ServiceURL != "" {
+		c.conversations.Store(activity.Conversation.ID, teamsConversationRef{
+			ServiceURL:     activity.ServiceURL,
+			ConversationID: activity.Conversation.ID,
+			Bot:            activity.Recipient,
+			ReplyToID:      activity.ID,
+		})
+	}
+
+	switch activity.Type {
+	case "message":
+		c.handleMessageActivity(activity)
+	case "conversationUpdate":
+		logger.InfoCF("teams", "Conversation update", map[string]interface{}{
+			"conversation_id":   activity.Conversation.ID,
+			"conversation_type": activity.Conversation.ConversationType,
+		})
+	default:
+		logger.DebugCF("teams", "Ignoring activity", map[string]interface{}{
+			"type": activity.Type,
+		})
+	}
+}
+
+func (c *TeamsChannel) handleMessageActivity(activity teamsActivity) {
+	senderID := activity.From.ID
+	if activity.From.AadObjectID != "" {
+		senderID = activity.From.ID + "|" + activity.From.AadObjectID
+	}
+
+	// 检查白名单，避免为被拒绝的用户下载附件
+	if !c.IsAllowed(senderID) {
+		logger.DebugCF("teams", "Message rejected by allowlist", map[string]interface{}{
+			"user_id": activity.From.ID,
+		})
+		return
+	}
+
+	chatID := activity.Conversation.ID
+
+	content := stripTeamsMentions(activity)
+
+	metadata := map[string]string{
+		"activity_id":       activity.ID,
+		"conversation_type": activity.Conversation.ConversationType,
+		"tenant_id":         activity.Conversation.TenantID,
+		"user_name":         activity.From.Name,
+		"platform":          "teams",
+	}
+	if activity.Locale != "" {
+		metadata[locale.MetaLocale] = activity.Locale
+	}
+	for _, e := range activity.Entities {
+		if e.Type == "clientInfo" && e.Timezone != "" {
+			metadata[locale.MetaTimeZone] = e.Timezone
+		}
+	}
+
+	// Adaptive Card Action.Submit sends the card's data as the activity value.
+	if content == "" && len(activity.Value) > 0 && string(activity.Value) != "null" {
+		content = string(activity.Value)
+		metadata["card_action"] = "true"
+	}
+
+	var mediaPaths []string
+	localFiles := []string{} // 跟踪需要清理的本地文件
+
+	// 确保临时文件在函数返回时被清理
+	defer func() {
+		for _, file := range localFiles {
+			if err := os.Remove(file); err != nil {
+				logger.DebugCF("teams", "Failed to cleanup temp file", map[string]interface{}{
+					"file":  file,
+					"error": err.Error(),
+				})
+			}
+		}
+	}()
+
+	for _, att := range activity.Attachments {
+		localPath, name := c.downloadAttachment(activity.ServiceURL, att)
+		if localPath == "" {
+			continue
+		}
+		localFiles = append(localFiles, localPath)
+		mediaPaths = append(mediaPaths, localPath)
+		content = appendContent(content, fmt.Sprintf("[file: %s]", name))
+	}
+
+	if strings.TrimSpace(content) == "" {
+		return
+	}
+
+	logger.DebugCF("teams", "Received message", map[string]interface{}{
+		"sender_id": senderID,
+		"chat_id":   chatID,
+		"preview":   utils.Truncate(content, 50),
+	})
+
+	c.HandleMessage(senderID, chatID, content, mediaPaths, metadata)
+}
+
+// stripTeamsMentions removes the bot's own <at>...</at> mention from the text.
+func stripTeamsMentions(activity teamsActivity) string {
+	text := activity.Text
+	for _, entity := range activity.Entities {
+		if entity.Type == "mention" && entity.Mentioned.ID == activity.Recipient.ID && entity.Text != "" {
+			text = strings.ReplaceAll(text, entity.Text, "")
+		}
+	}
+	return strings.TrimSpace(text)
+}
+
+// downloadAttachment stores a file or image attachment locally and returns its
+// path and display name. Cards and the HTML copy of the message are skipped.
+func (c *TeamsChannel) downloadAttachment(serviceURL string, att teamsAttachment) (string, string) {
+	name := att.Name
+	downloadURL := att.ContentURL
+	headers := map[string]string{}
+
+	switch {
+	case att.ContentType == "application/vnd.microsoft.teams.file.download.info":
+		var info struct {
+			DownloadURL string `json:"downloadUrl"`
+			FileType    string `json:"fileType"`
+		}
+		if err := json.Unmarshal(att.Content, &info); err != nil || info.DownloadURL == "" {
+			return "", ""
+		}
+		// Pre-authenticated SharePoint URL; no bot token needed.
+		downloadURL = info.DownloadURL
+	case att.ContentType == "text/html",
+		strings.HasPrefix(att.ContentType, "application/vnd.microsoft.card."):
+		return "", ""
+	case downloadURL == "":
+		return "", ""
+	default:
+		// Inline images are hosted by the Bot Framework and need the bot token.
+		if sameHost(downloadURL, serviceURL) || strings.HasSuffix(hostOf(downloadURL), ".botframework.com") {
+			token, err := c.tokenSource.Token()
+			if err != nil {
+				logger.ErrorCF("teams", "Failed to get token for attachment download", map[string]interface{}{
+					"error": err.Error(),
+				})
+				return "", ""
+			}
+			headers["Authorization"] = "Bearer " + token.AccessToken
+		}
+	}
+
+	if name == "" {
+		name = "attachment"
+		if exts := strings.SplitN(att.ContentType, "/", 2); len(exts) == 2 && exts[0] == "image" {
+			name = "image." + exts[1]
+		}
+	}
+
+	path := utils.DownloadFile(downloadURL, name, utils.DownloadOptions{
+		LoggerPrefix: "teams",
+		ExtraHeaders: headers,
+	})
+	return path, name
+}
+
+func (c *TeamsChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	if !c.IsRunning() {
+		return fmt.Errorf("teams channel not running")
+	}
+
+	value, ok := c.conversations.Load(msg.ChatID)
+	if !ok {
+		return fmt.Errorf("unknown teams conversation %s (no activity received from it yet)", msg.ChatID)
+	}
+	ref := value.(teamsConversationRef)
+
+	var activities []teamsActivity
+	if c.config.AdaptiveCards && needsAdaptiveCard(msg.Content) {
+		card, err := json.Marshal(renderAdaptiveCard(msg.Content))
+		if err != nil {
+			return fmt.Errorf("failed to render adaptive card: %w", err)
+		}
+		activities = append(activities, teamsActivity{
+			Type: "message",
+			Attachments: []teamsAttachment{{
+				ContentType: "application/vnd.microsoft.card.adaptive",
+				Content:     card,
+			}},
+		})
+	} else {
+		for _, chunk := range utils.SplitMessage(msg.Content, teamsMaxMessageLen) {
+			activities = append(activities, teamsActivity{
+				Type:       "message",
+				Text:       chunk,
+				TextFormat: "markdown",
+			})
+		}
+	}
+
+	last := &activities[len(activities)-1]
+	for _, path := range msg.Media {
+		att, err := inlineTeamsImage(path)
+		if err != nil {
+			logger.WarnCF("teams", "Attachment not sent", map[string]interface{}{
+				"path":  path,
+				"error": err.Error(),
+			})
+			continue
+		}
+		last.Attachments = append(last.Attachments, att)
+	}
+
+	for _, activity := range activities {
+		activity.From = ref.Bot
+		activity.Conversation = teamsConversation{ID: ref.ConversationID}
+		activity.ReplyToID = ref.ReplyToID
+		if err := c.postActivity(ctx, ref, activity); err != nil {
+			return fmt.Errorf("failed to send teams message: %w", err)
+		}
+	}
+
+	logger.DebugCF("teams", "Message sent", map[string]interface{}{
+		"conversation_id": ref.ConversationID,
+		"activities":      len(activities),
+	})
+	return nil
+}
+
+// SendTyping implements TypingChannel for conversations the bot has seen.
+// Teams shows the indicator for a few seconds or until the bot posts.
+func (c *TeamsChannel) SendTyping(ctx context.Context, chatID string) error {
+	value, ok := c.conversations.Load(chatID)
+	if !ok {
+		return nil
+	}
+	ref := value.(teamsConversationRef)
+	return c.postActivity(ctx, ref, teamsActivity{Type: "typing", From: ref.Bot})
+}
+
+func (c *TeamsChannel) postActivity(ctx context.Context, ref teamsConversationRef, activity teamsActivity) error {
+	data, err := json.Marshal(activity)
+	if err != nil {
+		return err
+	}
+
+	endpoint := strings.TrimRight(ref.ServiceURL, "/") + "/v3/conversations/" + url.PathEscape(ref.ConversationID) + "/activities"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.apiClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("status %d: %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
+
+// inlineTeamsImage embeds a small local image as a data URL attachment.
+// Teams has no upload endpoint for bots outside of personal chats, so other
+// file types are not supported.
+func inlineTeamsImage(path string) (teamsAttachment, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return teamsAttachment{}, err
+	}
+	if info.Size() > teamsMaxInlineImage {
+		return teamsAttachment{}, fmt.Errorf("image larger than %d bytes", teamsMaxInlineImage)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return teamsAttachment{}, err
+	}
+	contentType := http.DetectContentType(data)
+	if !strings.HasPrefix(contentType, "image/") {
+		return teamsAttachment{}, fmt.Errorf("only images can be attached (got %s)", contentType)
+	}
+
+	return teamsAttachment{
+		ContentType: contentType,
+		ContentURL:  "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(data),
+		Name:        filepath.Base(path),
+	}, nil
+}
+
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+func sameHost(a, b string) bool {
+	host := hostOf(a)
+	return host != "" && host == hostOf(b)
+}
@@ -0,0 +1,242 @@
+package channels
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	teamsClockSkew       = 5 * time.Minute
+	teamsKeyRefreshEvery = 24 * time.Hour
+	teamsKeyRetryAfter   = 5 * time.Minute
+)
+
+// teamsKeySet validates Bot Framework JWTs against the signing keys published
+// through an OpenID metadata document. Keys are cached and refreshed daily, or
+// earlier when a token is signed with an unknown key id.
+type teamsKeySet struct {
+	metadataURL string
+	httpClient  *http.Client
+
+	mu          sync.Mutex
+	issuer      string
+	keys        map[string]teamsSigningKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+}
+
+type teamsSigningKey struct {
+	key          *rsa.PublicKey
+	endorsements []string
+}
+
+type teamsClaims struct {
+	Issuer     string          `json:"iss"`
+	Audience   json.RawMessage `json:"aud"`
+	Expiry     int64           `json:"exp"`
+	NotBefore  int64           `json:"nbf"`
+	ServiceURL string          `json:"serviceurl"`
+}
+
+func newTeamsKeySet(metadataURL string, client *http.Client) *teamsKeySet {
+	return &teamsKeySet{
+		metadataURL: metadataURL,
+		httpClient:  client,
+		keys:        make(map[string]teamsSigningKey),
+	}
+}
+
+// Verify checks the signature and standard claims of a Bot Framework token and
+// returns its claims. channelID is checked against the key's endorsements.
+func (ks *teamsKeySet) Verify(ctx context.Context, token, audience, channelID string) (*teamsClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("invalid token header: %w", err)
+	}
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("unsupported signing algorithm %q", header.Alg)
+	}
+
+	key, issuer, err := ks.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid token signature encoding: %w", err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key.key, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, fmt.Errorf("invalid token signature")
+	}
+
+	var claims teamsClaims
+	if err := decodeJWTSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("invalid token claims: %w", err)
+	}
+
+	now := time.Now()
+	if claims.Expiry == 0 || now.After(time.Unix(claims.Expiry, 0).Add(teamsClockSkew)) {
+		return nil, fmt.Errorf("token expired")
+	}
+	if claims.NotBefore != 0 && now.Add(teamsClockSkew).Before(time.Unix(claims.NotBefore, 0)) {
+		return nil, fmt.Errorf("token not yet valid")
+	}
+	if issuer != "" && claims.Issuer != issuer {
+		return nil, fmt.Errorf("unexpected token issuer %q", claims.Issuer)
+	}
+	if !claims.hasAudience(audience) {
+		return nil, fmt.Errorf("token audience does not match app id")
+	}
+	if channelID != "" && len(key.endorsements) > 0 && !containsString(key.endorsements, channelID) {
+		return nil, fmt.Errorf("signing key is not endorsed for channel %q", channelID)
+	}
+
+	return &claims, nil
+}
+
+func (c *teamsClaims) hasAudience(audience string) bool {
+	var single string
+	if err := json.Unmarshal(c.Audience, &single); err == nil {
+		return single == audience
+	}
+	var list []string
+	if err := json.Unmarshal(c.Audience, &list); err == nil {
+		return containsString(list, audience)
+	}
+	return false
+}
+
+func (ks *teamsKeySet) key(ctx context.Context, kid string) (teamsSigningKey, string, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	key, ok := ks.keys[kid]
+	stale := time.Since(ks.fetchedAt) > teamsKeyRefreshEvery
+	if ok && !stale {
+		return key, ks.issuer, nil
+	}
+
+	// Unknown key ids trigger a refresh, but not more often than teamsKeyRetryAfter.
+	if !stale && time.Since(ks.lastAttempt) < teamsKeyRetryAfter {
+		return teamsSigningKey{}, "", fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	ks.lastAttempt = time.Now()
+	if err := ks.refresh(ctx); err != nil {
+		if ok {
+			// Keep serving the cached key if the refresh fails.
+			return key, ks.issuer, nil
+		}
+		return teamsSigningKey{}, "", fmt.Errorf("failed to fetch signing keys: %w", err)
+	}
+
+	key, ok = ks.keys[kid]
+	if !ok {
+		return teamsSigningKey{}, "", fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, ks.issuer, nil
+}
+
+func (ks *teamsKeySet) refresh(ctx context.Context) error {
+	var metadata struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := ks.getJSON(ctx, ks.metadataURL, &metadata); err != nil {
+		return fmt.Errorf("openid metadata: %w", err)
+	}
+	if metadata.JWKSURI == "" {
+		return fmt.Errorf("openid metadata has no jwks_uri")
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kty          string   `json:"kty"`
+			Kid          string   `json:"kid"`
+			N            string   `json:"n"`
+			E            string   `json:"e"`
+			Endorsements []string `json:"endorsements"`
+		} `json:"keys"`
+	}
+	if err := ks.getJSON(ctx, metadata.JWKSURI, &jwks); err != nil {
+		return fmt.Errorf("jwks: %w", err)
+	}
+
+	keys := make(map[string]teamsSigningKey, len(jwks.Keys))
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil {
+			continue
+		}
+		keys[k.Kid] = teamsSigningKey{
+			key: &rsa.PublicKey{
+				N: new(big.Int).SetBytes(n),
+				E: int(new(big.Int).SetBytes(e).Int64()),
+			},
+			endorsements: k.Endorsements,
+		}
+	}
+
+	ks.issuer = metadata.Issuer
+	ks.keys = keys
+	ks.fetchedAt = time.Now()
+	return nil
+}
+
+func (ks *teamsKeySet) getJSON(ctx context.Context, url string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := ks.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func decodeJWTSegment(segment string, out interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
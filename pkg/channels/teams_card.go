@@ -0,0 +1,161 @@
+package channels
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	teamsHeadingRe        = regexp.MustCompile(`^(#{1,6})\s+(.+)$`)
+	teamsTableSeparatorRe = regexp.MustCompile(`^\|?\s*:?-{3,}:?\s*(\|\s*:?-{3,}:?\s*)*\|?$`)
+)
+
+// needsAdaptiveCard reports whether markdown content uses constructs that
+// Teams' markdown renderer handles poorly: code blocks, tables and headings.
+func needsAdaptiveCard(content string) bool {
+	if strings.Contains(content, "```") {
+		return true
+	}
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if teamsHeadingRe.MatchString(line) {
+			return true
+		}
+		if strings.HasPrefix(line, "|") && i+1 < len(lines) && teamsTableSeparatorRe.MatchString(strings.TrimSpace(lines[i+1])) {
+			return true
+		}
+	}
+	return false
+}
+
+// renderAdaptiveCard converts markdown into an Adaptive Card. Paragraphs stay
+// TextBlocks (which support basic markdown), headings become bold blocks,
+// fenced code becomes monospace containers and pipe tables become Table elements.
+func renderAdaptiveCard(content string) map[string]interface{} {
+	var body []interface{}
+	var paragraph []string
+
+	flush := func() {
+		text := strings.TrimSpace(strings.Join(paragraph, "\n"))
+		paragraph = nil
+		if text != "" {
+			body = append(body, adaptiveTextBlock(text, nil))
+		}
+	}
+
+	lines := strings.Split(content, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(trimmed, "```"):
+			flush()
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, lines[i])
+			}
+			body = append(body, map[string]interface{}{
+				"type":  "Container",
+				"style": "emphasis",
+				"items": []interface{}{
+					adaptiveTextBlock(strings.Join(code, "\n"), map[string]interface{}{"fontType": "Monospace"}),
+				},
+			})
+
+		case teamsHeadingRe.MatchString(trimmed):
+			flush()
+			m := teamsHeadingRe.FindStringSubmatch(trimmed)
+			size := "Default"
+			switch len(m[1]) {
+			case 1:
+				size = "Large"
+			case 2:
+				size = "Medium"
+			}
+			body = append(body, adaptiveTextBlock(m[2], map[string]interface{}{"weight": "Bolder", "size": size}))
+
+		case strings.HasPrefix(trimmed, "|") && i+1 < len(lines) && teamsTableSeparatorRe.MatchString(strings.TrimSpace(lines[i+1])):
+			flush()
+			rows := [][]string{splitTableRow(trimmed)}
+			for i += 2; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), "|"); i++ {
+				rows = append(rows, splitTableRow(strings.TrimSpace(lines[i])))
+			}
+			i--
+			body = append(body, adaptiveTable(rows))
+
+		case trimmed == "":
+			flush()
+
+		default:
+			paragraph = append(paragraph, line)
+		}
+	}
+	flush()
+
+	return map[string]interface{}{
+		"type":    "AdaptiveCard",
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"version": "1.5",
+		"body":    body,
+	}
+}
+
+func adaptiveTextBlock(text string, extra map[string]interface{}) map[string]interface{} {
+	block := map[string]interface{}{
+		"type": "TextBlock",
+		"text": text,
+		"wrap": true,
+	}
+	for k, v := range extra {
+		block[k] = v
+	}
+	return block
+}
+
+func adaptiveTable(rows [][]string) map[string]interface{} {
+	width := 0
+	for _, row := range rows {
+		if len(row) > width {
+			width = len(row)
+		}
+	}
+
+	columns := make([]interface{}, width)
+	for i := range columns {
+		columns[i] = map[string]interface{}{"width": 1}
+	}
+
+	tableRows := make([]interface{}, 0, len(rows))
+	for _, row := range rows {
+		cells := make([]interface{}, width)
+		for i := range cells {
+			text := ""
+			if i < len(row) {
+				text = row[i]
+			}
+			cells[i] = map[string]interface{}{
+				"type":  "TableCell",
+				"items": []interface{}{adaptiveTextBlock(text, nil)},
+			}
+		}
+		tableRows = append(tableRows, map[string]interface{}{"type": "TableRow", "cells": cells})
+	}
+
+	return map[string]interface{}{
+		"type":             "Table",
+		"firstRowAsHeader": true,
+		"columns":          columns,
+		"rows":             tableRows,
+	}
+}
+
+func splitTableRow(line string) []string {
+	line = strings.TrimPrefix(strings.TrimSuffix(line, "|"), "|")
+	cells := strings.Split(line, "|")
+	for i := range cells {
+		cells[i] = strings.TrimSpace(cells[i])
+	}
+	return cells
+}
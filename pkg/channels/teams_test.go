@@ -0,0 +1,316 @@
+package channels
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+const testTeamsAppID = "app-123"
+
+// fakeBotFramework serves OpenID metadata, a local key set, the token endpoint
+// and a connector service URL.
+type fakeBotFramework struct {
+	srv    *httptest.Server
+	key    *rsa.PrivateKey
+	posted chan teamsActivity
+}
+
+func newFakeBotFramework(t *testing.T) *fakeBotFramework {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeBotFramework{key: key, posted: make(chan teamsActivity, 10)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/openid", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":   "https://api.botframework.com",
+			"jwks_uri": f.srv.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]interface{}{{
+				"kty":          "RSA",
+				"kid":          "k1",
+				"n":            base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":            base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+				"endorsements": []string{"msteams"},
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		id, secret, ok := r.BasicAuth()
+		if !ok {
+			id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+		}
+		if id != testTeamsAppID || secret != "pw" || r.PostForm.Get("scope") != teamsBotFrameworkScope {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"bot-access","token_type":"Bearer","expires_in":3600}`))
+	})
+	mux.HandleFunc("/v3/conversations/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer bot-access" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var activity teamsActivity
+		json.NewDecoder(r.Body).Decode(&activity)
+		f.posted <- activity
+		w.Write([]byte(`{"id":"reply-1"}`))
+	})
+	mux.HandleFunc("/attachments/cat.png", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer bot-access" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("\x89PNG\r\n\x1a\nfake"))
+	})
+	f.srv = httptest.NewServer(mux)
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+func (f *fakeBotFramework) sign(t *testing.T, claims map[string]interface{}) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": "k1"})
+	payload, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, f.key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func (f *fakeBotFramework) validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"iss":        "https://api.botframework.com",
+		"aud":        testTeamsAppID,
+		"exp":        time.Now().Add(time.Hour).Unix(),
+		"nbf":        time.Now().Add(-time.Minute).Unix(),
+		"serviceurl": f.srv.URL,
+	}
+}
+
+func startTestTeams(t *testing.T, f *fakeBotFramework) (*TeamsChannel, *bus.MessageBus) {
+	t.Helper()
+	mb := bus.NewMessageBus()
+	ch, err := NewTeamsChannel(config.TeamsConfig{
+		AppID:             testTeamsAppID,
+		AppPassword:       "pw",
+		OpenIDMetadataURL: f.srv.URL + "/openid",
+		AdaptiveCards:     true,
+	}, mb)
+	if err != nil {
+		t.Fatalf("NewTeamsChannel: %v", err)
+	}
+	ch.tokenURL = f.srv.URL + "/token"
+	if err := ch.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { ch.Stop(context.Background()) })
+	return ch, mb
+}
+
+func postTeamsActivity(ch *TeamsChannel, token string, activity map[string]interface{}) int {
+	body, _ := json.Marshal(activity)
+	req := httptest.NewRequest(http.MethodPost, "/webhook/teams", strings.NewReader(string(body)))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	ch.WebhookHandler().ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func (f *fakeBotFramework) messageActivity(text string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":         "message",
+		"id":           "act-1",
+		"serviceUrl":   f.srv.URL,
+		"channelId":    "msteams",
+		"from":         map[string]string{"id": "29:user", "name": "Ann", "aadObjectId": "aad-1"},
+		"conversation": map[string]string{"id": "a:conv;messageid=1", "conversationType": "channel"},
+		"recipient":    map[string]string{"id": "28:bot", "name": "PicoBot"},
+		"text":         text,
+		"entities": []map[string]interface{}{{
+			"type":      "mention",
+			"text":      "<at>PicoBot</at>",
+			"mentioned": map[string]string{"id": "28:bot", "name": "PicoBot"},
+		}},
+	}
+}
+
+func TestTeamsWebhookAuth(t *testing.T) {
+	f := newFakeBotFramework(t)
+	ch, mb := startTestTeams(t, f)
+
+	activity := f.messageActivity("<at>PicoBot</at> hello")
+
+	expired := f.validClaims()
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+	wrongAud := f.validClaims()
+	wrongAud["aud"] = "someone-else"
+	wrongService := f.validClaims()
+	wrongService["serviceurl"] = "https://evil.example.com"
+	tampered := f.sign(t, f.validClaims())
+	tampered = tampered[:len(tampered)-4] + "AAAA"
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"missing token", ""},
+		{"garbage", "not.a.jwt"},
+		{"bad signature", tampered},
+		{"expired", f.sign(t, expired)},
+		{"wrong audience", f.sign(t, wrongAud)},
+		{"service url mismatch", f.sign(t, wrongService)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := postTeamsActivity(ch, tt.token, activity); code != http.StatusUnauthorized {
+				t.Fatalf("status = %d, want 401", code)
+			}
+		})
+	}
+
+	if code := postTeamsActivity(ch, f.sign(t, f.validClaims()), activity); code != http.StatusOK {
+		t.Fatalf("valid token: status = %d, want 200", code)
+	}
+	msg := consumeInbound(t, mb)
+	if msg.Content != "hello" || msg.ChatID != "a:conv;messageid=1" || msg.SenderID != "29:user|aad-1" {
+		t.Fatalf("unexpected inbound message: %+v", msg)
+	}
+}
+
+func TestTeamsAttachmentsAndReplies(t *testing.T) {
+	f := newFakeBotFramework(t)
+	ch, mb := startTestTeams(t, f)
+
+	activity := f.messageActivity("<at>PicoBot</at> what is this?")
+	activity["attachments"] = []map[string]interface{}{
+		{"contentType": "image/png", "contentUrl": f.srv.URL + "/attachments/cat.png"},
+		{"contentType": "text/html", "content": "<p>what is this?</p>"},
+	}
+	if code := postTeamsActivity(ch, f.sign(t, f.validClaims()), activity); code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+
+	msg := consumeInbound(t, mb)
+	if len(msg.Media) != 1 || !strings.Contains(msg.Content, "[file: image.png]") {
+		t.Fatalf("expected one downloaded image, got %+v", msg)
+	}
+
+	if err := ch.SendTyping(context.Background(), msg.ChatID); err != nil {
+		t.Fatalf("SendTyping: %v", err)
+	}
+	waitTyping := func() {
+		for {
+			select {
+			case a := <-f.posted:
+				if a.Type == "typing" {
+					return
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("no typing activity")
+			}
+		}
+	}
+	waitTyping()
+
+	if err := ch.Send(context.Background(), bus.OutboundMessage{ChatID: msg.ChatID, Content: "It's a **cat**."}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	reply := <-f.posted
+	if reply.Type != "message" || reply.Text != "It's a **cat**." || reply.ReplyToID != "act-1" || len(reply.Attachments) != 0 {
+		t.Fatalf("unexpected reply: %+v", reply)
+	}
+
+	rich := "# Result\n\n| a | b |\n|---|---|\n| 1 | 2 |\n\n```\ncode\n```"
+	if err := ch.Send(context.Background(), bus.OutboundMessage{ChatID: msg.ChatID, Content: rich}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	reply = <-f.posted
+	if len(reply.Attachments) != 1 || reply.Attachments[0].ContentType != "application/vnd.microsoft.card.adaptive" {
+		t.Fatalf("expected an adaptive card, got %+v", reply)
+	}
+
+	if err := ch.Send(context.Background(), bus.OutboundMessage{ChatID: "unknown", Content: "x"}); err == nil {
+		t.Fatal("expected error for unknown conversation")
+	}
+}
+
+func TestRenderAdaptiveCard(t *testing.T) {
+	if needsAdaptiveCard("just **bold** text\n- item") {
+		t.Error("plain markdown should not need a card")
+	}
+
+	content := "## Title\nSome text\n\n| Name | Qty |\n| --- | --- |\n| apple | 3 |\n| pear | 5 |\n\n```go\nfmt.Println()\n```"
+	if !needsAdaptiveCard(content) {
+		t.Fatal("expected card for headings, tables and code")
+	}
+
+	card := renderAdaptiveCard(content)
+	body := card["body"].([]interface{})
+	if len(body) != 4 {
+		t.Fatalf("expected 4 body elements, got %d: %v", len(body), body)
+	}
+
+	heading := body[0].(map[string]interface{})
+	if heading["text"] != "Title" || heading["weight"] != "Bolder" || heading["size"] != "Medium" {
+		t.Errorf("unexpected heading %v", heading)
+	}
+	if body[1].(map[string]interface{})["text"] != "Some text" {
+		t.Errorf("unexpected paragraph %v", body[1])
+	}
+	table := body[2].(map[string]interface{})
+	if table["type"] != "Table" || len(table["rows"].([]interface{})) != 3 {
+		t.Errorf("unexpected table %v", table)
+	}
+	code := body[3].(map[string]interface{})["items"].([]interface{})[0].(map[string]interface{})
+	if code["text"] != "fmt.Println()" || code["fontType"] != "Monospace" {
+		t.Errorf("unexpected code block %v", code)
+	}
+}
+
+func TestTeamsLifecycle(t *testing.T) {
+	if _, err := NewTeamsChannel(config.TeamsConfig{AppID: testTeamsAppID}, bus.NewMessageBus()); err == nil {
+		t.Fatal("expected error without app password")
+	}
+
+	f := newFakeBotFramework(t)
+	ch, _ := startTestTeams(t, f)
+	if ch.WebhookPath() != "/webhook/teams" {
+		t.Fatalf("WebhookPath() = %q", ch.WebhookPath())
+	}
+
+	ch.Stop(context.Background())
+	if code := postTeamsActivity(ch, f.sign(t, f.validClaims()), f.messageActivity("hi")); code != http.StatusServiceUnavailable {
+		t.Fatalf("status after Stop = %d, want 503", code)
+	}
+	if err := ch.Send(context.Background(), bus.OutboundMessage{ChatID: "a:conv", Content: "x"}); err == nil {
+		t.Fatal("expected Send to fail after Stop")
+	}
+}
@@ -2,13 +2,19 @@ package channels
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"os"
 	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/mymmrac/telego"
@@ -16,61 +22,81 @@ import (
 
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/locale"
 	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/network"
 	"github.com/sipeed/picoclaw/pkg/utils"
 	"github.com/sipeed/picoclaw/pkg/voice"
 )
 
+// telegramMaxMessageLen leaves headroom below Telegram's 4096 character limit
+// for the markup added by markdownToTelegramHTML.
+const telegramMaxMessageLen = 4000
+
+// telegramStateNamespace holds the offset of the last update, so polling
+// resumes after it instead of receiving updates again after a restart.
+const telegramStateNamespace = "telegram"
+
+// telegramAllowedUpdates are the update types requested in both polling and webhook mode.
+var telegramAllowedUpdates = []string{"message", "edited_message", "callback_query", "message_reaction"}
+
 type TelegramChannel struct {
 	*BaseChannel
-	bot          *telego.Bot
-	config       config.TelegramConfig
-	chatIDs      map[string]int64
-	transcriber  *voice.GroqTranscriber
-	placeholders sync.Map // chatID -> messageID
-	stopThinking sync.Map // chatID -> thinkingCancel
+	bot            *telego.Bot
+	config         config.TelegramConfig
+	chatIDs        map[string]int64
+	transcriber    *voice.GroqTranscriber
+	placeholders   sync.Map // chatID -> message ID of the progress placeholder
+	ctx            context.Context
+	cancel         context.CancelFunc
+	webhookSecret  string
+	webhookActive  atomic.Bool
+	webhookUpdates chan telego.Update
+	lastUpdateID   atomic.Int64
+	savedUpdateID  atomic.Int64 // the offset last written to the state store
+
+	answersMu  sync.Mutex
+	answers    map[string]string // "chatID:messageID" -> trace ID of the turn it answers
+	answerKeys []string          // oldest first, at most telegramMaxAnswers
 }
 
-type thinkingCancel struct {
-	fn context.CancelFunc
-}
-
-func (c *thinkingCancel) Cancel() {
-	if c != nil && c.fn != nil {
-		c.fn()
-	}
-}
+// telegramMaxAnswers is how many answers are remembered for reactions.
+const telegramMaxAnswers = 512
 
 func NewTelegramChannel(cfg config.TelegramConfig, bus *bus.MessageBus) (*TelegramChannel, error) {
-	var opts []telego.BotOption
-
 	if cfg.Proxy != "" {
-		proxyURL, parseErr := url.Parse(cfg.Proxy)
-		if parseErr != nil {
+		if _, parseErr := url.Parse(cfg.Proxy); parseErr != nil {
 			return nil, fmt.Errorf("invalid proxy URL %q: %w", cfg.Proxy, parseErr)
 		}
-		opts = append(opts, telego.WithHTTPClient(&http.Client{
-			Transport: &http.Transport{
-				Proxy: http.ProxyURL(proxyURL),
-			},
-		}))
 	}
 
-	bot, err := telego.NewBot(cfg.Token, opts...)
+	// The channel's own proxy setting overrides the global network proxy.
+	client := network.NewClient("telegram", network.Options{Proxy: cfg.Proxy})
+	bot, err := telego.NewBot(cfg.Token, telego.WithHTTPClient(client))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create telegram bot: %w", err)
 	}
 
 	base := NewBaseChannel("telegram", cfg, bus, cfg.AllowFrom)
 
+	secret := cfg.WebhookSecret
+	if secret == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+		}
+		secret = hex.EncodeToString(buf)
+	}
+
 	return &TelegramChannel{
-		BaseChannel:  base,
-		bot:          bot,
-		config:       cfg,
-		chatIDs:      make(map[string]int64),
-		transcriber:  nil,
-		placeholders: sync.Map{},
-		stopThinking: sync.Map{},
+		BaseChannel:    base,
+		bot:            bot,
+		config:         cfg,
+		chatIDs:        make(map[string]int64),
+		transcriber:    nil,
+		placeholders:   sync.Map{},
+		webhookSecret:  secret,
+		webhookUpdates: make(chan telego.Update, 128),
 	}, nil
 }
 
@@ -79,12 +105,37 @@ func (c *TelegramChannel) SetTranscriber(transcriber *voice.GroqTranscriber) {
 }
 
 func (c *TelegramChannel) Start(ctx context.Context) error {
+	if c.IsRunning() {
+		return nil
+	}
+
+	c.ctx, c.cancel = context.WithCancel(ctx)
+	c.loadLastUpdateID()
+	go saveEvery(c.ctx, c.saveLastUpdateID)
+
+	if c.config.WebhookURL != "" {
+		err := c.startWebhook(c.ctx)
+		if err == nil {
+			c.setRunning(true)
+			return nil
+		}
+		logger.WarnCF("telegram", "Webhook setup failed, falling back to polling", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
 	logger.InfoC("telegram", "Starting Telegram bot (polling mode)...")
 
-	updates, err := c.bot.UpdatesViaLongPolling(ctx, &telego.GetUpdatesParams{
-		Timeout: 30,
-	})
+	// getUpdates is refused while a webhook is set, e.g. one left over from a previous run.
+	if err := c.bot.DeleteWebhook(c.ctx, &telego.DeleteWebhookParams{}); err != nil {
+		logger.WarnCF("telegram", "Failed to delete webhook before polling", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	updates, err := c.bot.UpdatesViaLongPolling(c.ctx, c.pollingParams())
 	if err != nil {
+		c.cancel()
 		return fmt.Errorf("failed to start long polling: %w", err)
 	}
 
@@ -93,32 +144,215 @@ func (c *TelegramChannel) Start(ctx context.Context) error {
 		"username": c.bot.Username(),
 	})
 
-	go func() {
+	go c.pollLoop(c.ctx, updates)
+
+	return nil
+}
+
+// startWebhook registers the webhook with Telegram. Updates then arrive on the
+// gateway HTTP server via WebhookHandler.
+func (c *TelegramChannel) startWebhook(ctx context.Context) error {
+	logger.InfoCF("telegram", "Starting Telegram bot (webhook mode)...", map[string]interface{}{
+		"url": c.config.WebhookURL,
+	})
+
+	err := c.bot.SetWebhook(ctx, &telego.SetWebhookParams{
+		URL:            c.config.WebhookURL,
+		SecretToken:    c.webhookSecret,
+		AllowedUpdates: telegramAllowedUpdates,
+	})
+	if err != nil {
+		return fmt.Errorf("setWebhook: %w", err)
+	}
+
+	c.webhookActive.Store(true)
+	go c.processUpdates(ctx, c.webhookUpdates)
+
+	logger.InfoC("telegram", "Telegram webhook registered")
+	return nil
+}
+
+func (c *TelegramChannel) pollingParams() *telego.GetUpdatesParams {
+	return &telego.GetUpdatesParams{
+		Offset:         int(c.lastUpdateID.Load()) + 1,
+		Timeout:        30,
+		AllowedUpdates: telegramAllowedUpdates,
+	}
+}
+
+// pollLoop consumes long-polling updates and restarts polling with backoff
+// whenever the updates channel closes before the channel is stopped.
+func (c *TelegramChannel) pollLoop(ctx context.Context, updates <-chan telego.Update) {
+	backoff := time.Second
+	for {
+		c.processUpdates(ctx, updates)
+		if ctx.Err() != nil {
+			return
+		}
+
+		logger.WarnCF("telegram", "Updates channel closed, restarting polling", map[string]interface{}{
+			"backoff": backoff.String(),
+		})
+
 		for {
 			select {
 			case <-ctx.Done():
 				return
-			case update, ok := <-updates:
-				if !ok {
-					logger.InfoC("telegram", "Updates channel closed, reconnecting...")
-					return
-				}
-				if update.Message != nil {
-					c.handleMessage(ctx, update)
-				}
+			case <-time.After(backoff):
+			}
+
+			var err error
+			updates, err = c.bot.UpdatesViaLongPolling(ctx, c.pollingParams())
+			if err == nil {
+				backoff = time.Second
+				break
+			}
+
+			logger.ErrorCF("telegram", "Failed to restart polling", map[string]interface{}{
+				"error": err.Error(),
+			})
+			backoff *= 2
+			if backoff > time.Minute {
+				backoff = time.Minute
 			}
 		}
-	}()
+	}
+}
 
-	return nil
+// loadLastUpdateID restores the update offset saved by a previous run.
+func (c *TelegramChannel) loadLastUpdateID() {
+	if c.state == nil {
+		return
+	}
+	var id int64
+	if ok, err := c.state.Get(telegramStateNamespace, "last_update_id", &id); err != nil {
+		logger.WarnCF("telegram", "Failed to load update offset", map[string]interface{}{
+			"error": err.Error(),
+		})
+	} else if ok && id > c.lastUpdateID.Load() {
+		c.lastUpdateID.Store(id)
+		c.savedUpdateID.Store(id)
+	}
+}
+
+// saveLastUpdateID saves the update offset if it moved since the last save.
+// After a crash, at most the updates of one stateSaveInterval come again.
+func (c *TelegramChannel) saveLastUpdateID() {
+	id := c.lastUpdateID.Load()
+	if c.state == nil || id == c.savedUpdateID.Load() {
+		return
+	}
+	if err := c.state.Set(telegramStateNamespace, "last_update_id", id, 0); err != nil {
+		logger.WarnCF("telegram", "Failed to save update offset", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.savedUpdateID.Store(id)
+}
+
+// processUpdates handles updates in order until the channel closes or ctx ends.
+func (c *TelegramChannel) processUpdates(ctx context.Context, updates <-chan telego.Update) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case update, ok := <-updates:
+			if !ok {
+				return
+			}
+			c.handleUpdate(ctx, update)
+		}
+	}
+}
+
+func (c *TelegramChannel) handleUpdate(ctx context.Context, update telego.Update) {
+	if int64(update.UpdateID) > c.lastUpdateID.Load() {
+		c.lastUpdateID.Store(int64(update.UpdateID))
+	}
+
+	switch {
+	case update.Message != nil:
+		c.handleMessage(ctx, update.Message, false)
+	case update.EditedMessage != nil:
+		c.handleMessage(ctx, update.EditedMessage, true)
+	case update.CallbackQuery != nil:
+		c.handleCallbackQuery(ctx, update.CallbackQuery)
+	case update.MessageReaction != nil:
+		c.handleReaction(update.MessageReaction)
+	}
 }
 
 func (c *TelegramChannel) Stop(ctx context.Context) error {
 	logger.InfoC("telegram", "Stopping Telegram bot...")
 	c.setRunning(false)
+	if c.cancel != nil {
+		c.cancel()
+	}
+	c.saveLastUpdateID()
+
+	// Telegram keeps posting to a registered webhook; with it removed,
+	// pending updates wait for the next run instead of failing delivery.
+	if c.webhookActive.Swap(false) {
+		if err := c.bot.DeleteWebhook(ctx, &telego.DeleteWebhookParams{}); err != nil {
+			logger.WarnCF("telegram", "Failed to delete webhook", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+	}
 	return nil
 }
 
+// WebhookPath implements WebhookChannel. It is empty unless webhook_url is set.
+func (c *TelegramChannel) WebhookPath() string {
+	if c.config.WebhookURL == "" {
+		return ""
+	}
+	if c.config.WebhookPath == "" {
+		return "/webhook/telegram"
+	}
+	return c.config.WebhookPath
+}
+
+func (c *TelegramChannel) WebhookHandler() http.Handler {
+	return http.HandlerFunc(c.serveWebhook)
+}
+
+func (c *TelegramChannel) serveWebhook(w http.ResponseWriter, r *http.Request) {
+	if !c.webhookActive.Load() {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := r.Header.Get("X-Telegram-Bot-Api-Secret-Token")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(c.webhookSecret)) != 1 {
+		logger.WarnCF("telegram", "Webhook request with invalid secret token", map[string]interface{}{
+			"remote_addr": r.RemoteAddr,
+		})
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var update telego.Update
+	if err := json.NewDecoder(io.LimitReader(r.Body, 4<<20)).Decode(&update); err != nil {
+		logger.WarnCF("telegram", "Failed to decode webhook update", map[string]interface{}{
+			"error": err.Error(),
+		})
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	select {
+	case c.webhookUpdates <- update:
+		w.WriteHeader(http.StatusOK)
+	case <-r.Context().Done():
+	}
+}
+
 func (c *TelegramChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
 	if !c.IsRunning() {
 		return fmt.Errorf("telegram bot not running")
@@ -129,45 +363,162 @@ func (c *TelegramChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 		return fmt.Errorf("invalid chat ID: %w", err)
 	}
 
-	// Stop thinking animation
-	if stop, ok := c.stopThinking.Load(msg.ChatID); ok {
-		if cf, ok := stop.(*thinkingCancel); ok && cf != nil {
-			cf.Cancel()
-		}
-		c.stopThinking.Delete(msg.ChatID)
-	}
+	chunks := utils.SplitMessage(msg.Content, telegramMaxMessageLen)
+	keyboard := telegramKeyboard(msg.Buttons)
 
-	htmlContent := markdownToTelegramHTML(msg.Content)
-
-	// Try to edit placeholder
-	if pID, ok := c.placeholders.Load(msg.ChatID); ok {
-		c.placeholders.Delete(msg.ChatID)
-		editMsg := tu.EditMessageText(tu.ID(chatID), pID.(int), htmlContent)
+	// Try to edit the progress placeholder into the first part
+	if pID, ok := c.placeholders.LoadAndDelete(msg.ChatID); ok {
+		editMsg := tu.EditMessageText(tu.ID(chatID), pID.(int), markdownToTelegramHTML(chunks[0]))
 		editMsg.ParseMode = telego.ModeHTML
+		if len(chunks) == 1 {
+			editMsg.ReplyMarkup = keyboard
+		}
 
 		if _, err = c.bot.EditMessageText(ctx, editMsg); err == nil {
-			return nil
+			chunks = chunks[1:]
+			c.rememberAnswer(msg.ChatID, pID.(int), msg.TraceID)
 		}
 		// Fallback to new message if edit fails
 	}
 
-	tgMsg := tu.Message(tu.ID(chatID), htmlContent)
+	for i, chunk := range chunks {
+		// Buttons go under the last part
+		var markup *telego.InlineKeyboardMarkup
+		if i == len(chunks)-1 {
+			markup = keyboard
+		}
+		messageID, err := c.sendText(ctx, chatID, chunk, markup)
+		if err != nil {
+			return err
+		}
+		c.rememberAnswer(msg.ChatID, messageID, msg.TraceID)
+	}
+
+	return nil
+}
+
+// rememberAnswer notes that messageID answers the turn traceID, so
+// reactions to it rate that turn.
+func (c *TelegramChannel) rememberAnswer(chatID string, messageID int, traceID string) {
+	if traceID == "" {
+		return
+	}
+	key := fmt.Sprintf("%s:%d", chatID, messageID)
+	c.answersMu.Lock()
+	defer c.answersMu.Unlock()
+	if c.answers == nil {
+		c.answers = make(map[string]string)
+	}
+	if len(c.answerKeys) >= telegramMaxAnswers {
+		delete(c.answers, c.answerKeys[0])
+		c.answerKeys = c.answerKeys[1:]
+	}
+	c.answers[key] = traceID
+	c.answerKeys = append(c.answerKeys, key)
+}
+
+// answeredTurn returns the trace ID of the turn messageID answers, if any.
+func (c *TelegramChannel) answeredTurn(chatID string, messageID int) string {
+	c.answersMu.Lock()
+	defer c.answersMu.Unlock()
+	return c.answers[fmt.Sprintf("%s:%d", chatID, messageID)]
+}
+
+// SendTyping implements TypingChannel. Telegram shows the action for five
+// seconds or until the bot posts.
+func (c *TelegramChannel) SendTyping(ctx context.Context, chatID string) error {
+	if !c.IsRunning() {
+		return fmt.Errorf("telegram bot not running")
+	}
+	id, err := parseChatID(chatID)
+	if err != nil {
+		return fmt.Errorf("invalid chat ID: %w", err)
+	}
+	return c.bot.SendChatAction(ctx, tu.ChatAction(tu.ID(id), telego.ChatActionTyping))
+}
+
+// SendProgress implements ProgressChannel. The placeholder is posted on the
+// first status of a turn, edited afterwards and finally edited into the
+// reply by Send.
+func (c *TelegramChannel) SendProgress(ctx context.Context, chatID, status string) error {
+	if !c.IsRunning() {
+		return fmt.Errorf("telegram bot not running")
+	}
+	id, err := parseChatID(chatID)
+	if err != nil {
+		return fmt.Errorf("invalid chat ID: %w", err)
+	}
+	if pID, ok := c.placeholders.Load(chatID); ok {
+		_, err := c.bot.EditMessageText(ctx, tu.EditMessageText(tu.ID(id), pID.(int), status))
+		return err
+	}
+	pMsg, err := c.bot.SendMessage(ctx, tu.Message(tu.ID(id), status))
+	if err != nil {
+		return err
+	}
+	c.placeholders.Store(chatID, pMsg.MessageID)
+	return nil
+}
+
+// ClearProgress implements ProgressChannel. It only has work to do when the
+// turn ended without a reply.
+func (c *TelegramChannel) ClearProgress(ctx context.Context, chatID string) error {
+	pID, ok := c.placeholders.LoadAndDelete(chatID)
+	if !ok {
+		return nil
+	}
+	id, err := parseChatID(chatID)
+	if err != nil {
+		return fmt.Errorf("invalid chat ID: %w", err)
+	}
+	return c.bot.DeleteMessage(ctx, tu.Delete(tu.ID(id), pID.(int)))
+}
+
+// telegramKeyboard lays out buttons as one row of an inline keyboard.
+func telegramKeyboard(buttons []bus.Button) *telego.InlineKeyboardMarkup {
+	if len(buttons) == 0 {
+		return nil
+	}
+	row := make([]telego.InlineKeyboardButton, len(buttons))
+	for i, b := range buttons {
+		row[i] = tu.InlineKeyboardButton(b.Text).WithCallbackData(b.Data)
+	}
+	return tu.InlineKeyboard(row)
+}
+
+// sendText sends content and returns the ID of the sent message.
+func (c *TelegramChannel) sendText(ctx context.Context, chatID int64, content string, markup *telego.InlineKeyboardMarkup) (int, error) {
+	tgMsg := tu.Message(tu.ID(chatID), markdownToTelegramHTML(content))
 	tgMsg.ParseMode = telego.ModeHTML
+	if markup != nil {
+		tgMsg.ReplyMarkup = markup
+	}
 
-	if _, err = c.bot.SendMessage(ctx, tgMsg); err != nil {
+	sent, err := c.bot.SendMessage(ctx, tgMsg)
+	if err != nil {
 		logger.ErrorCF("telegram", "HTML parse failed, falling back to plain text", map[string]interface{}{
 			"error": err.Error(),
 		})
+		tgMsg.Text = content
 		tgMsg.ParseMode = ""
-		_, err = c.bot.SendMessage(ctx, tgMsg)
-		return err
+		if sent, err = c.bot.SendMessage(ctx, tgMsg); err != nil {
+			return 0, err
+		}
 	}
 
-	return nil
+	return sent.MessageID, nil
 }
 
-func (c *TelegramChannel) handleMessage(ctx context.Context, update telego.Update) {
-	message := update.Message
+func telegramSenderID(user *telego.User) (userID, senderID string) {
+	userID = fmt.Sprintf("%d", user.ID)
+	senderID = userID
+	if user.Username != "" {
+		senderID = fmt.Sprintf("%s|%s", userID, user.Username)
+	}
+	return userID, senderID
+}
+
+func (c *TelegramChannel) handleMessage(ctx context.Context, message *telego.Message, edited bool) {
 	if message == nil {
 		return
 	}
@@ -177,11 +528,7 @@ func (c *TelegramChannel) handleMessage(ctx context.Context, update telego.Updat
 		return
 	}
 
-	userID := fmt.Sprintf("%d", user.ID)
-	senderID := userID
-	if user.Username != "" {
-		senderID = fmt.Sprintf("%s|%s", userID, user.Username)
-	}
+	userID, senderID := telegramSenderID(user)
 
 	// 检查白名单，避免为被拒绝的用户下载附件
 	if !c.IsAllowed(userID) && !c.IsAllowed(senderID) {
@@ -298,47 +645,111 @@ func (c *TelegramChannel) handleMessage(ctx context.Context, update telego.Updat
 		content = "[empty message]"
 	}
 
+	if edited {
+		content = "[edited] " + content
+	}
+
 	logger.DebugCF("telegram", "Received message", map[string]interface{}{
 		"sender_id": senderID,
 		"chat_id":   fmt.Sprintf("%d", chatID),
 		"preview":   utils.Truncate(content, 50),
 	})
 
-	// Thinking indicator
-	err := c.bot.SendChatAction(ctx, tu.ChatAction(tu.ID(chatID), telego.ChatActionTyping))
-	if err != nil {
-		logger.ErrorCF("telegram", "Failed to send chat action", map[string]interface{}{
+	metadata := map[string]string{
+		"message_id": fmt.Sprintf("%d", message.MessageID),
+		"user_id":    fmt.Sprintf("%d", user.ID),
+		"username":   user.Username,
+		"first_name": user.FirstName,
+		"is_group":   fmt.Sprintf("%t", message.Chat.Type != "private"),
+	}
+	if user.LanguageCode != "" {
+		metadata[locale.MetaLocale] = user.LanguageCode
+	}
+	if edited {
+		metadata["edited"] = "true"
+	}
+
+	c.HandleMessage(senderID, fmt.Sprintf("%d", chatID), content, mediaPaths, metadata)
+}
+
+// handleCallbackQuery forwards an inline keyboard button press as a message
+// whose content is the button's callback data.
+func (c *TelegramChannel) handleCallbackQuery(ctx context.Context, query *telego.CallbackQuery) {
+	// Always answer so the client stops showing the loading indicator.
+	if err := c.bot.AnswerCallbackQuery(ctx, tu.CallbackQuery(query.ID)); err != nil {
+		logger.DebugCF("telegram", "Failed to answer callback query", map[string]interface{}{
 			"error": err.Error(),
 		})
 	}
 
-	// Stop any previous thinking animation
-	chatIDStr := fmt.Sprintf("%d", chatID)
-	if prevStop, ok := c.stopThinking.Load(chatIDStr); ok {
-		if cf, ok := prevStop.(*thinkingCancel); ok && cf != nil {
-			cf.Cancel()
-		}
+	userID, senderID := telegramSenderID(&query.From)
+	if !c.IsAllowed(userID) && !c.IsAllowed(senderID) {
+		logger.DebugCF("telegram", "Callback query rejected by allowlist", map[string]interface{}{
+			"user_id": userID,
+		})
+		return
 	}
 
-	// Create cancel function for thinking state
-	_, thinkCancel := context.WithTimeout(ctx, 5*time.Minute)
-	c.stopThinking.Store(chatIDStr, &thinkingCancel{fn: thinkCancel})
-
-	pMsg, err := c.bot.SendMessage(ctx, tu.Message(tu.ID(chatID), "Thinking... 💭"))
-	if err == nil {
-		pID := pMsg.MessageID
-		c.placeholders.Store(chatIDStr, pID)
+	if query.Message == nil || query.Data == "" {
+		return
 	}
 
+	chat := query.Message.GetChat()
+	c.chatIDs[senderID] = chat.ID
+
+	logger.DebugCF("telegram", "Received callback query", map[string]interface{}{
+		"sender_id": senderID,
+		"chat_id":   fmt.Sprintf("%d", chat.ID),
+		"data":      utils.Truncate(query.Data, 50),
+	})
+
 	metadata := map[string]string{
-		"message_id": fmt.Sprintf("%d", message.MessageID),
-		"user_id":    fmt.Sprintf("%d", user.ID),
-		"username":   user.Username,
-		"first_name": user.FirstName,
-		"is_group":   fmt.Sprintf("%t", message.Chat.Type != "private"),
+		"message_id":        fmt.Sprintf("%d", query.Message.GetMessageID()),
+		"callback_query_id": query.ID,
+		"callback_data":     query.Data,
+		"user_id":           userID,
+		"username":          query.From.Username,
+		"first_name":        query.From.FirstName,
+		"is_group":          fmt.Sprintf("%t", chat.Type != "private"),
 	}
 
-	c.HandleMessage(senderID, fmt.Sprintf("%d", chatID), content, mediaPaths, metadata)
+	c.HandleMessage(senderID, fmt.Sprintf("%d", chat.ID), query.Data, []string{}, metadata)
+}
+
+// handleReaction forwards emoji reactions as messages whose content is the
+// emoji, marked with the "reaction" metadata key. Only added reactions
+// count; removed and anonymous ones are ignored.
+func (c *TelegramChannel) handleReaction(update *telego.MessageReactionUpdated) {
+	if update.User == nil {
+		return
+	}
+	userID, senderID := telegramSenderID(update.User)
+	if !c.IsAllowed(userID) && !c.IsAllowed(senderID) {
+		return
+	}
+
+	old := make(map[string]bool)
+	for _, r := range update.OldReaction {
+		if emoji, ok := r.(*telego.ReactionTypeEmoji); ok {
+			old[emoji.Emoji] = true
+		}
+	}
+	for _, r := range update.NewReaction {
+		emoji, ok := r.(*telego.ReactionTypeEmoji)
+		if !ok || old[emoji.Emoji] {
+			continue
+		}
+		metadata := map[string]string{
+			"message_id": fmt.Sprintf("%d", update.MessageID),
+			"trace_id":   c.answeredTurn(fmt.Sprintf("%d", update.Chat.ID), update.MessageID),
+			"reaction":   emoji.Emoji,
+			"user_id":    userID,
+			"username":   update.User.Username,
+			"first_name": update.User.FirstName,
+			"is_group":   fmt.Sprintf("%t", update.Chat.Type != "private"),
+		}
+		c.HandleMessage(senderID, fmt.Sprintf("%d", update.Chat.ID), emoji.Emoji, []string{}, metadata)
+	}
 }
 
 func (c *TelegramChannel) downloadPhoto(ctx context.Context, fileID string) string {
@@ -0,0 +1,254 @@
+package channels
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mymmrac/telego"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/channels/channeltest"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/state"
+)
+
+const testTelegramToken = "123456:ABC-DEF1234ghIkl-zyx57W2v1u123ew112"
+
+func newTestTelegramChannel(t *testing.T, cfg config.TelegramConfig, api *channeltest.Telegram) (*TelegramChannel, *bus.MessageBus) {
+	t.Helper()
+
+	cfg.Token = testTelegramToken
+	mb := bus.NewMessageBus()
+	ch, err := NewTelegramChannel(cfg, mb)
+	if err != nil {
+		t.Fatalf("NewTelegramChannel: %v", err)
+	}
+	ch.bot, err = telego.NewBot(testTelegramToken,
+		telego.WithAPIServer(api.URL()),
+		telego.WithHTTPClient(api.Client()),
+		telego.WithDiscardLogger())
+	if err != nil {
+		t.Fatalf("NewBot: %v", err)
+	}
+	return ch, mb
+}
+
+func postTelegramWebhook(ch *TelegramChannel, secret, body string) int {
+	req := httptest.NewRequest(http.MethodPost, "/webhook/telegram", strings.NewReader(body))
+	req.Header.Set("X-Telegram-Bot-Api-Secret-Token", secret)
+	rec := httptest.NewRecorder()
+	ch.WebhookHandler().ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestTelegramWebhookMode(t *testing.T) {
+	api := channeltest.NewTelegram(t)
+	ch, mb := newTestTelegramChannel(t, config.TelegramConfig{
+		WebhookURL:    "https://bot.example.com/webhook/telegram",
+		WebhookSecret: "s3cret",
+	}, api)
+
+	if got := ch.WebhookPath(); got != "/webhook/telegram" {
+		t.Fatalf("WebhookPath() = %q", got)
+	}
+
+	if code := postTelegramWebhook(ch, "s3cret", `{"update_id":1}`); code != http.StatusNotFound {
+		t.Fatalf("before Start: status = %d, want 404", code)
+	}
+
+	if err := ch.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer ch.Stop(context.Background())
+
+	if !api.Called("setWebhook") || api.Called("getUpdates") {
+		t.Fatalf("expected webhook mode, calls = %v", api.Calls())
+	}
+
+	message := `{"update_id":10,"message":{"message_id":5,"date":0,"chat":{"id":42,"type":"private"},"from":{"id":7,"is_bot":false,"first_name":"Ann","username":"ann"},"text":"hello"}}`
+	if code := postTelegramWebhook(ch, "wrong", message); code != http.StatusUnauthorized {
+		t.Fatalf("bad secret: status = %d, want 401", code)
+	}
+	if code := postTelegramWebhook(ch, "s3cret", "{not json"); code != http.StatusBadRequest {
+		t.Fatalf("bad body: status = %d, want 400", code)
+	}
+	if code := postTelegramWebhook(ch, "s3cret", message); code != http.StatusOK {
+		t.Fatalf("good secret: status = %d, want 200", code)
+	}
+
+	msg := consumeInbound(t, mb)
+	if msg.ChatID != "42" || msg.Content != "hello" || msg.SenderID != "7|ann" {
+		t.Fatalf("unexpected inbound message: %+v", msg)
+	}
+
+	edited := `{"update_id":11,"edited_message":{"message_id":5,"date":0,"chat":{"id":42,"type":"private"},"from":{"id":7,"is_bot":false,"first_name":"Ann"},"text":"hello again"}}`
+	postTelegramWebhook(ch, "s3cret", edited)
+	msg = consumeInbound(t, mb)
+	if msg.Content != "[edited] hello again" || msg.Metadata["edited"] != "true" {
+		t.Fatalf("unexpected edited message: %+v", msg)
+	}
+
+	callback := `{"update_id":12,"callback_query":{"id":"cb1","from":{"id":7,"is_bot":false,"first_name":"Ann"},"chat_instance":"x","data":"confirm","message":{"message_id":6,"date":1,"chat":{"id":42,"type":"private"}}}}`
+	postTelegramWebhook(ch, "s3cret", callback)
+	msg = consumeInbound(t, mb)
+	if msg.Content != "confirm" || msg.ChatID != "42" || msg.Metadata["callback_query_id"] != "cb1" {
+		t.Fatalf("unexpected callback message: %+v", msg)
+	}
+	if !api.Called("answerCallbackQuery") {
+		t.Fatal("callback query was not answered")
+	}
+
+	reaction := `{"update_id":13,"message_reaction":{"chat":{"id":42,"type":"private"},"message_id":6,"user":{"id":7,"is_bot":false,"first_name":"Ann"},"date":1,"old_reaction":[{"type":"emoji","emoji":"👀"}],"new_reaction":[{"type":"emoji","emoji":"👀"},{"type":"emoji","emoji":"👍"}]}}`
+	postTelegramWebhook(ch, "s3cret", reaction)
+	msg = consumeInbound(t, mb)
+	if msg.Content != "👍" || msg.Metadata["reaction"] != "👍" || msg.Metadata["message_id"] != "6" {
+		t.Fatalf("unexpected reaction message: %+v", msg)
+	}
+
+	if api.Called("deleteWebhook") {
+		t.Fatal("webhook deleted while running")
+	}
+	if err := ch.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if !api.Called("deleteWebhook") {
+		t.Fatalf("webhook not deleted on Stop, calls = %v", api.Calls())
+	}
+}
+
+func TestTelegramReactionsCarryTraceID(t *testing.T) {
+	api := channeltest.NewTelegram(t)
+	ch, mb := newTestTelegramChannel(t, config.TelegramConfig{
+		WebhookURL:    "https://bot.example.com/webhook/telegram",
+		WebhookSecret: "s3cret",
+	}, api)
+	if err := ch.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer ch.Stop(context.Background())
+
+	if err := ch.Send(context.Background(), bus.OutboundMessage{ChatID: "42", Content: "answer", TraceID: "t1"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	sent := api.Messages("42")
+	if len(sent) != 1 {
+		t.Fatalf("sent = %+v", sent)
+	}
+
+	react := func(updateID, messageID int) bus.InboundMessage {
+		postTelegramWebhook(ch, "s3cret", fmt.Sprintf(`{"update_id":%d,"message_reaction":{"chat":{"id":42,"type":"group"},"message_id":%d,"user":{"id":7,"is_bot":false,"first_name":"Ann"},"date":1,"old_reaction":[],"new_reaction":[{"type":"emoji","emoji":"👍"}]}}`, updateID, messageID))
+		return consumeInbound(t, mb)
+	}
+	if msg := react(1, sent[0].ID); msg.Metadata["trace_id"] != "t1" {
+		t.Errorf("reaction to the answer: trace_id = %q", msg.Metadata["trace_id"])
+	}
+	if msg := react(2, sent[0].ID+100); msg.Metadata["trace_id"] != "" {
+		t.Errorf("reaction to another message: trace_id = %q", msg.Metadata["trace_id"])
+	}
+}
+
+func TestTelegramWebhookFallsBackToPolling(t *testing.T) {
+	api := channeltest.NewTelegram(t)
+	api.SetResponse("setWebhook", `{"ok":false,"error_code":400,"description":"Bad Request: bad webhook: HTTPS url must be provided for webhook"}`)
+	api.PushUpdate(`{"update_id":20,"message":{"message_id":1,"date":0,"chat":{"id":42,"type":"private"},"from":{"id":7,"is_bot":false,"first_name":"Ann"},"text":"polled"}}`)
+	ch, mb := newTestTelegramChannel(t, config.TelegramConfig{
+		WebhookURL: "http://not-https.example.com/hook",
+	}, api)
+
+	if err := ch.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer ch.Stop(context.Background())
+
+	msg := consumeInbound(t, mb)
+	if msg.Content != "polled" {
+		t.Fatalf("unexpected inbound message: %+v", msg)
+	}
+	if !api.Called("deleteWebhook") {
+		t.Fatal("expected deleteWebhook before polling")
+	}
+	if code := postTelegramWebhook(ch, ch.webhookSecret, `{"update_id":21}`); code != http.StatusNotFound {
+		t.Fatalf("webhook should be inactive after fallback, status = %d", code)
+	}
+}
+
+func TestTelegramPollLoopRestarts(t *testing.T) {
+	api := channeltest.NewTelegram(t)
+	ch, mb := newTestTelegramChannel(t, config.TelegramConfig{}, api)
+	store := state.NewManager(t.TempDir())
+	ch.SetStateStore(store)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// A closed updates channel stands in for polling that ended unexpectedly.
+	closed := make(chan telego.Update)
+	close(closed)
+
+	api.PushUpdate(`{"update_id":30,"message":{"message_id":1,"date":0,"chat":{"id":42,"type":"private"},"from":{"id":7,"is_bot":false,"first_name":"Ann"},"text":"after restart"}}`)
+
+	go ch.pollLoop(ctx, closed)
+
+	msg := consumeInbound(t, mb)
+	if msg.Content != "after restart" {
+		t.Fatalf("unexpected inbound message: %+v", msg)
+	}
+	if got := ch.lastUpdateID.Load(); got != 30 {
+		t.Fatalf("lastUpdateID = %d, want 30", got)
+	}
+	if _, ok := store.Lookup(telegramStateNamespace, "last_update_id"); ok {
+		t.Fatal("offset saved with every update")
+	}
+	ch.saveLastUpdateID()
+
+	// The next run resumes after the saved offset
+	next, _ := newTestTelegramChannel(t, config.TelegramConfig{}, api)
+	next.SetStateStore(store)
+	next.loadLastUpdateID()
+	if got := next.pollingParams().Offset; got != 31 {
+		t.Fatalf("offset after restart = %d, want 31", got)
+	}
+}
+
+func TestTelegramProgress(t *testing.T) {
+	api := channeltest.NewTelegram(t)
+	ch, _ := newTestTelegramChannel(t, config.TelegramConfig{}, api)
+	ctx := context.Background()
+	if err := ch.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { ch.Stop(ctx) })
+
+	if err := ch.SendTyping(ctx, "4242"); err != nil || !api.Called("sendChatAction") {
+		t.Fatalf("SendTyping: %v", err)
+	}
+
+	// Statuses edit one placeholder, which becomes the reply.
+	for _, status := range []string{"Searching the web…", "Running a command…"} {
+		if err := ch.SendProgress(ctx, "4242", status); err != nil {
+			t.Fatalf("SendProgress: %v", err)
+		}
+	}
+	if err := ch.Send(ctx, bus.OutboundMessage{ChatID: "4242", Content: "done"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if err := ch.ClearProgress(ctx, "4242"); err != nil {
+		t.Fatalf("ClearProgress: %v", err)
+	}
+	if msgs := api.Messages("4242"); len(msgs) != 1 || msgs[0].Text != "done" {
+		t.Fatalf("messages = %+v", msgs)
+	}
+	if api.Called("deleteMessage") {
+		t.Error("placeholder deleted after the reply replaced it")
+	}
+
+	// A turn without a reply removes its placeholder.
+	ch.SendProgress(ctx, "4242", "Running a command…")
+	if err := ch.ClearProgress(ctx, "4242"); err != nil || !api.Called("deleteMessage") {
+		t.Fatalf("ClearProgress: %v", err)
+	}
+}
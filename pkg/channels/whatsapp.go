@@ -12,6 +12,7 @@ import (
 
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/network"
 	"github.com/sipeed/picoclaw/pkg/utils"
 )
 
@@ -38,8 +39,7 @@ func NewWhatsAppChannel(cfg config.WhatsAppConfig, bus *bus.MessageBus) (*WhatsA
 func (c *WhatsAppChannel) Start(ctx context.Context) error {
 	log.Printf("Starting WhatsApp channel connecting to %s...", c.url)
 
-	dialer := websocket.DefaultDialer
-	dialer.HandshakeTimeout = 10 * time.Second
+	dialer := network.WebSocketDialer("whatsapp", network.Options{})
 
 	conn, _, err := dialer.Dial(c.url, nil)
 	if err != nil {
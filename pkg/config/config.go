@@ -1,13 +1,17 @@
 package config
 
 import (
+	"crypto/ed25519"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
-
-	"github.com/caarlos0/env/v11"
+	"time"
 )
 
 // FlexibleStringSlice is a []string that also accepts JSON numbers,
@@ -44,14 +48,24 @@ func (f *FlexibleStringSlice) UnmarshalJSON(data []byte) error {
 }
 
 type Config struct {
-	Agents    AgentsConfig    `json:"agents"`
-	Channels  ChannelsConfig  `json:"channels"`
-	Providers ProvidersConfig `json:"providers"`
-	Gateway   GatewayConfig   `json:"gateway"`
-	Tools     ToolsConfig     `json:"tools"`
-	Heartbeat HeartbeatConfig `json:"heartbeat"`
-	Devices   DevicesConfig   `json:"devices"`
-	mu        sync.RWMutex
+	Agents       AgentsConfig       `json:"agents"`
+	Channels     ChannelsConfig     `json:"channels"`
+	Providers    ProvidersConfig    `json:"providers"`
+	Gateway      GatewayConfig      `json:"gateway"`
+	Tools        ToolsConfig        `json:"tools"`
+	Heartbeat    HeartbeatConfig    `json:"heartbeat"`
+	Devices      DevicesConfig      `json:"devices"`
+	Network      NetworkConfig      `json:"network"`
+	Federation   FederationConfig   `json:"federation"`
+	Bus          BusConfig          `json:"bus"`
+	Connectivity ConnectivityConfig `json:"connectivity"`
+	Feedback     FeedbackConfig     `json:"feedback"`
+	Tenants      TenantsConfig      `json:"tenants"`
+	Memory       MemoryConfig       `json:"memory"`
+	Update       UpdateConfig       `json:"update"`
+	Translate    TranslateConfig    `json:"translate"`
+	Progress     ProgressConfig     `json:"progress"`
+	mu           sync.RWMutex
 }
 
 type AgentsConfig struct {
@@ -66,19 +80,28 @@ type AgentDefaults struct {
 	MaxTokens           int     `json:"max_tokens" env:"PICOCLAW_AGENTS_DEFAULTS_MAX_TOKENS"`
 	Temperature         float64 `json:"temperature" env:"PICOCLAW_AGENTS_DEFAULTS_TEMPERATURE"`
 	MaxToolIterations   int     `json:"max_tool_iterations" env:"PICOCLAW_AGENTS_DEFAULTS_MAX_TOOL_ITERATIONS"`
+	// Timezone and Locale apply to users and chats that have not set their
+	// own. An empty timezone means the server's.
+	Timezone string `json:"timezone" env:"PICOCLAW_AGENTS_DEFAULTS_TIMEZONE"`
+	Locale   string `json:"locale" env:"PICOCLAW_AGENTS_DEFAULTS_LOCALE"`
+	// MaxPinnedChars caps the notes pinned to one conversation, which are
+	// sent with every turn.
+	MaxPinnedChars int `json:"max_pinned_chars" env:"PICOCLAW_AGENTS_DEFAULTS_MAX_PINNED_CHARS"`
 }
 
 type ChannelsConfig struct {
-	WhatsApp WhatsAppConfig `json:"whatsapp"`
-	Telegram TelegramConfig `json:"telegram"`
-	Feishu   FeishuConfig   `json:"feishu"`
-	Discord  DiscordConfig  `json:"discord"`
-	MaixCam  MaixCamConfig  `json:"maixcam"`
-	QQ       QQConfig       `json:"qq"`
-	DingTalk DingTalkConfig `json:"dingtalk"`
-	Slack    SlackConfig    `json:"slack"`
-	LINE     LINEConfig     `json:"line"`
-	OneBot   OneBotConfig   `json:"onebot"`
+	WhatsApp   WhatsAppConfig   `json:"whatsapp"`
+	Telegram   TelegramConfig   `json:"telegram"`
+	Feishu     FeishuConfig     `json:"feishu"`
+	Discord    DiscordConfig    `json:"discord"`
+	MaixCam    MaixCamConfig    `json:"maixcam"`
+	QQ         QQConfig         `json:"qq"`
+	DingTalk   DingTalkConfig   `json:"dingtalk"`
+	Slack      SlackConfig      `json:"slack"`
+	LINE       LINEConfig       `json:"line"`
+	OneBot     OneBotConfig     `json:"onebot"`
+	Mattermost MattermostConfig `json:"mattermost"`
+	Teams      TeamsConfig      `json:"teams"`
 }
 
 type WhatsAppConfig struct {
@@ -88,10 +111,13 @@ type WhatsAppConfig struct {
 }
 
 type TelegramConfig struct {
-	Enabled   bool                `json:"enabled" env:"PICOCLAW_CHANNELS_TELEGRAM_ENABLED"`
-	Token     string              `json:"token" env:"PICOCLAW_CHANNELS_TELEGRAM_TOKEN"`
-	Proxy     string              `json:"proxy" env:"PICOCLAW_CHANNELS_TELEGRAM_PROXY"`
-	AllowFrom FlexibleStringSlice `json:"allow_from" env:"PICOCLAW_CHANNELS_TELEGRAM_ALLOW_FROM"`
+	Enabled       bool                `json:"enabled" env:"PICOCLAW_CHANNELS_TELEGRAM_ENABLED"`
+	Token         string              `json:"token" env:"PICOCLAW_CHANNELS_TELEGRAM_TOKEN"`
+	Proxy         string              `json:"proxy" env:"PICOCLAW_CHANNELS_TELEGRAM_PROXY"`
+	WebhookURL    string              `json:"webhook_url" env:"PICOCLAW_CHANNELS_TELEGRAM_WEBHOOK_URL"` // public HTTPS URL; empty = long polling
+	WebhookPath   string              `json:"webhook_path" env:"PICOCLAW_CHANNELS_TELEGRAM_WEBHOOK_PATH"`
+	WebhookSecret string              `json:"webhook_secret" env:"PICOCLAW_CHANNELS_TELEGRAM_WEBHOOK_SECRET"`
+	AllowFrom     FlexibleStringSlice `json:"allow_from" env:"PICOCLAW_CHANNELS_TELEGRAM_ALLOW_FROM"`
 }
 
 type FeishuConfig struct {
@@ -149,13 +175,50 @@ type LINEConfig struct {
 
 type OneBotConfig struct {
 	Enabled            bool                `json:"enabled" env:"PICOCLAW_CHANNELS_ONEBOT_ENABLED"`
+	Mode               string              `json:"mode" env:"PICOCLAW_CHANNELS_ONEBOT_MODE"` // "forward" (default), "reverse" or "http"
 	WSUrl              string              `json:"ws_url" env:"PICOCLAW_CHANNELS_ONEBOT_WS_URL"`
 	AccessToken        string              `json:"access_token" env:"PICOCLAW_CHANNELS_ONEBOT_ACCESS_TOKEN"`
 	ReconnectInterval  int                 `json:"reconnect_interval" env:"PICOCLAW_CHANNELS_ONEBOT_RECONNECT_INTERVAL"`
+	ListenHost         string              `json:"listen_host" env:"PICOCLAW_CHANNELS_ONEBOT_LISTEN_HOST"`
+	ListenPort         int                 `json:"listen_port" env:"PICOCLAW_CHANNELS_ONEBOT_LISTEN_PORT"`
+	ListenPath         string              `json:"listen_path" env:"PICOCLAW_CHANNELS_ONEBOT_LISTEN_PATH"`
+	HTTPAPIUrl         string              `json:"http_api_url" env:"PICOCLAW_CHANNELS_ONEBOT_HTTP_API_URL"`
+	Secret             string              `json:"secret" env:"PICOCLAW_CHANNELS_ONEBOT_SECRET"`
 	GroupTriggerPrefix []string            `json:"group_trigger_prefix" env:"PICOCLAW_CHANNELS_ONEBOT_GROUP_TRIGGER_PREFIX"`
+	EventRules         []OneBotEventRule   `json:"event_rules"`
 	AllowFrom          FlexibleStringSlice `json:"allow_from" env:"PICOCLAW_CHANNELS_ONEBOT_ALLOW_FROM"`
 }
 
+type MattermostConfig struct {
+	Enabled           bool                `json:"enabled" env:"PICOCLAW_CHANNELS_MATTERMOST_ENABLED"`
+	ServerURL         string              `json:"server_url" env:"PICOCLAW_CHANNELS_MATTERMOST_SERVER_URL"`
+	Token             string              `json:"token" env:"PICOCLAW_CHANNELS_MATTERMOST_TOKEN"`
+	SlashCommandToken string              `json:"slash_command_token" env:"PICOCLAW_CHANNELS_MATTERMOST_SLASH_COMMAND_TOKEN"`
+	SlashCommandPath  string              `json:"slash_command_path" env:"PICOCLAW_CHANNELS_MATTERMOST_SLASH_COMMAND_PATH"`
+	AllowFrom         FlexibleStringSlice `json:"allow_from" env:"PICOCLAW_CHANNELS_MATTERMOST_ALLOW_FROM"`
+}
+
+type TeamsConfig struct {
+	Enabled           bool                `json:"enabled" env:"PICOCLAW_CHANNELS_TEAMS_ENABLED"`
+	AppID             string              `json:"app_id" env:"PICOCLAW_CHANNELS_TEAMS_APP_ID"`
+	AppPassword       string              `json:"app_password" env:"PICOCLAW_CHANNELS_TEAMS_APP_PASSWORD"`
+	TenantID          string              `json:"tenant_id" env:"PICOCLAW_CHANNELS_TEAMS_TENANT_ID"` // single-tenant bots only; empty = multi-tenant
+	WebhookPath       string              `json:"webhook_path" env:"PICOCLAW_CHANNELS_TEAMS_WEBHOOK_PATH"`
+	OpenIDMetadataURL string              `json:"openid_metadata_url" env:"PICOCLAW_CHANNELS_TEAMS_OPENID_METADATA_URL"`
+	AdaptiveCards     bool                `json:"adaptive_cards" env:"PICOCLAW_CHANNELS_TEAMS_ADAPTIVE_CARDS"`
+	AllowFrom         FlexibleStringSlice `json:"allow_from" env:"PICOCLAW_CHANNELS_TEAMS_ALLOW_FROM"`
+}
+
+// OneBotEventRule decides what happens to a notice or request event.
+// Event is matched against "<post_type>.<notice_type|request_type>[.<sub_type>]",
+// e.g. "notice.group_increase", "notice.notify.poke" or "request.friend".
+// A rule without sub_type matches every sub_type of that event.
+type OneBotEventRule struct {
+	Event  string `json:"event"`
+	Action string `json:"action"`          // "agent", "reply", "approve", "reject" or "ignore"
+	Reply  string `json:"reply,omitempty"` // template for "reply", supports {user_id}, {group_id}, {comment}
+}
+
 type HeartbeatConfig struct {
 	Enabled  bool `json:"enabled" env:"PICOCLAW_HEARTBEAT_ENABLED"`
 	Interval int  `json:"interval" env:"PICOCLAW_HEARTBEAT_INTERVAL"` // minutes, min 5
@@ -166,6 +229,221 @@ type DevicesConfig struct {
 	MonitorUSB bool `json:"monitor_usb" env:"PICOCLAW_DEVICES_MONITOR_USB"`
 }
 
+// NetworkConfig applies to every outgoing HTTP connection. Timeouts are in
+// seconds; zero keeps each component's own default.
+type NetworkConfig struct {
+	// Proxy is an http://, https:// or socks5:// URL. Empty uses the
+	// HTTP_PROXY/HTTPS_PROXY/NO_PROXY environment variables; "direct"
+	// disables proxying.
+	Proxy          string              `json:"proxy" env:"PICOCLAW_NETWORK_PROXY"`
+	NoProxy        FlexibleStringSlice `json:"no_proxy" env:"PICOCLAW_NETWORK_NO_PROXY"`
+	CAFile         string              `json:"ca_file" env:"PICOCLAW_NETWORK_CA_FILE"` // PEM bundle trusted in addition to the system roots
+	ConnectTimeout int                 `json:"connect_timeout" env:"PICOCLAW_NETWORK_CONNECT_TIMEOUT"`
+	Timeout        int                 `json:"timeout" env:"PICOCLAW_NETWORK_TIMEOUT"`
+	IPFamily       string              `json:"ip_family" env:"PICOCLAW_NETWORK_IP_FAMILY"` // "", "ipv4" or "ipv6"
+	UserAgent      string              `json:"user_agent" env:"PICOCLAW_NETWORK_USER_AGENT"`
+	// Components overrides settings per component: providers, web, media,
+	// skills, voice, federation, update, or a channel name such as telegram.
+	Components map[string]NetworkOverride `json:"components,omitempty"`
+}
+
+// NetworkOverride replaces the non-empty fields of NetworkConfig for one component.
+type NetworkOverride struct {
+	Proxy          string              `json:"proxy,omitempty"`
+	NoProxy        FlexibleStringSlice `json:"no_proxy,omitempty"`
+	CAFile         string              `json:"ca_file,omitempty"`
+	ConnectTimeout int                 `json:"connect_timeout,omitempty"`
+	Timeout        int                 `json:"timeout,omitempty"`
+	IPFamily       string              `json:"ip_family,omitempty"`
+	UserAgent      string              `json:"user_agent,omitempty"`
+}
+
+// BusConfig connects the two halves of a split deployment, started with
+// `picoclaw gateway --role channels` and `--role agent`. One side sets URL
+// and connects; the other serves Path on its gateway. Both use Token.
+type BusConfig struct {
+	URL   string `json:"url" env:"PICOCLAW_BUS_URL"` // ws:// or wss:// address of the other side's bus path
+	Path  string `json:"path" env:"PICOCLAW_BUS_PATH"`
+	Token string `json:"token" env:"PICOCLAW_BUS_TOKEN"`
+	// ExportTools are the tools the channels side runs for the agent,
+	// typically the hardware tools of the board it runs on.
+	ExportTools FlexibleStringSlice `json:"export_tools" env:"PICOCLAW_BUS_EXPORT_TOOLS"`
+}
+
+// ConnectivityConfig makes the agent notice when the provider cannot be
+// reached. While offline, messages are acknowledged and queued, and answered
+// once the provider is back.
+type ConnectivityConfig struct {
+	Enabled  bool `json:"enabled" env:"PICOCLAW_CONNECTIVITY_ENABLED"`
+	Interval int  `json:"interval" env:"PICOCLAW_CONNECTIVITY_INTERVAL"` // seconds between probes while online
+	Timeout  int  `json:"timeout" env:"PICOCLAW_CONNECTIVITY_TIMEOUT"`   // seconds per probe
+	// ProbeURLs are checked in addition to the provider's own endpoint.
+	ProbeURLs FlexibleStringSlice `json:"probe_urls" env:"PICOCLAW_CONNECTIVITY_PROBE_URLS"`
+	MaxQueue  int                 `json:"max_queue" env:"PICOCLAW_CONNECTIVITY_MAX_QUEUE"`
+	// LocalTools can be run directly with "/tool <name> [json args]",
+	// without the provider, e.g. hardware tools while offline.
+	LocalTools FlexibleStringSlice `json:"local_tools" env:"PICOCLAW_CONNECTIVITY_LOCAL_TOOLS"`
+}
+
+// FeedbackConfig controls recording of turns and user ratings in
+// workspace/feedback.
+type FeedbackConfig struct {
+	Enabled bool `json:"enabled" env:"PICOCLAW_FEEDBACK_ENABLED"`
+	// Buttons adds 👍/👎 buttons under answers, on channels that support them.
+	Buttons  bool `json:"buttons" env:"PICOCLAW_FEEDBACK_BUTTONS"`
+	MaxTurns int  `json:"max_turns" env:"PICOCLAW_FEEDBACK_MAX_TURNS"` // turns kept for rating
+}
+
+// TenantsConfig gives each user of a public bot, or each chat, its own
+// workspace under workspace/tenants, provisioned from a template and held
+// to quotas.
+type TenantsConfig struct {
+	Enabled bool `json:"enabled" env:"PICOCLAW_TENANTS_ENABLED"`
+	// Scope is "sender" for a workspace per user or "chat" for one per chat.
+	Scope string `json:"scope" env:"PICOCLAW_TENANTS_SCOPE"`
+	// Template is copied into new workspaces. When empty, they get the
+	// bootstrap files (AGENTS.md, IDENTITY.md, SOUL.md, TOOLS.md) of the
+	// main workspace.
+	Template        string `json:"template" env:"PICOCLAW_TENANTS_TEMPLATE"`
+	MaxDiskMB       int    `json:"max_disk_mb" env:"PICOCLAW_TENANTS_MAX_DISK_MB"`
+	MaxCronJobs     int    `json:"max_cron_jobs" env:"PICOCLAW_TENANTS_MAX_CRON_JOBS"`
+	MaxTokensPerDay int    `json:"max_tokens_per_day" env:"PICOCLAW_TENANTS_MAX_TOKENS_PER_DAY"` // 0 for no limit
+	// AllowExec lets tenants run shell commands in their workspace. The
+	// command guard is not a sandbox; leave it off for untrusted users.
+	AllowExec bool `json:"allow_exec" env:"PICOCLAW_TENANTS_ALLOW_EXEC"`
+	// Admins ("channel:sender_id") keep the main workspace and can manage
+	// tenants with /tenants.
+	Admins FlexibleStringSlice `json:"admins" env:"PICOCLAW_TENANTS_ADMINS"`
+}
+
+// TemplatePath returns the expanded template directory, or "" for the
+// default template.
+func (t TenantsConfig) TemplatePath() string {
+	return expandHome(t.Template)
+}
+
+// MemoryConfig bounds how much memory the process uses. The low_memory
+// profile sets conservative values for small boards.
+type MemoryConfig struct {
+	// LimitMB is the soft limit handed to the Go runtime (GOMEMLIMIT); 0
+	// leaves it unset. A GOMEMLIMIT environment variable takes precedence.
+	LimitMB int `json:"limit_mb" env:"PICOCLAW_MEMORY_LIMIT_MB"`
+	// MaxReadKB caps what read_file, web_fetch and exec hold in memory.
+	MaxReadKB int `json:"max_read_kb" env:"PICOCLAW_MEMORY_MAX_READ_KB"`
+	// Sessions idle this long are saved and dropped from memory, and
+	// loaded again when used; 0 keeps them.
+	SessionIdleMinutes int `json:"session_idle_minutes" env:"PICOCLAW_MEMORY_SESSION_IDLE_MINUTES"`
+	MaxSessions        int `json:"max_sessions" env:"PICOCLAW_MEMORY_MAX_SESSIONS"` // in memory; 0 for no limit
+	// MaxBackground is how many subagents and summaries run at once. It
+	// drops to one under memory pressure.
+	MaxBackground int `json:"max_background" env:"PICOCLAW_MEMORY_MAX_BACKGROUND"`
+	// PressurePercent of limit_mb is where the governor starts evicting.
+	PressurePercent int `json:"pressure_percent" env:"PICOCLAW_MEMORY_PRESSURE_PERCENT"`
+	CheckInterval   int `json:"check_interval" env:"PICOCLAW_MEMORY_CHECK_INTERVAL"` // seconds
+}
+
+// UpdateConfig is where `picoclaw update` finds new releases and the key
+// they must be signed with.
+type UpdateConfig struct {
+	// ManifestURL is an http(s) URL or a local path of the release
+	// manifest. Asset URLs in it may be relative to it.
+	ManifestURL string `json:"manifest_url" env:"PICOCLAW_UPDATE_MANIFEST_URL"`
+	Channel     string `json:"channel" env:"PICOCLAW_UPDATE_CHANNEL"` // stable or beta
+	// PublicKey is the base64 ed25519 key releases are signed with.
+	// Nothing is installed without it.
+	PublicKey string `json:"public_key" env:"PICOCLAW_UPDATE_PUBLIC_KEY"`
+	// CheckInterval is how often, in hours, the gateway looks for a new
+	// release and tells the owner; 0 disables.
+	CheckInterval int `json:"check_interval" env:"PICOCLAW_UPDATE_CHECK_INTERVAL"`
+	// Notify is the "channel:chat_id" told about new releases; empty uses
+	// the last active chat.
+	Notify string `json:"notify" env:"PICOCLAW_UPDATE_NOTIFY"`
+}
+
+// TranslateConfig turns group chats into translation relays: the agent
+// stays quiet there and each message is posted again in the group's other
+// languages.
+type TranslateConfig struct {
+	Enabled bool `json:"enabled" env:"PICOCLAW_TRANSLATE_ENABLED"`
+	// Model is served by the agent's provider; a small, cheap one is
+	// enough. Empty uses agents.defaults.model.
+	Model string `json:"model" env:"PICOCLAW_TRANSLATE_MODEL"`
+	// BatchWindow is how long, in seconds, messages are collected before
+	// they are translated in one request; 0 translates each at once.
+	BatchWindow int              `json:"batch_window" env:"PICOCLAW_TRANSLATE_BATCH_WINDOW"`
+	MaxBatch    int              `json:"max_batch" env:"PICOCLAW_TRANSLATE_MAX_BATCH"` // messages per request
+	Groups      []TranslateGroup `json:"groups"`
+}
+
+// TranslateGroup is a chat relayed between Languages, given as codes such
+// as "zh", "ja" and "en".
+type TranslateGroup struct {
+	Channel   string              `json:"channel"`
+	ChatID    string              `json:"chat_id"`
+	Languages FlexibleStringSlice `json:"languages"`
+}
+
+// ProgressConfig controls what users see while the agent works on their
+// message, on channels that support it.
+type ProgressConfig struct {
+	// Typing keeps the channel's typing indicator alive during a turn.
+	Typing bool `json:"typing" env:"PICOCLAW_PROGRESS_TYPING"`
+	// Status shows what the agent is doing ("Searching the web…") in one
+	// placeholder message, edited as the turn goes on.
+	Status bool `json:"status" env:"PICOCLAW_PROGRESS_STATUS"`
+	// Interval is the minimum time, in seconds, between two status edits.
+	Interval int `json:"interval" env:"PICOCLAW_PROGRESS_INTERVAL"`
+	// Channels overrides Typing and Status per channel name.
+	Channels map[string]ProgressOverride `json:"channels,omitempty"`
+}
+
+// ProgressOverride replaces the set fields of ProgressConfig for one channel.
+type ProgressOverride struct {
+	Typing *bool `json:"typing,omitempty"`
+	Status *bool `json:"status,omitempty"`
+}
+
+// For returns whether typing and status updates are on for channel.
+func (p ProgressConfig) For(channel string) (typing, status bool) {
+	typing, status = p.Typing, p.Status
+	if o, ok := p.Channels[channel]; ok {
+		if o.Typing != nil {
+			typing = *o.Typing
+		}
+		if o.Status != nil {
+			status = *o.Status
+		}
+	}
+	return typing, status
+}
+
+// FederationConfig lets this instance take tasks from other PicoClaw
+// instances (peers) and hand tasks to them with the ask_peer tool.
+type FederationConfig struct {
+	Enabled bool `json:"enabled" env:"PICOCLAW_FEDERATION_ENABLED"`
+	// Name, Description and Capabilities are advertised to peers in the agent card.
+	Name         string              `json:"name" env:"PICOCLAW_FEDERATION_NAME"`
+	Description  string              `json:"description" env:"PICOCLAW_FEDERATION_DESCRIPTION"`
+	Capabilities FlexibleStringSlice `json:"capabilities" env:"PICOCLAW_FEDERATION_CAPABILITIES"`
+	// Path is where the endpoint is served on the gateway.
+	Path string `json:"path" env:"PICOCLAW_FEDERATION_PATH"`
+	// PublicURL is the endpoint's address as peers reach it, e.g.
+	// http://10.0.0.5:18790/a2a. With it, peers push results of background
+	// tasks back instead of being polled.
+	PublicURL   string       `json:"public_url" env:"PICOCLAW_FEDERATION_PUBLIC_URL"`
+	TaskTimeout int          `json:"task_timeout" env:"PICOCLAW_FEDERATION_TASK_TIMEOUT"` // seconds
+	Peers       []PeerConfig `json:"peers"`
+}
+
+// PeerConfig is another instance. Both sides configure the same token for
+// each other; it authenticates requests in either direction.
+type PeerConfig struct {
+	Name        string `json:"name"`
+	URL         string `json:"url"`
+	Token       string `json:"token"`
+	Description string `json:"description,omitempty"` // shown until the peer's card is fetched
+}
+
 type ProvidersConfig struct {
 	Anthropic     ProviderConfig `json:"anthropic"`
 	OpenAI        ProviderConfig `json:"openai"`
@@ -191,8 +469,20 @@ type ProviderConfig struct {
 }
 
 type GatewayConfig struct {
-	Host string `json:"host" env:"PICOCLAW_GATEWAY_HOST"`
-	Port int    `json:"port" env:"PICOCLAW_GATEWAY_PORT"`
+	Host  string      `json:"host" env:"PICOCLAW_GATEWAY_HOST"`
+	Port  int         `json:"port" env:"PICOCLAW_GATEWAY_PORT"`
+	Admin AdminConfig `json:"admin"`
+	// ControlSocket is the Unix socket `picoclaw top` connects to. Empty disables it.
+	ControlSocket string `json:"control_socket" env:"PICOCLAW_GATEWAY_CONTROL_SOCKET"`
+}
+
+// AdminConfig controls the web admin dashboard served by the gateway.
+// It uses its own credentials, independent of any channel secrets.
+type AdminConfig struct {
+	Enabled  bool   `json:"enabled" env:"PICOCLAW_GATEWAY_ADMIN_ENABLED"`
+	Path     string `json:"path" env:"PICOCLAW_GATEWAY_ADMIN_PATH"`
+	Username string `json:"username" env:"PICOCLAW_GATEWAY_ADMIN_USERNAME"`
+	Password string `json:"password" env:"PICOCLAW_GATEWAY_ADMIN_PASSWORD"`
 }
 
 type BraveConfig struct {
@@ -211,8 +501,16 @@ type WebToolsConfig struct {
 	DuckDuckGo DuckDuckGoConfig `json:"duckduckgo"`
 }
 
+// MediaToolsConfig sets the retention of the media store in the workspace.
+// Zero disables a limit.
+type MediaToolsConfig struct {
+	MaxSizeMB  int `json:"max_size_mb" env:"PICOCLAW_TOOLS_MEDIA_MAX_SIZE_MB"`
+	MaxAgeDays int `json:"max_age_days" env:"PICOCLAW_TOOLS_MEDIA_MAX_AGE_DAYS"`
+}
+
 type ToolsConfig struct {
-	Web WebToolsConfig `json:"web"`
+	Web   WebToolsConfig   `json:"web"`
+	Media MediaToolsConfig `json:"media"`
 }
 
 func DefaultConfig() *Config {
@@ -226,6 +524,7 @@ func DefaultConfig() *Config {
 				MaxTokens:           8192,
 				Temperature:         0.7,
 				MaxToolIterations:   20,
+				MaxPinnedChars:      2000,
 			},
 		},
 		Channels: ChannelsConfig{
@@ -235,9 +534,12 @@ func DefaultConfig() *Config {
 				AllowFrom: FlexibleStringSlice{},
 			},
 			Telegram: TelegramConfig{
-				Enabled:   false,
-				Token:     "",
-				AllowFrom: FlexibleStringSlice{},
+				Enabled:       false,
+				Token:         "",
+				WebhookURL:    "",
+				WebhookPath:   "/webhook/telegram",
+				WebhookSecret: "",
+				AllowFrom:     FlexibleStringSlice{},
 			},
 			Feishu: FeishuConfig{
 				Enabled:           false,
@@ -287,12 +589,37 @@ func DefaultConfig() *Config {
 			},
 			OneBot: OneBotConfig{
 				Enabled:            false,
+				Mode:               "forward",
 				WSUrl:              "ws://127.0.0.1:3001",
 				AccessToken:        "",
 				ReconnectInterval:  5,
+				ListenHost:         "0.0.0.0",
+				ListenPort:         18792,
+				ListenPath:         "/onebot",
+				HTTPAPIUrl:         "http://127.0.0.1:3000",
+				Secret:             "",
 				GroupTriggerPrefix: []string{},
+				EventRules:         []OneBotEventRule{},
 				AllowFrom:          FlexibleStringSlice{},
 			},
+			Mattermost: MattermostConfig{
+				Enabled:           false,
+				ServerURL:         "",
+				Token:             "",
+				SlashCommandToken: "",
+				SlashCommandPath:  "/webhook/mattermost",
+				AllowFrom:         FlexibleStringSlice{},
+			},
+			Teams: TeamsConfig{
+				Enabled:           false,
+				AppID:             "",
+				AppPassword:       "",
+				TenantID:          "",
+				WebhookPath:       "/webhook/teams",
+				OpenIDMetadataURL: "https://login.botframework.com/v1/.well-known/openidconfiguration",
+				AdaptiveCards:     true,
+				AllowFrom:         FlexibleStringSlice{},
+			},
 		},
 		Providers: ProvidersConfig{
 			Anthropic:    ProviderConfig{},
@@ -309,6 +636,13 @@ func DefaultConfig() *Config {
 		Gateway: GatewayConfig{
 			Host: "0.0.0.0",
 			Port: 18790,
+			Admin: AdminConfig{
+				Enabled:  false,
+				Path:     "/admin",
+				Username: "admin",
+				Password: "",
+			},
+			ControlSocket: "~/.picoclaw/gateway.sock",
 		},
 		Tools: ToolsConfig{
 			Web: WebToolsConfig{
@@ -322,6 +656,10 @@ func DefaultConfig() *Config {
 					MaxResults: 5,
 				},
 			},
+			Media: MediaToolsConfig{
+				MaxSizeMB:  200,
+				MaxAgeDays: 30,
+			},
 		},
 		Heartbeat: HeartbeatConfig{
 			Enabled:  true,
@@ -331,29 +669,75 @@ func DefaultConfig() *Config {
 			Enabled:    false,
 			MonitorUSB: true,
 		},
+		Bus: BusConfig{
+			Path:        "/bus",
+			ExportTools: FlexibleStringSlice{"i2c", "spi"},
+		},
+		Connectivity: ConnectivityConfig{
+			Enabled:    false,
+			Interval:   60,
+			Timeout:    10,
+			ProbeURLs:  FlexibleStringSlice{},
+			MaxQueue:   200,
+			LocalTools: FlexibleStringSlice{"i2c", "spi"},
+		},
+		Feedback: FeedbackConfig{
+			Enabled:  true,
+			Buttons:  false,
+			MaxTurns: 1000,
+		},
+		Tenants: TenantsConfig{
+			Enabled:         false,
+			Scope:           "sender",
+			MaxDiskMB:       50,
+			MaxCronJobs:     10,
+			MaxTokensPerDay: 200000,
+			AllowExec:       false,
+			Admins:          FlexibleStringSlice{},
+		},
+		Memory: MemoryConfig{
+			LimitMB:            0,
+			MaxReadKB:          2048,
+			SessionIdleMinutes: 0,
+			MaxSessions:        0,
+			MaxBackground:      4,
+			PressurePercent:    85,
+			CheckInterval:      30,
+		},
+		Update: UpdateConfig{
+			ManifestURL:   "",
+			Channel:       "stable",
+			PublicKey:     "",
+			CheckInterval: 0,
+			Notify:        "",
+		},
+		Translate: TranslateConfig{
+			Enabled:     false,
+			Model:       "",
+			BatchWindow: 5,
+			MaxBatch:    10,
+			Groups:      []TranslateGroup{},
+		},
+		Progress: ProgressConfig{
+			Typing:   true,
+			Status:   true,
+			Interval: 3,
+		},
+		Federation: FederationConfig{
+			Enabled:      false,
+			Name:         "picoclaw",
+			Capabilities: FlexibleStringSlice{},
+			Path:         "/a2a",
+			TaskTimeout:  600,
+			Peers:        []PeerConfig{},
+		},
 	}
 }
 
+// LoadConfig loads the config at path with its config.d overlays and
+// environment overrides. See ResolveConfig.
 func LoadConfig(path string) (*Config, error) {
-	cfg := DefaultConfig()
-
-	data, err := os.ReadFile(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return cfg, nil
-		}
-		return nil, err
-	}
-
-	if err := json.Unmarshal(data, cfg); err != nil {
-		return nil, err
-	}
-
-	if err := env.Parse(cfg); err != nil {
-		return nil, err
-	}
-
-	return cfg, nil
+	return LoadConfigProfile(path, "")
 }
 
 func SaveConfig(path string, cfg *Config) error {
@@ -373,12 +757,189 @@ func SaveConfig(path string, cfg *Config) error {
 	return os.WriteFile(path, data, 0644)
 }
 
+// Validate checks values that would otherwise only fail at runtime.
+// All problems are reported together.
+func (c *Config) Validate() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	d := c.Agents.Defaults
+	check(strings.TrimSpace(d.Workspace) != "", "agents.defaults.workspace is required")
+	check(strings.TrimSpace(d.Model) != "", "agents.defaults.model is required")
+	check(d.MaxTokens > 0, "agents.defaults.max_tokens must be positive")
+	check(d.Temperature >= 0 && d.Temperature <= 2, "agents.defaults.temperature must be between 0 and 2")
+	check(d.MaxToolIterations > 0, "agents.defaults.max_tool_iterations must be positive")
+	check(d.MaxPinnedChars > 0, "agents.defaults.max_pinned_chars must be positive")
+	if d.Timezone != "" {
+		_, err := time.LoadLocation(d.Timezone)
+		check(err == nil, "agents.defaults.timezone %q is not a known time zone", d.Timezone)
+	}
+
+	check(c.Gateway.Port > 0 && c.Gateway.Port <= 65535, "gateway.port must be between 1 and 65535")
+	if a := c.Gateway.Admin; a.Enabled {
+		check(strings.HasPrefix(a.Path, "/") && a.Path != "/", "gateway.admin.path must start with / and not be the root")
+		check(a.Username != "" && a.Password != "", "gateway.admin.username and password are required when the dashboard is enabled")
+	}
+
+	check(c.Heartbeat.Interval >= 0, "heartbeat.interval must not be negative")
+	check(c.Tools.Media.MaxSizeMB >= 0, "tools.media.max_size_mb must not be negative")
+	check(c.Tools.Media.MaxAgeDays >= 0, "tools.media.max_age_days must not be negative")
+
+	checkNetwork := func(prefix, proxy, ipFamily string, connectTimeout, timeout int) {
+		switch ipFamily {
+		case "", "any", "ipv4", "ipv6":
+		default:
+			errs = append(errs, fmt.Errorf("%s.ip_family %q must be ipv4, ipv6 or empty", prefix, ipFamily))
+		}
+		if proxy != "" && proxy != "direct" {
+			u, err := url.Parse(proxy)
+			ok := err == nil && u.Host != ""
+			if ok {
+				switch u.Scheme {
+				case "http", "https", "socks5", "socks5h":
+				default:
+					ok = false
+				}
+			}
+			check(ok, "%s.proxy %q must be an http, https or socks5 URL, or \"direct\"", prefix, proxy)
+		}
+		check(connectTimeout >= 0 && timeout >= 0, "%s timeouts must not be negative", prefix)
+	}
+	n := c.Network
+	checkNetwork("network", n.Proxy, n.IPFamily, n.ConnectTimeout, n.Timeout)
+	for name, o := range n.Components {
+		checkNetwork("network.components."+name, o.Proxy, o.IPFamily, o.ConnectTimeout, o.Timeout)
+	}
+
+	if b := c.Bus; b.URL != "" {
+		u, err := url.Parse(b.URL)
+		check(err == nil && (u.Scheme == "ws" || u.Scheme == "wss") && u.Host != "", "bus.url must be a ws or wss URL")
+	}
+	check(strings.HasPrefix(c.Bus.Path, "/") && c.Bus.Path != "/", "bus.path must start with / and not be the root")
+	if c.Bus.Token != "" {
+		check(len(c.Bus.Token) >= 16, "bus.token must be at least 16 characters")
+	}
+
+	if cn := c.Connectivity; cn.Enabled {
+		check(cn.Interval > 0, "connectivity.interval must be positive")
+		check(cn.Timeout > 0, "connectivity.timeout must be positive")
+		check(cn.MaxQueue > 0, "connectivity.max_queue must be positive")
+		for i, raw := range cn.ProbeURLs {
+			u, err := url.Parse(raw)
+			check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "connectivity.probe_urls[%d] must be an http or https URL", i)
+		}
+	}
+
+	if fb := c.Feedback; fb.Enabled {
+		check(fb.MaxTurns > 0, "feedback.max_turns must be positive")
+	}
+
+	if t := c.Tenants; t.Enabled {
+		check(t.Scope == "sender" || t.Scope == "chat", "tenants.scope must be sender or chat")
+		check(t.MaxDiskMB > 0, "tenants.max_disk_mb must be positive")
+		check(t.MaxCronJobs >= 0, "tenants.max_cron_jobs must not be negative")
+		check(t.MaxTokensPerDay >= 0, "tenants.max_tokens_per_day must not be negative")
+		for i, a := range t.Admins {
+			channel, id, ok := strings.Cut(a, ":")
+			check(ok && channel != "" && id != "", "tenants.admins[%d] must be channel:sender_id", i)
+		}
+	}
+
+	m := c.Memory
+	check(m.LimitMB >= 0, "memory.limit_mb must not be negative")
+	check(m.MaxReadKB > 0, "memory.max_read_kb must be positive")
+	check(m.SessionIdleMinutes >= 0, "memory.session_idle_minutes must not be negative")
+	check(m.MaxSessions >= 0, "memory.max_sessions must not be negative")
+	check(m.MaxBackground > 0, "memory.max_background must be positive")
+	check(m.PressurePercent > 0 && m.PressurePercent <= 100, "memory.pressure_percent must be between 1 and 100")
+	check(m.CheckInterval > 0, "memory.check_interval must be positive")
+
+	u := c.Update
+	check(u.Channel == "stable" || u.Channel == "beta", "update.channel must be stable or beta")
+	check(u.CheckInterval >= 0, "update.check_interval must not be negative")
+	if u.PublicKey != "" {
+		key, err := base64.StdEncoding.DecodeString(u.PublicKey)
+		check(err == nil && len(key) == ed25519.PublicKeySize, "update.public_key must be a base64 ed25519 public key")
+	}
+	if u.Notify != "" {
+		channel, id, ok := strings.Cut(u.Notify, ":")
+		check(ok && channel != "" && id != "", "update.notify must be channel:chat_id")
+	}
+
+	if tr := c.Translate; tr.Enabled {
+		check(tr.BatchWindow >= 0, "translate.batch_window must not be negative")
+		check(tr.MaxBatch > 0, "translate.max_batch must be positive")
+		groups := map[string]bool{}
+		for i, g := range tr.Groups {
+			check(g.Channel != "" && g.ChatID != "", "translate.groups[%d] needs a channel and chat_id", i)
+			check(len(g.Languages) >= 2, "translate.groups[%d] needs at least two languages", i)
+			check(!groups[g.Channel+":"+g.ChatID], "translate.groups[%d] repeats %s:%s", i, g.Channel, g.ChatID)
+			groups[g.Channel+":"+g.ChatID] = true
+		}
+	}
+
+	check(c.Progress.Interval > 0, "progress.interval must be positive")
+
+	if f := c.Federation; f.Enabled {
+		check(strings.TrimSpace(f.Name) != "", "federation.name is required")
+		check(strings.HasPrefix(f.Path, "/") && f.Path != "/", "federation.path must start with / and not be the root")
+		check(f.TaskTimeout >= 0, "federation.task_timeout must not be negative")
+		if f.PublicURL != "" {
+			u, err := url.Parse(f.PublicURL)
+			check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "federation.public_url must be an http or https URL")
+		}
+		names := map[string]bool{}
+		tokens := map[string]bool{}
+		for i, p := range f.Peers {
+			check(p.Name != "", "federation.peers[%d].name is required", i)
+			check(!names[strings.ToLower(p.Name)], "federation.peers[%d].name %q is used twice", i, p.Name)
+			names[strings.ToLower(p.Name)] = true
+			u, err := url.Parse(p.URL)
+			check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "federation.peers[%d].url must be an http or https URL", i)
+			check(len(p.Token) >= 16, "federation.peers[%d].token must be at least 16 characters", i)
+			// The token identifies the peer on incoming requests
+			check(!tokens[p.Token], "federation.peers[%d].token is shared with another peer", i)
+			tokens[p.Token] = true
+		}
+	}
+
+	if tg := c.Channels.Telegram; tg.WebhookURL != "" {
+		check(strings.HasPrefix(tg.WebhookURL, "https://"), "channels.telegram.webhook_url must be an https URL")
+	}
+	if ob := c.Channels.OneBot; ob.Enabled {
+		switch strings.ToLower(strings.TrimSpace(ob.Mode)) {
+		case "", "forward", "reverse", "http":
+		default:
+			errs = append(errs, fmt.Errorf("channels.onebot.mode %q must be forward, reverse or http", ob.Mode))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
 func (c *Config) WorkspacePath() string {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	return expandHome(c.Agents.Defaults.Workspace)
 }
 
+// ControlSocketPath returns the expanded path of the gateway control socket.
+func (c *Config) ControlSocketPath() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.Gateway.ControlSocket == "" {
+		return ""
+	}
+	return expandHome(c.Gateway.ControlSocket)
+}
+
 func (c *Config) GetAPIKey() string {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
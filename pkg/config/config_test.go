@@ -1,6 +1,7 @@
 package config
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -177,3 +178,68 @@ func TestConfig_Complete(t *testing.T) {
 		t.Error("Heartbeat should be enabled by default")
 	}
 }
+
+// TestConfig_Validate verifies invalid values are reported and defaults pass
+func TestConfig_Validate(t *testing.T) {
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Fatalf("DefaultConfig().Validate() = %v, want nil", err)
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(*Config)
+		want   string
+	}{
+		{"empty model", func(c *Config) { c.Agents.Defaults.Model = "" }, "agents.defaults.model"},
+		{"temperature", func(c *Config) { c.Agents.Defaults.Temperature = 3 }, "temperature"},
+		{"port", func(c *Config) { c.Gateway.Port = 70000 }, "gateway.port"},
+		{"admin without password", func(c *Config) { c.Gateway.Admin.Enabled = true }, "gateway.admin.username and password"},
+		{"telegram webhook", func(c *Config) { c.Channels.Telegram.WebhookURL = "http://example.com" }, "webhook_url"},
+		{"onebot mode", func(c *Config) {
+			c.Channels.OneBot.Enabled = true
+			c.Channels.OneBot.Mode = "sideways"
+		}, "channels.onebot.mode"},
+		{"network proxy scheme", func(c *Config) { c.Network.Proxy = "ftp://proxy:21" }, "network.proxy"},
+		{"network ip family", func(c *Config) { c.Network.IPFamily = "ipv5" }, "network.ip_family"},
+		{"network component override", func(c *Config) {
+			c.Network.Components = map[string]NetworkOverride{"web": {Proxy: "proxy-without-scheme"}}
+		}, "network.components.web.proxy"},
+		{"bus url", func(c *Config) { c.Bus.URL = "http://agent:18790/bus" }, "bus.url"},
+		{"progress interval", func(c *Config) { c.Progress.Interval = 0 }, "progress.interval"},
+		{"federation peer token", func(c *Config) {
+			c.Federation.Enabled = true
+			c.Federation.Peers = []PeerConfig{{Name: "garage", URL: "http://10.0.0.2:18790/a2a", Token: "short"}}
+		}, "federation.peers[0].token"},
+		{"federation shared token", func(c *Config) {
+			c.Federation.Enabled = true
+			c.Federation.Peers = []PeerConfig{
+				{Name: "garage", URL: "http://10.0.0.2:18790/a2a", Token: "0123456789abcdef"},
+				{Name: "office", URL: "http://10.0.0.3:18790/a2a", Token: "0123456789abcdef"},
+			}
+		}, "federation.peers[1].token is shared"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			tt.mutate(cfg)
+			err := cfg.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() = %v, want error mentioning %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestProgressConfig_For(t *testing.T) {
+	off := false
+	p := DefaultConfig().Progress
+	p.Channels = map[string]ProgressOverride{"slack": {Status: &off}}
+
+	if typing, status := p.For("telegram"); !typing || !status {
+		t.Errorf("For(telegram) = %v, %v, want defaults", typing, status)
+	}
+	if typing, status := p.For("slack"); !typing || status {
+		t.Errorf("For(slack) = %v, %v, want typing only", typing, status)
+	}
+}
@@ -0,0 +1,349 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/caarlos0/env/v11"
+)
+
+// Config is composed from layers, each overriding the previous one:
+//
+//  1. built-in defaults
+//  2. the base file (config.json)
+//  3. config.d/*.json next to the base file, in lexical order
+//  4. profiles/<name>.json next to the base file, when a profile is selected;
+//     built-in profiles (low_memory) apply first and the file, if any, on top
+//  5. PICOCLAW_* environment variables
+//
+// Layers are deep-merged: objects merge key by key, while lists and scalars
+// replace the previous value. A JSON null removes the key, restoring the
+// built-in default.
+const (
+	OverlayDirName = "config.d"
+	ProfileDirName = "profiles"
+
+	// SourceDefault is the provenance of values no layer sets.
+	SourceDefault = "default"
+)
+
+// builtinProfiles can be selected without a file. low_memory is for boards
+// with 64MB of RAM or less: a 48MB soft limit, small reads, few sessions in
+// memory and no background work in parallel.
+var builtinProfiles = map[string]string{
+	"low_memory": `{
+		"memory": {
+			"limit_mb": 48,
+			"max_read_kb": 256,
+			"session_idle_minutes": 10,
+			"max_sessions": 8,
+			"max_background": 1,
+			"pressure_percent": 70,
+			"check_interval": 10
+		},
+		"feedback": {"max_turns": 100},
+		"connectivity": {"max_queue": 50}
+	}`,
+}
+
+// BuiltinProfiles returns the names of the built-in profiles.
+func BuiltinProfiles() []string {
+	names := make([]string, 0, len(builtinProfiles))
+	for name := range builtinProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Resolved is a config together with where each of its values came from.
+type Resolved struct {
+	Config *Config
+	// Layers are the files that were applied, in order.
+	Layers []string
+	// Sources maps a dotted JSON path (e.g. "channels.telegram.token") to the
+	// file or "env:NAME" that set it. Paths not listed are defaults.
+	Sources map[string]string
+}
+
+// Source returns where the value at path came from.
+func (r *Resolved) Source(path string) string {
+	if src, ok := r.Sources[path]; ok {
+		return src
+	}
+	return SourceDefault
+}
+
+// LoadConfigProfile loads the config at path with its overlays and the
+// named profile. An empty profile applies none.
+func LoadConfigProfile(path, profile string) (*Config, error) {
+	r, err := ResolveConfig(path, profile)
+	if err != nil {
+		return nil, err
+	}
+	return r.Config, nil
+}
+
+// LoadBaseConfig loads the defaults and the base file only, without
+// overlays, profiles or environment variables. Use it to modify and save
+// the base file without copying the other layers into it.
+func LoadBaseConfig(path string) (*Config, error) {
+	cfg := DefaultConfig()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// OverlayFiles returns the config.d overlays of the base file at path, in
+// the order they are applied.
+func OverlayFiles(path string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(filepath.Dir(path), OverlayDirName, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// ProfileFile returns the file of the named profile.
+func ProfileFile(path, profile string) (string, error) {
+	if profile == "" || profile != filepath.Base(profile) || strings.HasPrefix(profile, ".") {
+		return "", fmt.Errorf("invalid profile name %q", profile)
+	}
+	return filepath.Join(filepath.Dir(path), ProfileDirName, profile+".json"), nil
+}
+
+// ResolveConfig merges all layers for the base file at path. A missing base
+// file is not an error; a missing profile is.
+func ResolveConfig(path, profile string) (*Resolved, error) {
+	tree, err := toTree(DefaultConfig())
+	if err != nil {
+		return nil, err
+	}
+	r := &Resolved{Sources: make(map[string]string)}
+	// applyData merges one layer; source names it in Layers and Sources.
+	applyData := func(source string, data []byte) error {
+		var layer map[string]interface{}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&layer); err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+		mergeTree(tree, layer, "", source, r.Sources)
+		r.Layers = append(r.Layers, source)
+		return nil
+	}
+	apply := func(file string, required bool) error {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			if os.IsNotExist(err) && !required {
+				return nil
+			}
+			return err
+		}
+		return applyData(file, data)
+	}
+
+	if err := apply(path, false); err != nil {
+		return nil, err
+	}
+
+	overlays, err := OverlayFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range overlays {
+		if err := apply(file, true); err != nil {
+			return nil, err
+		}
+	}
+
+	if profile != "" {
+		file, err := ProfileFile(path, profile)
+		if err != nil {
+			return nil, err
+		}
+		builtin, ok := builtinProfiles[profile]
+		if ok {
+			if err := applyData("builtin:"+profile, []byte(builtin)); err != nil {
+				return nil, err
+			}
+		}
+		if err := apply(file, !ok); err != nil {
+			if os.IsNotExist(err) {
+				return nil, fmt.Errorf("profile %q not found: %s does not exist", profile, file)
+			}
+			return nil, err
+		}
+	}
+
+	merged, err := json.Marshal(tree)
+	if err != nil {
+		return nil, err
+	}
+	cfg := DefaultConfig()
+	if err := json.Unmarshal(merged, cfg); err != nil {
+		return nil, err
+	}
+
+	for name, p := range envPaths(reflect.TypeOf(Config{}), "") {
+		if v, ok := os.LookupEnv(name); ok && v != "" {
+			forgetSources(r.Sources, p)
+			r.Sources[p] = "env:" + name
+		}
+	}
+	if err := env.Parse(cfg); err != nil {
+		return nil, err
+	}
+
+	r.Config = cfg
+	return r, nil
+}
+
+// mergeTree deep-merges src into dst, recording the source of every value
+// it sets.
+func mergeTree(dst, src map[string]interface{}, prefix, source string, sources map[string]string) {
+	for k, v := range src {
+		p := joinPath(prefix, k)
+		switch val := v.(type) {
+		case nil:
+			delete(dst, k)
+			forgetSources(sources, p)
+		case map[string]interface{}:
+			child, ok := dst[k].(map[string]interface{})
+			if !ok {
+				forgetSources(sources, p)
+				child = make(map[string]interface{})
+				dst[k] = child
+			}
+			mergeTree(child, val, p, source, sources)
+		default:
+			forgetSources(sources, p)
+			dst[k] = v
+			sources[p] = source
+		}
+	}
+}
+
+// forgetSources drops the provenance of path and everything below it.
+func forgetSources(sources map[string]string, path string) {
+	for p := range sources {
+		if p == path || strings.HasPrefix(p, path+".") {
+			delete(sources, p)
+		}
+	}
+}
+
+func joinPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func toTree(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var tree map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&tree); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// envPaths maps each env variable of a config struct to its JSON path.
+func envPaths(t reflect.Type, prefix string) map[string]string {
+	out := make(map[string]string)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		p := joinPath(prefix, name)
+		if e := f.Tag.Get("env"); e != "" {
+			out[strings.Split(e, ",")[0]] = p
+		}
+		if f.Type.Kind() == reflect.Struct {
+			for k, v := range envPaths(f.Type, p) {
+				out[k] = v
+			}
+		}
+	}
+	return out
+}
+
+// Leaf is one effective config value.
+type Leaf struct {
+	Path   string
+	Value  interface{}
+	Source string
+}
+
+// Leaves flattens the resolved config into its values, sorted by path.
+// Lists are single values. Secrets are masked unless showSecrets is set.
+func (r *Resolved) Leaves(showSecrets bool) ([]Leaf, error) {
+	r.Config.mu.RLock()
+	tree, err := toTree(r.Config)
+	r.Config.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var leaves []Leaf
+	var walk func(node map[string]interface{}, prefix string)
+	walk = func(node map[string]interface{}, prefix string) {
+		for k, v := range node {
+			p := joinPath(prefix, k)
+			if child, ok := v.(map[string]interface{}); ok && len(child) > 0 {
+				walk(child, p)
+				continue
+			}
+			if s, ok := v.(string); ok && s != "" && !showSecrets && IsSecretKey(k) {
+				v = "********"
+			}
+			leaves = append(leaves, Leaf{Path: p, Value: v, Source: r.Source(p)})
+		}
+	}
+	walk(tree, "")
+	sort.Slice(leaves, func(i, j int) bool { return leaves[i].Path < leaves[j].Path })
+	return leaves, nil
+}
+
+// IsSecretKey reports whether a config key holds a credential.
+func IsSecretKey(key string) bool {
+	switch key {
+	case "token", "secret", "password":
+		return true
+	}
+	for _, suffix := range []string{"_key", "_token", "_secret", "_password"} {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
+}
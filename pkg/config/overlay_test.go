@@ -0,0 +1,207 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResolveConfig_Overlays(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "config.json")
+	writeFile(t, base, `{
+		"agents": {"defaults": {"model": "base-model", "max_tokens": 1000}},
+		"channels": {"telegram": {"enabled": true, "token": "base-token", "allow_from": ["1", "2"]}}
+	}`)
+	// Lexical order: 20 overrides 10.
+	writeFile(t, filepath.Join(dir, "config.d", "20-board.json"), `{
+		"channels": {"telegram": {"token": "board-token", "allow_from": ["3"]}}
+	}`)
+	writeFile(t, filepath.Join(dir, "config.d", "10-common.json"), `{
+		"channels": {"telegram": {"token": "common-token"}},
+		"agents": {"defaults": {"max_tokens": 2000}}
+	}`)
+	writeFile(t, filepath.Join(dir, "config.d", "notes.txt"), `ignored`)
+
+	r, err := ResolveConfig(base, "")
+	if err != nil {
+		t.Fatalf("ResolveConfig: %v", err)
+	}
+	cfg := r.Config
+
+	if cfg.Channels.Telegram.Token != "board-token" {
+		t.Errorf("token = %q, want board-token", cfg.Channels.Telegram.Token)
+	}
+	if got := strings.Join(cfg.Channels.Telegram.AllowFrom, ","); got != "3" {
+		t.Errorf("lists should replace, got allow_from %q", got)
+	}
+	if !cfg.Channels.Telegram.Enabled || cfg.Agents.Defaults.Model != "base-model" {
+		t.Error("maps should merge: values only in the base file were lost")
+	}
+	if cfg.Agents.Defaults.MaxTokens != 2000 {
+		t.Errorf("max_tokens = %d, want 2000", cfg.Agents.Defaults.MaxTokens)
+	}
+	if cfg.Heartbeat.Interval != 30 {
+		t.Errorf("defaults should apply, got heartbeat interval %d", cfg.Heartbeat.Interval)
+	}
+
+	wantLayers := []string{base, filepath.Join(dir, "config.d", "10-common.json"), filepath.Join(dir, "config.d", "20-board.json")}
+	if strings.Join(r.Layers, "|") != strings.Join(wantLayers, "|") {
+		t.Errorf("layers = %v, want %v", r.Layers, wantLayers)
+	}
+
+	for path, want := range map[string]string{
+		"channels.telegram.token":      wantLayers[2],
+		"channels.telegram.allow_from": wantLayers[2],
+		"channels.telegram.enabled":    base,
+		"agents.defaults.max_tokens":   wantLayers[1],
+		"heartbeat.interval":           SourceDefault,
+	} {
+		if got := r.Source(path); got != want {
+			t.Errorf("source of %s = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestResolveConfig_ProfileAndEnv(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "config.json")
+	writeFile(t, base, `{"gateway": {"port": 1111}, "heartbeat": {"interval": 10}}`)
+	writeFile(t, filepath.Join(dir, "config.d", "10.json"), `{"gateway": {"host": "127.0.0.1"}}`)
+	writeFile(t, filepath.Join(dir, "profiles", "lab.json"), `{"gateway": {"port": null}, "agents": {"defaults": {"model": "lab"}}}`)
+	t.Setenv("PICOCLAW_HEARTBEAT_INTERVAL", "7")
+
+	r, err := ResolveConfig(base, "lab")
+	if err != nil {
+		t.Fatalf("ResolveConfig: %v", err)
+	}
+	if r.Config.Gateway.Port != DefaultConfig().Gateway.Port {
+		t.Errorf("null should restore the default port, got %d", r.Config.Gateway.Port)
+	}
+	if r.Source("gateway.port") != SourceDefault {
+		t.Errorf("source of a nulled value = %q", r.Source("gateway.port"))
+	}
+	if r.Config.Agents.Defaults.Model != "lab" || r.Config.Gateway.Host != "127.0.0.1" {
+		t.Errorf("profile or overlay not applied: %+v", r.Config.Gateway)
+	}
+	if r.Config.Heartbeat.Interval != 7 || r.Source("heartbeat.interval") != "env:PICOCLAW_HEARTBEAT_INTERVAL" {
+		t.Errorf("env override: interval %d from %q", r.Config.Heartbeat.Interval, r.Source("heartbeat.interval"))
+	}
+
+	if _, err := ResolveConfig(base, "missing"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("missing profile: %v", err)
+	}
+	if _, err := ResolveConfig(base, "../config"); err == nil {
+		t.Error("profile names with path separators should be rejected")
+	}
+}
+
+func TestResolveConfig_Errors(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "config.json")
+
+	r, err := ResolveConfig(base, "")
+	if err != nil {
+		t.Fatalf("missing base file should load defaults: %v", err)
+	}
+	if len(r.Layers) != 0 || r.Config.Gateway.Port != 18790 {
+		t.Errorf("unexpected result for missing base: %+v", r.Layers)
+	}
+
+	writeFile(t, filepath.Join(dir, "config.d", "bad.json"), `{"gateway": `)
+	if _, err := ResolveConfig(base, ""); err == nil || !strings.Contains(err.Error(), "bad.json") {
+		t.Errorf("broken overlay should name the file, got %v", err)
+	}
+}
+
+func TestLoadBaseConfig_IgnoresLayers(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "config.json")
+	writeFile(t, base, `{"gateway": {"port": 1111}}`)
+	writeFile(t, filepath.Join(dir, "config.d", "10.json"), `{"gateway": {"port": 2222}}`)
+	t.Setenv("PICOCLAW_GATEWAY_HOST", "10.0.0.1")
+
+	cfg, err := LoadBaseConfig(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Gateway.Port != 1111 || cfg.Gateway.Host != "0.0.0.0" {
+		t.Errorf("base config picked up other layers: %+v", cfg.Gateway)
+	}
+}
+
+func TestResolved_LeavesMasksSecrets(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "config.json")
+	writeFile(t, base, `{"channels": {"telegram": {"token": "secret-value"}}}`)
+
+	r, err := ResolveConfig(base, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	find := func(leaves []Leaf, path string) *Leaf {
+		for i := range leaves {
+			if leaves[i].Path == path {
+				return &leaves[i]
+			}
+		}
+		return nil
+	}
+
+	leaves, err := r.Leaves(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf := find(leaves, "channels.telegram.token")
+	if leaf == nil || leaf.Value != "********" || leaf.Source != base {
+		t.Errorf("masked leaf = %+v", leaf)
+	}
+	if find(leaves, "channels.telegram") != nil {
+		t.Error("objects should be flattened into their values")
+	}
+
+	leaves, _ = r.Leaves(true)
+	if leaf := find(leaves, "channels.telegram.token"); leaf == nil || leaf.Value != "secret-value" {
+		t.Errorf("unmasked leaf = %+v", leaf)
+	}
+}
+
+func TestResolveConfig_BuiltinProfile(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "config.json")
+
+	r, err := ResolveConfig(base, "low_memory")
+	if err != nil {
+		t.Fatalf("ResolveConfig: %v", err)
+	}
+	if r.Config.Memory.LimitMB != 48 || r.Config.Memory.MaxBackground != 1 {
+		t.Errorf("low_memory not applied: %+v", r.Config.Memory)
+	}
+	if r.Source("memory.limit_mb") != "builtin:low_memory" {
+		t.Errorf("source of memory.limit_mb = %q", r.Source("memory.limit_mb"))
+	}
+	if err := r.Config.Validate(); err != nil {
+		t.Errorf("low_memory does not validate: %v", err)
+	}
+
+	// A profile file of the same name adjusts the built-in one
+	writeFile(t, filepath.Join(dir, "profiles", "low_memory.json"), `{"memory": {"limit_mb": 96}}`)
+	r, err = ResolveConfig(base, "low_memory")
+	if err != nil {
+		t.Fatalf("ResolveConfig: %v", err)
+	}
+	if r.Config.Memory.LimitMB != 96 || r.Config.Memory.MaxSessions != 8 {
+		t.Errorf("profile file not merged over the built-in: %+v", r.Config.Memory)
+	}
+}
@@ -0,0 +1,177 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// Inspired by and based on nanobot: https://github.com/HKUDS/nanobot
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+// Package connectivity tells whether the LLM provider can be reached. The
+// Monitor probes the provider's endpoint and any configured URLs; while none
+// of them answers, the agent is offline and queues turns in a Queue.
+package connectivity
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/network"
+)
+
+const (
+	defaultInterval = time.Minute
+	defaultTimeout  = 10 * time.Second
+	// While offline, probes run more often so queued turns are answered soon
+	// after the uplink returns.
+	maxOfflineInterval = 15 * time.Second
+)
+
+// Monitor tracks whether the provider is reachable.
+type Monitor struct {
+	targets  []string
+	interval time.Duration
+	client   *http.Client
+
+	mu        sync.Mutex
+	online    bool
+	since     time.Time
+	listeners []func(online bool)
+}
+
+// New creates a monitor that probes endpoint, the provider's API base, and
+// cfg.ProbeURLs. The state starts online.
+func New(cfg config.ConnectivityConfig, endpoint string) *Monitor {
+	m := &Monitor{
+		interval: time.Duration(cfg.Interval) * time.Second,
+		online:   true,
+		since:    time.Now(),
+	}
+	if m.interval <= 0 {
+		m.interval = defaultInterval
+	}
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	m.client = network.NewClient(network.ComponentProviders, network.Options{Timeout: timeout})
+	m.client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	for _, raw := range append([]string{endpoint}, cfg.ProbeURLs...) {
+		if u, err := url.Parse(raw); err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" {
+			m.targets = append(m.targets, raw)
+		}
+	}
+	if len(m.targets) == 0 {
+		logger.WarnC("connectivity", "No endpoint to probe; the agent is always considered online")
+	}
+	return m
+}
+
+// Targets returns the URLs that are probed.
+func (m *Monitor) Targets() []string {
+	return m.targets
+}
+
+// Online reports whether the last check reached any target.
+func (m *Monitor) Online() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.online
+}
+
+// Since returns when the current state began.
+func (m *Monitor) Since() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.since
+}
+
+// OnChange registers fn to be called, outside any lock, whenever the state
+// flips.
+func (m *Monitor) OnChange(fn func(online bool)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.listeners = append(m.listeners, fn)
+}
+
+// Check probes the targets now and returns the new state. Any HTTP
+// response, whatever its status, counts as reachable.
+func (m *Monitor) Check(ctx context.Context) bool {
+	if len(m.targets) == 0 {
+		return true
+	}
+	online := false
+	for _, target := range m.targets {
+		if m.probe(ctx, target) {
+			online = true
+			break
+		}
+	}
+	m.set(online)
+	return online
+}
+
+func (m *Monitor) probe(ctx context.Context, target string) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, target, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		logger.DebugCF("connectivity", "Probe failed", map[string]interface{}{
+			"target": target,
+			"error":  err.Error(),
+		})
+		return false
+	}
+	resp.Body.Close()
+	return true
+}
+
+func (m *Monitor) set(online bool) {
+	m.mu.Lock()
+	if m.online == online {
+		m.mu.Unlock()
+		return
+	}
+	m.online = online
+	m.since = time.Now()
+	listeners := append([]func(bool){}, m.listeners...)
+	m.mu.Unlock()
+
+	if online {
+		logger.InfoC("connectivity", "Provider reachable again")
+	} else {
+		logger.WarnCF("connectivity", "Provider unreachable, going offline", map[string]interface{}{
+			"targets": m.targets,
+		})
+	}
+	for _, fn := range listeners {
+		fn(online)
+	}
+}
+
+// Run checks periodically until ctx is done: every interval while online,
+// and more often while offline.
+func (m *Monitor) Run(ctx context.Context) {
+	if len(m.targets) == 0 {
+		return
+	}
+	for {
+		online := m.Check(ctx)
+		wait := m.interval
+		if !online {
+			wait = min(m.interval, maxOfflineInterval)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
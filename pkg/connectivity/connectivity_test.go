@@ -0,0 +1,83 @@
+package connectivity
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestMonitorDetectsOutage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Errors still prove the host is reachable
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	m := New(config.ConnectivityConfig{Timeout: 2}, srv.URL+"/v1")
+
+	var changes []bool
+	m.OnChange(func(online bool) { changes = append(changes, online) })
+
+	ctx := context.Background()
+	if !m.Check(ctx) {
+		t.Fatal("reachable endpoint reported offline")
+	}
+	srv.Close()
+	if m.Check(ctx) || m.Online() {
+		t.Fatal("closed endpoint reported online")
+	}
+	m.Check(ctx)
+	if len(changes) != 1 || changes[0] {
+		t.Errorf("changes = %v, want one switch to offline", changes)
+	}
+}
+
+func TestMonitorWithoutTargets(t *testing.T) {
+	m := New(config.ConnectivityConfig{}, "")
+	if !m.Check(context.Background()) {
+		t.Error("a monitor without targets should stay online")
+	}
+}
+
+func TestQueuePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "queue.json")
+	q := NewQueue(path, 2)
+	for _, text := range []string{"one", "two", "three"} {
+		if err := q.Push(bus.InboundMessage{Channel: "telegram", ChatID: "42", Content: text}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reopened := NewQueue(path, 2)
+	if reopened.Len() != 2 {
+		t.Fatalf("len = %d, want 2", reopened.Len())
+	}
+	items := reopened.Replay()
+	if len(items) != 2 || items[0].Message.Content != "two" || items[1].Message.Content != "three" {
+		t.Fatalf("replayed %+v, want the two newest", items)
+	}
+	if again := reopened.Replay(); len(again) != 0 {
+		t.Errorf("replayed %d messages twice", len(again))
+	}
+
+	// Messages stay on disk until their turn is done
+	if NewQueue(path, 2).Len() != 2 {
+		t.Error("replayed messages removed before they were handled")
+	}
+	reopened.Release(items[0].ID)
+	if err := reopened.Done(items[1].ID); err != nil {
+		t.Fatal(err)
+	}
+	if retry := reopened.Replay(); len(retry) != 1 || retry[0].ID != items[0].ID {
+		t.Errorf("released message not replayed again: %+v", retry)
+	}
+	if err := reopened.Done(items[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if NewQueue(path, 2).Len() != 0 {
+		t.Error("handled messages still on disk")
+	}
+}
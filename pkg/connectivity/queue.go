@@ -0,0 +1,154 @@
+package connectivity
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+const defaultMaxQueue = 200
+
+// Queued is a message received while offline.
+type Queued struct {
+	ID       string             `json:"id"`
+	Message  bus.InboundMessage `json:"message"`
+	QueuedAt time.Time          `json:"queued_at"`
+}
+
+// Queue holds messages received while offline. It is saved on every change
+// so queued turns survive a restart. A replayed message stays queued until
+// its turn is done, so a restart during the replay loses nothing.
+type Queue struct {
+	path      string
+	max       int
+	mu        sync.Mutex
+	items     []Queued
+	replaying map[string]bool // IDs handed out by Replay and not yet done
+	seq       int
+}
+
+// NewQueue opens the queue stored at path, keeping at most max messages.
+func NewQueue(path string, max int) *Queue {
+	if max <= 0 {
+		max = defaultMaxQueue
+	}
+	q := &Queue{path: path, max: max, replaying: make(map[string]bool)}
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &q.items); err != nil {
+			logger.WarnCF("connectivity", "Ignoring unreadable offline queue", map[string]interface{}{
+				"path":  path,
+				"error": err.Error(),
+			})
+			q.items = nil
+		}
+	}
+	// Queues saved before messages had IDs
+	for i := range q.items {
+		if q.items[i].ID == "" {
+			q.items[i].ID = q.nextID()
+		}
+	}
+	return q
+}
+
+// nextID returns an ID unique within the queue. q.mu must be held or q
+// not yet shared.
+func (q *Queue) nextID() string {
+	q.seq++
+	return strconv.FormatInt(time.Now().UnixNano(), 36) + "-" + strconv.Itoa(q.seq)
+}
+
+// Push appends msg. When the queue is full the oldest message is dropped.
+func (q *Queue) Push(msg bus.InboundMessage) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.items = append(q.items, Queued{ID: q.nextID(), Message: msg, QueuedAt: time.Now()})
+	if over := len(q.items) - q.max; over > 0 {
+		logger.WarnCF("connectivity", "Offline queue full, dropping oldest messages", map[string]interface{}{
+			"dropped": over,
+		})
+		for _, item := range q.items[:over] {
+			delete(q.replaying, item.ID)
+		}
+		q.items = append([]Queued(nil), q.items[over:]...)
+	}
+	return q.saveLocked()
+}
+
+// Replay returns the queued messages that are not being replayed already,
+// oldest first. They stay queued until Done or, to be replayed again later,
+// Release.
+func (q *Queue) Replay() []Queued {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var items []Queued
+	for _, item := range q.items {
+		if !q.replaying[item.ID] {
+			q.replaying[item.ID] = true
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Done removes the message with id once its replayed turn has completed.
+func (q *Queue) Done(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.replaying, id)
+	for i, item := range q.items {
+		if item.ID == id {
+			q.items = slices.Delete(q.items, i, i+1)
+			return q.saveLocked()
+		}
+	}
+	return nil
+}
+
+// Release keeps the message with id queued for the next Replay, after its
+// turn could not run.
+func (q *Queue) Release(id string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.replaying, id)
+}
+
+// Len returns the number of queued messages.
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
+}
+
+func (q *Queue) saveLocked() error {
+	if len(q.items) == 0 {
+		if err := os.Remove(q.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(q.items, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal offline queue: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(q.path), 0755); err != nil {
+		return err
+	}
+	tmp := q.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write offline queue: %w", err)
+	}
+	if err := os.Rename(tmp, q.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to save offline queue: %w", err)
+	}
+	return nil
+}
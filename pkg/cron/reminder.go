@@ -0,0 +1,172 @@
+package cron
+
+import (
+	"log"
+	"strings"
+	"time"
+)
+
+// AckPolicy makes a delivered reminder repeat until it is acknowledged.
+type AckPolicy struct {
+	RepeatEveryMS   int64  `json:"repeatEveryMs"`
+	MaxAttempts     int    `json:"maxAttempts,omitempty"`     // 0: until acknowledged
+	EscalateAfter   int    `json:"escalateAfter,omitempty"`   // unacknowledged attempts before escalating; 0: never
+	EscalateChannel string `json:"escalateChannel,omitempty"` // default: the reminder's channel
+	EscalateTo      string `json:"escalateTo,omitempty"`
+}
+
+// Escalates reports whether attempt is the one that also goes to the
+// escalation contact.
+func (p *AckPolicy) Escalates(attempt int) bool {
+	return p != nil && p.EscalateAfter > 0 && p.EscalateTo != "" && attempt == p.EscalateAfter+1
+}
+
+// ReminderState tracks a fired reminder that waits for acknowledgement.
+// Each delivery, the first included, is an attempt.
+type ReminderState struct {
+	FiredAtMS     int64 `json:"firedAtMs"`
+	Attempts      int   `json:"attempts"`
+	NextAtMS      int64 `json:"nextAtMs,omitempty"` // 0: no more attempts
+	EscalatedAtMS int64 `json:"escalatedAtMs,omitempty"`
+}
+
+// Target returns the channel and chat a job delivers to, with the same
+// defaults the job handler applies.
+func (j *CronJob) Target() (channel, chatID string) {
+	channel, chatID = j.Payload.Channel, j.Payload.To
+	if channel == "" {
+		channel = "cli"
+	}
+	if chatID == "" {
+		chatID = "direct"
+	}
+	return channel, chatID
+}
+
+// EscalationTarget returns where escalations of the job go, if anywhere.
+func (j *CronJob) EscalationTarget() (channel, chatID string, ok bool) {
+	ack := j.Payload.Ack
+	if ack == nil || ack.EscalateTo == "" {
+		return "", "", false
+	}
+	channel, _ = j.Target()
+	if ack.EscalateChannel != "" {
+		channel = ack.EscalateChannel
+	}
+	return channel, ack.EscalateTo, true
+}
+
+// awaits reports whether the job waits for an acknowledgement from channel
+// and chatID: the reminder's own chat or the escalation contact's.
+func (j *CronJob) awaits(channel, chatID string) bool {
+	if j.State.Reminder == nil {
+		return false
+	}
+	if ch, to := j.Target(); ch == channel && to == chatID {
+		return true
+	}
+	ch, to, ok := j.EscalationTarget()
+	return ok && ch == channel && to == chatID
+}
+
+// advance records an attempt made at now and schedules the next one.
+func (r *ReminderState) advance(p *AckPolicy, now int64) {
+	r.Attempts++
+	r.NextAtMS = 0
+	if p.MaxAttempts == 0 || r.Attempts < p.MaxAttempts {
+		r.NextAtMS = now + p.RepeatEveryMS
+	}
+	if p.Escalates(r.Attempts) {
+		r.EscalatedAtMS = now
+	}
+}
+
+// PendingReminders returns the reminders waiting for an acknowledgement
+// from channel and chatID.
+func (cs *CronService) PendingReminders(channel, chatID string) []CronJob {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	var jobs []CronJob
+	for _, job := range cs.store.Jobs {
+		if job.awaits(channel, chatID) {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs
+}
+
+// Acknowledge stops the pending reminders of channel and chatID, or only
+// jobID when it is set, and records who acknowledged them and when. It
+// returns the acknowledged jobs with the reminder state they had.
+func (cs *CronService) Acknowledge(channel, chatID, jobID, by string) []CronJob {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	now := time.Now().UnixMilli()
+	var acked []CronJob
+	for i := range cs.store.Jobs {
+		job := &cs.store.Jobs[i]
+		if !job.awaits(channel, chatID) || (jobID != "" && job.ID != jobID) {
+			continue
+		}
+		job.State.LastAckAtMS = &now
+		job.State.LastAckBy = by
+		job.UpdatedAtMS = now
+		acked = append(acked, *job)
+		job.State.Reminder = nil
+	}
+	if len(acked) > 0 {
+		if err := cs.saveStoreUnsafe(); err != nil {
+			log.Printf("[cron] failed to save store after acknowledgement: %v", err)
+		}
+	}
+	return acked
+}
+
+// dueRemindersUnsafe advances the reminders whose next attempt is due and
+// returns copies of their jobs for the handler. Jobs in skip are about to
+// fire anew, which replaces their reminder.
+func (cs *CronService) dueRemindersUnsafe(now int64, skip map[string]bool) []CronJob {
+	var due []CronJob
+	for i := range cs.store.Jobs {
+		job := &cs.store.Jobs[i]
+		r := job.State.Reminder
+		if r == nil || r.NextAtMS == 0 || r.NextAtMS > now || skip[job.ID] || job.Payload.Ack == nil {
+			continue
+		}
+		r.advance(job.Payload.Ack, now)
+		jobCopy := *job
+		state := *r
+		jobCopy.State.Reminder = &state
+		due = append(due, jobCopy)
+	}
+	return due
+}
+
+var ackWords = map[string]bool{
+	"ack": true, "ok": true, "okay": true, "done": true, "did it": true, "taken": true, "took it": true,
+	"got it": true, "yes": true, "y": true,
+	"好": true, "好的": true, "收到": true, "完成": true, "已完成": true, "吃了": true, "已吃": true, "知道了": true,
+	"はい": true, "了解": true, "完了": true, "済み": true,
+	"👍": true, "👌": true, "✅": true, "✔": true, "✔️": true, "☑️": true, "🆗": true, "🙏": true, "❤": true, "❤️": true, "🫡": true,
+}
+
+// AckData is the button data that acknowledges the reminder of jobID.
+func AckData(jobID string) string {
+	return "ack:" + jobID
+}
+
+// MatchAck reports whether text acknowledges a reminder: a short reply such
+// as "done", "ok" or "收到", an approving emoji, "/ack [id]", or the data of
+// an acknowledgement button. jobID is set when the text names one job.
+func MatchAck(text string) (jobID string, ok bool) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	if id, found := strings.CutPrefix(text, "ack:"); found {
+		return id, id != ""
+	}
+	if text == "/ack" || strings.HasPrefix(text, "/ack ") {
+		return strings.TrimSpace(strings.TrimPrefix(text, "/ack")), true
+	}
+	text = strings.TrimRight(text, "!.。！~ ")
+	return "", ackWords[text]
+}
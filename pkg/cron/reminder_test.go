@@ -0,0 +1,106 @@
+package cron
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReminderRepeatsUntilAcknowledged(t *testing.T) {
+	var attempts []int
+	cs := NewCronService(filepath.Join(t.TempDir(), "jobs.json"), func(job *CronJob) (string, error) {
+		attempts = append(attempts, job.State.Reminder.Attempts)
+		return "ok", nil
+	})
+	cs.running = true
+
+	at := time.Now().Add(time.Hour).UnixMilli()
+	job, err := cs.AddJob("pills", CronSchedule{Kind: "at", AtMS: &at}, "Take your pills", true, "telegram", "42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	job.Payload.Ack = &AckPolicy{RepeatEveryMS: 1, EscalateAfter: 2, EscalateTo: "99"}
+	if err := cs.UpdateJob(job); err != nil {
+		t.Fatal(err)
+	}
+
+	cs.executeJobByID(job.ID)
+	for i := 0; i < 2; i++ {
+		time.Sleep(2 * time.Millisecond)
+		cs.checkJobs()
+	}
+	if len(attempts) != 3 || attempts[0] != 1 || attempts[2] != 3 {
+		t.Fatalf("attempts = %v, want [1 2 3]", attempts)
+	}
+	pending := cs.PendingReminders("telegram", "99")
+	if len(pending) != 1 || pending[0].State.Reminder.EscalatedAtMS == 0 {
+		t.Fatalf("escalation contact sees %+v, want the escalated reminder", pending)
+	}
+
+	if acked := cs.Acknowledge("telegram", "7", "", "someone"); len(acked) != 0 {
+		t.Fatalf("unrelated chat acknowledged %d reminders", len(acked))
+	}
+	acked := cs.Acknowledge("telegram", "42", "", "user1")
+	if len(acked) != 1 {
+		t.Fatalf("acknowledged %d reminders, want 1", len(acked))
+	}
+
+	stored := cs.ListJobs(true)
+	if len(stored) != 1 || stored[0].State.Reminder != nil || stored[0].State.LastAckAtMS == nil || stored[0].State.LastAckBy != "user1" {
+		t.Fatalf("job after acknowledgement = %+v", stored)
+	}
+	time.Sleep(2 * time.Millisecond)
+	cs.checkJobs()
+	if len(attempts) != 3 {
+		t.Errorf("reminder repeated after acknowledgement: %v", attempts)
+	}
+}
+
+func TestReminderStopsAtMaxAttempts(t *testing.T) {
+	calls := 0
+	cs := NewCronService(filepath.Join(t.TempDir(), "jobs.json"), func(job *CronJob) (string, error) {
+		calls++
+		return "ok", nil
+	})
+	cs.running = true
+
+	every := int64(time.Hour / time.Millisecond)
+	job, _ := cs.AddJob("water", CronSchedule{Kind: "every", EveryMS: &every}, "Water the plants", true, "cli", "direct")
+	job.Payload.Ack = &AckPolicy{RepeatEveryMS: 1, MaxAttempts: 2}
+	cs.UpdateJob(job)
+
+	cs.executeJobByID(job.ID)
+	for i := 0; i < 3; i++ {
+		time.Sleep(2 * time.Millisecond)
+		cs.checkJobs()
+	}
+	if calls != 2 {
+		t.Errorf("delivered %d times, want 2", calls)
+	}
+	if len(cs.PendingReminders("cli", "direct")) != 1 {
+		t.Error("reminder should stay pending after the last attempt")
+	}
+}
+
+func TestMatchAck(t *testing.T) {
+	tests := []struct {
+		text  string
+		id    string
+		match bool
+	}{
+		{"Done!", "", true},
+		{"收到", "", true},
+		{"👍", "", true},
+		{"ack:abc123", "abc123", true},
+		{"/ack abc123", "abc123", true},
+		{"/ack", "", true},
+		{"done with the report, now what?", "", false},
+		{"ack:", "", false},
+	}
+	for _, tt := range tests {
+		id, ok := MatchAck(tt.text)
+		if id != tt.id || ok != tt.match {
+			t.Errorf("MatchAck(%q) = %q, %v; want %q, %v", tt.text, id, ok, tt.id, tt.match)
+		}
+	}
+}
@@ -23,12 +23,14 @@ type CronSchedule struct {
 }
 
 type CronPayload struct {
-	Kind    string `json:"kind"`
-	Message string `json:"message"`
-	Command string `json:"command,omitempty"`
-	Deliver bool   `json:"deliver"`
-	Channel string `json:"channel,omitempty"`
-	To      string `json:"to,omitempty"`
+	Kind    string     `json:"kind"`
+	Message string     `json:"message"`
+	Command string     `json:"command,omitempty"`
+	Deliver bool       `json:"deliver"`
+	Channel string     `json:"channel,omitempty"`
+	To      string     `json:"to,omitempty"`
+	Ack     *AckPolicy `json:"ack,omitempty"`    // repeat a delivered reminder until acknowledged
+	Tenant  string     `json:"tenant,omitempty"` // tenant the job was created for
 }
 
 type CronJobState struct {
@@ -36,6 +38,10 @@ type CronJobState struct {
 	LastRunAtMS *int64 `json:"lastRunAtMs,omitempty"`
 	LastStatus  string `json:"lastStatus,omitempty"`
 	LastError   string `json:"lastError,omitempty"`
+
+	Reminder    *ReminderState `json:"reminder,omitempty"` // set while waiting for an acknowledgement
+	LastAckAtMS *int64         `json:"lastAckAtMs,omitempty"`
+	LastAckBy   string         `json:"lastAckBy,omitempty"`
 }
 
 type CronJob struct {
@@ -160,6 +166,8 @@ func (cs *CronService) checkJobs() {
 			cs.store.Jobs[i].State.NextRunAtMS = nil
 		}
 	}
+	reminders := cs.dueRemindersUnsafe(now, dueMap)
+	onJob := cs.onJob
 
 	if err := cs.saveStoreUnsafe(); err != nil {
 		log.Printf("[cron] failed to save store: %v", err)
@@ -171,6 +179,14 @@ func (cs *CronService) checkJobs() {
 	for _, jobID := range dueJobIDs {
 		cs.executeJobByID(jobID)
 	}
+	for i := range reminders {
+		if onJob == nil {
+			break
+		}
+		if _, err := onJob(&reminders[i]); err != nil {
+			log.Printf("[cron] reminder %s: %v", reminders[i].ID, err)
+		}
+	}
 }
 
 func (cs *CronService) executeJobByID(jobID string) {
@@ -192,6 +208,13 @@ func (cs *CronService) executeJobByID(jobID string) {
 		return
 	}
 
+	// A reminder that needs acknowledging starts over, even if the last one
+	// was never acknowledged
+	if callbackJob.Payload.Ack != nil && callbackJob.Payload.Deliver {
+		callbackJob.State.Reminder = &ReminderState{FiredAtMS: startTime}
+		callbackJob.State.Reminder.advance(callbackJob.Payload.Ack, startTime)
+	}
+
 	var err error
 	if cs.onJob != nil {
 		_, err = cs.onJob(callbackJob)
@@ -222,11 +245,15 @@ func (cs *CronService) executeJobByID(jobID string) {
 	} else {
 		job.State.LastStatus = "ok"
 		job.State.LastError = ""
+		if callbackJob.State.Reminder != nil {
+			job.State.Reminder = callbackJob.State.Reminder
+		}
 	}
 
 	// Compute next run time
 	if job.Schedule.Kind == "at" {
-		if job.DeleteAfterRun {
+		// Reminders are kept to record their acknowledgement
+		if job.DeleteAfterRun && job.Payload.Ack == nil {
 			cs.removeJobUnsafe(job.ID)
 		} else {
 			job.Enabled = false
@@ -263,8 +290,16 @@ func (cs *CronService) computeNextRun(schedule *CronSchedule, nowMS int64) *int6
 			return nil
 		}
 
-		// Use gronx to calculate next run time
+		// Use gronx to calculate next run time, in the schedule's zone
 		now := time.UnixMilli(nowMS)
+		if schedule.TZ != "" {
+			loc, err := time.LoadLocation(schedule.TZ)
+			if err != nil {
+				log.Printf("[cron] unknown time zone '%s' for expr '%s', using local time", schedule.TZ, schedule.Expr)
+			} else {
+				now = now.In(loc)
+			}
+		}
 		nextTime, err := gronx.NextTickAfter(schedule.Expr, now, false)
 		if err != nil {
 			log.Printf("[cron] failed to compute next run for expr '%s': %v", schedule.Expr, err)
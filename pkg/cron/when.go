@@ -0,0 +1,787 @@
+package cron
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseWhen turns a schedule phrase in English or Chinese, such as "every 2
+// hours", "every weekday at 9", "next Friday 18:00", "每天早上8点" or "last
+// day of the month", into a schedule. Dates and times are read in now's
+// location, which also becomes the zone of calendar schedules. The returned
+// description restates the schedule so the user can check it.
+//
+// Parsing is strict: words it does not know are an error rather than being
+// ignored, so "every weekday except holidays" is rejected instead of
+// silently losing the exception.
+func ParseWhen(text string, now time.Time) (CronSchedule, string, error) {
+	s := &whenSpec{now: now}
+	rest := normalizeWhen(text)
+	if rest == "" {
+		return CronSchedule{}, "", fmt.Errorf("empty schedule")
+	}
+	for rest != "" {
+		if n := matchRule(s, rest); n > 0 {
+			if s.err != nil {
+				return CronSchedule{}, "", s.err
+			}
+			rest = rest[n:]
+			continue
+		}
+		if loc := whenFiller.FindStringIndex(rest); loc != nil {
+			rest = rest[loc[1]:]
+			continue
+		}
+		word := rest
+		if i := strings.IndexAny(word, " ,"); i > 0 {
+			word = word[:i]
+		}
+		return CronSchedule{}, "", fmt.Errorf("can't understand %q in %q", word, text)
+	}
+	return s.schedule()
+}
+
+type dayPeriod int
+
+const (
+	periodNone dayPeriod = iota
+	periodMorning
+	periodNoon
+	periodAfternoon
+	periodNight
+)
+
+// How a one-shot weekday is picked.
+const (
+	weekdayUpcoming = iota // the next such day, today if the time is still ahead
+	weekdayAfter           // the next such day after today ("next Friday")
+	weekdayInWeek          // in the calendar week weekOffset weeks from now ("下周五")
+)
+
+// whenSpec collects what a phrase says before it becomes a schedule.
+type whenSpec struct {
+	now time.Time
+	err error
+
+	in        time.Duration // one-shot, relative to now
+	every     time.Duration // fixed interval
+	everyDays int           // "every 2 days"
+
+	repeat      bool // calendar recurrence
+	weekly      bool
+	monthly     bool
+	weekdays    []time.Weekday
+	lastWeekday bool // "last Friday of the month"
+	monthDay    int  // 1-31, or -1 for the last day of the month
+
+	date        time.Time // one-shot date
+	hasDate     bool
+	weekday     time.Weekday // one-shot weekday
+	hasWeekday  bool
+	weekdayMode int
+	weekOffset  int
+
+	hour, minute int
+	hasTime      bool
+	period       dayPeriod
+}
+
+type whenRule struct {
+	re *regexp.Regexp
+	fn func(s *whenSpec, m []string)
+}
+
+func rule(pattern string, fn func(s *whenSpec, m []string)) whenRule {
+	return whenRule{re: regexp.MustCompile(`^(?:` + pattern + `)`), fn: fn}
+}
+
+// matchRule applies the first rule matching the start of rest and returns
+// the length it consumed.
+func matchRule(s *whenSpec, rest string) int {
+	for _, r := range whenRules {
+		if m := r.re.FindStringSubmatch(rest); m != nil && m[0] != "" {
+			r.fn(s, m)
+			return len(m[0])
+		}
+	}
+	return 0
+}
+
+var whenFiller = regexp.MustCompile(`^(?:[\s,;]+|(?:at|on|the|of|and|from|starting|around|about)\b|的|在|于|和|及|、|起)`)
+
+const (
+	enNum      = `\d+|an?|one|two|three|four|five|six|seven|eight|nine|ten|eleven|twelve|fifteen|twenty|thirty|other`
+	enUnit     = `seconds?|secs?|minutes?|mins?|hours?|hrs?|days?|weeks?`
+	enWeekday  = `monday|mon|tuesday|tues|tue|wednesday|wed|thursday|thurs|thu|friday|fri|saturday|sat|sunday|sun`
+	enMonth    = `jan(?:uary)?|feb(?:ruary)?|mar(?:ch)?|apr(?:il)?|may|june?|july?|aug(?:ust)?|sept?(?:ember)?|oct(?:ober)?|nov(?:ember)?|dec(?:ember)?`
+	zhUnit     = `秒钟?|分钟?|小时|钟头|天|日|周|星期|礼拜`
+	zhWeekday  = `[1-7日天]`
+	zhWeekWord = `(?:周|星期|礼拜)`
+)
+
+var whenRules = []whenRule{
+	// Intervals
+	rule(`(?:every|each) half(?: an)? hour\b`, func(s *whenSpec, m []string) { s.setEvery(30 * time.Minute) }),
+	rule(`(?:every|each) (?:(`+enNum+`) )?(seconds?|secs?|minutes?|mins?|hours?|hrs?)\b`, func(s *whenSpec, m []string) {
+		s.setEvery(time.Duration(enNumber(m[1], 1)) * unitOf(m[2]))
+	}),
+	rule(`hourly\b`, func(s *whenSpec, m []string) { s.setEvery(time.Hour) }),
+	rule(`每隔?(\d+|半)?个?(秒钟?|分钟?|小时|钟头)`, func(s *whenSpec, m []string) {
+		if m[1] == "半" {
+			s.setEvery(unitOf(m[2]) / 2)
+			return
+		}
+		s.setEvery(time.Duration(enNumber(m[1], 1)) * unitOf(m[2]))
+	}),
+
+	// Calendar recurrence
+	rule(`(?:every|each) (?:(`+enNum+`) )?days?\b|daily\b|everyday\b`, func(s *whenSpec, m []string) { s.setDaily(enNumber(m[1], 1)) }),
+	rule(`每隔?(\d+)?(?:天|日)|天天`, func(s *whenSpec, m []string) { s.setDaily(enNumber(m[1], 1)) }),
+	rule(`(?:every|each) (morning|afternoon|evening|night)\b`, func(s *whenSpec, m []string) {
+		s.setDaily(1)
+		s.setPeriod(m[1])
+	}),
+	rule(`(?:(?:every|each|on) )?(?:week ?days|workdays|business days|working days)\b|(?:every|each) (?:week ?day|workday|business day|working day)\b`, func(s *whenSpec, m []string) {
+		s.setWeekdays(time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday)
+	}),
+	rule(`每个?工作日|工作日(?:每天)?|(?:每)?`+zhWeekWord+`1(?:到|至|-|~)`+zhWeekWord+`?5`, func(s *whenSpec, m []string) {
+		s.setWeekdays(time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday)
+	}),
+	rule(`(?:(?:every|each|on) )?weekends\b|(?:every|each) weekend\b|每个?周末`, func(s *whenSpec, m []string) {
+		s.setWeekdays(time.Saturday, time.Sunday)
+	}),
+	rule(`(?:every|each) (?:(`+enNum+`) )?weeks?\b|weekly\b`, func(s *whenSpec, m []string) {
+		if enNumber(m[1], 1) != 1 {
+			s.fail("only weekly schedules are supported, not every %s weeks", m[1])
+		}
+		s.repeat, s.weekly = true, true
+	}),
+	rule(`每个?`+zhWeekWord+`((?:`+zhWeekday+`[、,和及]?)*)`, func(s *whenSpec, m []string) {
+		s.repeat, s.weekly = true, true
+		for _, r := range strings.Trim(m[1], "、,和及") {
+			if r != '、' && r != ',' && r != '和' && r != '及' {
+				s.weekdays = append(s.weekdays, zhWeekdayOf(string(r)))
+			}
+		}
+	}),
+	rule(`(?:the )?(?:last day|end) of (the|every|each|this) month\b`, func(s *whenSpec, m []string) {
+		s.monthDay = -1
+		if m[1] != "this" {
+			s.repeat, s.monthly = true, true
+		}
+	}),
+	rule(`(每个?月|本月|这个月|当月)?(?:的)?(?:最后1天|月底|月末)`, func(s *whenSpec, m []string) {
+		s.monthDay = -1
+		if strings.HasPrefix(m[1], "每") {
+			s.repeat, s.monthly = true, true
+		}
+	}),
+	rule(`(?:the )?last (`+enWeekday+`) of (?:the|every|each) month\b`, func(s *whenSpec, m []string) {
+		s.repeat, s.monthly, s.lastWeekday = true, true, true
+		s.weekdays = []time.Weekday{enWeekdayOf(m[1])}
+	}),
+	rule(`(?:every|each) months?\b|monthly\b|of the month\b`, func(s *whenSpec, m []string) { s.repeat, s.monthly = true, true }),
+	rule(`每个?月(?:的)?(?:(\d{1,2})[号日])?`, func(s *whenSpec, m []string) {
+		s.repeat, s.monthly = true, true
+		if m[1] != "" {
+			s.setMonthDay(m[1])
+		}
+	}),
+
+	// Days
+	rule(`(?:(every|each|next|this|on) )?(`+enWeekday+`)(s)?\b`, func(s *whenSpec, m []string) {
+		wd := enWeekdayOf(m[2])
+		switch {
+		case m[1] == "every" || m[1] == "each" || m[3] != "" || (s.repeat && s.weekly):
+			s.repeat, s.weekly = true, true
+			s.weekdays = append(s.weekdays, wd)
+		case m[1] == "next":
+			s.setWeekday(wd, weekdayAfter, 0)
+		default:
+			s.setWeekday(wd, weekdayUpcoming, 0)
+		}
+	}),
+	rule(`(下下个?|下个?|这个?|本)?`+zhWeekWord+`(`+zhWeekday+`)`, func(s *whenSpec, m []string) {
+		wd := zhWeekdayOf(m[2])
+		switch {
+		case s.repeat && s.weekly:
+			s.weekdays = append(s.weekdays, wd)
+		case strings.HasPrefix(m[1], "下下"):
+			s.setWeekday(wd, weekdayInWeek, 2)
+		case strings.HasPrefix(m[1], "下"):
+			s.setWeekday(wd, weekdayInWeek, 1)
+		case m[1] != "":
+			s.setWeekday(wd, weekdayInWeek, 0)
+		default:
+			s.setWeekday(wd, weekdayUpcoming, 0)
+		}
+	}),
+	rule(`weekend\b|周末`, func(s *whenSpec, m []string) { s.setWeekday(time.Saturday, weekdayUpcoming, 0) }),
+	rule(`(?:the )?day after tomorrow\b|大后天|后天`, func(s *whenSpec, m []string) {
+		if m[0] == "大后天" {
+			s.setDays(3)
+		} else {
+			s.setDays(2)
+		}
+	}),
+	rule(`today\b|tonight\b|tomorrow\b|tmrw?\b|今天|今日|今早|今晚|明天|明日|明早|明晚`, func(s *whenSpec, m []string) {
+		switch m[0] {
+		case "today", "tonight", "今天", "今日", "今早", "今晚":
+			s.setDays(0)
+		default:
+			s.setDays(1)
+		}
+		switch m[0] {
+		case "tonight", "今晚", "明晚":
+			s.period = periodNight
+		case "今早", "明早":
+			s.period = periodMorning
+		}
+	}),
+	rule(`(\d{4})-(\d{1,2})-(\d{1,2})`, func(s *whenSpec, m []string) { s.setDate(m[1], m[2], m[3]) }),
+	rule(`(?:(\d{4})年)?(\d{1,2})月(\d{1,2})[日号]`, func(s *whenSpec, m []string) { s.setDate(m[1], m[2], m[3]) }),
+	rule(`(`+enMonth+`)\.? (\d{1,2})(?:st|nd|rd|th)?\b`, func(s *whenSpec, m []string) {
+		s.setDate("", strconv.Itoa(int(enMonthOf(m[1]))), m[2])
+	}),
+	rule(`(\d{1,2})(?:st|nd|rd|th)? (?:of )?(`+enMonth+`)\b`, func(s *whenSpec, m []string) {
+		s.setDate("", strconv.Itoa(int(enMonthOf(m[2]))), m[1])
+	}),
+	rule(`(\d{1,2})(?:st|nd|rd|th)\b|(\d{1,2})[号日]`, func(s *whenSpec, m []string) { s.setMonthDay(m[1] + m[2]) }),
+
+	// Relative times
+	rule(`(?:in|after) (`+enNum+`|half an?) (`+enUnit+`)\b(?: from now| later)?|(`+enNum+`) (`+enUnit+`) (?:from now|later)\b`, func(s *whenSpec, m []string) {
+		num, unit := m[1], m[2]
+		if num == "" {
+			num, unit = m[3], m[4]
+		}
+		if strings.HasPrefix(num, "half") {
+			s.setIn(unitOf(unit) / 2)
+			return
+		}
+		s.setIn(time.Duration(enNumber(num, 1)) * unitOf(unit))
+	}),
+	rule(`(?:in |after )?half an? hour\b(?: from now| later)?`, func(s *whenSpec, m []string) { s.setIn(30 * time.Minute) }),
+	rule(`(\d+|半)个?(`+zhUnit+`)(?:以后|之后|后)`, func(s *whenSpec, m []string) {
+		if m[1] == "半" {
+			s.setIn(unitOf(m[2]) / 2)
+			return
+		}
+		s.setIn(time.Duration(enNumber(m[1], 1)) * unitOf(m[2]))
+	}),
+
+	// Times of day
+	rule(`noon\b|midday\b`, func(s *whenSpec, m []string) { s.setTime(12, 0) }),
+	rule(`midnight\b`, func(s *whenSpec, m []string) { s.setTime(0, 0) }),
+	rule(`(?:in the )?(morning|afternoon|evening|night)\b`, func(s *whenSpec, m []string) { s.setPeriod(m[1]) }),
+	rule(`早上|早晨|清晨|上午|凌晨|中午|下午|傍晚|晚上|夜里|夜间`, func(s *whenSpec, m []string) { s.setPeriod(m[0]) }),
+	rule(`(\d{1,2})[点时](?:(\d{1,2})分?|(半)|(1刻)|(3刻))?钟?`, func(s *whenSpec, m []string) {
+		h, _ := strconv.Atoi(m[1])
+		min := 0
+		switch {
+		case m[2] != "":
+			min, _ = strconv.Atoi(m[2])
+		case m[3] != "":
+			min = 30
+		case m[4] != "":
+			min = 15
+		case m[5] != "":
+			min = 45
+		}
+		s.setTime(h, min)
+	}),
+	rule(`(\d{1,2})(?::(\d{2}))?(?: ?(am|pm|o'?clock))?\b`, func(s *whenSpec, m []string) {
+		h, _ := strconv.Atoi(m[1])
+		min, _ := strconv.Atoi(m[2])
+		switch m[3] {
+		case "am", "pm":
+			if h < 1 || h > 12 {
+				s.fail("%s%s is not a valid time", m[1], m[3])
+				return
+			}
+			h %= 12
+			if m[3] == "pm" {
+				h += 12
+			}
+		}
+		s.setTime(h, min)
+	}),
+}
+
+func (s *whenSpec) fail(format string, args ...interface{}) {
+	if s.err == nil {
+		s.err = fmt.Errorf(format, args...)
+	}
+}
+
+func (s *whenSpec) setEvery(d time.Duration) {
+	if d <= 0 {
+		s.fail("the interval must be positive")
+	}
+	s.every = d
+}
+
+func (s *whenSpec) setDaily(n int) {
+	if n < 1 {
+		s.fail("the interval must be positive")
+	}
+	s.repeat = true
+	if n > 1 {
+		s.everyDays = n
+	}
+}
+
+func (s *whenSpec) setWeekdays(days ...time.Weekday) {
+	s.repeat, s.weekly = true, true
+	s.weekdays = append(s.weekdays, days...)
+}
+
+func (s *whenSpec) setWeekday(wd time.Weekday, mode, offset int) {
+	if s.hasWeekday || s.hasDate {
+		s.fail("more than one day given")
+	}
+	s.weekday, s.weekdayMode, s.weekOffset, s.hasWeekday = wd, mode, offset, true
+}
+
+func (s *whenSpec) setDays(offset int) {
+	if s.hasWeekday || s.hasDate {
+		s.fail("more than one day given")
+	}
+	y, mo, d := s.now.Date()
+	s.date = time.Date(y, mo, d+offset, 0, 0, 0, 0, s.now.Location())
+	s.hasDate = true
+}
+
+func (s *whenSpec) setDate(year, month, day string) {
+	if s.hasWeekday || s.hasDate {
+		s.fail("more than one day given")
+	}
+	mo, _ := strconv.Atoi(month)
+	d, _ := strconv.Atoi(day)
+	y := s.now.Year()
+	if year != "" {
+		y, _ = strconv.Atoi(year)
+	}
+	date := time.Date(y, time.Month(mo), d, 0, 0, 0, 0, s.now.Location())
+	if mo < 1 || mo > 12 || date.Day() != d {
+		s.fail("%s-%s is not a valid date", month, day)
+		return
+	}
+	// Without a year, a date that has passed means next year's
+	y0, m0, d0 := s.now.Date()
+	if year == "" && date.Before(time.Date(y0, m0, d0, 0, 0, 0, 0, s.now.Location())) {
+		date = date.AddDate(1, 0, 0)
+	}
+	s.date, s.hasDate = date, true
+}
+
+func (s *whenSpec) setMonthDay(day string) {
+	d, _ := strconv.Atoi(day)
+	if d < 1 || d > 31 {
+		s.fail("%s is not a day of the month", day)
+	}
+	s.monthDay = d
+}
+
+func (s *whenSpec) setIn(d time.Duration) {
+	if d <= 0 {
+		s.fail("the delay must be positive")
+	}
+	s.in = d
+}
+
+func (s *whenSpec) setTime(h, m int) {
+	if s.hasTime {
+		s.fail("more than one time given")
+	}
+	if h > 23 || m > 59 {
+		s.fail("%d:%02d is not a valid time", h, m)
+	}
+	s.hour, s.minute, s.hasTime = h, m, true
+}
+
+func (s *whenSpec) setPeriod(word string) {
+	switch word {
+	case "morning", "早上", "早晨", "清晨", "上午", "凌晨":
+		s.period = periodMorning
+	case "中午":
+		s.period = periodNoon
+	case "afternoon", "下午":
+		s.period = periodAfternoon
+	default:
+		s.period = periodNight
+	}
+}
+
+// clock returns the time of day: the one given, adjusted for the part of
+// the day, or a default for that part (09:00 when nothing was said).
+func (s *whenSpec) clock() (int, int) {
+	if !s.hasTime {
+		switch s.period {
+		case periodMorning:
+			return 8, 0
+		case periodNoon:
+			return 12, 0
+		case periodAfternoon:
+			return 15, 0
+		case periodNight:
+			return 20, 0
+		}
+		return 9, 0
+	}
+	h := s.hour
+	switch s.period {
+	case periodMorning:
+		if h == 12 {
+			h = 0
+		}
+	case periodNoon:
+		if h < 6 {
+			h += 12
+		}
+	case periodAfternoon:
+		if h < 12 {
+			h += 12
+		}
+	case periodNight:
+		if h == 12 {
+			h = 0
+		} else if h >= 5 && h < 12 {
+			h += 12
+		}
+	}
+	return h, s.minute
+}
+
+func (s *whenSpec) hasCalendar() bool {
+	return s.hasDate || s.hasWeekday || s.monthDay != 0 || s.hasTime || s.period != periodNone || len(s.weekdays) > 0 || s.monthly
+}
+
+func (s *whenSpec) schedule() (CronSchedule, string, error) {
+	switch {
+	case s.every > 0:
+		if s.repeat || s.in > 0 || s.hasCalendar() {
+			return CronSchedule{}, "", fmt.Errorf("an interval like \"every 2 hours\" can't be combined with days or times")
+		}
+		ms := s.every.Milliseconds()
+		return CronSchedule{Kind: "every", EveryMS: &ms}, "every " + describeInterval(s.every), nil
+
+	case s.everyDays > 1:
+		if s.in > 0 || s.hasCalendar() {
+			return CronSchedule{}, "", fmt.Errorf("\"every %d days\" can't have a day or time; use a daily or weekly schedule instead", s.everyDays)
+		}
+		d := time.Duration(s.everyDays) * 24 * time.Hour
+		ms := d.Milliseconds()
+		return CronSchedule{Kind: "every", EveryMS: &ms}, "every " + describeInterval(d), nil
+
+	case s.in > 0:
+		if s.repeat || s.hasDate || s.hasWeekday || s.monthDay != 0 {
+			return CronSchedule{}, "", fmt.Errorf("a delay like \"in 10 minutes\" can't be combined with days")
+		}
+		at := s.now.Add(s.in)
+		if s.hasTime || s.period != periodNone {
+			h, m := s.clock()
+			y, mo, d := at.Date()
+			at = time.Date(y, mo, d, h, m, 0, 0, s.now.Location())
+			if !at.After(s.now) {
+				return CronSchedule{}, "", fmt.Errorf("%s is in the past", at.Format("2006-01-02 15:04"))
+			}
+		}
+		return s.once(at)
+
+	case s.repeat:
+		return s.recurring()
+
+	default:
+		return s.oneShot()
+	}
+}
+
+func (s *whenSpec) recurring() (CronSchedule, string, error) {
+	if s.hasDate || s.hasWeekday {
+		return CronSchedule{}, "", fmt.Errorf("a repeating schedule can't be on a single date")
+	}
+	h, m := s.clock()
+	at := fmt.Sprintf("%02d:%02d", h, m)
+	dom, dow := "*", "*"
+	var desc, note string
+
+	switch {
+	case s.monthly && s.lastWeekday:
+		dow = fmt.Sprintf("%dL", s.weekdays[0])
+		desc = fmt.Sprintf("on the last %s of every month at %s", s.weekdays[0], at)
+	case s.monthly:
+		if len(s.weekdays) > 0 {
+			return CronSchedule{}, "", fmt.Errorf("a monthly schedule can't also be on weekdays")
+		}
+		day := s.monthDay
+		if day == 0 {
+			day = s.now.Day()
+		}
+		if day == -1 {
+			dom = "L"
+			desc = "on the last day of every month at " + at
+		} else {
+			dom = strconv.Itoa(day)
+			desc = fmt.Sprintf("on day %d of every month at %s", day, at)
+			if day > 28 {
+				note = ", skipped in shorter months"
+			}
+		}
+	case s.monthDay != 0:
+		return CronSchedule{}, "", fmt.Errorf("a day of the month needs \"every month\"")
+	case s.weekly:
+		days := s.weekdays
+		if len(days) == 0 {
+			days = []time.Weekday{s.now.Weekday()}
+		}
+		days = uniqueWeekdays(days)
+		parts := make([]string, len(days))
+		names := make([]string, len(days))
+		for i, d := range days {
+			parts[i] = strconv.Itoa(int(d))
+			names[i] = d.String()
+		}
+		dow = strings.Join(parts, ",")
+		switch dow {
+		case "1,2,3,4,5":
+			desc = "every weekday (Monday to Friday) at " + at
+		case "0,6":
+			desc = "every Saturday and Sunday at " + at
+		default:
+			desc = "every " + joinList(names) + " at " + at
+		}
+	default:
+		desc = "every day at " + at
+	}
+
+	tz := s.now.Location().String()
+	if tz == "Local" {
+		tz = ""
+	}
+	schedule := CronSchedule{
+		Kind: "cron",
+		Expr: fmt.Sprintf("%d %d %s * %s", m, h, dom, dow),
+		TZ:   tz,
+	}
+	return schedule, desc + zoneSuffix(s.now) + note, nil
+}
+
+func (s *whenSpec) oneShot() (CronSchedule, string, error) {
+	h, m := s.clock()
+	loc := s.now.Location()
+	y, mo, d := s.now.Date()
+	today := time.Date(y, mo, d, 0, 0, 0, 0, loc)
+	atOn := func(day time.Time) time.Time {
+		return time.Date(day.Year(), day.Month(), day.Day(), h, m, 0, 0, loc)
+	}
+
+	var at time.Time
+	switch {
+	case s.hasDate:
+		at = atOn(s.date)
+	case s.hasWeekday:
+		switch s.weekdayMode {
+		case weekdayInWeek:
+			monday := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+			at = atOn(monday.AddDate(0, 0, 7*s.weekOffset+(int(s.weekday)+6)%7))
+		default:
+			ahead := (int(s.weekday) - int(today.Weekday()) + 7) % 7
+			if ahead == 0 && (s.weekdayMode == weekdayAfter || !atOn(today).After(s.now)) {
+				ahead = 7
+			}
+			at = atOn(today.AddDate(0, 0, ahead))
+		}
+	case s.monthDay != 0:
+		for i := 0; i < 12 && (at.IsZero() || !at.After(s.now)); i++ {
+			first := time.Date(y, mo+time.Month(i), 1, 0, 0, 0, 0, loc)
+			day := s.monthDay
+			if day == -1 {
+				day = first.AddDate(0, 1, -1).Day()
+			}
+			if candidate := first.AddDate(0, 0, day-1); candidate.Month() == first.Month() {
+				at = atOn(candidate)
+			}
+		}
+	case s.hasTime || s.period != periodNone:
+		// A bare time means the next one
+		at = atOn(today)
+		if !at.After(s.now) {
+			at = atOn(today.AddDate(0, 0, 1))
+		}
+	default:
+		return CronSchedule{}, "", fmt.Errorf("no day or time given")
+	}
+
+	if !at.After(s.now) {
+		return CronSchedule{}, "", fmt.Errorf("%s is in the past", at.Format("2006-01-02 15:04"))
+	}
+	return s.once(at)
+}
+
+func (s *whenSpec) once(at time.Time) (CronSchedule, string, error) {
+	ms := at.UnixMilli()
+	return CronSchedule{Kind: "at", AtMS: &ms}, "once, on " + at.Format("Mon 2006-01-02 at 15:04") + zoneSuffix(s.now), nil
+}
+
+func zoneSuffix(now time.Time) string {
+	name := now.Location().String()
+	if name == "Local" {
+		name = now.Format("MST")
+	}
+	return " (" + name + ")"
+}
+
+func describeInterval(d time.Duration) string {
+	unit := func(n int64, name string) string {
+		if n == 1 {
+			return name
+		}
+		return fmt.Sprintf("%d %ss", n, name)
+	}
+	switch {
+	case d%(24*time.Hour) == 0:
+		return unit(int64(d/(24*time.Hour)), "day")
+	case d%time.Hour == 0:
+		return unit(int64(d/time.Hour), "hour")
+	case d%time.Minute == 0:
+		return unit(int64(d/time.Minute), "minute")
+	default:
+		return unit(int64(d/time.Second), "second")
+	}
+}
+
+func joinList(items []string) string {
+	if len(items) < 2 {
+		return strings.Join(items, "")
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " and " + items[len(items)-1]
+}
+
+func uniqueWeekdays(days []time.Weekday) []time.Weekday {
+	var seen [7]bool
+	var out []time.Weekday
+	// Monday first, Sunday last, as people list them
+	for _, d := range []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday} {
+		for _, want := range days {
+			if want == d && !seen[d] {
+				seen[d] = true
+				out = append(out, d)
+			}
+		}
+	}
+	if seen[time.Saturday] && seen[time.Sunday] && len(out) == 2 {
+		return []time.Weekday{time.Sunday, time.Saturday}
+	}
+	return out
+}
+
+func unitOf(u string) time.Duration {
+	switch {
+	case strings.HasPrefix(u, "s"), strings.HasPrefix(u, "秒"):
+		return time.Second
+	case strings.HasPrefix(u, "m"), strings.HasPrefix(u, "分"):
+		return time.Minute
+	case strings.HasPrefix(u, "h"), u == "小时", u == "钟头":
+		return time.Hour
+	case strings.HasPrefix(u, "d"), u == "天", u == "日":
+		return 24 * time.Hour
+	default:
+		return 7 * 24 * time.Hour
+	}
+}
+
+var enNumbers = map[string]int{
+	"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8,
+	"nine": 9, "ten": 10, "eleven": 11, "twelve": 12, "fifteen": 15, "twenty": 20, "thirty": 30, "other": 2,
+}
+
+func enNumber(s string, def int) int {
+	if s == "" {
+		return def
+	}
+	if n, ok := enNumbers[s]; ok {
+		return n
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+func enWeekdayOf(s string) time.Weekday {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.HasPrefix(strings.ToLower(d.String()), s[:3]) {
+			return d
+		}
+	}
+	return time.Sunday
+}
+
+func enMonthOf(s string) time.Month {
+	for m := time.January; m <= time.December; m++ {
+		if strings.HasPrefix(strings.ToLower(m.String()), s[:3]) {
+			return m
+		}
+	}
+	return time.January
+}
+
+func zhWeekdayOf(s string) time.Weekday {
+	if s == "日" || s == "天" || s == "7" {
+		return time.Sunday
+	}
+	n, _ := strconv.Atoi(s)
+	return time.Weekday(n)
+}
+
+var (
+	whenSpaces    = regexp.MustCompile(`\s+`)
+	zhNumeralRun  = regexp.MustCompile(`[零〇一二两三四五六七八九十]+`)
+	zhDigitValues = map[rune]int{'零': 0, '〇': 0, '一': 1, '二': 2, '两': 2, '三': 3, '四': 4, '五': 5, '六': 6, '七': 7, '八': 8, '九': 9}
+)
+
+// normalizeWhen lowercases the phrase, folds full-width characters and
+// writes Chinese numerals as digits, so the rules only deal with one form.
+func normalizeWhen(text string) string {
+	text = strings.ToLower(strings.TrimSpace(text))
+	text = strings.Map(func(r rune) rune {
+		switch {
+		case r >= '０' && r <= '９':
+			return '0' + (r - '０')
+		case r == '：':
+			return ':'
+		case r == '，':
+			return ','
+		case r == '　':
+			return ' '
+		}
+		return r
+	}, text)
+	text = strings.NewReplacer("a.m.", "am", "p.m.", "pm").Replace(text)
+	text = zhNumeralRun.ReplaceAllStringFunc(text, func(run string) string {
+		return strconv.Itoa(zhNumber(run))
+	})
+	return whenSpaces.ReplaceAllString(text, " ")
+}
+
+// zhNumber reads a Chinese numeral below 100 ("十五", "二十三") or a run of
+// digits ("二〇二六").
+func zhNumber(run string) int {
+	if tens, ones, ok := strings.Cut(run, "十"); ok {
+		t := 1
+		if tens != "" {
+			t = zhNumber(tens)
+		}
+		o := 0
+		if ones != "" {
+			o = zhNumber(ones)
+		}
+		return t*10 + o
+	}
+	n := 0
+	for _, r := range run {
+		n = n*10 + zhDigitValues[r]
+	}
+	return n
+}
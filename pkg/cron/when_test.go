@@ -0,0 +1,120 @@
+package cron
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adhocore/gronx"
+)
+
+func TestParseWhen(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skip("no tzdata")
+	}
+	// Saturday morning
+	now := time.Date(2026, 10, 17, 10, 30, 0, 0, loc)
+
+	tests := []struct {
+		text string
+		kind string
+		want string // interval, cron expression or one-shot time
+	}{
+		{"every 2 hours", "every", "2h0m0s"},
+		{"every half hour", "every", "30m0s"},
+		{"every other day", "every", "48h0m0s"},
+		{"每隔30分钟", "every", "30m0s"},
+		{"every weekday at 9", "cron", "0 9 * * 1,2,3,4,5"},
+		{"weekdays 9am", "cron", "0 9 * * 1,2,3,4,5"},
+		{"every monday and thursday at 8:15", "cron", "15 8 * * 1,4"},
+		{"every sunday evening", "cron", "0 20 * * 0"},
+		{"last day of the month", "cron", "0 9 L * *"},
+		{"the last day of every month at 6pm", "cron", "0 18 L * *"},
+		{"last friday of the month", "cron", "0 9 * * 5L"},
+		{"every month on the 15th at noon", "cron", "0 12 15 * *"},
+		{"每天早上8点", "cron", "0 8 * * *"},
+		{"每天晚上十一点半", "cron", "30 23 * * *"},
+		{"每周一三五下午3点", "cron", "0 15 * * 1,3,5"},
+		{"周一到周五早上9点", "cron", "0 9 * * 1,2,3,4,5"},
+		{"每月15号上午十点", "cron", "0 10 15 * *"},
+		{"每月最后一天", "cron", "0 9 L * *"},
+		{"in 10 minutes", "at", "2026-10-17 10:40"},
+		{"2 hours from now", "at", "2026-10-17 12:30"},
+		{"半小时后", "at", "2026-10-17 11:00"},
+		{"next Friday 18:00", "at", "2026-10-23 18:00"},
+		{"friday", "at", "2026-10-23 09:00"},
+		{"tomorrow at 7:30am", "at", "2026-10-18 07:30"},
+		{"at 9", "at", "2026-10-18 09:00"},
+		{"dec 25 at noon", "at", "2026-12-25 12:00"},
+		{"tonight", "at", "2026-10-17 20:00"},
+		{"今晚10点", "at", "2026-10-17 22:00"},
+		{"下周五晚上8点", "at", "2026-10-23 20:00"},
+		{"3月5日", "at", "2027-03-05 09:00"},
+	}
+	for _, tt := range tests {
+		s, desc, err := ParseWhen(tt.text, now)
+		if err != nil {
+			t.Errorf("%q: %v", tt.text, err)
+			continue
+		}
+		if s.Kind != tt.kind {
+			t.Errorf("%q: kind = %s, want %s", tt.text, s.Kind, tt.kind)
+			continue
+		}
+		var got string
+		switch s.Kind {
+		case "every":
+			got = (time.Duration(*s.EveryMS) * time.Millisecond).String()
+		case "cron":
+			got = s.Expr
+			if s.TZ != "Asia/Shanghai" {
+				t.Errorf("%q: tz = %q", tt.text, s.TZ)
+			}
+			if _, err := gronx.NextTickAfter(s.Expr, now, false); err != nil {
+				t.Errorf("%q: %s does not evaluate: %v", tt.text, s.Expr, err)
+			}
+		case "at":
+			got = time.UnixMilli(*s.AtMS).In(loc).Format("2006-01-02 15:04")
+		}
+		if got != tt.want {
+			t.Errorf("%q: got %s, want %s (%s)", tt.text, got, tt.want, desc)
+		}
+		if desc == "" {
+			t.Errorf("%q: no description", tt.text)
+		}
+	}
+}
+
+func TestParseWhenRejects(t *testing.T) {
+	now := time.Date(2026, 10, 17, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"", "empty"},
+		{"every weekday at 9 except holidays", `"except"`},
+		{"every day at 25:00", "not a valid time"},
+		{"2026-10-16 9:00", "in the past"},
+		{"every 2 hours at 9", "can't be combined"},
+		{"every 3 days at 8", "every 3 days"},
+		{"feb 30", "not a valid date"},
+	}
+	for _, tt := range tests {
+		_, _, err := ParseWhen(tt.text, now)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: err = %v, want %q", tt.text, err, tt.want)
+		}
+	}
+}
+
+func TestParseWhenDescribes(t *testing.T) {
+	now := time.Date(2026, 10, 17, 10, 30, 0, 0, time.UTC)
+	_, desc, err := ParseWhen("every weekday at 9", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "every weekday (Monday to Friday) at 09:00 (UTC)"; desc != want {
+		t.Errorf("desc = %q, want %q", desc, want)
+	}
+}
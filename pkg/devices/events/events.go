@@ -1,6 +1,10 @@
 package events
 
-import "context"
+import (
+	"context"
+
+	"github.com/sipeed/picoclaw/pkg/i18n"
+)
 
 type EventSource interface {
 	Kind() Kind
@@ -36,22 +40,21 @@ type DeviceEvent struct {
 	Raw          map[string]string // Raw properties for extensibility
 }
 
-func (e *DeviceEvent) FormatMessage() string {
-	actionEmoji := "🔌"
-	actionText := "Connected"
+// FormatMessage describes the event for a user with the given locale.
+func (e *DeviceEvent) FormatMessage(locale string) string {
+	title := i18n.DeviceConnected
 	if e.Action == ActionRemove {
-		actionEmoji = "🔌"
-		actionText = "Disconnected"
+		title = i18n.DeviceDisconnected
 	}
 
-	msg := actionEmoji + " Device " + actionText + "\n\n"
-	msg += "Type: " + string(e.Kind) + "\n"
-	msg += "Device: " + e.Vendor + " " + e.Product + "\n"
+	msg := i18n.T(locale, title) + "\n\n"
+	msg += i18n.T(locale, i18n.DeviceType, string(e.Kind)) + "\n"
+	msg += i18n.T(locale, i18n.DeviceName, e.Vendor+" "+e.Product) + "\n"
 	if e.Capabilities != "" {
-		msg += "Capabilities: " + e.Capabilities + "\n"
+		msg += i18n.T(locale, i18n.DeviceCapabilities, e.Capabilities) + "\n"
 	}
 	if e.Serial != "" {
-		msg += "Serial: " + e.Serial + "\n"
+		msg += i18n.T(locale, i18n.DeviceSerial, e.Serial) + "\n"
 	}
 	return msg
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/sipeed/picoclaw/pkg/constants"
 	"github.com/sipeed/picoclaw/pkg/devices/events"
 	"github.com/sipeed/picoclaw/pkg/devices/sources"
+	"github.com/sipeed/picoclaw/pkg/i18n"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/state"
 )
@@ -116,7 +117,7 @@ func (s *Service) sendNotification(ev *events.DeviceEvent) {
 	lastChannel := s.state.GetLastChannel()
 	if lastChannel == "" {
 		logger.DebugCF("devices", "No last channel, skipping notification", map[string]interface{}{
-			"event": ev.FormatMessage(),
+			"event": ev.FormatMessage(""),
 		})
 		return
 	}
@@ -126,7 +127,7 @@ func (s *Service) sendNotification(ev *events.DeviceEvent) {
 		return
 	}
 
-	msg := ev.FormatMessage()
+	msg := ev.FormatMessage(i18n.ForChat(platform, userID))
 	msgBus.PublishOutbound(bus.OutboundMessage{
 		Channel: platform,
 		ChatID:  userID,
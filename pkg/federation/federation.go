@@ -0,0 +1,310 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// Inspired by and based on nanobot: https://github.com/HKUDS/nanobot
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+// Package federation lets PicoClaw instances hand tasks to each other. Each
+// instance serves an A2A-compatible JSON-RPC endpoint on the gateway and
+// calls its configured peers the same way. A peer pair shares a token that
+// authenticates requests in both directions.
+package federation
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/network"
+)
+
+// Channel is the channel name of turns run for a peer.
+const Channel = "peer"
+
+const (
+	defaultPath        = "/a2a"
+	defaultTaskTimeout = 10 * time.Minute
+	cardRefresh        = 10 * time.Minute
+	// Finished tasks are kept this long for tasks/get.
+	taskRetention = time.Hour
+	maxTasks      = 256
+)
+
+// Executor runs a task as an agent turn. AgentLoop implements it.
+type Executor interface {
+	ProcessDirectWithChannel(ctx context.Context, content, sessionKey, channel, chatID string) (string, error)
+}
+
+// Node is this instance's side of the federation: the endpoint peers call
+// and the clients used to call them.
+type Node struct {
+	cfg     config.FederationConfig
+	exec    Executor
+	path    string
+	timeout time.Duration
+	peers   []*Peer
+	client  *http.Client
+	// poll overrides how often Wait polls a peer; zero uses the defaults.
+	poll time.Duration
+
+	mu      sync.Mutex
+	tasks   map[string]*task
+	waiters map[string]chan *Task // by push notification token
+}
+
+// New creates the node for cfg. Tasks from peers are run by exec.
+func New(cfg config.FederationConfig, exec Executor) *Node {
+	n := &Node{
+		cfg:     cfg,
+		exec:    exec,
+		path:    strings.TrimRight(cfg.Path, "/"),
+		timeout: time.Duration(cfg.TaskTimeout) * time.Second,
+		client:  network.NewClient(network.ComponentFederation, network.Options{Timeout: 30 * time.Second}),
+		tasks:   make(map[string]*task),
+		waiters: make(map[string]chan *Task),
+	}
+	if n.path == "" {
+		n.path = defaultPath
+	}
+	if n.timeout <= 0 {
+		n.timeout = defaultTaskTimeout
+	}
+	for _, pc := range cfg.Peers {
+		n.peers = append(n.peers, newPeer(pc, n.timeout))
+	}
+	return n
+}
+
+// Name returns the name this instance advertises.
+func (n *Node) Name() string {
+	return n.cfg.Name
+}
+
+// Path returns the URL path of the endpoint on the gateway.
+func (n *Node) Path() string {
+	return n.path
+}
+
+// Peers returns the configured peers.
+func (n *Node) Peers() []*Peer {
+	return n.peers
+}
+
+// Peer returns the peer with the given name.
+func (n *Node) Peer(name string) (*Peer, bool) {
+	for _, p := range n.peers {
+		if strings.EqualFold(p.Name, name) {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// Start fetches the peers' agent cards now and then periodically, until
+// ctx is done.
+func (n *Node) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(cardRefresh)
+		defer ticker.Stop()
+		for {
+			n.RefreshCards(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// RefreshCards fetches the agent card of every peer. Peers that cannot be
+// reached keep their last card.
+func (n *Node) RefreshCards(ctx context.Context) {
+	for _, p := range n.peers {
+		if _, err := p.FetchCard(ctx); err != nil {
+			logger.WarnCF("federation", "Failed to fetch peer card", map[string]interface{}{
+				"peer":  p.Name,
+				"error": err.Error(),
+			})
+		}
+	}
+}
+
+// Card returns the agent card this instance serves.
+func (n *Node) Card() AgentCard {
+	card := AgentCard{
+		Name:               n.cfg.Name,
+		Description:        n.cfg.Description,
+		Version:            "1.0",
+		ProtocolVersion:    ProtocolVersion,
+		Capabilities:       Capabilities{PushNotifications: true},
+		DefaultInputModes:  []string{"text/plain"},
+		DefaultOutputModes: []string{"text/plain"},
+		Skills:             []Skill{},
+	}
+	if n.cfg.PublicURL != "" {
+		card.URL = strings.TrimRight(n.cfg.PublicURL, "/")
+	}
+	for _, c := range n.cfg.Capabilities {
+		card.Skills = append(card.Skills, Skill{
+			ID:          slug(c),
+			Name:        c,
+			Description: c,
+			Tags:        []string{},
+		})
+	}
+	return card
+}
+
+// authenticate returns the peer whose token the request carries.
+func (n *Node) authenticate(r *http.Request) (*Peer, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return nil, false
+	}
+	for _, p := range n.peers {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(p.token)) == 1 {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// Ask sends text to a peer and waits for the result.
+func (n *Node) Ask(ctx context.Context, peer, text, contextID string) (*Task, error) {
+	p, ok := n.Peer(peer)
+	if !ok {
+		return nil, fmt.Errorf("unknown peer %q", peer)
+	}
+	msg := TextMessage("user", text)
+	msg.ContextID = contextID
+	return p.Send(ctx, MessageSendParams{
+		Message:       msg,
+		Configuration: &SendConfiguration{Blocking: true},
+	})
+}
+
+// Submit sends text to a peer without waiting. Pass the returned task to
+// Wait to get the result. When a public URL is configured the peer pushes
+// the result back; otherwise Wait polls for it.
+func (n *Node) Submit(ctx context.Context, peer, text, contextID string) (*Pending, error) {
+	p, ok := n.Peer(peer)
+	if !ok {
+		return nil, fmt.Errorf("unknown peer %q", peer)
+	}
+	msg := TextMessage("user", text)
+	msg.ContextID = contextID
+	params := MessageSendParams{Message: msg, Configuration: &SendConfiguration{}}
+
+	pending := &Pending{peer: p}
+	if n.cfg.PublicURL != "" {
+		pending.token = newID() + newID()
+		pending.pushed = make(chan *Task, 1)
+		params.Configuration.PushNotificationConfig = &PushConfig{
+			URL:   strings.TrimRight(n.cfg.PublicURL, "/") + "/push",
+			Token: pending.token,
+		}
+		n.mu.Lock()
+		n.waiters[pending.token] = pending.pushed
+		n.mu.Unlock()
+	}
+
+	t, err := p.Send(ctx, params)
+	if err != nil {
+		n.forget(pending)
+		return nil, err
+	}
+	pending.Task = t
+	return pending, nil
+}
+
+// Pending is a task submitted to a peer.
+type Pending struct {
+	Task   *Task
+	peer   *Peer
+	token  string
+	pushed chan *Task
+}
+
+// Wait returns the submitted task once it has finished, either from a push
+// notification or by polling the peer. It gives up after the task timeout.
+func (n *Node) Wait(ctx context.Context, pending *Pending) (*Task, error) {
+	defer n.forget(pending)
+	if pending.Task.Status.State.Terminal() {
+		return pending.Task, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, n.timeout)
+	defer cancel()
+
+	// Push notifications can get lost, so poll slowly even when they are on
+	interval := n.poll
+	if interval == 0 {
+		interval = 5 * time.Second
+		if pending.pushed != nil {
+			interval = 30 * time.Second
+		}
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case t := <-pending.pushed:
+			if t.ID == pending.Task.ID && t.Status.State.Terminal() {
+				return t, nil
+			}
+		case <-ticker.C:
+			t, err := pending.peer.GetTask(ctx, pending.Task.ID)
+			if err != nil {
+				logger.WarnCF("federation", "Failed to poll peer task", map[string]interface{}{
+					"peer":  pending.peer.Name,
+					"task":  pending.Task.ID,
+					"error": err.Error(),
+				})
+				continue
+			}
+			if t.Status.State.Terminal() {
+				return t, nil
+			}
+		case <-ctx.Done():
+			if _, err := pending.peer.CancelTask(context.Background(), pending.Task.ID); err != nil {
+				logger.DebugCF("federation", "Failed to cancel peer task", map[string]interface{}{
+					"task":  pending.Task.ID,
+					"error": err.Error(),
+				})
+			}
+			return nil, fmt.Errorf("task %s on %s: %w", pending.Task.ID, pending.peer.Name, ctx.Err())
+		}
+	}
+}
+
+func (n *Node) forget(pending *Pending) {
+	if pending.token == "" {
+		return
+	}
+	n.mu.Lock()
+	delete(n.waiters, pending.token)
+	n.mu.Unlock()
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+var nonSlug = regexp.MustCompile(`[^a-z0-9]+`)
+
+func slug(s string) string {
+	return strings.Trim(nonSlug.ReplaceAllString(strings.ToLower(s), "-"), "-")
+}
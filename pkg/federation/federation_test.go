@@ -0,0 +1,238 @@
+package federation
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// echoAgent answers every task with its name and the task text.
+type echoAgent struct {
+	name    string
+	block   chan struct{} // when set, turns wait on it or their context
+	mu      sync.Mutex
+	session string
+	chatID  string
+}
+
+func (a *echoAgent) ProcessDirectWithChannel(ctx context.Context, content, sessionKey, channel, chatID string) (string, error) {
+	a.mu.Lock()
+	a.session, a.chatID = sessionKey, chatID
+	a.mu.Unlock()
+	if a.block != nil {
+		select {
+		case <-a.block:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	return a.name + " did: " + content[strings.Index(content, "\n")+1:], nil
+}
+
+type instance struct {
+	node  *Node
+	agent *echoAgent
+	srv   *httptest.Server
+}
+
+// newPair starts two instances, alpha and beta, that know each other.
+// With push set, alpha advertises a public URL so beta pushes results.
+func newPair(t *testing.T, push bool) (alpha, beta *instance) {
+	t.Helper()
+	const token = "alpha-beta-shared-secret"
+	alpha, beta = &instance{agent: &echoAgent{name: "alpha"}}, &instance{agent: &echoAgent{name: "beta"}}
+	for _, in := range []*instance{alpha, beta} {
+		in := in
+		in.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			in.node.ServeHTTP(w, r)
+		}))
+		t.Cleanup(in.srv.Close)
+	}
+
+	alphaCfg := config.FederationConfig{
+		Enabled: true,
+		Name:    "alpha",
+		Path:    "/a2a",
+		Peers:   []config.PeerConfig{{Name: "beta", URL: beta.srv.URL + "/a2a", Token: token}},
+	}
+	if push {
+		alphaCfg.PublicURL = alpha.srv.URL + "/a2a"
+	}
+	alpha.node = New(alphaCfg, alpha.agent)
+	beta.node = New(config.FederationConfig{
+		Enabled:      true,
+		Name:         "beta",
+		Description:  "Runs in the garage",
+		Capabilities: config.FlexibleStringSlice{"Garage door", "Weather station"},
+		Path:         "/a2a",
+		Peers:        []config.PeerConfig{{Name: "alpha", URL: alpha.srv.URL + "/a2a", Token: token}},
+	}, beta.agent)
+	alpha.node.poll = 20 * time.Millisecond
+	return alpha, beta
+}
+
+func TestCardRequiresToken(t *testing.T) {
+	alpha, beta := newPair(t, false)
+
+	resp, err := http.Get(beta.srv.URL + "/.well-known/agent.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("anonymous card request: status %d, want 401", resp.StatusCode)
+	}
+
+	alpha.node.RefreshCards(context.Background())
+	peer, _ := alpha.node.Peer("beta")
+	if peer.Description() != "Runs in the garage" {
+		t.Errorf("description = %q", peer.Description())
+	}
+	caps := peer.Capabilities()
+	if len(caps) != 2 || caps[0] != "Garage door" {
+		t.Errorf("capabilities = %v", caps)
+	}
+}
+
+func TestRejectsWrongToken(t *testing.T) {
+	_, beta := newPair(t, false)
+	intruder := newPeer(config.PeerConfig{Name: "beta", URL: beta.srv.URL + "/a2a", Token: "not-the-shared-secret"}, time.Minute)
+	_, err := intruder.Send(context.Background(), MessageSendParams{Message: TextMessage("user", "open the door")})
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("err = %v, want 401", err)
+	}
+}
+
+func TestAskBlocking(t *testing.T) {
+	alpha, beta := newPair(t, false)
+
+	task, err := alpha.node.Ask(context.Background(), "beta", "check the door", "telegram:42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Status.State != StateCompleted {
+		t.Fatalf("state = %s", task.Status.State)
+	}
+	if got := task.Result(); got != "beta did: check the door" {
+		t.Errorf("result = %q", got)
+	}
+	if beta.agent.session != "peer:alpha:telegram:42" || beta.agent.chatID != "alpha" {
+		t.Errorf("ran in session %q chat %q", beta.agent.session, beta.agent.chatID)
+	}
+
+	if _, err := alpha.node.Ask(context.Background(), "gamma", "hi", ""); err == nil {
+		t.Error("unknown peer should fail")
+	}
+}
+
+func TestAsyncResultIsPushed(t *testing.T) {
+	alpha, beta := newPair(t, true)
+	alpha.node.poll = time.Hour // only the push can deliver
+	beta.agent.block = make(chan struct{})
+
+	pending, err := alpha.node.Submit(context.Background(), "beta", "water the plants", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending.Task.Status.State.Terminal() {
+		t.Fatalf("async task finished early: %s", pending.Task.Status.State)
+	}
+	close(beta.agent.block)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	task, err := alpha.node.Wait(ctx, pending)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Result() != "beta did: water the plants" {
+		t.Errorf("result = %q", task.Result())
+	}
+}
+
+func TestAsyncResultIsPolled(t *testing.T) {
+	alpha, _ := newPair(t, false)
+
+	pending, err := alpha.node.Submit(context.Background(), "beta", "read the thermometer", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	task, err := alpha.node.Wait(ctx, pending)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Status.State != StateCompleted {
+		t.Errorf("state = %s", task.Status.State)
+	}
+}
+
+func TestCancelTask(t *testing.T) {
+	alpha, beta := newPair(t, false)
+	beta.agent.block = make(chan struct{})
+	defer close(beta.agent.block)
+
+	pending, err := alpha.node.Submit(context.Background(), "beta", "wait forever", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	peer, _ := alpha.node.Peer("beta")
+	task, err := peer.CancelTask(context.Background(), pending.Task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Status.State != StateCanceled {
+		t.Errorf("state = %s, want canceled", task.Status.State)
+	}
+	if _, err := peer.CancelTask(context.Background(), pending.Task.ID); err == nil {
+		t.Error("canceling a finished task should fail")
+	}
+
+	// beta cannot see tasks it did not submit to alpha
+	other, _ := beta.node.Peer("alpha")
+	if _, err := other.GetTask(context.Background(), pending.Task.ID); err == nil {
+		t.Error("task should not be visible to another peer")
+	}
+}
+
+func TestPushOnlyToPeerHost(t *testing.T) {
+	alpha, _ := newPair(t, false)
+	var hits atomic.Int32
+	elsewhere := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer elsewhere.Close()
+
+	beta, _ := alpha.node.Peer("beta")
+	task, err := beta.Send(context.Background(), MessageSendParams{
+		Message: TextMessage("user", "water the plants"),
+		Configuration: &SendConfiguration{
+			Blocking:               true,
+			PushNotificationConfig: &PushConfig{URL: elsewhere.URL + "/push", Token: "x"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !task.Status.State.Terminal() {
+		t.Fatalf("task state = %s", task.Status.State)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if hits.Load() != 0 {
+		t.Error("result pushed to a host other than the peer's")
+	}
+
+	if !samePeerHost("http://10.0.0.5:18790/a2a", "http://10.0.0.5:18790/a2a/push") ||
+		samePeerHost("http://10.0.0.5:18790/a2a", "http://127.0.0.1:18790/a2a/push") ||
+		samePeerHost("http://10.0.0.5:18790/a2a", "file://10.0.0.5:18790/x") {
+		t.Error("samePeerHost")
+	}
+}
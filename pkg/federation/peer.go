@@ -0,0 +1,175 @@
+package federation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/network"
+)
+
+// maxResponseSize caps what is read from a peer.
+const maxResponseSize = 4 << 20
+
+// Peer is a remote instance this one can send tasks to.
+type Peer struct {
+	Name        string
+	URL         string
+	description string
+	token       string
+	client      *http.Client
+
+	mu   sync.RWMutex
+	card *AgentCard
+}
+
+func newPeer(pc config.PeerConfig, taskTimeout time.Duration) *Peer {
+	return &Peer{
+		Name:        pc.Name,
+		URL:         strings.TrimRight(pc.URL, "/"),
+		description: pc.Description,
+		token:       pc.Token,
+		// Blocking sends last as long as the task
+		client: network.NewClient(network.ComponentFederation, network.Options{Timeout: taskTimeout + 30*time.Second}),
+	}
+}
+
+// Card returns the last agent card fetched from the peer.
+func (p *Peer) Card() (AgentCard, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.card == nil {
+		return AgentCard{}, false
+	}
+	return *p.card, true
+}
+
+// Description returns what the peer says about itself, or the configured
+// description before its card has been fetched.
+func (p *Peer) Description() string {
+	if card, ok := p.Card(); ok && card.Description != "" {
+		return card.Description
+	}
+	return p.description
+}
+
+// Capabilities returns the names of the skills the peer advertises.
+func (p *Peer) Capabilities() []string {
+	card, _ := p.Card()
+	out := make([]string, 0, len(card.Skills))
+	for _, s := range card.Skills {
+		name := s.Name
+		if name == "" {
+			name = s.ID
+		}
+		if s.Description != "" && s.Description != name {
+			name += " (" + s.Description + ")"
+		}
+		out = append(out, name)
+	}
+	return out
+}
+
+// FetchCard fetches and caches the peer's agent card.
+func (p *Peer) FetchCard(ctx context.Context) (AgentCard, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.URL+"/.well-known/agent.json", nil)
+	if err != nil {
+		return AgentCard{}, err
+	}
+	var card AgentCard
+	if err := p.do(req, &card); err != nil {
+		return AgentCard{}, err
+	}
+	p.mu.Lock()
+	p.card = &card
+	p.mu.Unlock()
+	return card, nil
+}
+
+// Send calls message/send.
+func (p *Peer) Send(ctx context.Context, params MessageSendParams) (*Task, error) {
+	var t Task
+	if err := p.call(ctx, MethodSend, params, &t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// GetTask calls tasks/get.
+func (p *Peer) GetTask(ctx context.Context, id string) (*Task, error) {
+	var t Task
+	if err := p.call(ctx, MethodGet, TaskIDParams{ID: id}, &t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// CancelTask calls tasks/cancel.
+func (p *Peer) CancelTask(ctx context.Context, id string) (*Task, error) {
+	var t Task
+	if err := p.call(ctx, MethodCancel, TaskIDParams{ID: id}, &t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (p *Peer) call(ctx context.Context, method string, params, result interface{}) error {
+	rawParams, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(rpcRequest{
+		JSONRPC: "2.0",
+		ID:      json.RawMessage(`"` + newID() + `"`),
+		Method:  method,
+		Params:  rawParams,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var resp rpcResponse
+	if err := p.do(req, &resp); err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("%s: %w", p.Name, resp.Error)
+	}
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		return fmt.Errorf("%s: invalid %s result: %w", p.Name, method, err)
+	}
+	return nil
+}
+
+func (p *Peer) do(req *http.Request, out interface{}) error {
+	req.Header.Set("Authorization", "Bearer "+p.token)
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", p.Name, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return fmt.Errorf("%s: %w", p.Name, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: HTTP %d: %s", p.Name, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("%s: invalid response: %w", p.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package federation
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// The wire format follows the A2A (Agent2Agent) protocol: JSON-RPC 2.0 over
+// HTTP with an agent card for discovery. Only text parts are used.
+
+// ProtocolVersion is the A2A version the types follow.
+const ProtocolVersion = "0.3.0"
+
+// AgentCard describes an agent and what it can do.
+type AgentCard struct {
+	Name               string       `json:"name"`
+	Description        string       `json:"description"`
+	URL                string       `json:"url,omitempty"`
+	Version            string       `json:"version"`
+	ProtocolVersion    string       `json:"protocolVersion"`
+	Capabilities       Capabilities `json:"capabilities"`
+	DefaultInputModes  []string     `json:"defaultInputModes"`
+	DefaultOutputModes []string     `json:"defaultOutputModes"`
+	Skills             []Skill      `json:"skills"`
+}
+
+// Capabilities are the optional protocol features an agent supports.
+type Capabilities struct {
+	Streaming         bool `json:"streaming"`
+	PushNotifications bool `json:"pushNotifications"`
+}
+
+// Skill is one capability an agent advertises.
+type Skill struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+}
+
+// Part is a piece of message content. PicoClaw sends and reads text parts.
+type Part struct {
+	Kind string `json:"kind"`
+	Text string `json:"text,omitempty"`
+}
+
+// Message is one turn of a conversation between agents.
+type Message struct {
+	Kind      string `json:"kind"`
+	Role      string `json:"role"` // "user" or "agent"
+	Parts     []Part `json:"parts"`
+	MessageID string `json:"messageId"`
+	ContextID string `json:"contextId,omitempty"`
+	TaskID    string `json:"taskId,omitempty"`
+}
+
+// TextMessage returns a message with a single text part.
+func TextMessage(role, text string) *Message {
+	return &Message{
+		Kind:      "message",
+		Role:      role,
+		Parts:     []Part{{Kind: "text", Text: text}},
+		MessageID: newID(),
+	}
+}
+
+// Text returns the text parts of the message joined by newlines.
+func (m *Message) Text() string {
+	if m == nil {
+		return ""
+	}
+	return partsText(m.Parts)
+}
+
+func partsText(parts []Part) string {
+	var texts []string
+	for _, p := range parts {
+		if p.Kind == "text" && p.Text != "" {
+			texts = append(texts, p.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// TaskState is the lifecycle state of a task.
+type TaskState string
+
+const (
+	StateSubmitted TaskState = "submitted"
+	StateWorking   TaskState = "working"
+	StateCompleted TaskState = "completed"
+	StateFailed    TaskState = "failed"
+	StateCanceled  TaskState = "canceled"
+	StateRejected  TaskState = "rejected"
+)
+
+// Terminal reports whether the task can no longer change.
+func (s TaskState) Terminal() bool {
+	switch s {
+	case StateCompleted, StateFailed, StateCanceled, StateRejected:
+		return true
+	}
+	return false
+}
+
+// TaskStatus is the current state of a task, with an optional message
+// (the error of a failed task, for example).
+type TaskStatus struct {
+	State     TaskState `json:"state"`
+	Message   *Message  `json:"message,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Artifact is an output of a task.
+type Artifact struct {
+	ArtifactID string `json:"artifactId"`
+	Name       string `json:"name,omitempty"`
+	Parts      []Part `json:"parts"`
+}
+
+// Task is a unit of work one agent asked another to do.
+type Task struct {
+	Kind      string     `json:"kind"`
+	ID        string     `json:"id"`
+	ContextID string     `json:"contextId"`
+	Status    TaskStatus `json:"status"`
+	Artifacts []Artifact `json:"artifacts,omitempty"`
+}
+
+// Result returns the text output of the task, or the status message when
+// there is none.
+func (t *Task) Result() string {
+	var texts []string
+	for _, a := range t.Artifacts {
+		if text := partsText(a.Parts); text != "" {
+			texts = append(texts, text)
+		}
+	}
+	if len(texts) == 0 {
+		return t.Status.Message.Text()
+	}
+	return strings.Join(texts, "\n")
+}
+
+// PushConfig asks the remote agent to POST the task to URL when it
+// finishes, with Token in the X-A2A-Notification-Token header.
+type PushConfig struct {
+	URL   string `json:"url"`
+	Token string `json:"token,omitempty"`
+}
+
+// SendConfiguration controls how message/send behaves.
+type SendConfiguration struct {
+	// Blocking waits for the task to finish before responding.
+	Blocking               bool        `json:"blocking"`
+	PushNotificationConfig *PushConfig `json:"pushNotificationConfig,omitempty"`
+}
+
+// MessageSendParams are the parameters of message/send.
+type MessageSendParams struct {
+	Message       *Message           `json:"message"`
+	Configuration *SendConfiguration `json:"configuration,omitempty"`
+}
+
+// TaskIDParams are the parameters of tasks/get and tasks/cancel.
+type TaskIDParams struct {
+	ID string `json:"id"`
+}
+
+// JSON-RPC methods.
+const (
+	MethodSend   = "message/send"
+	MethodGet    = "tasks/get"
+	MethodCancel = "tasks/cancel"
+)
+
+// NotificationTokenHeader carries the push notification token.
+const NotificationTokenHeader = "X-A2A-Notification-Token"
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// RPCError is a JSON-RPC error returned by a peer.
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return e.Message
+}
+
+// JSON-RPC and A2A error codes.
+const (
+	codeParseError        = -32700
+	codeInvalidRequest    = -32600
+	codeMethodNotFound    = -32601
+	codeInvalidParams     = -32602
+	codeInternalError     = -32603
+	codeTaskNotFound      = -32001
+	codeTaskNotCancelable = -32002
+)
@@ -0,0 +1,371 @@
+package federation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// maxRequestSize caps the body of requests from peers.
+const maxRequestSize = 1 << 20
+
+// task is a task a peer asked this instance to run.
+type task struct {
+	Task
+	peer     string
+	push     *PushConfig
+	cancel   context.CancelFunc
+	done     chan struct{}
+	finished time.Time
+}
+
+// ServeHTTP serves the JSON-RPC endpoint at Path, the agent card at
+// Path/.well-known/agent.json (and /.well-known/agent.json) and push
+// notifications at Path/push.
+func (n *Node) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/.well-known/agent.json"):
+		if _, ok := n.authenticate(r); !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		writeJSON(w, n.Card())
+	case r.URL.Path == n.path+"/push":
+		n.handlePush(w, r)
+	case r.URL.Path == n.path || r.URL.Path == n.path+"/":
+		n.handleRPC(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (n *Node) handleRPC(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	peer, ok := n.authenticate(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req rpcRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxRequestSize)).Decode(&req); err != nil {
+		writeRPC(w, nil, nil, &RPCError{Code: codeParseError, Message: "invalid JSON"})
+		return
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		writeRPC(w, req.ID, nil, &RPCError{Code: codeInvalidRequest, Message: "invalid JSON-RPC request"})
+		return
+	}
+
+	var result interface{}
+	var rpcErr *RPCError
+	switch req.Method {
+	case MethodSend:
+		var params MessageSendParams
+		if err := json.Unmarshal(req.Params, &params); err != nil || params.Message == nil || params.Message.Text() == "" {
+			rpcErr = &RPCError{Code: codeInvalidParams, Message: "message with a text part is required"}
+			break
+		}
+		result, rpcErr = n.send(r.Context(), peer, params)
+	case MethodGet, MethodCancel:
+		var params TaskIDParams
+		if err := json.Unmarshal(req.Params, &params); err != nil || params.ID == "" {
+			rpcErr = &RPCError{Code: codeInvalidParams, Message: "task id is required"}
+			break
+		}
+		if req.Method == MethodGet {
+			result, rpcErr = n.getTask(peer, params.ID)
+		} else {
+			result, rpcErr = n.cancelTask(peer, params.ID)
+		}
+	default:
+		rpcErr = &RPCError{Code: codeMethodNotFound, Message: fmt.Sprintf("method %s not supported", req.Method)}
+	}
+	writeRPC(w, req.ID, result, rpcErr)
+}
+
+// send starts a task for the message. Blocking requests wait for it to
+// finish, or until the request is canceled.
+func (n *Node) send(ctx context.Context, peer *Peer, params MessageSendParams) (interface{}, *RPCError) {
+	cfg := params.Configuration
+	if cfg == nil {
+		cfg = &SendConfiguration{}
+	}
+	t := n.startTask(peer, params.Message, cfg.PushNotificationConfig)
+	if !cfg.Blocking {
+		return n.snapshot(t), nil
+	}
+	select {
+	case <-t.done:
+	case <-ctx.Done():
+	}
+	return n.snapshot(t), nil
+}
+
+func (n *Node) startTask(peer *Peer, msg *Message, push *PushConfig) *task {
+	contextID := msg.ContextID
+	if contextID == "" {
+		contextID = newID()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), n.timeout)
+	t := &task{
+		Task: Task{
+			Kind:      "task",
+			ID:        newID(),
+			ContextID: contextID,
+			Status:    TaskStatus{State: StateSubmitted, Timestamp: time.Now()},
+		},
+		peer:   peer.Name,
+		push:   push,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	if push != nil && push.URL == "" {
+		t.push = nil
+	}
+	if t.push != nil && !samePeerHost(peer.URL, push.URL) {
+		logger.WarnCF("federation", "Ignoring push URL outside the peer's host", map[string]interface{}{
+			"peer": peer.Name,
+			"url":  push.URL,
+		})
+		t.push = nil
+	}
+
+	n.mu.Lock()
+	n.pruneLocked()
+	n.tasks[t.ID] = t
+	n.mu.Unlock()
+
+	logger.InfoCF("federation", "Task received from peer", map[string]interface{}{
+		"peer":    peer.Name,
+		"task":    t.ID,
+		"context": contextID,
+	})
+	go n.run(ctx, t, msg.Text())
+	return t
+}
+
+func (n *Node) run(ctx context.Context, t *task, text string) {
+	defer t.cancel()
+	n.setStatus(t, StateWorking)
+
+	// One session per peer conversation
+	sessionKey := fmt.Sprintf("%s:%s:%s", Channel, t.peer, t.ContextID)
+	content := fmt.Sprintf("[Task from peer agent %s]\n%s", t.peer, text)
+	out, err := n.exec.ProcessDirectWithChannel(ctx, content, sessionKey, Channel, t.peer)
+
+	n.mu.Lock()
+	switch {
+	case t.Status.State == StateCanceled:
+	case err != nil && errors.Is(err, context.Canceled):
+		t.Status = TaskStatus{State: StateCanceled, Timestamp: time.Now()}
+	case err != nil:
+		t.Status = TaskStatus{State: StateFailed, Message: TextMessage("agent", err.Error()), Timestamp: time.Now()}
+	default:
+		t.Artifacts = []Artifact{{ArtifactID: newID(), Name: "response", Parts: []Part{{Kind: "text", Text: out}}}}
+		t.Status = TaskStatus{State: StateCompleted, Timestamp: time.Now()}
+	}
+	t.finished = time.Now()
+	snapshot := t.Task
+	push := t.push
+	close(t.done)
+	n.mu.Unlock()
+
+	logger.InfoCF("federation", "Task finished", map[string]interface{}{
+		"peer":  t.peer,
+		"task":  t.ID,
+		"state": string(snapshot.Status.State),
+	})
+	if push != nil {
+		n.notify(push, &snapshot)
+	}
+}
+
+func (n *Node) setStatus(t *task, state TaskState) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if !t.Status.State.Terminal() {
+		t.Status = TaskStatus{State: state, Timestamp: time.Now()}
+	}
+}
+
+func (n *Node) snapshot(t *task) *Task {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	out := t.Task
+	return &out
+}
+
+// lookup returns a task of peer. Peers cannot see each other's tasks.
+func (n *Node) lookup(peer *Peer, id string) (*task, *RPCError) {
+	n.mu.Lock()
+	t, ok := n.tasks[id]
+	n.mu.Unlock()
+	if !ok || t.peer != peer.Name {
+		return nil, &RPCError{Code: codeTaskNotFound, Message: "task not found"}
+	}
+	return t, nil
+}
+
+func (n *Node) getTask(peer *Peer, id string) (interface{}, *RPCError) {
+	t, rpcErr := n.lookup(peer, id)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	return n.snapshot(t), nil
+}
+
+func (n *Node) cancelTask(peer *Peer, id string) (interface{}, *RPCError) {
+	t, rpcErr := n.lookup(peer, id)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	n.mu.Lock()
+	if t.Status.State.Terminal() {
+		n.mu.Unlock()
+		return nil, &RPCError{Code: codeTaskNotCancelable, Message: "task already finished"}
+	}
+	t.Status = TaskStatus{State: StateCanceled, Timestamp: time.Now()}
+	n.mu.Unlock()
+	t.cancel()
+	return n.snapshot(t), nil
+}
+
+// pruneLocked drops finished tasks past their retention, then the oldest
+// finished tasks while there are too many.
+func (n *Node) pruneLocked() {
+	var finished []*task
+	for id, t := range n.tasks {
+		if t.finished.IsZero() {
+			continue
+		}
+		if time.Since(t.finished) > taskRetention {
+			delete(n.tasks, id)
+			continue
+		}
+		finished = append(finished, t)
+	}
+	if len(n.tasks) < maxTasks {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].finished.Before(finished[j].finished) })
+	for _, t := range finished {
+		if len(n.tasks) < maxTasks {
+			break
+		}
+		delete(n.tasks, t.ID)
+	}
+}
+
+// samePeerHost reports whether pushURL is on the host and port of the
+// peer's configured URL. Results are only pushed back to the peer itself,
+// so a peer can't make this instance post to other addresses.
+func samePeerHost(peerURL, pushURL string) bool {
+	peer, err := url.Parse(peerURL)
+	if err != nil {
+		return false
+	}
+	push, err := url.Parse(pushURL)
+	if err != nil || (push.Scheme != "http" && push.Scheme != "https") {
+		return false
+	}
+	return strings.EqualFold(peer.Host, push.Host)
+}
+
+// notify posts a finished task to the caller's push URL. It retries a few
+// times; the caller also polls, so a lost notification only delays the
+// result.
+func (n *Node) notify(push *PushConfig, t *Task) {
+	body, err := json.Marshal(t)
+	if err != nil {
+		return
+	}
+	for attempt := 0; attempt < 3; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * 5 * time.Second)
+		}
+		req, err := http.NewRequest(http.MethodPost, push.URL, bytes.NewReader(body))
+		if err != nil {
+			break
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if push.Token != "" {
+			req.Header.Set(NotificationTokenHeader, push.Token)
+		}
+		resp, err := n.client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode < 300 {
+				return
+			}
+			err = fmt.Errorf("HTTP %d", resp.StatusCode)
+		}
+		logger.WarnCF("federation", "Failed to push task result", map[string]interface{}{
+			"task":    t.ID,
+			"attempt": attempt + 1,
+			"error":   err.Error(),
+		})
+	}
+}
+
+// handlePush receives the result of a task this instance submitted. The
+// notification token, random per task, authenticates the request.
+func (n *Node) handlePush(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	token := r.Header.Get(NotificationTokenHeader)
+	n.mu.Lock()
+	ch, ok := n.waiters[token]
+	n.mu.Unlock()
+	if token == "" || !ok {
+		http.Error(w, "unknown notification token", http.StatusUnauthorized)
+		return
+	}
+
+	var t Task
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxResponseSize)).Decode(&t); err != nil {
+		http.Error(w, "invalid task", http.StatusBadRequest)
+		return
+	}
+	select {
+	case ch <- &t:
+	default:
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeRPC(w http.ResponseWriter, id json.RawMessage, result interface{}, rpcErr *RPCError) {
+	resp := rpcResponse{JSONRPC: "2.0", ID: id, Error: rpcErr}
+	if id == nil {
+		resp.ID = json.RawMessage("null")
+	}
+	if rpcErr == nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			resp.Error = &RPCError{Code: codeInternalError, Message: err.Error()}
+		} else {
+			resp.Result = data
+		}
+	}
+	writeJSON(w, resp)
+}
@@ -0,0 +1,145 @@
+package feedback
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Example is a rated turn: prompt, context, response and the rating it got.
+// With several ratings the latest verdict counts and all comments are kept.
+type Example struct {
+	Turn
+	Rating   int      `json:"rating"`
+	Comments []string `json:"comments,omitempty"`
+	Sources  []string `json:"sources,omitempty"`
+}
+
+// Dataset joins the turns on record with their ratings. minScore and
+// maxScore bound the rating of the examples returned; rated comments
+// without a verdict count as 0. Unrated turns are left out unless
+// includeUnrated is set.
+func (s *Store) Dataset(minScore, maxScore int, includeUnrated bool) ([]Example, error) {
+	ratings, err := s.Ratings()
+	if err != nil {
+		return nil, err
+	}
+	byTrace := make(map[string]*Example)
+	var order []string
+	for _, t := range s.Turns() {
+		byTrace[t.TraceID] = &Example{Turn: t}
+		order = append(order, t.TraceID)
+	}
+
+	rated := make(map[string]bool)
+	for _, r := range ratings {
+		e, ok := byTrace[r.TraceID]
+		if !ok {
+			continue // the turn has been dropped
+		}
+		rated[r.TraceID] = true
+		if r.Score != 0 {
+			e.Rating = r.Score
+		}
+		if r.Comment != "" {
+			e.Comments = append(e.Comments, r.Comment)
+		}
+		if !contains(e.Sources, r.Source) {
+			e.Sources = append(e.Sources, r.Source)
+		}
+	}
+
+	var out []Example
+	for _, id := range order {
+		e := byTrace[id]
+		if !rated[id] && !includeUnrated {
+			continue
+		}
+		if e.Rating < minScore || e.Rating > maxScore {
+			continue
+		}
+		out = append(out, *e)
+	}
+	return out, nil
+}
+
+// WriteDataset writes examples as JSON lines.
+func WriteDataset(w io.Writer, examples []Example) error {
+	enc := json.NewEncoder(w)
+	for _, e := range examples {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Scenario is an eval case made from a badly rated turn: replay the
+// context and prompt, and check the new answer against the criteria.
+type Scenario struct {
+	Name             string    `json:"name"`
+	TraceID          string    `json:"trace_id"`
+	SessionKey       string    `json:"session_key"`
+	Context          []Message `json:"context,omitempty"`
+	Prompt           string    `json:"prompt"`
+	RejectedResponse string    `json:"rejected_response"`
+	Feedback         []string  `json:"feedback,omitempty"`
+	Criteria         []string  `json:"criteria"`
+}
+
+// ScenarioFor builds the eval scenario for an example.
+func ScenarioFor(e Example) Scenario {
+	criteria := []string{"The answer must not repeat the mistakes of the rejected response."}
+	for _, c := range e.Comments {
+		criteria = append(criteria, "The answer must address this feedback: "+c)
+	}
+	return Scenario{
+		Name:             fmt.Sprintf("feedback-%s-%s", e.Time.Format("20060102"), e.TraceID),
+		TraceID:          e.TraceID,
+		SessionKey:       e.SessionKey,
+		Context:          e.Context,
+		Prompt:           e.Prompt,
+		RejectedResponse: e.Response,
+		Feedback:         e.Comments,
+		Criteria:         criteria,
+	}
+}
+
+// WriteScenarios writes one scenario file per badly rated example into dir,
+// named after the scenario, and returns their paths. Existing files for the
+// same turns are replaced.
+func WriteScenarios(dir string, examples []Example) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, e := range examples {
+		if e.Rating >= 0 {
+			continue
+		}
+		sc := ScenarioFor(e)
+		data, err := json.MarshalIndent(sc, "", "  ")
+		if err != nil {
+			return paths, err
+		}
+		path := filepath.Join(dir, sc.Name+".json")
+		if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
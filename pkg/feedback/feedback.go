@@ -0,0 +1,275 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// Inspired by and based on nanobot: https://github.com/HKUDS/nanobot
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+// Package feedback keeps a record of agent turns and of how users rated
+// them, so bad answers can be found, exported as a dataset and turned into
+// eval scenarios. Turns and ratings are stored as JSON lines in the
+// workspace; ratings refer to turns by trace ID.
+package feedback
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+const (
+	defaultMaxTurns = 1000
+	turnsFile       = "turns.jsonl"
+	ratingsFile     = "ratings.jsonl"
+)
+
+// ErrUnknownTurn is returned when rating a turn that is not on record.
+var ErrUnknownTurn = errors.New("unknown turn")
+
+// Message is a message of the conversation a turn answered.
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Turn is an answered user message.
+type Turn struct {
+	TraceID    string    `json:"trace_id"`
+	Time       time.Time `json:"time"`
+	SessionKey string    `json:"session_key"`
+	Channel    string    `json:"channel"`
+	ChatID     string    `json:"chat_id"`
+	SenderID   string    `json:"sender_id,omitempty"`
+	Model      string    `json:"model,omitempty"`
+	Context    []Message `json:"context,omitempty"` // the conversation before the prompt
+	Prompt     string    `json:"prompt"`
+	Response   string    `json:"response"`
+	Iterations int       `json:"iterations,omitempty"`
+}
+
+// Rating is a user's judgement of a turn. Score is 1 for good, -1 for bad
+// and 0 for a comment without a verdict.
+type Rating struct {
+	TraceID string    `json:"trace_id"`
+	Time    time.Time `json:"time"`
+	Score   int       `json:"score"`
+	Comment string    `json:"comment,omitempty"`
+	Source  string    `json:"source"` // "reaction", "button", "command" or "text"
+	By      string    `json:"by,omitempty"`
+}
+
+// Store records turns and ratings under a directory. It keeps the most
+// recent turns; ratings are kept in full. It is safe for concurrent use.
+type Store struct {
+	dir      string
+	maxTurns int
+
+	mu        sync.Mutex
+	turns     []Turn // oldest first
+	fileTurns int    // lines in the turns file, compacted at twice maxTurns
+}
+
+// NewStore opens the store in dir, keeping at most maxTurns turns.
+func NewStore(dir string, maxTurns int) *Store {
+	if maxTurns <= 0 {
+		maxTurns = defaultMaxTurns
+	}
+	s := &Store{dir: dir, maxTurns: maxTurns}
+	if err := readLines(filepath.Join(dir, turnsFile), func(line []byte) error {
+		var t Turn
+		if err := json.Unmarshal(line, &t); err != nil {
+			return err
+		}
+		s.turns = append(s.turns, t)
+		s.fileTurns++
+		return nil
+	}); err != nil {
+		logger.WarnCF("feedback", "Ignoring unreadable turn log", map[string]interface{}{
+			"dir":   dir,
+			"error": err.Error(),
+		})
+	}
+	if over := len(s.turns) - maxTurns; over > 0 {
+		s.turns = append([]Turn(nil), s.turns[over:]...)
+	}
+	return s
+}
+
+// Dir returns the directory the store keeps its files in.
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// RecordTurn stores t, assigning its trace ID and time when unset, and
+// returns the trace ID.
+func (s *Store) RecordTurn(t Turn) (string, error) {
+	if t.TraceID == "" {
+		t.TraceID = newTraceID()
+	}
+	if t.Time.IsZero() {
+		t.Time = time.Now()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.turns = append(s.turns, t)
+	if over := len(s.turns) - s.maxTurns; over > 0 {
+		s.turns = append([]Turn(nil), s.turns[over:]...)
+	}
+	if s.fileTurns >= 2*s.maxTurns {
+		return t.TraceID, s.compactLocked()
+	}
+	if err := appendLine(filepath.Join(s.dir, turnsFile), t); err != nil {
+		return t.TraceID, err
+	}
+	s.fileTurns++
+	return t.TraceID, nil
+}
+
+// Last returns the most recent turn in a chat.
+func (s *Store) Last(channel, chatID string) (Turn, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.turns) - 1; i >= 0; i-- {
+		if s.turns[i].Channel == channel && s.turns[i].ChatID == chatID {
+			return s.turns[i], true
+		}
+	}
+	return Turn{}, false
+}
+
+// Turn returns the turn with traceID, if it is still on record.
+func (s *Store) Turn(traceID string) (Turn, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.turns) - 1; i >= 0; i-- {
+		if s.turns[i].TraceID == traceID {
+			return s.turns[i], true
+		}
+	}
+	return Turn{}, false
+}
+
+// Turns returns the turns on record, oldest first.
+func (s *Store) Turns() []Turn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Turn(nil), s.turns...)
+}
+
+// Rate stores r for a turn on record.
+func (s *Store) Rate(r Rating) error {
+	if _, ok := s.Turn(r.TraceID); !ok {
+		return ErrUnknownTurn
+	}
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return appendLine(filepath.Join(s.dir, ratingsFile), r)
+}
+
+// Ratings returns all stored ratings, oldest first.
+func (s *Store) Ratings() ([]Rating, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ratings []Rating
+	err := readLines(filepath.Join(s.dir, ratingsFile), func(line []byte) error {
+		var r Rating
+		if err := json.Unmarshal(line, &r); err != nil {
+			return err
+		}
+		ratings = append(ratings, r)
+		return nil
+	})
+	return ratings, err
+}
+
+// compactLocked rewrites the turns file with the turns still kept.
+func (s *Store) compactLocked() error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+	path := filepath.Join(s.dir, turnsFile)
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	for _, t := range s.turns {
+		if err := enc.Encode(t); err != nil {
+			f.Close()
+			os.Remove(tmp)
+			return err
+		}
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to save turn log: %w", err)
+	}
+	s.fileTurns = len(s.turns)
+	return nil
+}
+
+func appendLine(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func readLines(path string, fn func(line []byte) error) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64<<10), 16<<20)
+	for sc.Scan() {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		if err := fn(sc.Bytes()); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
+
+func newTraceID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,151 @@
+package feedback
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStoreRateAndDataset(t *testing.T) {
+	dir := t.TempDir()
+	s := NewStore(dir, 10)
+
+	good, err := s.RecordTurn(Turn{Channel: "telegram", ChatID: "42", Prompt: "2+2?", Response: "4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad, _ := s.RecordTurn(Turn{
+		Channel:  "telegram",
+		ChatID:   "42",
+		Context:  []Message{{Role: "user", Content: "I live in Lyon"}},
+		Prompt:   "Weather here?",
+		Response: "It is sunny in Paris.",
+	})
+	s.RecordTurn(Turn{Channel: "slack", ChatID: "C1", Prompt: "hi", Response: "hello"})
+
+	if last, ok := s.Last("telegram", "42"); !ok || last.TraceID != bad {
+		t.Fatalf("Last = %+v, %v", last, ok)
+	}
+	if err := s.Rate(Rating{TraceID: good, Score: 1, Source: "reaction"}); err != nil {
+		t.Fatal(err)
+	}
+	s.Rate(Rating{TraceID: bad, Score: -1, Source: "button"})
+	s.Rate(Rating{TraceID: bad, Comment: "wrong city", Source: "command"})
+	if err := s.Rate(Rating{TraceID: "nope", Score: 1}); !errors.Is(err, ErrUnknownTurn) {
+		t.Errorf("rating an unknown turn: %v", err)
+	}
+
+	// Reopening reads everything back
+	s = NewStore(dir, 10)
+	all, err := s.Dataset(-1, 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("rated examples = %d, want 2", len(all))
+	}
+	if e := all[1]; e.TraceID != bad || e.Rating != -1 || len(e.Comments) != 1 || len(e.Sources) != 2 {
+		t.Errorf("bad example = %+v", e)
+	}
+	if withUnrated, _ := s.Dataset(-1, 1, true); len(withUnrated) != 3 {
+		t.Errorf("with unrated = %d, want 3", len(withUnrated))
+	}
+	badOnly, _ := s.Dataset(-1, -1, false)
+	if len(badOnly) != 1 {
+		t.Fatalf("bad examples = %d, want 1", len(badOnly))
+	}
+
+	var buf bytes.Buffer
+	if err := WriteDataset(&buf, all); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != 2 {
+		t.Errorf("dataset lines = %d", lines)
+	}
+
+	paths, err := WriteScenarios(filepath.Join(dir, "evals"), all)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 1 {
+		t.Fatalf("scenarios = %v, want one for the bad turn", paths)
+	}
+	data, _ := os.ReadFile(paths[0])
+	var sc Scenario
+	if err := json.Unmarshal(data, &sc); err != nil {
+		t.Fatal(err)
+	}
+	if sc.TraceID != bad || sc.RejectedResponse != "It is sunny in Paris." || len(sc.Context) != 1 || len(sc.Criteria) != 2 {
+		t.Errorf("scenario = %+v", sc)
+	}
+}
+
+func TestStoreCompactsTurns(t *testing.T) {
+	dir := t.TempDir()
+	s := NewStore(dir, 2)
+	for i := 0; i < 7; i++ {
+		if _, err := s.RecordTurn(Turn{Channel: "cli", ChatID: "direct", Prompt: "p"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(s.Turns()); n != 2 {
+		t.Errorf("kept turns = %d, want 2", n)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, turnsFile))
+	if lines := strings.Count(string(data), "\n"); lines > 4 {
+		t.Errorf("turn log has %d lines, want it compacted", lines)
+	}
+	if n := len(NewStore(dir, 2).Turns()); n != 2 {
+		t.Errorf("turns after reopening = %d, want 2", n)
+	}
+}
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		text    string
+		score   int
+		comment string
+		ok      bool
+	}{
+		{"/feedback good", 1, "", true},
+		{"/feedback bad wrong city", -1, "wrong city", true},
+		{"/feedback 👎", -1, "", true},
+		{"/feedback too long-winded", 0, "too long-winded", true},
+		{"/feedback", 0, "", true},
+		{"/feedbacks good", 0, "", false},
+		{"good answer", 0, "", false},
+	}
+	for _, tt := range tests {
+		score, comment, ok := ParseCommand(tt.text)
+		if score != tt.score || comment != tt.comment || ok != tt.ok {
+			t.Errorf("ParseCommand(%q) = %d, %q, %v", tt.text, score, comment, ok)
+		}
+	}
+}
+
+func TestButtonsAndComplaints(t *testing.T) {
+	if id, score, ok := ParseButton(ButtonData("abc", -1)); !ok || id != "abc" || score != -1 {
+		t.Errorf("ParseButton = %q, %d, %v", id, score, ok)
+	}
+	for _, data := range []string{"ack:abc", "rate:abc", "rate::up", "rate:abc:maybe"} {
+		if _, _, ok := ParseButton(data); ok {
+			t.Errorf("ParseButton(%q) accepted", data)
+		}
+	}
+
+	for _, text := range []string{"That’s wrong", "不对，是明天", "間違っています"} {
+		if !IsComplaint(text) {
+			t.Errorf("IsComplaint(%q) = false", text)
+		}
+	}
+	long := "Can you explain why people say that's wrong " + strings.Repeat("when they talk about this topic ", 5)
+	for _, text := range []string{"thanks!", long} {
+		if IsComplaint(text) {
+			t.Errorf("IsComplaint(%q) = true", text)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package feedback
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+var reactionScores = map[string]int{
+	"👍": 1, "👌": 1, "❤": 1, "❤️": 1, "🔥": 1, "👏": 1, "💯": 1, "🎉": 1, "🥰": 1, "😍": 1,
+	"👎": -1, "💩": -1, "🤬": -1, "🤮": -1, "😡": -1, "🤡": -1,
+}
+
+// ReactionScore maps an emoji reaction to a score. Reactions that say
+// nothing about quality are not ratings.
+func ReactionScore(emoji string) (int, bool) {
+	score, ok := reactionScores[emoji]
+	return score, ok
+}
+
+// ButtonData is the data of a rating button for a turn.
+func ButtonData(traceID string, score int) string {
+	if score > 0 {
+		return "rate:" + traceID + ":up"
+	}
+	return "rate:" + traceID + ":down"
+}
+
+// ParseButton reads the data of a rating button.
+func ParseButton(data string) (traceID string, score int, ok bool) {
+	rest, found := strings.CutPrefix(strings.TrimSpace(data), "rate:")
+	if !found {
+		return "", 0, false
+	}
+	traceID, vote, found := strings.Cut(rest, ":")
+	if !found || traceID == "" {
+		return "", 0, false
+	}
+	switch vote {
+	case "up":
+		return traceID, 1, true
+	case "down":
+		return traceID, -1, true
+	}
+	return "", 0, false
+}
+
+var commandScores = map[string]int{
+	"good": 1, "+": 1, "+1": 1, "up": 1, "yes": 1, "👍": 1, "好": 1, "赞": 1, "良い": 1,
+	"bad": -1, "-": -1, "-1": -1, "down": -1, "no": -1, "wrong": -1, "👎": -1, "差": -1, "错": -1, "不好": -1, "悪い": -1,
+}
+
+// ParseCommand reads "/feedback <good|bad> [comment]" or "/feedback
+// <comment>". ok is false when text is not the command; an empty comment
+// with a zero score means the command had no arguments.
+func ParseCommand(text string) (score int, comment string, ok bool) {
+	text = strings.TrimSpace(text)
+	if text != "/feedback" && !strings.HasPrefix(text, "/feedback ") {
+		return 0, "", false
+	}
+	args := strings.TrimSpace(strings.TrimPrefix(text, "/feedback"))
+	first, rest, _ := strings.Cut(args, " ")
+	if s, known := commandScores[strings.ToLower(first)]; known {
+		return s, strings.TrimSpace(rest), true
+	}
+	return 0, args, true
+}
+
+var complaints = []string{
+	"that's wrong", "that is wrong", "thats wrong", "wrong answer", "that's not right", "that's incorrect",
+	"that is incorrect", "not what i asked",
+	"不对", "错了", "答错了", "不是这样",
+	"違います", "間違って",
+}
+
+// maxComplaintLen keeps longer messages that merely mention a phrase from
+// counting as complaints.
+const maxComplaintLen = 120
+
+// IsComplaint reports whether text is a short message saying the last
+// answer was wrong.
+func IsComplaint(text string) bool {
+	if utf8.RuneCountInString(text) > maxComplaintLen {
+		return false
+	}
+	text = strings.ToLower(strings.ReplaceAll(text, "’", "'"))
+	for _, phrase := range complaints {
+		if strings.Contains(text, phrase) {
+			return true
+		}
+	}
+	return false
+}
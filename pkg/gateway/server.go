@@ -0,0 +1,114 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// Inspired by and based on nanobot: https://github.com/HKUDS/nanobot
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// Server is the gateway HTTP server. Channels that receive updates by
+// webhook, and other gateway endpoints, register their handlers on it
+// instead of opening their own listeners.
+type Server struct {
+	addr     string
+	mux      *http.ServeMux
+	server   *http.Server
+	listener net.Listener
+	mu       sync.Mutex
+}
+
+func NewServer(cfg config.GatewayConfig) *Server {
+	s := &Server{
+		addr: fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+		mux:  http.NewServeMux(),
+	}
+	s.mux.HandleFunc("/health", s.handleHealth)
+	return s
+}
+
+// Handle registers a handler for the given pattern. It must be called before Start.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+	logger.DebugCF("gateway", "Registered HTTP handler", map[string]interface{}{
+		"pattern": pattern,
+	})
+}
+
+// Start begins listening in the background. It returns once the listener is bound.
+func (s *Server) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.server != nil {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.addr, err)
+	}
+
+	s.listener = listener
+	s.server = &http.Server{
+		Handler:           s.mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	server := s.server
+	go func() {
+		logger.InfoCF("gateway", "Gateway HTTP server listening", map[string]interface{}{
+			"addr": listener.Addr().String(),
+		})
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logger.ErrorCF("gateway", "Gateway HTTP server error", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+	}()
+
+	return nil
+}
+
+func (s *Server) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	server := s.server
+	s.server = nil
+	s.listener = nil
+	s.mu.Unlock()
+
+	if server == nil {
+		return nil
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return server.Shutdown(shutdownCtx)
+}
+
+// Addr returns the bound address once started, or the configured one otherwise.
+func (s *Server) Addr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listener != nil {
+		return s.listener.Addr().String()
+	}
+	return s.addr
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"status":"ok"}`))
+}
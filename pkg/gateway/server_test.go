@@ -0,0 +1,55 @@
+package gateway
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestServerRoutesHandlers(t *testing.T) {
+	s := NewServer(config.GatewayConfig{Host: "127.0.0.1", Port: 0})
+	s.Handle("/webhook/test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hooked"))
+	}))
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer s.Stop(context.Background())
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"/health", http.StatusOK, `{"status":"ok"}`},
+		{"/webhook/test", http.StatusOK, "hooked"},
+		{"/missing", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		resp, err := http.Get("http://" + s.Addr() + tt.path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", tt.path, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != tt.wantStatus {
+			t.Errorf("GET %s status = %d, want %d", tt.path, resp.StatusCode, tt.wantStatus)
+		}
+		if tt.wantBody != "" && string(body) != tt.wantBody {
+			t.Errorf("GET %s body = %q, want %q", tt.path, body, tt.wantBody)
+		}
+	}
+
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatalf("second Stop: %v", err)
+	}
+}
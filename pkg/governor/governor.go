@@ -0,0 +1,270 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// Inspired by and based on nanobot: https://github.com/HKUDS/nanobot
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+// Package governor keeps the process within its memory budget. It hands
+// the configured soft limit to the Go runtime, watches the resident set
+// size, and when it nears the limit evicts idle sessions and caches,
+// returns freed memory to the OS and lets only one background task
+// (subagents, summaries) run at a time.
+package governor
+
+import (
+	"bufio"
+	"context"
+	"math"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// Evictor frees what it can and returns how many items it dropped. It is
+// called on every check; pressure asks it to keep only what is in use.
+type Evictor func(pressure bool) int
+
+// Status is what the governor reports to `picoclaw status` and `top`.
+type Status struct {
+	RSS       uint64 `json:"rss"`
+	HeapAlloc uint64 `json:"heap_alloc"`
+	// Limit is the soft limit in bytes, from memory.limit_mb or GOMEMLIMIT;
+	// 0 when there is none.
+	Limit           uint64           `json:"limit"`
+	Pressure        bool             `json:"pressure"`
+	MaxReadBytes    int64            `json:"max_read_bytes"`
+	Background      int              `json:"background"`       // tasks running
+	BackgroundLimit int              `json:"background_limit"` // tasks allowed at once
+	Evicted         map[string]int64 `json:"evicted,omitempty"`
+	LastCheck       time.Time        `json:"last_check"`
+}
+
+// Governor enforces a MemoryConfig. The zero limit disables pressure
+// handling but idle eviction and the background limit still apply.
+type Governor struct {
+	cfg config.MemoryConfig
+
+	mu       sync.Mutex
+	evictors map[string]Evictor
+	evicted  map[string]int64
+	pressure bool
+	last     Status
+	running  int
+	limit    int
+	changed  chan struct{} // closed and replaced when a slot may be free
+
+	readRSS func() uint64
+}
+
+// New returns a governor for cfg. Call Start to apply the soft limit and
+// begin watching.
+func New(cfg config.MemoryConfig) *Governor {
+	if cfg.MaxBackground <= 0 {
+		cfg.MaxBackground = 1
+	}
+	return &Governor{
+		cfg:      cfg,
+		evictors: make(map[string]Evictor),
+		evicted:  make(map[string]int64),
+		limit:    cfg.MaxBackground,
+		changed:  make(chan struct{}),
+		readRSS:  readRSS,
+	}
+}
+
+// Register adds an evictor under name, replacing one of the same name.
+func (g *Governor) Register(name string, fn Evictor) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.evictors[name] = fn
+}
+
+// MaxReadBytes is how much a single read may hold in memory.
+func (g *Governor) MaxReadBytes() int64 {
+	return int64(g.cfg.MaxReadKB) << 10
+}
+
+// Start sets the soft limit, unless GOMEMLIMIT is set, and checks memory
+// every check_interval until ctx is done.
+func (g *Governor) Start(ctx context.Context) {
+	if g.cfg.LimitMB > 0 && os.Getenv("GOMEMLIMIT") == "" {
+		debug.SetMemoryLimit(int64(g.cfg.LimitMB) << 20)
+		logger.InfoCF("governor", "Memory soft limit set", map[string]interface{}{
+			"limit_mb": g.cfg.LimitMB,
+		})
+	}
+
+	interval := time.Duration(g.cfg.CheckInterval) * time.Second
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		g.Check()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				g.Check()
+			}
+		}
+	}()
+}
+
+// Check measures memory once, runs the evictors and adjusts the background
+// limit.
+func (g *Governor) Check() Status {
+	limit := softLimit()
+	rss := g.readRSS()
+	pressure := limit > 0 && rss >= limit/100*uint64(g.cfg.PressurePercent)
+
+	g.mu.Lock()
+	evictors := make([]string, 0, len(g.evictors))
+	for name := range g.evictors {
+		evictors = append(evictors, name)
+	}
+	sort.Strings(evictors)
+	fns := make([]Evictor, len(evictors))
+	for i, name := range evictors {
+		fns[i] = g.evictors[name]
+	}
+	was := g.pressure
+	g.pressure = pressure
+	g.mu.Unlock()
+
+	dropped := make(map[string]int)
+	for i, fn := range fns {
+		if n := fn(pressure); n > 0 {
+			dropped[evictors[i]] = n
+		}
+	}
+	if pressure {
+		debug.FreeOSMemory()
+		rss = g.readRSS()
+	}
+
+	if pressure != was {
+		fields := map[string]interface{}{"rss_mb": rss >> 20, "limit_mb": limit >> 20}
+		if pressure {
+			logger.WarnCF("governor", "Memory pressure: evicting and running background tasks one at a time", fields)
+		} else {
+			logger.InfoCF("governor", "Memory pressure over", fields)
+		}
+	}
+
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	g.mu.Lock()
+	for name, n := range dropped {
+		g.evicted[name] += int64(n)
+	}
+	if pressure {
+		g.limit = 1
+	} else {
+		g.limit = g.cfg.MaxBackground
+	}
+	g.wakeLocked()
+	g.last = Status{
+		RSS:       rss,
+		HeapAlloc: ms.HeapAlloc,
+		Limit:     limit,
+		Pressure:  pressure,
+		LastCheck: time.Now(),
+	}
+	g.mu.Unlock()
+	return g.Status()
+}
+
+// Status returns the result of the last check with current counters.
+func (g *Governor) Status() Status {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	s := g.last
+	s.MaxReadBytes = g.MaxReadBytes()
+	s.Background = g.running
+	s.BackgroundLimit = g.limit
+	if len(g.evicted) > 0 {
+		s.Evicted = make(map[string]int64, len(g.evicted))
+		for name, n := range g.evicted {
+			s.Evicted[name] = n
+		}
+	}
+	return s
+}
+
+// Acquire waits for a background slot. Call release when done.
+func (g *Governor) Acquire(ctx context.Context) (release func(), err error) {
+	for {
+		g.mu.Lock()
+		if g.running < g.limit {
+			g.running++
+			g.mu.Unlock()
+			var once sync.Once
+			return func() {
+				once.Do(func() {
+					g.mu.Lock()
+					g.running--
+					g.wakeLocked()
+					g.mu.Unlock()
+				})
+			}, nil
+		}
+		changed := g.changed
+		g.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+func (g *Governor) wakeLocked() {
+	close(g.changed)
+	g.changed = make(chan struct{})
+}
+
+// softLimit returns the runtime's memory limit, or 0 when none is set.
+func softLimit() uint64 {
+	limit := debug.SetMemoryLimit(-1)
+	if limit <= 0 || limit == math.MaxInt64 {
+		return 0
+	}
+	return uint64(limit)
+}
+
+// readRSS returns the resident set size from /proc, or the memory obtained
+// from the OS where /proc is not available.
+func readRSS() uint64 {
+	if f, err := os.Open("/proc/self/status"); err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if !strings.HasPrefix(line, "VmRSS:") {
+				continue
+			}
+			fields := strings.Fields(strings.TrimPrefix(line, "VmRSS:"))
+			if len(fields) > 0 {
+				if kb, err := strconv.ParseUint(fields[0], 10, 64); err == nil {
+					return kb << 10
+				}
+			}
+		}
+	}
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return ms.Sys
+}
@@ -0,0 +1,92 @@
+package governor
+
+import (
+	"context"
+	"math"
+	"runtime/debug"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func testConfig() config.MemoryConfig {
+	return config.MemoryConfig{MaxReadKB: 64, MaxBackground: 2, PressurePercent: 80, CheckInterval: 1}
+}
+
+func TestAcquireLimitsBackgroundTasks(t *testing.T) {
+	g := New(testConfig())
+	ctx := context.Background()
+
+	r1, _ := g.Acquire(ctx)
+	r2, _ := g.Acquire(ctx)
+	short, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := g.Acquire(short); err == nil {
+		t.Fatal("a third task ran with max_background 2")
+	}
+
+	got := make(chan struct{})
+	go func() {
+		release, err := g.Acquire(ctx)
+		if err == nil {
+			release()
+		}
+		close(got)
+	}()
+	r1()
+	r1() // releasing twice frees one slot only
+	select {
+	case <-got:
+	case <-time.After(time.Second):
+		t.Fatal("waiting task not woken by release")
+	}
+	r2()
+	if s := g.Status(); s.Background != 0 || s.BackgroundLimit != 2 || s.MaxReadBytes != 64<<10 {
+		t.Errorf("status = %+v", s)
+	}
+}
+
+func TestCheckUnderPressure(t *testing.T) {
+	prev := debug.SetMemoryLimit(100 << 20)
+	t.Cleanup(func() { debug.SetMemoryLimit(prev) })
+
+	g := New(testConfig())
+	rss := uint64(50 << 20)
+	g.readRSS = func() uint64 { return rss }
+	var calls []bool
+	g.Register("sessions", func(pressure bool) int {
+		calls = append(calls, pressure)
+		if pressure {
+			return 3
+		}
+		return 0
+	})
+
+	if s := g.Check(); s.Pressure || s.Limit != 100<<20 || s.BackgroundLimit != 2 {
+		t.Errorf("calm status = %+v", s)
+	}
+	rss = 90 << 20
+	s := g.Check()
+	if !s.Pressure || s.BackgroundLimit != 1 || s.Evicted["sessions"] != 3 {
+		t.Errorf("pressure status = %+v", s)
+	}
+	rss = 40 << 20
+	if s := g.Check(); s.Pressure || s.BackgroundLimit != 2 || s.Evicted["sessions"] != 3 {
+		t.Errorf("recovered status = %+v", s)
+	}
+	if len(calls) != 3 || calls[0] || !calls[1] || calls[2] {
+		t.Errorf("evictor calls = %v", calls)
+	}
+}
+
+func TestNoLimitNoPressure(t *testing.T) {
+	prev := debug.SetMemoryLimit(math.MaxInt64)
+	t.Cleanup(func() { debug.SetMemoryLimit(prev) })
+
+	g := New(testConfig())
+	g.readRSS = func() uint64 { return 1 << 40 }
+	if s := g.Check(); s.Pressure || s.Limit != 0 {
+		t.Errorf("status without a limit = %+v", s)
+	}
+}
@@ -12,10 +12,13 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/constants"
+	"github.com/sipeed/picoclaw/pkg/i18n"
+	"github.com/sipeed/picoclaw/pkg/locale"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/state"
 	"github.com/sipeed/picoclaw/pkg/tools"
@@ -24,8 +27,17 @@ import (
 const (
 	minIntervalMinutes     = 5
 	defaultIntervalMinutes = 30
+
+	// stateNamespace holds the last run, so a restart doesn't repeat a
+	// heartbeat that just ran.
+	stateNamespace = "heartbeat"
 )
 
+type lastRun struct {
+	At     time.Time `json:"at"`
+	Status string    `json:"status"`
+}
+
 // HeartbeatHandler is the function type for handling heartbeat.
 // It returns a ToolResult that can indicate async operations.
 // channel and chatID are derived from the last active user channel.
@@ -41,6 +53,10 @@ type HeartbeatService struct {
 	enabled   bool
 	mu        sync.RWMutex
 	stopChan  chan struct{}
+	locales   atomic.Pointer[locale.Store]
+
+	lastRunAt  time.Time
+	lastStatus string
 }
 
 // NewHeartbeatService creates a new heartbeat service
@@ -54,12 +70,18 @@ func NewHeartbeatService(workspace string, intervalMinutes int, enabled bool) *H
 		intervalMinutes = defaultIntervalMinutes
 	}
 
-	return &HeartbeatService{
+	hs := &HeartbeatService{
 		workspace: workspace,
 		interval:  time.Duration(intervalMinutes) * time.Minute,
 		enabled:   enabled,
 		state:     state.NewManager(workspace),
 	}
+	var last lastRun
+	if ok, _ := hs.state.Get(stateNamespace, "last_run", &last); ok {
+		hs.lastRunAt = last.At
+		hs.lastStatus = last.Status
+	}
+	return hs
 }
 
 // SetBus sets the message bus for delivering heartbeat results.
@@ -69,6 +91,12 @@ func (hs *HeartbeatService) SetBus(msgBus *bus.MessageBus) {
 	hs.bus = msgBus
 }
 
+// SetLocales makes the prompt and the log show times in the time zone of
+// the chat the heartbeat reports to.
+func (hs *HeartbeatService) SetLocales(store *locale.Store) {
+	hs.locales.Store(store)
+}
+
 // SetHandler sets the heartbeat handler.
 func (hs *HeartbeatService) SetHandler(handler HeartbeatHandler) {
 	hs.mu.Lock()
@@ -122,13 +150,50 @@ func (hs *HeartbeatService) IsRunning() bool {
 	return hs.stopChan != nil
 }
 
+// Status reports the service configuration and the outcome of the last heartbeat.
+func (hs *HeartbeatService) Status() map[string]interface{} {
+	hs.mu.RLock()
+	defer hs.mu.RUnlock()
+
+	status := map[string]interface{}{
+		"enabled":          hs.enabled,
+		"running":          hs.stopChan != nil,
+		"interval_minutes": hs.interval.Minutes(),
+		"last_status":      hs.lastStatus,
+	}
+	if !hs.lastRunAt.IsZero() {
+		status["last_run_at"] = hs.lastRunAt
+	}
+	return status
+}
+
+// recordRun remembers when the last heartbeat ran and how it ended.
+func (hs *HeartbeatService) recordRun(status string) {
+	hs.mu.Lock()
+	hs.lastRunAt = time.Now()
+	hs.lastStatus = status
+	last := lastRun{At: hs.lastRunAt, Status: status}
+	hs.mu.Unlock()
+
+	if err := hs.state.Set(stateNamespace, "last_run", last, 0); err != nil {
+		hs.logError("Failed to save heartbeat state: %v", err)
+	}
+}
+
 // runLoop runs the heartbeat ticker
 func (hs *HeartbeatService) runLoop(stopChan chan struct{}) {
 	ticker := time.NewTicker(hs.interval)
 	defer ticker.Stop()
 
-	// Run first heartbeat after initial delay
-	time.AfterFunc(time.Second, func() {
+	// Run first heartbeat after initial delay, or when it is due if one ran
+	// shortly before a restart
+	delay := time.Second
+	hs.mu.RLock()
+	if due := time.Until(hs.lastRunAt.Add(hs.interval)); due > delay {
+		delay = due
+	}
+	hs.mu.RUnlock()
+	time.AfterFunc(delay, func() {
 		hs.executeHeartbeat()
 	})
 
@@ -159,21 +224,23 @@ func (hs *HeartbeatService) executeHeartbeat() {
 
 	logger.DebugC("heartbeat", "Executing heartbeat")
 
-	prompt := hs.buildPrompt()
+	// Get last channel info for context
+	lastChannel := hs.state.GetLastChannel()
+	channel, chatID := hs.parseLastChannel(lastChannel)
+
+	prompt := hs.buildPrompt(hs.settingsFor(channel, chatID))
 	if prompt == "" {
 		logger.InfoC("heartbeat", "No heartbeat prompt (HEARTBEAT.md empty or missing)")
+		hs.recordRun("empty")
 		return
 	}
 
 	if handler == nil {
 		hs.logError("Heartbeat handler not configured")
+		hs.recordRun("error")
 		return
 	}
 
-	// Get last channel info for context
-	lastChannel := hs.state.GetLastChannel()
-	channel, chatID := hs.parseLastChannel(lastChannel)
-
 	// Debug log for channel resolution
 	hs.logInfo("Resolved channel: %s, chatID: %s (from lastChannel: %s)", channel, chatID, lastChannel)
 
@@ -181,12 +248,14 @@ func (hs *HeartbeatService) executeHeartbeat() {
 
 	if result == nil {
 		hs.logInfo("Heartbeat handler returned nil result")
+		hs.recordRun("ok")
 		return
 	}
 
 	// Handle different result types
 	if result.IsError {
 		hs.logError("Heartbeat error: %s", result.ForLLM)
+		hs.recordRun("error")
 		return
 	}
 
@@ -196,12 +265,14 @@ func (hs *HeartbeatService) executeHeartbeat() {
 			map[string]interface{}{
 				"message": result.ForLLM,
 			})
+		hs.recordRun("async")
 		return
 	}
 
 	// Check if silent
 	if result.Silent {
 		hs.logInfo("Heartbeat OK - silent")
+		hs.recordRun("ok")
 		return
 	}
 
@@ -212,17 +283,31 @@ func (hs *HeartbeatService) executeHeartbeat() {
 		hs.sendResponse(result.ForLLM)
 	}
 
+	hs.recordRun("sent")
 	hs.logInfo("Heartbeat completed: %s", result.ForLLM)
 }
 
-// buildPrompt builds the heartbeat prompt from HEARTBEAT.md
-func (hs *HeartbeatService) buildPrompt() string {
+// settingsFor returns the locale settings of a chat.
+func (hs *HeartbeatService) settingsFor(channel, chatID string) locale.Settings {
+	var settings locale.Settings
+	if store := hs.locales.Load(); store != nil {
+		settings = store.Resolve(channel, chatID, "")
+	}
+	if settings.Locale == "" {
+		settings.Locale = i18n.ForChat(channel, chatID)
+	}
+	return settings
+}
+
+// buildPrompt builds the heartbeat prompt from HEARTBEAT.md, in the
+// language and time zone of settings.
+func (hs *HeartbeatService) buildPrompt(settings locale.Settings) string {
 	heartbeatPath := filepath.Join(hs.workspace, "HEARTBEAT.md")
 
 	data, err := os.ReadFile(heartbeatPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			hs.createDefaultHeartbeatTemplate()
+			hs.createDefaultHeartbeatTemplate(settings.Locale)
 			return ""
 		}
 		hs.logError("Error reading HEARTBEAT.md: %v", err)
@@ -234,46 +319,15 @@ func (hs *HeartbeatService) buildPrompt() string {
 		return ""
 	}
 
-	now := time.Now().Format("2006-01-02 15:04:05")
-	return fmt.Sprintf(`# Heartbeat Check
-
-Current time: %s
-
-You are a proactive AI assistant. This is a scheduled heartbeat check.
-Review the following tasks and execute any necessary actions using available skills.
-If there is nothing that requires attention, respond ONLY with: HEARTBEAT_OK
-
-%s
-`, now, content)
+	now := time.Now().In(settings.Location()).Format("2006-01-02 15:04:05 MST")
+	return i18n.T(settings.Locale, i18n.HeartbeatPrompt, now, content)
 }
 
 // createDefaultHeartbeatTemplate creates the default HEARTBEAT.md file
-func (hs *HeartbeatService) createDefaultHeartbeatTemplate() {
+func (hs *HeartbeatService) createDefaultHeartbeatTemplate(locale string) {
 	heartbeatPath := filepath.Join(hs.workspace, "HEARTBEAT.md")
 
-	defaultContent := `# Heartbeat Check List
-
-This file contains tasks for the heartbeat service to check periodically.
-
-## Examples
-
-- Check for unread messages
-- Review upcoming calendar events
-- Check device status (e.g., MaixCam)
-
-## Instructions
-
-- Execute ALL tasks listed below. Do NOT skip any task.
-- For simple tasks (e.g., report current time), respond directly.
-- For complex tasks that may take time, use the spawn tool to create a subagent.
-- The spawn tool is async - subagent results will be sent to the user automatically.
-- After spawning a subagent, CONTINUE to process remaining tasks.
-- Only respond with HEARTBEAT_OK when ALL tasks are done AND nothing needs attention.
-
----
-
-Add your heartbeat tasks below this line:
-`
+	defaultContent := i18n.T(locale, i18n.HeartbeatDefaultFile)
 
 	if err := os.WriteFile(heartbeatPath, []byte(defaultContent), 0644); err != nil {
 		hs.logError("Failed to create default HEARTBEAT.md: %v", err)
@@ -360,6 +414,8 @@ func (hs *HeartbeatService) log(level, format string, args ...any) {
 	}
 	defer f.Close()
 
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
+	// Parsed without logging, unlike parseLastChannel
+	channel, chatID, _ := strings.Cut(hs.state.GetLastChannel(), ":")
+	timestamp := time.Now().In(hs.settingsFor(channel, chatID).Location()).Format("2006-01-02 15:04:05 MST")
 	fmt.Fprintf(f, "[%s] [%s] %s\n", timestamp, level, fmt.Sprintf(format, args...))
 }
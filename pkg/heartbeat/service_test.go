@@ -3,9 +3,11 @@ package heartbeat
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/sipeed/picoclaw/pkg/locale"
 	"github.com/sipeed/picoclaw/pkg/tools"
 )
 
@@ -83,6 +85,11 @@ func TestExecuteHeartbeat_Error(t *testing.T) {
 	if logContent == "" {
 		t.Error("Expected log file to contain error message")
 	}
+
+	status := hs.Status()
+	if status["last_status"] != "error" || status["last_run_at"] == nil {
+		t.Errorf("Status() = %v, want last_status=error with last_run_at", status)
+	}
 }
 
 func TestExecuteHeartbeat_Silent(t *testing.T) {
@@ -121,6 +128,10 @@ func TestExecuteHeartbeat_Silent(t *testing.T) {
 	if logContent == "" {
 		t.Error("Expected log file to contain completion message")
 	}
+
+	if status := hs.Status(); status["last_status"] != "ok" {
+		t.Errorf("Status() last_status = %v, want ok", status["last_status"])
+	}
 }
 
 func TestHeartbeatService_StartStop(t *testing.T) {
@@ -211,7 +222,7 @@ func TestHeartbeatFilePath(t *testing.T) {
 	hs := NewHeartbeatService(tmpDir, 30, true)
 
 	// Trigger default template creation
-	hs.buildPrompt()
+	hs.buildPrompt(locale.Settings{})
 
 	// Verify HEARTBEAT.md exists at workspace root
 	expectedPath := filepath.Join(tmpDir, "HEARTBEAT.md")
@@ -219,3 +230,22 @@ func TestHeartbeatFilePath(t *testing.T) {
 		t.Errorf("Expected HEARTBEAT.md at %s, but it doesn't exist", expectedPath)
 	}
 }
+
+// TestPromptInChatTimeZone verifies the prompt shows the time in the zone
+// of the chat the heartbeat reports to
+func TestPromptInChatTimeZone(t *testing.T) {
+	tmpDir := t.TempDir()
+	hs := NewHeartbeatService(tmpDir, 30, true)
+	os.WriteFile(filepath.Join(tmpDir, "HEARTBEAT.md"), []byte("Check the plants"), 0644)
+
+	locales := locale.NewStore(tmpDir, locale.Settings{})
+	if err := locales.SetChat("telegram", "42", locale.Settings{TimeZone: "Asia/Tokyo"}); err != nil {
+		t.Fatal(err)
+	}
+	hs.SetLocales(locales)
+
+	prompt := hs.buildPrompt(hs.settingsFor("telegram", "42"))
+	if !strings.Contains(prompt, time.Now().In(time.FixedZone("JST", 9*3600)).Format("2006-01-02 15:")) || !strings.Contains(prompt, "JST") {
+		t.Errorf("prompt not in the chat's zone: %q", prompt)
+	}
+}
@@ -0,0 +1,105 @@
+package i18n
+
+var en = map[string]string{
+	AgentNoResponse:   "I've completed processing but have no response to give.",
+	AgentError:        "Error processing message: %v",
+	AgentTurnCanceled: "Turn cancelled.",
+	AgentQueued:       "📡 I'm offline right now. Your message is queued and I'll answer when I'm back online.",
+	AgentToolUsage:    "Usage: /tool <name> [JSON arguments]. Available: %s",
+	AgentToolDenied:   "%s can't be run directly. Available: %s",
+
+	ChannelThinking: "Thinking... 💭",
+
+	CronCommandFailed: "Error executing scheduled command: %s",
+	CronCommandDone:   "Scheduled command '%s' executed:\n%s",
+
+	CronReminderAckHint:   "Reply \"done\" when you have, and I'll stop reminding you.",
+	CronReminderButton:    "✅ Done",
+	CronReminderRepeat:    "⏰ Reminder (attempt %d): %s",
+	CronReminderEscalated: "⚠️ A reminder for %s is still unacknowledged after %d attempts: %s",
+	CronReminderAcked:     "✅ Acknowledged: %s",
+
+	DeviceConnected:    "🔌 Device Connected",
+	DeviceDisconnected: "🔌 Device Disconnected",
+	DeviceType:         "Type: %s",
+	DeviceName:         "Device: %s",
+	DeviceCapabilities: "Capabilities: %s",
+	DeviceSerial:       "Serial: %s",
+
+	FeedbackThanks: "Thanks for the feedback.",
+	FeedbackUsage:  "Usage: /feedback good|bad [comment], or /feedback <comment>",
+	FeedbackNoTurn: "There is no answer here to rate yet.",
+
+	HeartbeatPrompt: `# Heartbeat Check
+
+Current time: %s
+
+You are a proactive AI assistant. This is a scheduled heartbeat check.
+Review the following tasks and execute any necessary actions using available skills.
+If there is nothing that requires attention, respond ONLY with: HEARTBEAT_OK
+
+%s
+`,
+	HeartbeatDefaultFile: `# Heartbeat Check List
+
+This file contains tasks for the heartbeat service to check periodically.
+
+## Examples
+
+- Check for unread messages
+- Review upcoming calendar events
+- Check device status (e.g., MaixCam)
+
+## Instructions
+
+- Execute ALL tasks listed below. Do NOT skip any task.
+- For simple tasks (e.g., report current time), respond directly.
+- For complex tasks that may take time, use the spawn tool to create a subagent.
+- The spawn tool is async - subagent results will be sent to the user automatically.
+- After spawning a subagent, CONTINUE to process remaining tasks.
+- Only respond with HEARTBEAT_OK when ALL tasks are done AND nothing needs attention.
+
+---
+
+Add your heartbeat tasks below this line:
+`,
+
+	PinUsage:   "Usage: /pin <note>, /pins, /unpin <n>, /unpin all",
+	PinAdded:   "📌 Pinned: %s",
+	PinFull:    "Pinned notes are limited to %d characters in total. Unpin or shorten one first.",
+	PinList:    "📌 Pinned notes:%s",
+	PinNone:    "Nothing is pinned in this chat. Pin a note with /pin <note>.",
+	PinRemoved: "Unpinned: %s",
+	PinUnknown: "There is no pinned note %s.",
+	PinCleared: "Unpinned %d notes.",
+
+	ProgressWebSearch: "🔎 Searching the web…",
+	ProgressWebFetch:  "🌐 Reading a web page…",
+	ProgressExec:      "⚙️ Running a command…",
+	ProgressFiles:     "📄 Working on files…",
+	ProgressDelegate:  "🤝 Handing part of the task off…",
+	ProgressTool:      "🛠️ Using %s…",
+
+	TenantQuota:    "You have used up today's allowance. Please try again tomorrow.",
+	TenantsUsage:   "Usage: /tenants [list], /tenants show <id>, /tenants purge <id>",
+	TenantsNone:    "No tenants yet.",
+	TenantsList:    "%d tenants:\n%s",
+	TenantsShow:    "%s\nOwner: %s:%s\nWorkspace: %s\nDisk: %s\nTokens: %d today, %d in total\nCreated: %s\nLast seen: %s",
+	TenantsUnknown: "No tenant %s.",
+	TenantsPurged:  "Tenant %s and its workspace were deleted.",
+
+	TranslateUsage:           "Usage: /translate [on|off], /glossary [add <term> = <translation> | remove <n> | clear]",
+	TranslateStatus:          "This group is translated between %s. Your messages are included; /translate off leaves them out.",
+	TranslateStatusOff:       "This group is translated between %s. Your messages are left out; /translate on includes them again.",
+	TranslateOff:             "Your messages will no longer be translated.",
+	TranslateOn:              "Your messages will be translated again.",
+	TranslateGlossary:        "Glossary:%s",
+	TranslateGlossaryEmpty:   "The glossary is empty. Add terms with /glossary add <term> = <translation>.",
+	TranslateGlossaryAdded:   "Added to the glossary.",
+	TranslateGlossaryRemoved: "Removed from the glossary.",
+	TranslateGlossaryCleared: "The glossary was cleared.",
+	TranslateGlossaryFull:    "The glossary is full (%d entries). Remove one first.",
+	TranslateGlossaryUnknown: "There is no glossary entry %s.",
+
+	UpdateAvailable: "🆕 picoclaw %s is available (this is %s). Run `picoclaw update` to install it.%s",
+}
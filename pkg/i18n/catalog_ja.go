@@ -0,0 +1,105 @@
+package i18n
+
+var ja = map[string]string{
+	AgentNoResponse:   "処理は完了しましたが、お返しする内容はありません。",
+	AgentError:        "メッセージの処理中にエラーが発生しました：%v",
+	AgentTurnCanceled: "このターンはキャンセルされました。",
+	AgentQueued:       "📡 現在オフラインです。メッセージはキューに入れました。オンラインに戻り次第お答えします。",
+	AgentToolUsage:    "使い方：/tool <名前> [JSON 引数]。利用可能：%s",
+	AgentToolDenied:   "%s は直接実行できません。利用可能：%s",
+
+	ChannelThinking: "考え中... 💭",
+
+	CronCommandFailed: "スケジュールされたコマンドの実行に失敗しました：%s",
+	CronCommandDone:   "スケジュールされたコマンド '%s' を実行しました：\n%s",
+
+	CronReminderAckHint:   "済んだら「完了」と返信してください。リマインドを止めます。",
+	CronReminderButton:    "✅ 完了",
+	CronReminderRepeat:    "⏰ リマインド（%d 回目）：%s",
+	CronReminderEscalated: "⚠️ %s へのリマインドが %d 回送っても確認されていません：%s",
+	CronReminderAcked:     "✅ 確認しました：%s",
+
+	DeviceConnected:    "🔌 デバイスが接続されました",
+	DeviceDisconnected: "🔌 デバイスが切断されました",
+	DeviceType:         "種類：%s",
+	DeviceName:         "デバイス：%s",
+	DeviceCapabilities: "機能：%s",
+	DeviceSerial:       "シリアル番号：%s",
+
+	FeedbackThanks: "フィードバックありがとうございます。",
+	FeedbackUsage:  "使い方：/feedback good|bad [コメント]、または /feedback <コメント>",
+	FeedbackNoTurn: "まだ評価できる回答がありません。",
+
+	HeartbeatPrompt: `# ハートビートチェック
+
+現在時刻：%s
+
+あなたは能動的な AI アシスタントです。これは定期的なハートビートチェックです。
+以下のタスクを確認し、利用可能なスキルを使って必要な操作を実行してください。
+対応が必要なことが何もなければ、HEARTBEAT_OK とだけ返信してください。
+
+%s
+`,
+	HeartbeatDefaultFile: `# ハートビートチェックリスト
+
+このファイルには、ハートビートサービスが定期的に確認するタスクを記載します。
+
+## 例
+
+- 未読メッセージを確認する
+- 今後の予定を確認する
+- デバイスの状態を確認する（例：MaixCam）
+
+## 指示
+
+- 以下に記載されたタスクをすべて実行してください。省略しないでください。
+- 簡単なタスク（例：現在時刻の報告）には直接返信してください。
+- 時間のかかる複雑なタスクには、spawn ツールでサブエージェントを作成してください。
+- spawn ツールは非同期です。サブエージェントの結果は自動的にユーザーに送信されます。
+- サブエージェントを作成した後も、残りのタスクの処理を続けてください。
+- すべてのタスクが完了し、対応が必要なことがない場合にのみ HEARTBEAT_OK と返信してください。
+
+---
+
+この行の下にハートビートタスクを追加してください：
+`,
+
+	PinUsage:   "使い方：/pin <メモ>、/pins、/unpin <番号>、/unpin all",
+	PinAdded:   "📌 ピン留めしました：%s",
+	PinFull:    "ピン留めできるメモは合計 %d 文字までです。先に外すか短くしてください。",
+	PinList:    "📌 ピン留めしたメモ：%s",
+	PinNone:    "この会話にピン留めはありません。/pin <メモ> で追加できます。",
+	PinRemoved: "ピン留めを外しました：%s",
+	PinUnknown: "%s 番のピン留めはありません。",
+	PinCleared: "%d 件のピン留めを外しました。",
+
+	ProgressWebSearch: "🔎 ウェブを検索しています…",
+	ProgressWebFetch:  "🌐 ウェブページを読んでいます…",
+	ProgressExec:      "⚙️ コマンドを実行しています…",
+	ProgressFiles:     "📄 ファイルを処理しています…",
+	ProgressDelegate:  "🤝 タスクの一部を任せています…",
+	ProgressTool:      "🛠️ %s を使っています…",
+
+	TenantQuota:    "本日の利用上限に達しました。明日また試してください。",
+	TenantsUsage:   "使い方：/tenants [list]、/tenants show <id>、/tenants purge <id>",
+	TenantsNone:    "テナントはまだありません。",
+	TenantsList:    "テナント %d 件：\n%s",
+	TenantsShow:    "%s\n所有者：%s:%s\nワークスペース：%s\nディスク：%s\nトークン：本日 %d、合計 %d\n作成：%s\n最終利用：%s",
+	TenantsUnknown: "テナント %s はありません。",
+	TenantsPurged:  "テナント %s とそのワークスペースを削除しました。",
+
+	TranslateUsage:           "使い方：/translate [on|off]、/glossary [add <用語> = <訳語> | remove <番号> | clear]",
+	TranslateStatus:          "このグループは %s の間で翻訳されています。あなたのメッセージも翻訳されます。/translate off で除外できます。",
+	TranslateStatusOff:       "このグループは %s の間で翻訳されています。あなたのメッセージは翻訳されません。/translate on で再開できます。",
+	TranslateOff:             "あなたのメッセージは今後翻訳されません。",
+	TranslateOn:              "あなたのメッセージの翻訳を再開します。",
+	TranslateGlossary:        "用語集：%s",
+	TranslateGlossaryEmpty:   "用語集は空です。/glossary add <用語> = <訳語> で追加できます。",
+	TranslateGlossaryAdded:   "用語集に追加しました。",
+	TranslateGlossaryRemoved: "用語集から削除しました。",
+	TranslateGlossaryCleared: "用語集を空にしました。",
+	TranslateGlossaryFull:    "用語集がいっぱいです（%d 件）。先に削除してください。",
+	TranslateGlossaryUnknown: "用語集に %s 番の項目はありません。",
+
+	UpdateAvailable: "🆕 picoclaw %s が公開されました（現在は %s）。`picoclaw update` でインストールできます。%s",
+}
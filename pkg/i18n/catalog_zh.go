@@ -0,0 +1,105 @@
+package i18n
+
+var zh = map[string]string{
+	AgentNoResponse:   "处理已完成，但没有需要回复的内容。",
+	AgentError:        "处理消息时出错：%v",
+	AgentTurnCanceled: "本轮已取消。",
+	AgentQueued:       "📡 当前处于离线状态。你的消息已加入队列，恢复联网后会立即回复。",
+	AgentToolUsage:    "用法：/tool <名称> [JSON 参数]。可用工具：%s",
+	AgentToolDenied:   "%s 不能直接运行。可用工具：%s",
+
+	ChannelThinking: "思考中... 💭",
+
+	CronCommandFailed: "定时命令执行失败：%s",
+	CronCommandDone:   "定时命令 '%s' 已执行：\n%s",
+
+	CronReminderAckHint:   "完成后请回复“收到”，我就不再提醒。",
+	CronReminderButton:    "✅ 完成",
+	CronReminderRepeat:    "⏰ 提醒（第 %d 次）：%s",
+	CronReminderEscalated: "⚠️ 发给 %s 的提醒已提醒 %d 次仍未确认：%s",
+	CronReminderAcked:     "✅ 已确认：%s",
+
+	DeviceConnected:    "🔌 设备已连接",
+	DeviceDisconnected: "🔌 设备已断开",
+	DeviceType:         "类型：%s",
+	DeviceName:         "设备：%s",
+	DeviceCapabilities: "功能：%s",
+	DeviceSerial:       "序列号：%s",
+
+	FeedbackThanks: "感谢你的反馈。",
+	FeedbackUsage:  "用法：/feedback good|bad [评论]，或 /feedback <评论>",
+	FeedbackNoTurn: "这里还没有可以评价的回答。",
+
+	HeartbeatPrompt: `# 心跳检查
+
+当前时间：%s
+
+你是一个主动的 AI 助手，这是一次定时心跳检查。
+请查看以下任务，并使用可用的技能执行需要的操作。
+如果没有需要处理的事项，只回复：HEARTBEAT_OK
+
+%s
+`,
+	HeartbeatDefaultFile: `# 心跳检查清单
+
+此文件包含心跳服务定期检查的任务。
+
+## 示例
+
+- 检查未读消息
+- 查看即将到来的日程
+- 检查设备状态（例如 MaixCam）
+
+## 说明
+
+- 执行下面列出的所有任务，不要跳过任何任务。
+- 简单任务（例如报告当前时间）直接回复。
+- 耗时较长的复杂任务，使用 spawn 工具创建子代理。
+- spawn 工具是异步的，子代理的结果会自动发送给用户。
+- 创建子代理后，继续处理剩余的任务。
+- 只有在所有任务都完成且没有需要关注的事项时，才回复 HEARTBEAT_OK。
+
+---
+
+在此行下方添加你的心跳任务：
+`,
+
+	PinUsage:   "用法：/pin <备注>、/pins、/unpin <编号>、/unpin all",
+	PinAdded:   "📌 已置顶：%s",
+	PinFull:    "置顶备注总共最多 %d 个字符。请先取消或缩短一条。",
+	PinList:    "📌 置顶备注：%s",
+	PinNone:    "此对话中没有置顶备注。使用 /pin <备注> 添加。",
+	PinRemoved: "已取消置顶：%s",
+	PinUnknown: "没有第 %s 条置顶备注。",
+	PinCleared: "已取消 %d 条置顶备注。",
+
+	ProgressWebSearch: "🔎 正在搜索网页…",
+	ProgressWebFetch:  "🌐 正在阅读网页…",
+	ProgressExec:      "⚙️ 正在运行命令…",
+	ProgressFiles:     "📄 正在处理文件…",
+	ProgressDelegate:  "🤝 正在分派部分任务…",
+	ProgressTool:      "🛠️ 正在使用 %s…",
+
+	TenantQuota:    "你今天的额度已用完，请明天再试。",
+	TenantsUsage:   "用法：/tenants [list]、/tenants show <id>、/tenants purge <id>",
+	TenantsNone:    "还没有租户。",
+	TenantsList:    "共 %d 个租户：\n%s",
+	TenantsShow:    "%s\n所有者：%s:%s\n工作区：%s\n磁盘：%s\n令牌：今天 %d，总计 %d\n创建于：%s\n最近活动：%s",
+	TenantsUnknown: "没有租户 %s。",
+	TenantsPurged:  "已删除租户 %s 及其工作区。",
+
+	TranslateUsage:           "用法：/translate [on|off]，/glossary [add <术语> = <译法> | remove <编号> | clear]",
+	TranslateStatus:          "本群在 %s 之间互译。你的消息会被翻译；发送 /translate off 可排除。",
+	TranslateStatusOff:       "本群在 %s 之间互译。你的消息不会被翻译；发送 /translate on 可恢复。",
+	TranslateOff:             "你的消息将不再被翻译。",
+	TranslateOn:              "你的消息将重新被翻译。",
+	TranslateGlossary:        "术语表：%s",
+	TranslateGlossaryEmpty:   "术语表为空。使用 /glossary add <术语> = <译法> 添加。",
+	TranslateGlossaryAdded:   "已加入术语表。",
+	TranslateGlossaryRemoved: "已从术语表删除。",
+	TranslateGlossaryCleared: "术语表已清空。",
+	TranslateGlossaryFull:    "术语表已满（%d 条），请先删除一条。",
+	TranslateGlossaryUnknown: "术语表中没有第 %s 条。",
+
+	UpdateAvailable: "🆕 picoclaw %s 已发布（当前为 %s）。运行 `picoclaw update` 进行安装。%s",
+}
@@ -0,0 +1,115 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// Inspired by and based on nanobot: https://github.com/HKUDS/nanobot
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+// Package i18n holds translations of the fixed messages PicoClaw sends to
+// users. Messages are looked up by key in the catalog of the user's
+// language, falling back to English.
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Fallback is the language every key must exist in.
+const Fallback = "en"
+
+// catalogs maps a language to its messages. Values are fmt templates.
+var catalogs = map[string]map[string]string{
+	"en": en,
+	"zh": zh,
+	"ja": ja,
+}
+
+// Languages returns the languages that have a catalog.
+func Languages() []string {
+	out := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		out = append(out, lang)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Lang returns the catalog language for a locale tag such as "zh-CN",
+// or Fallback if there is none.
+func Lang(locale string) string {
+	lang, _, _ := strings.Cut(strings.ToLower(strings.ReplaceAll(locale, "_", "-")), "-")
+	if _, ok := catalogs[lang]; ok {
+		return lang
+	}
+	return Fallback
+}
+
+// T returns the message for key in the language of locale, formatted with
+// args. Keys missing from the catalog come from the English one; unknown
+// keys are returned as they are.
+func T(locale, key string, args ...interface{}) string {
+	msg, ok := catalogs[Lang(locale)][key]
+	if !ok {
+		msg, ok = catalogs[Fallback][key]
+	}
+	if !ok {
+		return key
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// Resolver returns the locale of a user in a chat. senderID may be empty
+// for messages addressed to the whole chat.
+type Resolver func(channel, chatID, senderID string) string
+
+var (
+	mu       sync.RWMutex
+	resolver Resolver
+	fallback string
+)
+
+// SetResolver sets how the locale of a chat or user is found. The agent
+// registers its per-user settings here.
+func SetResolver(r Resolver) {
+	mu.Lock()
+	defer mu.Unlock()
+	resolver = r
+}
+
+// SetDefault sets the locale used when no resolver is registered, e.g. for
+// the configured agents.defaults.locale.
+func SetDefault(locale string) {
+	mu.Lock()
+	defer mu.Unlock()
+	fallback = locale
+}
+
+// Default returns the configured default locale.
+func Default() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return fallback
+}
+
+// ForUser returns the locale for a user in a chat.
+func ForUser(channel, chatID, senderID string) string {
+	mu.RLock()
+	r, def := resolver, fallback
+	mu.RUnlock()
+	if r != nil {
+		if locale := r(channel, chatID, senderID); locale != "" {
+			return locale
+		}
+	}
+	return def
+}
+
+// ForChat returns the locale for messages to a whole chat.
+func ForChat(channel, chatID string) string {
+	return ForUser(channel, chatID, "")
+}
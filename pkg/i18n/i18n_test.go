@@ -0,0 +1,97 @@
+package i18n
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var verbRe = regexp.MustCompile(`%[-+# 0]*[0-9]*(\.[0-9]+)?[a-zA-Z%]`)
+
+// TestCatalogsComplete keeps every catalog in step with the English one:
+// same keys, same format verbs in the same order, and protocol tokens kept.
+func TestCatalogsComplete(t *testing.T) {
+	for _, lang := range Languages() {
+		if lang == Fallback {
+			continue
+		}
+		catalog := catalogs[lang]
+		for key, want := range catalogs[Fallback] {
+			got, ok := catalog[key]
+			if !ok {
+				t.Errorf("%s: missing key %q", lang, key)
+				continue
+			}
+			if w, g := verbs(want), verbs(got); w != g {
+				t.Errorf("%s: %q has format verbs %q, English has %q", lang, key, g, w)
+			}
+			if strings.Contains(want, "HEARTBEAT_OK") && !strings.Contains(got, "HEARTBEAT_OK") {
+				t.Errorf("%s: %q must keep HEARTBEAT_OK untranslated", lang, key)
+			}
+		}
+		for key := range catalog {
+			if _, ok := catalogs[Fallback][key]; !ok {
+				t.Errorf("%s: key %q is not in the English catalog", lang, key)
+			}
+		}
+	}
+}
+
+func verbs(s string) string {
+	return strings.Join(verbRe.FindAllString(s, -1), " ")
+}
+
+func TestT(t *testing.T) {
+	tests := []struct {
+		locale string
+		key    string
+		args   []interface{}
+		want   string
+	}{
+		{"zh-CN", DeviceType, []interface{}{"usb"}, "类型：usb"},
+		{"ja_JP", AgentTurnCanceled, nil, "このターンはキャンセルされました。"},
+		{"fr-FR", AgentError, []interface{}{"boom"}, "Error processing message: boom"},
+		{"", AgentTurnCanceled, nil, "Turn cancelled."},
+		{"zh", "no.such.key", nil, "no.such.key"},
+	}
+	for _, tt := range tests {
+		if got := T(tt.locale, tt.key, tt.args...); got != tt.want {
+			t.Errorf("T(%q, %q) = %q, want %q", tt.locale, tt.key, got, tt.want)
+		}
+	}
+
+	en["test.only_en"] = "english"
+	defer delete(en, "test.only_en")
+	if got := T("zh", "test.only_en"); got != "english" {
+		t.Errorf("missing key should fall back to English, got %q", got)
+	}
+}
+
+func TestResolver(t *testing.T) {
+	defer SetResolver(nil)
+	defer SetDefault("")
+
+	SetDefault("ja")
+	if got := ForChat("telegram", "1"); got != "ja" {
+		t.Errorf("default = %q", got)
+	}
+
+	SetResolver(func(channel, chatID, senderID string) string {
+		if senderID == "alice" {
+			return "zh-CN"
+		}
+		return ""
+	})
+	if got := ForUser("telegram", "1", "alice"); got != "zh-CN" {
+		t.Errorf("resolved = %q", got)
+	}
+	if got := ForUser("telegram", "1", "bob"); got != "ja" {
+		t.Errorf("unresolved user should get the default, got %q", got)
+	}
+
+	langs := Languages()
+	if !sort.StringsAreSorted(langs) || len(langs) < 3 {
+		t.Errorf("languages = %v", langs)
+	}
+}
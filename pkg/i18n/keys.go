@@ -0,0 +1,76 @@
+package i18n
+
+// Message keys. Every key must be in the English catalog; the test keeps
+// the other catalogs complete.
+const (
+	AgentNoResponse   = "agent.no_response"
+	AgentError        = "agent.error"
+	AgentTurnCanceled = "agent.turn_cancelled"
+	AgentQueued       = "agent.queued_offline"
+	AgentToolUsage    = "agent.tool_usage"
+	AgentToolDenied   = "agent.tool_denied"
+
+	ChannelThinking = "channel.thinking"
+
+	CronCommandFailed = "cron.command_failed"
+	CronCommandDone   = "cron.command_done"
+
+	CronReminderAckHint   = "cron.reminder_ack_hint"
+	CronReminderButton    = "cron.reminder_button"
+	CronReminderRepeat    = "cron.reminder_repeat"
+	CronReminderEscalated = "cron.reminder_escalated"
+	CronReminderAcked     = "cron.reminder_acked"
+
+	DeviceConnected    = "device.connected"
+	DeviceDisconnected = "device.disconnected"
+	DeviceType         = "device.type"
+	DeviceName         = "device.name"
+	DeviceCapabilities = "device.capabilities"
+	DeviceSerial       = "device.serial"
+
+	FeedbackThanks = "feedback.thanks"
+	FeedbackUsage  = "feedback.usage"
+	FeedbackNoTurn = "feedback.no_turn"
+
+	HeartbeatPrompt      = "heartbeat.prompt"
+	HeartbeatDefaultFile = "heartbeat.default_file"
+
+	PinUsage   = "pin.usage"
+	PinAdded   = "pin.added"
+	PinFull    = "pin.full"
+	PinList    = "pin.list"
+	PinNone    = "pin.none"
+	PinRemoved = "pin.removed"
+	PinUnknown = "pin.unknown"
+	PinCleared = "pin.cleared"
+
+	ProgressWebSearch = "progress.web_search"
+	ProgressWebFetch  = "progress.web_fetch"
+	ProgressExec      = "progress.exec"
+	ProgressFiles     = "progress.files"
+	ProgressDelegate  = "progress.delegate"
+	ProgressTool      = "progress.tool"
+
+	TenantQuota    = "tenant.quota"
+	TenantsUsage   = "tenants.usage"
+	TenantsNone    = "tenants.none"
+	TenantsList    = "tenants.list"
+	TenantsShow    = "tenants.show"
+	TenantsUnknown = "tenants.unknown"
+	TenantsPurged  = "tenants.purged"
+
+	TranslateUsage           = "translate.usage"
+	TranslateStatus          = "translate.status"
+	TranslateStatusOff       = "translate.status_off"
+	TranslateOff             = "translate.off"
+	TranslateOn              = "translate.on"
+	TranslateGlossary        = "translate.glossary"
+	TranslateGlossaryEmpty   = "translate.glossary_empty"
+	TranslateGlossaryAdded   = "translate.glossary_added"
+	TranslateGlossaryRemoved = "translate.glossary_removed"
+	TranslateGlossaryCleared = "translate.glossary_cleared"
+	TranslateGlossaryFull    = "translate.glossary_full"
+	TranslateGlossaryUnknown = "translate.glossary_unknown"
+
+	UpdateAvailable = "update.available"
+)
@@ -0,0 +1,329 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// Inspired by and based on nanobot: https://github.com/HKUDS/nanobot
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+// Package locale keeps the time zone and locale of each user and chat, so
+// that dates in the prompt, daily notes, schedules and replies match the
+// person talking to the agent rather than the server.
+package locale
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	// Boards often ship without a zoneinfo database.
+	_ "time/tzdata"
+)
+
+// Metadata keys channels set on inbound messages when the platform reports
+// the sender's settings.
+const (
+	MetaTimeZone = "timezone"
+	MetaLocale   = "locale"
+)
+
+// Settings are a time zone (IANA name such as "Europe/Berlin") and a locale
+// (BCP 47 tag such as "de-DE"). Empty fields fall back to the next level.
+type Settings struct {
+	TimeZone string `json:"timezone,omitempty"`
+	Locale   string `json:"locale,omitempty"`
+}
+
+var localeRe = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// Normalize cleans up the settings and checks them. Locale tags accept "_"
+// as separator ("pt_BR").
+func (s Settings) Normalize() (Settings, error) {
+	s.TimeZone = strings.TrimSpace(s.TimeZone)
+	s.Locale = strings.ReplaceAll(strings.TrimSpace(s.Locale), "_", "-")
+	if s.TimeZone != "" {
+		if _, err := time.LoadLocation(s.TimeZone); err != nil {
+			return s, fmt.Errorf("unknown time zone %q", s.TimeZone)
+		}
+	}
+	if s.Locale != "" && !localeRe.MatchString(s.Locale) {
+		return s, fmt.Errorf("invalid locale %q (use a tag such as en-US or zh-CN)", s.Locale)
+	}
+	return s, nil
+}
+
+// Location returns the time zone, or the server's zone when none is set.
+func (s Settings) Location() *time.Location {
+	if s.TimeZone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(s.TimeZone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// ZoneName returns the time zone for display.
+func (s Settings) ZoneName() string {
+	if s.TimeZone != "" {
+		return s.TimeZone
+	}
+	return time.Local.String()
+}
+
+// merge fills empty fields from fallback.
+func (s Settings) merge(fallback Settings) Settings {
+	if s.TimeZone == "" {
+		s.TimeZone = fallback.TimeZone
+	}
+	if s.Locale == "" {
+		s.Locale = fallback.Locale
+	}
+	return s
+}
+
+// Format formats t in the settings' zone, in a layout that suits the
+// locale.
+func (s Settings) Format(t time.Time) string {
+	return t.In(s.Location()).Format(layout(s.Locale))
+}
+
+// FormatDate formats the date of t in the settings' zone.
+func (s Settings) FormatDate(t time.Time) string {
+	return t.In(s.Location()).Format(dateLayout(s.Locale))
+}
+
+func layout(locale string) string {
+	return dateLayout(locale) + " " + timeLayout(locale) + " MST"
+}
+
+func dateLayout(locale string) string {
+	lang, region, _ := strings.Cut(strings.ToLower(locale), "-")
+	switch lang {
+	case "en":
+		if region == "us" {
+			return "Mon Jan 2, 2006"
+		}
+		return "Mon 2 Jan 2006"
+	case "zh", "ja":
+		return "2006年1月2日"
+	case "ko":
+		return "2006년 1월 2일"
+	case "de", "ru", "pl", "cs", "fi", "nb", "da", "tr":
+		return "02.01.2006"
+	case "fr", "es", "it", "pt", "nl", "vi", "id":
+		return "02/01/2006"
+	}
+	return "2006-01-02 (Mon)"
+}
+
+func timeLayout(locale string) string {
+	if strings.EqualFold(locale, "en-US") {
+		return "3:04 PM"
+	}
+	return "15:04"
+}
+
+// Entry is the stored settings of one user or chat.
+type Entry struct {
+	Settings
+	// Detected holds settings reported by the platform. Explicit settings
+	// win over them.
+	Detected Settings  `json:"detected,omitempty"`
+	Updated  time.Time `json:"updated"`
+}
+
+func (e Entry) effective() Settings {
+	return e.Settings.merge(e.Detected)
+}
+
+type storeData struct {
+	Users map[string]*Entry `json:"users"`
+	Chats map[string]*Entry `json:"chats"`
+}
+
+// Store persists settings per user and per chat in the workspace.
+type Store struct {
+	path     string
+	defaults Settings
+	mu       sync.RWMutex
+	data     storeData
+}
+
+// NewStore opens the settings in workspace/state/locale.json. defaults
+// apply to users and chats without settings of their own.
+func NewStore(workspace string, defaults Settings) *Store {
+	s := &Store{
+		path:     filepath.Join(workspace, "state", "locale.json"),
+		defaults: defaults,
+		data:     storeData{Users: map[string]*Entry{}, Chats: map[string]*Entry{}},
+	}
+	if data, err := os.ReadFile(s.path); err == nil {
+		json.Unmarshal(data, &s.data)
+		if s.data.Users == nil {
+			s.data.Users = map[string]*Entry{}
+		}
+		if s.data.Chats == nil {
+			s.data.Chats = map[string]*Entry{}
+		}
+	}
+	return s
+}
+
+func key(channel, id string) string {
+	return channel + ":" + id
+}
+
+// Defaults returns the configured defaults.
+func (s *Store) Defaults() Settings {
+	return s.defaults
+}
+
+// Resolve returns the settings for a sender in a chat: explicit user
+// settings, then explicit chat settings, then what the platform reported
+// for the user, then the defaults. Each field resolves on its own.
+func (s *Store) Resolve(channel, chatID, senderID string) Settings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var user, chat *Entry
+	if senderID != "" {
+		user = s.data.Users[key(channel, senderID)]
+	}
+	if chatID != "" {
+		chat = s.data.Chats[key(channel, chatID)]
+	}
+
+	var out Settings
+	if user != nil {
+		out = user.Settings
+	}
+	if chat != nil {
+		out = out.merge(chat.effective())
+	}
+	if user != nil {
+		out = out.merge(user.Detected)
+	}
+	return out.merge(s.defaults)
+}
+
+// User returns the stored entry of a user.
+func (s *Store) User(channel, senderID string) (Entry, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	e, ok := s.data.Users[key(channel, senderID)]
+	if !ok {
+		return Entry{}, false
+	}
+	return *e, true
+}
+
+// Chat returns the stored entry of a chat.
+func (s *Store) Chat(channel, chatID string) (Entry, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	e, ok := s.data.Chats[key(channel, chatID)]
+	if !ok {
+		return Entry{}, false
+	}
+	return *e, true
+}
+
+// SetUser stores explicit settings for a user. Empty fields keep their
+// current value.
+func (s *Store) SetUser(channel, senderID string, st Settings) error {
+	return s.set(s.data.Users, key(channel, senderID), st, false)
+}
+
+// SetChat stores explicit settings for a chat. Empty fields keep their
+// current value.
+func (s *Store) SetChat(channel, chatID string, st Settings) error {
+	return s.set(s.data.Chats, key(channel, chatID), st, false)
+}
+
+// Detect records settings the platform reported for a user. It only writes
+// when they changed.
+func (s *Store) Detect(channel, senderID string, st Settings) error {
+	if senderID == "" || (st.TimeZone == "" && st.Locale == "") {
+		return nil
+	}
+	return s.set(s.data.Users, key(channel, senderID), st, true)
+}
+
+func (s *Store) set(m map[string]*Entry, k string, st Settings, detected bool) error {
+	st, err := st.Normalize()
+	if err != nil {
+		if detected {
+			// Platforms send odd values now and then; keep what we have
+			return nil
+		}
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := m[k]
+	if !ok {
+		e = &Entry{}
+		m[k] = e
+	}
+	target := &e.Settings
+	if detected {
+		target = &e.Detected
+	}
+	merged := st.merge(*target)
+	if ok && merged == *target {
+		return nil
+	}
+	*target = merged
+	e.Updated = time.Now()
+	return s.saveLocked()
+}
+
+func (s *Store) saveLocked() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// Turn identifies who a turn is for and the settings that apply.
+type Turn struct {
+	Channel  string
+	ChatID   string
+	SenderID string
+	Settings Settings
+}
+
+type turnKey struct{}
+
+// WithTurn attaches the turn to ctx for the tools it runs.
+func WithTurn(ctx context.Context, t Turn) context.Context {
+	return context.WithValue(ctx, turnKey{}, t)
+}
+
+// FromContext returns the turn attached to ctx.
+func FromContext(ctx context.Context) (Turn, bool) {
+	t, ok := ctx.Value(turnKey{}).(Turn)
+	return t, ok
+}
+
+// SettingsFrom returns the settings of the turn in ctx, or the zero
+// settings (server zone) outside a turn.
+func SettingsFrom(ctx context.Context) Settings {
+	t, _ := FromContext(ctx)
+	return t.Settings
+}
@@ -0,0 +1,95 @@
+package locale
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolveOrder(t *testing.T) {
+	dir := t.TempDir()
+	s := NewStore(dir, Settings{TimeZone: "UTC", Locale: "en"})
+
+	if got := s.Resolve("telegram", "chat1", "alice"); got != (Settings{TimeZone: "UTC", Locale: "en"}) {
+		t.Errorf("defaults not applied: %+v", got)
+	}
+
+	if err := s.Detect("telegram", "alice", Settings{Locale: "de"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetChat("telegram", "chat1", Settings{TimeZone: "Asia/Shanghai", Locale: "zh-CN"}); err != nil {
+		t.Fatal(err)
+	}
+	// Explicit chat settings win over what the platform reported.
+	if got := s.Resolve("telegram", "chat1", "alice"); got != (Settings{TimeZone: "Asia/Shanghai", Locale: "zh-CN"}) {
+		t.Errorf("chat settings: %+v", got)
+	}
+	// In another chat the detected locale applies.
+	if got := s.Resolve("telegram", "chat2", "alice"); got != (Settings{TimeZone: "UTC", Locale: "de"}) {
+		t.Errorf("detected settings: %+v", got)
+	}
+
+	if err := s.SetUser("telegram", "alice", Settings{TimeZone: "Europe/Berlin"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Resolve("telegram", "chat1", "alice"); got != (Settings{TimeZone: "Europe/Berlin", Locale: "zh-CN"}) {
+		t.Errorf("user settings should win field by field: %+v", got)
+	}
+	if got := s.Resolve("slack", "chat1", "alice"); got.TimeZone != "UTC" {
+		t.Errorf("settings leaked across channels: %+v", got)
+	}
+
+	reopened := NewStore(dir, Settings{})
+	if e, ok := reopened.User("telegram", "alice"); !ok || e.TimeZone != "Europe/Berlin" || e.Detected.Locale != "de" {
+		t.Errorf("settings not persisted: %+v", e)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	if st, err := (Settings{TimeZone: " America/New_York ", Locale: "pt_BR"}).Normalize(); err != nil || st.Locale != "pt-BR" || st.TimeZone != "America/New_York" {
+		t.Errorf("Normalize = %+v, %v", st, err)
+	}
+	for _, bad := range []Settings{{TimeZone: "Mars/Olympus"}, {Locale: "english please"}} {
+		if _, err := bad.Normalize(); err == nil {
+			t.Errorf("%+v should be rejected", bad)
+		}
+	}
+
+	s := NewStore(t.TempDir(), Settings{})
+	if err := s.SetUser("telegram", "bob", Settings{TimeZone: "Nowhere"}); err == nil {
+		t.Error("explicit invalid zone should fail")
+	}
+	if err := s.Detect("telegram", "bob", Settings{TimeZone: "Nowhere"}); err != nil {
+		t.Errorf("invalid detected values should be ignored, got %v", err)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	at := time.Date(2026, 3, 1, 23, 30, 0, 0, time.UTC)
+	tests := []struct {
+		settings Settings
+		want     string
+	}{
+		{Settings{TimeZone: "Asia/Shanghai", Locale: "zh-CN"}, "2026年3月2日 07:30 CST"},
+		{Settings{TimeZone: "America/New_York", Locale: "en-US"}, "Sun Mar 1, 2026 6:30 PM EST"},
+		{Settings{TimeZone: "Europe/Berlin", Locale: "de-DE"}, "02.03.2026 00:30 CET"},
+		{Settings{TimeZone: "UTC"}, "2026-03-01 (Sun) 23:30 UTC"},
+	}
+	for _, tt := range tests {
+		if got := tt.settings.Format(at); got != tt.want {
+			t.Errorf("Format(%+v) = %q, want %q", tt.settings, got, tt.want)
+		}
+	}
+}
+
+func TestContext(t *testing.T) {
+	if st := SettingsFrom(context.Background()); st != (Settings{}) {
+		t.Errorf("no turn should give zero settings, got %+v", st)
+	}
+	ctx := WithTurn(context.Background(), Turn{Channel: "slack", SenderID: "U1", Settings: Settings{TimeZone: "Asia/Tokyo"}})
+	turn, ok := FromContext(ctx)
+	if !ok || turn.SenderID != "U1" || !strings.Contains(SettingsFrom(ctx).Location().String(), "Tokyo") {
+		t.Errorf("turn = %+v", turn)
+	}
+}
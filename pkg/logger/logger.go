@@ -34,6 +34,10 @@ var (
 	logger       *Logger
 	once         sync.Once
 	mu           sync.RWMutex
+
+	listeners      = map[int]func(LogEntry){}
+	nextListenerID int
+	listenersMu    sync.RWMutex
 )
 
 type Logger struct {
@@ -96,6 +100,24 @@ func DisableFileLogging() {
 	}
 }
 
+// AddListener registers fn to receive every entry that passes the level
+// filter, e.g. to stream activity to the admin dashboard. fn is called
+// synchronously and must not block. The returned function removes it.
+func AddListener(fn func(LogEntry)) (remove func()) {
+	listenersMu.Lock()
+	defer listenersMu.Unlock()
+
+	id := nextListenerID
+	nextListenerID++
+	listeners[id] = fn
+
+	return func() {
+		listenersMu.Lock()
+		defer listenersMu.Unlock()
+		delete(listeners, id)
+	}
+}
+
 func logMessage(level LogLevel, component string, message string, fields map[string]interface{}) {
 	if level < currentLevel {
 		return
@@ -116,6 +138,12 @@ func logMessage(level LogLevel, component string, message string, fields map[str
 		}
 	}
 
+	listenersMu.RLock()
+	for _, fn := range listeners {
+		fn(entry)
+	}
+	listenersMu.RUnlock()
+
 	if logger.file != nil {
 		jsonData, err := json.Marshal(entry)
 		if err == nil {
@@ -137,3 +137,30 @@ func TestLoggerHelperFunctions(t *testing.T) {
 	DebugC("test", "Debug with component")
 	WarnF("Warning with fields", map[string]interface{}{"key": "value"})
 }
+
+func TestAddListener(t *testing.T) {
+	initialLevel := GetLevel()
+	defer SetLevel(initialLevel)
+
+	SetLevel(INFO)
+
+	var got []LogEntry
+	remove := AddListener(func(e LogEntry) {
+		got = append(got, e)
+	})
+
+	DebugC("test", "filtered out")
+	InfoCF("test", "delivered", map[string]interface{}{"key": "value"})
+	remove()
+	InfoC("test", "after remove")
+
+	if len(got) != 1 {
+		t.Fatalf("listener received %d entries, want 1", len(got))
+	}
+	if got[0].Level != "INFO" || got[0].Component != "test" || got[0].Message != "delivered" {
+		t.Errorf("unexpected entry: %+v", got[0])
+	}
+	if got[0].Fields["key"] != "value" {
+		t.Errorf("fields = %v, want key=value", got[0].Fields)
+	}
+}
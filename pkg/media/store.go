@@ -0,0 +1,408 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// Inspired by and based on nanobot: https://github.com/HKUDS/nanobot
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+// Package media keeps inbound attachments inside the workspace. Files are
+// stored under their SHA-256, so the same content sent twice is kept once,
+// and are referenced from sessions as "media:<id>".
+package media
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// RefPrefix marks a media reference in messages and sessions.
+const RefPrefix = "media:"
+
+// idLength is the number of hex digits of the SHA-256 used as the ID.
+const idLength = 12
+
+// Item describes one stored file.
+type Item struct {
+	ID       string `json:"id"`
+	SHA256   string `json:"sha256"`
+	Name     string `json:"name"`
+	MIMEType string `json:"mime_type"`
+	Size     int64  `json:"size"`
+	Channel  string `json:"channel,omitempty"`
+	SenderID string `json:"sender_id,omitempty"`
+	ChatID   string `json:"chat_id,omitempty"`
+	// Owners are everyone who sent the content; Channel, SenderID and
+	// ChatID are the first of them.
+	Owners  []Owner   `json:"owners,omitempty"`
+	Created time.Time `json:"created"`
+	// LastSeen is updated when the same content arrives again. Retention
+	// expires items by it.
+	LastSeen time.Time `json:"last_seen"`
+	// File is the path of the content, relative to the store directory.
+	File string `json:"file"`
+}
+
+// Ref returns the reference used for the item in messages.
+func (it Item) Ref() string {
+	return RefPrefix + it.ID
+}
+
+// Owner is a sender of an item in a chat.
+type Owner struct {
+	Channel  string `json:"channel,omitempty"`
+	SenderID string `json:"sender_id,omitempty"`
+	ChatID   string `json:"chat_id,omitempty"`
+}
+
+// Origin describes where a file came from.
+type Origin struct {
+	Channel  string
+	SenderID string
+	ChatID   string
+	// Name is the original file name, used for display and the extension.
+	Name string
+}
+
+// Options are the retention limits of a store. Zero disables a limit.
+type Options struct {
+	MaxBytes int64
+	MaxAge   time.Duration
+}
+
+// Store is a content-addressed file store with a JSON index.
+type Store struct {
+	dir   string
+	opts  Options
+	mu    sync.Mutex
+	items map[string]*Item
+	now   func() time.Time
+}
+
+// NewStore opens the store in dir, creating it if needed, and applies the
+// retention limits.
+func NewStore(dir string, opts Options) (*Store, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "files"), 0755); err != nil {
+		return nil, err
+	}
+	s := &Store{
+		dir:   dir,
+		opts:  opts,
+		items: make(map[string]*Item),
+		now:   time.Now,
+	}
+	data, err := os.ReadFile(s.indexPath())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if len(data) > 0 {
+		var items []*Item
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, fmt.Errorf("media index: %w", err)
+		}
+		for _, it := range items {
+			if len(it.Owners) == 0 {
+				it.addOwner(Owner{it.Channel, it.SenderID, it.ChatID})
+			}
+			s.items[it.ID] = it
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.pruneLocked(""); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Dir returns the directory of the store.
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+func (s *Store) indexPath() string {
+	return filepath.Join(s.dir, "index.json")
+}
+
+// Put stores the content of r and returns its item. If the content is
+// already stored, the existing item is returned with LastSeen updated.
+func (s *Store) Put(r io.Reader, origin Origin) (Item, error) {
+	tmp, err := os.CreateTemp(filepath.Join(s.dir, "files"), ".upload-*")
+	if err != nil {
+		return Item{}, err
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	var head [512]byte
+	n, _ := io.ReadFull(r, head[:])
+	size, err := io.Copy(io.MultiWriter(tmp, h), io.MultiReader(bytes.NewReader(head[:n]), r))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return Item{}, err
+	}
+
+	sum := hex.EncodeToString(h.Sum(nil))
+	id := sum[:idLength]
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	owner := Owner{origin.Channel, origin.SenderID, origin.ChatID}
+	if it, ok := s.items[id]; ok {
+		it.LastSeen = now
+		it.addOwner(owner)
+		return it.clone(), s.saveLocked()
+	}
+
+	name := filepath.Base(origin.Name)
+	if name == "." || name == string(filepath.Separator) {
+		name = ""
+	}
+	ext := strings.ToLower(filepath.Ext(name))
+	it := &Item{
+		ID:       id,
+		SHA256:   sum,
+		Name:     name,
+		MIMEType: detectType(head[:n], ext),
+		Size:     size,
+		Channel:  origin.Channel,
+		SenderID: origin.SenderID,
+		ChatID:   origin.ChatID,
+		Created:  now,
+		LastSeen: now,
+		File:     filepath.Join("files", sum+ext),
+	}
+	it.addOwner(owner)
+	if err := os.Rename(tmp.Name(), filepath.Join(s.dir, it.File)); err != nil {
+		return Item{}, err
+	}
+	s.items[id] = it
+
+	if err := s.pruneLocked(id); err != nil {
+		return Item{}, err
+	}
+	return it.clone(), nil
+}
+
+func (it *Item) addOwner(o Owner) {
+	if o == (Owner{}) || slices.Contains(it.Owners, o) {
+		return
+	}
+	it.Owners = append(it.Owners, o)
+}
+
+func (it *Item) clone() Item {
+	c := *it
+	c.Owners = slices.Clone(it.Owners)
+	return c
+}
+
+// PutFile stores a copy of the file at path. The file itself is left alone.
+func (s *Store) PutFile(path string, origin Origin) (Item, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Item{}, err
+	}
+	defer f.Close()
+	if origin.Name == "" {
+		origin.Name = filepath.Base(path)
+	}
+	return s.Put(f, origin)
+}
+
+func detectType(head []byte, ext string) string {
+	if t := mime.TypeByExtension(ext); t != "" {
+		return t
+	}
+	return http.DetectContentType(head)
+}
+
+// Get returns the item with the given ID or reference.
+func (s *Store) Get(id string) (Item, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it, ok := s.items[strings.TrimPrefix(id, RefPrefix)]
+	if !ok {
+		return Item{}, false
+	}
+	return it.clone(), true
+}
+
+// Path returns the absolute path of an item's content.
+func (s *Store) Path(it Item) string {
+	return filepath.Join(s.dir, it.File)
+}
+
+// List returns the stored items, most recently seen first.
+func (s *Store) List() []Item {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Item, 0, len(s.items))
+	for _, it := range s.items {
+		out = append(out, it.clone())
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].LastSeen.Equal(out[j].LastSeen) {
+			return out[i].LastSeen.After(out[j].LastSeen)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// Usage returns the number of items and their total size.
+func (s *Store) Usage() (count int, size int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, it := range s.items {
+		size += it.Size
+	}
+	return len(s.items), size
+}
+
+// Delete removes an item and its content.
+func (s *Store) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id = strings.TrimPrefix(id, RefPrefix)
+	it, ok := s.items[id]
+	if !ok {
+		return fmt.Errorf("media %s not found", id)
+	}
+	s.removeLocked(it)
+	return s.saveLocked()
+}
+
+// Disown removes the owners for which owned returns true from an item. The
+// item and its content are removed with its last owner. It reports whether
+// that happened.
+func (s *Store) Disown(id string, owned func(Owner) bool) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id = strings.TrimPrefix(id, RefPrefix)
+	it, ok := s.items[id]
+	if !ok {
+		return false, fmt.Errorf("media %s not found", id)
+	}
+	it.Owners = slices.DeleteFunc(it.Owners, owned)
+	if len(it.Owners) > 0 {
+		return false, s.saveLocked()
+	}
+	s.removeLocked(it)
+	return true, s.saveLocked()
+}
+
+// Prune applies the retention limits now and returns how many items were
+// removed.
+func (s *Store) Prune() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	before := len(s.items)
+	err := s.pruneLocked("")
+	return before - len(s.items), err
+}
+
+// pruneLocked drops items older than MaxAge, then the least recently seen
+// items until the store fits in MaxBytes. The item keep is never dropped
+// for size, so a file larger than the limit is still available to the turn
+// that received it. The index is saved.
+func (s *Store) pruneLocked(keep string) error {
+	var total int64
+	items := make([]*Item, 0, len(s.items))
+	for _, it := range s.items {
+		if s.opts.MaxAge > 0 && s.now().Sub(it.LastSeen) > s.opts.MaxAge && it.ID != keep {
+			s.removeLocked(it)
+			continue
+		}
+		total += it.Size
+		items = append(items, it)
+	}
+
+	if s.opts.MaxBytes > 0 && total > s.opts.MaxBytes {
+		sort.Slice(items, func(i, j int) bool { return items[i].LastSeen.Before(items[j].LastSeen) })
+		for _, it := range items {
+			if total <= s.opts.MaxBytes {
+				break
+			}
+			if it.ID == keep {
+				continue
+			}
+			s.removeLocked(it)
+			total -= it.Size
+		}
+	}
+	return s.saveLocked()
+}
+
+func (s *Store) removeLocked(it *Item) {
+	if err := os.Remove(filepath.Join(s.dir, it.File)); err != nil && !os.IsNotExist(err) {
+		logger.WarnCF("media", "Failed to remove media file", map[string]interface{}{
+			"id":    it.ID,
+			"error": err.Error(),
+		})
+	}
+	delete(s.items, it.ID)
+}
+
+// saveLocked writes the index atomically.
+func (s *Store) saveLocked() error {
+	items := make([]*Item, 0, len(s.items))
+	for _, it := range s.items {
+		items = append(items, it)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+	data, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.indexPath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.indexPath())
+}
+
+// Describe returns a one-line description of an item for the agent, with
+// its path relative to workspace when the store is inside it.
+func (s *Store) Describe(it Item, workspace string) string {
+	path := s.Path(it)
+	if workspace != "" {
+		if rel, err := filepath.Rel(workspace, path); err == nil && !strings.HasPrefix(rel, "..") {
+			path = rel
+		}
+	}
+	name := it.Name
+	if name == "" {
+		name = "unnamed"
+	}
+	return fmt.Sprintf("%s %s (%s, %s) at %s", it.Ref(), name, it.MIMEType, FormatSize(it.Size), path)
+}
+
+// FormatSize formats a byte count for humans.
+func FormatSize(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
@@ -0,0 +1,119 @@
+package media
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T, opts Options) (*Store, *time.Time) {
+	t.Helper()
+	s, err := NewStore(filepath.Join(t.TempDir(), "media"), opts)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	return s, &now
+}
+
+func put(t *testing.T, s *Store, content, name string) Item {
+	t.Helper()
+	it, err := s.Put(strings.NewReader(content), Origin{Channel: "telegram", SenderID: "42", ChatID: "7", Name: name})
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	return it
+}
+
+func TestPutDeduplicates(t *testing.T) {
+	s, now := newTestStore(t, Options{})
+
+	png := "\x89PNG\r\n\x1a\n" + strings.Repeat("x", 100)
+	a := put(t, s, png, "photo.png")
+	if a.MIMEType != "image/png" || a.Size != int64(len(png)) || a.Channel != "telegram" || a.SenderID != "42" {
+		t.Errorf("unexpected item %+v", a)
+	}
+	if len(a.ID) != idLength || a.Ref() != "media:"+a.ID {
+		t.Errorf("id %q, ref %q", a.ID, a.Ref())
+	}
+	data, err := os.ReadFile(s.Path(a))
+	if err != nil || string(data) != png {
+		t.Fatalf("stored content differs: %v", err)
+	}
+
+	*now = now.Add(time.Hour)
+	b := put(t, s, png, "again.png")
+	if b.ID != a.ID || b.Name != "photo.png" || !b.LastSeen.After(a.LastSeen) {
+		t.Errorf("same content should reuse the item: %+v", b)
+	}
+	if count, _ := s.Usage(); count != 1 {
+		t.Errorf("count = %d, want 1", count)
+	}
+	files, _ := filepath.Glob(filepath.Join(s.Dir(), "files", "*"))
+	if len(files) != 1 {
+		t.Errorf("files on disk = %v", files)
+	}
+
+	if txt := put(t, s, "plain words", ""); txt.MIMEType != "text/plain; charset=utf-8" {
+		t.Errorf("sniffed type = %q", txt.MIMEType)
+	}
+}
+
+func TestIndexPersists(t *testing.T) {
+	s, _ := newTestStore(t, Options{})
+	it := put(t, s, "hello", "note.txt")
+
+	reopened, err := NewStore(s.Dir(), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := reopened.Get(it.Ref())
+	if !ok || got.Name != "note.txt" || got.ChatID != "7" {
+		t.Errorf("reopened item = %+v, %v", got, ok)
+	}
+
+	if err := reopened.Delete(it.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(reopened.Path(got)); !os.IsNotExist(err) {
+		t.Error("content not removed with the item")
+	}
+	if err := reopened.Delete(it.ID); err == nil {
+		t.Error("deleting twice should fail")
+	}
+}
+
+func TestRetention(t *testing.T) {
+	s, now := newTestStore(t, Options{MaxBytes: 25, MaxAge: 24 * time.Hour})
+
+	old := put(t, s, strings.Repeat("a", 10), "old")
+	*now = now.Add(time.Hour)
+	mid := put(t, s, strings.Repeat("b", 10), "mid")
+	*now = now.Add(time.Hour)
+
+	// Over the size limit: the least recently seen item goes.
+	put(t, s, strings.Repeat("c", 10), "new")
+	if _, ok := s.Get(old.ID); ok {
+		t.Error("oldest item kept over the size limit")
+	}
+	if _, ok := s.Get(mid.ID); !ok {
+		t.Error("newer item dropped")
+	}
+
+	// A single file larger than the limit stays until something newer arrives.
+	big := put(t, s, strings.Repeat("d", 40), "big")
+	if _, ok := s.Get(big.ID); !ok {
+		t.Error("the item just stored was pruned")
+	}
+	if count, _ := s.Usage(); count != 1 {
+		t.Errorf("count = %d, want only the big item", count)
+	}
+
+	*now = now.Add(25 * time.Hour)
+	if n, err := s.Prune(); err != nil || n != 1 {
+		t.Errorf("Prune() = %d, %v; want 1 expired item", n, err)
+	}
+}
@@ -219,7 +219,7 @@ func executeConfigMigration(srcConfigPath, dstConfigPath, picoClawHome string) e
 	}
 
 	if _, err := os.Stat(dstConfigPath); err == nil {
-		existing, err := config.LoadConfig(dstConfigPath)
+		existing, err := config.LoadBaseConfig(dstConfigPath)
 		if err != nil {
 			return fmt.Errorf("loading existing PicoClaw config: %w", err)
 		}
@@ -0,0 +1,109 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Client talks to the monitor API of a running gateway, either over the
+// local control socket or over HTTP through the admin dashboard.
+type Client struct {
+	baseURL  string
+	username string
+	password string
+	http     *http.Client
+}
+
+// NewSocketClient connects through the Unix socket at path.
+func NewSocketClient(path string) *Client {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		},
+	}
+	return &Client{
+		baseURL: "http://gateway",
+		http:    &http.Client{Transport: transport, Timeout: 5 * time.Second},
+	}
+}
+
+// NewHTTPClient connects to the admin dashboard at baseURL
+// (e.g. http://127.0.0.1:18790/admin) with its credentials.
+func NewHTTPClient(baseURL, username, password string) *Client {
+	return &Client{
+		baseURL:  strings.TrimRight(baseURL, "/") + "/api/top",
+		username: username,
+		password: password,
+		http:     &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Snapshot fetches the current state.
+func (c *Client) Snapshot(ctx context.Context) (*Snapshot, error) {
+	var s Snapshot
+	if err := c.do(ctx, http.MethodGet, "/snapshot", &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// CancelTurn cancels a running turn.
+func (c *Client) CancelTurn(ctx context.Context, id int64) error {
+	return c.do(ctx, http.MethodPost, fmt.Sprintf("/turns/%d/cancel", id), nil)
+}
+
+// PauseChannel holds a channel's inbound messages until it is resumed.
+func (c *Client) PauseChannel(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodPost, "/channels/"+url.PathEscape(name)+"/pause", nil)
+}
+
+// ResumeChannel resumes a paused channel and releases its held messages.
+func (c *Client) ResumeChannel(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodPost, "/channels/"+url.PathEscape(name)+"/resume", nil)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, out interface{}) error {
+	var body io.Reader
+	if method != http.MethodGet {
+		body = strings.NewReader("{}")
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error string `json:"error"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if json.Unmarshal(data, &e) == nil && e.Error != "" {
+			return fmt.Errorf("%s", e.Error)
+		}
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
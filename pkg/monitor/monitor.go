@@ -0,0 +1,295 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// Inspired by and based on nanobot: https://github.com/HKUDS/nanobot
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+// Package monitor tracks what a running agent is doing (turns in flight,
+// recent traffic, token usage) and serves it to `picoclaw top`. It also
+// implements the two controls top offers: cancelling a turn and pausing a
+// channel.
+package monitor
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+const (
+	recentPerChannel = 10
+	previewLen       = 120
+	usageWindow      = 5 * time.Minute
+	// maxHeldPerChannel bounds the messages kept for a paused channel.
+	maxHeldPerChannel = 100
+)
+
+// Turn is an agent turn in progress.
+type Turn struct {
+	ID         int64     `json:"id"`
+	SessionKey string    `json:"session_key"`
+	Channel    string    `json:"channel"`
+	ChatID     string    `json:"chat_id"`
+	StartedAt  time.Time `json:"started_at"`
+	Iteration  int       `json:"iteration"`
+	Tool       string    `json:"tool,omitempty"`
+
+	cancel context.CancelFunc
+}
+
+// Message is a recent message seen on the bus.
+type Message struct {
+	Time      time.Time `json:"time"`
+	Direction string    `json:"direction"` // "in" or "out"
+	ChatID    string    `json:"chat_id"`
+	Preview   string    `json:"preview"`
+}
+
+// Usage summarizes LLM token consumption.
+type Usage struct {
+	PromptTotal     int64   `json:"prompt_total"`
+	CompletionTotal int64   `json:"completion_total"`
+	TokensPerMinute float64 `json:"tokens_per_minute"` // averaged over the last minute
+	TokensLast5Min  int64   `json:"tokens_last_5min"`
+}
+
+type usageSample struct {
+	at     time.Time
+	tokens int
+}
+
+// Monitor records live agent activity. It is safe for concurrent use.
+type Monitor struct {
+	mu         sync.Mutex
+	turns      map[int64]*Turn
+	nextTurnID int64
+	recent     map[string][]Message
+	held       map[string][]bus.InboundMessage // key present = channel paused
+	samples    []usageSample
+	prompt     int64
+	completion int64
+	now        func() time.Time
+}
+
+func NewMonitor() *Monitor {
+	return &Monitor{
+		turns:  make(map[int64]*Turn),
+		recent: make(map[string][]Message),
+		held:   make(map[string][]bus.InboundMessage),
+		now:    time.Now,
+	}
+}
+
+// BeginTurn registers a turn and returns a context that is cancelled when
+// the turn is cancelled from top. Callers must call EndTurn.
+func (m *Monitor) BeginTurn(ctx context.Context, sessionKey, channel, chatID string) (context.Context, *Turn) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextTurnID++
+	t := &Turn{
+		ID:         m.nextTurnID,
+		SessionKey: sessionKey,
+		Channel:    channel,
+		ChatID:     chatID,
+		StartedAt:  m.now(),
+		cancel:     cancel,
+	}
+	m.turns[t.ID] = t
+	return ctx, t
+}
+
+// EndTurn removes a finished turn and releases its context.
+func (m *Monitor) EndTurn(t *Turn) {
+	m.mu.Lock()
+	delete(m.turns, t.ID)
+	m.mu.Unlock()
+	t.cancel()
+}
+
+// SetIteration records the LLM iteration a turn is on. It clears the tool.
+func (m *Monitor) SetIteration(t *Turn, iteration int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t.Iteration = iteration
+	t.Tool = ""
+}
+
+// SetTool records the tool a turn is currently executing.
+func (m *Monitor) SetTool(t *Turn, tool string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t.Tool = tool
+}
+
+// CancelTurn cancels a running turn. It returns false if no such turn exists.
+func (m *Monitor) CancelTurn(id int64) bool {
+	m.mu.Lock()
+	t, ok := m.turns[id]
+	m.mu.Unlock()
+
+	if !ok {
+		return false
+	}
+	logger.InfoCF("monitor", "Turn cancelled", map[string]interface{}{
+		"turn_id":     id,
+		"session_key": t.SessionKey,
+	})
+	t.cancel()
+	return true
+}
+
+// Turns returns the turns in progress, oldest first.
+func (m *Monitor) Turns() []Turn {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	turns := make([]Turn, 0, len(m.turns))
+	for _, t := range m.turns {
+		turns = append(turns, *t)
+	}
+	sort.Slice(turns, func(i, j int) bool { return turns[i].ID < turns[j].ID })
+	return turns
+}
+
+// RecordUsage adds the token counts of one LLM response.
+func (m *Monitor) RecordUsage(promptTokens, completionTokens int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prompt += int64(promptTokens)
+	m.completion += int64(completionTokens)
+	m.samples = append(m.samples, usageSample{at: m.now(), tokens: promptTokens + completionTokens})
+	m.pruneSamples()
+}
+
+func (m *Monitor) pruneSamples() {
+	cutoff := m.now().Add(-usageWindow)
+	i := 0
+	for i < len(m.samples) && m.samples[i].at.Before(cutoff) {
+		i++
+	}
+	m.samples = m.samples[i:]
+}
+
+// Usage returns token totals and recent rates.
+func (m *Monitor) Usage() Usage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pruneSamples()
+
+	u := Usage{PromptTotal: m.prompt, CompletionTotal: m.completion}
+	lastMinute := m.now().Add(-time.Minute)
+	for _, s := range m.samples {
+		u.TokensLast5Min += int64(s.tokens)
+		if !s.at.Before(lastMinute) {
+			u.TokensPerMinute += float64(s.tokens)
+		}
+	}
+	return u
+}
+
+// ObserveInbound implements bus.Observer.
+func (m *Monitor) ObserveInbound(msg bus.InboundMessage) {
+	m.record(msg.Channel, "in", msg.ChatID, msg.Content)
+}
+
+// ObserveOutbound implements bus.Observer.
+// Typing and progress indicators are not traffic worth listing.
+func (m *Monitor) ObserveOutbound(msg bus.OutboundMessage) {
+	if msg.Activity != nil {
+		return
+	}
+	m.record(msg.Channel, "out", msg.ChatID, msg.Content)
+}
+
+func (m *Monitor) record(channel, direction, chatID, content string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	msgs := append(m.recent[channel], Message{
+		Time:      m.now(),
+		Direction: direction,
+		ChatID:    chatID,
+		Preview:   utils.Truncate(content, previewLen),
+	})
+	if len(msgs) > recentPerChannel {
+		msgs = msgs[len(msgs)-recentPerChannel:]
+	}
+	m.recent[channel] = msgs
+}
+
+// Recent returns the latest messages of each channel, oldest first.
+func (m *Monitor) Recent() map[string][]Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := make(map[string][]Message, len(m.recent))
+	for ch, msgs := range m.recent {
+		out[ch] = append([]Message(nil), msgs...)
+	}
+	return out
+}
+
+// Pause stops the agent from processing a channel's inbound messages.
+// They are held until Resume.
+func (m *Monitor) Pause(channel string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.held[channel]; !ok {
+		m.held[channel] = []bus.InboundMessage{}
+		logger.InfoCF("monitor", "Channel paused", map[string]interface{}{"channel": channel})
+	}
+}
+
+// Resume unpauses a channel and returns the messages held while it was
+// paused, for the caller to republish.
+func (m *Monitor) Resume(channel string) []bus.InboundMessage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	held, ok := m.held[channel]
+	if ok {
+		delete(m.held, channel)
+		logger.InfoCF("monitor", "Channel resumed", map[string]interface{}{
+			"channel": channel,
+			"held":    len(held),
+		})
+	}
+	return held
+}
+
+// Hold keeps msg if its channel is paused and reports whether it did.
+func (m *Monitor) Hold(msg bus.InboundMessage) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	held, paused := m.held[msg.Channel]
+	if !paused {
+		return false
+	}
+	if len(held) >= maxHeldPerChannel {
+		logger.WarnCF("monitor", "Paused channel queue full, dropping oldest message", map[string]interface{}{
+			"channel": msg.Channel,
+		})
+		held = held[1:]
+	}
+	m.held[msg.Channel] = append(held, msg)
+	return true
+}
+
+// Paused returns the paused channels and how many messages each is holding.
+func (m *Monitor) Paused() map[string]int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := make(map[string]int, len(m.held))
+	for ch, held := range m.held {
+		out[ch] = len(held)
+	}
+	return out
+}
@@ -0,0 +1,300 @@
+package monitor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/governor"
+)
+
+type fakeChannels map[string]bool
+
+func (f fakeChannels) GetStatus() map[string]interface{} {
+	out := make(map[string]interface{}, len(f))
+	for name, running := range f {
+		out[name] = map[string]interface{}{"enabled": true, "running": running}
+	}
+	return out
+}
+
+func TestTurns(t *testing.T) {
+	m := NewMonitor()
+
+	ctx, turn := m.BeginTurn(context.Background(), "telegram:1", "telegram", "1")
+	m.SetIteration(turn, 2)
+	m.SetTool(turn, "web_search")
+
+	turns := m.Turns()
+	if len(turns) != 1 {
+		t.Fatalf("got %d turns, want 1", len(turns))
+	}
+	if got := turns[0]; got.Iteration != 2 || got.Tool != "web_search" || got.SessionKey != "telegram:1" {
+		t.Errorf("unexpected turn %+v", got)
+	}
+
+	m.SetIteration(turn, 3)
+	if got := m.Turns()[0].Tool; got != "" {
+		t.Errorf("tool should reset on a new iteration, got %q", got)
+	}
+
+	if m.CancelTurn(turn.ID + 1) {
+		t.Error("cancelling an unknown turn should fail")
+	}
+	if !m.CancelTurn(turn.ID) {
+		t.Fatal("CancelTurn failed")
+	}
+	select {
+	case <-ctx.Done():
+	default:
+		t.Error("turn context not cancelled")
+	}
+
+	m.EndTurn(turn)
+	if len(m.Turns()) != 0 {
+		t.Error("turn not removed by EndTurn")
+	}
+}
+
+func TestUsage(t *testing.T) {
+	m := NewMonitor()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+
+	m.RecordUsage(100, 20) // 12:00
+	now = now.Add(2 * time.Minute)
+	m.RecordUsage(50, 10) // 12:02
+	now = now.Add(30 * time.Second)
+
+	u := m.Usage()
+	if u.PromptTotal != 150 || u.CompletionTotal != 30 {
+		t.Errorf("totals = %d/%d, want 150/30", u.PromptTotal, u.CompletionTotal)
+	}
+	if u.TokensPerMinute != 60 {
+		t.Errorf("tokens/min = %v, want 60", u.TokensPerMinute)
+	}
+	if u.TokensLast5Min != 180 {
+		t.Errorf("last 5 min = %d, want 180", u.TokensLast5Min)
+	}
+
+	now = now.Add(10 * time.Minute)
+	u = m.Usage()
+	if u.TokensLast5Min != 0 || u.PromptTotal != 150 {
+		t.Errorf("old samples should leave the window but not the totals: %+v", u)
+	}
+}
+
+func TestRecentAndPause(t *testing.T) {
+	m := NewMonitor()
+	mb := bus.NewMessageBus()
+	mb.AddObserver(m)
+
+	for i := 0; i < recentPerChannel+5; i++ {
+		mb.PublishInbound(bus.InboundMessage{Channel: "slack", ChatID: "C1", Content: "hi"})
+		mb.ConsumeInbound(context.Background())
+	}
+	mb.PublishOutbound(bus.OutboundMessage{Channel: "slack", ChatID: "C1", Content: "hello"})
+
+	recent := m.Recent()["slack"]
+	if len(recent) != recentPerChannel {
+		t.Fatalf("kept %d messages, want %d", len(recent), recentPerChannel)
+	}
+	if last := recent[len(recent)-1]; last.Direction != "out" || last.Preview != "hello" {
+		t.Errorf("unexpected last message %+v", last)
+	}
+
+	msg := bus.InboundMessage{Channel: "slack", ChatID: "C1", Content: "queued"}
+	if m.Hold(msg) {
+		t.Fatal("message held for a channel that is not paused")
+	}
+	m.Pause("slack")
+	if !m.Hold(msg) || !m.Hold(msg) {
+		t.Fatal("message not held for a paused channel")
+	}
+	if got := m.Paused()["slack"]; got != 2 {
+		t.Errorf("paused count = %d, want 2", got)
+	}
+
+	held := m.Resume("slack")
+	if len(held) != 2 || held[0].Content != "queued" {
+		t.Errorf("resume returned %+v", held)
+	}
+	if _, ok := m.Paused()["slack"]; ok {
+		t.Error("channel still paused after resume")
+	}
+}
+
+func TestSocketAPI(t *testing.T) {
+	m := NewMonitor()
+	mb := bus.NewMessageBus()
+	mb.AddObserver(m)
+	src := Sources{Monitor: m, Bus: mb, Channels: fakeChannels{"telegram": true, "slack": false}}
+
+	path := filepath.Join(t.TempDir(), "gw.sock")
+	srv, err := ListenSocket(path, NewHandler(src))
+	if err != nil {
+		t.Fatalf("ListenSocket: %v", err)
+	}
+	defer srv.Close()
+
+	if _, err := ListenSocket(path, NewHandler(src)); err == nil {
+		t.Error("second listener on a live socket should fail")
+	}
+
+	ctx := context.Background()
+	client := NewSocketClient(path)
+
+	turnCtx, turn := m.BeginTurn(ctx, "telegram:42", "telegram", "42")
+	defer m.EndTurn(turn)
+	m.RecordUsage(10, 5)
+
+	snap, err := client.Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	if len(snap.Turns) != 1 || snap.Turns[0].SessionKey != "telegram:42" {
+		t.Errorf("turns = %+v", snap.Turns)
+	}
+	if len(snap.Channels) != 2 || snap.Channels[0].Name != "slack" || !snap.Channels[1].Running {
+		t.Errorf("channels = %+v", snap.Channels)
+	}
+	if snap.Usage.PromptTotal != 10 || snap.Memory.Goroutines == 0 {
+		t.Errorf("usage/memory missing: %+v %+v", snap.Usage, snap.Memory)
+	}
+
+	if err := client.CancelTurn(ctx, turn.ID); err != nil {
+		t.Fatalf("CancelTurn: %v", err)
+	}
+	if turnCtx.Err() == nil {
+		t.Error("turn not cancelled over the socket")
+	}
+	if err := client.CancelTurn(ctx, 9999); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("cancel of unknown turn: %v", err)
+	}
+
+	if err := client.PauseChannel(ctx, "telegram"); err != nil {
+		t.Fatalf("PauseChannel: %v", err)
+	}
+	m.Hold(bus.InboundMessage{Channel: "telegram", ChatID: "42", Content: "later"})
+	snap, _ = client.Snapshot(ctx)
+	if ch := snap.Channels[1]; !ch.Paused || ch.Held != 1 {
+		t.Errorf("telegram should be paused with 1 held: %+v", ch)
+	}
+
+	if err := client.ResumeChannel(ctx, "telegram"); err != nil {
+		t.Fatalf("ResumeChannel: %v", err)
+	}
+	consumeCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	msg, ok := mb.ConsumeInbound(consumeCtx)
+	if !ok || msg.Content != "later" {
+		t.Errorf("held message not republished: %+v %v", msg, ok)
+	}
+}
+
+func TestHTTPClientUsesAdminPrefix(t *testing.T) {
+	var gotPath, gotUser string
+	api := http.StripPrefix("/admin/api/top", NewHandler(Sources{Monitor: NewMonitor()}))
+	// Mimic the admin dashboard mount: <base>/api/top/... behind basic auth.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotUser, _, _ = r.BasicAuth()
+		api.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	client := NewHTTPClient(srv.URL+"/admin/", "admin", "secret")
+	if _, err := client.Snapshot(context.Background()); err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	if gotPath != "/admin/api/top/snapshot" || gotUser != "admin" {
+		t.Errorf("request went to %q as %q", gotPath, gotUser)
+	}
+}
+
+func TestRender(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	s := &Snapshot{
+		Time: now,
+		Turns: []Turn{
+			{ID: 7, SessionKey: "telegram:42", Iteration: 3, Tool: "web_search", StartedAt: now.Add(-75 * time.Second)},
+		},
+		Queue:    QueueDepth{Inbound: 2},
+		Channels: []ChannelInfo{{Name: "slack", Running: true, Paused: true, Held: 4}, {Name: "telegram", Running: true}},
+		Recent: map[string][]Message{
+			"telegram": {{Time: now, Direction: "in", ChatID: "42", Preview: "what's\nthe weather"}},
+		},
+		Subagents: []SubagentInfo{{ID: "subagent-1", Label: "research", StartedAt: now.Add(-time.Minute)}},
+		Cron:      []CronInfo{{Name: "daily news", NextRunAt: now.Add(2 * time.Hour)}},
+		Usage:     Usage{PromptTotal: 12000, CompletionTotal: 3000, TokensPerMinute: 1500},
+		Memory: MemoryInfo{HeapAlloc: 5 << 20, Sys: 20 << 20, Goroutines: 30,
+			Governor: &governor.Status{RSS: 40 << 20, Limit: 48 << 20, Pressure: true, Background: 1, BackgroundLimit: 1}},
+	}
+
+	out := renderTop(s, &topView{focus: focusChannels}, 0, 0, true)
+	for _, want := range []string{
+		"queue in 2 out 0",
+		"5.0 MiB heap",
+		"rss 40.0 MiB of 48.0 MiB limit   PRESSURE   background 1/1",
+		"1.5k/min",
+		"total 15.0k",
+		"#7",
+		"telegram:42",
+		"iter 3",
+		"tool web_search",
+		"1m15s",
+		"> slack",
+		"paused (4 held)",
+		"subagent-1",
+		"daily news",
+		"in 2h00m",
+		"what's the weather",
+		"[q] quit",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("render missing %q:\n%s", want, out)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSuffix(renderTop(s, &topView{}, 20, 6, true), "\r\n"), "\r\n")
+	if len(lines) != 6 {
+		t.Errorf("got %d lines for height 6", len(lines))
+	}
+	for _, line := range lines {
+		if n := len([]rune(strings.TrimSuffix(line, "\x1b[K"))); n > 20 && !strings.Contains(line, "\x1b[7m") {
+			t.Errorf("line wider than 20: %q", line)
+		}
+	}
+}
+
+func TestHandleKey(t *testing.T) {
+	s := &Snapshot{
+		Turns:    []Turn{{ID: 1}, {ID: 2}},
+		Channels: []ChannelInfo{{Name: "a"}, {Name: "b"}, {Name: "c"}},
+	}
+	v := &topView{}
+
+	v.handleKey("j", s)
+	v.handleKey("j", s)
+	if v.turn != 1 {
+		t.Errorf("turn selection = %d, want 1 (clamped)", v.turn)
+	}
+	v.handleKey("\t", s)
+	v.handleKey("\x1b[B", s)
+	v.handleKey("\x1b[B", s)
+	v.handleKey("k", s)
+	if v.focus != focusChannels || v.channel != 1 {
+		t.Errorf("channel selection = %d (focus %d)", v.channel, v.focus)
+	}
+
+	for key, want := range map[string]topAction{"c": actionCancelTurn, "p": actionTogglePause, "q": actionQuit, "x": actionNone} {
+		if got := v.handleKey(key, s); got != want {
+			t.Errorf("key %q = %v, want %v", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/cron"
+	"github.com/sipeed/picoclaw/pkg/governor"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+// upcomingCronJobs is how many of the next cron jobs a snapshot lists.
+const upcomingCronJobs = 5
+
+// ChannelStatusProvider reports the state of the configured channels.
+// channels.Manager implements it.
+type ChannelStatusProvider interface {
+	GetStatus() map[string]interface{}
+}
+
+// Sources are the services a snapshot is assembled from. Nil fields are skipped.
+type Sources struct {
+	Monitor   *Monitor
+	Bus       *bus.MessageBus
+	Channels  ChannelStatusProvider
+	Subagents *tools.SubagentManager
+	Cron      *cron.CronService
+	Governor  *governor.Governor
+}
+
+// Snapshot is everything `picoclaw top` shows.
+type Snapshot struct {
+	Time      time.Time            `json:"time"`
+	Turns     []Turn               `json:"turns"`
+	Queue     QueueDepth           `json:"queue"`
+	Channels  []ChannelInfo        `json:"channels"`
+	Recent    map[string][]Message `json:"recent"`
+	Subagents []SubagentInfo       `json:"subagents"`
+	Cron      []CronInfo           `json:"cron"`
+	Usage     Usage                `json:"usage"`
+	Memory    MemoryInfo           `json:"memory"`
+}
+
+type QueueDepth struct {
+	Inbound  int `json:"inbound"`
+	Outbound int `json:"outbound"`
+}
+
+type ChannelInfo struct {
+	Name    string `json:"name"`
+	Running bool   `json:"running"`
+	Paused  bool   `json:"paused"`
+	Held    int    `json:"held,omitempty"`
+}
+
+type SubagentInfo struct {
+	ID        string    `json:"id"`
+	Label     string    `json:"label"`
+	Task      string    `json:"task"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+type CronInfo struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	NextRunAt time.Time `json:"next_run_at"`
+}
+
+type MemoryInfo struct {
+	HeapAlloc  uint64 `json:"heap_alloc"`
+	Sys        uint64 `json:"sys"`
+	NumGC      uint32 `json:"num_gc"`
+	Goroutines int    `json:"goroutines"`
+	// Governor is the memory governor's last check, when there is one.
+	Governor *governor.Status `json:"governor,omitempty"`
+}
+
+// TakeSnapshot collects the current state from all sources.
+func (src Sources) TakeSnapshot() Snapshot {
+	s := Snapshot{
+		Time:      time.Now(),
+		Turns:     []Turn{},
+		Channels:  []ChannelInfo{},
+		Recent:    map[string][]Message{},
+		Subagents: []SubagentInfo{},
+		Cron:      []CronInfo{},
+	}
+
+	paused := map[string]int{}
+	if src.Monitor != nil {
+		s.Turns = src.Monitor.Turns()
+		s.Recent = src.Monitor.Recent()
+		s.Usage = src.Monitor.Usage()
+		paused = src.Monitor.Paused()
+	}
+
+	if src.Bus != nil {
+		s.Queue.Inbound, s.Queue.Outbound = src.Bus.QueueDepth()
+	}
+
+	if src.Channels != nil {
+		for name, raw := range src.Channels.GetStatus() {
+			info := ChannelInfo{Name: name}
+			if st, ok := raw.(map[string]interface{}); ok {
+				info.Running, _ = st["running"].(bool)
+			}
+			info.Held, info.Paused = paused[name]
+			s.Channels = append(s.Channels, info)
+		}
+		sort.Slice(s.Channels, func(i, j int) bool { return s.Channels[i].Name < s.Channels[j].Name })
+	}
+
+	if src.Subagents != nil {
+		for _, task := range src.Subagents.TaskSnapshots() {
+			if task.Status != "running" {
+				continue
+			}
+			s.Subagents = append(s.Subagents, SubagentInfo{
+				ID:        task.ID,
+				Label:     task.Label,
+				Task:      task.Task,
+				StartedAt: time.UnixMilli(task.Created),
+			})
+		}
+		sort.Slice(s.Subagents, func(i, j int) bool { return s.Subagents[i].StartedAt.Before(s.Subagents[j].StartedAt) })
+	}
+
+	if src.Cron != nil {
+		for _, job := range src.Cron.ListJobs(false) {
+			if job.State.NextRunAtMS == nil {
+				continue
+			}
+			s.Cron = append(s.Cron, CronInfo{ID: job.ID, Name: job.Name, NextRunAt: time.UnixMilli(*job.State.NextRunAtMS)})
+		}
+		sort.Slice(s.Cron, func(i, j int) bool { return s.Cron[i].NextRunAt.Before(s.Cron[j].NextRunAt) })
+		if len(s.Cron) > upcomingCronJobs {
+			s.Cron = s.Cron[:upcomingCronJobs]
+		}
+	}
+
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	s.Memory = MemoryInfo{
+		HeapAlloc:  ms.HeapAlloc,
+		Sys:        ms.Sys,
+		NumGC:      ms.NumGC,
+		Goroutines: runtime.NumGoroutine(),
+	}
+	if src.Governor != nil {
+		status := src.Governor.Status()
+		s.Memory.Governor = &status
+	}
+
+	return s
+}
+
+// NewHandler serves the monitor API:
+//
+//	GET  /snapshot
+//	POST /turns/{id}/cancel
+//	POST /channels/{name}/pause
+//	POST /channels/{name}/resume
+func NewHandler(src Sources) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /snapshot", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, src.TakeSnapshot())
+	})
+
+	mux.HandleFunc("POST /turns/{id}/cancel", func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil || src.Monitor == nil || !src.Monitor.CancelTurn(id) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "turn not found"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"cancelled": id})
+	})
+
+	mux.HandleFunc("POST /channels/{name}/pause", func(w http.ResponseWriter, r *http.Request) {
+		if src.Monitor == nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "monitor not available"})
+			return
+		}
+		src.Monitor.Pause(r.PathValue("name"))
+		writeJSON(w, http.StatusOK, map[string]interface{}{"paused": r.PathValue("name")})
+	})
+
+	mux.HandleFunc("POST /channels/{name}/resume", func(w http.ResponseWriter, r *http.Request) {
+		if src.Monitor == nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "monitor not available"})
+			return
+		}
+		held := src.Monitor.Resume(r.PathValue("name"))
+		if src.Bus != nil && len(held) > 0 {
+			// Republish in the background: the inbound queue may be full.
+			go func() {
+				for _, msg := range held {
+					src.Bus.PublishInbound(msg)
+				}
+			}()
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"resumed": r.PathValue("name"), "released": len(held)})
+	})
+
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// SocketServer serves the monitor API on a local Unix socket. Access is
+// limited by file permissions: the socket is only usable by its owner.
+type SocketServer struct {
+	path     string
+	server   *http.Server
+	listener net.Listener
+}
+
+// ListenSocket starts serving handler on a Unix socket at path, replacing
+// a stale socket left behind by a previous run.
+func ListenSocket(path string, handler http.Handler) (*SocketServer, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("control socket %s is in use by another gateway", path)
+	}
+	os.Remove(path)
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	s := &SocketServer{
+		path:     path,
+		listener: listener,
+		server:   &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second},
+	}
+	go func() {
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logger.ErrorCF("monitor", "Control socket server error", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+	}()
+	return s, nil
+}
+
+// Close stops the server and removes the socket file.
+func (s *SocketServer) Close() error {
+	err := s.server.Close()
+	os.Remove(s.path)
+	return err
+}
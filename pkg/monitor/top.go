@@ -0,0 +1,418 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chzyer/readline"
+)
+
+const (
+	topRefreshInterval = time.Second
+	recentShownPerChan = 3
+)
+
+type topFocus int
+
+const (
+	focusTurns topFocus = iota
+	focusChannels
+)
+
+// topView is the interactive state of the top screen.
+type topView struct {
+	focus   topFocus
+	turn    int // selected index in the turns list
+	channel int // selected index in the channels list
+	status  string
+}
+
+// topAction is what a key press asks the dashboard to do.
+type topAction int
+
+const (
+	actionNone topAction = iota
+	actionQuit
+	actionCancelTurn
+	actionTogglePause
+)
+
+// handleKey updates the view for a key and returns the action it triggers.
+func (v *topView) handleKey(key string, s *Snapshot) topAction {
+	switch key {
+	case "q", "\x03":
+		return actionQuit
+	case "\t":
+		if v.focus == focusTurns {
+			v.focus = focusChannels
+		} else {
+			v.focus = focusTurns
+		}
+	case "j", "\x1b[B":
+		v.move(1, s)
+	case "k", "\x1b[A":
+		v.move(-1, s)
+	case "c":
+		return actionCancelTurn
+	case "p":
+		return actionTogglePause
+	}
+	return actionNone
+}
+
+func (v *topView) move(delta int, s *Snapshot) {
+	if s == nil {
+		return
+	}
+	if v.focus == focusTurns {
+		v.turn = clamp(v.turn+delta, len(s.Turns))
+	} else {
+		v.channel = clamp(v.channel+delta, len(s.Channels))
+	}
+}
+
+func clamp(i, n int) int {
+	if n == 0 || i < 0 {
+		return 0
+	}
+	if i >= n {
+		return n - 1
+	}
+	return i
+}
+
+// PrintSnapshot writes a single plain-text snapshot to out.
+func PrintSnapshot(ctx context.Context, client *Client, out io.Writer) error {
+	snap, err := client.Snapshot(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot reach the gateway: %w", err)
+	}
+	_, err = io.WriteString(out, strings.ReplaceAll(renderTop(snap, &topView{}, 0, 0, false), "\r\n", "\n"))
+	return err
+}
+
+// RunTop shows the live dashboard until the user quits. If in is not a
+// terminal it prints a single snapshot instead.
+func RunTop(ctx context.Context, client *Client, in *os.File, out io.Writer) error {
+	fd := int(in.Fd())
+	if !readline.IsTerminal(fd) {
+		return PrintSnapshot(ctx, client, out)
+	}
+
+	snap, err := client.Snapshot(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot reach the gateway: %w", err)
+	}
+
+	state, err := readline.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer readline.Restore(fd, state)
+
+	fmt.Fprint(out, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(out, "\x1b[?25h\x1b[?1049l")
+
+	keys := make(chan string)
+	go readKeys(in, keys)
+
+	ticker := time.NewTicker(topRefreshInterval)
+	defer ticker.Stop()
+
+	view := &topView{}
+	for {
+		width, height, err := readline.GetSize(fd)
+		if err != nil || width <= 0 {
+			width, height = 80, 24
+		}
+		view.turn = clamp(view.turn, len(snap.Turns))
+		view.channel = clamp(view.channel, len(snap.Channels))
+		fmt.Fprint(out, "\x1b[H"+renderTop(snap, view, width, height, true)+"\x1b[J")
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case key, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			switch view.handleKey(key, snap) {
+			case actionQuit:
+				return nil
+			case actionCancelTurn:
+				view.status = cancelSelectedTurn(ctx, client, snap, view)
+			case actionTogglePause:
+				view.status = toggleSelectedChannel(ctx, client, snap, view)
+			default:
+				continue
+			}
+		case <-ticker.C:
+		}
+
+		if s, err := client.Snapshot(ctx); err != nil {
+			view.status = "refresh failed: " + err.Error()
+		} else {
+			snap = s
+		}
+	}
+}
+
+func cancelSelectedTurn(ctx context.Context, client *Client, s *Snapshot, v *topView) string {
+	if v.turn >= len(s.Turns) {
+		return "no turn selected"
+	}
+	t := s.Turns[v.turn]
+	if err := client.CancelTurn(ctx, t.ID); err != nil {
+		return "cancel failed: " + err.Error()
+	}
+	return fmt.Sprintf("cancelled turn #%d (%s)", t.ID, t.SessionKey)
+}
+
+func toggleSelectedChannel(ctx context.Context, client *Client, s *Snapshot, v *topView) string {
+	if v.channel >= len(s.Channels) {
+		return "no channel selected"
+	}
+	ch := s.Channels[v.channel]
+	if ch.Paused {
+		if err := client.ResumeChannel(ctx, ch.Name); err != nil {
+			return "resume failed: " + err.Error()
+		}
+		return "resumed " + ch.Name
+	}
+	if err := client.PauseChannel(ctx, ch.Name); err != nil {
+		return "pause failed: " + err.Error()
+	}
+	return "paused " + ch.Name
+}
+
+// readKeys turns raw terminal input into key strings. Arrow keys arrive as
+// a single read of their escape sequence.
+func readKeys(in io.Reader, keys chan<- string) {
+	defer close(keys)
+	buf := make([]byte, 16)
+	for {
+		n, err := in.Read(buf)
+		if err != nil {
+			return
+		}
+		seq := string(buf[:n])
+		if strings.HasPrefix(seq, "\x1b[") && n >= 3 {
+			keys <- seq[:3]
+			continue
+		}
+		for _, r := range seq {
+			keys <- string(r)
+		}
+	}
+}
+
+// renderTop draws one frame. height 0 means unlimited.
+func renderTop(s *Snapshot, v *topView, width, height int, interactive bool) string {
+	var lines []string
+	add := func(format string, args ...interface{}) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	}
+
+	add("picoclaw top  %s   mem %s heap / %s sys   goroutines %d   queue in %d out %d",
+		s.Time.Format("15:04:05"), humanBytes(s.Memory.HeapAlloc), humanBytes(s.Memory.Sys),
+		s.Memory.Goroutines, s.Queue.Inbound, s.Queue.Outbound)
+	if g := s.Memory.Governor; g != nil && g.Limit > 0 {
+		state := "ok"
+		if g.Pressure {
+			state = "PRESSURE"
+		}
+		add("memory  rss %s of %s limit   %s   background %d/%d",
+			humanBytes(g.RSS), humanBytes(g.Limit), state, g.Background, g.BackgroundLimit)
+	}
+	add("tokens  %s/min   last 5m %s   total %s (prompt %s, completion %s)",
+		humanCount(int64(s.Usage.TokensPerMinute)), humanCount(s.Usage.TokensLast5Min),
+		humanCount(s.Usage.PromptTotal+s.Usage.CompletionTotal),
+		humanCount(s.Usage.PromptTotal), humanCount(s.Usage.CompletionTotal))
+	add("")
+
+	add("%s (%d)", heading("TURNS", v.focus == focusTurns && interactive), len(s.Turns))
+	if len(s.Turns) == 0 {
+		add("  idle")
+	}
+	for i, t := range s.Turns {
+		tool := ""
+		if t.Tool != "" {
+			tool = "tool " + t.Tool
+		}
+		add("%s#%-4d %-28s iter %-3d %-24s %s", cursor(interactive && v.focus == focusTurns && v.turn == i),
+			t.ID, t.SessionKey, t.Iteration, tool, humanDuration(s.Time.Sub(t.StartedAt)))
+	}
+	add("")
+
+	add("%s", heading("CHANNELS", v.focus == focusChannels && interactive))
+	if len(s.Channels) == 0 {
+		add("  none enabled")
+	}
+	for i, ch := range s.Channels {
+		state := "stopped"
+		if ch.Running {
+			state = "running"
+		}
+		if ch.Paused {
+			state += fmt.Sprintf(", paused (%d held)", ch.Held)
+		}
+		add("%s%-12s %s", cursor(interactive && v.focus == focusChannels && v.channel == i), ch.Name, state)
+	}
+	add("")
+
+	if len(s.Subagents) > 0 {
+		add("SUBAGENTS (%d)", len(s.Subagents))
+		for _, sa := range s.Subagents {
+			label := sa.Label
+			if label == "" {
+				label = sa.Task
+			}
+			add("  %-14s %-40s %s", sa.ID, label, humanDuration(s.Time.Sub(sa.StartedAt)))
+		}
+		add("")
+	}
+
+	if len(s.Cron) > 0 {
+		add("UPCOMING CRON")
+		for _, job := range s.Cron {
+			add("  %-24s in %-10s %s", job.Name, humanDuration(job.NextRunAt.Sub(s.Time)), job.NextRunAt.Format("Jan 02 15:04"))
+		}
+		add("")
+	}
+
+	add("RECENT")
+	names := make([]string, 0, len(s.Recent))
+	for name := range s.Recent {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		add("  no messages yet")
+	}
+	for _, name := range names {
+		msgs := s.Recent[name]
+		if len(msgs) > recentShownPerChan {
+			msgs = msgs[len(msgs)-recentShownPerChan:]
+		}
+		for _, m := range msgs {
+			arrow := "←"
+			if m.Direction == "out" {
+				arrow = "→"
+			}
+			add("  %-10s %s %s %s: %s", name, m.Time.Format("15:04:05"), arrow, m.ChatID, oneLine(m.Preview))
+		}
+	}
+
+	footer := ""
+	if interactive {
+		footer = "[tab] switch  [j/k] select  [c] cancel turn  [p] pause/resume channel  [q] quit"
+		if v.status != "" {
+			footer += "   " + v.status
+		}
+	}
+
+	if height > 0 && len(lines) > height-1 {
+		lines = lines[:height-1]
+	}
+	if footer != "" {
+		lines = append(lines, footer)
+	}
+
+	for i, line := range lines {
+		lines[i] = fitWidth(line, width)
+		if interactive {
+			lines[i] += "\x1b[K" // clear what the previous frame left on this row
+		}
+	}
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+func heading(title string, focused bool) string {
+	if focused {
+		return "\x1b[7m" + title + "\x1b[0m"
+	}
+	return title
+}
+
+func cursor(selected bool) string {
+	if selected {
+		return "> "
+	}
+	return "  "
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// fitWidth truncates a line to width runes, ignoring escape sequences.
+func fitWidth(s string, width int) string {
+	if width <= 0 {
+		return s
+	}
+	var b strings.Builder
+	visible := 0
+	inEscape := false
+	for _, r := range s {
+		switch {
+		case r == '\x1b':
+			inEscape = true
+		case inEscape:
+			if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
+				inEscape = false
+			}
+		default:
+			if visible >= width {
+				continue
+			}
+			visible++
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func humanBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func humanCount(n int64) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1_000_000)
+	case n >= 1_000:
+		return fmt.Sprintf("%.1fk", float64(n)/1_000)
+	default:
+		return fmt.Sprintf("%d", n)
+	}
+}
+
+func humanDuration(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
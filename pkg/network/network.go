@@ -0,0 +1,353 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// Inspired by and based on nanobot: https://github.com/HKUDS/nanobot
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+// Package network builds the HTTP clients used by every subsystem, so that
+// proxies, extra CA certificates, timeouts, the IP family and the user
+// agent are configured once in the "network" section of the config.
+package network
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"golang.org/x/net/http/httpproxy"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// Component names that can be given their own settings under
+// network.components. Channels use their config name (e.g. "telegram").
+const (
+	ComponentProviders  = "providers"
+	ComponentWeb        = "web"
+	ComponentMedia      = "media"
+	ComponentSkills     = "skills"
+	ComponentVoice      = "voice"
+	ComponentFederation = "federation"
+	ComponentUpdate     = "update"
+	ComponentBus        = "bus"
+)
+
+// ProxyDirect disables proxying, including the proxy environment variables.
+const ProxyDirect = "direct"
+
+const defaultConnectTimeout = 30 * time.Second
+
+// Options are a component's own defaults. Configured settings take
+// precedence over Timeout; Proxy (e.g. a provider's proxy setting) takes
+// precedence over the configured proxy.
+type Options struct {
+	Timeout time.Duration
+	Proxy   string
+}
+
+// settings are the effective values for one component.
+type settings struct {
+	proxy          string
+	noProxy        string
+	caFile         string
+	connectTimeout time.Duration
+	timeout        time.Duration
+	ipFamily       string
+	userAgent      string
+}
+
+var (
+	mu         sync.Mutex
+	current    config.NetworkConfig
+	pools      = map[string]*x509.CertPool{}
+	transports = map[string]http.RoundTripper{}
+)
+
+// Configure replaces the process-wide network settings. Clients created
+// before the call keep their old settings.
+func Configure(cfg config.NetworkConfig) error {
+	newPools := map[string]*x509.CertPool{}
+	files := []string{cfg.CAFile}
+	for _, o := range cfg.Components {
+		files = append(files, o.CAFile)
+	}
+	for _, file := range files {
+		if file == "" || newPools[file] != nil {
+			continue
+		}
+		pool, err := loadCertPool(file)
+		if err != nil {
+			return err
+		}
+		newPools[file] = pool
+	}
+
+	for _, proxy := range proxiesOf(cfg) {
+		if _, err := parseProxy(proxy); err != nil {
+			return err
+		}
+	}
+	for name, family := range familiesOf(cfg) {
+		if _, err := dialNetwork(family); err != nil {
+			return fmt.Errorf("network%s: %w", name, err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	current = cfg
+	pools = newPools
+	transports = map[string]http.RoundTripper{}
+	return nil
+}
+
+func proxiesOf(cfg config.NetworkConfig) []string {
+	out := []string{cfg.Proxy}
+	for _, o := range cfg.Components {
+		out = append(out, o.Proxy)
+	}
+	return out
+}
+
+func familiesOf(cfg config.NetworkConfig) map[string]string {
+	out := map[string]string{"": cfg.IPFamily}
+	for name, o := range cfg.Components {
+		out[".components."+name] = o.IPFamily
+	}
+	return out
+}
+
+// NewClient returns an HTTP client for component.
+func NewClient(component string, opts Options) *http.Client {
+	mu.Lock()
+	s := resolve(component, opts)
+	rt := transportLocked(component, s)
+	mu.Unlock()
+
+	return &http.Client{Timeout: s.timeout, Transport: rt}
+}
+
+// Transport returns the round tripper for component, for libraries that
+// build their own http.Client.
+func Transport(component string, opts Options) http.RoundTripper {
+	mu.Lock()
+	defer mu.Unlock()
+	return transportLocked(component, resolve(component, opts))
+}
+
+func resolve(component string, opts Options) settings {
+	cfg := current
+	s := settings{
+		proxy:          cfg.Proxy,
+		noProxy:        strings.Join(cfg.NoProxy, ","),
+		caFile:         cfg.CAFile,
+		connectTimeout: seconds(cfg.ConnectTimeout),
+		timeout:        seconds(cfg.Timeout),
+		ipFamily:       cfg.IPFamily,
+		userAgent:      cfg.UserAgent,
+	}
+	if o, ok := cfg.Components[component]; ok {
+		if o.Proxy != "" {
+			s.proxy = o.Proxy
+		}
+		if len(o.NoProxy) > 0 {
+			s.noProxy = strings.Join(o.NoProxy, ",")
+		}
+		if o.CAFile != "" {
+			s.caFile = o.CAFile
+		}
+		if o.ConnectTimeout > 0 {
+			s.connectTimeout = seconds(o.ConnectTimeout)
+		}
+		if o.Timeout > 0 {
+			s.timeout = seconds(o.Timeout)
+		}
+		if o.IPFamily != "" {
+			s.ipFamily = o.IPFamily
+		}
+		if o.UserAgent != "" {
+			s.userAgent = o.UserAgent
+		}
+	}
+
+	if opts.Proxy != "" {
+		s.proxy = opts.Proxy
+	}
+	if s.timeout == 0 {
+		s.timeout = opts.Timeout
+	}
+	if s.connectTimeout == 0 {
+		s.connectTimeout = defaultConnectTimeout
+	}
+	return s
+}
+
+func seconds(n int) time.Duration {
+	return time.Duration(n) * time.Second
+}
+
+// transportLocked returns a cached transport for s, so that clients with
+// the same settings share connections. mu must be held.
+func transportLocked(component string, s settings) http.RoundTripper {
+	key := fmt.Sprintf("%s|%+v", component, s)
+	if rt, ok := transports[key]; ok {
+		return rt
+	}
+
+	t := http.DefaultTransport.(*http.Transport).Clone()
+
+	dialer := &net.Dialer{Timeout: s.connectTimeout, KeepAlive: 30 * time.Second}
+	network, _ := dialNetwork(s.ipFamily)
+	t.DialContext = func(ctx context.Context, _, addr string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, addr)
+	}
+
+	if pool := pools[s.caFile]; pool != nil {
+		t.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+
+	proxyFunc, err := parseProxy(s.proxy)
+	if err != nil {
+		logger.WarnCF("network", "Ignoring invalid proxy", map[string]interface{}{
+			"component": component,
+			"error":     err.Error(),
+		})
+		proxyFunc = http.ProxyFromEnvironment
+		s.proxy = ""
+	}
+	t.Proxy = withNoProxy(proxyFunc, s)
+
+	var rt http.RoundTripper = t
+	if s.userAgent != "" {
+		rt = &userAgentTransport{userAgent: s.userAgent, next: t}
+	}
+	transports[key] = rt
+	return rt
+}
+
+// WebSocketDialer returns a dialer for component's WebSocket connections,
+// with the proxy, CA certificates, connect timeout and IP family of its
+// HTTP client.
+func WebSocketDialer(component string, opts Options) *websocket.Dialer {
+	mu.Lock()
+	rt := transportLocked(component, resolve(component, opts))
+	mu.Unlock()
+
+	if ua, ok := rt.(*userAgentTransport); ok {
+		rt = ua.next
+	}
+	t := rt.(*http.Transport)
+	d := &websocket.Dialer{
+		Proxy:            t.Proxy,
+		NetDialContext:   t.DialContext,
+		HandshakeTimeout: 10 * time.Second,
+	}
+	if t.TLSClientConfig != nil {
+		d.TLSClientConfig = t.TLSClientConfig.Clone()
+	}
+	return d
+}
+
+// CloseIdleConnections closes the kept-alive connections of all clients,
+// freeing their buffers. It returns how many transports it closed them on.
+func CloseIdleConnections() int {
+	mu.Lock()
+	defer mu.Unlock()
+	n := 0
+	for _, rt := range transports {
+		if ua, ok := rt.(*userAgentTransport); ok {
+			rt = ua.next
+		}
+		if t, ok := rt.(*http.Transport); ok {
+			t.CloseIdleConnections()
+			n++
+		}
+	}
+	return n
+}
+
+// parseProxy turns a proxy setting into a proxy function. Empty means the
+// environment variables.
+func parseProxy(proxy string) (func(*http.Request) (*url.URL, error), error) {
+	switch proxy {
+	case "":
+		return http.ProxyFromEnvironment, nil
+	case ProxyDirect:
+		return nil, nil
+	}
+	u, err := url.Parse(proxy)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q", proxy)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q in %q", u.Scheme, proxy)
+	}
+	return http.ProxyURL(u), nil
+}
+
+// withNoProxy applies the configured no_proxy list to an explicit proxy.
+// The environment proxy already honours NO_PROXY.
+func withNoProxy(proxy func(*http.Request) (*url.URL, error), s settings) func(*http.Request) (*url.URL, error) {
+	if proxy == nil || s.noProxy == "" || s.proxy == "" {
+		return proxy
+	}
+	pc := &httpproxy.Config{HTTPProxy: s.proxy, HTTPSProxy: s.proxy, NoProxy: s.noProxy}
+	match := pc.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return match(req.URL)
+	}
+}
+
+func dialNetwork(family string) (string, error) {
+	switch strings.ToLower(family) {
+	case "", "any":
+		return "tcp", nil
+	case "ipv4":
+		return "tcp4", nil
+	case "ipv6":
+		return "tcp6", nil
+	}
+	return "", fmt.Errorf("invalid ip_family %q (use ipv4 or ipv6)", family)
+}
+
+func loadCertPool(file string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA file: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in CA file %s", file)
+	}
+	return pool, nil
+}
+
+// userAgentTransport sets the configured user agent on requests that do
+// not set their own.
+type userAgentTransport struct {
+	userAgent string
+	next      http.RoundTripper
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+	return t.next.RoundTrip(req)
+}
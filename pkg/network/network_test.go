@@ -0,0 +1,239 @@
+package network
+
+import (
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func configure(t *testing.T, cfg config.NetworkConfig) {
+	t.Helper()
+	if err := Configure(cfg); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	t.Cleanup(func() { Configure(config.NetworkConfig{}) })
+}
+
+// fakeProxy answers every request itself and counts them.
+func fakeProxy(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		io.WriteString(w, "proxied "+r.URL.Host)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+func get(t *testing.T, c *http.Client, url string) (string, error) {
+	t.Helper()
+	resp, err := c.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return string(body), nil
+}
+
+func TestProxyAndNoProxy(t *testing.T) {
+	proxy, hits := fakeProxy(t)
+	configure(t, config.NetworkConfig{
+		Proxy:   proxy.URL,
+		NoProxy: config.FlexibleStringSlice{"internal.invalid"},
+		Components: map[string]config.NetworkOverride{
+			ComponentWeb: {Proxy: ProxyDirect},
+		},
+	})
+
+	body, err := get(t, NewClient(ComponentProviders, Options{}), "http://api.example.invalid/v1")
+	if err != nil || body != "proxied api.example.invalid" {
+		t.Fatalf("request not sent through the proxy: %q, %v", body, err)
+	}
+
+	if _, err := get(t, NewClient(ComponentProviders, Options{}), "http://internal.invalid/"); err == nil {
+		t.Error("no_proxy host should be dialled directly (and fail to resolve)")
+	}
+	if _, err := get(t, NewClient(ComponentWeb, Options{}), "http://api.example.invalid/"); err == nil {
+		t.Error("component override \"direct\" should bypass the proxy")
+	}
+	if got := hits.Load(); got != 1 {
+		t.Errorf("proxy hits = %d, want 1", got)
+	}
+}
+
+func TestOptionProxyOverridesGlobal(t *testing.T) {
+	global, globalHits := fakeProxy(t)
+	own, ownHits := fakeProxy(t)
+	configure(t, config.NetworkConfig{Proxy: global.URL})
+
+	if _, err := get(t, NewClient(ComponentProviders, Options{Proxy: own.URL}), "http://llm.invalid/"); err != nil {
+		t.Fatal(err)
+	}
+	if globalHits.Load() != 0 || ownHits.Load() != 1 {
+		t.Errorf("hits global=%d own=%d, want 0/1", globalHits.Load(), ownHits.Load())
+	}
+}
+
+func TestCAFile(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	if _, err := get(t, NewClient(ComponentWeb, Options{}), srv.URL); err == nil {
+		t.Fatal("self-signed server should not be trusted by default")
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caFile, certPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	configure(t, config.NetworkConfig{CAFile: caFile})
+
+	if body, err := get(t, NewClient(ComponentWeb, Options{}), srv.URL); err != nil || body != "ok" {
+		t.Errorf("request with extra CA failed: %q, %v", body, err)
+	}
+}
+
+func TestIPFamily(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	configure(t, config.NetworkConfig{IPFamily: "ipv4"})
+	if _, err := get(t, NewClient(ComponentMedia, Options{}), srv.URL); err != nil {
+		t.Errorf("ipv4 request to %s failed: %v", srv.URL, err)
+	}
+
+	configure(t, config.NetworkConfig{IPFamily: "ipv6"})
+	if _, err := get(t, NewClient(ComponentMedia, Options{}), srv.URL); err == nil {
+		t.Errorf("ipv6-only client reached the IPv4 address %s", srv.URL)
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.UserAgent())
+	}))
+	defer srv.Close()
+
+	configure(t, config.NetworkConfig{
+		UserAgent:  "picoclaw-board",
+		Components: map[string]config.NetworkOverride{ComponentSkills: {UserAgent: "picoclaw-skills"}},
+	})
+
+	if body, _ := get(t, NewClient(ComponentVoice, Options{}), srv.URL); body != "picoclaw-board" {
+		t.Errorf("user agent = %q", body)
+	}
+	if body, _ := get(t, NewClient(ComponentSkills, Options{}), srv.URL); body != "picoclaw-skills" {
+		t.Errorf("component user agent = %q", body)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("User-Agent", "custom")
+	resp, err := NewClient(ComponentVoice, Options{}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if body, _ := io.ReadAll(resp.Body); string(body) != "custom" {
+		t.Errorf("explicit user agent replaced: %q", body)
+	}
+}
+
+func TestTimeouts(t *testing.T) {
+	if c := NewClient(ComponentProviders, Options{Timeout: 2 * time.Minute}); c.Timeout != 2*time.Minute {
+		t.Errorf("component default timeout = %v", c.Timeout)
+	}
+
+	configure(t, config.NetworkConfig{
+		Timeout:    30,
+		Components: map[string]config.NetworkOverride{ComponentProviders: {Timeout: 300}},
+	})
+	if c := NewClient(ComponentWeb, Options{Timeout: 10 * time.Second}); c.Timeout != 30*time.Second {
+		t.Errorf("global timeout not applied: %v", c.Timeout)
+	}
+	if c := NewClient(ComponentProviders, Options{Timeout: 2 * time.Minute}); c.Timeout != 5*time.Minute {
+		t.Errorf("component override not applied: %v", c.Timeout)
+	}
+}
+
+func TestTransportsAreShared(t *testing.T) {
+	configure(t, config.NetworkConfig{})
+	a := NewClient(ComponentWeb, Options{Timeout: time.Second})
+	b := NewClient(ComponentWeb, Options{Timeout: time.Second})
+	if a.Transport != b.Transport {
+		t.Error("clients with the same settings should share a transport")
+	}
+}
+
+func TestConfigureErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.NetworkConfig
+		want string
+	}{
+		{"proxy scheme", config.NetworkConfig{Proxy: "ftp://proxy:21"}, "unsupported proxy scheme"},
+		{"missing CA", config.NetworkConfig{CAFile: "/nonexistent/ca.pem"}, "CA file"},
+		{"ip family", config.NetworkConfig{Components: map[string]config.NetworkOverride{"web": {IPFamily: "ipx"}}}, "components.web"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Configure(tt.cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Configure() = %v, want error mentioning %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebSocketDialer(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if conn, err := upgrader.Upgrade(w, r, nil); err == nil {
+			conn.Close()
+		}
+	}))
+	defer srv.Close()
+	wsURL := "wss" + strings.TrimPrefix(srv.URL, "https")
+
+	if conn, _, err := WebSocketDialer("mattermost", Options{}).Dial(wsURL, nil); err == nil {
+		conn.Close()
+		t.Fatal("self-signed server should not be trusted by default")
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caFile, certPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	configure(t, config.NetworkConfig{
+		CAFile: caFile,
+		Components: map[string]config.NetworkOverride{
+			"onebot": {Proxy: "http://proxy.invalid:3128"},
+		},
+	})
+
+	conn, _, err := WebSocketDialer("mattermost", Options{}).Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("dial with extra CA failed: %v", err)
+	}
+	conn.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, "https://qq.example.com/ws", nil)
+	if u, err := WebSocketDialer("onebot", Options{}).Proxy(req); err != nil || u == nil || u.Host != "proxy.invalid:3128" {
+		t.Errorf("onebot proxy = %v, %v", u, err)
+	}
+}
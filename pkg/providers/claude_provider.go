@@ -8,8 +8,11 @@ import (
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/option"
 	"github.com/sipeed/picoclaw/pkg/auth"
+	"github.com/sipeed/picoclaw/pkg/network"
 )
 
+const claudeBaseURL = "https://api.anthropic.com"
+
 type ClaudeProvider struct {
 	client      *anthropic.Client
 	tokenSource func() (string, error)
@@ -18,7 +21,8 @@ type ClaudeProvider struct {
 func NewClaudeProvider(token string) *ClaudeProvider {
 	client := anthropic.NewClient(
 		option.WithAuthToken(token),
-		option.WithBaseURL("https://api.anthropic.com"),
+		option.WithBaseURL(claudeBaseURL),
+		option.WithHTTPClient(network.NewClient(network.ComponentProviders, network.Options{})),
 	)
 	return &ClaudeProvider{client: &client}
 }
@@ -52,6 +56,10 @@ func (p *ClaudeProvider) Chat(ctx context.Context, messages []Message, tools []T
 	return parseClaudeResponse(resp), nil
 }
 
+func (p *ClaudeProvider) Endpoint() string {
+	return claudeBaseURL
+}
+
 func (p *ClaudeProvider) GetDefaultModel() string {
 	return "claude-sonnet-4-5-20250929"
 }
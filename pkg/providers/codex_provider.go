@@ -10,6 +10,7 @@ import (
 	"github.com/openai/openai-go/v3/option"
 	"github.com/openai/openai-go/v3/responses"
 	"github.com/sipeed/picoclaw/pkg/auth"
+	"github.com/sipeed/picoclaw/pkg/network"
 )
 
 type CodexProvider struct {
@@ -18,12 +19,16 @@ type CodexProvider struct {
 	tokenSource func() (string, string, error)
 }
 
-const defaultCodexInstructions = "You are Codex, a coding assistant."
+const (
+	defaultCodexInstructions = "You are Codex, a coding assistant."
+	codexBaseURL             = "https://chatgpt.com/backend-api/codex"
+)
 
 func NewCodexProvider(token, accountID string) *CodexProvider {
 	opts := []option.RequestOption{
-		option.WithBaseURL("https://chatgpt.com/backend-api/codex"),
+		option.WithBaseURL(codexBaseURL),
 		option.WithAPIKey(token),
+		option.WithHTTPClient(network.NewClient(network.ComponentProviders, network.Options{})),
 	}
 	if accountID != "" {
 		opts = append(opts, option.WithHeader("Chatgpt-Account-Id", accountID))
@@ -64,6 +69,10 @@ func (p *CodexProvider) Chat(ctx context.Context, messages []Message, tools []To
 	return parseCodexResponse(resp), nil
 }
 
+func (p *CodexProvider) Endpoint() string {
+	return codexBaseURL
+}
+
 func (p *CodexProvider) GetDefaultModel() string {
 	return "gpt-4o"
 }
@@ -13,12 +13,12 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
 	"strings"
 	"time"
 
 	"github.com/sipeed/picoclaw/pkg/auth"
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/network"
 )
 
 type HTTPProvider struct {
@@ -27,27 +27,23 @@ type HTTPProvider struct {
 	httpClient *http.Client
 }
 
+// NewHTTPProvider creates a provider for an OpenAI-compatible API. proxy,
+// if set, overrides the global network proxy.
 func NewHTTPProvider(apiKey, apiBase, proxy string) *HTTPProvider {
-	client := &http.Client{
-		Timeout: 120 * time.Second,
-	}
-
-	if proxy != "" {
-		proxyURL, err := url.Parse(proxy)
-		if err == nil {
-			client.Transport = &http.Transport{
-				Proxy: http.ProxyURL(proxyURL),
-			}
-		}
-	}
-
 	return &HTTPProvider{
-		apiKey:     apiKey,
-		apiBase:    strings.TrimRight(apiBase, "/"),
-		httpClient: client,
+		apiKey:  apiKey,
+		apiBase: strings.TrimRight(apiBase, "/"),
+		httpClient: network.NewClient(network.ComponentProviders, network.Options{
+			Timeout: 120 * time.Second,
+			Proxy:   proxy,
+		}),
 	}
 }
 
+func (p *HTTPProvider) Endpoint() string {
+	return p.apiBase
+}
+
 func (p *HTTPProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
 	if p.apiBase == "" {
 		return nil, fmt.Errorf("API base not configured")
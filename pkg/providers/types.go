@@ -40,6 +40,12 @@ type LLMProvider interface {
 	GetDefaultModel() string
 }
 
+// EndpointReporter is implemented by providers that talk to a remote API,
+// so connectivity checks know which host to probe.
+type EndpointReporter interface {
+	Endpoint() string
+}
+
 type ToolDefinition struct {
 	Type     string                 `json:"type"`
 	Function ToolFunctionDefinition `json:"function"`
@@ -1,33 +1,66 @@
 package session
 
 import (
+	"bufio"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/providers"
 )
 
+// ErrUnreadable is returned when a session exists on disk but can't be
+// loaded. Such a session is not replaced, so its history isn't overwritten.
+var ErrUnreadable = errors.New("session file can't be read")
+
 type Session struct {
 	Key      string              `json:"key"`
 	Messages []providers.Message `json:"messages"`
 	Summary  string              `json:"summary,omitempty"`
-	Created  time.Time           `json:"created"`
-	Updated  time.Time           `json:"updated"`
+	// Pins are notes that stay in the context of every turn; truncation
+	// and summarization leave them alone.
+	Pins    []string  `json:"pins,omitempty"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+}
+
+// SessionInfo describes a session without its messages.
+type SessionInfo struct {
+	Key          string    `json:"key"`
+	MessageCount int       `json:"message_count"`
+	HasSummary   bool      `json:"has_summary"`
+	Pins         int       `json:"pins"`
+	Created      time.Time `json:"created"`
+	Updated      time.Time `json:"updated"`
+}
+
+// storedSession is a session that is only on disk.
+type storedSession struct {
+	info SessionInfo
+	path string
 }
 
 type SessionManager struct {
 	sessions map[string]*Session
-	mu       sync.RWMutex
-	storage  string
+	// onDisk are sessions not loaded yet or dropped from memory by Evict.
+	// They are loaded from storage when used.
+	onDisk  map[string]storedSession
+	mu      sync.RWMutex
+	storage string
 }
 
 func NewSessionManager(storage string) *SessionManager {
 	sm := &SessionManager{
 		sessions: make(map[string]*Session),
+		onDisk:   make(map[string]storedSession),
 		storage:  storage,
 	}
 
@@ -43,20 +76,39 @@ func (sm *SessionManager) GetOrCreate(key string) *Session {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
-	session, ok := sm.sessions[key]
+	session, ok := sm.lookup(key)
 	if ok {
 		return session
 	}
 
-	session = &Session{
+	session, err := sm.create(key)
+	if err != nil {
+		// Serve the turn from a session that is never saved
+		return &Session{
+			Key:      key,
+			Messages: []providers.Message{},
+			Created:  time.Now(),
+			Updated:  time.Now(),
+		}
+	}
+	return session
+}
+
+// create adds a new session for key. It fails with ErrUnreadable when key
+// has a session on disk that lookup couldn't load. sm.mu must be held for
+// writing.
+func (sm *SessionManager) create(key string) (*Session, error) {
+	if _, ok := sm.onDisk[key]; ok {
+		return nil, ErrUnreadable
+	}
+	session := &Session{
 		Key:      key,
 		Messages: []providers.Message{},
 		Created:  time.Now(),
 		Updated:  time.Now(),
 	}
 	sm.sessions[key] = session
-
-	return session
+	return session, nil
 }
 
 func (sm *SessionManager) AddMessage(sessionKey, role, content string) {
@@ -72,14 +124,12 @@ func (sm *SessionManager) AddFullMessage(sessionKey string, msg providers.Messag
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
-	session, ok := sm.sessions[sessionKey]
+	session, ok := sm.lookup(sessionKey)
 	if !ok {
-		session = &Session{
-			Key:      sessionKey,
-			Messages: []providers.Message{},
-			Created:  time.Now(),
+		var err error
+		if session, err = sm.create(sessionKey); err != nil {
+			return
 		}
-		sm.sessions[sessionKey] = session
 	}
 
 	session.Messages = append(session.Messages, msg)
@@ -87,10 +137,10 @@ func (sm *SessionManager) AddFullMessage(sessionKey string, msg providers.Messag
 }
 
 func (sm *SessionManager) GetHistory(key string) []providers.Message {
-	sm.mu.RLock()
-	defer sm.mu.RUnlock()
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
 
-	session, ok := sm.sessions[key]
+	session, ok := sm.lookup(key)
 	if !ok {
 		return []providers.Message{}
 	}
@@ -101,10 +151,10 @@ func (sm *SessionManager) GetHistory(key string) []providers.Message {
 }
 
 func (sm *SessionManager) GetSummary(key string) string {
-	sm.mu.RLock()
-	defer sm.mu.RUnlock()
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
 
-	session, ok := sm.sessions[key]
+	session, ok := sm.lookup(key)
 	if !ok {
 		return ""
 	}
@@ -115,7 +165,7 @@ func (sm *SessionManager) SetSummary(key string, summary string) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
-	session, ok := sm.sessions[key]
+	session, ok := sm.lookup(key)
 	if ok {
 		session.Summary = summary
 		session.Updated = time.Now()
@@ -126,7 +176,7 @@ func (sm *SessionManager) TruncateHistory(key string, keepLast int) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
-	session, ok := sm.sessions[key]
+	session, ok := sm.lookup(key)
 	if !ok {
 		return
 	}
@@ -145,6 +195,165 @@ func (sm *SessionManager) TruncateHistory(key string, keepLast int) {
 	session.Updated = time.Now()
 }
 
+// Rewind drops the messages added after the history had n messages, e.g.
+// those of a turn that is going to be retried.
+func (sm *SessionManager) Rewind(key string, n int) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, ok := sm.lookup(key)
+	if !ok || n < 0 || len(session.Messages) <= n {
+		return
+	}
+	session.Messages = session.Messages[:n]
+	session.Updated = time.Now()
+}
+
+// List returns all sessions, most recently updated first.
+func (sm *SessionManager) List() []SessionInfo {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	infos := make([]SessionInfo, 0, len(sm.sessions)+len(sm.onDisk))
+	for _, stored := range sm.onDisk {
+		infos = append(infos, stored.info)
+	}
+	for _, s := range sm.sessions {
+		infos = append(infos, infoOf(s))
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Updated.After(infos[j].Updated)
+	})
+	return infos
+}
+
+// Reset clears a session's history, summary and pinned notes and persists
+// the result.
+// It returns false if the session does not exist.
+func (sm *SessionManager) Reset(key string) (bool, error) {
+	sm.mu.Lock()
+	session, ok := sm.lookup(key)
+	if ok {
+		session.Messages = []providers.Message{}
+		session.Summary = ""
+		session.Pins = nil
+		session.Updated = time.Now()
+	}
+	sm.mu.Unlock()
+
+	if !ok {
+		return false, nil
+	}
+	return true, sm.Save(key)
+}
+
+// Evict saves and drops from memory the sessions not updated within idle,
+// and the least recently updated ones beyond keep in memory. A zero idle or
+// keep disables that criterion. Evicted sessions are loaded again when
+// used. It returns how many sessions were dropped.
+func (sm *SessionManager) Evict(idle time.Duration, keep int) int {
+	if sm.storage == "" {
+		return 0
+	}
+
+	type candidate struct {
+		session *Session
+		updated time.Time
+	}
+	sm.mu.RLock()
+	candidates := make([]candidate, 0, len(sm.sessions))
+	for _, s := range sm.sessions {
+		candidates = append(candidates, candidate{s, s.Updated})
+	}
+	sm.mu.RUnlock()
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].updated.After(candidates[j].updated)
+	})
+
+	evicted := 0
+	for i, c := range candidates {
+		if !(idle > 0 && time.Since(c.updated) > idle) && !(keep > 0 && i >= keep) {
+			continue
+		}
+		key := c.session.Key
+		if err := sm.Save(key); err != nil {
+			continue
+		}
+
+		sm.mu.Lock()
+		// Keep sessions that changed after they were picked
+		if current, ok := sm.sessions[key]; ok && current == c.session && current.Updated.Equal(c.updated) {
+			sm.onDisk[key] = storedSession{infoOf(current), filepath.Join(sm.storage, sanitizeFilename(key)+".json")}
+			delete(sm.sessions, key)
+			evicted++
+		}
+		sm.mu.Unlock()
+	}
+	return evicted
+}
+
+// Resident returns how many sessions are in memory and how many are only
+// on disk.
+func (sm *SessionManager) Resident() (inMemory, onDisk int) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return len(sm.sessions), len(sm.onDisk)
+}
+
+// lookup returns the session for key, loading it back from storage if it
+// is not in memory. A session that fails to load stays on disk, see
+// create. sm.mu must be held for writing.
+func (sm *SessionManager) lookup(key string) (*Session, bool) {
+	if session, ok := sm.sessions[key]; ok {
+		return session, true
+	}
+	stored, ok := sm.onDisk[key]
+	if !ok {
+		return nil, false
+	}
+
+	session, err := readSession(stored.path, key)
+	if err != nil {
+		logger.ErrorCF("session", "Failed to load session", map[string]interface{}{
+			"key":   key,
+			"error": err.Error(),
+		})
+		return nil, false
+	}
+	delete(sm.onDisk, key)
+	sm.sessions[key] = session
+	return session, true
+}
+
+func readSession(path, key string) (*Session, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, err
+	}
+	if session.Key != key {
+		return nil, fmt.Errorf("%s holds session %q", path, session.Key)
+	}
+	if session.Messages == nil {
+		session.Messages = []providers.Message{}
+	}
+	return &session, nil
+}
+
+func infoOf(s *Session) SessionInfo {
+	return SessionInfo{
+		Key:          s.Key,
+		MessageCount: len(s.Messages),
+		HasSummary:   s.Summary != "",
+		Pins:         len(s.Pins),
+		Created:      s.Created,
+		Updated:      s.Updated,
+	}
+}
+
 // sanitizeFilename converts a session key into a cross-platform safe filename.
 // Session keys use "channel:chatID" (e.g. "telegram:123456") but ':' is the
 // volume separator on Windows, so filepath.Base would misinterpret the key.
@@ -180,6 +389,7 @@ func (sm *SessionManager) Save(key string) error {
 	snapshot := Session{
 		Key:     stored.Key,
 		Summary: stored.Summary,
+		Pins:    slices.Clone(stored.Pins),
 		Created: stored.Created,
 		Updated: stored.Updated,
 	}
@@ -249,18 +459,99 @@ func (sm *SessionManager) loadSessions() error {
 		}
 
 		sessionPath := filepath.Join(sm.storage, file.Name())
-		data, err := os.ReadFile(sessionPath)
-		if err != nil {
+		info, err := readSessionInfo(sessionPath)
+		if err != nil || info.Key == "" {
 			continue
 		}
 
-		var session Session
-		if err := json.Unmarshal(data, &session); err != nil {
-			continue
+		// Sessions are loaded when first used
+		sm.onDisk[info.Key] = storedSession{info, sessionPath}
+	}
+
+	return nil
+}
+
+// readSessionInfo reads the key and metadata of a session file. Messages
+// are counted while streaming past them, never held in memory.
+func readSessionInfo(path string) (SessionInfo, error) {
+	var info SessionInfo
+	f, err := os.Open(path)
+	if err != nil {
+		return info, err
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(bufio.NewReader(f))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return info, fmt.Errorf("%s is not a session", path)
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return info, err
 		}
+		switch tok {
+		case "key":
+			err = dec.Decode(&info.Key)
+		case "created":
+			err = dec.Decode(&info.Created)
+		case "updated":
+			err = dec.Decode(&info.Updated)
+		case "summary":
+			var summary string
+			err = dec.Decode(&summary)
+			info.HasSummary = summary != ""
+		case "messages":
+			info.MessageCount, err = countElements(dec)
+		case "pins":
+			info.Pins, err = countElements(dec)
+		default:
+			err = skipValue(dec)
+		}
+		if err != nil {
+			return info, err
+		}
+	}
+	return info, nil
+}
 
-		sm.sessions[session.Key] = &session
+// countElements skips the array at the decoder's position and returns its
+// length. A null counts as empty.
+func countElements(dec *json.Decoder) (int, error) {
+	tok, err := dec.Token()
+	if err != nil || tok == nil {
+		return 0, err
+	}
+	if tok != json.Delim('[') {
+		return 0, fmt.Errorf("expected an array, got %v", tok)
+	}
+	n := 0
+	for dec.More() {
+		if err := skipValue(dec); err != nil {
+			return n, err
+		}
+		n++
 	}
+	_, err = dec.Token()
+	return n, err
+}
 
-	return nil
+// skipValue skips the value at the decoder's position.
+func skipValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
 }
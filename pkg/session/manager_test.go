@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestSanitizeFilename(t *testing.T) {
@@ -72,3 +73,158 @@ func TestSave_RejectsPathTraversal(t *testing.T) {
 		}
 	}
 }
+
+func TestListAndReset(t *testing.T) {
+	tmpDir := t.TempDir()
+	sm := NewSessionManager(tmpDir)
+
+	sm.AddMessage("telegram:1", "user", "hello")
+	sm.AddMessage("slack:C1", "user", "hi")
+	sm.AddMessage("slack:C1", "assistant", "hey")
+	sm.SetSummary("slack:C1", "greetings")
+
+	infos := sm.List()
+	if len(infos) != 2 {
+		t.Fatalf("List() returned %d sessions, want 2", len(infos))
+	}
+	if infos[0].Key != "slack:C1" || infos[0].MessageCount != 2 || !infos[0].HasSummary {
+		t.Errorf("most recent session = %+v, want slack:C1 with 2 messages and a summary", infos[0])
+	}
+
+	ok, err := sm.Reset("slack:C1")
+	if !ok || err != nil {
+		t.Fatalf("Reset() = %v, %v", ok, err)
+	}
+	if len(sm.GetHistory("slack:C1")) != 0 || sm.GetSummary("slack:C1") != "" {
+		t.Error("Reset() did not clear history and summary")
+	}
+
+	reloaded := NewSessionManager(tmpDir)
+	if len(reloaded.GetHistory("slack:C1")) != 0 {
+		t.Error("reset was not persisted")
+	}
+
+	if ok, _ := sm.Reset("missing"); ok {
+		t.Error("Reset() of a missing session returned true")
+	}
+}
+
+func TestEvictAndReload(t *testing.T) {
+	sm := NewSessionManager(t.TempDir())
+	for _, key := range []string{"cli:a", "cli:b", "cli:c"} {
+		sm.AddMessage(key, "user", "hi "+key)
+		if key == "cli:a" {
+			sm.SetSummary(key, "greetings")
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	// Keep the two most recent in memory
+	if n := sm.Evict(0, 2); n != 1 {
+		t.Fatalf("Evict = %d, want 1", n)
+	}
+	if inMemory, onDisk := sm.Resident(); inMemory != 2 || onDisk != 1 {
+		t.Errorf("Resident = %d, %d", inMemory, onDisk)
+	}
+	if len(sm.List()) != 3 {
+		t.Errorf("List lost an evicted session: %+v", sm.List())
+	}
+
+	if h := sm.GetHistory("cli:a"); len(h) != 1 || h[0].Content != "hi cli:a" || sm.GetSummary("cli:a") != "greetings" {
+		t.Errorf("evicted session not reloaded: %+v", h)
+	}
+	sm.AddMessage("cli:a", "assistant", "hello")
+	if h := sm.GetHistory("cli:a"); len(h) != 2 {
+		t.Errorf("history after reload = %d messages", len(h))
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	if n := sm.Evict(time.Millisecond, 0); n != 3 {
+		t.Errorf("idle Evict = %d, want 3", n)
+	}
+	if inMemory, _ := sm.Resident(); inMemory != 0 {
+		t.Errorf("%d sessions still in memory", inMemory)
+	}
+	if ok, _ := sm.Reset("cli:b"); !ok || len(sm.GetHistory("cli:b")) != 0 {
+		t.Error("Reset of an evicted session failed")
+	}
+}
+
+func TestPinsSurviveTruncationAndReload(t *testing.T) {
+	dir := t.TempDir()
+	sm := NewSessionManager(dir)
+	key := "telegram:42"
+	sm.AddMessage(key, "user", "let's plan the trip")
+
+	if err := sm.Pin(key, "Osaka trip, budget ¥300k", 30); err != nil {
+		t.Fatal(err)
+	}
+	if err := sm.Pin(key, "Osaka trip, budget ¥300k", 30); err != nil {
+		t.Errorf("pinning a note twice: %v", err)
+	}
+	if err := sm.Pin(key, "leaving on May 3", 30); err != ErrPinsFull {
+		t.Errorf("pin over the cap: %v", err)
+	}
+	sm.Pin(key, "May 3", 30)
+
+	sm.SetSummary(key, "they talked about trains")
+	sm.TruncateHistory(key, 0)
+	sm.Save(key)
+
+	sm = NewSessionManager(dir)
+	if pins := sm.GetPins(key); len(pins) != 2 || pins[0] != "Osaka trip, budget ¥300k" || pins[1] != "May 3" {
+		t.Fatalf("pins after reload = %q", pins)
+	}
+	if info := sm.List(); len(info) != 1 || info[0].Pins != 2 {
+		t.Errorf("List = %+v", info)
+	}
+	if note, ok := sm.Unpin(key, 1); !ok || note != "Osaka trip, budget ¥300k" {
+		t.Errorf("Unpin = %q, %v", note, ok)
+	}
+	if _, ok := sm.Unpin(key, 2); ok {
+		t.Error("unpinned a note that is not there")
+	}
+	sm.Reset(key)
+	if len(sm.GetPins(key)) != 0 {
+		t.Error("Reset kept the pins")
+	}
+}
+
+func TestUnreadableSessionIsNotReplaced(t *testing.T) {
+	dir := t.TempDir()
+	sm := NewSessionManager(dir)
+	sm.AddMessage("cli:a", "user", "keep me")
+	sm.AddMessage("cli:a", "assistant", "kept")
+	if err := sm.Save("cli:a"); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := NewSessionManager(dir)
+	if info := reopened.List(); len(info) != 1 || info[0].MessageCount != 2 || info[0].Key != "cli:a" {
+		t.Fatalf("index from disk = %+v", info)
+	}
+
+	// The file turns unreadable after the index was built
+	path := filepath.Join(dir, "cli_a.json")
+	good, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, good[:len(good)/2], 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened.GetOrCreate("cli:a").Messages = nil
+	reopened.AddMessage("cli:a", "user", "new")
+	if err := reopened.Save("cli:a"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Once readable again, the history is intact
+	if err := os.WriteFile(path, good, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if h := reopened.GetHistory("cli:a"); len(h) != 2 || h[0].Content != "keep me" {
+		t.Errorf("history = %+v", h)
+	}
+}
@@ -0,0 +1,108 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrPinsFull is returned when a note would take the pinned notes of a
+// session over their size limit.
+var ErrPinsFull = errors.New("pinned notes are full")
+
+// GetPins returns the notes pinned to a session, oldest first.
+func (sm *SessionManager) GetPins(key string) []string {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, ok := sm.lookup(key)
+	if !ok {
+		return nil
+	}
+	return slices.Clone(session.Pins)
+}
+
+// Pin adds note to the pinned notes of a session, creating the session if
+// needed. Together the notes stay within maxChars characters. Pinning a
+// note that is already pinned does nothing.
+func (sm *SessionManager) Pin(key, note string, maxChars int) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, ok := sm.lookup(key)
+	if !ok {
+		var err error
+		if session, err = sm.create(key); err != nil {
+			return err
+		}
+	}
+	if slices.Contains(session.Pins, note) {
+		return nil
+	}
+	used := utf8.RuneCountInString(note)
+	for _, p := range session.Pins {
+		used += utf8.RuneCountInString(p)
+	}
+	if used > maxChars {
+		return ErrPinsFull
+	}
+	session.Pins = append(session.Pins, note)
+	session.Updated = time.Now()
+	return nil
+}
+
+// Unpin removes the n-th pinned note of a session, counting from 1, and
+// returns it.
+func (sm *SessionManager) Unpin(key string, n int) (string, bool) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, ok := sm.lookup(key)
+	if !ok || n < 1 || n > len(session.Pins) {
+		return "", false
+	}
+	note := session.Pins[n-1]
+	session.Pins = slices.Delete(session.Pins, n-1, n)
+	session.Updated = time.Now()
+	return note, true
+}
+
+// ClearPins removes all pinned notes of a session and returns how many
+// there were.
+func (sm *SessionManager) ClearPins(key string) int {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, ok := sm.lookup(key)
+	if !ok {
+		return 0
+	}
+	n := len(session.Pins)
+	session.Pins = nil
+	session.Updated = time.Now()
+	return n
+}
+
+type contextKey struct{}
+
+type turnSession struct {
+	sessions *SessionManager
+	key      string
+}
+
+// WithContext returns ctx carrying the session a turn belongs to, for the
+// tools it runs.
+func WithContext(ctx context.Context, sm *SessionManager, key string) context.Context {
+	return context.WithValue(ctx, contextKey{}, turnSession{sm, key})
+}
+
+// FromContext returns the session of the turn in ctx.
+func FromContext(ctx context.Context) (sm *SessionManager, key string, ok bool) {
+	ts, ok := ctx.Value(contextKey{}).(turnSession)
+	if !ok || ts.sessions == nil {
+		return nil, "", false
+	}
+	return ts.sessions, ts.key, true
+}
@@ -10,6 +10,8 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/sipeed/picoclaw/pkg/network"
 )
 
 type SkillInstaller struct {
@@ -45,7 +47,7 @@ func (si *SkillInstaller) InstallFromGitHub(ctx context.Context, repo string) er
 
 	url := fmt.Sprintf("https://raw.githubusercontent.com/%s/main/SKILL.md", repo)
 
-	client := &http.Client{Timeout: 15 * time.Second}
+	client := network.NewClient(network.ComponentSkills, network.Options{Timeout: 15 * time.Second})
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
@@ -95,7 +97,7 @@ func (si *SkillInstaller) Uninstall(skillName string) error {
 func (si *SkillInstaller) ListAvailableSkills(ctx context.Context) ([]AvailableSkill, error) {
 	url := "https://raw.githubusercontent.com/sipeed/picoclaw-skills/main/skills.json"
 
-	client := &http.Client{Timeout: 15 * time.Second}
+	client := network.NewClient(network.ComponentSkills, network.Options{Timeout: 15 * time.Second})
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
@@ -0,0 +1,295 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Entry is a value stored under a namespace and key.
+type Entry struct {
+	Value   json.RawMessage `json:"value"`
+	Updated time.Time       `json:"updated"`
+	Expires time.Time       `json:"expires,omitzero"` // zero: never
+}
+
+func (e *Entry) expired(now time.Time) bool {
+	return !e.Expires.IsZero() && !now.Before(e.Expires)
+}
+
+// Change is passed to watchers after a key was written or removed. Value is
+// nil for removals, including expired entries dropped on a later write.
+type Change struct {
+	Namespace string
+	Key       string
+	Value     json.RawMessage
+}
+
+type watcher struct {
+	namespace string
+	fn        func(Change)
+}
+
+// Set stores value, encoded as JSON, under namespace and key. With a
+// positive ttl the entry expires after that long; otherwise it is kept
+// until deleted.
+func (sm *Manager) Set(namespace, key string, value interface{}, ttl time.Duration) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s/%s: %w", namespace, key, err)
+	}
+	_, err = sm.write(namespace, key, func(json.RawMessage) (json.RawMessage, error) {
+		return raw, nil
+	}, ttl, true)
+	return err
+}
+
+// SetMany stores values under namespace by key, like Set, but saves the
+// state once for all of them.
+func (sm *Manager) SetMany(namespace string, values map[string]interface{}, ttl time.Duration) error {
+	if namespace == "" {
+		return fmt.Errorf("namespace and key are required")
+	}
+	raws := make(map[string]json.RawMessage, len(values))
+	for key, value := range values {
+		if key == "" {
+			return fmt.Errorf("namespace and key are required")
+		}
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s/%s: %w", namespace, key, err)
+		}
+		raws[key] = raw
+	}
+	if len(raws) == 0 {
+		return nil
+	}
+
+	sm.mu.Lock()
+	now := time.Now()
+	changes := sm.sweepLocked(now)
+	if sm.state.Namespaces == nil {
+		sm.state.Namespaces = make(map[string]map[string]*Entry)
+	}
+	entries := sm.state.Namespaces[namespace]
+	if entries == nil {
+		entries = make(map[string]*Entry)
+		sm.state.Namespaces[namespace] = entries
+	}
+	for key, raw := range raws {
+		e := &Entry{Value: raw, Updated: now}
+		if ttl > 0 {
+			e.Expires = now.Add(ttl)
+		}
+		entries[key] = e
+		changes = append(changes, Change{Namespace: namespace, Key: key, Value: raw})
+	}
+	err := sm.saveLocked(now)
+	sm.mu.Unlock()
+
+	sm.notify(changes)
+	return err
+}
+
+// Get decodes the value under namespace and key into dst. It reports false
+// when there is no such entry or it has expired.
+func (sm *Manager) Get(namespace, key string, dst interface{}) (bool, error) {
+	raw, ok := sm.Lookup(namespace, key)
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(raw, dst); err != nil {
+		return true, fmt.Errorf("failed to decode %s/%s: %w", namespace, key, err)
+	}
+	return true, nil
+}
+
+// Lookup returns the entry under namespace and key, unless it has expired.
+func (sm *Manager) Lookup(namespace, key string) (json.RawMessage, bool) {
+	e, ok := sm.Entry(namespace, key)
+	return e.Value, ok
+}
+
+// Entry returns a copy of the entry under namespace and key, unless it has
+// expired.
+func (sm *Manager) Entry(namespace, key string) (Entry, bool) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	e, ok := sm.state.Namespaces[namespace][key]
+	if !ok || e.expired(time.Now()) {
+		return Entry{}, false
+	}
+	return *e, true
+}
+
+// Update replaces the value under namespace and key with the result of fn,
+// atomically with respect to other writers. fn gets the current value, or
+// nil when there is none. A positive ttl resets the expiry; zero keeps the
+// current one. The new value is returned.
+func (sm *Manager) Update(namespace, key string, ttl time.Duration, fn func(cur json.RawMessage) (interface{}, error)) (json.RawMessage, error) {
+	return sm.write(namespace, key, func(cur json.RawMessage) (json.RawMessage, error) {
+		next, err := fn(cur)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(next)
+	}, ttl, false)
+}
+
+// Delete removes the entry under namespace and key. It reports whether
+// there was one.
+func (sm *Manager) Delete(namespace, key string) (bool, error) {
+	sm.mu.Lock()
+	now := time.Now()
+	e, ok := sm.state.Namespaces[namespace][key]
+	if !ok {
+		sm.mu.Unlock()
+		return false, nil
+	}
+	delete(sm.state.Namespaces[namespace], key)
+	changes := append(sm.sweepLocked(now), Change{Namespace: namespace, Key: key})
+	err := sm.saveLocked(now)
+	sm.mu.Unlock()
+
+	sm.notify(changes)
+	return !e.expired(now), err
+}
+
+// Keys returns the live keys of namespace, sorted.
+func (sm *Manager) Keys(namespace string) []string {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	now := time.Now()
+	var keys []string
+	for k, e := range sm.state.Namespaces[namespace] {
+		if !e.expired(now) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Namespaces returns the names of the namespaces that hold entries, sorted.
+func (sm *Manager) Namespaces() []string {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	var names []string
+	for ns, entries := range sm.state.Namespaces {
+		if len(entries) > 0 {
+			names = append(names, ns)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Watch calls fn after each change in namespace, or in every namespace
+// when it is empty. fn runs on the writer's goroutine, after the change is
+// saved. The returned function stops the notifications.
+func (sm *Manager) Watch(namespace string, fn func(Change)) (cancel func()) {
+	sm.watchMu.Lock()
+	defer sm.watchMu.Unlock()
+	if sm.watchers == nil {
+		sm.watchers = make(map[int]watcher)
+	}
+	id := sm.nextID
+	sm.nextID++
+	sm.watchers[id] = watcher{namespace: namespace, fn: fn}
+	return func() {
+		sm.watchMu.Lock()
+		defer sm.watchMu.Unlock()
+		delete(sm.watchers, id)
+	}
+}
+
+func (sm *Manager) write(namespace, key string, fn func(cur json.RawMessage) (json.RawMessage, error), ttl time.Duration, resetExpiry bool) (json.RawMessage, error) {
+	if namespace == "" || key == "" {
+		return nil, fmt.Errorf("namespace and key are required")
+	}
+
+	sm.mu.Lock()
+	now := time.Now()
+	changes := sm.sweepLocked(now)
+	var cur json.RawMessage
+	e, ok := sm.state.Namespaces[namespace][key]
+	if ok {
+		cur = e.Value
+	}
+	next, err := fn(cur)
+	if err != nil {
+		sm.mu.Unlock()
+		sm.notify(changes)
+		return nil, err
+	}
+
+	if !ok {
+		e = &Entry{}
+		if sm.state.Namespaces == nil {
+			sm.state.Namespaces = make(map[string]map[string]*Entry)
+		}
+		if sm.state.Namespaces[namespace] == nil {
+			sm.state.Namespaces[namespace] = make(map[string]*Entry)
+		}
+		sm.state.Namespaces[namespace][key] = e
+	}
+	e.Value = next
+	e.Updated = now
+	if ttl > 0 {
+		e.Expires = now.Add(ttl)
+	} else if resetExpiry {
+		e.Expires = time.Time{}
+	}
+	changes = append(changes, Change{Namespace: namespace, Key: key, Value: next})
+	err = sm.saveLocked(now)
+	sm.mu.Unlock()
+
+	sm.notify(changes)
+	return next, err
+}
+
+// sweepLocked drops expired entries and returns them as removals.
+func (sm *Manager) sweepLocked(now time.Time) []Change {
+	var changes []Change
+	for ns, entries := range sm.state.Namespaces {
+		for k, e := range entries {
+			if e.expired(now) {
+				delete(entries, k)
+				changes = append(changes, Change{Namespace: ns, Key: k})
+			}
+		}
+		if len(entries) == 0 {
+			delete(sm.state.Namespaces, ns)
+		}
+	}
+	return changes
+}
+
+func (sm *Manager) saveLocked(now time.Time) error {
+	sm.state.Timestamp = now
+	if err := sm.saveAtomic(); err != nil {
+		return fmt.Errorf("failed to save state atomically: %w", err)
+	}
+	return nil
+}
+
+func (sm *Manager) notify(changes []Change) {
+	if len(changes) == 0 {
+		return
+	}
+	sm.watchMu.Lock()
+	var fns []watcher
+	for _, w := range sm.watchers {
+		fns = append(fns, w)
+	}
+	sm.watchMu.Unlock()
+
+	for _, c := range changes {
+		for _, w := range fns {
+			if w.namespace == "" || w.namespace == c.Namespace {
+				w.fn(c)
+			}
+		}
+	}
+}
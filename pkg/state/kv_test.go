@@ -0,0 +1,105 @@
+package state
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestKVPersistsAcrossRestarts(t *testing.T) {
+	dir := t.TempDir()
+	sm := NewManager(dir)
+	if NewManager(dir) != sm {
+		t.Fatal("managers of one workspace should be shared")
+	}
+	sm.SetLastChannel("telegram:42")
+	if err := sm.Set("heartbeat", "last_run", map[string]string{"status": "ok"}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := sm.Set("cache", "token", "abc", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := openManager(dir)
+	var last map[string]string
+	if ok, err := reopened.Get("heartbeat", "last_run", &last); !ok || err != nil || last["status"] != "ok" {
+		t.Errorf("heartbeat/last_run = %v, %v, %v", last, ok, err)
+	}
+	if e, ok := reopened.Entry("cache", "token"); !ok || e.Expires.IsZero() {
+		t.Errorf("cache/token = %+v, %v; want an expiry", e, ok)
+	}
+	if reopened.GetLastChannel() != "telegram:42" {
+		t.Errorf("last channel = %q", reopened.GetLastChannel())
+	}
+}
+
+func TestKVExpiryAndWatch(t *testing.T) {
+	sm := openManager(t.TempDir())
+
+	var changes []Change
+	stop := sm.Watch("dedup", func(c Change) { changes = append(changes, c) })
+	sm.Watch("other", func(c Change) { t.Errorf("unexpected change %+v", c) })
+
+	sm.Set("dedup", "msg-1", true, 10*time.Millisecond)
+	if _, ok := sm.Lookup("dedup", "msg-1"); !ok {
+		t.Fatal("entry missing before expiry")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := sm.Lookup("dedup", "msg-1"); ok {
+		t.Error("expired entry still visible")
+	}
+
+	// The next write sweeps the expired entry
+	sm.Set("dedup", "msg-2", true, 0)
+	if len(changes) != 3 || changes[1].Key != "msg-1" || changes[1].Value != nil || changes[2].Key != "msg-2" {
+		t.Errorf("changes = %+v", changes)
+	}
+	if keys := sm.Keys("dedup"); len(keys) != 1 || keys[0] != "msg-2" {
+		t.Errorf("keys = %v", keys)
+	}
+
+	stop()
+	sm.Delete("dedup", "msg-2")
+	if len(changes) != 3 {
+		t.Error("watcher called after stop")
+	}
+}
+
+func TestKVUpdate(t *testing.T) {
+	sm := openManager(t.TempDir())
+	incr := func(cur json.RawMessage) (interface{}, error) {
+		var n int
+		json.Unmarshal(cur, &n)
+		return n + 1, nil
+	}
+	sm.Update("counters", "visits", time.Hour, incr)
+	next, err := sm.Update("counters", "visits", 0, incr)
+	if err != nil || string(next) != "2" {
+		t.Fatalf("visits = %s, %v", next, err)
+	}
+	// A zero ttl keeps the expiry set before
+	if e, _ := sm.Entry("counters", "visits"); e.Expires.IsZero() {
+		t.Error("expiry was cleared")
+	}
+}
+
+func TestKVSetMany(t *testing.T) {
+	dir := t.TempDir()
+	sm := openManager(dir)
+	var changes []Change
+	sm.Watch("dedup", func(c Change) { changes = append(changes, c) })
+
+	if err := sm.SetMany("dedup", map[string]interface{}{"1": true, "2": true}, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 {
+		t.Errorf("changes = %+v", changes)
+	}
+	reopened := openManager(dir)
+	if keys := reopened.Keys("dedup"); len(keys) != 2 {
+		t.Errorf("keys after reopen = %v", keys)
+	}
+	if e, ok := reopened.Entry("dedup", "1"); !ok || e.Expires.IsZero() {
+		t.Errorf("dedup/1 = %+v, %v; want an expiry", e, ok)
+	}
+}
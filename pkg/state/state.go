@@ -21,6 +21,9 @@ type State struct {
 
 	// Timestamp is the last time this state was updated
 	Timestamp time.Time `json:"timestamp"`
+
+	// Namespaces hold the key-value state of other components, see Set.
+	Namespaces map[string]map[string]*Entry `json:"namespaces,omitempty"`
 }
 
 // Manager manages persistent state with atomic saves.
@@ -29,10 +32,33 @@ type Manager struct {
 	state     *State
 	mu        sync.RWMutex
 	stateFile string
+
+	watchMu  sync.Mutex
+	watchers map[int]watcher
+	nextID   int
 }
 
-// NewManager creates a new state manager for the given workspace.
+var (
+	managersMu sync.Mutex
+	managers   = map[string]*Manager{}
+)
+
+// NewManager returns the state manager for the given workspace. Components
+// of one process share it, so their writes don't overwrite each other.
 func NewManager(workspace string) *Manager {
+	managersMu.Lock()
+	defer managersMu.Unlock()
+	key := filepath.Clean(workspace)
+	if sm, ok := managers[key]; ok {
+		return sm
+	}
+	sm := openManager(workspace)
+	managers[key] = sm
+	return sm
+}
+
+// openManager loads the state of workspace into a new manager.
+func openManager(workspace string) *Manager {
 	stateDir := filepath.Join(workspace, "state")
 	stateFile := filepath.Join(stateDir, "state.json")
 	oldStateFile := filepath.Join(workspace, "state.json")
@@ -0,0 +1,462 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// Inspired by and based on nanobot: https://github.com/HKUDS/nanobot
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+// Package tenant isolates the users of a public bot from each other. Each
+// tenant, a sender or a chat, gets a workspace of its own under
+// workspace/tenants/<id>/workspace, provisioned from a template, and is held
+// to quotas on disk space, cron jobs and tokens per day. The tenant of a
+// turn travels in its context so tools can scope themselves to it.
+package tenant
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+const (
+	infoFile     = "tenant.json"
+	workspaceDir = "workspace"
+	dayLayout    = "2006-01-02"
+)
+
+// ErrQuota is returned, wrapped, when a tenant is over one of its quotas.
+var ErrQuota = errors.New("quota exceeded")
+
+// ErrNotFound is returned for a tenant that does not exist.
+var ErrNotFound = errors.New("tenant not found")
+
+// defaultTemplate is copied from the main workspace when no template is
+// configured. Memory, user notes and skills stay private to the owner.
+var defaultTemplate = []string{"AGENTS.md", "IDENTITY.md", "SOUL.md", "TOOLS.md"}
+
+// Info is what is kept about a tenant in its tenant.json.
+type Info struct {
+	ID          string    `json:"id"`
+	Channel     string    `json:"channel"`
+	Owner       string    `json:"owner"` // sender or chat ID the tenant belongs to
+	Created     time.Time `json:"created"`
+	LastSeen    time.Time `json:"last_seen"`
+	TokensDay   string    `json:"tokens_day,omitempty"` // day TokensToday counts, in local time
+	TokensToday int64     `json:"tokens_today"`
+	TokensTotal int64     `json:"tokens_total"`
+}
+
+// Tenant is a provisioned tenant. It is safe for concurrent use.
+type Tenant struct {
+	dir string
+	m   *Manager
+
+	mu   sync.Mutex
+	info Info
+}
+
+// Manager provisions tenants and enforces their quotas.
+type Manager struct {
+	root      string
+	workspace string // the main workspace, source of the default template
+	template  string
+	cfg       config.TenantsConfig
+	admins    map[string]bool
+	nowFunc   func() time.Time
+
+	mu      sync.Mutex
+	tenants map[string]*Tenant
+	onPurge []func(id string)
+}
+
+// NewManager manages the tenants of the main workspace, under
+// workspace/tenants.
+func NewManager(workspace string, cfg config.TenantsConfig) *Manager {
+	m := &Manager{
+		root:      filepath.Join(workspace, "tenants"),
+		workspace: workspace,
+		template:  cfg.TemplatePath(),
+		cfg:       cfg,
+		admins:    make(map[string]bool),
+		nowFunc:   time.Now,
+		tenants:   make(map[string]*Tenant),
+	}
+	for _, a := range cfg.Admins {
+		m.admins[a] = true
+	}
+	return m
+}
+
+// Root returns the directory tenants are kept in.
+func (m *Manager) Root() string {
+	return m.root
+}
+
+// IsAdmin reports whether a sender is a tenant admin. Admins use the main
+// workspace.
+func (m *Manager) IsAdmin(channel, senderID string) bool {
+	return m.admins[channel+":"+senderID]
+}
+
+// OnPurge registers fn to clean up what other components keep for a
+// tenant when it is purged.
+func (m *Manager) OnPurge(fn func(id string)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onPurge = append(m.onPurge, fn)
+}
+
+// Owner returns the channel and owner ID the tenant of a message belongs
+// to: the sender, or the chat in chat scope.
+func (m *Manager) Owner(channel, chatID, senderID string) (string, string) {
+	if m.cfg.Scope == "chat" {
+		return channel, chatID
+	}
+	return channel, senderID
+}
+
+// ID returns the ID of the tenant an owner maps to. IDs are safe as
+// directory names; owners whose IDs are not get a hash suffix so no two
+// owners share a tenant.
+func ID(channel, owner string) string {
+	raw := channel + "-" + owner
+	safe := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.' {
+			return r
+		}
+		return '_'
+	}, raw)
+	if safe == raw && !strings.HasPrefix(safe, ".") {
+		return safe
+	}
+	sum := sha256.Sum256([]byte(channel + ":" + owner))
+	return strings.TrimLeft(safe, ".") + "-" + hex.EncodeToString(sum[:4])
+}
+
+// Resolve returns the tenant of a message, provisioning its workspace on
+// first contact.
+func (m *Manager) Resolve(channel, chatID, senderID string) (*Tenant, error) {
+	channel, owner := m.Owner(channel, chatID, senderID)
+	if owner == "" {
+		return nil, fmt.Errorf("no %s to scope the tenant to", m.cfg.Scope)
+	}
+	id := ID(channel, owner)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.tenants[id]
+	if !ok {
+		var err error
+		if t, err = m.loadLocked(id); errors.Is(err, ErrNotFound) {
+			t, err = m.provisionLocked(id, channel, owner)
+		}
+		if err != nil {
+			return nil, err
+		}
+		m.tenants[id] = t
+	}
+	t.touch()
+	return t, nil
+}
+
+// Get returns an existing tenant.
+func (m *Manager) Get(id string) (*Tenant, error) {
+	if id == "" || id != filepath.Base(id) || strings.HasPrefix(id, ".") {
+		return nil, ErrNotFound
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if t, ok := m.tenants[id]; ok {
+		return t, nil
+	}
+	t, err := m.loadLocked(id)
+	if err != nil {
+		return nil, err
+	}
+	m.tenants[id] = t
+	return t, nil
+}
+
+// List returns the tenants on disk, most recently seen first.
+func (m *Manager) List() ([]Info, error) {
+	entries, err := os.ReadDir(m.root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var infos []Info
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		t, err := m.Get(e.Name())
+		if err != nil {
+			continue
+		}
+		infos = append(infos, t.Info())
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].LastSeen.After(infos[j].LastSeen) })
+	return infos, nil
+}
+
+// Purge deletes a tenant and everything in its workspace. The next message
+// from its owner provisions a fresh one.
+func (m *Manager) Purge(id string) error {
+	t, err := m.Get(id)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	delete(m.tenants, id)
+	hooks := append([]func(string){}, m.onPurge...)
+	m.mu.Unlock()
+
+	if err := os.RemoveAll(t.dir); err != nil {
+		return fmt.Errorf("failed to remove tenant %s: %w", id, err)
+	}
+	for _, fn := range hooks {
+		fn(id)
+	}
+	logger.InfoCF("tenant", "Tenant purged", map[string]interface{}{"id": id})
+	return nil
+}
+
+func (m *Manager) loadLocked(id string) (*Tenant, error) {
+	dir := filepath.Join(m.root, id)
+	data, err := os.ReadFile(filepath.Join(dir, infoFile))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	t := &Tenant{dir: dir, m: m}
+	if err := json.Unmarshal(data, &t.info); err != nil {
+		return nil, fmt.Errorf("tenant %s: %w", id, err)
+	}
+	return t, nil
+}
+
+func (m *Manager) provisionLocked(id, channel, owner string) (*Tenant, error) {
+	dir := filepath.Join(m.root, id)
+	ws := filepath.Join(dir, workspaceDir)
+	if err := os.MkdirAll(ws, 0755); err != nil {
+		return nil, err
+	}
+	if err := m.copyTemplate(ws); err != nil {
+		return nil, fmt.Errorf("failed to provision tenant %s: %w", id, err)
+	}
+	now := m.nowFunc()
+	t := &Tenant{dir: dir, m: m, info: Info{ID: id, Channel: channel, Owner: owner, Created: now, LastSeen: now}}
+	if err := t.saveLocked(); err != nil {
+		return nil, err
+	}
+	logger.InfoCF("tenant", "Tenant provisioned", map[string]interface{}{"id": id, "channel": channel})
+	return t, nil
+}
+
+func (m *Manager) copyTemplate(dst string) error {
+	if m.template == "" {
+		for _, name := range defaultTemplate {
+			err := copyFile(filepath.Join(m.workspace, name), filepath.Join(dst, name))
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		return nil
+	}
+	return filepath.WalkDir(m.template, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(m.template, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !d.Type().IsRegular() {
+			return nil // no symlinks out of the tenant's workspace
+		}
+		return copyFile(path, target)
+	})
+}
+
+// ID returns the tenant's ID.
+func (t *Tenant) ID() string {
+	return t.info.ID
+}
+
+// Owns reports whether a message from senderID in a chat belongs to the
+// tenant.
+func (t *Tenant) Owns(channel, chatID, senderID string) bool {
+	channel, owner := t.m.Owner(channel, chatID, senderID)
+	return ID(channel, owner) == t.info.ID
+}
+
+// Workspace returns the directory the tenant's files, memory, skills and
+// sessions live in.
+func (t *Tenant) Workspace() string {
+	return filepath.Join(t.dir, workspaceDir)
+}
+
+// Info returns what is kept about the tenant.
+func (t *Tenant) Info() Info {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	info := t.info
+	if info.TokensDay != t.today() {
+		info.TokensToday = 0
+	}
+	return info
+}
+
+// DiskUsage returns the bytes used by the tenant's workspace.
+func (t *Tenant) DiskUsage() int64 {
+	var total int64
+	filepath.WalkDir(t.Workspace(), func(_ string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			if fi, err := d.Info(); err == nil {
+				total += fi.Size()
+			}
+		}
+		return nil
+	})
+	return total
+}
+
+// CheckDisk returns an error wrapping ErrQuota when writing extra more bytes
+// would take the tenant over its disk quota.
+func (t *Tenant) CheckDisk(extra int64) error {
+	limit := int64(t.m.cfg.MaxDiskMB) << 20
+	if used := t.DiskUsage(); used+extra > limit {
+		return fmt.Errorf("%w: the workspace would use %.1f MB of %d MB; delete files first",
+			ErrQuota, float64(used+extra)/(1<<20), t.m.cfg.MaxDiskMB)
+	}
+	return nil
+}
+
+// AllowExec reports whether the tenant may run shell commands.
+func (t *Tenant) AllowExec() bool {
+	return t.m.cfg.AllowExec
+}
+
+// MaxCronJobs returns how many cron jobs the tenant may have.
+func (t *Tenant) MaxCronJobs() int {
+	return t.m.cfg.MaxCronJobs
+}
+
+// CheckTokens returns an error wrapping ErrQuota once the tenant has used
+// its tokens for the day.
+func (t *Tenant) CheckTokens() error {
+	limit := int64(t.m.cfg.MaxTokensPerDay)
+	if limit == 0 {
+		return nil
+	}
+	if used := t.Info().TokensToday; used >= limit {
+		return fmt.Errorf("%w: %d of %d tokens used today", ErrQuota, used, limit)
+	}
+	return nil
+}
+
+// AddTokens counts tokens used by the tenant.
+func (t *Tenant) AddTokens(n int) {
+	if n <= 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if day := t.today(); t.info.TokensDay != day {
+		t.info.TokensDay, t.info.TokensToday = day, 0
+	}
+	t.info.TokensToday += int64(n)
+	t.info.TokensTotal += int64(n)
+	t.saveOrWarnLocked()
+}
+
+func (t *Tenant) touch() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.info.LastSeen = t.m.nowFunc()
+	t.saveOrWarnLocked()
+}
+
+func (t *Tenant) today() string {
+	return t.m.nowFunc().Format(dayLayout)
+}
+
+func (t *Tenant) saveOrWarnLocked() {
+	if err := t.saveLocked(); err != nil {
+		logger.WarnCF("tenant", "Failed to save tenant", map[string]interface{}{
+			"id":    t.info.ID,
+			"error": err.Error(),
+		})
+	}
+}
+
+func (t *Tenant) saveLocked() error {
+	data, err := json.MarshalIndent(t.info, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(t.dir, infoFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+type contextKey struct{}
+
+// WithContext returns ctx carrying the tenant of a turn.
+func WithContext(ctx context.Context, t *Tenant) context.Context {
+	if t == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, contextKey{}, t)
+}
+
+// FromContext returns the tenant of a turn, or nil outside tenant mode and
+// for admins.
+func FromContext(ctx context.Context) *Tenant {
+	t, _ := ctx.Value(contextKey{}).(*Tenant)
+	return t
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
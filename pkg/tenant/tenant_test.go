@@ -0,0 +1,142 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func testConfig() config.TenantsConfig {
+	return config.TenantsConfig{Enabled: true, Scope: "sender", MaxDiskMB: 1, MaxCronJobs: 2, MaxTokensPerDay: 100}
+}
+
+func TestResolveProvisionsFromWorkspace(t *testing.T) {
+	ws := t.TempDir()
+	os.WriteFile(filepath.Join(ws, "SOUL.md"), []byte("be kind"), 0644)
+	os.MkdirAll(filepath.Join(ws, "memory"), 0755)
+	os.WriteFile(filepath.Join(ws, "memory", "MEMORY.md"), []byte("owner's secrets"), 0644)
+
+	m := NewManager(ws, testConfig())
+	tn, err := m.Resolve("telegram", "group1", "42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tn.ID() != "telegram-42" {
+		t.Errorf("ID = %q", tn.ID())
+	}
+	if data, _ := os.ReadFile(filepath.Join(tn.Workspace(), "SOUL.md")); string(data) != "be kind" {
+		t.Errorf("SOUL.md = %q, want the template copy", data)
+	}
+	if _, err := os.Stat(filepath.Join(tn.Workspace(), "memory", "MEMORY.md")); !os.IsNotExist(err) {
+		t.Error("the owner's memory was copied into a tenant")
+	}
+
+	// The same sender in another chat is the same tenant
+	again, _ := m.Resolve("telegram", "group2", "42")
+	if again != tn {
+		t.Error("a sender got two tenants")
+	}
+	if other, _ := m.Resolve("telegram", "group1", "43"); other.ID() == tn.ID() {
+		t.Error("two senders share a tenant")
+	}
+	if !tn.Owns("telegram", "group2", "42") || tn.Owns("telegram", "group1", "43") {
+		t.Error("Owns does not follow the sender scope")
+	}
+
+	// A restart finds the tenant on disk
+	infos, err := NewManager(ws, testConfig()).List()
+	if err != nil || len(infos) != 2 {
+		t.Fatalf("List = %v, %v", infos, err)
+	}
+}
+
+func TestResolveChatScopeAndTemplateDir(t *testing.T) {
+	ws, tmpl := t.TempDir(), t.TempDir()
+	os.MkdirAll(filepath.Join(tmpl, "skills", "faq"), 0755)
+	os.WriteFile(filepath.Join(tmpl, "skills", "faq", "SKILL.md"), []byte("# FAQ"), 0644)
+	cfg := testConfig()
+	cfg.Scope, cfg.Template = "chat", tmpl
+
+	m := NewManager(ws, cfg)
+	a, _ := m.Resolve("discord", "chan/1", "u1")
+	b, _ := m.Resolve("discord", "chan/1", "u2")
+	if a != b {
+		t.Error("chat scope gave members of a chat different tenants")
+	}
+	if strings.ContainsAny(a.ID(), "/:") || filepath.Base(a.Workspace()) != workspaceDir {
+		t.Errorf("unsafe tenant ID %q", a.ID())
+	}
+	if _, err := os.Stat(filepath.Join(a.Workspace(), "skills", "faq", "SKILL.md")); err != nil {
+		t.Errorf("template not copied: %v", err)
+	}
+}
+
+func TestQuotas(t *testing.T) {
+	m := NewManager(t.TempDir(), testConfig())
+	day := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	m.nowFunc = func() time.Time { return day }
+	tn, _ := m.Resolve("slack", "C1", "U1")
+
+	tn.AddTokens(60)
+	if err := tn.CheckTokens(); err != nil {
+		t.Errorf("under quota: %v", err)
+	}
+	tn.AddTokens(60)
+	if err := tn.CheckTokens(); !errors.Is(err, ErrQuota) {
+		t.Errorf("over quota: %v", err)
+	}
+	day = day.Add(24 * time.Hour)
+	if err := tn.CheckTokens(); err != nil {
+		t.Errorf("quota not reset the next day: %v", err)
+	}
+	if info := tn.Info(); info.TokensTotal != 120 {
+		t.Errorf("total tokens = %d", info.TokensTotal)
+	}
+
+	if err := tn.CheckDisk(512 << 10); err != nil {
+		t.Errorf("half a MB refused: %v", err)
+	}
+	os.WriteFile(filepath.Join(tn.Workspace(), "big.bin"), make([]byte, 900<<10), 0644)
+	if err := tn.CheckDisk(200 << 10); !errors.Is(err, ErrQuota) {
+		t.Errorf("write over the disk quota allowed: %v", err)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	m := NewManager(t.TempDir(), testConfig())
+	tn, _ := m.Resolve("telegram", "1", "1")
+	var purged []string
+	m.OnPurge(func(id string) { purged = append(purged, id) })
+
+	if err := m.Purge("../etc"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Purge outside the tenants dir: %v", err)
+	}
+	if err := m.Purge(tn.ID()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(tn.Workspace()); !os.IsNotExist(err) {
+		t.Error("workspace still there")
+	}
+	if len(purged) != 1 || purged[0] != tn.ID() {
+		t.Errorf("purge hooks saw %v", purged)
+	}
+	if fresh, _ := m.Resolve("telegram", "1", "1"); fresh == tn {
+		t.Error("purged tenant reused")
+	}
+}
+
+func TestContext(t *testing.T) {
+	if FromContext(context.Background()) != nil {
+		t.Error("tenant in an empty context")
+	}
+	tn, _ := NewManager(t.TempDir(), testConfig()).Resolve("cli", "x", "y")
+	if FromContext(WithContext(context.Background(), tn)) != tn {
+		t.Error("tenant lost in context")
+	}
+}
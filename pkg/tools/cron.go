@@ -3,11 +3,15 @@ package tools
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/cron"
+	"github.com/sipeed/picoclaw/pkg/i18n"
+	"github.com/sipeed/picoclaw/pkg/locale"
+	"github.com/sipeed/picoclaw/pkg/tenant"
 	"github.com/sipeed/picoclaw/pkg/utils"
 )
 
@@ -22,6 +26,7 @@ type CronTool struct {
 	executor    JobExecutor
 	msgBus      *bus.MessageBus
 	execTool    *ExecTool
+	tenants     *tenant.Manager
 	channel     string
 	chatID      string
 	mu          sync.RWMutex
@@ -44,7 +49,7 @@ func (t *CronTool) Name() string {
 
 // Description returns the tool description
 func (t *CronTool) Description() string {
-	return "Schedule reminders, tasks, or system commands. IMPORTANT: When user asks to be reminded or scheduled, you MUST call this tool. Use 'at_seconds' for one-time reminders (e.g., 'remind me in 10 minutes' → at_seconds=600). Use 'every_seconds' ONLY for recurring tasks (e.g., 'every 2 hours' → every_seconds=7200). Use 'cron_expr' for complex recurring schedules. Use 'command' to execute shell commands directly."
+	return "Schedule reminders, tasks, or system commands. IMPORTANT: When user asks to be reminded or scheduled, you MUST call this tool. Prefer 'when' with the user's own phrasing of the schedule (e.g., 'in 10 minutes', 'every weekday at 9', '每天早上8点'); it is resolved in the user's time zone and the result echoes how it was understood. If 'when' can't parse the phrase, use 'at_seconds' for one-time reminders, 'every_seconds' for fixed intervals or 'cron_expr' for other recurring schedules. Use 'command' to execute shell commands directly. For reminders that must not be missed (medication, critical chores), set 'repeat_until_ack' so the reminder repeats until the user acknowledges it, optionally escalating to another contact; use action 'ack' when the user says in their own words that it is done."
 }
 
 // Parameters returns the tool parameters schema
@@ -54,7 +59,7 @@ func (t *CronTool) Parameters() map[string]interface{} {
 		"properties": map[string]interface{}{
 			"action": map[string]interface{}{
 				"type":        "string",
-				"enum":        []string{"add", "list", "remove", "enable", "disable"},
+				"enum":        []string{"add", "list", "remove", "enable", "disable", "ack"},
 				"description": "Action to perform. Use 'add' when user wants to schedule a reminder or task.",
 			},
 			"message": map[string]interface{}{
@@ -65,6 +70,10 @@ func (t *CronTool) Parameters() map[string]interface{} {
 				"type":        "string",
 				"description": "Optional: Shell command to execute directly (e.g., 'df -h'). If set, the agent will run this command and report output instead of just showing the message. 'deliver' will be forced to false for commands.",
 			},
+			"when": map[string]interface{}{
+				"type":        "string",
+				"description": "Schedule in plain English or Chinese, e.g. 'every 2 hours', 'next Friday 18:00', 'last day of the month', '每天早上8点'. Takes precedence over at_seconds, every_seconds and cron_expr.",
+			},
 			"at_seconds": map[string]interface{}{
 				"type":        "integer",
 				"description": "One-time reminder: seconds from now when to trigger (e.g., 600 for 10 minutes later). Use this for one-time reminders like 'remind me in 10 minutes'.",
@@ -77,9 +86,33 @@ func (t *CronTool) Parameters() map[string]interface{} {
 				"type":        "string",
 				"description": "Cron expression for complex recurring schedules (e.g., '0 9 * * *' for daily at 9am). Use this for complex recurring schedules.",
 			},
+			"repeat_until_ack": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Repeat a delivered reminder until the user acknowledges it by replying 'done', pressing the button or reacting 👍.",
+			},
+			"repeat_minutes": map[string]interface{}{
+				"type":        "integer",
+				"description": "Minutes between repeats of an unacknowledged reminder (default 10).",
+			},
+			"max_attempts": map[string]interface{}{
+				"type":        "integer",
+				"description": "Stop repeating after this many deliveries (default: until acknowledged).",
+			},
+			"escalate_after": map[string]interface{}{
+				"type":        "integer",
+				"description": "After this many unacknowledged deliveries, also notify escalate_to.",
+			},
+			"escalate_to": map[string]interface{}{
+				"type":        "string",
+				"description": "Chat ID of a second contact to notify when the reminder goes unacknowledged.",
+			},
+			"escalate_channel": map[string]interface{}{
+				"type":        "string",
+				"description": "Channel of escalate_to (default: the current channel).",
+			},
 			"job_id": map[string]interface{}{
 				"type":        "string",
-				"description": "Job ID (for remove/enable/disable)",
+				"description": "Job ID (for remove/enable/disable, and ack when several reminders are pending)",
 			},
 			"deliver": map[string]interface{}{
 				"type":        "boolean",
@@ -98,6 +131,49 @@ func (t *CronTool) SetContext(channel, chatID string) {
 	t.chatID = chatID
 }
 
+// SetTenants scopes jobs to the tenant of the turn that creates them. Jobs
+// of a purged tenant are removed with it.
+func (t *CronTool) SetTenants(m *tenant.Manager) {
+	t.tenants = m
+	m.OnPurge(func(id string) {
+		for _, job := range t.cronService.ListJobs(true) {
+			if job.Payload.Tenant == id {
+				t.cronService.RemoveJob(job.ID)
+			}
+		}
+	})
+}
+
+// tenantJobs returns the jobs of the turn's tenant, or those created
+// outside tenants.
+func (t *CronTool) tenantJobs(ctx context.Context, includeDisabled bool) []cron.CronJob {
+	id := tenantID(ctx)
+	var jobs []cron.CronJob
+	for _, job := range t.cronService.ListJobs(includeDisabled) {
+		if job.Payload.Tenant == id {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs
+}
+
+// ownsJob reports whether the turn may change a job.
+func (t *CronTool) ownsJob(ctx context.Context, jobID string) bool {
+	for _, job := range t.tenantJobs(ctx, true) {
+		if job.ID == jobID {
+			return true
+		}
+	}
+	return false
+}
+
+func tenantID(ctx context.Context) string {
+	if tn := tenant.FromContext(ctx); tn != nil {
+		return tn.ID()
+	}
+	return ""
+}
+
 // Execute runs the tool with the given arguments
 func (t *CronTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
 	action, ok := args["action"].(string)
@@ -107,21 +183,23 @@ func (t *CronTool) Execute(ctx context.Context, args map[string]interface{}) *To
 
 	switch action {
 	case "add":
-		return t.addJob(args)
+		return t.addJob(ctx, args)
 	case "list":
-		return t.listJobs()
+		return t.listJobs(ctx)
 	case "remove":
-		return t.removeJob(args)
+		return t.removeJob(ctx, args)
 	case "enable":
-		return t.enableJob(args, true)
+		return t.enableJob(ctx, args, true)
 	case "disable":
-		return t.enableJob(args, false)
+		return t.enableJob(ctx, args, false)
+	case "ack":
+		return t.ackJob(args)
 	default:
 		return ErrorResult(fmt.Sprintf("unknown action: %s", action))
 	}
 }
 
-func (t *CronTool) addJob(args map[string]interface{}) *ToolResult {
+func (t *CronTool) addJob(ctx context.Context, args map[string]interface{}) *ToolResult {
 	t.mu.RLock()
 	channel := t.channel
 	chatID := t.chatID
@@ -137,14 +215,22 @@ func (t *CronTool) addJob(args map[string]interface{}) *ToolResult {
 	}
 
 	var schedule cron.CronSchedule
+	var understood string
 
-	// Check for at_seconds (one-time), every_seconds (recurring), or cron_expr
+	// Check for when, at_seconds (one-time), every_seconds (recurring), or cron_expr
 	atSeconds, hasAt := args["at_seconds"].(float64)
 	everySeconds, hasEvery := args["every_seconds"].(float64)
 	cronExpr, hasCron := args["cron_expr"].(string)
 
-	// Priority: at_seconds > every_seconds > cron_expr
-	if hasAt {
+	// Priority: when > at_seconds > every_seconds > cron_expr
+	if when, ok := args["when"].(string); ok && when != "" {
+		var err error
+		now := time.Now().In(locale.SettingsFrom(ctx).Location())
+		schedule, understood, err = cron.ParseWhen(when, now)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("could not parse when %q: %v", when, err))
+		}
+	} else if hasAt {
 		atMS := time.Now().UnixMilli() + int64(atSeconds)*1000
 		schedule = cron.CronSchedule{
 			Kind: "at",
@@ -157,12 +243,14 @@ func (t *CronTool) addJob(args map[string]interface{}) *ToolResult {
 			EveryMS: &everyMS,
 		}
 	} else if hasCron {
+		// Cron expressions run on the user's clock
 		schedule = cron.CronSchedule{
 			Kind: "cron",
 			Expr: cronExpr,
+			TZ:   locale.SettingsFrom(ctx).TimeZone,
 		}
 	} else {
-		return ErrorResult("one of at_seconds, every_seconds, or cron_expr is required")
+		return ErrorResult("one of when, at_seconds, every_seconds, or cron_expr is required")
 	}
 
 	// Read deliver parameter, default to true
@@ -172,6 +260,25 @@ func (t *CronTool) addJob(args map[string]interface{}) *ToolResult {
 	}
 
 	command, _ := args["command"].(string)
+	ack, err := ackPolicy(args)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	if ack != nil && (command != "" || !deliver) {
+		return ErrorResult("repeat_until_ack needs a delivered reminder, not a command or agent task")
+	}
+	tn := tenant.FromContext(ctx)
+	if tn != nil {
+		if command != "" && !tn.AllowExec() {
+			return ErrorResult("command jobs are not available in this workspace")
+		}
+		if ack != nil && ack.EscalateTo != "" {
+			return ErrorResult("reminders can't be escalated to other chats from this workspace")
+		}
+		if n := len(t.tenantJobs(ctx, true)); n >= tn.MaxCronJobs() {
+			return ErrorResult(fmt.Sprintf("%v: %d of %d cron jobs in use; remove one first", tenant.ErrQuota, n, tn.MaxCronJobs()))
+		}
+	}
 	if command != "" {
 		// Commands must be processed by agent/exec tool, so deliver must be false (or handled specifically)
 		// Actually, let's keep deliver=false to let the system know it's not a simple chat message
@@ -195,17 +302,30 @@ func (t *CronTool) addJob(args map[string]interface{}) *ToolResult {
 		return ErrorResult(fmt.Sprintf("Error adding job: %v", err))
 	}
 
-	if command != "" {
+	if command != "" || ack != nil || tn != nil {
 		job.Payload.Command = command
+		job.Payload.Ack = ack
+		job.Payload.Tenant = tenantID(ctx)
+		// Reminders are kept after firing to record their acknowledgement
+		if ack != nil {
+			job.DeleteAfterRun = false
+		}
 		// Need to save the updated payload
 		t.cronService.UpdateJob(job)
 	}
 
-	return SilentResult(fmt.Sprintf("Cron job added: %s (id: %s)", job.Name, job.ID))
+	result := fmt.Sprintf("Cron job added: %s (id: %s)", job.Name, job.ID)
+	if understood != "" {
+		result += ", scheduled " + understood
+	}
+	if job.State.NextRunAtMS != nil {
+		result += fmt.Sprintf(", next run %s", locale.SettingsFrom(ctx).Format(time.UnixMilli(*job.State.NextRunAtMS)))
+	}
+	return SilentResult(result)
 }
 
-func (t *CronTool) listJobs() *ToolResult {
-	jobs := t.cronService.ListJobs(false)
+func (t *CronTool) listJobs(ctx context.Context) *ToolResult {
+	jobs := t.tenantJobs(ctx, false)
 
 	if len(jobs) == 0 {
 		return SilentResult("No scheduled jobs")
@@ -218,34 +338,48 @@ func (t *CronTool) listJobs() *ToolResult {
 			scheduleInfo = fmt.Sprintf("every %ds", *j.Schedule.EveryMS/1000)
 		} else if j.Schedule.Kind == "cron" {
 			scheduleInfo = j.Schedule.Expr
+			if j.Schedule.TZ != "" {
+				scheduleInfo += " " + j.Schedule.TZ
+			}
 		} else if j.Schedule.Kind == "at" {
 			scheduleInfo = "one-time"
 		} else {
 			scheduleInfo = "unknown"
 		}
+		if j.State.NextRunAtMS != nil {
+			scheduleInfo += ", next " + locale.SettingsFrom(ctx).Format(time.UnixMilli(*j.State.NextRunAtMS))
+		}
+		if r := j.State.Reminder; r != nil {
+			scheduleInfo += fmt.Sprintf(", awaiting acknowledgement after %d attempts", r.Attempts)
+		} else if j.State.LastAckAtMS != nil {
+			scheduleInfo += ", last acknowledged " + locale.SettingsFrom(ctx).Format(time.UnixMilli(*j.State.LastAckAtMS))
+		}
 		result += fmt.Sprintf("- %s (id: %s, %s)\n", j.Name, j.ID, scheduleInfo)
 	}
 
 	return SilentResult(result)
 }
 
-func (t *CronTool) removeJob(args map[string]interface{}) *ToolResult {
+func (t *CronTool) removeJob(ctx context.Context, args map[string]interface{}) *ToolResult {
 	jobID, ok := args["job_id"].(string)
 	if !ok || jobID == "" {
 		return ErrorResult("job_id is required for remove")
 	}
 
-	if t.cronService.RemoveJob(jobID) {
+	if t.ownsJob(ctx, jobID) && t.cronService.RemoveJob(jobID) {
 		return SilentResult(fmt.Sprintf("Cron job removed: %s", jobID))
 	}
 	return ErrorResult(fmt.Sprintf("Job %s not found", jobID))
 }
 
-func (t *CronTool) enableJob(args map[string]interface{}, enable bool) *ToolResult {
+func (t *CronTool) enableJob(ctx context.Context, args map[string]interface{}, enable bool) *ToolResult {
 	jobID, ok := args["job_id"].(string)
 	if !ok || jobID == "" {
 		return ErrorResult("job_id is required for enable/disable")
 	}
+	if !t.ownsJob(ctx, jobID) {
+		return ErrorResult(fmt.Sprintf("Job %s not found", jobID))
+	}
 
 	job := t.cronService.EnableJob(jobID, enable)
 	if job == nil {
@@ -273,6 +407,18 @@ func (t *CronTool) ExecuteJob(ctx context.Context, job *cron.CronJob) string {
 		chatID = "direct"
 	}
 
+	// Jobs of a tenant run in its workspace, and not at all without one
+	if job.Payload.Tenant != "" {
+		if t.tenants == nil {
+			return fmt.Sprintf("Error: job of tenant %s, but tenants are disabled", job.Payload.Tenant)
+		}
+		tn, err := t.tenants.Get(job.Payload.Tenant)
+		if err != nil {
+			return fmt.Sprintf("Error: tenant %s: %v", job.Payload.Tenant, err)
+		}
+		ctx = tenant.WithContext(ctx, tn)
+	}
+
 	// Execute command if present
 	if job.Payload.Command != "" {
 		args := map[string]interface{}{
@@ -280,11 +426,12 @@ func (t *CronTool) ExecuteJob(ctx context.Context, job *cron.CronJob) string {
 		}
 
 		result := t.execTool.Execute(ctx, args)
+		lang := i18n.ForChat(channel, chatID)
 		var output string
 		if result.IsError {
-			output = fmt.Sprintf("Error executing scheduled command: %s", result.ForLLM)
+			output = i18n.T(lang, i18n.CronCommandFailed, result.ForLLM)
 		} else {
-			output = fmt.Sprintf("Scheduled command '%s' executed:\n%s", job.Payload.Command, result.ForLLM)
+			output = i18n.T(lang, i18n.CronCommandDone, job.Payload.Command, result.ForLLM)
 		}
 
 		t.msgBus.PublishOutbound(bus.OutboundMessage{
@@ -296,6 +443,10 @@ func (t *CronTool) ExecuteJob(ctx context.Context, job *cron.CronJob) string {
 	}
 
 	// If deliver=true, send message directly without agent processing
+	if job.Payload.Deliver && job.State.Reminder != nil {
+		t.deliverReminder(job, channel, chatID)
+		return "ok"
+	}
 	if job.Payload.Deliver {
 		t.msgBus.PublishOutbound(bus.OutboundMessage{
 			Channel: channel,
@@ -325,3 +476,124 @@ func (t *CronTool) ExecuteJob(ctx context.Context, job *cron.CronJob) string {
 	_ = response // Will be sent by AgentLoop
 	return "ok"
 }
+
+// deliverReminder sends an attempt of a reminder that waits for
+// acknowledgement, and escalates it when the policy says so.
+func (t *CronTool) deliverReminder(job *cron.CronJob, channel, chatID string) {
+	r := job.State.Reminder
+	lang := i18n.ForChat(channel, chatID)
+	content := job.Payload.Message
+	if r.Attempts > 1 {
+		content = i18n.T(lang, i18n.CronReminderRepeat, r.Attempts, content)
+	}
+	t.msgBus.PublishOutbound(bus.OutboundMessage{
+		Channel: channel,
+		ChatID:  chatID,
+		Content: content + "\n\n" + i18n.T(lang, i18n.CronReminderAckHint),
+		Buttons: []bus.Button{{Text: i18n.T(lang, i18n.CronReminderButton), Data: cron.AckData(job.ID)}},
+	})
+
+	if !job.Payload.Ack.Escalates(r.Attempts) {
+		return
+	}
+	escChannel, escChatID, _ := job.EscalationTarget()
+	escLang := i18n.ForChat(escChannel, escChatID)
+	t.msgBus.PublishOutbound(bus.OutboundMessage{
+		Channel: escChannel,
+		ChatID:  escChatID,
+		Content: i18n.T(escLang, i18n.CronReminderEscalated, chatID, r.Attempts-1, job.Payload.Message) +
+			"\n\n" + i18n.T(escLang, i18n.CronReminderAckHint),
+		Buttons: []bus.Button{{Text: i18n.T(escLang, i18n.CronReminderButton), Data: cron.AckData(job.ID)}},
+	})
+}
+
+// HandleAck is an agent interceptor that acknowledges pending reminders
+// when the user replies "done", presses the reminder's button or reacts to
+// it. Other messages, and acknowledgements in chats with nothing pending,
+// go on to the agent.
+func (t *CronTool) HandleAck(msg bus.InboundMessage) (string, bool) {
+	jobID, ok := cron.MatchAck(msg.Content)
+	if !ok {
+		return "", false
+	}
+	// A stale button has nothing left to acknowledge but is no message either
+	button := strings.HasPrefix(strings.TrimSpace(msg.Content), "ack:")
+	if len(t.cronService.PendingReminders(msg.Channel, msg.ChatID)) == 0 {
+		return "", button
+	}
+
+	acked := t.cronService.Acknowledge(msg.Channel, msg.ChatID, jobID, msg.SenderID)
+	if len(acked) == 0 {
+		return "", button
+	}
+	return t.confirmAck(acked, msg.Channel, msg.ChatID), true
+}
+
+func (t *CronTool) ackJob(args map[string]interface{}) *ToolResult {
+	t.mu.RLock()
+	channel, chatID := t.channel, t.chatID
+	t.mu.RUnlock()
+
+	jobID, _ := args["job_id"].(string)
+	acked := t.cronService.Acknowledge(channel, chatID, jobID, "agent")
+	if len(acked) == 0 {
+		return ErrorResult("no reminder is waiting for acknowledgement in this chat")
+	}
+	t.confirmAck(acked, channel, chatID)
+	names := make([]string, len(acked))
+	for i, j := range acked {
+		names[i] = j.Name
+	}
+	return SilentResult("Acknowledged: " + strings.Join(names, ", "))
+}
+
+// confirmAck tells the other side of escalated reminders that they were
+// acknowledged from channel and chatID, and returns the confirmation for
+// the acknowledging chat.
+func (t *CronTool) confirmAck(acked []cron.CronJob, channel, chatID string) string {
+	names := make([]string, len(acked))
+	for i := range acked {
+		job := &acked[i]
+		names[i] = job.Name
+		if job.State.Reminder == nil || job.State.Reminder.EscalatedAtMS == 0 {
+			continue
+		}
+		otherChannel, otherChatID := job.Target()
+		if otherChannel == channel && otherChatID == chatID {
+			otherChannel, otherChatID, _ = job.EscalationTarget()
+		}
+		t.msgBus.PublishOutbound(bus.OutboundMessage{
+			Channel: otherChannel,
+			ChatID:  otherChatID,
+			Content: i18n.T(i18n.ForChat(otherChannel, otherChatID), i18n.CronReminderAcked, job.Name),
+		})
+	}
+	return i18n.T(i18n.ForChat(channel, chatID), i18n.CronReminderAcked, strings.Join(names, ", "))
+}
+
+// ackPolicy reads the repeat-until-acknowledged settings of an add.
+func ackPolicy(args map[string]interface{}) (*cron.AckPolicy, error) {
+	if repeat, _ := args["repeat_until_ack"].(bool); !repeat {
+		return nil, nil
+	}
+	minutes := 10.0
+	if m, ok := args["repeat_minutes"].(float64); ok {
+		if m < 1 {
+			return nil, fmt.Errorf("repeat_minutes must be at least 1")
+		}
+		minutes = m
+	}
+	policy := &cron.AckPolicy{RepeatEveryMS: int64(minutes * float64(time.Minute/time.Millisecond))}
+	if n, ok := args["max_attempts"].(float64); ok {
+		policy.MaxAttempts = int(n)
+	}
+	if n, ok := args["escalate_after"].(float64); ok {
+		policy.EscalateAfter = int(n)
+	}
+	policy.EscalateTo, _ = args["escalate_to"].(string)
+	policy.EscalateChannel, _ = args["escalate_channel"].(string)
+	if policy.EscalateAfter > 0 && policy.EscalateTo == "" {
+		return nil, fmt.Errorf("escalate_after needs escalate_to")
+	}
+	return policy, nil
+}
@@ -0,0 +1,115 @@
+package tools
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/cron"
+	"github.com/sipeed/picoclaw/pkg/tenant"
+)
+
+func TestCronToolReminderAck(t *testing.T) {
+	dir := t.TempDir()
+	cs := cron.NewCronService(filepath.Join(dir, "jobs.json"), nil)
+	mb := bus.NewMessageBus()
+	tool := NewCronTool(cs, nil, mb, dir)
+	tool.SetContext("telegram", "42")
+
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"action":           "add",
+		"message":          "Take your pills",
+		"at_seconds":       float64(3600),
+		"repeat_until_ack": true,
+		"escalate_after":   float64(1),
+		"escalate_to":      "99",
+	})
+	if result.IsError {
+		t.Fatalf("add failed: %s", result.ForLLM)
+	}
+	job := cs.ListJobs(true)[0]
+	if job.Payload.Ack == nil || job.DeleteAfterRun {
+		t.Fatalf("reminder job = %+v", job)
+	}
+
+	if _, handled := tool.HandleAck(bus.InboundMessage{Channel: "telegram", ChatID: "42", Content: "done"}); handled {
+		t.Fatal("acknowledged a reminder that has not fired")
+	}
+
+	// Second attempt, which escalates
+	job.State.Reminder = &cron.ReminderState{Attempts: 2, EscalatedAtMS: 1}
+	cs.UpdateJob(&job)
+	tool.ExecuteJob(context.Background(), &job)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	reminder, _ := mb.SubscribeOutbound(ctx)
+	if reminder.ChatID != "42" || !strings.Contains(reminder.Content, "Take your pills") || len(reminder.Buttons) != 1 {
+		t.Fatalf("reminder = %+v", reminder)
+	}
+	escalation, _ := mb.SubscribeOutbound(ctx)
+	if escalation.ChatID != "99" || !strings.Contains(escalation.Content, "Take your pills") {
+		t.Fatalf("escalation = %+v", escalation)
+	}
+
+	reply, handled := tool.HandleAck(bus.InboundMessage{Channel: "telegram", ChatID: "42", SenderID: "7", Content: reminder.Buttons[0].Data})
+	if !handled || reply == "" {
+		t.Fatalf("button press not handled: %q, %v", reply, handled)
+	}
+	if notice, _ := mb.SubscribeOutbound(ctx); notice.ChatID != "99" {
+		t.Errorf("escalation contact not told about the acknowledgement: %+v", notice)
+	}
+	if got := cs.ListJobs(true)[0].State; got.Reminder != nil || got.LastAckBy != "7" {
+		t.Errorf("state after acknowledgement = %+v", got)
+	}
+
+	// A second press of the same button is swallowed
+	if _, handled := tool.HandleAck(bus.InboundMessage{Channel: "telegram", ChatID: "42", Content: reminder.Buttons[0].Data}); !handled {
+		t.Error("stale button press reached the agent")
+	}
+}
+
+func TestCronToolTenantJobs(t *testing.T) {
+	dir := t.TempDir()
+	cs := cron.NewCronService(filepath.Join(dir, "jobs.json"), nil)
+	tool := NewCronTool(cs, nil, bus.NewMessageBus(), dir)
+	m := tenant.NewManager(dir, config.TenantsConfig{Enabled: true, Scope: "sender", MaxDiskMB: 1, MaxCronJobs: 1})
+	tool.SetTenants(m)
+	tool.SetContext("telegram", "42")
+
+	add := map[string]interface{}{"action": "add", "message": "water the plants", "at_seconds": float64(60)}
+	if result := tool.Execute(context.Background(), add); result.IsError {
+		t.Fatalf("owner add failed: %s", result.ForLLM)
+	}
+	ownerJob := cs.ListJobs(true)[0].ID
+
+	tn, _ := m.Resolve("telegram", "42", "7")
+	ctx := tenant.WithContext(context.Background(), tn)
+	if result := tool.Execute(ctx, map[string]interface{}{"action": "list"}); strings.Contains(result.ForLLM, "water") {
+		t.Errorf("tenant sees the owner's jobs: %s", result.ForLLM)
+	}
+	if result := tool.Execute(ctx, map[string]interface{}{"action": "remove", "job_id": ownerJob}); !result.IsError {
+		t.Error("tenant removed the owner's job")
+	}
+	if result := tool.Execute(ctx, add); result.IsError {
+		t.Fatalf("tenant add failed: %s", result.ForLLM)
+	}
+	if result := tool.Execute(ctx, add); !result.IsError || !strings.Contains(result.ForLLM, "quota") {
+		t.Errorf("second tenant job over the quota: %+v", result)
+	}
+	command := map[string]interface{}{"action": "add", "message": "x", "command": "ls", "at_seconds": float64(60)}
+	if result := tool.Execute(ctx, command); !result.IsError {
+		t.Error("tenant added a command job")
+	}
+
+	if err := m.Purge(tn.ID()); err != nil {
+		t.Fatal(err)
+	}
+	if jobs := cs.ListJobs(true); len(jobs) != 1 || jobs[0].ID != ownerJob {
+		t.Errorf("jobs after purge = %+v", jobs)
+	}
+}
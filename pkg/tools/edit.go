@@ -67,7 +67,8 @@ func (t *EditFileTool) Execute(ctx context.Context, args map[string]interface{})
 		return ErrorResult("new_text is required")
 	}
 
-	resolvedPath, err := validatePath(path, t.allowedDir, t.restrict)
+	allowedDir, restrict := workspaceFor(ctx, t.allowedDir, t.restrict)
+	resolvedPath, err := validatePath(path, allowedDir, restrict)
 	if err != nil {
 		return ErrorResult(err.Error())
 	}
@@ -93,6 +94,9 @@ func (t *EditFileTool) Execute(ctx context.Context, args map[string]interface{})
 	}
 
 	newContent := strings.Replace(contentStr, oldText, newText, 1)
+	if err := checkDiskQuota(ctx, resolvedPath, int64(len(newContent)-len(contentStr)), false); err != nil {
+		return ErrorResult(err.Error())
+	}
 
 	if err := os.WriteFile(resolvedPath, []byte(newContent), 0644); err != nil {
 		return ErrorResult(fmt.Sprintf("failed to write file: %v", err))
@@ -146,10 +150,14 @@ func (t *AppendFileTool) Execute(ctx context.Context, args map[string]interface{
 		return ErrorResult("content is required")
 	}
 
-	resolvedPath, err := validatePath(path, t.workspace, t.restrict)
+	workspace, restrict := workspaceFor(ctx, t.workspace, t.restrict)
+	resolvedPath, err := validatePath(path, workspace, restrict)
 	if err != nil {
 		return ErrorResult(err.Error())
 	}
+	if err := checkDiskQuota(ctx, resolvedPath, int64(len(content)), false); err != nil {
+		return ErrorResult(err.Error())
+	}
 
 	f, err := os.OpenFile(resolvedPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
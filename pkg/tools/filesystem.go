@@ -6,6 +6,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/tenant"
 )
 
 // validatePath ensures the given path is within the workspace if restrict is true.
@@ -36,6 +38,31 @@ func validatePath(path, workspace string, restrict bool) (string, error) {
 	return absPath, nil
 }
 
+// workspaceFor returns the workspace a turn's file operations apply to.
+// Tenants are always held to their own workspace.
+func workspaceFor(ctx context.Context, workspace string, restrict bool) (string, bool) {
+	if t := tenant.FromContext(ctx); t != nil {
+		return t.Workspace(), true
+	}
+	return workspace, restrict
+}
+
+// checkDiskQuota refuses a write that would take the turn's tenant over its
+// disk quota. grow is how much the file at path grows by, or its new size
+// when replaced is set.
+func checkDiskQuota(ctx context.Context, path string, grow int64, replaced bool) error {
+	t := tenant.FromContext(ctx)
+	if t == nil {
+		return nil
+	}
+	if replaced {
+		if fi, err := os.Stat(path); err == nil {
+			grow -= fi.Size()
+		}
+	}
+	return t.CheckDisk(grow)
+}
+
 type ReadFileTool struct {
 	workspace string
 	restrict  bool
@@ -72,16 +99,25 @@ func (t *ReadFileTool) Execute(ctx context.Context, args map[string]interface{})
 		return ErrorResult("path is required")
 	}
 
-	resolvedPath, err := validatePath(path, t.workspace, t.restrict)
+	workspace, restrict := workspaceFor(ctx, t.workspace, t.restrict)
+	resolvedPath, err := validatePath(path, workspace, restrict)
 	if err != nil {
 		return ErrorResult(err.Error())
 	}
 
-	content, err := os.ReadFile(resolvedPath)
+	f, err := os.Open(resolvedPath)
 	if err != nil {
 		return ErrorResult(fmt.Sprintf("failed to read file: %v", err))
 	}
+	defer f.Close()
 
+	content, truncated, err := readCapped(f, ReadLimit())
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to read file: %v", err))
+	}
+	if truncated {
+		return NewToolResult(string(content) + fmt.Sprintf("\n... (truncated: only the first %d bytes were read)", len(content)))
+	}
 	return NewToolResult(string(content))
 }
 
@@ -130,11 +166,16 @@ func (t *WriteFileTool) Execute(ctx context.Context, args map[string]interface{}
 		return ErrorResult("content is required")
 	}
 
-	resolvedPath, err := validatePath(path, t.workspace, t.restrict)
+	workspace, restrict := workspaceFor(ctx, t.workspace, t.restrict)
+	resolvedPath, err := validatePath(path, workspace, restrict)
 	if err != nil {
 		return ErrorResult(err.Error())
 	}
 
+	if err := checkDiskQuota(ctx, resolvedPath, int64(len(content)), true); err != nil {
+		return ErrorResult(err.Error())
+	}
+
 	dir := filepath.Dir(resolvedPath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return ErrorResult(fmt.Sprintf("failed to create directory: %v", err))
@@ -183,7 +224,8 @@ func (t *ListDirTool) Execute(ctx context.Context, args map[string]interface{})
 		path = "."
 	}
 
-	resolvedPath, err := validatePath(path, t.workspace, t.restrict)
+	workspace, restrict := workspaceFor(ctx, t.workspace, t.restrict)
+	resolvedPath, err := validatePath(path, workspace, restrict)
 	if err != nil {
 		return ErrorResult(err.Error())
 	}
@@ -6,6 +6,9 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/tenant"
 )
 
 // TestFilesystemTool_ReadFile_Success verifies successful file reading
@@ -39,6 +42,24 @@ func TestFilesystemTool_ReadFile_Success(t *testing.T) {
 	}
 }
 
+// TestFilesystemTool_ReadFile_Capped verifies large files are read only up to the read limit
+func TestFilesystemTool_ReadFile_Capped(t *testing.T) {
+	prev := ReadLimit()
+	SetReadLimit(1024)
+	t.Cleanup(func() { SetReadLimit(prev) })
+
+	testFile := filepath.Join(t.TempDir(), "big.txt")
+	os.WriteFile(testFile, []byte(strings.Repeat("z", 5000)), 0644)
+
+	result := (&ReadFileTool{}).Execute(context.Background(), map[string]interface{}{"path": testFile})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	if strings.Count(result.ForLLM, "z") != 1024 || !strings.Contains(result.ForLLM, "truncated") {
+		t.Errorf("Expected the first 1024 bytes and a note, got %d bytes", len(result.ForLLM))
+	}
+}
+
 // TestFilesystemTool_ReadFile_NotFound verifies error handling for missing file
 func TestFilesystemTool_ReadFile_NotFound(t *testing.T) {
 	tool := &ReadFileTool{}
@@ -247,3 +268,38 @@ func TestFilesystemTool_ListDir_DefaultPath(t *testing.T) {
 		t.Errorf("Expected success with default path '.', got IsError=true: %s", result.ForLLM)
 	}
 }
+
+// TestFilesystemTool_TenantScope verifies tenants are held to their own workspace and disk quota
+func TestFilesystemTool_TenantScope(t *testing.T) {
+	mainWS := t.TempDir()
+	os.WriteFile(filepath.Join(mainWS, "secret.txt"), []byte("owner only"), 0644)
+	m := tenant.NewManager(mainWS, config.TenantsConfig{Enabled: true, Scope: "sender", MaxDiskMB: 1, MaxCronJobs: 1})
+	tn, err := m.Resolve("telegram", "1", "42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := tenant.WithContext(context.Background(), tn)
+
+	write := NewWriteFileTool(mainWS, false)
+	if result := write.Execute(ctx, map[string]interface{}{"path": "notes.md", "content": "mine"}); result.IsError {
+		t.Fatalf("write failed: %s", result.ForLLM)
+	}
+	if _, err := os.Stat(filepath.Join(tn.Workspace(), "notes.md")); err != nil {
+		t.Errorf("file not written to the tenant's workspace: %v", err)
+	}
+
+	read := NewReadFileTool(mainWS, false)
+	for _, path := range []string{filepath.Join(mainWS, "secret.txt"), "../../../secret.txt"} {
+		if result := read.Execute(ctx, map[string]interface{}{"path": path}); !result.IsError {
+			t.Errorf("tenant read %s: %s", path, result.ForLLM)
+		}
+	}
+
+	big := strings.Repeat("x", 2<<20)
+	if result := write.Execute(ctx, map[string]interface{}{"path": "big.txt", "content": big}); !result.IsError || !strings.Contains(result.ForLLM, "quota") {
+		t.Errorf("write over the disk quota: %+v", result)
+	}
+	if result := NewExecTool(mainWS, false).Execute(ctx, map[string]interface{}{"command": "cat secret.txt"}); !result.IsError {
+		t.Errorf("tenant ran a command: %s", result.ForLLM)
+	}
+}
@@ -0,0 +1,266 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/state"
+	"github.com/sipeed/picoclaw/pkg/tenant"
+)
+
+const (
+	// kvPrefix keeps the agent's namespaces apart from those of other components.
+	kvPrefix = "kv:"
+	// kvTenantPrefix, followed by the tenant ID and ":", keeps each tenant's
+	// namespaces apart.
+	kvTenantPrefix   = "kv@"
+	kvDefaultNS      = "default"
+	kvMaxValueBytes  = 8 << 10
+	kvMaxKeysPerNS   = 1000
+	kvMaxNamespaces  = 100 // per prefix, so per tenant in multi-tenant mode
+	kvListValueBytes = 200
+)
+
+// KVTool gives the agent a persistent key-value scratchpad for structured
+// state such as counters and last-seen values.
+type KVTool struct {
+	state *state.Manager
+}
+
+func NewKVTool(sm *state.Manager) *KVTool {
+	return &KVTool{state: sm}
+}
+
+func (t *KVTool) Name() string {
+	return "kv"
+}
+
+func (t *KVTool) Description() string {
+	return "Persistent key-value store for small structured state that doesn't belong in memory notes: counters, last-seen values, flags, timestamps. Values are any JSON. Keys can expire after a ttl. Use namespaces to group related keys."
+}
+
+func (t *KVTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"get", "set", "delete", "list", "incr"},
+				"description": "get, set or delete a key; list the keys of a namespace (or the namespaces when none is given); incr adds to a numeric value",
+			},
+			"namespace": map[string]interface{}{
+				"type":        "string",
+				"description": "Group of keys, e.g. 'plants' (default: 'default')",
+			},
+			"key": map[string]interface{}{
+				"type":        "string",
+				"description": "Key within the namespace",
+			},
+			"value": map[string]interface{}{
+				"description": "JSON value to store (for set)",
+			},
+			"by": map[string]interface{}{
+				"type":        "number",
+				"description": "Amount to add (for incr, default 1)",
+			},
+			"ttl": map[string]interface{}{
+				"type":        "string",
+				"description": "Expire the key after this long, e.g. '90s', '2h' (for set and incr)",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *KVTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	action, _ := args["action"].(string)
+	ns, _ := args["namespace"].(string)
+	ns = strings.TrimSpace(ns)
+	key, _ := args["key"].(string)
+	prefix := kvPrefix
+	if tn := tenant.FromContext(ctx); tn != nil {
+		prefix = kvTenantPrefix + tn.ID() + ":"
+	}
+
+	if action == "list" {
+		return t.list(prefix, ns)
+	}
+	if ns == "" {
+		ns = kvDefaultNS
+	}
+	if key == "" && action != "" {
+		return ErrorResult("key is required")
+	}
+	ttl, err := parseTTL(args["ttl"])
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	switch action {
+	case "get":
+		e, ok := t.state.Entry(prefix+ns, key)
+		if !ok {
+			return SilentResult(fmt.Sprintf("%s/%s is not set", ns, key))
+		}
+		return SilentResult(fmt.Sprintf("%s/%s = %s%s", ns, key, e.Value, expiresNote(e)))
+	case "set":
+		value, ok := args["value"]
+		if !ok {
+			return ErrorResult("value is required for set")
+		}
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("value is not valid JSON: %v", err))
+		}
+		if len(raw) > kvMaxValueBytes {
+			return ErrorResult(fmt.Sprintf("value is %d bytes; the limit is %d", len(raw), kvMaxValueBytes))
+		}
+		if err := t.checkRoom(prefix, ns, key); err != nil {
+			return ErrorResult(err.Error())
+		}
+		if err := t.state.Set(prefix+ns, key, value, ttl); err != nil {
+			return ErrorResult(err.Error()).WithError(err)
+		}
+		return SilentResult(fmt.Sprintf("%s/%s set", ns, key))
+	case "delete":
+		existed, err := t.state.Delete(prefix+ns, key)
+		if err != nil {
+			return ErrorResult(err.Error()).WithError(err)
+		}
+		if !existed {
+			return SilentResult(fmt.Sprintf("%s/%s was not set", ns, key))
+		}
+		return SilentResult(fmt.Sprintf("%s/%s deleted", ns, key))
+	case "incr":
+		by := 1.0
+		if v, ok := args["by"].(float64); ok {
+			by = v
+		}
+		if err := t.checkRoom(prefix, ns, key); err != nil {
+			return ErrorResult(err.Error())
+		}
+		next, err := t.state.Update(prefix+ns, key, ttl, func(cur json.RawMessage) (interface{}, error) {
+			var n float64
+			if cur != nil {
+				if err := json.Unmarshal(cur, &n); err != nil {
+					return nil, fmt.Errorf("%s/%s holds %s, not a number", ns, key, cur)
+				}
+			}
+			return n + by, nil
+		})
+		if err != nil {
+			return ErrorResult(err.Error())
+		}
+		return SilentResult(fmt.Sprintf("%s/%s = %s", ns, key, next))
+	case "":
+		return ErrorResult("action is required")
+	default:
+		return ErrorResult(fmt.Sprintf("unknown action: %s", action))
+	}
+}
+
+// checkRoom reports an error when adding key would exceed the number of
+// keys in ns or the number of namespaces under prefix.
+func (t *KVTool) checkRoom(prefix, ns, key string) error {
+	if _, exists := t.state.Lookup(prefix+ns, key); exists {
+		return nil
+	}
+	keys := len(t.state.Keys(prefix + ns))
+	if keys >= kvMaxKeysPerNS {
+		return fmt.Errorf("namespace %s is full (%d keys); delete some first", ns, kvMaxKeysPerNS)
+	}
+	if keys > 0 {
+		return nil
+	}
+	namespaces := 0
+	for _, name := range t.state.Namespaces() {
+		if strings.HasPrefix(name, prefix) {
+			namespaces++
+		}
+	}
+	if namespaces >= kvMaxNamespaces {
+		return fmt.Errorf("there are already %d namespaces; delete the keys of one first", kvMaxNamespaces)
+	}
+	return nil
+}
+
+func (t *KVTool) list(prefix, ns string) *ToolResult {
+	if ns == "" {
+		var names []string
+		for _, name := range t.state.Namespaces() {
+			if strings.HasPrefix(name, prefix) {
+				names = append(names, strings.TrimPrefix(name, prefix))
+			}
+		}
+		if len(names) == 0 {
+			return SilentResult("No keys stored")
+		}
+		return SilentResult("Namespaces: " + strings.Join(names, ", "))
+	}
+
+	keys := t.state.Keys(prefix + ns)
+	if len(keys) == 0 {
+		return SilentResult(fmt.Sprintf("Namespace %s is empty", ns))
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d keys in %s:\n", len(keys), ns)
+	for _, k := range keys {
+		e, ok := t.state.Entry(prefix+ns, k)
+		if !ok {
+			continue
+		}
+		value := string(e.Value)
+		if len(value) > kvListValueBytes {
+			value = value[:kvListValueBytes] + "..."
+		}
+		fmt.Fprintf(&sb, "- %s = %s%s\n", k, value, expiresNote(e))
+	}
+	return SilentResult(sb.String())
+}
+
+func expiresNote(e state.Entry) string {
+	if e.Expires.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf(" (expires in %s)", time.Until(e.Expires).Round(time.Second))
+}
+
+// parseTTL accepts a duration string or a number of seconds.
+func parseTTL(v interface{}) (time.Duration, error) {
+	switch ttl := v.(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		return time.Duration(ttl * float64(time.Second)), nil
+	case string:
+		if ttl == "" {
+			return 0, nil
+		}
+		d, err := time.ParseDuration(ttl)
+		if err != nil || d < 0 {
+			return 0, fmt.Errorf("invalid ttl %q (use e.g. '90s' or '2h')", ttl)
+		}
+		return d, nil
+	default:
+		return 0, fmt.Errorf("invalid ttl %v", v)
+	}
+}
+
+// DeleteTenantKV deletes the namespaces the kv tool keeps for a tenant.
+func DeleteTenantKV(sm *state.Manager, id string) error {
+	prefix := kvTenantPrefix + id + ":"
+	for _, name := range sm.Namespaces() {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		for _, key := range sm.Keys(name) {
+			if _, err := sm.Delete(name, key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
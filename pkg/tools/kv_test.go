@@ -0,0 +1,67 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/state"
+)
+
+func TestKVTool(t *testing.T) {
+	tool := NewKVTool(state.NewManager(t.TempDir()))
+	ctx := context.Background()
+	run := func(args map[string]interface{}) *ToolResult {
+		t.Helper()
+		res := tool.Execute(ctx, args)
+		if res.IsError {
+			t.Fatalf("%v: %s", args, res.ForLLM)
+		}
+		return res
+	}
+
+	run(map[string]interface{}{"action": "set", "namespace": "plants", "key": "last_watered", "value": map[string]interface{}{"fern": "2026-10-01"}})
+	run(map[string]interface{}{"action": "incr", "namespace": "plants", "key": "waterings", "ttl": "24h"})
+	if res := run(map[string]interface{}{"action": "incr", "namespace": "plants", "key": "waterings", "by": 2.0}); !strings.HasSuffix(res.ForLLM, "= 3") {
+		t.Errorf("incr = %q", res.ForLLM)
+	}
+	if res := run(map[string]interface{}{"action": "get", "namespace": "plants", "key": "last_watered"}); !strings.Contains(res.ForLLM, `"fern":"2026-10-01"`) {
+		t.Errorf("get = %q", res.ForLLM)
+	}
+	if res := run(map[string]interface{}{"action": "list", "namespace": "plants"}); !strings.Contains(res.ForLLM, "waterings = 3 (expires in") {
+		t.Errorf("list = %q", res.ForLLM)
+	}
+	if res := run(map[string]interface{}{"action": "list"}); res.ForLLM != "Namespaces: plants" {
+		t.Errorf("namespaces = %q", res.ForLLM)
+	}
+
+	if res := tool.Execute(ctx, map[string]interface{}{"action": "incr", "namespace": "plants", "key": "last_watered"}); !res.IsError {
+		t.Error("incr of a non-number should fail")
+	}
+	run(map[string]interface{}{"action": "delete", "namespace": "plants", "key": "waterings"})
+	if res := run(map[string]interface{}{"action": "get", "namespace": "plants", "key": "waterings"}); !strings.Contains(res.ForLLM, "not set") {
+		t.Errorf("get after delete = %q", res.ForLLM)
+	}
+}
+
+func TestKVToolNamespaceLimit(t *testing.T) {
+	tool := NewKVTool(state.NewManager(t.TempDir()))
+	ctx := context.Background()
+	for i := 0; i < kvMaxNamespaces; i++ {
+		args := map[string]interface{}{"action": "set", "namespace": fmt.Sprintf("ns%d", i), "key": "k", "value": 1.0}
+		if res := tool.Execute(ctx, args); res.IsError {
+			t.Fatalf("set %d: %s", i, res.ForLLM)
+		}
+	}
+	for _, action := range []string{"set", "incr"} {
+		args := map[string]interface{}{"action": action, "namespace": "one_more", "key": "k", "value": 1.0}
+		if res := tool.Execute(ctx, args); !res.IsError {
+			t.Errorf("%s created namespace %d", action, kvMaxNamespaces+1)
+		}
+	}
+	// Existing namespaces still take new keys
+	if res := tool.Execute(ctx, map[string]interface{}{"action": "incr", "namespace": "ns0", "key": "other"}); res.IsError {
+		t.Errorf("incr in an existing namespace: %s", res.ForLLM)
+	}
+}
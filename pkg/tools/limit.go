@@ -0,0 +1,62 @@
+package tools
+
+import (
+	"bytes"
+	"io"
+	"sync/atomic"
+)
+
+// readLimit caps what a single read_file, web_fetch or exec holds in
+// memory. memory.max_read_kb sets it.
+var readLimit atomic.Int64
+
+func init() {
+	readLimit.Store(2 << 20)
+}
+
+// SetReadLimit sets the largest read tools hold in memory, in bytes.
+func SetReadLimit(n int64) {
+	if n > 0 {
+		readLimit.Store(n)
+	}
+}
+
+// ReadLimit returns the largest read tools hold in memory, in bytes.
+func ReadLimit() int64 {
+	return readLimit.Load()
+}
+
+// readCapped reads at most max bytes from r. truncated reports whether r
+// had more.
+func readCapped(r io.Reader, max int64) (data []byte, truncated bool, err error) {
+	data, err = io.ReadAll(io.LimitReader(r, max+1))
+	if int64(len(data)) > max {
+		return data[:max], true, err
+	}
+	return data, false, err
+}
+
+// cappedBuffer keeps the first max bytes written to it and counts the
+// rest, so a chatty command cannot fill memory.
+type cappedBuffer struct {
+	buf     bytes.Buffer
+	max     int64
+	dropped int64
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	keep := b.max - int64(b.buf.Len())
+	if keep < 0 {
+		keep = 0
+	}
+	if int64(len(p)) > keep {
+		b.dropped += int64(len(p)) - keep
+		b.buf.Write(p[:keep])
+	} else {
+		b.buf.Write(p)
+	}
+	return len(p), nil
+}
+
+func (b *cappedBuffer) Len() int       { return b.buf.Len() }
+func (b *cappedBuffer) String() string { return b.buf.String() }
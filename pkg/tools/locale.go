@@ -0,0 +1,110 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/locale"
+)
+
+// LocaleTool reads and changes the time zone and locale of the current
+// user or chat.
+type LocaleTool struct {
+	store *locale.Store
+}
+
+func NewLocaleTool(store *locale.Store) *LocaleTool {
+	return &LocaleTool{store: store}
+}
+
+func (t *LocaleTool) Name() string {
+	return "locale"
+}
+
+func (t *LocaleTool) Description() string {
+	return "Get or set the time zone and locale used for this user (or the whole chat). Set them when the user says where they are, what time it is for them, or which date format or language they prefer, so that dates, reminders and daily notes follow their clock."
+}
+
+func (t *LocaleTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"get", "set"},
+				"description": "Action to perform",
+			},
+			"timezone": map[string]interface{}{
+				"type":        "string",
+				"description": "IANA time zone, e.g. 'Asia/Shanghai' or 'America/New_York' (for set)",
+			},
+			"locale": map[string]interface{}{
+				"type":        "string",
+				"description": "Locale tag, e.g. 'zh-CN' or 'en-US' (for set)",
+			},
+			"scope": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"user", "chat"},
+				"description": "Whether the setting is for the current user (default) or everyone in the chat",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *LocaleTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	turn, ok := locale.FromContext(ctx)
+	if !ok || turn.Channel == "" {
+		return ErrorResult("no conversation context; use this tool while talking to a user")
+	}
+
+	action, _ := args["action"].(string)
+	switch action {
+	case "get":
+		return SilentResult(t.describe(turn))
+	case "set":
+		st := locale.Settings{}
+		st.TimeZone, _ = args["timezone"].(string)
+		st.Locale, _ = args["locale"].(string)
+		if st.TimeZone == "" && st.Locale == "" {
+			return ErrorResult("timezone or locale is required for set")
+		}
+
+		var err error
+		scope, _ := args["scope"].(string)
+		switch scope {
+		case "chat":
+			if turn.ChatID == "" {
+				return ErrorResult("no chat in this conversation")
+			}
+			err = t.store.SetChat(turn.Channel, turn.ChatID, st)
+		case "", "user":
+			if turn.SenderID == "" {
+				return ErrorResult("no user in this conversation; use scope 'chat'")
+			}
+			scope = "user"
+			err = t.store.SetUser(turn.Channel, turn.SenderID, st)
+		default:
+			return ErrorResult(fmt.Sprintf("unknown scope: %s", scope))
+		}
+		if err != nil {
+			return ErrorResult(err.Error())
+		}
+		turn.Settings = t.store.Resolve(turn.Channel, turn.ChatID, turn.SenderID)
+		return SilentResult(fmt.Sprintf("Saved for this %s.\n%s", scope, t.describe(turn)))
+	case "":
+		return ErrorResult("action is required")
+	default:
+		return ErrorResult(fmt.Sprintf("unknown action: %s", action))
+	}
+}
+
+func (t *LocaleTool) describe(turn locale.Turn) string {
+	st := turn.Settings
+	loc := st.Locale
+	if loc == "" {
+		loc = "not set"
+	}
+	return fmt.Sprintf("Time zone: %s\nLocale: %s\nLocal time: %s", st.ZoneName(), loc, st.Format(time.Now()))
+}
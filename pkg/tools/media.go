@@ -0,0 +1,148 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/locale"
+	"github.com/sipeed/picoclaw/pkg/media"
+	"github.com/sipeed/picoclaw/pkg/tenant"
+)
+
+// MediaTool lets the agent inspect and clean up the media store.
+type MediaTool struct {
+	store     *media.Store
+	workspace string
+}
+
+// NewMediaTool creates a MediaTool for store. Paths are shown relative to
+// workspace.
+func NewMediaTool(store *media.Store, workspace string) *MediaTool {
+	return &MediaTool{store: store, workspace: workspace}
+}
+
+func (t *MediaTool) Name() string {
+	return "media"
+}
+
+func (t *MediaTool) Description() string {
+	return "Manage files users sent in chats. Attachments appear in messages as media:<id>. Use 'list' to see stored files, 'describe' to get a file's details and path (for read_file or message attachments), and 'delete' to remove one."
+}
+
+func (t *MediaTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"list", "describe", "delete"},
+				"description": "Action to perform",
+			},
+			"id": map[string]interface{}{
+				"type":        "string",
+				"description": "Media ID or media:<id> reference (for describe/delete)",
+			},
+			"channel": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional: only list files from this channel",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *MediaTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	action, _ := args["action"].(string)
+	switch action {
+	case "list":
+		channel, _ := args["channel"].(string)
+		return t.list(ctx, channel)
+	case "describe":
+		return t.describe(ctx, args)
+	case "delete":
+		id, _ := args["id"].(string)
+		if id == "" {
+			return ErrorResult("id is required for delete")
+		}
+		if _, _, ok := t.get(ctx, id); !ok {
+			return ErrorResult(fmt.Sprintf("media %s not found", id))
+		}
+		// A tenant only gives up its own copy; others who sent the same
+		// file keep it
+		if tn := tenant.FromContext(ctx); tn != nil {
+			if _, err := t.store.Disown(id, func(o media.Owner) bool {
+				return tn.Owns(o.Channel, o.ChatID, o.SenderID)
+			}); err != nil {
+				return ErrorResult(err.Error())
+			}
+		} else if err := t.store.Delete(id); err != nil {
+			return ErrorResult(err.Error())
+		}
+		return SilentResult(fmt.Sprintf("Deleted %s%s", media.RefPrefix, strings.TrimPrefix(id, media.RefPrefix)))
+	case "":
+		return ErrorResult("action is required")
+	default:
+		return ErrorResult(fmt.Sprintf("unknown action: %s", action))
+	}
+}
+
+// visible reports whether the turn may see an item and returns the sender
+// to show for it. Tenants only see what they sent themselves.
+func visible(ctx context.Context, it media.Item) (media.Owner, bool) {
+	tn := tenant.FromContext(ctx)
+	if tn == nil {
+		return media.Owner{Channel: it.Channel, SenderID: it.SenderID, ChatID: it.ChatID}, true
+	}
+	for _, o := range it.Owners {
+		if tn.Owns(o.Channel, o.ChatID, o.SenderID) {
+			return o, true
+		}
+	}
+	return media.Owner{}, false
+}
+
+func (t *MediaTool) get(ctx context.Context, id string) (media.Item, media.Owner, bool) {
+	it, ok := t.store.Get(id)
+	if !ok {
+		return media.Item{}, media.Owner{}, false
+	}
+	owner, ok := visible(ctx, it)
+	return it, owner, ok
+}
+
+func (t *MediaTool) list(ctx context.Context, channel string) *ToolResult {
+	settings := locale.SettingsFrom(ctx)
+	var sb strings.Builder
+	n := 0
+	for _, it := range t.store.List() {
+		owner, ok := visible(ctx, it)
+		if !ok || channel != "" && owner.Channel != channel {
+			continue
+		}
+		n++
+		fmt.Fprintf(&sb, "- %s, from %s:%s, %s\n",
+			t.store.Describe(it, t.workspace), owner.Channel, owner.SenderID, settings.Format(it.LastSeen))
+	}
+	if n == 0 {
+		return SilentResult("No media stored")
+	}
+	count, size := t.store.Usage()
+	return SilentResult(fmt.Sprintf("Media (%d shown, %d stored, %s total):\n%s", n, count, media.FormatSize(size), sb.String()))
+}
+
+func (t *MediaTool) describe(ctx context.Context, args map[string]interface{}) *ToolResult {
+	settings := locale.SettingsFrom(ctx)
+	id, _ := args["id"].(string)
+	if id == "" {
+		return ErrorResult("id is required for describe")
+	}
+	it, owner, ok := t.get(ctx, id)
+	if !ok {
+		return ErrorResult(fmt.Sprintf("media %s not found", id))
+	}
+	return SilentResult(fmt.Sprintf(
+		"%s\nSHA-256: %s\nChannel: %s\nSender: %s\nChat: %s\nReceived: %s\nLast seen: %s",
+		t.store.Describe(it, t.workspace), it.SHA256, owner.Channel, owner.SenderID, owner.ChatID,
+		settings.Format(it.Created), settings.Format(it.LastSeen)))
+}
@@ -0,0 +1,93 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/media"
+	"github.com/sipeed/picoclaw/pkg/tenant"
+)
+
+func TestMediaTool(t *testing.T) {
+	workspace := t.TempDir()
+	store, err := media.NewStore(filepath.Join(workspace, "media"), media.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	item, err := store.Put(strings.NewReader("hello"), media.Origin{Channel: "slack", SenderID: "U1", Name: "note.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Put(strings.NewReader("other"), media.Origin{Channel: "telegram", Name: "x.txt"})
+
+	tool := NewMediaTool(store, workspace)
+	ctx := context.Background()
+
+	res := tool.Execute(ctx, map[string]interface{}{"action": "list", "channel": "slack"})
+	if res.IsError || !strings.Contains(res.ForLLM, item.Ref()) || strings.Contains(res.ForLLM, "x.txt") {
+		t.Errorf("list = %q", res.ForLLM)
+	}
+
+	res = tool.Execute(ctx, map[string]interface{}{"action": "describe", "id": item.Ref()})
+	wantPath := filepath.Join("media", "files", item.SHA256+".txt")
+	if res.IsError || !strings.Contains(res.ForLLM, "at "+wantPath) || !strings.Contains(res.ForLLM, "Sender: U1") {
+		t.Errorf("describe = %q", res.ForLLM)
+	}
+
+	if res := tool.Execute(ctx, map[string]interface{}{"action": "delete", "id": item.ID}); res.IsError {
+		t.Fatalf("delete: %s", res.ForLLM)
+	}
+	if res := tool.Execute(ctx, map[string]interface{}{"action": "describe", "id": item.ID}); !res.IsError {
+		t.Error("describe after delete should fail")
+	}
+}
+
+func TestMediaToolSharedAcrossTenants(t *testing.T) {
+	workspace := t.TempDir()
+	store, err := media.NewStore(filepath.Join(workspace, "media"), media.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	item, _ := store.Put(strings.NewReader("same file"), media.Origin{Channel: "telegram", SenderID: "1", ChatID: "1", Name: "a.txt"})
+	store.Put(strings.NewReader("same file"), media.Origin{Channel: "telegram", SenderID: "2", ChatID: "2", Name: "a.txt"})
+
+	m := tenant.NewManager(workspace, config.TenantsConfig{Enabled: true, Scope: "sender"})
+	ctxFor := func(sender string) context.Context {
+		tn, err := m.Resolve("telegram", sender, sender)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tenant.WithContext(context.Background(), tn)
+	}
+	first, second, third := ctxFor("1"), ctxFor("2"), ctxFor("3")
+	tool := NewMediaTool(store, workspace)
+
+	res := tool.Execute(second, map[string]interface{}{"action": "describe", "id": item.Ref()})
+	if res.IsError || !strings.Contains(res.ForLLM, "Sender: 2") {
+		t.Errorf("describe by the second sender = %q", res.ForLLM)
+	}
+	if res := tool.Execute(third, map[string]interface{}{"action": "describe", "id": item.Ref()}); !res.IsError {
+		t.Error("a tenant who never sent the file can see it")
+	}
+
+	if res := tool.Execute(first, map[string]interface{}{"action": "delete", "id": item.Ref()}); res.IsError {
+		t.Fatalf("delete: %s", res.ForLLM)
+	}
+	if res := tool.Execute(first, map[string]interface{}{"action": "describe", "id": item.Ref()}); !res.IsError {
+		t.Error("file still visible to the tenant who deleted it")
+	}
+	if _, err := os.Stat(store.Path(item)); err != nil {
+		t.Fatalf("file removed while the second sender still has it: %v", err)
+	}
+
+	if res := tool.Execute(second, map[string]interface{}{"action": "delete", "id": item.Ref()}); res.IsError {
+		t.Fatalf("delete: %s", res.ForLLM)
+	}
+	if _, err := os.Stat(store.Path(item)); !os.IsNotExist(err) {
+		t.Error("file kept after its last owner deleted it")
+	}
+}
@@ -3,15 +3,23 @@ package tools
 import (
 	"context"
 	"fmt"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/media"
+	"github.com/sipeed/picoclaw/pkg/tenant"
 )
 
 type SendCallback func(channel, chatID, content string) error
 
+// SendMediaCallback sends a message with local files attached.
+type SendMediaCallback func(channel, chatID, content string, media []string) error
+
 type MessageTool struct {
-	sendCallback   SendCallback
-	defaultChannel string
-	defaultChatID  string
-	sentInRound    bool // Tracks whether a message was sent in the current processing round
+	sendCallback      SendCallback
+	sendMediaCallback SendMediaCallback
+	defaultChannel    string
+	defaultChatID     string
+	sentInRound       bool // Tracks whether a message was sent in the current processing round
 }
 
 func NewMessageTool() *MessageTool {
@@ -42,6 +50,11 @@ func (t *MessageTool) Parameters() map[string]interface{} {
 				"type":        "string",
 				"description": "Optional: target chat/user ID",
 			},
+			"media": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Optional: paths of local files or media:<id> references to attach (channels without attachment support ignore them)",
+			},
 		},
 		"required": []string{"content"},
 	}
@@ -62,6 +75,10 @@ func (t *MessageTool) SetSendCallback(callback SendCallback) {
 	t.sendCallback = callback
 }
 
+func (t *MessageTool) SetSendMediaCallback(callback SendMediaCallback) {
+	t.sendMediaCallback = callback
+}
+
 func (t *MessageTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
 	content, ok := args["content"].(string)
 	if !ok {
@@ -82,11 +99,43 @@ func (t *MessageTool) Execute(ctx context.Context, args map[string]interface{})
 		return &ToolResult{ForLLM: "No target channel/chat specified", IsError: true}
 	}
 
-	if t.sendCallback == nil {
+	// Tenants only talk to their own chat and attach their own files
+	tn := tenant.FromContext(ctx)
+	if tn != nil && (channel != t.defaultChannel || chatID != t.defaultChatID) {
+		return &ToolResult{ForLLM: "Messages can only be sent to the current chat", IsError: true}
+	}
+
+	var attachments []string
+	if raw, ok := args["media"].([]interface{}); ok {
+		for _, item := range raw {
+			path, ok := item.(string)
+			if !ok || path == "" {
+				continue
+			}
+			if tn != nil && !strings.HasPrefix(path, media.RefPrefix) {
+				resolved, err := validatePath(path, tn.Workspace(), true)
+				if err != nil {
+					return &ToolResult{ForLLM: fmt.Sprintf("attachment %s: %v", path, err), IsError: true}
+				}
+				path = resolved
+			}
+			attachments = append(attachments, path)
+		}
+	}
+
+	var err error
+	switch {
+	case len(attachments) > 0 && t.sendMediaCallback != nil:
+		err = t.sendMediaCallback(channel, chatID, content, attachments)
+	case len(attachments) > 0:
+		return &ToolResult{ForLLM: "Sending attachments not configured", IsError: true}
+	case t.sendCallback != nil:
+		err = t.sendCallback(channel, chatID, content)
+	default:
 		return &ToolResult{ForLLM: "Message sending not configured", IsError: true}
 	}
 
-	if err := t.sendCallback(channel, chatID, content); err != nil {
+	if err != nil {
 		return &ToolResult{
 			ForLLM:  fmt.Sprintf("sending message: %v", err),
 			IsError: true,
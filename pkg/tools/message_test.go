@@ -257,3 +257,33 @@ func TestMessageTool_Parameters(t *testing.T) {
 		t.Error("Expected chat_id type to be 'string'")
 	}
 }
+
+func TestMessageTool_Execute_WithMedia(t *testing.T) {
+	tool := NewMessageTool()
+	tool.SetContext("test-channel", "test-chat-id")
+
+	args := map[string]interface{}{
+		"content": "See attached",
+		"media":   []interface{}{"/tmp/report.pdf"},
+	}
+
+	// Without a media callback, attachments cannot be sent
+	tool.SetSendCallback(func(channel, chatID, content string) error { return nil })
+	result := tool.Execute(context.Background(), args)
+	if !result.IsError {
+		t.Error("Expected IsError=true when media callback not configured")
+	}
+
+	var sentMedia []string
+	tool.SetSendMediaCallback(func(channel, chatID, content string, media []string) error {
+		sentMedia = media
+		return nil
+	})
+	result = tool.Execute(context.Background(), args)
+	if result.IsError {
+		t.Fatalf("Expected success, got %s", result.ForLLM)
+	}
+	if len(sentMedia) != 1 || sentMedia[0] != "/tmp/report.pdf" {
+		t.Errorf("Expected media [/tmp/report.pdf], got %v", sentMedia)
+	}
+}
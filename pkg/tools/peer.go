@@ -0,0 +1,156 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/federation"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// AskPeerTool hands a task to another PicoClaw instance of the federation.
+type AskPeerTool struct {
+	node          *federation.Node
+	bus           *bus.MessageBus
+	originChannel string
+	originChatID  string
+	callback      AsyncCallback
+}
+
+func NewAskPeerTool(node *federation.Node, msgBus *bus.MessageBus) *AskPeerTool {
+	return &AskPeerTool{
+		node:          node,
+		bus:           msgBus,
+		originChannel: "cli",
+		originChatID:  "direct",
+	}
+}
+
+// SetCallback implements AsyncTool.
+func (t *AskPeerTool) SetCallback(cb AsyncCallback) {
+	t.callback = cb
+}
+
+func (t *AskPeerTool) SetContext(channel, chatID string) {
+	t.originChannel = channel
+	t.originChatID = chatID
+}
+
+func (t *AskPeerTool) Name() string {
+	return "ask_peer"
+}
+
+// Description lists the peers with what they advertise, so the model can
+// pick one.
+func (t *AskPeerTool) Description() string {
+	var sb strings.Builder
+	sb.WriteString("Ask another agent (a peer) to do a task it is better placed for, and get its answer. Set async for long tasks; the result is then delivered to this chat when it is ready. Peers:")
+	for _, p := range t.node.Peers() {
+		fmt.Fprintf(&sb, "\n- %s", p.Name)
+		if desc := p.Description(); desc != "" {
+			fmt.Fprintf(&sb, ": %s", desc)
+		}
+		if caps := p.Capabilities(); len(caps) > 0 {
+			fmt.Fprintf(&sb, " Capabilities: %s.", strings.Join(caps, "; "))
+		}
+	}
+	return sb.String()
+}
+
+func (t *AskPeerTool) Parameters() map[string]interface{} {
+	names := make([]string, 0, len(t.node.Peers()))
+	for _, p := range t.node.Peers() {
+		names = append(names, p.Name)
+	}
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"peer": map[string]interface{}{
+				"type":        "string",
+				"enum":        names,
+				"description": "Peer to ask",
+			},
+			"task": map[string]interface{}{
+				"type":        "string",
+				"description": "What the peer should do, with all the context it needs",
+			},
+			"async": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Run in the background and deliver the result later (default false)",
+			},
+		},
+		"required": []string{"peer", "task"},
+	}
+}
+
+func (t *AskPeerTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	peer, _ := args["peer"].(string)
+	task, _ := args["task"].(string)
+	if peer == "" || task == "" {
+		return ErrorResult("peer and task are required")
+	}
+	// Tasks from peers are answered here, not passed around the federation
+	if t.originChannel == federation.Channel {
+		return ErrorResult("this task came from a peer; do it yourself instead of asking another peer")
+	}
+	contextID := fmt.Sprintf("%s:%s", t.originChannel, t.originChatID)
+
+	async, _ := args["async"].(bool)
+	if !async {
+		result, err := t.node.Ask(ctx, peer, task, contextID)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("failed to ask %s: %v", peer, err)).WithError(err)
+		}
+		return peerResult(peer, result)
+	}
+
+	pending, err := t.node.Submit(ctx, peer, task, contextID)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to send task to %s: %v", peer, err)).WithError(err)
+	}
+	go t.wait(peer, pending, t.originChannel, t.originChatID, t.callback)
+	return AsyncResult(fmt.Sprintf("Task sent to %s (id %s). Its result will be delivered to this chat when it is done.", peer, pending.Task.ID))
+}
+
+// wait delivers the result of a background task to the agent, which then
+// reports it in the chat the task came from.
+func (t *AskPeerTool) wait(peer string, pending *federation.Pending, channel, chatID string, cb AsyncCallback) {
+	result, err := t.node.Wait(context.Background(), pending)
+	var res *ToolResult
+	if err != nil {
+		res = ErrorResult(fmt.Sprintf("task %s on %s did not finish: %v", pending.Task.ID, peer, err))
+	} else {
+		res = peerResult(peer, result)
+	}
+	logger.InfoCF("federation", "Peer task finished", map[string]interface{}{
+		"peer":  peer,
+		"task":  pending.Task.ID,
+		"error": res.IsError,
+	})
+
+	if t.bus != nil {
+		t.bus.PublishInbound(bus.InboundMessage{
+			Channel:  "system",
+			SenderID: "peer:" + peer,
+			// Format: "original_channel:original_chat_id" for routing back
+			ChatID:  fmt.Sprintf("%s:%s", channel, chatID),
+			Content: fmt.Sprintf("Peer '%s' finished task %s.\n\nResult:\n%s", peer, pending.Task.ID, res.ForLLM),
+		})
+	}
+	if cb != nil {
+		cb(context.Background(), res)
+	}
+}
+
+func peerResult(peer string, task *federation.Task) *ToolResult {
+	switch task.Status.State {
+	case federation.StateCompleted:
+		return SilentResult(fmt.Sprintf("%s answered:\n%s", peer, task.Result()))
+	case federation.StateFailed, federation.StateRejected:
+		return ErrorResult(fmt.Sprintf("%s could not do the task: %s", peer, task.Result()))
+	default:
+		return ErrorResult(fmt.Sprintf("%s did not finish the task (state %s)", peer, task.Status.State))
+	}
+}
@@ -0,0 +1,74 @@
+package tools
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/federation"
+)
+
+type upperAgent struct{}
+
+func (upperAgent) ProcessDirectWithChannel(ctx context.Context, content, sessionKey, channel, chatID string) (string, error) {
+	return strings.ToUpper(content[strings.Index(content, "\n")+1:]), nil
+}
+
+func TestAskPeerTool(t *testing.T) {
+	const token = "home-office-shared-secret"
+	var home, office *federation.Node
+	homeSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { home.ServeHTTP(w, r) }))
+	defer homeSrv.Close()
+	officeSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { office.ServeHTTP(w, r) }))
+	defer officeSrv.Close()
+
+	home = federation.New(config.FederationConfig{
+		Name:      "home",
+		Path:      "/a2a",
+		PublicURL: homeSrv.URL + "/a2a",
+		Peers:     []config.PeerConfig{{Name: "office", URL: officeSrv.URL + "/a2a", Token: token, Description: "Office desktop"}},
+	}, upperAgent{})
+	office = federation.New(config.FederationConfig{
+		Name:  "office",
+		Path:  "/a2a",
+		Peers: []config.PeerConfig{{Name: "home", URL: homeSrv.URL + "/a2a", Token: token}},
+	}, upperAgent{})
+
+	msgBus := bus.NewMessageBus()
+	tool := NewAskPeerTool(home, msgBus)
+	tool.SetContext("telegram", "42")
+	if !strings.Contains(tool.Description(), "office: Office desktop") {
+		t.Errorf("description = %q", tool.Description())
+	}
+
+	ctx := context.Background()
+	res := tool.Execute(ctx, map[string]interface{}{"peer": "office", "task": "print report"})
+	if res.IsError || !strings.Contains(res.ForLLM, "PRINT REPORT") {
+		t.Fatalf("sync result = %+v", res)
+	}
+
+	res = tool.Execute(ctx, map[string]interface{}{"peer": "office", "task": "backup", "async": true})
+	if !res.Async {
+		t.Fatalf("async result = %+v", res)
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	msg, ok := msgBus.ConsumeInbound(waitCtx)
+	if !ok {
+		t.Fatal("async result was not delivered")
+	}
+	if msg.Channel != "system" || msg.ChatID != "telegram:42" || msg.SenderID != "peer:office" || !strings.Contains(msg.Content, "BACKUP") {
+		t.Errorf("delivered %+v", msg)
+	}
+
+	// Turns run for a peer must not fan out further
+	tool.SetContext(federation.Channel, "office")
+	if res := tool.Execute(ctx, map[string]interface{}{"peer": "office", "task": "loop"}); !res.IsError {
+		t.Error("ask_peer from a peer turn should fail")
+	}
+}
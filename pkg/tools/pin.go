@@ -0,0 +1,107 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/session"
+)
+
+// PinTool keeps notes pinned to the current conversation. Pinned notes are
+// in the context of every turn and are not summarized away.
+type PinTool struct {
+	maxChars int
+}
+
+// NewPinTool returns the tool, allowing maxChars characters of notes per
+// conversation.
+func NewPinTool(maxChars int) *PinTool {
+	return &PinTool{maxChars: maxChars}
+}
+
+func (t *PinTool) Name() string {
+	return "pin"
+}
+
+func (t *PinTool) Description() string {
+	return "Pin a fact to this conversation so it stays in your context on every turn, even after older messages are summarized, e.g. 'Planning the Osaka trip, budget ¥300k'. Use it for what matters throughout this conversation; lasting facts about the user belong in memory. list shows the pinned notes with their numbers; remove takes a number."
+}
+
+func (t *PinTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"add", "list", "remove"},
+				"description": "add a note, list the pinned notes or remove one",
+			},
+			"note": map[string]interface{}{
+				"type":        "string",
+				"description": "The fact to pin, short and self-contained (for add)",
+			},
+			"index": map[string]interface{}{
+				"type":        "integer",
+				"description": "Number of the note as shown by list (for remove)",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *PinTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	sessions, key, ok := session.FromContext(ctx)
+	if !ok {
+		return ErrorResult("no conversation context; use this tool while talking to a user")
+	}
+
+	action, _ := args["action"].(string)
+	switch action {
+	case "add":
+		note, _ := args["note"].(string)
+		note = strings.TrimSpace(note)
+		if note == "" {
+			return ErrorResult("note is required for add")
+		}
+		if err := sessions.Pin(key, note, t.maxChars); errors.Is(err, session.ErrPinsFull) {
+			return ErrorResult(fmt.Sprintf("pinned notes are limited to %d characters in total; remove or shorten one first", t.maxChars))
+		}
+		sessions.Save(key)
+		return SilentResult("Pinned: " + note)
+	case "list":
+		pins := sessions.GetPins(key)
+		if len(pins) == 0 {
+			return SilentResult("Nothing is pinned in this conversation")
+		}
+		return SilentResult(formatPins(pins))
+	case "remove":
+		n, ok := args["index"].(float64)
+		if !ok {
+			return ErrorResult("index is required for remove")
+		}
+		note, ok := sessions.Unpin(key, int(n))
+		if !ok {
+			return ErrorResult(fmt.Sprintf("there is no pinned note %d", int(n)))
+		}
+		sessions.Save(key)
+		return SilentResult("Unpinned: " + note)
+	case "":
+		return ErrorResult("action is required")
+	default:
+		return ErrorResult(fmt.Sprintf("unknown action: %s", action))
+	}
+}
+
+// formatPins numbers pinned notes for a listing.
+func formatPins(pins []string) string {
+	var sb strings.Builder
+	for i, p := range pins {
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+		fmt.Fprintf(&sb, "%d. %s", i+1, p)
+	}
+	return sb.String()
+}
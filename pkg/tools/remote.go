@@ -0,0 +1,87 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
+
+// RemoteTool runs a tool offered by the other process of a split
+// deployment, e.g. the hardware tools of a board that runs the channels.
+type RemoteTool struct {
+	link    *bus.Link
+	spec    bus.ToolSpec
+	channel string
+	chatID  string
+}
+
+func NewRemoteTool(link *bus.Link, spec bus.ToolSpec) *RemoteTool {
+	return &RemoteTool{link: link, spec: spec}
+}
+
+func (t *RemoteTool) Name() string {
+	return t.spec.Name
+}
+
+func (t *RemoteTool) Description() string {
+	return t.spec.Description
+}
+
+func (t *RemoteTool) Parameters() map[string]interface{} {
+	return t.spec.Parameters
+}
+
+func (t *RemoteTool) SetContext(channel, chatID string) {
+	t.channel = channel
+	t.chatID = chatID
+}
+
+func (t *RemoteTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	reply, err := t.link.CallTool(ctx, bus.ToolCall{
+		Name:    t.spec.Name,
+		Args:    args,
+		Channel: t.channel,
+		ChatID:  t.chatID,
+	})
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("remote tool %s: %v", t.spec.Name, err)).WithError(err)
+	}
+	return &ToolResult{
+		ForLLM:  reply.ForLLM,
+		ForUser: reply.ForUser,
+		Silent:  reply.Silent,
+		IsError: reply.IsError,
+	}
+}
+
+// ExportTools offers the named tools of registry to the other side of link.
+// Names not in the registry are skipped.
+func ExportTools(link *bus.Link, registry *ToolRegistry, names []string) {
+	var specs []bus.ToolSpec
+	exported := map[string]bool{}
+	for _, name := range names {
+		tool, ok := registry.Get(name)
+		if !ok {
+			continue
+		}
+		exported[name] = true
+		specs = append(specs, bus.ToolSpec{
+			Name:        tool.Name(),
+			Description: tool.Description(),
+			Parameters:  tool.Parameters(),
+		})
+	}
+	link.ExportTools(specs, func(ctx context.Context, call bus.ToolCall) bus.ToolReply {
+		if !exported[call.Name] {
+			return bus.ToolReply{ForLLM: fmt.Sprintf("tool %s is not offered", call.Name), IsError: true}
+		}
+		res := registry.ExecuteWithContext(ctx, call.Name, call.Args, call.Channel, call.ChatID, nil)
+		return bus.ToolReply{
+			ForLLM:  res.ForLLM,
+			ForUser: res.ForUser,
+			Silent:  res.Silent,
+			IsError: res.IsError,
+		}
+	})
+}
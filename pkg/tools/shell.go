@@ -1,7 +1,6 @@
 package tools
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"os"
@@ -11,6 +10,8 @@ import (
 	"runtime"
 	"strings"
 	"time"
+
+	"github.com/sipeed/picoclaw/pkg/tenant"
 )
 
 type ExecTool struct {
@@ -74,7 +75,23 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) *To
 	}
 
 	cwd := t.workingDir
-	if wd, ok := args["working_dir"].(string); ok && wd != "" {
+	restrict := t.restrictToWorkspace
+	wd, _ := args["working_dir"].(string)
+	if tn := tenant.FromContext(ctx); tn != nil {
+		// Tenants run commands only when allowed, and only in their workspace
+		if !tn.AllowExec() {
+			return ErrorResult("exec is not available in this workspace")
+		}
+		cwd, restrict = tn.Workspace(), true
+		if wd != "" {
+			resolved, err := validatePath(wd, cwd, true)
+			if err != nil {
+				return ErrorResult(err.Error())
+			}
+			wd = resolved
+		}
+	}
+	if wd != "" {
 		cwd = wd
 	}
 
@@ -85,7 +102,7 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) *To
 		}
 	}
 
-	if guardError := t.guardCommand(command, cwd); guardError != "" {
+	if guardError := t.guardCommand(command, cwd, restrict); guardError != "" {
 		return ErrorResult(guardError)
 	}
 
@@ -102,9 +119,10 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) *To
 		cmd.Dir = cwd
 	}
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	stdout := &cappedBuffer{max: ReadLimit()}
+	stderr := &cappedBuffer{max: ReadLimit()}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
 
 	err := cmd.Run()
 	output := stdout.String()
@@ -129,8 +147,12 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) *To
 	}
 
 	maxLen := 10000
-	if len(output) > maxLen {
-		output = output[:maxLen] + fmt.Sprintf("\n... (truncated, %d more chars)", len(output)-maxLen)
+	if more := stdout.dropped + stderr.dropped; len(output) > maxLen || more > 0 {
+		if len(output) > maxLen {
+			more += int64(len(output) - maxLen)
+			output = output[:maxLen]
+		}
+		output += fmt.Sprintf("\n... (truncated, %d more chars)", more)
 	}
 
 	if err != nil {
@@ -148,7 +170,7 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) *To
 	}
 }
 
-func (t *ExecTool) guardCommand(command, cwd string) string {
+func (t *ExecTool) guardCommand(command, cwd string, restrict bool) string {
 	cmd := strings.TrimSpace(command)
 	lower := strings.ToLower(cmd)
 
@@ -171,7 +193,7 @@ func (t *ExecTool) guardCommand(command, cwd string) string {
 		}
 	}
 
-	if t.restrictToWorkspace {
+	if restrict {
 		if strings.Contains(cmd, "..\\") || strings.Contains(cmd, "../") {
 			return "Command blocked by safety guard (path traversal detected)"
 		}
@@ -186,6 +186,21 @@ func TestShellTool_OutputTruncation(t *testing.T) {
 	}
 }
 
+// TestShellTool_OutputCapped verifies output beyond the read limit is not kept in memory
+func TestShellTool_OutputCapped(t *testing.T) {
+	prev := ReadLimit()
+	SetReadLimit(100)
+	t.Cleanup(func() { SetReadLimit(prev) })
+
+	tool := NewExecTool("", false)
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"command": "printf '%0500d' 0",
+	})
+	if !strings.Contains(result.ForLLM, "truncated, 400 more chars") {
+		t.Errorf("Expected 400 dropped chars to be reported, got: %s", result.ForLLM)
+	}
+}
+
 // TestShellTool_RestrictToWorkspace verifies workspace restriction
 func TestShellTool_RestrictToWorkspace(t *testing.T) {
 	tmpDir := t.TempDir()
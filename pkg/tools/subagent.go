@@ -21,6 +21,12 @@ type SubagentTask struct {
 	Created       int64
 }
 
+// Gate limits how many background tasks run at once.
+// governor.Governor implements it.
+type Gate interface {
+	Acquire(ctx context.Context) (release func(), err error)
+}
+
 type SubagentManager struct {
 	tasks         map[string]*SubagentTask
 	mu            sync.RWMutex
@@ -31,6 +37,7 @@ type SubagentManager struct {
 	tools         *ToolRegistry
 	maxIterations int
 	nextID        int
+	gate          Gate
 }
 
 func NewSubagentManager(provider providers.LLMProvider, defaultModel, workspace string, bus *bus.MessageBus) *SubagentManager {
@@ -54,6 +61,13 @@ func (sm *SubagentManager) SetTools(tools *ToolRegistry) {
 	sm.tools = tools
 }
 
+// SetGate makes subagents wait for a slot of gate before running.
+func (sm *SubagentManager) SetGate(gate Gate) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.gate = gate
+}
+
 // RegisterTool registers a tool for subagent execution.
 func (sm *SubagentManager) RegisterTool(tool Tool) {
 	sm.mu.Lock()
@@ -123,8 +137,21 @@ After completing the task, provide a clear summary of what was done.`
 	sm.mu.RLock()
 	tools := sm.tools
 	maxIter := sm.maxIterations
+	gate := sm.gate
 	sm.mu.RUnlock()
 
+	if gate != nil {
+		release, err := gate.Acquire(ctx)
+		if err != nil {
+			sm.mu.Lock()
+			task.Status = "cancelled"
+			task.Result = "Task cancelled while waiting to run"
+			sm.mu.Unlock()
+			return
+		}
+		defer release()
+	}
+
 	loopResult, err := RunToolLoop(ctx, ToolLoopConfig{
 		Provider:      sm.provider,
 		Model:         sm.defaultModel,
@@ -205,6 +232,18 @@ func (sm *SubagentManager) ListTasks() []*SubagentTask {
 	return tasks
 }
 
+// TaskSnapshots returns copies of all tasks, safe to read while they run.
+func (sm *SubagentManager) TaskSnapshots() []SubagentTask {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	tasks := make([]SubagentTask, 0, len(sm.tasks))
+	for _, task := range sm.tasks {
+		tasks = append(tasks, *task)
+	}
+	return tasks
+}
+
 // SubagentTool executes a subagent task synchronously and returns the result.
 // Unlike SpawnTool which runs tasks asynchronously, SubagentTool waits for completion
 // and returns the result directly in the ToolResult.
@@ -4,12 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"regexp"
 	"strings"
 	"time"
+
+	"github.com/sipeed/picoclaw/pkg/network"
 )
 
 const (
@@ -36,14 +37,14 @@ func (p *BraveSearchProvider) Search(ctx context.Context, query string, count in
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("X-Subscription-Token", p.apiKey)
 
-	client := &http.Client{Timeout: 10 * time.Second}
+	client := network.NewClient(network.ComponentWeb, network.Options{Timeout: 10 * time.Second})
 	resp, err := client.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	body, _, err := readCapped(resp.Body, ReadLimit())
 	if err != nil {
 		return "", fmt.Errorf("failed to read response: %w", err)
 	}
@@ -96,14 +97,14 @@ func (p *DuckDuckGoSearchProvider) Search(ctx context.Context, query string, cou
 
 	req.Header.Set("User-Agent", userAgent)
 
-	client := &http.Client{Timeout: 10 * time.Second}
+	client := network.NewClient(network.ComponentWeb, network.Options{Timeout: 10 * time.Second})
 	resp, err := client.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	body, _, err := readCapped(resp.Body, ReadLimit())
 	if err != nil {
 		return "", fmt.Errorf("failed to read response: %w", err)
 	}
@@ -337,20 +338,12 @@ func (t *WebFetchTool) Execute(ctx context.Context, args map[string]interface{})
 
 	req.Header.Set("User-Agent", userAgent)
 
-	client := &http.Client{
-		Timeout: 60 * time.Second,
-		Transport: &http.Transport{
-			MaxIdleConns:        10,
-			IdleConnTimeout:     30 * time.Second,
-			DisableCompression:  false,
-			TLSHandshakeTimeout: 15 * time.Second,
-		},
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			if len(via) >= 5 {
-				return fmt.Errorf("stopped after 5 redirects")
-			}
-			return nil
-		},
+	client := network.NewClient(network.ComponentWeb, network.Options{Timeout: 60 * time.Second})
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 5 {
+			return fmt.Errorf("stopped after 5 redirects")
+		}
+		return nil
 	}
 
 	resp, err := client.Do(req)
@@ -359,7 +352,8 @@ func (t *WebFetchTool) Execute(ctx context.Context, args map[string]interface{})
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	// Only the start of a large page is read; it is truncated below anyway
+	body, bodyTruncated, err := readCapped(resp.Body, ReadLimit())
 	if err != nil {
 		return ErrorResult(fmt.Sprintf("failed to read response: %v", err))
 	}
@@ -387,8 +381,8 @@ func (t *WebFetchTool) Execute(ctx context.Context, args map[string]interface{})
 		extractor = "raw"
 	}
 
-	truncated := len(text) > maxChars
-	if truncated {
+	truncated := len(text) > maxChars || bodyTruncated
+	if len(text) > maxChars {
 		text = text[:maxChars]
 	}
 
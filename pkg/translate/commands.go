@@ -0,0 +1,116 @@
+package translate
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/i18n"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+// command runs /translate and /glossary, and reports whether text was one
+// of them.
+func (g *group) command(msg bus.InboundMessage, text string) (string, bool) {
+	name, args, _ := strings.Cut(text, " ")
+	// Telegram addresses commands in groups as /translate@botname
+	name, _, _ = strings.Cut(name, "@")
+	args = strings.TrimSpace(args)
+	lang := i18n.ForUser(msg.Channel, msg.ChatID, msg.SenderID)
+
+	switch strings.ToLower(name) {
+	case "/translate":
+		return g.translateCommand(lang, msg.SenderID, args), true
+	case "/glossary":
+		return g.glossaryCommand(lang, args), true
+	}
+	return "", false
+}
+
+// translateCommand shows the relay's settings or opts the sender out of
+// translation and back in.
+func (g *group) translateCommand(lang, senderID, args string) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	optedOut := slices.Contains(g.state.OptedOut, senderID)
+	switch strings.ToLower(args) {
+	case "":
+		if optedOut {
+			return i18n.T(lang, i18n.TranslateStatusOff, strings.Join(g.languages, ", "))
+		}
+		return i18n.T(lang, i18n.TranslateStatus, strings.Join(g.languages, ", "))
+	case "off":
+		if !optedOut {
+			g.state.OptedOut = append(g.state.OptedOut, senderID)
+			g.persist()
+		}
+		return i18n.T(lang, i18n.TranslateOff)
+	case "on":
+		if optedOut {
+			g.state.OptedOut = slices.DeleteFunc(g.state.OptedOut, func(id string) bool { return id == senderID })
+			g.persist()
+		}
+		return i18n.T(lang, i18n.TranslateOn)
+	}
+	return i18n.T(lang, i18n.TranslateUsage)
+}
+
+// glossaryCommand lists, adds and removes glossary entries.
+func (g *group) glossaryCommand(lang, args string) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	action, rest, _ := strings.Cut(args, " ")
+	rest = strings.TrimSpace(rest)
+	switch strings.ToLower(action) {
+	case "":
+		if len(g.state.Glossary) == 0 {
+			return i18n.T(lang, i18n.TranslateGlossaryEmpty)
+		}
+		var sb strings.Builder
+		for i, entry := range g.state.Glossary {
+			fmt.Fprintf(&sb, "\n%d. %s", i+1, entry)
+		}
+		return i18n.T(lang, i18n.TranslateGlossary, sb.String())
+	case "add":
+		if rest == "" {
+			break
+		}
+		entry := utils.Truncate(rest, maxEntryLen)
+		if !slices.Contains(g.state.Glossary, entry) {
+			if len(g.state.Glossary) >= maxGlossary {
+				return i18n.T(lang, i18n.TranslateGlossaryFull, maxGlossary)
+			}
+			g.state.Glossary = append(g.state.Glossary, entry)
+			g.persist()
+		}
+		return i18n.T(lang, i18n.TranslateGlossaryAdded)
+	case "remove":
+		n, err := strconv.Atoi(rest)
+		if err != nil || n < 1 || n > len(g.state.Glossary) {
+			return i18n.T(lang, i18n.TranslateGlossaryUnknown, rest)
+		}
+		g.state.Glossary = slices.Delete(g.state.Glossary, n-1, n)
+		g.persist()
+		return i18n.T(lang, i18n.TranslateGlossaryRemoved)
+	case "clear":
+		g.state.Glossary = nil
+		g.persist()
+		return i18n.T(lang, i18n.TranslateGlossaryCleared)
+	}
+	return i18n.T(lang, i18n.TranslateUsage)
+}
+
+// persist saves the group's settings, logging a failure. g.mu must be held.
+func (g *group) persist() {
+	if err := g.save(); err != nil {
+		logger.WarnCF("translate", "Failed to save group settings", map[string]interface{}{
+			"path":  g.path,
+			"error": err.Error(),
+		})
+	}
+}
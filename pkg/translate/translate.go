@@ -0,0 +1,412 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// Inspired by and based on nanobot: https://github.com/HKUDS/nanobot
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+// Package translate turns group chats into translation relays. In a relay
+// group the agent stays quiet; messages are collected for a few seconds,
+// translated together with one request to a cheap model, and posted back
+// in the group's other languages. Each group keeps a glossary, and members
+// can keep their messages out of it.
+package translate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+const (
+	requestTimeout = 2 * time.Minute
+	// maxGlossary and maxEntryLen keep the glossary, which goes into every
+	// request, small.
+	maxGlossary = 50
+	maxEntryLen = 200
+)
+
+// languageNames spell out common codes for the model.
+var languageNames = map[string]string{
+	"en": "English",
+	"zh": "Chinese",
+	"ja": "Japanese",
+	"ko": "Korean",
+	"fr": "French",
+	"de": "German",
+	"es": "Spanish",
+	"it": "Italian",
+	"pt": "Portuguese",
+	"ru": "Russian",
+	"vi": "Vietnamese",
+	"th": "Thai",
+}
+
+// Relay translates the messages of the configured groups. It is safe for
+// concurrent use.
+type Relay struct {
+	provider providers.LLMProvider
+	model    string
+	bus      *bus.MessageBus
+	window   time.Duration
+	maxBatch int
+	groups   map[string]*group // by channel:chat_id
+
+	// onUsage, when set, is told the tokens of each request.
+	onUsage func(prompt, completion int)
+}
+
+type group struct {
+	channel   string
+	chatID    string
+	languages []string
+	path      string
+
+	mu      sync.Mutex
+	state   groupState
+	pending []message
+	timer   *time.Timer
+
+	// sending keeps one request of the group in flight at a time.
+	sending sync.Mutex
+}
+
+// groupState is what members change in chat; it is saved per group.
+type groupState struct {
+	Glossary []string `json:"glossary,omitempty"`
+	OptedOut []string `json:"opted_out,omitempty"` // sender IDs
+}
+
+type message struct {
+	sender string
+	text   string
+}
+
+// NewRelay returns a relay for the groups in cfg. Translations use
+// cfg.Model, or defaultModel when it is empty, and go out on msgBus.
+// Glossaries and opt-outs are kept in dir.
+func NewRelay(cfg config.TranslateConfig, provider providers.LLMProvider, defaultModel string, msgBus *bus.MessageBus, dir string) *Relay {
+	model := cfg.Model
+	if model == "" {
+		model = defaultModel
+	}
+	r := &Relay{
+		provider: provider,
+		model:    model,
+		bus:      msgBus,
+		window:   time.Duration(cfg.BatchWindow) * time.Second,
+		maxBatch: max(cfg.MaxBatch, 1),
+		groups:   make(map[string]*group),
+	}
+	for _, gc := range cfg.Groups {
+		g := &group{
+			channel:   gc.Channel,
+			chatID:    gc.ChatID,
+			languages: gc.Languages,
+			path:      filepath.Join(dir, utils.SanitizeFilename(gc.Channel+"_"+gc.ChatID)+".json"),
+		}
+		g.load()
+		r.groups[gc.Channel+":"+gc.ChatID] = g
+	}
+	return r
+}
+
+// OnUsage sets fn to be told the tokens each translation request used.
+func (r *Relay) OnUsage(fn func(prompt, completion int)) {
+	r.onUsage = fn
+}
+
+// Intercept is an agent interceptor. It takes every message of a relay
+// group, so the agent never answers there, and queues it for translation
+// unless it is a /translate or /glossary command.
+func (r *Relay) Intercept(msg bus.InboundMessage) (reply string, handled bool) {
+	g := r.groups[msg.Channel+":"+msg.ChatID]
+	if g == nil {
+		return "", false
+	}
+	text := strings.TrimSpace(msg.Content)
+	if reply, ok := g.command(msg, text); ok {
+		return reply, true
+	}
+	// Reactions, edits and messages with nothing to translate, such as a
+	// sticker or a link, pass silently
+	if msg.Metadata["reaction"] != "" || msg.Metadata["edited"] != "" || !hasWords(text) {
+		return "", true
+	}
+	if g.optedOut(msg.SenderID) {
+		return "", true
+	}
+	r.add(g, message{sender: senderName(msg), text: text})
+	return "", true
+}
+
+// Flush translates what is waiting in every group without waiting for the
+// batch window.
+func (r *Relay) Flush() {
+	for _, g := range r.groups {
+		r.flush(g)
+	}
+}
+
+func (r *Relay) add(g *group, m message) {
+	g.mu.Lock()
+	g.pending = append(g.pending, m)
+	if len(g.pending) < r.maxBatch && r.window > 0 {
+		if g.timer == nil {
+			g.timer = time.AfterFunc(r.window, func() { r.flush(g) })
+		}
+		g.mu.Unlock()
+		return
+	}
+	batch := g.take()
+	g.mu.Unlock()
+	go r.send(g, batch)
+}
+
+func (r *Relay) flush(g *group) {
+	g.mu.Lock()
+	batch := g.take()
+	g.mu.Unlock()
+	if len(batch) > 0 {
+		r.send(g, batch)
+	}
+}
+
+// take empties the queue of g. g.mu must be held.
+func (g *group) take() []message {
+	if g.timer != nil {
+		g.timer.Stop()
+		g.timer = nil
+	}
+	batch := g.pending
+	g.pending = nil
+	return batch
+}
+
+func (r *Relay) send(g *group, batch []message) {
+	g.sending.Lock()
+	defer g.sending.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	results, err := r.translate(ctx, g, batch)
+	if err != nil {
+		logger.WarnCF("translate", "Translation failed", map[string]interface{}{
+			"channel":  g.channel,
+			"chat_id":  g.chatID,
+			"messages": len(batch),
+			"error":    err.Error(),
+		})
+		return
+	}
+	if out := format(g.languages, batch, results); out != "" {
+		r.bus.PublishOutbound(bus.OutboundMessage{
+			Channel: g.channel,
+			ChatID:  g.chatID,
+			Content: out,
+		})
+	}
+}
+
+// result is the model's answer for one message.
+type result struct {
+	ID           int               `json:"id"`
+	Lang         string            `json:"lang"`
+	Translations map[string]string `json:"translations"`
+}
+
+func (r *Relay) translate(ctx context.Context, g *group, batch []message) ([]result, error) {
+	type item struct {
+		ID   int    `json:"id"`
+		Text string `json:"text"`
+	}
+	items := make([]item, len(batch))
+	for i, m := range batch {
+		items[i] = item{ID: i + 1, Text: m.text}
+	}
+	input, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.provider.Chat(ctx, []providers.Message{
+		{Role: "system", Content: g.prompt()},
+		{Role: "user", Content: string(input)},
+	}, nil, r.model, map[string]interface{}{
+		"max_tokens":  4096,
+		"temperature": 0.2,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Usage != nil && r.onUsage != nil {
+		r.onUsage(resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+	}
+	return parseResults(resp.Content)
+}
+
+// prompt tells the model the group's languages and glossary.
+func (g *group) prompt() string {
+	names := make([]string, len(g.languages))
+	for i, code := range g.languages {
+		if name, ok := languageNames[strings.ToLower(code)]; ok {
+			names[i] = fmt.Sprintf("%s (%s)", name, code)
+		} else {
+			names[i] = code
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "You translate the messages of a group chat between %s.\n", strings.Join(names, ", "))
+	sb.WriteString("The user sends the messages as a JSON array. For each message, identify its language and translate it into each of the other languages. ")
+	sb.WriteString("Leave out every language the message is already written in. Keep names, emoji and tone; do not explain or answer the messages.\n")
+	sb.WriteString(`Reply with JSON only, in this form: {"messages":[{"id":1,"lang":"<code>","translations":{"<code>":"<text>"}}]}, using the language codes above.`)
+
+	g.mu.Lock()
+	glossary := g.state.Glossary
+	g.mu.Unlock()
+	if len(glossary) > 0 {
+		sb.WriteString("\n\nGlossary; always translate these terms this way:\n")
+		for _, entry := range glossary {
+			sb.WriteString("- " + entry + "\n")
+		}
+	}
+	return sb.String()
+}
+
+func parseResults(content string) ([]result, error) {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return nil, errors.New("no JSON in the reply")
+	}
+	var reply struct {
+		Messages []result `json:"messages"`
+	}
+	if err := json.Unmarshal([]byte(content[start:end+1]), &reply); err != nil {
+		return nil, fmt.Errorf("unreadable reply: %w", err)
+	}
+	return reply.Messages, nil
+}
+
+// format renders the translations of a batch as one message. Translations
+// into a message's own language, into languages the group does not use,
+// or identical to the original are left out.
+func format(languages []string, batch []message, results []result) string {
+	var entries []string
+	for _, res := range results {
+		if res.ID < 1 || res.ID > len(batch) {
+			continue
+		}
+		m := batch[res.ID-1]
+		var lines []string
+		for _, lang := range languages {
+			if strings.EqualFold(lang, res.Lang) {
+				continue
+			}
+			text := strings.TrimSpace(lookup(res.Translations, lang))
+			if text == "" || strings.EqualFold(text, m.text) {
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("[%s] %s", lang, text))
+		}
+		if len(lines) > 0 {
+			entries = append(entries, m.sender+":\n"+strings.Join(lines, "\n"))
+		}
+	}
+	return strings.Join(entries, "\n\n")
+}
+
+func lookup(translations map[string]string, lang string) string {
+	if text, ok := translations[lang]; ok {
+		return text
+	}
+	for code, text := range translations {
+		if strings.EqualFold(code, lang) {
+			return text
+		}
+	}
+	return ""
+}
+
+// hasWords reports whether text has letters outside of links, i.e.
+// something a translation could change.
+func hasWords(text string) bool {
+	for _, field := range strings.Fields(text) {
+		if strings.HasPrefix(field, "http://") || strings.HasPrefix(field, "https://") {
+			continue
+		}
+		for _, r := range field {
+			if unicode.IsLetter(r) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// senderName is how a message's author is shown above its translations.
+func senderName(msg bus.InboundMessage) string {
+	for _, key := range []string{"first_name", "display_name", "sender_name", "nickname", "user_name", "username"} {
+		if name := msg.Metadata[key]; name != "" {
+			return name
+		}
+	}
+	// Telegram sender IDs are "id|username"
+	if _, name, ok := strings.Cut(msg.SenderID, "|"); ok && name != "" {
+		return name
+	}
+	return msg.SenderID
+}
+
+func (g *group) load() {
+	data, err := os.ReadFile(g.path)
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &g.state); err != nil {
+		logger.WarnCF("translate", "Ignoring unreadable group settings", map[string]interface{}{
+			"path":  g.path,
+			"error": err.Error(),
+		})
+	}
+}
+
+// save writes the group's settings. g.mu must be held.
+func (g *group) save() error {
+	data, err := json.MarshalIndent(g.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(g.path), 0755); err != nil {
+		return err
+	}
+	tmp := g.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, g.path)
+}
+
+func (g *group) optedOut(senderID string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, id := range g.state.OptedOut {
+		if id == senderID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+package translate
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+type fakeProvider struct {
+	reply string
+
+	mu    sync.Mutex
+	calls [][]providers.Message
+	model string
+}
+
+func (p *fakeProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls = append(p.calls, messages)
+	p.model = model
+	return &providers.LLMResponse{
+		Content: p.reply,
+		Usage:   &providers.UsageInfo{PromptTokens: 100, CompletionTokens: 40},
+	}, nil
+}
+
+func (p *fakeProvider) GetDefaultModel() string {
+	return "default-model"
+}
+
+func (p *fakeProvider) requests() [][]providers.Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([][]providers.Message(nil), p.calls...)
+}
+
+func testConfig(window, maxBatch int) config.TranslateConfig {
+	return config.TranslateConfig{
+		Enabled:     true,
+		Model:       "cheap-model",
+		BatchWindow: window,
+		MaxBatch:    maxBatch,
+		Groups: []config.TranslateGroup{
+			{Channel: "telegram", ChatID: "-100", Languages: config.FlexibleStringSlice{"zh", "ja", "en"}},
+		},
+	}
+}
+
+func groupMessage(sender, name, text string) bus.InboundMessage {
+	return bus.InboundMessage{
+		Channel:  "telegram",
+		ChatID:   "-100",
+		SenderID: sender,
+		Content:  text,
+		Metadata: map[string]string{"first_name": name},
+	}
+}
+
+func outbound(t *testing.T, mb *bus.MessageBus) string {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	msg, ok := mb.SubscribeOutbound(ctx)
+	if !ok {
+		t.Fatal("no translation posted")
+	}
+	return msg.Content
+}
+
+func TestRelayBatchesAndSkipsSourceLanguage(t *testing.T) {
+	p := &fakeProvider{reply: "```json\n" + `{"messages":[
+		{"id":1,"lang":"en","translations":{"zh":"大家好","ja":"みなさんこんにちは","en":"Hello all"}},
+		{"id":2,"lang":"zh","translations":{"en":"Dinner is ready","ja":"","zh":"饭好了"}}
+	]}` + "\n```"}
+	mb := bus.NewMessageBus()
+	r := NewRelay(testConfig(3600, 10), p, "default-model", mb, t.TempDir())
+	var tokens int
+	r.OnUsage(func(prompt, completion int) { tokens += prompt + completion })
+
+	if _, handled := r.Intercept(bus.InboundMessage{Channel: "telegram", ChatID: "42", Content: "hi"}); handled {
+		t.Fatal("message outside a relay group intercepted")
+	}
+	for _, msg := range []bus.InboundMessage{
+		groupMessage("1|alice", "Alice", "Hello all"),
+		groupMessage("2|bob", "Bob", "饭好了"),
+		groupMessage("2|bob", "Bob", "👍 https://example.com/photo"),
+	} {
+		if reply, handled := r.Intercept(msg); !handled || reply != "" {
+			t.Fatalf("Intercept(%q) = %q, %v", msg.Content, reply, handled)
+		}
+	}
+	if len(p.requests()) != 0 {
+		t.Fatal("translated before the batch window closed")
+	}
+
+	r.Flush()
+	calls := p.requests()
+	if len(calls) != 1 || p.model != "cheap-model" {
+		t.Fatalf("%d requests to %q, want one to cheap-model", len(calls), p.model)
+	}
+	if input := calls[0][1].Content; strings.Contains(input, "👍") || !strings.Contains(input, "饭好了") {
+		t.Errorf("request input = %s", input)
+	}
+	if tokens != 140 {
+		t.Errorf("usage reported %d tokens", tokens)
+	}
+
+	want := "Alice:\n[zh] 大家好\n[ja] みなさんこんにちは\n\nBob:\n[en] Dinner is ready"
+	if got := outbound(t, mb); got != want {
+		t.Errorf("posted:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRelayFlushesFullBatch(t *testing.T) {
+	p := &fakeProvider{reply: `{"messages":[{"id":2,"lang":"ja","translations":{"en":"Good night"}}]}`}
+	mb := bus.NewMessageBus()
+	r := NewRelay(testConfig(3600, 2), p, "default-model", mb, t.TempDir())
+
+	r.Intercept(groupMessage("1", "Alice", "Hello"))
+	r.Intercept(groupMessage("3", "Kenji", "おやすみ"))
+	if got := outbound(t, mb); got != "Kenji:\n[en] Good night" {
+		t.Errorf("posted %q", got)
+	}
+}
+
+func TestRelayOptOutAndGlossary(t *testing.T) {
+	p := &fakeProvider{reply: `{"messages":[]}`}
+	dir := t.TempDir()
+	r := NewRelay(testConfig(3600, 10), p, "default-model", bus.NewMessageBus(), dir)
+
+	if reply, _ := r.Intercept(groupMessage("1|alice", "Alice", "/translate off")); !strings.Contains(reply, "no longer") {
+		t.Errorf("opt-out reply = %q", reply)
+	}
+	if reply, _ := r.Intercept(groupMessage("1|alice", "Alice", "/translate@picoclaw_bot")); !strings.Contains(reply, "left out") {
+		t.Errorf("status reply = %q", reply)
+	}
+	r.Intercept(groupMessage("1|alice", "Alice", "Private thought"))
+	r.Flush()
+	if len(p.requests()) != 0 {
+		t.Fatal("message of an opted-out member translated")
+	}
+
+	r.Intercept(groupMessage("2", "Bob", "/glossary add 小明 = Xiaoming = シャオミン"))
+	r.Intercept(groupMessage("2", "Bob", "/glossary add PicoClaw stays PicoClaw"))
+	r.Intercept(groupMessage("2", "Bob", "/glossary remove 2"))
+	if reply, _ := r.Intercept(groupMessage("2", "Bob", "/glossary remove 5")); !strings.Contains(reply, "no glossary entry 5") {
+		t.Errorf("unknown entry reply = %q", reply)
+	}
+
+	// Settings survive a restart
+	r = NewRelay(testConfig(3600, 10), p, "default-model", bus.NewMessageBus(), dir)
+	if reply, _ := r.Intercept(groupMessage("2", "Bob", "/glossary")); !strings.Contains(reply, "1. 小明 = Xiaoming") || strings.Contains(reply, "PicoClaw") {
+		t.Errorf("glossary = %q", reply)
+	}
+	r.Intercept(groupMessage("1|alice", "Alice", "Still private"))
+	r.Intercept(groupMessage("2", "Bob", "小明来了吗"))
+	r.Flush()
+	calls := p.requests()
+	if len(calls) != 1 {
+		t.Fatalf("%d requests, want 1", len(calls))
+	}
+	if prompt := calls[0][0].Content; !strings.Contains(prompt, "- 小明 = Xiaoming = シャオミン") {
+		t.Errorf("glossary missing from prompt:\n%s", prompt)
+	}
+	if input := calls[0][1].Content; strings.Contains(input, "Still private") {
+		t.Errorf("opted-out message sent: %s", input)
+	}
+
+	r.Intercept(groupMessage("1|alice", "Alice", "/translate on"))
+	r.Intercept(groupMessage("1|alice", "Alice", "Back in"))
+	r.Flush()
+	if len(p.requests()) != 2 {
+		t.Error("message not translated after opting back in")
+	}
+}
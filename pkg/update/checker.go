@@ -0,0 +1,98 @@
+package update
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// Checker looks for new releases on a schedule and reports each new
+// version once.
+type Checker struct {
+	cfg     config.UpdateConfig
+	current string
+	// seenFile remembers the last version reported, across restarts.
+	seenFile string
+	notify   func(rel *Release)
+}
+
+// NewChecker returns a checker for the running version. notify is called
+// for each release newer than current, once per version.
+func NewChecker(cfg config.UpdateConfig, current, seenFile string, notify func(rel *Release)) *Checker {
+	return &Checker{cfg: cfg, current: current, seenFile: seenFile, notify: notify}
+}
+
+// Check fetches the manifest and notifies about a newer release not
+// reported before.
+func (c *Checker) Check(ctx context.Context) (*Release, error) {
+	m, err := FetchManifest(ctx, c.cfg.ManifestURL)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := m.Latest(c.cfg.Channel)
+	if err != nil {
+		return nil, err
+	}
+	if Compare(rel.Version, c.current) <= 0 {
+		return nil, nil
+	}
+	if c.seen() == rel.Version {
+		return rel, nil
+	}
+	c.notify(rel)
+	c.remember(rel.Version)
+	return rel, nil
+}
+
+// Run checks every check_interval hours until ctx is done.
+func (c *Checker) Run(ctx context.Context) {
+	interval := time.Duration(c.cfg.CheckInterval) * time.Hour
+	if interval <= 0 || c.cfg.ManifestURL == "" {
+		return
+	}
+	// Let the gateway settle before the first check
+	timer := time.NewTimer(time.Minute)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+		if _, err := c.Check(ctx); err != nil {
+			logger.WarnCF("update", "Update check failed", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+		timer.Reset(interval)
+	}
+}
+
+type seenState struct {
+	Notified string `json:"notified"`
+}
+
+func (c *Checker) seen() string {
+	data, err := os.ReadFile(c.seenFile)
+	if err != nil {
+		return ""
+	}
+	var s seenState
+	json.Unmarshal(data, &s)
+	return s.Notified
+}
+
+func (c *Checker) remember(version string) {
+	data, _ := json.Marshal(seenState{Notified: version})
+	os.MkdirAll(filepath.Dir(c.seenFile), 0755)
+	if err := os.WriteFile(c.seenFile, data, 0644); err != nil {
+		logger.WarnCF("update", "Failed to record the notified version", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+}
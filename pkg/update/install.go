@@ -0,0 +1,221 @@
+package update
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+const (
+	// maxBinarySize bounds an asset download.
+	maxBinarySize = 256 << 20
+	// maxBootAttempts is how many starts a new binary gets to confirm
+	// itself before the previous one is restored.
+	maxBootAttempts = 3
+	smokeTimeout    = 15 * time.Second
+)
+
+// ErrRolledBack means Boot restored the previous binary. The process
+// should exit so its supervisor starts the restored one.
+var ErrRolledBack = errors.New("update failed to start; previous binary restored")
+
+// pending is written next to the binary after a swap, until the new
+// binary confirms it starts.
+type pending struct {
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Installed time.Time `json:"installed"`
+	Attempts  int       `json:"attempts"`
+}
+
+// Installer replaces the binary at Exe.
+type Installer struct {
+	Exe string
+	Key ed25519.PublicKey
+
+	// smoke runs the downloaded binary before it is swapped in.
+	smoke func(ctx context.Context, path, version string) error
+}
+
+// NewInstaller returns an installer for the binary at exe, accepting
+// assets signed with key.
+func NewInstaller(exe string, key ed25519.PublicKey) *Installer {
+	return &Installer{Exe: exe, Key: key, smoke: runVersion}
+}
+
+// Executable returns the path of the running binary, with symlinks
+// resolved so the real file is replaced.
+func Executable() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(exe)
+}
+
+// Install downloads the asset of rel from m, verifies its digest and
+// signature, checks that it runs, and swaps it in for the current binary,
+// which is kept as <exe>.old. from is the version being replaced.
+func (in *Installer) Install(ctx context.Context, m *Manifest, rel *Release, a *Asset, from string) error {
+	if len(in.Key) != ed25519.PublicKeySize {
+		return errors.New("no public key to verify the release with")
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(in.Exe), ".picoclaw-update-*")
+	if err != nil {
+		return fmt.Errorf("cannot write next to %s: %w", in.Exe, err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	r, err := open(ctx, m.resolve(a.URL))
+	if err != nil {
+		tmp.Close()
+		return fmt.Errorf("download: %w", err)
+	}
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, h), io.LimitReader(r, maxBinarySize+1))
+	r.Close()
+	if err == nil && n > maxBinarySize {
+		err = fmt.Errorf("larger than %d MB", maxBinarySize>>20)
+	}
+	if err == nil {
+		err = tmp.Chmod(0755)
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("download: %w", err)
+	}
+
+	if err := Verify(in.Key, rel.Version, a, hex.EncodeToString(h.Sum(nil))); err != nil {
+		return err
+	}
+	if err := in.smoke(ctx, tmpPath, rel.Version); err != nil {
+		return fmt.Errorf("new binary does not run: %w", err)
+	}
+
+	old := in.Exe + ".old"
+	os.Remove(old)
+	if err := os.Rename(in.Exe, old); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, in.Exe); err != nil {
+		if rerr := os.Rename(old, in.Exe); rerr != nil {
+			return fmt.Errorf("%v; restoring the previous binary also failed: %w", err, rerr)
+		}
+		return err
+	}
+
+	logger.InfoCF("update", "Binary replaced", map[string]interface{}{
+		"from": from,
+		"to":   rel.Version,
+	})
+	return writePending(in.Exe, pending{From: from, To: rel.Version, Installed: time.Now()})
+}
+
+// Boot counts a start of a freshly installed binary. When it has started
+// maxBootAttempts times without Confirm, the previous binary is restored
+// and ErrRolledBack returned.
+func Boot(exe string) error {
+	p, err := readPending(exe)
+	if err != nil || p == nil {
+		return err
+	}
+	p.Attempts++
+	if p.Attempts <= maxBootAttempts {
+		return writePending(exe, *p)
+	}
+	if err := Rollback(exe); err != nil {
+		return err
+	}
+	logger.ErrorCF("update", "New binary did not start; rolled back", map[string]interface{}{
+		"failed":   p.To,
+		"restored": p.From,
+	})
+	return ErrRolledBack
+}
+
+// Confirm marks the installed binary as good.
+func Confirm(exe string) error {
+	err := os.Remove(exe + ".pending")
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// Pending returns the version installed but not yet confirmed, if any.
+func Pending(exe string) string {
+	p, _ := readPending(exe)
+	if p == nil {
+		return ""
+	}
+	return p.To
+}
+
+// Rollback restores the binary kept by the last Install.
+func Rollback(exe string) error {
+	old := exe + ".old"
+	if _, err := os.Stat(old); err != nil {
+		return fmt.Errorf("no previous binary at %s", old)
+	}
+	if err := os.Rename(old, exe); err != nil {
+		return err
+	}
+	return Confirm(exe)
+}
+
+func readPending(exe string) (*pending, error) {
+	data, err := os.ReadFile(exe + ".pending")
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var p pending
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func writePending(exe string, p pending) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := exe + ".pending.tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, exe+".pending")
+}
+
+// runVersion runs `<path> version` and checks it reports version.
+func runVersion(ctx context.Context, path, version string) error {
+	ctx, cancel := context.WithTimeout(ctx, smokeTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, path, "version").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+	}
+	if !strings.Contains(string(out), strings.TrimPrefix(version, "v")) {
+		return fmt.Errorf("it reports %q, not version %s", strings.TrimSpace(string(out)), version)
+	}
+	return nil
+}
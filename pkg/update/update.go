@@ -0,0 +1,277 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// Inspired by and based on nanobot: https://github.com/HKUDS/nanobot
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+// Package update replaces the running binary with a newer release. Releases
+// are listed per channel (stable, beta) in a manifest served over HTTP or
+// read from a local path. Each asset carries the sha256 of the binary and
+// an ed25519 signature over its version, platform and digest, so a mirror
+// can serve the files without being trusted.
+package update
+
+import (
+	"cmp"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/network"
+)
+
+// maxManifestSize bounds the manifest download.
+const maxManifestSize = 1 << 20
+
+var (
+	ErrNoRelease = errors.New("no release")
+	ErrNoAsset   = errors.New("no binary for this platform")
+	ErrChecksum  = errors.New("sha256 mismatch")
+	ErrSignature = errors.New("bad signature")
+)
+
+// Manifest lists the latest release of each channel.
+type Manifest struct {
+	Channels map[string]Release `json:"channels"`
+
+	// source is where the manifest was read from; relative asset URLs
+	// resolve against it.
+	source string
+}
+
+// Release is one version and its binaries.
+type Release struct {
+	Version string  `json:"version"`
+	Notes   string  `json:"notes,omitempty"`
+	Assets  []Asset `json:"assets"`
+}
+
+// Asset is the binary for one platform. URL may be relative to the
+// manifest.
+type Asset struct {
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+	URL       string `json:"url"`
+	Size      int64  `json:"size,omitempty"`
+	SHA256    string `json:"sha256"`
+	Signature string `json:"signature"` // base64 ed25519 over SignedMessage
+}
+
+// FetchManifest reads the manifest at src, an http(s) URL, a file:// URL
+// or a local path.
+func FetchManifest(ctx context.Context, src string) (*Manifest, error) {
+	r, err := open(ctx, src)
+	if err != nil {
+		return nil, fmt.Errorf("manifest: %w", err)
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(io.LimitReader(r, maxManifestSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("manifest: %w", err)
+	}
+	if len(data) > maxManifestSize {
+		return nil, fmt.Errorf("manifest: larger than %d bytes", maxManifestSize)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("manifest: %w", err)
+	}
+	m.source = src
+	return &m, nil
+}
+
+// Latest returns the release of channel.
+func (m *Manifest) Latest(channel string) (*Release, error) {
+	rel, ok := m.Channels[channel]
+	if !ok || rel.Version == "" {
+		return nil, fmt.Errorf("%w in channel %q", ErrNoRelease, channel)
+	}
+	return &rel, nil
+}
+
+// Asset returns the binary of r for goos/goarch.
+func (r *Release) Asset(goos, goarch string) (*Asset, error) {
+	for i := range r.Assets {
+		if r.Assets[i].OS == goos && r.Assets[i].Arch == goarch {
+			return &r.Assets[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w (%s/%s) in %s", ErrNoAsset, goos, goarch, r.Version)
+}
+
+// resolve returns where to download an asset URL from.
+func (m *Manifest) resolve(ref string) string {
+	if isRemote(ref) || strings.HasPrefix(ref, "file://") || filepath.IsAbs(ref) {
+		return ref
+	}
+	if isRemote(m.source) {
+		base, err := url.Parse(m.source)
+		if err == nil {
+			if u, err := base.Parse(ref); err == nil {
+				return u.String()
+			}
+		}
+		return ref
+	}
+	return filepath.Join(filepath.Dir(strings.TrimPrefix(m.source, "file://")), ref)
+}
+
+func isRemote(src string) bool {
+	return strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://")
+}
+
+// open reads src over HTTP or from the local filesystem.
+func open(ctx context.Context, src string) (io.ReadCloser, error) {
+	if !isRemote(src) {
+		return os.Open(strings.TrimPrefix(src, "file://"))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := network.NewClient(network.ComponentUpdate, network.Options{Timeout: 10 * time.Minute})
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: %s", src, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// SignedMessage is what an asset's signature covers. Binding the version
+// and platform keeps a signed old or foreign binary from being passed off
+// as this release.
+func SignedMessage(version, goos, goarch, sha256Hex string) []byte {
+	return []byte(fmt.Sprintf("picoclaw %s %s/%s %s", version, goos, goarch, strings.ToLower(sha256Hex)))
+}
+
+// ParsePublicKey decodes a base64 ed25519 public key.
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, errors.New("not a base64 ed25519 public key")
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// ParsePrivateKey decodes a base64 ed25519 private key.
+func ParsePrivateKey(s string) (ed25519.PrivateKey, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(key) != ed25519.PrivateKeySize {
+		return nil, errors.New("not a base64 ed25519 private key")
+	}
+	return ed25519.PrivateKey(key), nil
+}
+
+// Verify checks that sha256Hex, the digest of the downloaded binary, is
+// the one in a and that a is signed with key for version.
+func Verify(key ed25519.PublicKey, version string, a *Asset, sha256Hex string) error {
+	if !strings.EqualFold(a.SHA256, sha256Hex) {
+		return fmt.Errorf("%w: got %s, manifest has %s", ErrChecksum, sha256Hex, a.SHA256)
+	}
+	sig, err := base64.StdEncoding.DecodeString(a.Signature)
+	if err != nil || !ed25519.Verify(key, SignedMessage(version, a.OS, a.Arch, sha256Hex), sig) {
+		return ErrSignature
+	}
+	return nil
+}
+
+// Sign returns the manifest entry for the binary in r.
+func Sign(key ed25519.PrivateKey, version, goos, goarch string, r io.Reader) (Asset, error) {
+	h := sha256.New()
+	n, err := io.Copy(h, r)
+	if err != nil {
+		return Asset{}, err
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	return Asset{
+		OS:        goos,
+		Arch:      goarch,
+		Size:      n,
+		SHA256:    sum,
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, SignedMessage(version, goos, goarch, sum))),
+	}, nil
+}
+
+// gitDescribe matches the suffix `git describe` adds to builds after a tag.
+var gitDescribe = regexp.MustCompile(`^\d+-g[0-9a-f]+(-dirty)?$`)
+
+// Compare returns -1, 0 or 1 as version a is older than, the same as or
+// newer than b. Versions are dotted numbers with an optional "v" prefix
+// and "-prerelease" suffix. Builds described by git after a tag
+// (v1.2.0-3-gabc123) are newer than the tag. Anything else, such as "dev",
+// is older than every release.
+func Compare(a, b string) int {
+	na, ra, okA := parseVersion(a)
+	nb, rb, okB := parseVersion(b)
+	switch {
+	case !okA && !okB:
+		return 0
+	case !okA:
+		return -1
+	case !okB:
+		return 1
+	}
+	for i := 0; i < max(len(na), len(nb)); i++ {
+		var x, y int
+		if i < len(na) {
+			x = na[i]
+		}
+		if i < len(nb) {
+			y = nb[i]
+		}
+		if x != y {
+			return cmp.Compare(x, y)
+		}
+	}
+	if c := cmp.Compare(rank(ra), rank(rb)); c != 0 || ra == rb {
+		return c
+	}
+	return cmp.Compare(ra, rb)
+}
+
+// rank orders the suffixes of one version: prereleases, the release, then
+// later builds.
+func rank(suffix string) int {
+	switch {
+	case suffix == "":
+		return 1
+	case gitDescribe.MatchString(suffix):
+		return 2
+	}
+	return 0
+}
+
+func parseVersion(v string) (nums []int, suffix string, ok bool) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	core, suffix, _ := strings.Cut(v, "-")
+	if core == "" {
+		return nil, "", false
+	}
+	for _, part := range strings.Split(core, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, "", false
+		}
+		nums = append(nums, n)
+	}
+	return nums, suffix, true
+}
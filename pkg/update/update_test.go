@@ -0,0 +1,198 @@
+package update
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"v1.2.0", "1.2.0", 0},
+		{"1.10.0", "1.9.3", 1},
+		{"1.2", "1.2.1", -1},
+		{"1.2.0-beta.1", "1.2.0", -1},
+		{"1.2.0-beta.2", "1.2.0-beta.1", 1},
+		{"v1.2.0-3-gabc123", "v1.2.0", 1},
+		{"v1.2.0-3-gabc123-dirty", "v1.2.1", -1},
+		{"dev", "0.0.1", -1},
+		{"0.0.1", "dev", 1},
+	}
+	for _, tt := range tests {
+		if got := Compare(tt.a, tt.b); got != tt.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// release writes a signed binary and a manifest listing it into dir.
+func release(t *testing.T, dir string, key ed25519.PrivateKey, version string, binary []byte) string {
+	t.Helper()
+	os.WriteFile(filepath.Join(dir, "picoclaw-test"), binary, 0644)
+	asset, err := Sign(key, version, "linux", "arm64", bytes.NewReader(binary))
+	if err != nil {
+		t.Fatal(err)
+	}
+	asset.URL = "picoclaw-test"
+	data, _ := json.Marshal(Manifest{Channels: map[string]Release{
+		"stable": {Version: version, Assets: []Asset{asset}},
+	}})
+	path := filepath.Join(dir, "manifest.json")
+	os.WriteFile(path, data, 0644)
+	return path
+}
+
+func TestInstallVerifiesAndSwaps(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	dir := t.TempDir()
+	manifest := release(t, t.TempDir(), priv, "1.1.0", []byte("new binary"))
+	exe := filepath.Join(dir, "picoclaw")
+	os.WriteFile(exe, []byte("old binary"), 0755)
+
+	m, err := FetchManifest(context.Background(), manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rel, _ := m.Latest("stable")
+	asset, err := rel.Asset("linux", "arm64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rel.Asset("windows", "amd64"); !errors.Is(err, ErrNoAsset) {
+		t.Errorf("foreign platform: %v", err)
+	}
+
+	in := NewInstaller(exe, pub)
+	smoked := ""
+	in.smoke = func(_ context.Context, path, version string) error {
+		data, _ := os.ReadFile(path)
+		smoked = string(data)
+		return nil
+	}
+
+	// A key that did not sign the release is refused
+	other, _, _ := ed25519.GenerateKey(nil)
+	if err := NewInstaller(exe, other).Install(context.Background(), m, rel, asset, "1.0.0"); !errors.Is(err, ErrSignature) {
+		t.Errorf("wrong key: %v", err)
+	}
+	// So is a binary that is not the one signed
+	tampered := *asset
+	tampered.SHA256 = "00" + asset.SHA256[2:]
+	if err := in.Install(context.Background(), m, rel, &tampered, "1.0.0"); !errors.Is(err, ErrChecksum) {
+		t.Errorf("tampered digest: %v", err)
+	}
+	// And the signature of another version
+	relabeled := *rel
+	relabeled.Version = "9.9.9"
+	if err := in.Install(context.Background(), m, &relabeled, asset, "1.0.0"); !errors.Is(err, ErrSignature) {
+		t.Errorf("signature replayed for another version: %v", err)
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "old binary" {
+		t.Fatal("binary replaced by a rejected update")
+	}
+
+	if err := in.Install(context.Background(), m, rel, asset, "1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if smoked != "new binary" {
+		t.Errorf("smoke test ran on %q", smoked)
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "new binary" {
+		t.Errorf("binary = %q", data)
+	}
+	if data, _ := os.ReadFile(exe + ".old"); string(data) != "old binary" {
+		t.Errorf("previous binary = %q", data)
+	}
+	if Pending(exe) != "1.1.0" {
+		t.Errorf("pending = %q", Pending(exe))
+	}
+}
+
+func TestBootRollsBackAfterFailedStarts(t *testing.T) {
+	dir := t.TempDir()
+	exe := filepath.Join(dir, "picoclaw")
+	os.WriteFile(exe, []byte("new"), 0755)
+	os.WriteFile(exe+".old", []byte("old"), 0755)
+	writePending(exe, pending{From: "1.0.0", To: "1.1.0"})
+
+	for i := 0; i < maxBootAttempts; i++ {
+		if err := Boot(exe); err != nil {
+			t.Fatalf("start %d: %v", i+1, err)
+		}
+	}
+	if err := Boot(exe); !errors.Is(err, ErrRolledBack) {
+		t.Fatalf("start after %d failures: %v", maxBootAttempts, err)
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "old" {
+		t.Errorf("binary after rollback = %q", data)
+	}
+	if Pending(exe) != "" {
+		t.Error("still pending after rollback")
+	}
+
+	// A confirmed update stays
+	os.WriteFile(exe+".old", []byte("older"), 0755)
+	writePending(exe, pending{From: "1.0.0", To: "1.1.0"})
+	Boot(exe)
+	Confirm(exe)
+	for i := 0; i < maxBootAttempts+1; i++ {
+		if err := Boot(exe); err != nil {
+			t.Fatalf("boot after confirm: %v", err)
+		}
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "old" {
+		t.Errorf("confirmed binary replaced: %q", data)
+	}
+}
+
+func TestCheckerOverHTTP(t *testing.T) {
+	_, priv, _ := ed25519.GenerateKey(nil)
+	dir := t.TempDir()
+	release(t, dir, priv, "2.0.0", []byte("bin"))
+	srv := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer srv.Close()
+
+	m, err := FetchManifest(context.Background(), srv.URL+"/manifest.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.resolve("picoclaw-test"); got != srv.URL+"/picoclaw-test" {
+		t.Errorf("asset URL = %q", got)
+	}
+
+	var notified []string
+	cfg := config.UpdateConfig{ManifestURL: srv.URL + "/manifest.json", Channel: "stable"}
+	seen := filepath.Join(t.TempDir(), "update.json")
+	c := NewChecker(cfg, "1.0.0", seen, func(rel *Release) { notified = append(notified, rel.Version) })
+	for i := 0; i < 2; i++ {
+		if rel, err := c.Check(context.Background()); err != nil || rel == nil {
+			t.Fatalf("Check = %v, %v", rel, err)
+		}
+	}
+	// A restart does not repeat the notice either
+	NewChecker(cfg, "1.0.0", seen, func(rel *Release) { notified = append(notified, rel.Version) }).Check(context.Background())
+	if len(notified) != 1 || notified[0] != "2.0.0" {
+		t.Errorf("notified %v, want 2.0.0 once", notified)
+	}
+
+	upToDate := NewChecker(cfg, "2.0.0", filepath.Join(t.TempDir(), "u.json"), func(*Release) { t.Error("notified while up to date") })
+	if rel, err := upToDate.Check(context.Background()); err != nil || rel != nil {
+		t.Errorf("up to date: %v, %v", rel, err)
+	}
+	cfg.Channel = "beta"
+	if _, err := NewChecker(cfg, "1.0.0", seen, nil).Check(context.Background()); !errors.Is(err, ErrNoRelease) {
+		t.Errorf("missing channel: %v", err)
+	}
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/network"
 )
 
 // IsAudioFile checks if a file is an audio file based on its filename extension and content type.
@@ -53,6 +54,25 @@ type DownloadOptions struct {
 	LoggerPrefix string
 }
 
+// downloadPrefixLen is the length of the unique prefix DownloadFile puts
+// in front of file names, including the separating underscore.
+const downloadPrefixLen = 9
+
+// OriginalFilename returns the name a file had before DownloadFile made it
+// unique.
+func OriginalFilename(path string) string {
+	base := filepath.Base(path)
+	if len(base) <= downloadPrefixLen || base[downloadPrefixLen-1] != '_' {
+		return base
+	}
+	for _, r := range base[:downloadPrefixLen-1] {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return base
+		}
+	}
+	return base[downloadPrefixLen:]
+}
+
 // DownloadFile downloads a file from URL to a local temp directory.
 // Returns the local file path or empty string on error.
 func DownloadFile(url, filename string, opts DownloadOptions) string {
@@ -74,7 +94,7 @@ func DownloadFile(url, filename string, opts DownloadOptions) string {
 
 	// Generate unique filename with UUID prefix to prevent conflicts
 	safeName := SanitizeFilename(filename)
-	localPath := filepath.Join(mediaDir, uuid.New().String()[:8]+"_"+safeName)
+	localPath := filepath.Join(mediaDir, uuid.New().String()[:downloadPrefixLen-1]+"_"+safeName)
 
 	// Create HTTP request
 	req, err := http.NewRequest("GET", url, nil)
@@ -90,7 +110,7 @@ func DownloadFile(url, filename string, opts DownloadOptions) string {
 		req.Header.Set(key, value)
 	}
 
-	client := &http.Client{Timeout: opts.Timeout}
+	client := network.NewClient(network.ComponentMedia, network.Options{Timeout: opts.Timeout})
 	resp, err := client.Do(req)
 	if err != nil {
 		logger.ErrorCF(opts.LoggerPrefix, "Failed to download file", map[string]interface{}{
@@ -14,3 +14,38 @@ func Truncate(s string, maxLen int) string {
 	}
 	return string(runes[:maxLen-3]) + "..."
 }
+
+// SplitMessage splits s into chunks of at most maxLen runes, preferring to
+// break at a newline, then at a space, so that long replies can be sent to
+// platforms with a message size limit.
+func SplitMessage(s string, maxLen int) []string {
+	runes := []rune(s)
+	if maxLen <= 0 || len(runes) <= maxLen {
+		return []string{s}
+	}
+
+	var chunks []string
+	for len(runes) > maxLen {
+		cut := maxLen
+		for i := maxLen; i > maxLen/2; i-- {
+			if runes[i-1] == '\n' {
+				cut = i
+				break
+			}
+		}
+		if cut == maxLen {
+			for i := maxLen; i > maxLen/2; i-- {
+				if runes[i-1] == ' ' {
+					cut = i
+					break
+				}
+			}
+		}
+		chunks = append(chunks, string(runes[:cut]))
+		runes = runes[cut:]
+	}
+	if len(runes) > 0 {
+		chunks = append(chunks, string(runes))
+	}
+	return chunks
+}
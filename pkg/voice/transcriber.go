@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/network"
 	"github.com/sipeed/picoclaw/pkg/utils"
 )
 
@@ -35,9 +36,9 @@ func NewGroqTranscriber(apiKey string) *GroqTranscriber {
 	return &GroqTranscriber{
 		apiKey:  apiKey,
 		apiBase: apiBase,
-		httpClient: &http.Client{
+		httpClient: network.NewClient(network.ComponentVoice, network.Options{
 			Timeout: 60 * time.Second,
-		},
+		}),
 	}
 }
 